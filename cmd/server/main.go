@@ -27,6 +27,7 @@ import (
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/misc"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/store"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/tokencrypt"
 	_ "github.com/router-for-me/CLIProxyAPI/v6/internal/translator"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/translator_new"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/tui"
@@ -109,6 +110,7 @@ func main() {
 	var noIncognito bool
 	var useIncognito bool
 	var localModel bool
+	var migrateTokenEncryption bool
 
 	// Define command-line flags for different operation modes.
 	flag.BoolVar(&login, "login", false, "Login Google Account")
@@ -147,6 +149,7 @@ func main() {
 	flag.BoolVar(&tuiMode, "tui", false, "Start with terminal management UI")
 	flag.BoolVar(&standalone, "standalone", false, "In TUI mode, start an embedded local server")
 	flag.BoolVar(&localModel, "local-model", false, "Use embedded model catalog only, skip remote model fetching")
+	flag.BoolVar(&migrateTokenEncryption, "migrate-token-encryption", false, "Encrypt, re-key or decrypt all stored auth files to match token-encryption settings")
 
 	flag.CommandLine.Usage = func() {
 		out := flag.CommandLine.Output()
@@ -188,6 +191,10 @@ func main() {
 		pgStoreSchema        string
 		pgStoreLocalPath     string
 		pgStoreInst          *store.PostgresStore
+		useSQLiteStore       bool
+		sqliteStorePath      string
+		sqliteStoreLocalPath string
+		sqliteStoreInst      *store.SQLiteStore
 		useGitStore          bool
 		gitStoreRemoteURL    string
 		gitStoreUser         string
@@ -201,6 +208,8 @@ func main() {
 		objectStoreSecret    string
 		objectStoreBucket    string
 		objectStoreLocalPath string
+		objectStorePoll      time.Duration
+		objectStorePollOff   bool
 		objectStoreInst      *store.ObjectTokenStore
 	)
 
@@ -248,6 +257,13 @@ func main() {
 		}
 		useGitStore = false
 	}
+	if value, ok := lookupEnv("SQLITESTORE_PATH", "sqlitestore_path"); ok {
+		useSQLiteStore = true
+		sqliteStorePath = value
+	}
+	if value, ok := lookupEnv("SQLITESTORE_LOCAL_PATH", "sqlitestore_local_path"); ok {
+		sqliteStoreLocalPath = value
+	}
 	if value, ok := lookupEnv("GITSTORE_GIT_URL", "gitstore_git_url"); ok {
 		useGitStore = true
 		gitStoreRemoteURL = value
@@ -277,6 +293,15 @@ func main() {
 	if value, ok := lookupEnv("OBJECTSTORE_LOCAL_PATH", "objectstore_local_path"); ok {
 		objectStoreLocalPath = value
 	}
+	if value, ok := lookupEnv("OBJECTSTORE_POLL_INTERVAL", "objectstore_poll_interval"); ok {
+		if parsed, errParse := time.ParseDuration(value); errParse != nil {
+			log.Warnf("invalid OBJECTSTORE_POLL_INTERVAL %q, using the default: %v", value, errParse)
+		} else if parsed <= 0 {
+			objectStorePollOff = true
+		} else {
+			objectStorePoll = parsed
+		}
+	}
 
 	// Check for cloud deploy mode only on first execution
 	// Read env var name in uppercase: DEPLOY
@@ -286,7 +311,7 @@ func main() {
 	}
 
 	// Determine and load the configuration file.
-	// Prefer the Postgres store when configured, otherwise fallback to git or local files.
+	// Prefer the Postgres store when configured, then SQLite, object storage, git or local files.
 	var configFilePath string
 	if usePostgresStore {
 		if pgStoreLocalPath == "" {
@@ -318,6 +343,38 @@ func main() {
 			cfg.AuthDir = pgStoreInst.AuthDir()
 			log.Infof("postgres-backed token store enabled, workspace path: %s", pgStoreInst.WorkDir())
 		}
+	} else if useSQLiteStore {
+		if sqliteStoreLocalPath == "" {
+			if writableBase != "" {
+				sqliteStoreLocalPath = writableBase
+			} else {
+				sqliteStoreLocalPath = wd
+			}
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		sqliteStoreInst, err = store.NewSQLiteStore(ctx, store.SQLiteStoreConfig{
+			Path:     sqliteStorePath,
+			SpoolDir: filepath.Join(sqliteStoreLocalPath, "sqlitestore"),
+		})
+		cancel()
+		if err != nil {
+			log.Errorf("failed to initialize sqlite token store: %v", err)
+			return
+		}
+		examplePath := filepath.Join(wd, "config.example.yaml")
+		ctx, cancel = context.WithTimeout(context.Background(), 30*time.Second)
+		if errBootstrap := sqliteStoreInst.Bootstrap(ctx, examplePath); errBootstrap != nil {
+			cancel()
+			log.Errorf("failed to bootstrap sqlite-backed config: %v", errBootstrap)
+			return
+		}
+		cancel()
+		configFilePath = sqliteStoreInst.ConfigPath()
+		cfg, err = config.LoadConfigOptional(configFilePath, isCloudDeploy)
+		if err == nil {
+			cfg.AuthDir = sqliteStoreInst.AuthDir()
+			log.Infof("sqlite-backed token store enabled, database: %s", sqliteStoreInst.DatabasePath())
+		}
 	} else if useObjectStore {
 		if objectStoreLocalPath == "" {
 			if writableBase != "" {
@@ -362,6 +419,8 @@ func main() {
 			LocalRoot: objectStoreRoot,
 			UseSSL:    useSSL,
 			PathStyle: true,
+
+			PollInterval: objectStorePoll,
 		}
 		objectStoreInst, err = store.NewObjectTokenStore(objCfg)
 		if err != nil {
@@ -493,6 +552,12 @@ func main() {
 	}
 	managementasset.SetCurrentConfig(cfg)
 
+	// Install the auth file encryption keyring before any token store reads or writes auth files.
+	if errKeys := tokencrypt.Configure(cfg.TokenEncryption); errKeys != nil {
+		log.Errorf("failed to configure token encryption: %v", errKeys)
+		return
+	}
+
 	// Create login options to be used in authentication flows.
 	options := &cmd.LoginOptions{
 		NoBrowser:    noBrowser,
@@ -502,6 +567,8 @@ func main() {
 	// Register the shared token store once so all components use the same persistence backend.
 	if usePostgresStore {
 		sdkAuth.RegisterTokenStore(pgStoreInst)
+	} else if useSQLiteStore {
+		sdkAuth.RegisterTokenStore(sqliteStoreInst)
 	} else if useObjectStore {
 		sdkAuth.RegisterTokenStore(objectStoreInst)
 	} else if useGitStore {
@@ -515,7 +582,9 @@ func main() {
 
 	// Handle different command modes based on the provided flags.
 
-	if vertexImport != "" {
+	if migrateTokenEncryption {
+		cmd.DoMigrateTokenEncryption(cfg)
+	} else if vertexImport != "" {
 		// Handle Vertex service account import
 		cmd.DoVertexImport(cfg, vertexImport, vertexImportPrefix)
 	} else if login {
@@ -589,6 +658,12 @@ func main() {
 			cmd.WaitForCloudDeploy()
 			return
 		}
+		// Pull changes made by other replicas into the local workspace.
+		if usePostgresStore {
+			go pgStoreInst.WatchRemoteChanges(context.Background())
+		} else if useObjectStore && !objectStorePollOff {
+			go objectStoreInst.WatchRemoteChanges(context.Background())
+		}
 		if localModel && (!tuiMode || standalone) {
 			log.Info("Local model mode: using embedded model catalog, remote model updates disabled")
 		}
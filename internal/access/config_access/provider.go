@@ -4,6 +4,7 @@ import (
 	"context"
 	"net/http"
 	"strings"
+	"time"
 
 	sdkaccess "github.com/router-for-me/CLIProxyAPI/v6/sdk/access"
 	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
@@ -17,20 +18,20 @@ func Register(cfg *sdkconfig.SDKConfig) {
 	}
 
 	keys := normalizeKeys(cfg.APIKeys)
-	if len(keys) == 0 {
+	if len(keys) == 0 && len(cfg.ClientKeys) == 0 {
 		sdkaccess.UnregisterProvider(sdkaccess.AccessProviderTypeConfigAPIKey)
 		return
 	}
 
-	sdkaccess.RegisterProvider(
-		sdkaccess.AccessProviderTypeConfigAPIKey,
-		newProvider(sdkaccess.DefaultAccessProviderName, keys),
-	)
+	p := newProvider(sdkaccess.DefaultAccessProviderName, keys)
+	p.setClientKeys(cfg.ClientKeys)
+	sdkaccess.RegisterProvider(sdkaccess.AccessProviderTypeConfigAPIKey, p)
 }
 
 type provider struct {
-	name string
-	keys map[string]struct{}
+	name       string
+	keys       map[string]struct{}
+	clientKeys map[string]sdkconfig.ClientKey
 }
 
 func newProvider(name string, keys []string) *provider {
@@ -45,6 +46,24 @@ func newProvider(name string, keys []string) *provider {
 	return &provider{name: providerName, keys: keySet}
 }
 
+// setClientKeys indexes managed client keys by their secret.
+func (p *provider) setClientKeys(clientKeys []sdkconfig.ClientKey) {
+	if len(clientKeys) == 0 {
+		p.clientKeys = nil
+		return
+	}
+	p.clientKeys = make(map[string]sdkconfig.ClientKey, len(clientKeys))
+	for _, clientKey := range clientKeys {
+		key := strings.TrimSpace(clientKey.Key)
+		if key == "" {
+			continue
+		}
+		if _, exists := p.clientKeys[key]; !exists {
+			p.clientKeys[key] = clientKey
+		}
+	}
+}
+
 func (p *provider) Identifier() string {
 	if p == nil || p.name == "" {
 		return sdkaccess.DefaultAccessProviderName
@@ -56,7 +75,7 @@ func (p *provider) Authenticate(_ context.Context, r *http.Request) (*sdkaccess.
 	if p == nil {
 		return nil, sdkaccess.NewNotHandledError()
 	}
-	if len(p.keys) == 0 {
+	if len(p.keys) == 0 && len(p.clientKeys) == 0 {
 		return nil, sdkaccess.NewNotHandledError()
 	}
 	authHeader := r.Header.Get("Authorization")
@@ -98,11 +117,40 @@ func (p *provider) Authenticate(_ context.Context, r *http.Request) (*sdkaccess.
 				},
 			}, nil
 		}
+		if clientKey, ok := p.clientKeys[candidate.value]; ok {
+			return p.authenticateClientKey(r, &clientKey, candidate.source)
+		}
 	}
 
 	return nil, sdkaccess.NewInvalidCredentialError()
 }
 
+// authenticateClientKey applies the identity-level policy of a managed client key. Model
+// allowlists and budgets are enforced later, once the requested model is known.
+func (p *provider) authenticateClientKey(r *http.Request, clientKey *sdkconfig.ClientKey, source string) (*sdkaccess.Result, *sdkaccess.AuthError) {
+	if clientKey.Disabled {
+		return nil, sdkaccess.NewForbiddenError("API key disabled")
+	}
+	if clientKey.Expired(time.Now()) {
+		return nil, sdkaccess.NewForbiddenError("API key expired")
+	}
+	if r.URL != nil && !clientKey.AllowsEndpoint(r.URL.Path) {
+		return nil, sdkaccess.NewForbiddenError("API key not allowed to call this endpoint")
+	}
+	metadata := map[string]string{
+		"source":                        source,
+		sdkaccess.MetadataClientKeyName: clientKey.DisplayName(),
+	}
+	if clientKey.Owner != "" {
+		metadata[sdkaccess.MetadataClientKeyOwner] = clientKey.Owner
+	}
+	return &sdkaccess.Result{
+		Provider:  p.Identifier(),
+		Principal: clientKey.Key,
+		Metadata:  metadata,
+	}, nil
+}
+
 func extractBearerToken(header string) string {
 	if header == "" {
 		return ""
@@ -0,0 +1,101 @@
+// Package mtlsaccess implements the mtls access provider, which authenticates requests by the
+// client certificate verified during the TLS handshake.
+package mtlsaccess
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	sdkaccess "github.com/router-for-me/CLIProxyAPI/v6/sdk/access"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+)
+
+// ProviderName identifies the mtls provider instance.
+const ProviderName = "mtls"
+
+// Register installs the mtls provider when it is enabled in cfg and removes it otherwise.
+func Register(cfg *sdkconfig.SDKConfig) {
+	if cfg == nil || !cfg.MTLS.Enabled {
+		sdkaccess.UnregisterProvider(sdkaccess.AccessProviderTypeMTLS)
+		return
+	}
+	sdkaccess.RegisterProvider(sdkaccess.AccessProviderTypeMTLS, newProvider(cfg.MTLS.Normalized()))
+}
+
+type provider struct {
+	cfg sdkconfig.MTLSConfig
+}
+
+func newProvider(cfg sdkconfig.MTLSConfig) *provider {
+	return &provider{cfg: cfg}
+}
+
+func (p *provider) Identifier() string {
+	return ProviderName
+}
+
+// Authenticate maps the verified client certificate to a principal. Connections without a
+// verified certificate are left to other providers.
+func (p *provider) Authenticate(_ context.Context, r *http.Request) (*sdkaccess.Result, *sdkaccess.AuthError) {
+	if p == nil || r == nil {
+		return nil, sdkaccess.NewNotHandledError()
+	}
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil, sdkaccess.NewNoCredentialsError()
+	}
+	cert := r.TLS.VerifiedChains[0][0]
+	commonName := strings.TrimSpace(cert.Subject.CommonName)
+	sans := certificateSANs(cert)
+
+	principal := ""
+	for index := range p.cfg.Principals {
+		entry := &p.cfg.Principals[index]
+		if entry.Matches(commonName, sans, cert.Subject.Organization) {
+			principal = entry.Principal
+			break
+		}
+	}
+	if principal == "" && p.cfg.AllowUnmapped {
+		principal = commonName
+		if principal == "" && len(sans) > 0 {
+			principal = sans[0]
+		}
+	}
+	if principal == "" {
+		return nil, sdkaccess.NewForbiddenError("client certificate is not mapped to a principal")
+	}
+
+	fingerprint := sha256.Sum256(cert.Raw)
+	metadata := map[string]string{
+		"source":      "client-certificate",
+		"subject":     cert.Subject.String(),
+		"serial":      cert.SerialNumber.String(),
+		"fingerprint": hex.EncodeToString(fingerprint[:]),
+	}
+	if len(sans) > 0 {
+		metadata["san"] = strings.Join(sans, ",")
+	}
+	return &sdkaccess.Result{
+		Provider:  p.Identifier(),
+		Principal: principal,
+		Metadata:  metadata,
+	}, nil
+}
+
+// certificateSANs lists the DNS, URI, email and IP subject alternative names of cert.
+func certificateSANs(cert *x509.Certificate) []string {
+	sans := make([]string, 0, len(cert.DNSNames)+len(cert.URIs)+len(cert.EmailAddresses)+len(cert.IPAddresses))
+	sans = append(sans, cert.DNSNames...)
+	for _, uri := range cert.URIs {
+		sans = append(sans, uri.String())
+	}
+	sans = append(sans, cert.EmailAddresses...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	return sans
+}
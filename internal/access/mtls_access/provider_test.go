@@ -0,0 +1,111 @@
+package mtlsaccess
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	sdkaccess "github.com/router-for-me/CLIProxyAPI/v6/sdk/access"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+)
+
+func clientCertificate(t *testing.T, commonName string, organization string, uris ...string) *x509.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(42),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	if organization != "" {
+		template.Subject.Organization = []string{organization}
+	}
+	for _, raw := range uris {
+		parsed, errParse := url.Parse(raw)
+		if errParse != nil {
+			t.Fatalf("parse uri: %v", errParse)
+		}
+		template.URIs = append(template.URIs, parsed)
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("parse certificate: %v", err)
+	}
+	return cert
+}
+
+func requestWithCertificate(cert *x509.Certificate) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages", nil)
+	if cert != nil {
+		req.TLS = &tls.ConnectionState{
+			PeerCertificates: []*x509.Certificate{cert},
+			VerifiedChains:   [][]*x509.Certificate{{cert}},
+		}
+	}
+	return req
+}
+
+func TestProvider_MapsVerifiedCertificates(t *testing.T) {
+	p := newProvider(sdkconfig.MTLSConfig{
+		Enabled: true,
+		Principals: []sdkconfig.MTLSPrincipal{
+			{Principal: "billing", SAN: "spiffe://corp/ns/billing/*"},
+			{Principal: "platform", CommonName: "*.platform.internal", Organization: "Corp"},
+		},
+	}.Normalized())
+
+	req := requestWithCertificate(clientCertificate(t, "worker", "", "spiffe://corp/ns/billing/sa/api"))
+	result, authErr := p.Authenticate(req.Context(), req)
+	if authErr != nil {
+		t.Fatalf("Authenticate() error = %v", authErr)
+	}
+	if result.Principal != "billing" || result.Metadata["san"] != "spiffe://corp/ns/billing/sa/api" {
+		t.Fatalf("result = %+v, want billing principal with san metadata", result)
+	}
+
+	req = requestWithCertificate(clientCertificate(t, "ci.platform.internal", "Corp"))
+	if result, authErr = p.Authenticate(req.Context(), req); authErr != nil || result.Principal != "platform" {
+		t.Fatalf("Authenticate() = %+v, %v; want platform", result, authErr)
+	}
+
+	req = requestWithCertificate(clientCertificate(t, "ci.platform.internal", "Other"))
+	if _, authErr = p.Authenticate(req.Context(), req); !sdkaccess.IsAuthErrorCode(authErr, sdkaccess.AuthErrorCodeForbidden) {
+		t.Fatalf("unmapped certificate error = %v, want forbidden", authErr)
+	}
+
+	req = requestWithCertificate(nil)
+	if _, authErr = p.Authenticate(req.Context(), req); !sdkaccess.IsAuthErrorCode(authErr, sdkaccess.AuthErrorCodeNoCredentials) {
+		t.Fatalf("request without certificate error = %v, want no credentials", authErr)
+	}
+}
+
+func TestProvider_AllowUnmappedUsesCommonName(t *testing.T) {
+	p := newProvider(sdkconfig.MTLSConfig{Enabled: true, AllowUnmapped: true}.Normalized())
+
+	req := requestWithCertificate(clientCertificate(t, "batch-runner", ""))
+	result, authErr := p.Authenticate(req.Context(), req)
+	if authErr != nil {
+		t.Fatalf("Authenticate() error = %v", authErr)
+	}
+	if result.Principal != "batch-runner" || result.Provider != ProviderName {
+		t.Fatalf("result = %+v, want batch-runner from %s", result, ProviderName)
+	}
+}
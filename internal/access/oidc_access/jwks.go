@@ -0,0 +1,331 @@
+package oidcaccess
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// jwksMinRefreshInterval bounds how often an unknown key ID may force a refetch.
+	jwksMinRefreshInterval = time.Minute
+	// jwksMaxBodySize caps discovery and key set responses.
+	jwksMaxBodySize = 1 << 20
+	// jwksRefreshTimeout bounds a refresh, which runs detached from the requests waiting on it.
+	jwksRefreshTimeout = 15 * time.Second
+)
+
+// jsonWebKey is the subset of RFC 7517 fields needed for signature verification.
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type verificationKey struct {
+	kid string
+	alg string
+	key crypto.PublicKey
+}
+
+// keySet caches verification keys from a JWKS file or URL and refreshes them periodically.
+// Refreshes run outside mu and are shared by every request that needs one.
+type keySet struct {
+	file         string
+	url          string
+	issuer       string
+	client       *http.Client
+	refreshEvery time.Duration
+	refreshGroup singleflight.Group
+
+	mu          sync.Mutex
+	keys        []verificationKey
+	fetchedAt   time.Time
+	refreshing  bool
+	fileModTime time.Time
+	resolvedURL string
+}
+
+func newKeySet(file, url, issuer string, refreshEvery time.Duration) *keySet {
+	return &keySet{
+		file:         file,
+		url:          url,
+		issuer:       issuer,
+		client:       &http.Client{Timeout: 10 * time.Second},
+		refreshEvery: refreshEvery,
+	}
+}
+
+// lookup returns the keys that may have signed a token with kid, refreshing the set when it
+// is stale or does not know kid yet. A caller whose ctx ends while waiting on a refresh falls
+// back to the cached keys.
+func (s *keySet) lookup(ctx context.Context, kid string) ([]verificationKey, error) {
+	if s.needsRefresh(kid) {
+		result := s.refreshGroup.DoChan("refresh", func() (any, error) {
+			refreshCtx, cancel := context.WithTimeout(context.Background(), jwksRefreshTimeout)
+			defer cancel()
+			return nil, s.refresh(refreshCtx)
+		})
+		var errRefresh error
+		select {
+		case res := <-result:
+			errRefresh = res.Err
+		case <-ctx.Done():
+			errRefresh = ctx.Err()
+		}
+		if errRefresh != nil {
+			s.mu.Lock()
+			cached := len(s.keys) > 0
+			s.mu.Unlock()
+			if !cached {
+				return nil, errRefresh
+			}
+			log.Warnf("oidc-jwt: refreshing JWKS failed, keeping cached keys: %v", errRefresh)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if kid == "" {
+		return s.keys, nil
+	}
+	for _, key := range s.keys {
+		if key.kid == kid {
+			return []verificationKey{key}, nil
+		}
+	}
+	return nil, fmt.Errorf("no key with kid %q", kid)
+}
+
+// needsRefresh reports whether the caller should wait for a refresh: one is already in flight,
+// the cached set is stale, the key file changed, or kid is unknown and the last refresh is old
+// enough to try again. Starting a refresh records the attempt so a failing source is not
+// hammered on every request.
+func (s *keySet) needsRefresh(kid string) bool {
+	var fileModTime time.Time
+	if s.file != "" {
+		if info, errStat := os.Stat(s.file); errStat == nil {
+			fileModTime = info.ModTime()
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.refreshing {
+		return true
+	}
+	now := time.Now()
+	stale := s.fetchedAt.IsZero() || now.Sub(s.fetchedAt) >= s.refreshEvery
+	if !fileModTime.IsZero() && !fileModTime.Equal(s.fileModTime) {
+		stale = true
+	}
+	if !stale && kid != "" && !s.hasKidLocked(kid) && now.Sub(s.fetchedAt) >= jwksMinRefreshInterval {
+		stale = true
+	}
+	if stale {
+		s.refreshing = true
+		s.fetchedAt = now
+	}
+	return stale
+}
+
+func (s *keySet) hasKidLocked(kid string) bool {
+	for _, key := range s.keys {
+		if key.kid == kid {
+			return true
+		}
+	}
+	return false
+}
+
+// refresh reloads the key set without holding mu and swaps the new keys in when they parse.
+func (s *keySet) refresh(ctx context.Context) error {
+	defer func() {
+		s.mu.Lock()
+		s.refreshing = false
+		s.mu.Unlock()
+	}()
+
+	var (
+		data        []byte
+		fileModTime time.Time
+		err         error
+	)
+	if s.file != "" {
+		info, errStat := os.Stat(s.file)
+		if errStat != nil {
+			return fmt.Errorf("stat JWKS file: %w", errStat)
+		}
+		data, err = os.ReadFile(s.file)
+		if err != nil {
+			return fmt.Errorf("read JWKS file: %w", err)
+		}
+		fileModTime = info.ModTime()
+	} else {
+		url, errURL := s.jwksURL(ctx)
+		if errURL != nil {
+			return errURL
+		}
+		data, err = s.fetch(ctx, url)
+		if err != nil {
+			return fmt.Errorf("fetch JWKS: %w", err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file != "" {
+		s.fileModTime = fileModTime
+	}
+	keys, err := parseKeySet(data)
+	if err != nil {
+		return err
+	}
+	s.keys = keys
+	return nil
+}
+
+// jwksURL returns the configured JWKS URL or discovers it from the issuer.
+func (s *keySet) jwksURL(ctx context.Context) (string, error) {
+	if s.url != "" {
+		return s.url, nil
+	}
+	s.mu.Lock()
+	resolved := s.resolvedURL
+	s.mu.Unlock()
+	if resolved != "" {
+		return resolved, nil
+	}
+	if s.issuer == "" {
+		return "", fmt.Errorf("no JWKS source configured")
+	}
+	data, err := s.fetch(ctx, strings.TrimRight(s.issuer, "/")+"/.well-known/openid-configuration")
+	if err != nil {
+		return "", fmt.Errorf("fetch OIDC discovery document: %w", err)
+	}
+	var discovery struct {
+		JWKSURI string `json:"jwks_uri"`
+	}
+	if err = json.Unmarshal(data, &discovery); err != nil {
+		return "", fmt.Errorf("parse OIDC discovery document: %w", err)
+	}
+	if strings.TrimSpace(discovery.JWKSURI) == "" {
+		return "", fmt.Errorf("OIDC discovery document has no jwks_uri")
+	}
+	resolved = strings.TrimSpace(discovery.JWKSURI)
+	s.mu.Lock()
+	s.resolvedURL = resolved
+	s.mu.Unlock()
+	return resolved, nil
+}
+
+func (s *keySet) fetch(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if errClose := resp.Body.Close(); errClose != nil {
+			log.Errorf("oidc-jwt: close response body error: %v", errClose)
+		}
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d from %s", resp.StatusCode, url)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, jwksMaxBodySize))
+}
+
+// parseKeySet decodes a JWKS document into verification keys, skipping keys it cannot use.
+func parseKeySet(data []byte) ([]verificationKey, error) {
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("parse JWKS: %w", err)
+	}
+	keys := make([]verificationKey, 0, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		publicKey, err := jwk.publicKey()
+		if err != nil {
+			log.Debugf("oidc-jwt: skipping JWKS key %q: %v", jwk.Kid, err)
+			continue
+		}
+		keys = append(keys, verificationKey{kid: jwk.Kid, alg: jwk.Alg, key: publicKey})
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("JWKS contains no usable signing keys")
+	}
+	return keys, nil
+}
+
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, errN := decodeBigInt(k.N)
+		e, errE := decodeBigInt(k.E)
+		if errN != nil || errE != nil || !e.IsInt64() {
+			return nil, fmt.Errorf("invalid RSA key parameters")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, errX := decodeBigInt(k.X)
+		y, errY := decodeBigInt(k.Y)
+		if errX != nil || errY != nil || !curve.IsOnCurve(x, y) {
+			return nil, fmt.Errorf("invalid EC key parameters")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func decodeBigInt(raw string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(raw, "="))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("empty value")
+	}
+	return new(big.Int).SetBytes(data), nil
+}
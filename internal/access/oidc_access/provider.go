@@ -0,0 +1,333 @@
+// Package oidcaccess implements the oidc-jwt access provider, which authenticates requests
+// carrying bearer JWTs issued by an OpenID Connect identity provider.
+package oidcaccess
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	_ "crypto/sha256" // register SHA-256 for crypto.Hash
+	_ "crypto/sha512" // register SHA-384/512 for crypto.Hash
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	sdkaccess "github.com/router-for-me/CLIProxyAPI/v6/sdk/access"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// ProviderName identifies the oidc-jwt provider instance.
+const ProviderName = "oidc-jwt"
+
+var (
+	currentMu     sync.Mutex
+	currentCfg    sdkconfig.OIDCJWTConfig
+	currentLoaded *provider
+)
+
+// Register installs the oidc-jwt provider when it is enabled in cfg and removes it otherwise.
+// The provider, and with it the cached key set, is reused while its configuration is unchanged.
+func Register(cfg *sdkconfig.SDKConfig) {
+	currentMu.Lock()
+	defer currentMu.Unlock()
+
+	if cfg == nil || !cfg.OIDCJWT.Enabled {
+		currentLoaded = nil
+		sdkaccess.UnregisterProvider(sdkaccess.AccessProviderTypeOIDCJWT)
+		return
+	}
+	normalized := cfg.OIDCJWT.Normalized()
+	if normalized.JWKSFile == "" && normalized.JWKSURL == "" && normalized.Issuer == "" {
+		log.Warn("oidc-jwt: enabled without issuer, jwks-url or jwks-file; provider disabled")
+		currentLoaded = nil
+		sdkaccess.UnregisterProvider(sdkaccess.AccessProviderTypeOIDCJWT)
+		return
+	}
+	if len(normalized.Audiences) == 0 && !normalized.AllowAnyAudience {
+		log.Warn("oidc-jwt: enabled without audiences; set audiences, or allow-any-audience to accept tokens minted for any client; provider disabled")
+		currentLoaded = nil
+		sdkaccess.UnregisterProvider(sdkaccess.AccessProviderTypeOIDCJWT)
+		return
+	}
+	if currentLoaded == nil || !reflect.DeepEqual(currentCfg, normalized) {
+		currentCfg = normalized
+		currentLoaded = newProvider(normalized)
+	}
+	sdkaccess.RegisterProvider(sdkaccess.AccessProviderTypeOIDCJWT, currentLoaded)
+}
+
+type provider struct {
+	cfg           sdkconfig.OIDCJWTConfig
+	keys          *keySet
+	skew          time.Duration
+	audiences     map[string]struct{}
+	allowedGroups map[string]struct{}
+	now           func() time.Time
+}
+
+func newProvider(cfg sdkconfig.OIDCJWTConfig) *provider {
+	p := &provider{
+		cfg:  cfg,
+		keys: newKeySet(cfg.JWKSFile, cfg.JWKSURL, cfg.Issuer, time.Duration(cfg.JWKSRefreshSeconds)*time.Second),
+		skew: time.Duration(cfg.ClockSkewSeconds) * time.Second,
+		now:  time.Now,
+	}
+	if len(cfg.Audiences) > 0 {
+		p.audiences = make(map[string]struct{}, len(cfg.Audiences))
+		for _, audience := range cfg.Audiences {
+			p.audiences[audience] = struct{}{}
+		}
+	}
+	if len(cfg.AllowedGroups) > 0 {
+		p.allowedGroups = make(map[string]struct{}, len(cfg.AllowedGroups))
+		for _, group := range cfg.AllowedGroups {
+			p.allowedGroups[group] = struct{}{}
+		}
+	}
+	return p
+}
+
+func (p *provider) Identifier() string {
+	return ProviderName
+}
+
+// Authenticate validates a bearer JWT. Requests without one are left to other providers.
+func (p *provider) Authenticate(ctx context.Context, r *http.Request) (*sdkaccess.Result, *sdkaccess.AuthError) {
+	if p == nil || r == nil {
+		return nil, sdkaccess.NewNotHandledError()
+	}
+	token := bearerToken(r.Header.Get("Authorization"))
+	if token == "" {
+		return nil, sdkaccess.NewNoCredentialsError()
+	}
+	if strings.Count(token, ".") != 2 {
+		return nil, sdkaccess.NewNotHandledError()
+	}
+
+	claims, err := p.verify(ctx, token)
+	if err != nil {
+		log.Debugf("oidc-jwt: rejecting token: %v", err)
+		return nil, sdkaccess.NewInvalidCredentialError()
+	}
+
+	principal := claimString(claims[p.cfg.PrincipalClaim])
+	if principal == "" {
+		log.Debugf("oidc-jwt: token has no %q claim", p.cfg.PrincipalClaim)
+		return nil, sdkaccess.NewInvalidCredentialError()
+	}
+	groups := claimStrings(claims[p.cfg.GroupsClaim])
+	if len(p.allowedGroups) > 0 && !p.inAllowedGroup(groups) {
+		return nil, sdkaccess.NewForbiddenError("token subject is not in an allowed group")
+	}
+
+	metadata := map[string]string{
+		"source":  "authorization",
+		"subject": claimString(claims["sub"]),
+		"issuer":  claimString(claims["iss"]),
+	}
+	if len(groups) > 0 {
+		metadata["groups"] = strings.Join(groups, ",")
+	}
+	for _, name := range p.cfg.MetadataClaims {
+		if value := claimString(claims[name]); value != "" {
+			metadata[name] = value
+		} else if values := claimStrings(claims[name]); len(values) > 0 {
+			metadata[name] = strings.Join(values, ",")
+		}
+	}
+	return &sdkaccess.Result{
+		Provider:  p.Identifier(),
+		Principal: principal,
+		Metadata:  metadata,
+	}, nil
+}
+
+func (p *provider) inAllowedGroup(groups []string) bool {
+	for _, group := range groups {
+		if _, ok := p.allowedGroups[group]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// verify checks the token signature and its registered claims and returns the claim set.
+func (p *provider) verify(ctx context.Context, token string) (map[string]any, error) {
+	parts := strings.Split(token, ".")
+	headerBytes, errHeader := base64.RawURLEncoding.DecodeString(parts[0])
+	payloadBytes, errPayload := base64.RawURLEncoding.DecodeString(parts[1])
+	signature, errSignature := base64.RawURLEncoding.DecodeString(parts[2])
+	if errHeader != nil || errPayload != nil || errSignature != nil {
+		return nil, fmt.Errorf("malformed token encoding")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := json.Unmarshal(headerBytes, &header); err != nil {
+		return nil, fmt.Errorf("malformed token header: %w", err)
+	}
+	hashFunc, ok := signatureHash(header.Alg)
+	if !ok {
+		return nil, fmt.Errorf("unsupported signing algorithm %q", header.Alg)
+	}
+
+	keys, err := p.keys.lookup(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	digest := hashFunc.New()
+	digest.Write([]byte(parts[0] + "." + parts[1]))
+	sum := digest.Sum(nil)
+	verified := false
+	for _, key := range keys {
+		if key.alg != "" && key.alg != header.Alg {
+			continue
+		}
+		if verifySignature(header.Alg, hashFunc, key.key, sum, signature) {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, fmt.Errorf("signature verification failed")
+	}
+
+	var claims map[string]any
+	decoder := json.NewDecoder(strings.NewReader(string(payloadBytes)))
+	decoder.UseNumber()
+	if err = decoder.Decode(&claims); err != nil {
+		return nil, fmt.Errorf("malformed token claims: %w", err)
+	}
+	if err = p.validateClaims(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func (p *provider) validateClaims(claims map[string]any) error {
+	now := p.now()
+	expiresAt, ok := claimTime(claims["exp"])
+	if !ok {
+		return fmt.Errorf("token has no exp claim")
+	}
+	if !now.Before(expiresAt.Add(p.skew)) {
+		return fmt.Errorf("token expired at %s", expiresAt.UTC().Format(time.RFC3339))
+	}
+	if notBefore, ok := claimTime(claims["nbf"]); ok && now.Add(p.skew).Before(notBefore) {
+		return fmt.Errorf("token not valid before %s", notBefore.UTC().Format(time.RFC3339))
+	}
+	if issuedAt, ok := claimTime(claims["iat"]); ok && now.Add(p.skew).Before(issuedAt) {
+		return fmt.Errorf("token issued in the future")
+	}
+	if p.cfg.Issuer != "" && strings.TrimRight(claimString(claims["iss"]), "/") != strings.TrimRight(p.cfg.Issuer, "/") {
+		return fmt.Errorf("unexpected issuer %q", claimString(claims["iss"]))
+	}
+	if !p.cfg.AllowAnyAudience {
+		matched := false
+		for _, audience := range claimStrings(claims["aud"]) {
+			if _, ok := p.audiences[audience]; ok {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fmt.Errorf("token audience not accepted")
+		}
+	}
+	return nil
+}
+
+func signatureHash(alg string) (crypto.Hash, bool) {
+	switch alg {
+	case "RS256", "PS256", "ES256":
+		return crypto.SHA256, true
+	case "RS384", "PS384", "ES384":
+		return crypto.SHA384, true
+	case "RS512", "PS512", "ES512":
+		return crypto.SHA512, true
+	default:
+		return 0, false
+	}
+}
+
+func verifySignature(alg string, hashFunc crypto.Hash, key crypto.PublicKey, sum, signature []byte) bool {
+	switch publicKey := key.(type) {
+	case *rsa.PublicKey:
+		switch alg[:2] {
+		case "RS":
+			return rsa.VerifyPKCS1v15(publicKey, hashFunc, sum, signature) == nil
+		case "PS":
+			return rsa.VerifyPSS(publicKey, hashFunc, sum, signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}) == nil
+		}
+	case *ecdsa.PublicKey:
+		if alg[:2] != "ES" {
+			return false
+		}
+		size := (publicKey.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return false
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		return ecdsa.Verify(publicKey, sum, r, s)
+	}
+	return false
+}
+
+func bearerToken(header string) string {
+	scheme, token, ok := strings.Cut(strings.TrimSpace(header), " ")
+	if !ok || !strings.EqualFold(scheme, "bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+func claimString(value any) string {
+	switch typed := value.(type) {
+	case string:
+		return strings.TrimSpace(typed)
+	case json.Number:
+		return typed.String()
+	default:
+		return ""
+	}
+}
+
+// claimStrings reads a claim that may be a string array or a space- or comma-separated string.
+func claimStrings(value any) []string {
+	switch typed := value.(type) {
+	case string:
+		return strings.FieldsFunc(typed, func(r rune) bool { return r == ' ' || r == ',' })
+	case []any:
+		out := make([]string, 0, len(typed))
+		for _, item := range typed {
+			if text := claimString(item); text != "" {
+				out = append(out, text)
+			}
+		}
+		return out
+	default:
+		return nil
+	}
+}
+
+func claimTime(value any) (time.Time, bool) {
+	number, ok := value.(json.Number)
+	if !ok {
+		return time.Time{}, false
+	}
+	seconds, err := number.Float64()
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(seconds), 0), true
+}
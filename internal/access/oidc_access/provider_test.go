@@ -0,0 +1,287 @@
+package oidcaccess
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	sdkaccess "github.com/router-for-me/CLIProxyAPI/v6/sdk/access"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+)
+
+func encodeSegment(t *testing.T, value any) string {
+	t.Helper()
+	raw, err := json.Marshal(value)
+	if err != nil {
+		t.Fatalf("marshal segment: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func signRS256(t *testing.T, key *rsa.PrivateKey, kid string, claims map[string]any) string {
+	t.Helper()
+	signingInput := encodeSegment(t, map[string]string{"alg": "RS256", "kid": kid, "typ": "JWT"}) + "." + encodeSegment(t, claims)
+	sum := crypto.SHA256.New()
+	sum.Write([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum.Sum(nil))
+	if err != nil {
+		t.Fatalf("sign token: %v", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func signES256(t *testing.T, key *ecdsa.PrivateKey, kid string, claims map[string]any) string {
+	t.Helper()
+	signingInput := encodeSegment(t, map[string]string{"alg": "ES256", "kid": kid}) + "." + encodeSegment(t, claims)
+	sum := crypto.SHA256.New()
+	sum.Write([]byte(signingInput))
+	r, s, err := ecdsa.Sign(rand.Reader, key, sum.Sum(nil))
+	if err != nil {
+		t.Fatalf("sign token: %v", err)
+	}
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func rsaJWKS(t *testing.T, kid string, key *rsa.PublicKey) []byte {
+	t.Helper()
+	raw, err := json.Marshal(map[string]any{"keys": []map[string]string{{
+		"kty": "RSA",
+		"kid": kid,
+		"use": "sig",
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}}})
+	if err != nil {
+		t.Fatalf("marshal jwks: %v", err)
+	}
+	return raw
+}
+
+func authenticateBearer(p *provider, token string) (*sdkaccess.Result, *sdkaccess.AuthError) {
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	return p.Authenticate(req.Context(), req)
+}
+
+func TestProvider_ValidatesTokensAgainstJWKSFile(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	jwksPath := filepath.Join(t.TempDir(), "jwks.json")
+	if err = os.WriteFile(jwksPath, rsaJWKS(t, "k1", &key.PublicKey), 0o600); err != nil {
+		t.Fatalf("write jwks: %v", err)
+	}
+	p := newProvider(sdkconfig.OIDCJWTConfig{
+		Enabled:        true,
+		Issuer:         "https://sso.example.com",
+		Audiences:      []string{"cli-proxy"},
+		JWKSFile:       jwksPath,
+		AllowedGroups:  []string{"ai-users"},
+		MetadataClaims: []string{"email"},
+	}.Normalized())
+
+	now := time.Now()
+	claims := map[string]any{
+		"iss":    "https://sso.example.com",
+		"aud":    []string{"other", "cli-proxy"},
+		"sub":    "user-123",
+		"email":  "dev@example.com",
+		"groups": []string{"ai-users", "staff"},
+		"exp":    now.Add(time.Hour).Unix(),
+		"iat":    now.Unix(),
+	}
+
+	result, authErr := authenticateBearer(p, signRS256(t, key, "k1", claims))
+	if authErr != nil {
+		t.Fatalf("Authenticate() error = %v", authErr)
+	}
+	if result.Principal != "user-123" || result.Provider != ProviderName {
+		t.Fatalf("result = %+v, want principal user-123 from %s", result, ProviderName)
+	}
+	if result.Metadata["groups"] != "ai-users,staff" || result.Metadata["email"] != "dev@example.com" {
+		t.Fatalf("metadata = %v", result.Metadata)
+	}
+
+	cases := []struct {
+		name   string
+		mutate func(map[string]any)
+		code   sdkaccess.AuthErrorCode
+	}{
+		{"expired", func(c map[string]any) { c["exp"] = now.Add(-time.Hour).Unix() }, sdkaccess.AuthErrorCodeInvalidCredential},
+		{"wrong audience", func(c map[string]any) { c["aud"] = "someone-else" }, sdkaccess.AuthErrorCodeInvalidCredential},
+		{"wrong issuer", func(c map[string]any) { c["iss"] = "https://evil.example.com" }, sdkaccess.AuthErrorCodeInvalidCredential},
+		{"missing exp", func(c map[string]any) { delete(c, "exp") }, sdkaccess.AuthErrorCodeInvalidCredential},
+		{"group not allowed", func(c map[string]any) { c["groups"] = []string{"staff"} }, sdkaccess.AuthErrorCodeForbidden},
+	}
+	for _, tc := range cases {
+		mutated := make(map[string]any, len(claims))
+		for k, v := range claims {
+			mutated[k] = v
+		}
+		tc.mutate(mutated)
+		if _, authErr = authenticateBearer(p, signRS256(t, key, "k1", mutated)); !sdkaccess.IsAuthErrorCode(authErr, tc.code) {
+			t.Errorf("%s: Authenticate() error = %v, want %s", tc.name, authErr, tc.code)
+		}
+	}
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	if _, authErr = authenticateBearer(p, signRS256(t, otherKey, "k1", claims)); !sdkaccess.IsAuthErrorCode(authErr, sdkaccess.AuthErrorCodeInvalidCredential) {
+		t.Fatalf("forged signature error = %v, want invalid credential", authErr)
+	}
+	if _, authErr = authenticateBearer(p, "sk-plain-api-key"); !sdkaccess.IsAuthErrorCode(authErr, sdkaccess.AuthErrorCodeNotHandled) {
+		t.Fatalf("plain api key error = %v, want not handled", authErr)
+	}
+}
+
+func TestProvider_DiscoversAndRotatesRemoteKeys(t *testing.T) {
+	first, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	second, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	ecJWK := func(kid string, key *ecdsa.PublicKey) map[string]string {
+		return map[string]string{
+			"kty": "EC",
+			"kid": kid,
+			"crv": "P-256",
+			"x":   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+			"y":   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32))),
+		}
+	}
+	keys := []map[string]string{ecJWK("a", &first.PublicKey)}
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			_ = json.NewEncoder(w).Encode(map[string]string{"issuer": server.URL, "jwks_uri": server.URL + "/keys"})
+		case "/keys":
+			_ = json.NewEncoder(w).Encode(map[string]any{"keys": keys})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	p := newProvider(sdkconfig.OIDCJWTConfig{Enabled: true, Issuer: server.URL, AllowAnyAudience: true}.Normalized())
+	claims := map[string]any{"iss": server.URL, "sub": "svc", "exp": time.Now().Add(time.Hour).Unix()}
+
+	if _, authErr := authenticateBearer(p, signES256(t, first, "a", claims)); authErr != nil {
+		t.Fatalf("Authenticate() with discovered key error = %v", authErr)
+	}
+
+	keys = []map[string]string{ecJWK("b", &second.PublicKey)}
+	p.keys.mu.Lock()
+	p.keys.fetchedAt = time.Now().Add(-2 * jwksMinRefreshInterval)
+	p.keys.mu.Unlock()
+	if _, authErr := authenticateBearer(p, signES256(t, second, "b", claims)); authErr != nil {
+		t.Fatalf("Authenticate() with rotated key error = %v", authErr)
+	}
+}
+
+func TestKeySet_SharesRefreshAndDetachesItFromCallers(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	jwks := rsaJWKS(t, "k1", &key.PublicKey)
+	release := make(chan struct{})
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		<-release
+		_, _ = w.Write(jwks)
+	}))
+	defer server.Close()
+
+	set := newKeySet("", server.URL, "", time.Hour)
+
+	// A caller that gives up while the key set is empty gets its own error, and the refresh
+	// keeps running for everyone else.
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, errLookup := set.lookup(canceled, "k1"); errLookup == nil {
+		t.Fatalf("lookup() with canceled ctx error = nil")
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 4)
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errLookup := set.lookup(context.Background(), "k1")
+			errs <- errLookup
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	// The lock is free while the fetch is in flight.
+	set.mu.Lock()
+	set.mu.Unlock()
+	close(release)
+	wg.Wait()
+	close(errs)
+	for errLookup := range errs {
+		if errLookup != nil {
+			t.Fatalf("lookup() error = %v", errLookup)
+		}
+	}
+	if got := fetches.Load(); got != 1 {
+		t.Fatalf("JWKS fetched %d times, want 1 shared fetch", got)
+	}
+}
+
+func TestRegister_RequiresAudiencesUnlessAnyAudienceAllowed(t *testing.T) {
+	registered := func() bool {
+		for _, p := range sdkaccess.RegisteredProviders() {
+			if p.Identifier() == ProviderName {
+				return true
+			}
+		}
+		return false
+	}
+	t.Cleanup(func() { Register(nil) })
+
+	cfg := &sdkconfig.SDKConfig{OIDCJWT: sdkconfig.OIDCJWTConfig{Enabled: true, Issuer: "https://sso.example.com"}}
+	Register(cfg)
+	if registered() {
+		t.Fatal("provider registered without audiences")
+	}
+	cfg.OIDCJWT.AllowAnyAudience = true
+	Register(cfg)
+	if !registered() {
+		t.Fatal("provider not registered with allow-any-audience")
+	}
+
+	claims := map[string]any{"iss": "https://sso.example.com", "aud": "anyone", "exp": json.Number("4102444800")}
+	if err := newProvider(sdkconfig.OIDCJWTConfig{Issuer: "https://sso.example.com"}.Normalized()).validateClaims(claims); err == nil {
+		t.Fatal("validateClaims() accepted a token without configured audiences")
+	}
+	if err := newProvider(cfg.OIDCJWT.Normalized()).validateClaims(claims); err != nil {
+		t.Fatalf("validateClaims() with allow-any-audience error = %v", err)
+	}
+}
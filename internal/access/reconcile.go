@@ -7,6 +7,8 @@ import (
 	"strings"
 
 	configaccess "github.com/router-for-me/CLIProxyAPI/v6/internal/access/config_access"
+	mtlsaccess "github.com/router-for-me/CLIProxyAPI/v6/internal/access/mtls_access"
+	oidcaccess "github.com/router-for-me/CLIProxyAPI/v6/internal/access/oidc_access"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
 	sdkaccess "github.com/router-for-me/CLIProxyAPI/v6/sdk/access"
 	log "github.com/sirupsen/logrus"
@@ -86,6 +88,8 @@ func ApplyAccessProviders(manager *sdkaccess.Manager, oldCfg, newCfg *config.Con
 
 	existing := manager.Providers()
 	configaccess.Register(&newCfg.SDKConfig)
+	oidcaccess.Register(&newCfg.SDKConfig)
+	mtlsaccess.Register(&newCfg.SDKConfig)
 	providers, added, updated, removed, err := ReconcileProviders(oldCfg, newCfg, existing)
 	if err != nil {
 		log.Errorf("failed to reconcile request auth providers: %v", err)
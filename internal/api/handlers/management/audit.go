@@ -0,0 +1,180 @@
+package management
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultAuditQueryLimit = 100
+	maxAuditQueryLimit     = 1000
+)
+
+// secretQueryParams lists query parameters whose values are masked in audit entries.
+var secretQueryParams = map[string]struct{}{
+	"value":   {},
+	"key":     {},
+	"api-key": {},
+	"token":   {},
+}
+
+// auditedRequest reports whether a management call is written to the audit log: every
+// mutation plus reads that need more than the viewer role, such as token downloads.
+func auditedRequest(method, route string) bool {
+	if method != http.MethodGet && method != http.MethodHead && method != http.MethodOptions {
+		return true
+	}
+	return RequiredRole(method, route) != config.ManagementRoleViewer
+}
+
+// auditLog returns the audit log, creating it below the log directory on first use.
+func (h *Handler) auditLog() *logging.AuditLog {
+	h.auditMu.Lock()
+	defer h.auditMu.Unlock()
+	dir := filepath.Join(h.logDirectory(), logging.AuditLogDirName)
+	if h.audit == nil || h.audit.Dir() != dir {
+		if h.audit != nil {
+			_ = h.audit.Close()
+		}
+		h.audit = logging.NewAuditLog(dir)
+	}
+	return h.audit
+}
+
+// AuditMiddleware records management mutations with the caller, route and a redacted diff
+// of the config and auth file state. It must run after Middleware. Audited calls run one at
+// a time so a concurrent mutation never shows up in another call's diff.
+func (h *Handler) AuditMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := strings.TrimPrefix(c.FullPath(), managementRoutePrefix)
+		if (h.cfg != nil && h.cfg.RemoteManagement.DisableAuditLog) || !auditedRequest(c.Request.Method, route) {
+			c.Next()
+			return
+		}
+		before, after := h.runAuditedCall(c)
+
+		entry := logging.AuditEntry{
+			Timestamp:  time.Now().UTC(),
+			RemoteAddr: c.ClientIP(),
+			Principal:  c.GetString(managementKeyNameContextKey),
+			Role:       c.GetString(managementRoleContextKey),
+			Method:     c.Request.Method,
+			Route:      route,
+			Query:      redactAuditQuery(c.Request.URL.Query()),
+			Status:     c.Writer.Status(),
+			Changes:    logging.DiffAuditValues(before, after),
+		}
+		if errAppend := h.auditLog().Append(entry); errAppend != nil {
+			log.Errorf("management audit: failed to write entry for %s %s: %v", entry.Method, entry.Route, errAppend)
+		}
+	}
+}
+
+// runAuditedCall runs the remaining handlers between two state snapshots while holding the
+// audited call lock, which is released even when a handler panics.
+func (h *Handler) runAuditedCall(c *gin.Context) (before, after any) {
+	h.auditedCallMu.Lock()
+	defer h.auditedCallMu.Unlock()
+	before = h.auditSnapshot()
+	c.Next()
+	return before, h.auditSnapshot()
+}
+
+// auditSnapshot captures the redacted state a management call may change.
+func (h *Handler) auditSnapshot() any {
+	state := gin.H{}
+	if cfg := h.cfg; cfg != nil {
+		state["config"] = cfg
+		keys := make([]gin.H, 0, len(cfg.RemoteManagement.Keys))
+		for _, entry := range cfg.RemoteManagement.Keys {
+			keys = append(keys, gin.H{"name": entry.Name, "role": entry.Role})
+		}
+		state["remote-management"] = gin.H{
+			"allow-remote":      cfg.RemoteManagement.AllowRemote,
+			"secret-key-set":    cfg.RemoteManagement.SecretKey != "",
+			"keys":              keys,
+			"disable-audit-log": cfg.RemoteManagement.DisableAuditLog,
+		}
+	}
+	if h.authManager != nil {
+		auths := gin.H{}
+		for _, auth := range h.authManager.List() {
+			auths[auth.ID] = gin.H{
+				"provider":  auth.Provider,
+				"label":     auth.Label,
+				"prefix":    auth.Prefix,
+				"proxy_url": auth.ProxyURL,
+				"disabled":  auth.Disabled,
+			}
+		}
+		state["auth-files"] = auths
+	}
+	return redactSecrets(state)
+}
+
+func redactAuditQuery(values url.Values) string {
+	if len(values) == 0 {
+		return ""
+	}
+	redacted := make(url.Values, len(values))
+	for name, list := range values {
+		if _, secret := secretQueryParams[strings.ToLower(name)]; secret {
+			masked := make([]string, 0, len(list))
+			for _, value := range list {
+				masked = append(masked, util.HideAPIKey(value))
+			}
+			list = masked
+		}
+		redacted[name] = list
+	}
+	return redacted.Encode()
+}
+
+// GetAuditLog returns audit entries, newest first. Supported query parameters are since and
+// until (RFC 3339), principal, method, route (substring) and limit.
+func (h *Handler) GetAuditLog(c *gin.Context) {
+	filter := logging.AuditFilter{
+		Principal: strings.TrimSpace(c.Query("principal")),
+		Method:    strings.TrimSpace(c.Query("method")),
+		Route:     strings.TrimSpace(c.Query("route")),
+		Limit:     defaultAuditQueryLimit,
+	}
+	for name, target := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+		raw := strings.TrimSpace(c.Query(name))
+		if raw == "" {
+			continue
+		}
+		parsed, errParse := time.Parse(time.RFC3339, raw)
+		if errParse != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid %s, want RFC 3339 timestamp", name)})
+			return
+		}
+		*target = parsed
+	}
+	if raw := strings.TrimSpace(c.Query("limit")); raw != "" {
+		limit, errLimit := strconv.Atoi(raw)
+		if errLimit != nil || limit <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return
+		}
+		filter.Limit = min(limit, maxAuditQueryLimit)
+	}
+
+	entries, errQuery := h.auditLog().Query(filter)
+	if errQuery != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to read audit log: %v", errQuery)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"entries": entries, "count": len(entries)})
+}
@@ -0,0 +1,131 @@
+package management
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
+)
+
+func TestAuditMiddleware_RecordsRedactedMutations(t *testing.T) {
+	t.Setenv("MANAGEMENT_PASSWORD", "")
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{}
+	cfg.RemoteManagement.AllowRemote = true
+	cfg.RemoteManagement.Keys = []config.ManagementKey{
+		{Name: "ops-admin", Key: "admin-key", Role: config.ManagementRoleAdmin},
+		{Name: "dashboard", Key: "viewer-key", Role: config.ManagementRoleViewer},
+	}
+	h := NewHandler(cfg, "", nil)
+	h.SetLogDirectory(t.TempDir())
+
+	engine := gin.New()
+	mgmt := engine.Group("/v0/management")
+	mgmt.Use(h.Middleware(), h.AuditMiddleware())
+	mgmt.GET("/audit", h.GetAuditLog)
+	mgmt.GET("/usage", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{}) })
+	mgmt.PUT("/api-keys", func(c *gin.Context) {
+		h.cfg.APIKeys = append(h.cfg.APIKeys, "sk-new-secret-value")
+		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	})
+
+	serve := func(method, target, key string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, target, strings.NewReader("{}"))
+		req.Header.Set("Authorization", "Bearer "+key)
+		engine.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := serve(http.MethodPut, "/v0/management/api-keys", "admin-key"); rec.Code != http.StatusOK {
+		t.Fatalf("PUT status = %d", rec.Code)
+	}
+	serve(http.MethodGet, "/v0/management/usage", "viewer-key")
+
+	rec := serve(http.MethodGet, "/v0/management/audit?principal=ops-admin", "viewer-key")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /audit status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if strings.Contains(rec.Body.String(), "sk-new-secret-value") {
+		t.Fatalf("audit response leaked secret: %s", rec.Body.String())
+	}
+	var body struct {
+		Entries []logging.AuditEntry `json:"entries"`
+	}
+	if errUnmarshal := json.Unmarshal(rec.Body.Bytes(), &body); errUnmarshal != nil {
+		t.Fatalf("unmarshal: %v", errUnmarshal)
+	}
+	if len(body.Entries) != 1 {
+		t.Fatalf("entries = %+v, want only the PUT mutation", body.Entries)
+	}
+	entry := body.Entries[0]
+	if entry.Route != "/api-keys" || entry.Role != config.ManagementRoleAdmin || entry.Status != http.StatusOK {
+		t.Fatalf("entry = %+v", entry)
+	}
+	if len(entry.Changes) != 1 || entry.Changes[0].Path != "config.api-keys" || entry.Changes[0].After == nil {
+		t.Fatalf("changes = %+v, want config.api-keys addition", entry.Changes)
+	}
+}
+
+func TestAuditMiddleware_ConcurrentMutationsKeepSeparateDiffs(t *testing.T) {
+	t.Setenv("MANAGEMENT_PASSWORD", "")
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{}
+	cfg.RemoteManagement.AllowRemote = true
+	cfg.RemoteManagement.Keys = []config.ManagementKey{{Name: "ops-admin", Key: "admin-key", Role: config.ManagementRoleAdmin}}
+	h := NewHandler(cfg, "", nil)
+	h.SetLogDirectory(t.TempDir())
+
+	engine := gin.New()
+	mgmt := engine.Group("/v0/management")
+	mgmt.Use(h.Middleware(), h.AuditMiddleware())
+	mgmt.GET("/audit", h.GetAuditLog)
+	mgmt.PUT("/api-keys", func(c *gin.Context) {
+		h.mu.Lock()
+		h.cfg.APIKeys = append(h.cfg.APIKeys, c.Query("add"))
+		h.mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	})
+
+	serve := func(method, target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, target, strings.NewReader("{}"))
+		req.Header.Set("Authorization", "Bearer admin-key")
+		engine.ServeHTTP(rec, req)
+		return rec
+	}
+
+	var wg sync.WaitGroup
+	for _, key := range []string{"first", "second", "third"} {
+		wg.Add(1)
+		go func(key string) {
+			defer wg.Done()
+			serve(http.MethodPut, "/v0/management/api-keys?add="+key)
+		}(key)
+	}
+	wg.Wait()
+
+	rec := serve(http.MethodGet, "/v0/management/audit?method=PUT")
+	var body struct {
+		Entries []logging.AuditEntry `json:"entries"`
+	}
+	if errUnmarshal := json.Unmarshal(rec.Body.Bytes(), &body); errUnmarshal != nil {
+		t.Fatalf("unmarshal: %v", errUnmarshal)
+	}
+	if len(body.Entries) != 3 {
+		t.Fatalf("entries = %d, want 3", len(body.Entries))
+	}
+	for _, entry := range body.Entries {
+		if len(entry.Changes) != 1 {
+			t.Fatalf("changes = %+v, want only the call's own addition", entry.Changes)
+		}
+	}
+}
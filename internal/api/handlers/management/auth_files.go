@@ -37,9 +37,11 @@ import (
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/auth/kimi"
 	kiroauth "github.com/router-for-me/CLIProxyAPI/v6/internal/auth/kiro"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/auth/qwen"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/misc"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/tokencrypt"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
 	sdkAuth "github.com/router-for-me/CLIProxyAPI/v6/sdk/auth"
 	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
@@ -258,8 +260,9 @@ func (h *Handler) ListAuthFiles(c *gin.Context) {
 	}
 	auths := h.authManager.List()
 	files := make([]gin.H, 0, len(auths))
+	maskKeys := !roleAllows(callerRole(c), config.ManagementRoleAdmin)
 	for _, auth := range auths {
-		if entry := h.buildAuthFileEntry(auth); entry != nil {
+		if entry := h.buildAuthFileEntry(auth, maskKeys); entry != nil {
 			files = append(files, entry)
 		}
 	}
@@ -340,7 +343,12 @@ func (h *Handler) listAuthFilesFromDisk(c *gin.Context) {
 
 			// Read file to get type field
 			full := filepath.Join(h.cfg.AuthDir, name)
-			if data, errRead := os.ReadFile(full); errRead == nil {
+			if raw, errRead := os.ReadFile(full); errRead == nil {
+				fileData["encrypted"] = tokencrypt.IsSealed(raw)
+				data, errOpen := tokencrypt.Open(raw)
+				if errOpen != nil {
+					data = nil
+				}
 				typeValue := gjson.GetBytes(data, "type").String()
 				emailValue := gjson.GetBytes(data, "email").String()
 				fileData["type"] = typeValue
@@ -355,6 +363,26 @@ func (h *Handler) listAuthFilesFromDisk(c *gin.Context) {
 						}
 					}
 				}
+				if wv := gjson.GetBytes(data, "weight"); wv.Exists() {
+					switch wv.Type {
+					case gjson.Number:
+						fileData["weight"] = int(wv.Int())
+					case gjson.String:
+						if parsed, errAtoi := strconv.Atoi(strings.TrimSpace(wv.String())); errAtoi == nil {
+							fileData["weight"] = parsed
+						}
+					}
+				}
+				if mv := gjson.GetBytes(data, "max_concurrency"); mv.Exists() {
+					switch mv.Type {
+					case gjson.Number:
+						fileData["max_concurrency"] = int(mv.Int())
+					case gjson.String:
+						if parsed, errAtoi := strconv.Atoi(strings.TrimSpace(mv.String())); errAtoi == nil {
+							fileData["max_concurrency"] = parsed
+						}
+					}
+				}
 				if nv := gjson.GetBytes(data, "note"); nv.Exists() && nv.Type == gjson.String {
 					if trimmed := strings.TrimSpace(nv.String()); trimmed != "" {
 						fileData["note"] = trimmed
@@ -368,7 +396,9 @@ func (h *Handler) listAuthFilesFromDisk(c *gin.Context) {
 	c.JSON(200, gin.H{"files": files})
 }
 
-func (h *Handler) buildAuthFileEntry(auth *coreauth.Auth) gin.H {
+// buildAuthFileEntry describes auth for the listing. maskKeys hides upstream API keys from
+// callers below admin.
+func (h *Handler) buildAuthFileEntry(auth *coreauth.Auth, maskKeys bool) gin.H {
 	if auth == nil {
 		return nil
 	}
@@ -408,6 +438,9 @@ func (h *Handler) buildAuthFileEntry(auth *coreauth.Auth) gin.H {
 			entry["account_type"] = accountType
 		}
 		if account != "" {
+			if maskKeys && accountType == "api_key" {
+				account = util.HideAPIKey(account)
+			}
 			entry["account"] = account
 		}
 	}
@@ -424,6 +457,9 @@ func (h *Handler) buildAuthFileEntry(auth *coreauth.Auth) gin.H {
 	if !auth.NextRetryAfter.IsZero() {
 		entry["next_retry_after"] = auth.NextRetryAfter
 	}
+	if auth.Quota.RateLimit != nil {
+		entry["rate_limit"] = auth.Quota.RateLimit
+	}
 	if path != "" {
 		entry["path"] = path
 		entry["source"] = "file"
@@ -463,6 +499,59 @@ func (h *Handler) buildAuthFileEntry(auth *coreauth.Auth) gin.H {
 			}
 		}
 	}
+	// Expose weight the same way as priority.
+	if w := strings.TrimSpace(authAttribute(auth, "weight")); w != "" {
+		if parsed, err := strconv.Atoi(w); err == nil {
+			entry["weight"] = parsed
+		}
+	} else if auth.Metadata != nil {
+		if rawWeight, ok := auth.Metadata["weight"]; ok {
+			switch v := rawWeight.(type) {
+			case float64:
+				entry["weight"] = int(v)
+			case int:
+				entry["weight"] = v
+			case string:
+				if parsed, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+					entry["weight"] = parsed
+				}
+			}
+		}
+	}
+	// Expose the concurrency limit the same way as priority, plus the live in-flight count.
+	if mc := strings.TrimSpace(authAttribute(auth, "max_concurrency")); mc != "" {
+		if parsed, err := strconv.Atoi(mc); err == nil {
+			entry["max_concurrency"] = parsed
+		}
+	} else if auth.Metadata != nil {
+		if rawMax, ok := auth.Metadata["max_concurrency"]; ok {
+			switch v := rawMax.(type) {
+			case float64:
+				entry["max_concurrency"] = int(v)
+			case int:
+				entry["max_concurrency"] = v
+			case string:
+				if parsed, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+					entry["max_concurrency"] = parsed
+				}
+			}
+		}
+	}
+	if h.authManager != nil {
+		entry["in_flight"] = h.authManager.InFlight(auth.ID)
+	}
+	// Expose the availability schedule with the current state and the next window change.
+	if rawAvailability := strings.TrimSpace(authAttribute(auth, "availability")); rawAvailability != "" {
+		var schedule config.AvailabilitySchedule
+		if err := json.Unmarshal([]byte(rawAvailability), &schedule); err == nil {
+			available, changeAt := coreauth.AuthAvailability(auth, time.Now())
+			availability := gin.H{"schedule": schedule, "available": available}
+			if !changeAt.IsZero() {
+				availability["next_change_at"] = changeAt
+			}
+			entry["availability"] = availability
+		}
+	}
 	// Expose note from Attributes (set by synthesizer from JSON "note" field).
 	// Fall back to Metadata for auths registered via UploadAuthFile (no synthesizer).
 	if note := strings.TrimSpace(authAttribute(auth, "note")); note != "" {
@@ -576,7 +665,7 @@ func (h *Handler) DownloadAuthFile(c *gin.Context) {
 		return
 	}
 	full := filepath.Join(h.cfg.AuthDir, name)
-	data, err := os.ReadFile(full)
+	data, err := tokencrypt.ReadFile(full)
 	if err != nil {
 		if os.IsNotExist(err) {
 			c.JSON(404, gin.H{"error": "file not found"})
@@ -807,11 +896,19 @@ func (h *Handler) writeAuthFile(ctx context.Context, name string, data []byte) e
 			dst = abs
 		}
 	}
+	data, err := tokencrypt.Open(data)
+	if err != nil {
+		return err
+	}
 	auth, err := h.buildAuthFromFileData(dst, data)
 	if err != nil {
 		return err
 	}
-	if errWrite := os.WriteFile(dst, data, 0o600); errWrite != nil {
+	stored, err := tokencrypt.Seal(data)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt file: %w", err)
+	}
+	if errWrite := os.WriteFile(dst, stored, 0o600); errWrite != nil {
 		return fmt.Errorf("failed to write file: %w", errWrite)
 	}
 	if err := h.upsertAuthRecord(ctx, auth); err != nil {
@@ -1001,6 +1098,10 @@ func (h *Handler) buildAuthFromFileData(path string, data []byte) (*coreauth.Aut
 			return nil, fmt.Errorf("failed to read auth file: %w", err)
 		}
 	}
+	data, err := tokencrypt.Open(data)
+	if err != nil {
+		return nil, err
+	}
 	metadata := make(map[string]any)
 	if err := json.Unmarshal(data, &metadata); err != nil {
 		return nil, fmt.Errorf("invalid auth file: %w", err)
@@ -1130,7 +1231,7 @@ func (h *Handler) PatchAuthFileStatus(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"status": "ok", "disabled": *req.Disabled})
 }
 
-// PatchAuthFileFields updates editable fields (prefix, proxy_url, headers, priority, note) of an auth file.
+// PatchAuthFileFields updates editable fields (prefix, proxy_url, headers, priority, weight, max_concurrency, availability, note) of an auth file.
 func (h *Handler) PatchAuthFileFields(c *gin.Context) {
 	if h.authManager == nil {
 		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "core auth manager unavailable"})
@@ -1138,12 +1239,15 @@ func (h *Handler) PatchAuthFileFields(c *gin.Context) {
 	}
 
 	var req struct {
-		Name     string            `json:"name"`
-		Prefix   *string           `json:"prefix"`
-		ProxyURL *string           `json:"proxy_url"`
-		Headers  map[string]string `json:"headers"`
-		Priority *int              `json:"priority"`
-		Note     *string           `json:"note"`
+		Name           string            `json:"name"`
+		Prefix         *string           `json:"prefix"`
+		ProxyURL       *string           `json:"proxy_url"`
+		Headers        map[string]string `json:"headers"`
+		Priority       *int              `json:"priority"`
+		Weight         *int              `json:"weight"`
+		MaxConcurrency *int              `json:"max_concurrency"`
+		Availability   json.RawMessage   `json:"availability"`
+		Note           *string           `json:"note"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
@@ -1280,7 +1384,21 @@ func (h *Handler) PatchAuthFileFields(c *gin.Context) {
 			changed = true
 		}
 	}
-	if req.Priority != nil || req.Note != nil {
+	var availability *config.AvailabilitySchedule
+	if len(req.Availability) > 0 {
+		availability = &config.AvailabilitySchedule{}
+		if string(req.Availability) != "null" {
+			if err := json.Unmarshal(req.Availability, availability); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid availability"})
+				return
+			}
+			if err := coreauth.ValidateAvailabilitySchedule(availability); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid availability: %v", err)})
+				return
+			}
+		}
+	}
+	if req.Priority != nil || req.Weight != nil || req.MaxConcurrency != nil || availability != nil || req.Note != nil {
 		if targetAuth.Metadata == nil {
 			targetAuth.Metadata = make(map[string]any)
 		}
@@ -1297,6 +1415,34 @@ func (h *Handler) PatchAuthFileFields(c *gin.Context) {
 				targetAuth.Attributes["priority"] = strconv.Itoa(*req.Priority)
 			}
 		}
+		if req.Weight != nil {
+			if *req.Weight <= 0 {
+				delete(targetAuth.Metadata, "weight")
+				delete(targetAuth.Attributes, "weight")
+			} else {
+				targetAuth.Metadata["weight"] = *req.Weight
+				targetAuth.Attributes["weight"] = strconv.Itoa(*req.Weight)
+			}
+		}
+		if req.MaxConcurrency != nil {
+			if *req.MaxConcurrency <= 0 {
+				delete(targetAuth.Metadata, "max_concurrency")
+				delete(targetAuth.Attributes, "max_concurrency")
+			} else {
+				targetAuth.Metadata["max_concurrency"] = *req.MaxConcurrency
+				targetAuth.Attributes["max_concurrency"] = strconv.Itoa(*req.MaxConcurrency)
+			}
+		}
+		if availability != nil {
+			if availability.IsZero() {
+				delete(targetAuth.Metadata, "availability")
+				delete(targetAuth.Attributes, "availability")
+			} else {
+				encoded, _ := json.Marshal(availability)
+				targetAuth.Metadata["availability"] = availability
+				targetAuth.Attributes["availability"] = string(encoded)
+			}
+		}
 		if req.Note != nil {
 			trimmedNote := strings.TrimSpace(*req.Note)
 			if trimmedNote == "" {
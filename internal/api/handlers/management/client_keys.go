@@ -0,0 +1,237 @@
+package management
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+)
+
+// clientKeyView is a managed client key as returned by the management API.
+type clientKeyView struct {
+	config.ClientKey
+	Usage coreauth.ClientKeyUsage `json:"usage"`
+}
+
+// GetClientKeys lists managed client keys together with their current usage.
+func (h *Handler) GetClientKeys(c *gin.Context) {
+	items := make([]clientKeyView, 0, len(h.cfg.ClientKeys))
+	for _, entry := range h.cfg.ClientKeys {
+		view := clientKeyView{ClientKey: entry}
+		if h.authManager != nil {
+			view.Usage = h.authManager.ClientKeyUsage(entry.Key)
+		}
+		items = append(items, view)
+	}
+	c.JSON(http.StatusOK, gin.H{"client-keys": items})
+}
+
+// PostClientKey creates a managed client key. A random key is generated when none is given.
+func (h *Handler) PostClientKey(c *gin.Context) {
+	var entry config.ClientKey
+	if err := c.ShouldBindJSON(&entry); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+	entry.Key = strings.TrimSpace(entry.Key)
+	if entry.Key == "" {
+		key, errKey := generateClientKey()
+		if errKey != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": errKey.Error()})
+			return
+		}
+		entry.Key = key
+	}
+	if errValidate := validateClientKey(&entry); errValidate != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errValidate.Error()})
+		return
+	}
+	if h.cfg.FindClientKey(entry.Key) != nil || containsString(h.cfg.APIKeys, entry.Key) {
+		c.JSON(http.StatusConflict, gin.H{"error": "key already exists"})
+		return
+	}
+	h.cfg.ClientKeys = append(h.cfg.ClientKeys, entry)
+	h.cfg.SanitizeClientKeys()
+	h.persistWithResponse(c, http.StatusCreated, gin.H{"status": "ok", "client-key": entry})
+}
+
+// PutClientKeys replaces all managed client keys.
+func (h *Handler) PutClientKeys(c *gin.Context) {
+	data, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read body"})
+		return
+	}
+	var arr []config.ClientKey
+	if err = json.Unmarshal(data, &arr); err != nil {
+		var obj struct {
+			Items []config.ClientKey `json:"items"`
+		}
+		if err2 := json.Unmarshal(data, &obj); err2 != nil || len(obj.Items) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+			return
+		}
+		arr = obj.Items
+	}
+	for index := range arr {
+		if errValidate := validateClientKey(&arr[index]); errValidate != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("item %d: %v", index, errValidate)})
+			return
+		}
+	}
+	h.cfg.ClientKeys = append([]config.ClientKey(nil), arr...)
+	h.cfg.SanitizeClientKeys()
+	h.persist(c)
+}
+
+// PatchClientKey updates fields of one managed client key, selected by index or by
+// matching its key or name.
+func (h *Handler) PatchClientKey(c *gin.Context) {
+	type clientKeyPatch struct {
+		Key       *string                 `json:"key"`
+		Name      *string                 `json:"name"`
+		Owner     *string                 `json:"owner"`
+		Models    *[]string               `json:"models"`
+		Endpoints *[]string               `json:"endpoints"`
+		ExpiresAt *string                 `json:"expires-at"`
+		Budget    *config.ClientKeyBudget `json:"budget"`
+		Disabled  *bool                   `json:"disabled"`
+	}
+	var body struct {
+		Index *int            `json:"index"`
+		Match *string         `json:"match"`
+		Value *clientKeyPatch `json:"value"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil || body.Value == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+	targetIndex := -1
+	if body.Index != nil && *body.Index >= 0 && *body.Index < len(h.cfg.ClientKeys) {
+		targetIndex = *body.Index
+	}
+	if targetIndex == -1 && body.Match != nil {
+		targetIndex = h.findClientKeyIndex(strings.TrimSpace(*body.Match))
+	}
+	if targetIndex == -1 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "item not found"})
+		return
+	}
+
+	entry := h.cfg.ClientKeys[targetIndex]
+	if body.Value.Key != nil {
+		key := strings.TrimSpace(*body.Value.Key)
+		if key == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "key must not be empty"})
+			return
+		}
+		if key != entry.Key && (h.cfg.FindClientKey(key) != nil || containsString(h.cfg.APIKeys, key)) {
+			c.JSON(http.StatusConflict, gin.H{"error": "key already exists"})
+			return
+		}
+		entry.Key = key
+	}
+	if body.Value.Name != nil {
+		entry.Name = *body.Value.Name
+	}
+	if body.Value.Owner != nil {
+		entry.Owner = *body.Value.Owner
+	}
+	if body.Value.Models != nil {
+		entry.Models = append([]string(nil), (*body.Value.Models)...)
+	}
+	if body.Value.Endpoints != nil {
+		entry.Endpoints = append([]string(nil), (*body.Value.Endpoints)...)
+	}
+	if body.Value.ExpiresAt != nil {
+		entry.ExpiresAt = *body.Value.ExpiresAt
+	}
+	if body.Value.Budget != nil {
+		entry.Budget = *body.Value.Budget
+	}
+	if body.Value.Disabled != nil {
+		entry.Disabled = *body.Value.Disabled
+	}
+	if errValidate := validateClientKey(&entry); errValidate != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errValidate.Error()})
+		return
+	}
+	h.cfg.ClientKeys[targetIndex] = entry
+	h.cfg.SanitizeClientKeys()
+	h.persist(c)
+}
+
+// DeleteClientKey removes a managed client key selected by ?index=, ?key= or ?name=.
+func (h *Handler) DeleteClientKey(c *gin.Context) {
+	targetIndex := -1
+	if idxStr := c.Query("index"); idxStr != "" {
+		if idx, err := strconv.Atoi(idxStr); err == nil && idx >= 0 && idx < len(h.cfg.ClientKeys) {
+			targetIndex = idx
+		}
+	} else if key := strings.TrimSpace(c.Query("key")); key != "" {
+		targetIndex = h.findClientKeyIndex(key)
+	} else if name := strings.TrimSpace(c.Query("name")); name != "" {
+		targetIndex = h.findClientKeyIndex(name)
+	} else {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing index, key or name"})
+		return
+	}
+	if targetIndex == -1 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "item not found"})
+		return
+	}
+	h.cfg.ClientKeys = append(h.cfg.ClientKeys[:targetIndex], h.cfg.ClientKeys[targetIndex+1:]...)
+	h.persist(c)
+}
+
+// findClientKeyIndex returns the index of the client key whose key or name equals match.
+func (h *Handler) findClientKeyIndex(match string) int {
+	if match == "" {
+		return -1
+	}
+	for index := range h.cfg.ClientKeys {
+		if h.cfg.ClientKeys[index].Key == match {
+			return index
+		}
+	}
+	for index := range h.cfg.ClientKeys {
+		if h.cfg.ClientKeys[index].Name == match {
+			return index
+		}
+	}
+	return -1
+}
+
+func validateClientKey(entry *config.ClientKey) error {
+	if strings.TrimSpace(entry.Key) == "" {
+		return fmt.Errorf("key is required")
+	}
+	if _, err := entry.Expiry(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func generateClientKey() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate key: %w", err)
+	}
+	return "sk-" + hex.EncodeToString(b), nil
+}
+
+func containsString(values []string, target string) bool {
+	for _, value := range values {
+		if strings.TrimSpace(value) == target {
+			return true
+		}
+	}
+	return false
+}
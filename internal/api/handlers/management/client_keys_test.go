@@ -0,0 +1,92 @@
+package management
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+)
+
+func newClientKeysTestHandler(t *testing.T) *Handler {
+	t.Helper()
+	t.Setenv("MANAGEMENT_PASSWORD", "")
+	gin.SetMode(gin.TestMode)
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	if errWrite := os.WriteFile(configPath, []byte("port: 8317\n"), 0o600); errWrite != nil {
+		t.Fatalf("write config: %v", errWrite)
+	}
+	return NewHandler(&config.Config{}, configPath, nil)
+}
+
+func serveClientKeys(h *Handler, handler gin.HandlerFunc, method, target, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(rec)
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	ctx.Request = req
+	handler(ctx)
+	return rec
+}
+
+func TestClientKeys_CreatePatchDelete(t *testing.T) {
+	h := newClientKeysTestHandler(t)
+
+	rec := serveClientKeys(h, h.PostClientKey, http.MethodPost, "/v0/management/client-keys", `{"name":"team-a","owner":"alice","models":["claude-*"],"budget":{"daily-requests":10}}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("POST status = %d, body %s", rec.Code, rec.Body.String())
+	}
+	var created struct {
+		ClientKey config.ClientKey `json:"client-key"`
+	}
+	if errDecode := json.Unmarshal(rec.Body.Bytes(), &created); errDecode != nil {
+		t.Fatalf("decode POST response: %v", errDecode)
+	}
+	if !strings.HasPrefix(created.ClientKey.Key, "sk-") {
+		t.Fatalf("generated key = %q, want sk- prefix", created.ClientKey.Key)
+	}
+
+	rec = serveClientKeys(h, h.PostClientKey, http.MethodPost, "/v0/management/client-keys", `{"key":"`+created.ClientKey.Key+`"}`)
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("duplicate POST status = %d, want %d", rec.Code, http.StatusConflict)
+	}
+
+	rec = serveClientKeys(h, h.PatchClientKey, http.MethodPatch, "/v0/management/client-keys", `{"match":"team-a","value":{"disabled":true,"expires-at":"2030-01-31"}}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PATCH status = %d, body %s", rec.Code, rec.Body.String())
+	}
+	if got := h.cfg.ClientKeys[0]; !got.Disabled || got.ExpiresAt != "2030-01-31" || got.Owner != "alice" {
+		t.Fatalf("patched entry = %+v", got)
+	}
+
+	rec = serveClientKeys(h, h.PatchClientKey, http.MethodPatch, "/v0/management/client-keys", `{"index":0,"value":{"expires-at":"next week"}}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("PATCH with invalid expiry status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+
+	rec = serveClientKeys(h, h.GetClientKeys, http.MethodGet, "/v0/management/client-keys", "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"usage"`) {
+		t.Fatalf("GET status = %d, body %s", rec.Code, rec.Body.String())
+	}
+
+	rec = serveClientKeys(h, h.DeleteClientKey, http.MethodDelete, "/v0/management/client-keys?name=team-a", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("DELETE status = %d, body %s", rec.Code, rec.Body.String())
+	}
+	if len(h.cfg.ClientKeys) != 0 {
+		t.Fatalf("client keys after delete = %d, want 0", len(h.cfg.ClientKeys))
+	}
+
+	persisted, errLoad := config.LoadConfig(h.configFilePath)
+	if errLoad != nil {
+		t.Fatalf("reload config: %v", errLoad)
+	}
+	if len(persisted.ClientKeys) != 0 {
+		t.Fatalf("persisted client keys = %d, want 0", len(persisted.ClientKeys))
+	}
+}
@@ -28,7 +28,16 @@ func (h *Handler) GetConfig(c *gin.Context) {
 		c.JSON(200, gin.H{})
 		return
 	}
-	c.JSON(200, new(*h.cfg))
+	if !roleAllows(callerRole(c), config.ManagementRoleAdmin) {
+		c.JSON(200, redactSecrets(h.cfg))
+		return
+	}
+	view, err := h.cfg.WithSecretRefs()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to render config: %v", err)})
+		return
+	}
+	c.JSON(200, view)
 }
 
 type releaseInfo struct {
@@ -119,6 +128,13 @@ func (h *Handler) PutConfigYAML(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_yaml", "message": err.Error()})
 		return
 	}
+	h.mu.Lock()
+	paths := cfg.UnknownSecretRefs(h.cfg)
+	h.mu.Unlock()
+	if len(paths) > 0 {
+		c.JSON(http.StatusForbidden, gin.H{"error": "secret_reference_refused", "message": errNewSecretRefs(paths)})
+		return
+	}
 	// Validate config using LoadConfigOptional with optional=false to enforce parsing
 	tmpDir := filepath.Dir(h.configFilePath)
 	tmpFile, err := os.CreateTemp(tmpDir, "config-validate-*.yaml")
@@ -286,6 +302,8 @@ func normalizeRoutingStrategy(strategy string) (string, bool) {
 		return "round-robin", true
 	case "fill-first", "fillfirst", "ff":
 		return "fill-first", true
+	case "adaptive":
+		return "adaptive", true
 	default:
 		return "", false
 	}
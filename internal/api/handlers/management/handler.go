@@ -15,6 +15,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/buildinfo"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/usage"
 	sdkAuth "github.com/router-for-me/CLIProxyAPI/v6/sdk/auth"
 	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
@@ -48,6 +49,9 @@ type Handler struct {
 	envSecret           string
 	logDir              string
 	postAuthHook        coreauth.PostAuthHook
+	auditMu             sync.Mutex
+	audit               *logging.AuditLog
+	auditedCallMu       sync.Mutex // serializes audited calls so each diff holds only its own change
 }
 
 // NewHandler creates a new management handler instance.
@@ -137,6 +141,8 @@ func (h *Handler) SetPostAuthHook(hook coreauth.PostAuthHook) {
 // Middleware enforces access control for management endpoints.
 // All requests (local and remote) require a valid management key.
 // Additionally, remote access requires allow-remote-management=true.
+// The secret key, MANAGEMENT_PASSWORD and the local password carry the admin role; keys from
+// remote-management.keys carry their configured role, checked per route by RequiredRole.
 func (h *Handler) Middleware() gin.HandlerFunc {
 	const maxFailures = 5
 	const banDuration = 30 * time.Minute
@@ -202,7 +208,7 @@ func (h *Handler) Middleware() gin.HandlerFunc {
 				h.attemptsMu.Unlock()
 			}
 		}
-		if secretHash == "" && envSecret == "" {
+		if secretHash == "" && envSecret == "" && (cfg == nil || len(cfg.RemoteManagement.Keys) == 0) {
 			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "remote management key not set"})
 			return
 		}
@@ -229,29 +235,28 @@ func (h *Handler) Middleware() gin.HandlerFunc {
 			return
 		}
 
+		role, keyName := "", ""
 		if localClient {
-			if lp := h.localPassword; lp != "" {
-				if subtle.ConstantTimeCompare([]byte(provided), []byte(lp)) == 1 {
-					c.Next()
-					return
-				}
+			if lp := h.localPassword; lp != "" && subtle.ConstantTimeCompare([]byte(provided), []byte(lp)) == 1 {
+				role, keyName = config.ManagementRoleAdmin, "local-password"
 			}
 		}
-
-		if envSecret != "" && subtle.ConstantTimeCompare([]byte(provided), []byte(envSecret)) == 1 {
-			if !localClient {
-				h.attemptsMu.Lock()
-				if ai := h.failedAttempts[clientIP]; ai != nil {
-					ai.count = 0
-					ai.blockedUntil = time.Time{}
+		if role == "" && envSecret != "" && subtle.ConstantTimeCompare([]byte(provided), []byte(envSecret)) == 1 {
+			role, keyName = config.ManagementRoleAdmin, "MANAGEMENT_PASSWORD"
+		}
+		if role == "" && secretHash != "" && bcrypt.CompareHashAndPassword([]byte(secretHash), []byte(provided)) == nil {
+			role, keyName = config.ManagementRoleAdmin, "secret-key"
+		}
+		if role == "" && cfg != nil {
+			if entry := matchManagementKey(cfg.RemoteManagement.Keys, provided); entry != nil {
+				role, keyName = entry.Role, entry.Name
+				if keyName == "" {
+					keyName = entry.Role + "-key"
 				}
-				h.attemptsMu.Unlock()
 			}
-			c.Next()
-			return
 		}
 
-		if secretHash == "" || bcrypt.CompareHashAndPassword([]byte(secretHash), []byte(provided)) != nil {
+		if role == "" {
 			if !localClient {
 				fail()
 			}
@@ -268,23 +273,55 @@ func (h *Handler) Middleware() gin.HandlerFunc {
 			h.attemptsMu.Unlock()
 		}
 
+		c.Set(managementRoleContextKey, role)
+		c.Set(managementKeyNameContextKey, keyName)
+		if required := RequiredRole(c.Request.Method, c.FullPath()); !roleAllows(role, required) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "insufficient management role", "role": role, "required": required})
+			return
+		}
+
 		c.Next()
 	}
 }
 
 // persist saves the current in-memory config to disk.
 func (h *Handler) persist(c *gin.Context) bool {
+	return h.persistWithResponse(c, http.StatusOK, gin.H{"status": "ok"})
+}
+
+// persistWithResponse saves the config like persist and replies with body on success.
+func (h *Handler) persistWithResponse(c *gin.Context, status int, body any) bool {
 	h.mu.Lock()
 	defer h.mu.Unlock()
+	// Secret references may only come from the config file on disk; a remote caller must not
+	// make the server read files or environment variables for it. The rejected change is
+	// dropped by reloading the saved config.
+	if paths := h.cfg.UnknownSecretRefs(h.cfg); len(paths) > 0 {
+		if saved, errLoad := config.LoadConfig(h.configFilePath); errLoad == nil {
+			h.cfg = saved
+		}
+		c.JSON(http.StatusForbidden, gin.H{"error": errNewSecretRefs(paths)})
+		return false
+	}
+	// References loaded from disk and sent back unchanged are resolved again and saved as references.
+	if err := h.cfg.ResolveSecretRefs(); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": fmt.Sprintf("failed to resolve secret reference: %v", err)})
+		return false
+	}
 	// Preserve comments when writing
 	if err := config.SaveConfigPreserveComments(h.configFilePath, h.cfg); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to save config: %v", err)})
 		return false
 	}
-	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	c.JSON(status, body)
 	return true
 }
 
+// errNewSecretRefs describes secret references the management API refused to accept.
+func errNewSecretRefs(paths []string) string {
+	return fmt.Sprintf("secret references (env:, file:, ${VAR}) can only be added by editing the config file on the server: %s", strings.Join(paths, ", "))
+}
+
 // Helper methods for simple types
 func (h *Handler) updateBoolField(c *gin.Context, set func(bool)) {
 	var body struct {
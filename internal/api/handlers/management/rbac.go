@@ -0,0 +1,173 @@
+package management
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// managementRoleContextKey stores the authenticated caller's role on the gin context.
+	managementRoleContextKey = "managementRole"
+	// managementKeyNameContextKey stores the name of the management key used by the caller.
+	managementKeyNameContextKey = "managementKeyName"
+
+	managementRoutePrefix = "/v0/management"
+)
+
+// roleRank orders management roles so a higher rank includes every lower permission.
+func roleRank(role string) int {
+	switch role {
+	case config.ManagementRoleViewer:
+		return 1
+	case config.ManagementRoleOperator:
+		return 2
+	case config.ManagementRoleAdmin:
+		return 3
+	default:
+		return 0
+	}
+}
+
+// routeRoles overrides the default role for routes keyed by "METHOD /route". By default
+// GET routes need viewer and every other method needs admin.
+var routeRoles = map[string]string{
+	// Reads that expose secrets or raw request bodies.
+	"GET /config.yaml":               config.ManagementRoleAdmin,
+	"GET /auth-files/download":       config.ManagementRoleAdmin,
+	"GET /api-keys":                  config.ManagementRoleAdmin,
+	"GET /client-keys":               config.ManagementRoleAdmin,
+	"GET /gemini-api-key":            config.ManagementRoleAdmin,
+	"GET /claude-api-key":            config.ManagementRoleAdmin,
+	"GET /codex-api-key":             config.ManagementRoleAdmin,
+	"GET /openai-compatibility":      config.ManagementRoleAdmin,
+	"GET /vertex-api-key":            config.ManagementRoleAdmin,
+	"GET /ampcode":                   config.ManagementRoleAdmin,
+	"GET /ampcode/upstream-api-key":  config.ManagementRoleAdmin,
+	"GET /ampcode/upstream-api-keys": config.ManagementRoleAdmin,
+	"GET /usage/export":              config.ManagementRoleAdmin,
+	"GET /store-history":             config.ManagementRoleAdmin,
+	"GET /request-log-by-id/:id":     config.ManagementRoleOperator,
+	"GET /request-error-logs/:name":  config.ManagementRoleOperator,
+	"GET /anthropic-auth-url":        config.ManagementRoleOperator,
+	"GET /codex-auth-url":            config.ManagementRoleOperator,
+	"GET /gitlab-auth-url":           config.ManagementRoleOperator,
+	"GET /gemini-cli-auth-url":       config.ManagementRoleOperator,
+	"GET /antigravity-auth-url":      config.ManagementRoleOperator,
+	"GET /qwen-auth-url":             config.ManagementRoleOperator,
+	"GET /kilo-auth-url":             config.ManagementRoleOperator,
+	"GET /kimi-auth-url":             config.ManagementRoleOperator,
+	"GET /iflow-auth-url":            config.ManagementRoleOperator,
+	"GET /kiro-auth-url":             config.ManagementRoleOperator,
+	"GET /cursor-auth-url":           config.ManagementRoleOperator,
+	"GET /github-auth-url":           config.ManagementRoleOperator,
+	"GET /get-auth-status":           config.ManagementRoleOperator,
+	"POST /gitlab-auth-url":          config.ManagementRoleOperator,
+	"POST /iflow-auth-url":           config.ManagementRoleOperator,
+	"POST /oauth-callback":           config.ManagementRoleOperator,
+	"PATCH /auth-files/status":       config.ManagementRoleOperator,
+	"DELETE /logs":                   config.ManagementRoleOperator,
+	"POST /usage/import":             config.ManagementRoleOperator,
+}
+
+// RequiredRole returns the minimum role needed to call a management route. route is the
+// registered gin path, with or without the /v0/management prefix.
+func RequiredRole(method, route string) string {
+	route = strings.TrimPrefix(route, managementRoutePrefix)
+	if role, ok := routeRoles[method+" "+route]; ok {
+		return role
+	}
+	if method == http.MethodGet || method == http.MethodHead {
+		return config.ManagementRoleViewer
+	}
+	return config.ManagementRoleAdmin
+}
+
+// roleAllows reports whether role grants the permissions of required.
+func roleAllows(role, required string) bool {
+	return roleRank(role) > 0 && roleRank(role) >= roleRank(required)
+}
+
+// callerRole returns the management role of the current request, defaulting to admin for
+// handlers invoked without the middleware.
+func callerRole(c *gin.Context) string {
+	if c != nil {
+		if role, ok := c.Get(managementRoleContextKey); ok {
+			if text, okText := role.(string); okText && text != "" {
+				return text
+			}
+		}
+	}
+	return config.ManagementRoleAdmin
+}
+
+// matchManagementKey returns the role-bound key matching provided, or nil.
+func matchManagementKey(keys []config.ManagementKey, provided string) *config.ManagementKey {
+	for index := range keys {
+		entry := &keys[index]
+		if strings.HasPrefix(entry.Key, "$2") {
+			if bcrypt.CompareHashAndPassword([]byte(entry.Key), []byte(provided)) == nil {
+				return entry
+			}
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(entry.Key), []byte(provided)) == 1 {
+			return entry
+		}
+	}
+	return nil
+}
+
+// secretFieldNames lists config JSON fields whose values are masked for non-admin callers.
+var secretFieldNames = map[string]struct{}{
+	"api-key":          {},
+	"api-keys":         {},
+	"key":              {},
+	"access-token":     {},
+	"refresh-token":    {},
+	"upstream-api-key": {},
+	"secret-key":       {},
+	"headers":          {},
+}
+
+// redactSecrets returns a JSON-compatible copy of value with secret fields masked.
+func redactSecrets(value any) any {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return gin.H{}
+	}
+	var decoded any
+	if err = json.Unmarshal(raw, &decoded); err != nil {
+		return gin.H{}
+	}
+	return redactNode(decoded, false)
+}
+
+func redactNode(node any, secret bool) any {
+	switch typed := node.(type) {
+	case map[string]any:
+		for key, child := range typed {
+			_, isSecret := secretFieldNames[key]
+			typed[key] = redactNode(child, secret || isSecret)
+		}
+		return typed
+	case []any:
+		for index, child := range typed {
+			typed[index] = redactNode(child, secret)
+		}
+		return typed
+	case string:
+		if secret && typed != "" {
+			return util.HideAPIKey(typed)
+		}
+		return typed
+	default:
+		return typed
+	}
+}
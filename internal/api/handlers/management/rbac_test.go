@@ -0,0 +1,148 @@
+package management
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+)
+
+func newRBACTestEngine(t *testing.T) *gin.Engine {
+	t.Helper()
+	t.Setenv("MANAGEMENT_PASSWORD", "")
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{}
+	cfg.RemoteManagement.AllowRemote = true
+	cfg.APIKeys = []string{"sk-client-secret-value"}
+	cfg.RemoteManagement.Keys = []config.ManagementKey{
+		{Name: "dashboard", Key: "viewer-key", Role: config.ManagementRoleViewer},
+		{Name: "oncall", Key: "operator-key", Role: config.ManagementRoleOperator},
+		{Name: "ops-admin", Key: "admin-key", Role: config.ManagementRoleAdmin},
+	}
+	manager := coreauth.NewManager(nil, nil, nil)
+	if _, err := manager.Register(context.Background(), &coreauth.Auth{
+		ID:         "claude-upstream",
+		Provider:   "claude",
+		Status:     coreauth.StatusActive,
+		Attributes: map[string]string{"api_key": "sk-upstream-secret-value", "runtime_only": "true"},
+	}); err != nil {
+		t.Fatalf("Register auth: %v", err)
+	}
+	h := NewHandler(cfg, "", manager)
+
+	ok := func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"status": "ok"}) }
+	engine := gin.New()
+	mgmt := engine.Group("/v0/management")
+	mgmt.Use(h.Middleware())
+	mgmt.GET("/config", h.GetConfig)
+	mgmt.GET("/usage", ok)
+	mgmt.GET("/usage/export", h.ExportUsageStatistics)
+	mgmt.GET("/auth-files", h.ListAuthFiles)
+	mgmt.GET("/store-history", h.GetStoreHistory)
+	mgmt.GET("/auth-files/download", ok)
+	mgmt.PATCH("/auth-files/status", ok)
+	mgmt.PUT("/config.yaml", ok)
+	return engine
+}
+
+func serveRBAC(engine *gin.Engine, method, target, key string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(method, target, strings.NewReader("{}"))
+	req.Header.Set("Authorization", "Bearer "+key)
+	engine.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestMiddleware_EnforcesRolePerRoute(t *testing.T) {
+	engine := newRBACTestEngine(t)
+
+	cases := []struct {
+		key    string
+		method string
+		path   string
+		want   int
+	}{
+		{"viewer-key", http.MethodGet, "/v0/management/usage", http.StatusOK},
+		{"viewer-key", http.MethodGet, "/v0/management/auth-files/download", http.StatusForbidden},
+		{"viewer-key", http.MethodGet, "/v0/management/usage/export", http.StatusForbidden},
+		{"operator-key", http.MethodGet, "/v0/management/usage/export", http.StatusForbidden},
+		{"admin-key", http.MethodGet, "/v0/management/usage/export", http.StatusOK},
+		{"viewer-key", http.MethodGet, "/v0/management/auth-files", http.StatusOK},
+		{"operator-key", http.MethodGet, "/v0/management/store-history", http.StatusForbidden},
+		{"admin-key", http.MethodGet, "/v0/management/store-history", http.StatusBadRequest},
+		{"viewer-key", http.MethodPatch, "/v0/management/auth-files/status", http.StatusForbidden},
+		{"operator-key", http.MethodPatch, "/v0/management/auth-files/status", http.StatusOK},
+		{"operator-key", http.MethodPut, "/v0/management/config.yaml", http.StatusForbidden},
+		{"admin-key", http.MethodPut, "/v0/management/config.yaml", http.StatusOK},
+		{"admin-key", http.MethodGet, "/v0/management/auth-files/download", http.StatusOK},
+		{"unknown-key", http.MethodGet, "/v0/management/usage", http.StatusUnauthorized},
+	}
+	for _, tc := range cases {
+		if rec := serveRBAC(engine, tc.method, tc.path, tc.key); rec.Code != tc.want {
+			t.Errorf("%s %s with %s: status = %d, want %d (%s)", tc.method, tc.path, tc.key, rec.Code, tc.want, rec.Body.String())
+		}
+	}
+}
+
+func TestGetConfig_RedactsSecretsForViewer(t *testing.T) {
+	engine := newRBACTestEngine(t)
+
+	rec := serveRBAC(engine, http.MethodGet, "/v0/management/config", "viewer-key")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if strings.Contains(rec.Body.String(), "sk-client-secret-value") {
+		t.Fatalf("viewer config response leaked api key: %s", rec.Body.String())
+	}
+	var body map[string]any
+	if errUnmarshal := json.Unmarshal(rec.Body.Bytes(), &body); errUnmarshal != nil {
+		t.Fatalf("unmarshal: %v", errUnmarshal)
+	}
+	keys, _ := body["api-keys"].([]any)
+	if len(keys) != 1 || keys[0] != "sk-c...alue" {
+		t.Fatalf("api-keys = %v, want masked key", body["api-keys"])
+	}
+
+	rec = serveRBAC(engine, http.MethodGet, "/v0/management/config", "admin-key")
+	if !strings.Contains(rec.Body.String(), "sk-client-secret-value") {
+		t.Fatalf("admin config response should include api key: %s", rec.Body.String())
+	}
+}
+
+func TestListAuthFiles_MasksUpstreamKeysForViewer(t *testing.T) {
+	engine := newRBACTestEngine(t)
+
+	rec := serveRBAC(engine, http.MethodGet, "/v0/management/auth-files", "viewer-key")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if strings.Contains(rec.Body.String(), "sk-upstream-secret-value") {
+		t.Fatalf("viewer auth-files response leaked upstream key: %s", rec.Body.String())
+	}
+	if !strings.Contains(rec.Body.String(), `"account":"sk-u...alue"`) {
+		t.Fatalf("viewer auth-files response should show the masked key: %s", rec.Body.String())
+	}
+
+	rec = serveRBAC(engine, http.MethodGet, "/v0/management/auth-files", "admin-key")
+	if !strings.Contains(rec.Body.String(), "sk-upstream-secret-value") {
+		t.Fatalf("admin auth-files response should include upstream key: %s", rec.Body.String())
+	}
+}
+
+func TestRequiredRole_Defaults(t *testing.T) {
+	if got := RequiredRole(http.MethodGet, "/v0/management/logs"); got != config.ManagementRoleViewer {
+		t.Fatalf("GET /logs role = %q, want viewer", got)
+	}
+	if got := RequiredRole(http.MethodPut, "/v0/management/debug"); got != config.ManagementRoleAdmin {
+		t.Fatalf("PUT /debug role = %q, want admin", got)
+	}
+	if got := RequiredRole(http.MethodGet, "/gemini-cli-auth-url"); got != config.ManagementRoleOperator {
+		t.Fatalf("GET /gemini-cli-auth-url role = %q, want operator", got)
+	}
+}
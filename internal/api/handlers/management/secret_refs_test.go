@@ -0,0 +1,62 @@
+package management
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+)
+
+func TestPersist_RefusesNewSecretRefs(t *testing.T) {
+	t.Setenv("MANAGEMENT_PASSWORD", "")
+	t.Setenv("TEST_MGMT_CLAUDE_KEY", "sk-from-env")
+	gin.SetMode(gin.TestMode)
+	hostFile := filepath.Join(t.TempDir(), "host-secret")
+	if errWrite := os.WriteFile(hostFile, []byte("do-not-leak\n"), 0o600); errWrite != nil {
+		t.Fatalf("write host file: %v", errWrite)
+	}
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	if errWrite := os.WriteFile(configPath, []byte("claude-api-key:\n  - api-key: env:TEST_MGMT_CLAUDE_KEY\n"), 0o600); errWrite != nil {
+		t.Fatalf("write config: %v", errWrite)
+	}
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	h := NewHandler(cfg, configPath, nil)
+
+	// Sending back the reference the config was loaded with is allowed.
+	rec := serveClientKeys(h, h.PutClaudeKeys, http.MethodPut, "/v0/management/claude-api-key", `[{"api-key":"env:TEST_MGMT_CLAUDE_KEY"}]`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PUT unchanged reference status = %d, body %s", rec.Code, rec.Body.String())
+	}
+	if got := h.cfg.ClaudeKey[0].APIKey; got != "sk-from-env" {
+		t.Fatalf("resolved api-key = %q, want sk-from-env", got)
+	}
+
+	for _, ref := range []string{"file:" + hostFile, "env:MANAGEMENT_PASSWORD", "${TEST_MGMT_CLAUDE_KEY}"} {
+		rec = serveClientKeys(h, h.PutClaudeKeys, http.MethodPut, "/v0/management/claude-api-key", `[{"api-key":"`+ref+`"}]`)
+		if rec.Code != http.StatusForbidden {
+			t.Fatalf("PUT %s status = %d, want %d", ref, rec.Code, http.StatusForbidden)
+		}
+		if got := h.cfg.ClaudeKey[0].APIKey; got != "sk-from-env" {
+			t.Fatalf("api-key after refused PUT %s = %q, want the saved value", ref, got)
+		}
+	}
+
+	rec = serveClientKeys(h, h.PutConfigYAML, http.MethodPut, "/v0/management/config.yaml", "claude-api-key:\n  - api-key: file:"+hostFile+"\n")
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("PUT config.yaml with a new file reference status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	saved, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("read config: %v", err)
+	}
+	if strings.Contains(string(saved), hostFile) || strings.Contains(h.cfg.ClaudeKey[0].APIKey, "do-not-leak") {
+		t.Fatalf("refused reference reached the config:\n%s", saved)
+	}
+}
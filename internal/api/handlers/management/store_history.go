@@ -0,0 +1,51 @@
+package management
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/store"
+)
+
+const maxStoreHistoryLimit = 1000
+
+// storeHistorySource is implemented by token stores that keep revisions of the config and
+// auth records they persist.
+type storeHistorySource interface {
+	History(ctx context.Context, kind, id string, limit int) ([]store.SQLiteHistoryEntry, error)
+}
+
+// GetStoreHistory returns recorded revisions of the config ("kind=config") or of auth files
+// ("kind=auth", optionally narrowed with "id"), newest first.
+func (h *Handler) GetStoreHistory(c *gin.Context) {
+	source, ok := h.tokenStore.(storeHistorySource)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "store history unavailable for the configured store"})
+		return
+	}
+	kind := strings.TrimSpace(c.DefaultQuery("kind", store.SQLiteHistoryConfig))
+	if kind != store.SQLiteHistoryConfig && kind != store.SQLiteHistoryAuth {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid kind, want config or auth"})
+		return
+	}
+	limit := 0
+	if raw := strings.TrimSpace(c.Query("limit")); raw != "" {
+		parsed, errLimit := strconv.Atoi(raw)
+		if errLimit != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return
+		}
+		limit = min(parsed, maxStoreHistoryLimit)
+	}
+
+	entries, err := source.History(c.Request.Context(), kind, strings.TrimSpace(c.Query("id")), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to read store history: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"entries": entries, "count": len(entries)})
+}
@@ -2,11 +2,14 @@ package management
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/usage"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
 )
 
 type usageExportPayload struct {
@@ -26,6 +29,9 @@ func (h *Handler) GetUsageStatistics(c *gin.Context) {
 	if h != nil && h.usageStats != nil {
 		snapshot = h.usageStats.Snapshot()
 	}
+	if !roleAllows(callerRole(c), config.ManagementRoleAdmin) {
+		snapshot.APIs = maskUsageAPIKeys(snapshot.APIs)
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"usage":           snapshot,
 		"failed_requests": snapshot.FailureCount,
@@ -77,3 +83,22 @@ func (h *Handler) ImportUsageStatistics(c *gin.Context) {
 		"failed_requests": snapshot.FailureCount,
 	})
 }
+
+// maskUsageAPIKeys re-keys per-API statistics by masked client keys for non-admin callers.
+func maskUsageAPIKeys(apis map[string]usage.APISnapshot) map[string]usage.APISnapshot {
+	if len(apis) == 0 {
+		return apis
+	}
+	out := make(map[string]usage.APISnapshot, len(apis))
+	for key, snapshot := range apis {
+		masked := util.HideAPIKey(key)
+		for suffix := 2; ; suffix++ {
+			if _, exists := out[masked]; !exists {
+				break
+			}
+			masked = fmt.Sprintf("%s#%d", util.HideAPIKey(key), suffix)
+		}
+		out[masked] = snapshot
+	}
+	return out
+}
@@ -7,6 +7,8 @@ package api
 import (
 	"context"
 	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"net/http"
@@ -25,10 +27,12 @@ import (
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/api/modules"
 	ampmodule "github.com/router-for-me/CLIProxyAPI/v6/internal/api/modules/amp"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/auth/kiro"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/batch"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/managementasset"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/responsestore"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/usage"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
 	sdkaccess "github.com/router-for-me/CLIProxyAPI/v6/sdk/access"
@@ -179,6 +183,12 @@ type Server struct {
 	keepAliveOnTimeout func()
 	keepAliveHeartbeat chan struct{}
 	keepAliveStop      chan struct{}
+
+	// responsesStore keeps /v1/responses calls when responses-store is enabled.
+	responsesStore responsestore.Store
+
+	// batches runs the batch APIs when batches are enabled.
+	batches *batch.Manager
 }
 
 // NewServer creates and initializes a new API server instance.
@@ -276,8 +286,20 @@ func NewServer(cfg *config.Config, authManager *auth.Manager, accessManager *sdk
 	}
 	s.localPassword = optionState.localPassword
 
+	if store, errStore := responsestore.New(context.Background(), cfg.ResponsesStore); errStore != nil {
+		log.Errorf("Failed to initialize responses store: %v", errStore)
+	} else {
+		s.responsesStore = store
+	}
+	if cfg.Batches.Enabled {
+		s.batches = newBatchManager(cfg, configFilePath, authManager)
+	}
+
 	// Setup routes
 	s.setupRoutes()
+	if s.batches != nil {
+		s.batches.Start(context.Background())
+	}
 
 	// Register Amp module using V2 interface with Context
 	s.ampModule = ampmodule.NewLegacy(accessManager, AuthMiddleware(accessManager))
@@ -298,7 +320,7 @@ func NewServer(cfg *config.Config, authManager *auth.Manager, accessManager *sdk
 
 	// Register management routes when configuration or environment secrets are available,
 	// or when a local management password is provided (e.g. TUI mode).
-	hasManagementSecret := cfg.RemoteManagement.SecretKey != "" || envManagementSecret || s.localPassword != ""
+	hasManagementSecret := cfg.RemoteManagement.HasManagementSecret() || envManagementSecret || s.localPassword != ""
 	s.managementRoutesEnabled.Store(hasManagementSecret)
 	if hasManagementSecret {
 		s.registerManagementRoutes()
@@ -322,6 +344,26 @@ func NewServer(cfg *config.Config, authManager *auth.Manager, accessManager *sdk
 	return s
 }
 
+// newBatchManager opens the batch queue configured by cfg. Batch items pause while interactive
+// requests wait in the request queue. It returns nil when the queue cannot be opened.
+func newBatchManager(cfg *config.Config, configFilePath string, authManager *auth.Manager) *batch.Manager {
+	dir := cfg.Batches.Normalized().Dir
+	if dir == "" {
+		dir = filepath.Join(filepath.Dir(configFilePath), "batches")
+	} else if resolved, err := util.ResolveAuthDir(dir); err == nil {
+		dir = resolved
+	}
+	manager, err := batch.New(cfg.Batches, dir)
+	if err != nil {
+		log.Errorf("Failed to initialize batch queue: %v", err)
+		return nil
+	}
+	if authManager != nil {
+		manager.SetBusy(func() bool { return authManager.QueueDepth() > 0 })
+	}
+	return manager
+}
+
 // setupRoutes configures the API routes for the server.
 // It defines the endpoints and associates them with their respective handlers.
 func (s *Server) setupRoutes() {
@@ -334,7 +376,16 @@ func (s *Server) setupRoutes() {
 	geminiHandlers := gemini.NewGeminiAPIHandler(s.handlers)
 	geminiCLIHandlers := gemini.NewGeminiCLIAPIHandler(s.handlers)
 	claudeCodeHandlers := claude.NewClaudeCodeAPIHandler(s.handlers)
+	if s.batches != nil {
+		claudeCodeHandlers.SetBatchManager(s.batches)
+	}
 	openaiResponsesHandlers := openai.NewOpenAIResponsesAPIHandler(s.handlers)
+	if s.responsesStore != nil {
+		openaiResponsesHandlers.SetResponseStore(s.responsesStore)
+	}
+	if s.batches != nil {
+		openaiHandlers.SetBatchManager(s.batches, openaiResponsesHandlers)
+	}
 	ollamaHandlers := ollama.NewOllamaAPIHandler(s.handlers)
 
 	// OpenAI compatible API routes
@@ -344,11 +395,29 @@ func (s *Server) setupRoutes() {
 		v1.GET("/models", s.unifiedModelsHandler(openaiHandlers, claudeCodeHandlers))
 		v1.POST("/chat/completions", openaiHandlers.ChatCompletions)
 		v1.POST("/completions", openaiHandlers.Completions)
+		v1.POST("/embeddings", openaiHandlers.Embeddings)
+		v1.POST("/files", openaiHandlers.UploadFile)
+		v1.GET("/files", openaiHandlers.ListFiles)
+		v1.GET("/files/:id", openaiHandlers.GetFile)
+		v1.GET("/files/:id/content", openaiHandlers.GetFileContent)
+		v1.DELETE("/files/:id", openaiHandlers.DeleteFile)
+		v1.POST("/batches", openaiHandlers.CreateBatch)
+		v1.GET("/batches", openaiHandlers.ListBatches)
+		v1.GET("/batches/:id", openaiHandlers.GetBatch)
+		v1.POST("/batches/:id/cancel", openaiHandlers.CancelBatch)
 		v1.POST("/messages", claudeCodeHandlers.ClaudeMessages)
 		v1.POST("/messages/count_tokens", claudeCodeHandlers.ClaudeCountTokens)
+		v1.POST("/messages/batches", claudeCodeHandlers.CreateMessageBatch)
+		v1.GET("/messages/batches", claudeCodeHandlers.ListMessageBatches)
+		v1.GET("/messages/batches/:id", claudeCodeHandlers.GetMessageBatch)
+		v1.POST("/messages/batches/:id/cancel", claudeCodeHandlers.CancelMessageBatch)
+		v1.GET("/messages/batches/:id/results", claudeCodeHandlers.MessageBatchResults)
 		v1.GET("/responses", openaiResponsesHandlers.ResponsesWebsocket)
 		v1.POST("/responses", openaiResponsesHandlers.Responses)
 		v1.POST("/responses/compact", openaiResponsesHandlers.Compact)
+		v1.GET("/responses/:id", openaiResponsesHandlers.GetResponse)
+		v1.DELETE("/responses/:id", openaiResponsesHandlers.DeleteResponse)
+		v1.GET("/responses/:id/input_items", openaiResponsesHandlers.ListResponseInputItems)
 	}
 
 	// Gemini compatible API routes
@@ -391,6 +460,11 @@ func (s *Server) setupRoutes() {
 		apiGroup.POST("/chat", ollamaHandlers.Chat)
 		apiGroup.POST("/generate", ollamaHandlers.Generate)
 		apiGroup.POST("/show", ollamaHandlers.Show)
+		apiGroup.POST("/embed", ollamaHandlers.Embed)
+		apiGroup.POST("/embeddings", ollamaHandlers.Embeddings)
+		apiGroup.GET("/ps", ollamaHandlers.PS)
+		apiGroup.POST("/pull", ollamaHandlers.Pull)
+		apiGroup.DELETE("/delete", ollamaHandlers.Delete)
 	}
 
 	// Also support /ollama/api/* paths
@@ -400,6 +474,11 @@ func (s *Server) setupRoutes() {
 		ollamaGroup.POST("/chat", ollamaHandlers.Chat)
 		ollamaGroup.POST("/generate", ollamaHandlers.Generate)
 		ollamaGroup.POST("/show", ollamaHandlers.Show)
+		ollamaGroup.POST("/embed", ollamaHandlers.Embed)
+		ollamaGroup.POST("/embeddings", ollamaHandlers.Embeddings)
+		ollamaGroup.GET("/ps", ollamaHandlers.PS)
+		ollamaGroup.POST("/pull", ollamaHandlers.Pull)
+		ollamaGroup.DELETE("/delete", ollamaHandlers.Delete)
 	}
 
 	// OAuth callback endpoints (reuse main server port)
@@ -554,8 +633,10 @@ func (s *Server) registerManagementRoutes() {
 	log.Info("management routes registered after secret key configuration")
 
 	mgmt := s.engine.Group("/v0/management")
-	mgmt.Use(s.managementAvailabilityMiddleware(), s.mgmt.Middleware())
+	mgmt.Use(s.managementAvailabilityMiddleware(), s.mgmt.Middleware(), s.mgmt.AuditMiddleware())
 	{
+		mgmt.GET("/audit", s.mgmt.GetAuditLog)
+		mgmt.GET("/store-history", s.mgmt.GetStoreHistory)
 		mgmt.GET("/usage", s.mgmt.GetUsageStatistics)
 		mgmt.GET("/usage/export", s.mgmt.ExportUsageStatistics)
 		mgmt.POST("/usage/import", s.mgmt.ImportUsageStatistics)
@@ -606,6 +687,12 @@ func (s *Server) registerManagementRoutes() {
 		mgmt.PATCH("/api-keys", s.mgmt.PatchAPIKeys)
 		mgmt.DELETE("/api-keys", s.mgmt.DeleteAPIKeys)
 
+		mgmt.GET("/client-keys", s.mgmt.GetClientKeys)
+		mgmt.POST("/client-keys", s.mgmt.PostClientKey)
+		mgmt.PUT("/client-keys", s.mgmt.PutClientKeys)
+		mgmt.PATCH("/client-keys", s.mgmt.PatchClientKey)
+		mgmt.DELETE("/client-keys", s.mgmt.DeleteClientKey)
+
 		mgmt.GET("/gemini-api-key", s.mgmt.GetGeminiKeys)
 		mgmt.PUT("/gemini-api-key", s.mgmt.PutGeminiKeys)
 		mgmt.PATCH("/gemini-api-key", s.mgmt.PatchGeminiKey)
@@ -875,6 +962,11 @@ func (s *Server) Start() error {
 		if cert == "" || key == "" {
 			return fmt.Errorf("failed to start HTTPS server: tls.cert or tls.key is empty")
 		}
+		tlsConfig, errTLS := clientAuthTLSConfig(s.cfg.TLS)
+		if errTLS != nil {
+			return fmt.Errorf("failed to start HTTPS server: %v", errTLS)
+		}
+		s.server.TLSConfig = tlsConfig
 		log.Debugf("Starting API server on %s with TLS", s.server.Addr)
 		if errServeTLS := s.server.ListenAndServeTLS(cert, key); errServeTLS != nil && !errors.Is(errServeTLS, http.ErrServerClosed) {
 			return fmt.Errorf("failed to start HTTPS server: %v", errServeTLS)
@@ -890,6 +982,36 @@ func (s *Server) Start() error {
 	return nil
 }
 
+// clientAuthTLSConfig builds the server TLS settings for client certificate verification.
+// It returns nil when tls.client-ca is not configured.
+func clientAuthTLSConfig(cfg config.TLSConfig) (*tls.Config, error) {
+	caPath := strings.TrimSpace(cfg.ClientCA)
+	if caPath == "" {
+		return nil, nil
+	}
+	pemData, errRead := os.ReadFile(caPath)
+	if errRead != nil {
+		return nil, fmt.Errorf("read tls.client-ca: %w", errRead)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pemData) {
+		return nil, fmt.Errorf("tls.client-ca %s contains no PEM certificates", caPath)
+	}
+	clientAuth := tls.VerifyClientCertIfGiven
+	switch strings.ToLower(strings.TrimSpace(cfg.ClientAuth)) {
+	case "", "verify-if-given":
+	case "require":
+		clientAuth = tls.RequireAndVerifyClientCert
+	default:
+		return nil, fmt.Errorf("unsupported tls.client-auth %q, want verify-if-given or require", cfg.ClientAuth)
+	}
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ClientCAs:  pool,
+		ClientAuth: clientAuth,
+	}, nil
+}
+
 // Stop gracefully shuts down the API server without interrupting any
 // active connections.
 //
@@ -912,6 +1034,14 @@ func (s *Server) Stop(ctx context.Context) error {
 	if err := s.server.Shutdown(ctx); err != nil {
 		return fmt.Errorf("failed to shutdown HTTP server: %v", err)
 	}
+	if s.batches != nil {
+		s.batches.Stop()
+	}
+	if s.responsesStore != nil {
+		if err := s.responsesStore.Close(); err != nil {
+			log.Warnf("failed to close responses store: %v", err)
+		}
+	}
 
 	log.Debug("API server stopped")
 	return nil
@@ -1012,9 +1142,9 @@ func (s *Server) UpdateClients(cfg *config.Config) {
 
 	prevSecretEmpty := true
 	if oldCfg != nil {
-		prevSecretEmpty = oldCfg.RemoteManagement.SecretKey == ""
+		prevSecretEmpty = !oldCfg.RemoteManagement.HasManagementSecret()
 	}
-	newSecretEmpty := cfg.RemoteManagement.SecretKey == ""
+	newSecretEmpty := !cfg.RemoteManagement.HasManagementSecret()
 	if s.envManagementSecret {
 		s.registerManagementRoutes()
 		if s.managementRoutesEnabled.CompareAndSwap(false, true) {
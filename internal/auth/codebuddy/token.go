@@ -4,6 +4,7 @@
 package codebuddy
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -45,21 +46,27 @@ type CodeBuddyTokenStorage struct {
 //   - error: An error if the operation fails, nil otherwise
 func (s *CodeBuddyTokenStorage) SaveTokenToFile(authFilePath string) error {
 	misc.LogSavingCredentials(authFilePath)
-	s.Type = "codebuddy"
-	if err := os.MkdirAll(filepath.Dir(authFilePath), 0700); err != nil {
+	data, err := s.MarshalToken()
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(authFilePath), 0o700); err != nil {
 		return fmt.Errorf("failed to create directory: %w", err)
 	}
-
-	f, err := os.OpenFile(authFilePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
-	if err != nil {
-		return fmt.Errorf("failed to create token file: %w", err)
+	if err = os.WriteFile(authFilePath, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write token file: %w", err)
 	}
-	defer func() {
-		_ = f.Close()
-	}()
+	return nil
+}
 
-	if err = json.NewEncoder(f).Encode(s); err != nil {
-		return fmt.Errorf("failed to write token to file: %w", err)
+// MarshalToken returns the JSON document SaveTokenToFile writes, so callers can encrypt it
+// before anything reaches the disk.
+func (s *CodeBuddyTokenStorage) MarshalToken() ([]byte, error) {
+	s.Type = "codebuddy"
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	if err := enc.Encode(s); err != nil {
+		return nil, fmt.Errorf("failed to encode token: %w", err)
 	}
-	return nil
+	return buf.Bytes(), nil
 }
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/tokencrypt"
 )
 
 // KiroTokenStorage holds the persistent token data for Kiro authentication.
@@ -39,26 +41,32 @@ type KiroTokenStorage struct {
 
 // SaveTokenToFile persists the token storage to the specified file path.
 func (s *KiroTokenStorage) SaveTokenToFile(authFilePath string) error {
-	dir := filepath.Dir(authFilePath)
-	if err := os.MkdirAll(dir, 0700); err != nil {
+	data, err := s.MarshalToken()
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(authFilePath), 0o700); err != nil {
 		return fmt.Errorf("failed to create directory: %w", err)
 	}
+	if err = os.WriteFile(authFilePath, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write token file: %w", err)
+	}
+	return nil
+}
 
+// MarshalToken returns the JSON document SaveTokenToFile writes, so callers can encrypt it
+// before anything reaches the disk.
+func (s *KiroTokenStorage) MarshalToken() ([]byte, error) {
 	data, err := json.MarshalIndent(s, "", "  ")
 	if err != nil {
-		return fmt.Errorf("failed to marshal token storage: %w", err)
-	}
-
-	if err := os.WriteFile(authFilePath, data, 0600); err != nil {
-		return fmt.Errorf("failed to write token file: %w", err)
+		return nil, fmt.Errorf("failed to marshal token storage: %w", err)
 	}
-
-	return nil
+	return data, nil
 }
 
 // LoadFromFile loads token storage from the specified file path.
 func LoadFromFile(authFilePath string) (*KiroTokenStorage, error) {
-	data, err := os.ReadFile(authFilePath)
+	data, err := tokencrypt.ReadFile(authFilePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read token file: %w", err)
 	}
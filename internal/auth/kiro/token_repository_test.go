@@ -0,0 +1,57 @@
+package kiro
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/tokencrypt"
+)
+
+func TestFileTokenRepositoryHandlesSealedFiles(t *testing.T) {
+	ring, err := tokencrypt.NewKeyring([]tokencrypt.Key{{ID: "k1", Secret: bytes.Repeat([]byte{7}, 32)}}, true)
+	if err != nil {
+		t.Fatalf("NewKeyring() error = %v", err)
+	}
+	tokencrypt.SetDefault(ring)
+	t.Cleanup(func() { tokencrypt.SetDefault(nil) })
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "kiro-idc.json")
+	sealed, err := ring.Seal([]byte(`{"type":"kiro","auth_method":"IdC","access_token":"old","refresh_token":"refresh","client_id":"client","expires_at":"2000-01-01T00:00:00Z"}`))
+	if err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+	if err = os.WriteFile(path, sealed, 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	repo := NewFileTokenRepository(dir)
+	tokens := repo.FindOldestUnverified(0)
+	if len(tokens) != 1 || tokens[0].RefreshToken != "refresh" {
+		t.Fatalf("FindOldestUnverified() = %+v, want the sealed token", tokens)
+	}
+
+	token := tokens[0]
+	token.AccessToken = "new"
+	token.ExpiresAt = time.Now().Add(time.Hour)
+	if err = repo.UpdateToken(token); err != nil {
+		t.Fatalf("UpdateToken() error = %v", err)
+	}
+	stored, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if !tokencrypt.IsSealed(stored) {
+		t.Fatalf("updated file was written in plaintext: %s", stored)
+	}
+	loaded, err := LoadFromFile(path)
+	if err != nil {
+		t.Fatalf("LoadFromFile() error = %v", err)
+	}
+	if loaded.AccessToken != "new" || loaded.ClientID != "client" {
+		t.Fatalf("LoadFromFile() = %+v, want updated token with preserved fields", loaded)
+	}
+}
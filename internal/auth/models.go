@@ -2,6 +2,15 @@
 // It includes interfaces and implementations for token storage and authentication methods.
 package auth
 
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/misc"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/tokencrypt"
+)
+
 // TokenStorage defines the interface for storing authentication tokens.
 // Implementations of this interface should provide methods to persist
 // authentication tokens to a file system location.
@@ -15,3 +24,40 @@ type TokenStorage interface {
 	//   - error: An error if the save operation fails, nil otherwise
 	SaveTokenToFile(authFilePath string) error
 }
+
+// TokenMarshaler is implemented by token storages that can serialize themselves without
+// touching the file system. SaveSealedToken relies on it to encrypt tokens before they are
+// written.
+type TokenMarshaler interface {
+	// MarshalToken returns the document SaveTokenToFile would write.
+	MarshalToken() ([]byte, error)
+}
+
+// SaveSealedToken persists storage at authFilePath, encrypted with the default token keyring
+// when sealing is enabled. Storages implementing TokenMarshaler are sealed in memory and
+// written through a temporary file, so the plaintext never reaches the disk. Other storages
+// fall back to SaveTokenToFile followed by an in-place rewrite.
+func SaveSealedToken(storage TokenStorage, authFilePath string) error {
+	marshaler, ok := storage.(TokenMarshaler)
+	if !ok {
+		if err := storage.SaveTokenToFile(authFilePath); err != nil {
+			return err
+		}
+		if _, err := tokencrypt.SealFile(authFilePath); err != nil {
+			return fmt.Errorf("failed to encrypt token file: %w", err)
+		}
+		return nil
+	}
+	misc.LogSavingCredentials(authFilePath)
+	data, err := marshaler.MarshalToken()
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(authFilePath), 0o700); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	if err = tokencrypt.WriteFile(authFilePath, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write token file: %w", err)
+	}
+	return nil
+}
@@ -3,13 +3,13 @@
 package vertex
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/misc"
-	log "github.com/sirupsen/logrus"
 )
 
 // VertexCredentialStorage stores the service account JSON for Vertex AI access.
@@ -40,31 +40,34 @@ type VertexCredentialStorage struct {
 // It ensures the parent directory exists and logs the operation for transparency.
 func (s *VertexCredentialStorage) SaveTokenToFile(authFilePath string) error {
 	misc.LogSavingCredentials(authFilePath)
-	if s == nil {
-		return fmt.Errorf("vertex credential: storage is nil")
+	data, err := s.MarshalToken()
+	if err != nil {
+		return err
 	}
-	if s.ServiceAccount == nil {
-		return fmt.Errorf("vertex credential: service account content is empty")
+	if err = os.MkdirAll(filepath.Dir(authFilePath), 0o700); err != nil {
+		return fmt.Errorf("vertex credential: create directory failed: %w", err)
 	}
-	// Ensure we tag the file with the provider type.
-	s.Type = "vertex"
+	if err = os.WriteFile(authFilePath, data, 0o600); err != nil {
+		return fmt.Errorf("vertex credential: write file failed: %w", err)
+	}
+	return nil
+}
 
-	if err := os.MkdirAll(filepath.Dir(authFilePath), 0o700); err != nil {
-		return fmt.Errorf("vertex credential: create directory failed: %w", err)
+// MarshalToken returns the JSON document SaveTokenToFile writes, so callers can encrypt it
+// before anything reaches the disk.
+func (s *VertexCredentialStorage) MarshalToken() ([]byte, error) {
+	if s == nil {
+		return nil, fmt.Errorf("vertex credential: storage is nil")
 	}
-	f, err := os.Create(authFilePath)
-	if err != nil {
-		return fmt.Errorf("vertex credential: create file failed: %w", err)
+	if s.ServiceAccount == nil {
+		return nil, fmt.Errorf("vertex credential: service account content is empty")
 	}
-	defer func() {
-		if errClose := f.Close(); errClose != nil {
-			log.Errorf("vertex credential: failed to close file: %v", errClose)
-		}
-	}()
-	enc := json.NewEncoder(f)
+	s.Type = "vertex"
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
 	enc.SetIndent("", "  ")
-	if err = enc.Encode(s); err != nil {
-		return fmt.Errorf("vertex credential: encode failed: %w", err)
+	if err := enc.Encode(s); err != nil {
+		return nil, fmt.Errorf("vertex credential: encode failed: %w", err)
 	}
-	return nil
+	return buf.Bytes(), nil
 }
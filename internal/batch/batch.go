@@ -0,0 +1,382 @@
+// Package batch implements a persistent local job queue for the batch APIs. A batch is a list of
+// independent requests that run in the background through a Runner supplied by the API handler;
+// jobs survive restarts and resume where they stopped.
+package batch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// Job statuses.
+const (
+	StatusInProgress = "in_progress"
+	StatusCanceling  = "canceling"
+	StatusEnded      = "ended"
+)
+
+// Result types.
+const (
+	ResultSucceeded = "succeeded"
+	ResultErrored   = "errored"
+	ResultCanceled  = "canceled"
+	ResultExpired   = "expired"
+)
+
+var (
+	// ErrNotFound is returned for unknown batches.
+	ErrNotFound = errors.New("batch: not found")
+	// ErrNoRunner is returned when no runner is registered for a batch kind.
+	ErrNoRunner = errors.New("batch: no runner registered for kind")
+)
+
+// Item is one request of a batch.
+type Item struct {
+	CustomID string          `json:"custom_id"`
+	Params   json.RawMessage `json:"params"`
+}
+
+// Result is the outcome of one item.
+type Result struct {
+	CustomID string `json:"custom_id"`
+	Type     string `json:"type"`
+	// StatusCode is the HTTP status of the final attempt of an errored item.
+	StatusCode int `json:"status_code,omitempty"`
+	// Body is the response body of a succeeded item.
+	Body json.RawMessage `json:"body,omitempty"`
+	// Error describes why an errored item failed.
+	Error string `json:"error,omitempty"`
+}
+
+// Counts tallies the items of a job by state.
+type Counts struct {
+	Processing int `json:"processing"`
+	Succeeded  int `json:"succeeded"`
+	Errored    int `json:"errored"`
+	Canceled   int `json:"canceled"`
+	Expired    int `json:"expired"`
+}
+
+func (c *Counts) add(resultType string) {
+	c.Processing--
+	switch resultType {
+	case ResultSucceeded:
+		c.Succeeded++
+	case ResultErrored:
+		c.Errored++
+	case ResultCanceled:
+		c.Canceled++
+	case ResultExpired:
+		c.Expired++
+	}
+}
+
+// Job describes a batch.
+type Job struct {
+	ID string `json:"id"`
+	// Kind selects the Runner and keeps the batches of different APIs apart.
+	Kind string `json:"kind"`
+	// Owner is the SHA-256 digest of the client key that created the batch; the key itself is
+	// never stored. Items run on its behalf.
+	Owner     string `json:"owner,omitempty"`
+	OwnerName string `json:"owner_name,omitempty"`
+	// Principal is the identity that created the batch when it was not a configured key, such as
+	// an OIDC subject. Configured keys are resolved from Owner when items run.
+	Principal string `json:"principal,omitempty"`
+	Status    string `json:"status"`
+	Total     int    `json:"total"`
+	Counts    Counts `json:"-"`
+
+	CreatedAt         time.Time `json:"created_at"`
+	ExpiresAt         time.Time `json:"expires_at"`
+	CancelInitiatedAt time.Time `json:"cancel_initiated_at,omitempty"`
+	EndedAt           time.Time `json:"ended_at,omitempty"`
+
+	// Metadata carries API-specific fields of the batch.
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// Runner executes one item. It returns the response body, or the HTTP status and cause of a
+// failed attempt. Attempts failing with 429 are retried later until the batch expires, unless
+// the cause is wrapped with Final.
+type Runner func(ctx context.Context, job Job, item Item) ([]byte, int, error)
+
+// finalError marks a failure that retrying cannot fix.
+type finalError struct {
+	err error
+}
+
+func (e finalError) Error() string { return e.err.Error() }
+
+func (e finalError) Unwrap() error { return e.err }
+
+// Final marks err as a permanent rejection, such as a client key over its budget, so the item is
+// recorded as errored right away even when its status is 429.
+func Final(err error) error {
+	if err == nil {
+		return nil
+	}
+	return finalError{err: err}
+}
+
+func isFinal(err error) bool {
+	var final finalError
+	return errors.As(err, &final)
+}
+
+// Spec describes a batch to create.
+type Spec struct {
+	Kind string
+	// IDPrefix is prepended to the generated batch id, e.g. "msgbatch_".
+	IDPrefix  string
+	Owner     string
+	OwnerName string
+	Principal string
+	Items     []Item
+	Metadata  map[string]string
+}
+
+// jobState is the in-memory state of a job.
+type jobState struct {
+	job Job
+	// recorded holds the custom ids that already have a result.
+	recorded map[string]struct{}
+	// started is set once the dispatcher has begun handing out the job's items.
+	started bool
+	// draining is set while the janitor records canceled or expired results for the job.
+	draining bool
+}
+
+// fate returns the result type every item not yet started receives, or "" while items still run.
+func (js *jobState) fate(now time.Time) string {
+	switch {
+	case js.job.Status == StatusCanceling:
+		return ResultCanceled
+	case now.After(js.job.ExpiresAt):
+		return ResultExpired
+	default:
+		return ""
+	}
+}
+
+// Manager owns the batches stored in one directory and runs their items.
+type Manager struct {
+	dir         string
+	concurrency int
+	expiry      time.Duration
+	retention   time.Duration
+	files       *FileStore
+
+	mu      sync.Mutex
+	jobs    map[string]*jobState
+	runners map[string]Runner
+	busy    func() bool
+
+	wake   chan struct{}
+	work   chan task
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New opens the batches stored in dir, creating the directory if needed.
+func New(cfg config.BatchConfig, dir string) (*Manager, error) {
+	cfg = cfg.Normalized()
+	if dir == "" {
+		return nil, fmt.Errorf("batch: directory is required")
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("batch: create directory: %w", err)
+	}
+	m := &Manager{
+		dir:         dir,
+		concurrency: cfg.Concurrency,
+		expiry:      cfg.Expiry(),
+		retention:   cfg.Retention(),
+		jobs:        make(map[string]*jobState),
+		runners:     make(map[string]Runner),
+		wake:        make(chan struct{}, 1),
+		work:        make(chan task),
+	}
+	files, err := NewFileStore(filepath.Join(dir, filesDirName))
+	if err != nil {
+		return nil, err
+	}
+	m.files = files
+	if err = m.load(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Files returns the store for batch input and output files, kept beside the batches.
+func (m *Manager) Files() *FileStore {
+	return m.files
+}
+
+// RegisterRunner sets the runner for batches of kind. Runners must be registered before Start
+// so that resumed batches can run.
+func (m *Manager) RegisterRunner(kind string, runner Runner) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.runners[kind] = runner
+}
+
+// SetBusy installs a check that pauses batch work while it returns true, so batch items only
+// use capacity that interactive requests leave idle.
+func (m *Manager) SetBusy(busy func() bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.busy = busy
+}
+
+// Create stores a new batch and queues its items.
+func (m *Manager) Create(spec Spec) (Job, error) {
+	m.mu.Lock()
+	_, hasRunner := m.runners[spec.Kind]
+	m.mu.Unlock()
+	if !hasRunner {
+		return Job{}, fmt.Errorf("%w %q", ErrNoRunner, spec.Kind)
+	}
+
+	now := time.Now().UTC()
+	job := Job{
+		ID:        spec.IDPrefix + strings.ReplaceAll(uuid.NewString(), "-", ""),
+		Kind:      spec.Kind,
+		Owner:     spec.Owner,
+		OwnerName: spec.OwnerName,
+		Principal: spec.Principal,
+		Status:    StatusInProgress,
+		Total:     len(spec.Items),
+		Counts:    Counts{Processing: len(spec.Items)},
+		CreatedAt: now,
+		ExpiresAt: now.Add(m.expiry),
+		Metadata:  spec.Metadata,
+	}
+	if err := m.writeRequests(job.ID, spec.Items); err != nil {
+		_ = os.RemoveAll(m.jobDir(job.ID))
+		return Job{}, err
+	}
+	js := &jobState{job: job, recorded: make(map[string]struct{}, len(spec.Items))}
+	if job.Total == 0 {
+		js.job.Status = StatusEnded
+		js.job.EndedAt = now
+	}
+	if err := m.writeJob(&js.job); err != nil {
+		_ = os.RemoveAll(m.jobDir(job.ID))
+		return Job{}, err
+	}
+
+	m.mu.Lock()
+	m.jobs[job.ID] = js
+	snapshot := js.job
+	m.mu.Unlock()
+	m.signal()
+	return snapshot, nil
+}
+
+// Get returns the batch with the given id.
+func (m *Manager) Get(id string) (Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	js, ok := m.jobs[id]
+	if !ok {
+		return Job{}, ErrNotFound
+	}
+	return js.job, nil
+}
+
+// List returns the batches of kind owned by owner, newest first.
+func (m *Manager) List(kind, owner string) []Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	jobs := make([]Job, 0, len(m.jobs))
+	for _, js := range m.jobs {
+		if js.job.Kind != kind || js.job.Owner != owner {
+			continue
+		}
+		jobs = append(jobs, js.job)
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		if jobs[i].CreatedAt.Equal(jobs[j].CreatedAt) {
+			return jobs[i].ID > jobs[j].ID
+		}
+		return jobs[i].CreatedAt.After(jobs[j].CreatedAt)
+	})
+	return jobs
+}
+
+// Cancel stops a batch. Items already running finish; the others are recorded as canceled.
+func (m *Manager) Cancel(id string) (Job, error) {
+	m.mu.Lock()
+	js, ok := m.jobs[id]
+	if !ok {
+		m.mu.Unlock()
+		return Job{}, ErrNotFound
+	}
+	if js.job.Status != StatusInProgress {
+		snapshot := js.job
+		m.mu.Unlock()
+		return snapshot, nil
+	}
+	js.job.Status = StatusCanceling
+	js.job.CancelInitiatedAt = time.Now().UTC()
+	errWrite := m.writeJob(&js.job)
+	snapshot := js.job
+	m.mu.Unlock()
+	if errWrite != nil {
+		log.Warnf("batch %s: persist cancellation: %v", id, errWrite)
+	}
+	m.sweep()
+	return snapshot, nil
+}
+
+// Results calls fn for every recorded result of the batch, in completion order.
+func (m *Manager) Results(id string, fn func(Result) error) error {
+	if _, err := m.Get(id); err != nil {
+		return err
+	}
+	return m.readResults(id, fn)
+}
+
+// record stores the result of an item and ends the job once every item has a result.
+// Results for items that already have one are ignored.
+func (m *Manager) record(js *jobState, result Result) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, done := js.recorded[result.CustomID]; done || js.job.Status == StatusEnded {
+		return
+	}
+	if err := m.appendResult(js.job.ID, result); err != nil {
+		log.Errorf("batch %s: record result for %s: %v", js.job.ID, result.CustomID, err)
+		return
+	}
+	js.recorded[result.CustomID] = struct{}{}
+	js.job.Counts.add(result.Type)
+	if len(js.recorded) < js.job.Total {
+		return
+	}
+	js.job.Status = StatusEnded
+	js.job.EndedAt = time.Now().UTC()
+	if err := m.writeJob(&js.job); err != nil {
+		log.Errorf("batch %s: persist end: %v", js.job.ID, err)
+	}
+}
+
+func (m *Manager) signal() {
+	select {
+	case m.wake <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,229 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+)
+
+func waitForStatus(t *testing.T, m *Manager, id, status string) Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		job, err := m.Get(id)
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if job.Status == status {
+			return job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("batch %s status = %s, want %s", id, job.Status, status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func collectResults(t *testing.T, m *Manager, id string) map[string]Result {
+	t.Helper()
+	results := make(map[string]Result)
+	if err := m.Results(id, func(result Result) error {
+		results[result.CustomID] = result
+		return nil
+	}); err != nil {
+		t.Fatalf("Results: %v", err)
+	}
+	return results
+}
+
+func TestManagerRunsItemsAndRecordsResults(t *testing.T) {
+	m, err := New(config.BatchConfig{Concurrency: 2}, t.TempDir())
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	m.RegisterRunner("test", func(_ context.Context, _ Job, item Item) ([]byte, int, error) {
+		if item.CustomID == "bad" {
+			return nil, http.StatusBadRequest, errors.New("invalid")
+		}
+		return []byte(`{"ok":true}`), 0, nil
+	})
+	m.Start(context.Background())
+	t.Cleanup(m.Stop)
+
+	job, err := m.Create(Spec{Kind: "test", Owner: "key", Items: []Item{
+		{CustomID: "a", Params: []byte(`{}`)},
+		{CustomID: "b", Params: []byte(`{}`)},
+		{CustomID: "bad", Params: []byte(`{}`)},
+	}})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	job = waitForStatus(t, m, job.ID, StatusEnded)
+	if job.Counts.Succeeded != 2 || job.Counts.Errored != 1 || job.Counts.Processing != 0 {
+		t.Fatalf("counts = %+v", job.Counts)
+	}
+	results := collectResults(t, m, job.ID)
+	if results["bad"].StatusCode != http.StatusBadRequest || string(results["a"].Body) != `{"ok":true}` {
+		t.Fatalf("results = %+v", results)
+	}
+	if jobs := m.List("test", "other"); len(jobs) != 0 {
+		t.Fatalf("List for another owner returned %d batches", len(jobs))
+	}
+}
+
+func TestManagerCancelAndResume(t *testing.T) {
+	dir := t.TempDir()
+	m, err := New(config.BatchConfig{Concurrency: 1}, dir)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	m.RegisterRunner("test", func(context.Context, Job, Item) ([]byte, int, error) { return []byte(`{}`), 0, nil })
+	// Not started: nothing runs, so the batch stays in progress until it is reopened.
+	job, err := m.Create(Spec{Kind: "test", Items: []Item{{CustomID: "a", Params: []byte(`{}`)}, {CustomID: "b", Params: []byte(`{}`)}}})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	canceled, err := m.Create(Spec{Kind: "test", Items: []Item{{CustomID: "c", Params: []byte(`{}`)}}})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if _, err = m.Cancel(canceled.ID); err != nil {
+		t.Fatalf("Cancel: %v", err)
+	}
+	waitForStatus(t, m, canceled.ID, StatusEnded)
+	if results := collectResults(t, m, canceled.ID); results["c"].Type != ResultCanceled {
+		t.Fatalf("canceled results = %+v", results)
+	}
+
+	reopened, err := New(config.BatchConfig{Concurrency: 1}, dir)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	var runs atomic.Int32
+	reopened.RegisterRunner("test", func(context.Context, Job, Item) ([]byte, int, error) {
+		runs.Add(1)
+		return []byte(`{}`), 0, nil
+	})
+	reopened.Start(context.Background())
+	t.Cleanup(reopened.Stop)
+	resumed := waitForStatus(t, reopened, job.ID, StatusEnded)
+	if resumed.Counts.Succeeded != 2 || runs.Load() != 2 {
+		t.Fatalf("resumed counts = %+v, runs = %d", resumed.Counts, runs.Load())
+	}
+	if again, _ := reopened.Get(canceled.ID); again.Counts.Canceled != 1 {
+		t.Fatalf("reopened canceled counts = %+v", again.Counts)
+	}
+}
+
+func TestManagerRetriesRateLimitedItems(t *testing.T) {
+	m, err := New(config.BatchConfig{}, t.TempDir())
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	var attempts atomic.Int32
+	m.RegisterRunner("test", func(context.Context, Job, Item) ([]byte, int, error) {
+		if attempts.Add(1) == 1 {
+			return nil, http.StatusTooManyRequests, errors.New("busy")
+		}
+		return []byte(`{}`), 0, nil
+	})
+	m.Start(context.Background())
+	t.Cleanup(m.Stop)
+
+	job, err := m.Create(Spec{Kind: "test", Items: []Item{{CustomID: "a", Params: []byte(`{}`)}}})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	job = waitForStatus(t, m, job.ID, StatusEnded)
+	if job.Counts.Succeeded != 1 || attempts.Load() != 2 {
+		t.Fatalf("counts = %+v, attempts = %d", job.Counts, attempts.Load())
+	}
+}
+
+func TestManagerFailsRejectedItemsWithoutStallingOtherOwners(t *testing.T) {
+	m, err := New(config.BatchConfig{Concurrency: 1}, t.TempDir())
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	m.RegisterRunner("test", func(_ context.Context, job Job, _ Item) ([]byte, int, error) {
+		if job.Owner == "spent" {
+			return nil, http.StatusTooManyRequests, Final(errors.New("budget exceeded"))
+		}
+		return []byte(`{}`), 0, nil
+	})
+	m.Start(context.Background())
+	t.Cleanup(m.Stop)
+
+	spent, err := m.Create(Spec{Kind: "test", Owner: "spent", Items: []Item{{CustomID: "a", Params: []byte(`{}`)}, {CustomID: "b", Params: []byte(`{}`)}}})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	other, err := m.Create(Spec{Kind: "test", Owner: "other", Items: []Item{{CustomID: "c", Params: []byte(`{}`)}}})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if job := waitForStatus(t, m, other.ID, StatusEnded); job.Counts.Succeeded != 1 {
+		t.Fatalf("other owner counts = %+v", job.Counts)
+	}
+	job := waitForStatus(t, m, spent.ID, StatusEnded)
+	if job.Counts.Errored != 2 {
+		t.Fatalf("spent owner counts = %+v", job.Counts)
+	}
+	if results := collectResults(t, m, spent.ID); results["a"].StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("spent owner results = %+v", results)
+	}
+}
+
+func TestFileStorePersistsAndScopesFiles(t *testing.T) {
+	dir := t.TempDir()
+	m, err := New(config.BatchConfig{}, dir)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	file, err := m.Files().Create("key-a", "input.jsonl", "batch", strings.NewReader("{}\n"), 0)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if file.Bytes != 3 {
+		t.Fatalf("bytes = %d, want 3", file.Bytes)
+	}
+	if _, err = m.Files().Create("key-a", "big.jsonl", "batch", strings.NewReader("0123456789"), 4); !errors.Is(err, ErrFileTooLarge) {
+		t.Fatalf("oversized Create error = %v, want ErrFileTooLarge", err)
+	}
+
+	reopened, err := New(config.BatchConfig{}, dir)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	if got := reopened.List("", ""); len(got) != 0 {
+		t.Fatalf("files directory loaded as batches: %+v", got)
+	}
+	if got := reopened.Files().List("key-a", "batch"); len(got) != 1 || got[0].ID != file.ID {
+		t.Fatalf("List = %+v", got)
+	}
+	if got := reopened.Files().List("key-b", ""); len(got) != 0 {
+		t.Fatalf("other owner sees files: %+v", got)
+	}
+	content, err := reopened.Files().Open(file.ID)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	raw, _ := io.ReadAll(content)
+	_ = content.Close()
+	if string(raw) != "{}\n" {
+		t.Fatalf("content = %q", raw)
+	}
+	if err = reopened.Files().Delete(file.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err = reopened.Files().Get(file.ID); !errors.Is(err, ErrFileNotFound) {
+		t.Fatalf("Get after delete error = %v", err)
+	}
+}
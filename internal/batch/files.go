@@ -0,0 +1,191 @@
+package batch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+// filesDirName is the subdirectory of the batch directory holding uploaded and generated files.
+const filesDirName = "files"
+
+var (
+	// ErrFileNotFound is returned for unknown files.
+	ErrFileNotFound = errors.New("batch: file not found")
+	// ErrFileTooLarge is returned when an upload exceeds its size limit.
+	ErrFileTooLarge = errors.New("batch: file too large")
+)
+
+// File describes a stored file.
+type File struct {
+	ID string `json:"id"`
+	// Owner is the SHA-256 digest of the client key that uploaded the file.
+	Owner     string    `json:"owner,omitempty"`
+	Filename  string    `json:"filename"`
+	Purpose   string    `json:"purpose"`
+	Bytes     int64     `json:"bytes"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// FileStore keeps batch input and output files. Every file is "<id>.data" with its description
+// in "<id>.json".
+type FileStore struct {
+	dir   string
+	mu    sync.Mutex
+	files map[string]File
+}
+
+// NewFileStore opens the files stored in dir, creating the directory if needed.
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("batch: create files directory: %w", err)
+	}
+	s := &FileStore{dir: dir, files: make(map[string]File)}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("batch: read files directory: %w", err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		raw, errRead := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if errRead != nil {
+			log.Warnf("batch: skipping file %s: %v", entry.Name(), errRead)
+			continue
+		}
+		var file File
+		if errDecode := json.Unmarshal(raw, &file); errDecode != nil || file.ID+".json" != entry.Name() {
+			log.Warnf("batch: skipping file %s: invalid description", entry.Name())
+			continue
+		}
+		s.files[file.ID] = file
+	}
+	return s, nil
+}
+
+// Create stores the content read from r under a new id. Content beyond maxBytes fails with
+// ErrFileTooLarge; maxBytes <= 0 means no limit.
+func (s *FileStore) Create(owner, filename, purpose string, r io.Reader, maxBytes int64) (File, error) {
+	return s.Put("file-"+strings.ReplaceAll(uuid.NewString(), "-", ""), owner, filename, purpose, r, maxBytes)
+}
+
+// Put stores the content read from r under id, replacing any file with that id.
+func (s *FileStore) Put(id, owner, filename, purpose string, r io.Reader, maxBytes int64) (File, error) {
+	if id == "" || strings.ContainsAny(id, `/\`) || strings.HasPrefix(id, ".") {
+		return File{}, fmt.Errorf("batch: invalid file id %q", id)
+	}
+	tmp, err := os.CreateTemp(s.dir, ".tmp-*")
+	if err != nil {
+		return File{}, fmt.Errorf("batch: create temp file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	src := r
+	if maxBytes > 0 {
+		src = io.LimitReader(r, maxBytes+1)
+	}
+	written, errCopy := io.Copy(tmp, src)
+	errClose := tmp.Close()
+	if err = errors.Join(errCopy, errClose); err != nil {
+		return File{}, fmt.Errorf("batch: write file: %w", err)
+	}
+	if maxBytes > 0 && written > maxBytes {
+		return File{}, ErrFileTooLarge
+	}
+
+	file := File{ID: id, Owner: owner, Filename: filename, Purpose: purpose, Bytes: written, CreatedAt: time.Now().UTC()}
+	raw, err := json.Marshal(&file)
+	if err != nil {
+		return File{}, fmt.Errorf("batch: marshal file: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err = os.Rename(tmp.Name(), s.dataPath(id)); err != nil {
+		return File{}, fmt.Errorf("batch: store file: %w", err)
+	}
+	if err = os.WriteFile(filepath.Join(s.dir, id+".json"), raw, 0o600); err != nil {
+		_ = os.Remove(s.dataPath(id))
+		return File{}, fmt.Errorf("batch: store file: %w", err)
+	}
+	s.files[id] = file
+	return file, nil
+}
+
+// Get returns the file with the given id.
+func (s *FileStore) Get(id string) (File, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	file, ok := s.files[id]
+	if !ok {
+		return File{}, ErrFileNotFound
+	}
+	return file, nil
+}
+
+// List returns the files owned by owner, newest first, optionally restricted to purpose.
+func (s *FileStore) List(owner, purpose string) []File {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	files := make([]File, 0, len(s.files))
+	for _, file := range s.files {
+		if file.Owner != owner || (purpose != "" && file.Purpose != purpose) {
+			continue
+		}
+		files = append(files, file)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].CreatedAt.Equal(files[j].CreatedAt) {
+			return files[i].ID > files[j].ID
+		}
+		return files[i].CreatedAt.After(files[j].CreatedAt)
+	})
+	return files
+}
+
+// Open returns the content of the file with the given id.
+func (s *FileStore) Open(id string) (io.ReadCloser, error) {
+	if _, err := s.Get(id); err != nil {
+		return nil, err
+	}
+	file, err := os.Open(s.dataPath(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrFileNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("batch: open file: %w", err)
+	}
+	return file, nil
+}
+
+// Delete removes the file with the given id.
+func (s *FileStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.files[id]; !ok {
+		return ErrFileNotFound
+	}
+	delete(s.files, id)
+	errMeta := os.Remove(filepath.Join(s.dir, id+".json"))
+	errData := os.Remove(s.dataPath(id))
+	for _, err := range []error{errMeta, errData} {
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("batch: delete file: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *FileStore) dataPath(id string) string {
+	return filepath.Join(s.dir, id+".data")
+}
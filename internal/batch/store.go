@@ -0,0 +1,217 @@
+package batch
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// On disk every batch is a directory holding batch.json (the job, rewritten on status
+// changes), requests.jsonl (the items, written once) and results.jsonl (appended per item).
+const (
+	jobFileName      = "batch.json"
+	requestsFileName = "requests.jsonl"
+	resultsFileName  = "results.jsonl"
+)
+
+func (m *Manager) jobDir(id string) string {
+	return filepath.Join(m.dir, id)
+}
+
+// writeJob atomically replaces batch.json of job.
+func (m *Manager) writeJob(job *Job) error {
+	raw, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("batch: marshal job: %w", err)
+	}
+	dir := m.jobDir(job.ID)
+	tmp, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("batch: create temp file: %w", err)
+	}
+	_, errWrite := tmp.Write(raw)
+	errClose := tmp.Close()
+	if err = errors.Join(errWrite, errClose); err == nil {
+		err = os.Rename(tmp.Name(), filepath.Join(dir, jobFileName))
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("batch: write job: %w", err)
+	}
+	return nil
+}
+
+func (m *Manager) writeRequests(id string, items []Item) error {
+	dir := m.jobDir(id)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("batch: create job directory: %w", err)
+	}
+	file, err := os.OpenFile(filepath.Join(dir, requestsFileName), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("batch: create requests file: %w", err)
+	}
+	w := bufio.NewWriter(file)
+	encoder := json.NewEncoder(w)
+	for i := range items {
+		if err = encoder.Encode(&items[i]); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if errClose := file.Close(); err == nil {
+		err = errClose
+	}
+	if err != nil {
+		return fmt.Errorf("batch: write requests: %w", err)
+	}
+	return nil
+}
+
+// readItems calls fn for every item of job id until fn returns false.
+func (m *Manager) readItems(id string, fn func(Item) bool) error {
+	file, err := os.Open(filepath.Join(m.jobDir(id), requestsFileName))
+	if err != nil {
+		return fmt.Errorf("batch: open requests: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+	return readLines(file, func(line []byte) bool {
+		var item Item
+		if errItem := json.Unmarshal(line, &item); errItem != nil {
+			log.Warnf("batch %s: skipping malformed request line: %v", id, errItem)
+			return true
+		}
+		return fn(item)
+	})
+}
+
+func (m *Manager) appendResult(id string, result Result) error {
+	raw, err := json.Marshal(&result)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(filepath.Join(m.jobDir(id), resultsFileName), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(append(raw, '\n'))
+	if errClose := file.Close(); err == nil {
+		err = errClose
+	}
+	return err
+}
+
+// readResults calls fn for every result of job id. A truncated last line, left by a crash
+// mid-write, is ignored.
+func (m *Manager) readResults(id string, fn func(Result) error) error {
+	file, err := os.Open(filepath.Join(m.jobDir(id), resultsFileName))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("batch: open results: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+	var errFn error
+	errRead := readLines(file, func(line []byte) bool {
+		var result Result
+		if json.Unmarshal(line, &result) != nil {
+			return true
+		}
+		errFn = fn(result)
+		return errFn == nil
+	})
+	if errFn != nil {
+		return errFn
+	}
+	return errRead
+}
+
+// readLines calls fn for every non-empty line of r until fn returns false.
+func readLines(r io.Reader, fn func([]byte) bool) error {
+	reader := bufio.NewReaderSize(r, 64*1024)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if trimmed := bytes.TrimSpace(line); len(trimmed) > 0 {
+			if !fn(trimmed) {
+				return nil
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// load reads every stored batch and rebuilds its counts from its results.
+func (m *Manager) load() error {
+	entries, err := os.ReadDir(m.dir)
+	if err != nil {
+		return fmt.Errorf("batch: read directory: %w", err)
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") || entry.Name() == filesDirName {
+			continue
+		}
+		raw, errRead := os.ReadFile(filepath.Join(m.dir, entry.Name(), jobFileName))
+		if errRead != nil {
+			log.Warnf("batch: skipping %s: %v", entry.Name(), errRead)
+			continue
+		}
+		var job Job
+		if errDecode := json.Unmarshal(raw, &job); errDecode != nil || job.ID != entry.Name() {
+			log.Warnf("batch: skipping %s: invalid job file", entry.Name())
+			continue
+		}
+		js := &jobState{job: job, recorded: make(map[string]struct{})}
+		js.job.Counts = Counts{Processing: job.Total}
+		if errResults := m.readResults(job.ID, func(result Result) error {
+			if _, dup := js.recorded[result.CustomID]; !dup {
+				js.recorded[result.CustomID] = struct{}{}
+				js.job.Counts.add(result.Type)
+			}
+			return nil
+		}); errResults != nil {
+			log.Warnf("batch %s: reading results: %v", job.ID, errResults)
+		}
+		if js.job.Status != StatusEnded && len(js.recorded) >= js.job.Total {
+			js.job.Status = StatusEnded
+			js.job.EndedAt = time.Now().UTC()
+			_ = m.writeJob(&js.job)
+		}
+		m.jobs[job.ID] = js
+	}
+	return nil
+}
+
+// removeExpired deletes ended batches older than the retention period.
+func (m *Manager) removeExpired(now time.Time) {
+	m.mu.Lock()
+	var stale []string
+	for id, js := range m.jobs {
+		if js.job.Status == StatusEnded && now.Sub(js.job.EndedAt) > m.retention {
+			stale = append(stale, id)
+			delete(m.jobs, id)
+		}
+	}
+	m.mu.Unlock()
+	for _, id := range stale {
+		if err := os.RemoveAll(m.jobDir(id)); err != nil {
+			log.Warnf("batch %s: remove expired batch: %v", id, err)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package batch
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// idlePollInterval is how often paused dispatching rechecks whether it may continue.
+	idlePollInterval = time.Second
+	// sweepInterval is how often the janitor drains canceled and expired batches and removes
+	// batches past their retention.
+	sweepInterval = time.Minute
+
+	retryInitialBackoff = time.Second
+	retryMaxBackoff     = time.Minute
+)
+
+// task is one item handed from the dispatcher to a worker.
+type task struct {
+	js   *jobState
+	item Item
+}
+
+// Start launches the dispatcher, the workers and the janitor. Batches left unfinished by a
+// previous run resume.
+func (m *Manager) Start(ctx context.Context) {
+	ctx, m.cancel = context.WithCancel(ctx)
+	m.wg.Add(2 + m.concurrency)
+	go m.dispatchLoop(ctx)
+	go m.janitorLoop(ctx)
+	for i := 0; i < m.concurrency; i++ {
+		go m.workerLoop(ctx)
+	}
+	m.signal()
+}
+
+// Stop halts all batch work. Items interrupted mid-request run again on the next Start.
+func (m *Manager) Stop() {
+	if m.cancel == nil {
+		return
+	}
+	m.cancel()
+	m.wg.Wait()
+}
+
+// dispatchLoop hands out the items of one batch at a time, oldest batch first.
+func (m *Manager) dispatchLoop(ctx context.Context) {
+	defer m.wg.Done()
+	for {
+		js := m.nextJob()
+		if js == nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-m.wake:
+			}
+			continue
+		}
+		if errRead := m.readItems(js.job.ID, func(item Item) bool {
+			return m.dispatch(ctx, js, item)
+		}); errRead != nil {
+			log.Errorf("batch %s: %v", js.job.ID, errRead)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// nextJob returns the oldest unfinished batch whose items have not been handed out yet.
+func (m *Manager) nextJob() *jobState {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var pending []*jobState
+	for _, js := range m.jobs {
+		if !js.started && js.job.Status != StatusEnded {
+			pending = append(pending, js)
+		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].job.CreatedAt.Before(pending[j].job.CreatedAt) })
+	pending[0].started = true
+	return pending[0]
+}
+
+// dispatch hands item to a worker once batch work may run, or records its fate when the batch
+// was canceled or expired. It returns false when ctx is done.
+func (m *Manager) dispatch(ctx context.Context, js *jobState, item Item) bool {
+	for {
+		m.mu.Lock()
+		_, done := js.recorded[item.CustomID]
+		fate := js.fate(time.Now())
+		busy := m.busy != nil && m.busy()
+		m.mu.Unlock()
+		if done {
+			return true
+		}
+		if fate != "" {
+			m.record(js, Result{CustomID: item.CustomID, Type: fate})
+			return true
+		}
+		if !busy {
+			select {
+			case m.work <- task{js: js, item: item}:
+				return true
+			case <-ctx.Done():
+				return false
+			case <-time.After(idlePollInterval):
+				continue
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(idlePollInterval):
+		}
+	}
+}
+
+func (m *Manager) workerLoop(ctx context.Context) {
+	defer m.wg.Done()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case t := <-m.work:
+			m.run(ctx, t)
+		}
+	}
+}
+
+// run executes one item, retrying transiently rate-limited attempts until the batch is canceled
+// or expires.
+func (m *Manager) run(ctx context.Context, t task) {
+	backoff := retryInitialBackoff
+	for {
+		m.mu.Lock()
+		fate := t.js.fate(time.Now())
+		job := t.js.job
+		runner := m.runners[job.Kind]
+		m.mu.Unlock()
+		if fate != "" {
+			m.record(t.js, Result{CustomID: t.item.CustomID, Type: fate})
+			return
+		}
+		if runner == nil {
+			m.record(t.js, Result{CustomID: t.item.CustomID, Type: ResultErrored, StatusCode: http.StatusInternalServerError, Error: ErrNoRunner.Error()})
+			return
+		}
+
+		body, status, err := runner(ctx, job, t.item)
+		if ctx.Err() != nil {
+			return
+		}
+		if err == nil {
+			m.record(t.js, Result{CustomID: t.item.CustomID, Type: ResultSucceeded, Body: body})
+			return
+		}
+		if status != http.StatusTooManyRequests || isFinal(err) {
+			m.record(t.js, Result{CustomID: t.item.CustomID, Type: ResultErrored, StatusCode: status, Error: err.Error()})
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, retryMaxBackoff)
+	}
+}
+
+func (m *Manager) janitorLoop(ctx context.Context) {
+	defer m.wg.Done()
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+	m.sweep()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.sweep()
+		}
+	}
+}
+
+// sweep records the fate of batches that were canceled or expired before the dispatcher reached
+// them, and removes batches past their retention.
+func (m *Manager) sweep() {
+	now := time.Now()
+	m.mu.Lock()
+	var drain []*jobState
+	for _, js := range m.jobs {
+		if js.started || js.draining || js.job.Status == StatusEnded || js.fate(now) == "" {
+			continue
+		}
+		js.draining = true
+		drain = append(drain, js)
+	}
+	m.mu.Unlock()
+
+	for _, js := range drain {
+		go m.drain(js)
+	}
+	m.removeExpired(now)
+}
+
+func (m *Manager) drain(js *jobState) {
+	defer func() {
+		m.mu.Lock()
+		js.draining = false
+		m.mu.Unlock()
+	}()
+	if err := m.readItems(js.job.ID, func(item Item) bool {
+		m.mu.Lock()
+		fate := js.fate(time.Now())
+		m.mu.Unlock()
+		m.record(js, Result{CustomID: item.CustomID, Type: fate})
+		return true
+	}); err != nil {
+		log.Errorf("batch %s: %v", js.job.ID, err)
+	}
+}
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/tokencrypt"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	sdkAuth "github.com/router-for-me/CLIProxyAPI/v6/sdk/auth"
+	log "github.com/sirupsen/logrus"
+)
+
+// DoMigrateTokenEncryption rewrites every stored auth file so it matches the token-encryption
+// settings: plaintext files are encrypted, files under an older key are re-encrypted with the
+// primary key, and with encryption disabled (but keys configured) files are decrypted again.
+// Files are saved through the registered token store so remote stores receive the result.
+func DoMigrateTokenEncryption(cfg *config.Config) {
+	if cfg == nil {
+		cfg = &config.Config{}
+	}
+	if resolved, errResolve := util.ResolveAuthDir(cfg.AuthDir); errResolve == nil {
+		cfg.AuthDir = resolved
+	}
+	ring := tokencrypt.Default()
+	if ring == nil {
+		log.Error("token-encryption: no keys configured; set token-encryption in the config first")
+		return
+	}
+
+	store := sdkAuth.GetTokenStore()
+	if setter, ok := store.(interface{ SetBaseDir(string) }); ok {
+		setter.SetBaseDir(cfg.AuthDir)
+	}
+	ctx := context.Background()
+	auths, errList := store.List(ctx)
+	if errList != nil {
+		log.Errorf("token-encryption: list auth files failed: %v", errList)
+		return
+	}
+
+	migrated, failed := 0, 0
+	for _, auth := range auths {
+		if auth == nil || auth.Metadata == nil {
+			continue
+		}
+		if _, errSave := store.Save(ctx, auth); errSave != nil {
+			failed++
+			log.Errorf("token-encryption: rewrite %s failed: %v", auth.ID, errSave)
+			continue
+		}
+		migrated++
+	}
+	action := "encrypted"
+	if !ring.Sealing() {
+		action = "decrypted"
+	}
+	fmt.Printf("Token encryption migration complete: %d auth file(s) %s or already up to date, %d failed\n", migrated, action, failed)
+}
@@ -0,0 +1,54 @@
+package config
+
+import "encoding/json"
+
+// AvailabilitySchedule restricts when a credential may be used by the scheduler.
+// A credential with no schedule is always available.
+type AvailabilitySchedule struct {
+	// Timezone is the IANA zone used to interpret Windows (default UTC).
+	Timezone string `yaml:"timezone,omitempty" json:"timezone,omitempty"`
+
+	// Windows lists the periods in which the credential may be used.
+	// An empty list means every day, all day.
+	Windows []AvailabilityWindow `yaml:"windows,omitempty" json:"windows,omitempty"`
+
+	// ReservePercent keeps this share of the upstream rate-limit window for other users of
+	// the account: the credential is skipped once the reported remaining budget falls below it.
+	ReservePercent int `yaml:"reserve-percent,omitempty" json:"reserve-percent,omitempty"`
+}
+
+// AvailabilityWindow is one recurring period of availability.
+type AvailabilityWindow struct {
+	// Days selects weekdays using names and ranges, e.g. "mon-fri" or "sat,sun".
+	// Empty or "*" means every day.
+	Days string `yaml:"days,omitempty" json:"days,omitempty"`
+
+	// Start is the local start time as HH:MM (default 00:00).
+	Start string `yaml:"start,omitempty" json:"start,omitempty"`
+
+	// End is the local end time as HH:MM (default 24:00). An End at or before Start
+	// makes the window run past midnight into the following day.
+	End string `yaml:"end,omitempty" json:"end,omitempty"`
+}
+
+// IsZero reports whether the schedule imposes no restriction.
+func (s *AvailabilitySchedule) IsZero() bool {
+	return s == nil || (len(s.Windows) == 0 && s.ReservePercent <= 0)
+}
+
+// UnmarshalJSON accepts reserve_percent as an alias so auth files can use their usual snake_case keys.
+func (s *AvailabilitySchedule) UnmarshalJSON(data []byte) error {
+	type plain AvailabilitySchedule
+	var aux struct {
+		plain
+		ReservePercentSnake *int `json:"reserve_percent,omitempty"`
+	}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	*s = AvailabilitySchedule(aux.plain)
+	if aux.ReservePercentSnake != nil && s.ReservePercent == 0 {
+		s.ReservePercent = *aux.ReservePercentSnake
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultBatchConcurrency is how many batch items run at once when no concurrency is configured.
+	DefaultBatchConcurrency = 4
+	// DefaultBatchExpiry is how long a batch may run before its unfinished items expire.
+	DefaultBatchExpiry = 24 * time.Hour
+	// DefaultBatchRetention is how long finished batches and their results are kept.
+	DefaultBatchRetention = 29 * 24 * time.Hour
+)
+
+// BatchConfig configures the local job queue behind the batch APIs (/v1/messages/batches).
+// Batch items run through the normal request path at low priority: they never wait in the
+// request queue and pause while interactive requests are queued.
+type BatchConfig struct {
+	// Enabled exposes the batch endpoints and starts the queue.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// Dir holds the batch jobs. Defaults to a "batches" directory next to the config file.
+	Dir string `yaml:"dir,omitempty" json:"dir,omitempty"`
+
+	// Concurrency is how many batch items run at once. Defaults to 4.
+	Concurrency int `yaml:"concurrency,omitempty" json:"concurrency,omitempty"`
+
+	// ExpiryHours is how long a batch may run before its unfinished items expire. Defaults to 24.
+	ExpiryHours int `yaml:"expiry-hours,omitempty" json:"expiry-hours,omitempty"`
+
+	// RetentionDays is how long finished batches and their results are kept. Defaults to 29.
+	RetentionDays int `yaml:"retention-days,omitempty" json:"retention-days,omitempty"`
+}
+
+// Normalized returns a copy with trimmed fields and defaults applied.
+func (c BatchConfig) Normalized() BatchConfig {
+	c.Dir = strings.TrimSpace(c.Dir)
+	if c.Concurrency <= 0 {
+		c.Concurrency = DefaultBatchConcurrency
+	}
+	return c
+}
+
+// Expiry returns how long a batch may run before its unfinished items expire.
+func (c BatchConfig) Expiry() time.Duration {
+	if c.ExpiryHours <= 0 {
+		return DefaultBatchExpiry
+	}
+	return time.Duration(c.ExpiryHours) * time.Hour
+}
+
+// Retention returns how long finished batches and their results are kept.
+func (c BatchConfig) Retention() time.Duration {
+	if c.RetentionDays <= 0 {
+		return DefaultBatchRetention
+	}
+	return time.Duration(c.RetentionDays) * 24 * time.Hour
+}
@@ -0,0 +1,240 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ClientKey is a managed client API key with its own identity and usage policy.
+type ClientKey struct {
+	// Key is the secret clients present, exactly like an entry in api-keys.
+	Key string `yaml:"key" json:"key"`
+
+	// Name identifies the key in logs, usage records and the management API.
+	Name string `yaml:"name,omitempty" json:"name,omitempty"`
+
+	// Owner records who the key was issued to.
+	Owner string `yaml:"owner,omitempty" json:"owner,omitempty"`
+
+	// Models restricts the models the key may request. Entries support '*' wildcards
+	// and match case-insensitively. Empty allows every model.
+	Models []string `yaml:"models,omitempty" json:"models,omitempty"`
+
+	// Endpoints restricts the request paths the key may call, e.g. "/v1/chat/completions"
+	// or "/v1beta/*". Empty allows every endpoint.
+	Endpoints []string `yaml:"endpoints,omitempty" json:"endpoints,omitempty"`
+
+	// ExpiresAt is an RFC 3339 timestamp or a YYYY-MM-DD date after which the key is rejected.
+	// A date expires at the end of that day in UTC. Empty never expires.
+	ExpiresAt string `yaml:"expires-at,omitempty" json:"expires-at,omitempty"`
+
+	// Budget limits how much the key may consume per UTC day and month.
+	Budget ClientKeyBudget `yaml:"budget,omitempty" json:"budget,omitempty"`
+
+	// Disabled rejects the key without removing it.
+	Disabled bool `yaml:"disabled,omitempty" json:"disabled,omitempty"`
+}
+
+// ClientKeyBudget holds request and token limits for a client key. Zero means unlimited.
+type ClientKeyBudget struct {
+	DailyRequests   int64 `yaml:"daily-requests,omitempty" json:"daily-requests,omitempty"`
+	MonthlyRequests int64 `yaml:"monthly-requests,omitempty" json:"monthly-requests,omitempty"`
+	DailyTokens     int64 `yaml:"daily-tokens,omitempty" json:"daily-tokens,omitempty"`
+	MonthlyTokens   int64 `yaml:"monthly-tokens,omitempty" json:"monthly-tokens,omitempty"`
+}
+
+// IsZero reports whether the budget imposes no limit.
+func (b ClientKeyBudget) IsZero() bool {
+	return b.DailyRequests <= 0 && b.MonthlyRequests <= 0 && b.DailyTokens <= 0 && b.MonthlyTokens <= 0
+}
+
+// DisplayName returns the key name, falling back to a masked form of the key.
+func (k *ClientKey) DisplayName() string {
+	if k == nil {
+		return ""
+	}
+	if name := strings.TrimSpace(k.Name); name != "" {
+		return name
+	}
+	key := strings.TrimSpace(k.Key)
+	if len(key) <= 8 {
+		return "key-****"
+	}
+	return "key-" + key[:4] + "****" + key[len(key)-4:]
+}
+
+// Expiry parses ExpiresAt. It returns the zero time when the key never expires.
+func (k *ClientKey) Expiry() (time.Time, error) {
+	if k == nil {
+		return time.Time{}, nil
+	}
+	raw := strings.TrimSpace(k.ExpiresAt)
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	if parsed, err := time.Parse(time.RFC3339, raw); err == nil {
+		return parsed, nil
+	}
+	day, err := time.Parse(time.DateOnly, raw)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid expires-at %q, want RFC 3339 or YYYY-MM-DD", raw)
+	}
+	return day.AddDate(0, 0, 1), nil
+}
+
+// Expired reports whether the key has expired at now. Unparseable expiry dates count as expired.
+func (k *ClientKey) Expired(now time.Time) bool {
+	expiry, err := k.Expiry()
+	if err != nil {
+		return true
+	}
+	return !expiry.IsZero() && !now.Before(expiry)
+}
+
+// AllowsModel reports whether the key may request model.
+func (k *ClientKey) AllowsModel(model string) bool {
+	if k == nil || len(k.Models) == 0 {
+		return true
+	}
+	model = strings.ToLower(strings.TrimSpace(model))
+	for _, pattern := range k.Models {
+		if matchWildcardPattern(strings.ToLower(pattern), model) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsEndpoint reports whether the key may call the request path.
+func (k *ClientKey) AllowsEndpoint(path string) bool {
+	if k == nil || len(k.Endpoints) == 0 {
+		return true
+	}
+	for _, pattern := range k.Endpoints {
+		if matchWildcardPattern(pattern, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// FindClientKey returns the managed client key matching key, or nil.
+func (cfg *SDKConfig) FindClientKey(key string) *ClientKey {
+	if cfg == nil || key == "" {
+		return nil
+	}
+	for index := range cfg.ClientKeys {
+		if cfg.ClientKeys[index].Key == key {
+			return &cfg.ClientKeys[index]
+		}
+	}
+	return nil
+}
+
+// ClientKeyDigest returns the hex SHA-256 of a client API key. Records that belong to a client
+// store the digest instead of the key so that a leaked database or file does not leak keys.
+// An empty key yields an empty digest.
+func ClientKeyDigest(key string) string {
+	if key == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// FindKeyByDigest returns the managed client key or api-keys entry whose ClientKeyDigest is
+// digest. It reports false when no configured key matches.
+func (cfg *SDKConfig) FindKeyByDigest(digest string) (string, bool) {
+	if cfg == nil || digest == "" {
+		return "", false
+	}
+	for index := range cfg.ClientKeys {
+		if key := cfg.ClientKeys[index].Key; ClientKeyDigest(key) == digest {
+			return key, true
+		}
+	}
+	for _, key := range cfg.APIKeys {
+		if ClientKeyDigest(key) == digest {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// SanitizeClientKeys trims client key fields, drops entries without a key and removes
+// duplicate keys, keeping the first occurrence.
+func (cfg *SDKConfig) SanitizeClientKeys() {
+	if cfg == nil || len(cfg.ClientKeys) == 0 {
+		return
+	}
+	out := make([]ClientKey, 0, len(cfg.ClientKeys))
+	seen := make(map[string]struct{}, len(cfg.ClientKeys))
+	for _, entry := range cfg.ClientKeys {
+		entry.Key = strings.TrimSpace(entry.Key)
+		if entry.Key == "" {
+			continue
+		}
+		if _, exists := seen[entry.Key]; exists {
+			continue
+		}
+		seen[entry.Key] = struct{}{}
+		entry.Name = strings.TrimSpace(entry.Name)
+		entry.Owner = strings.TrimSpace(entry.Owner)
+		entry.ExpiresAt = strings.TrimSpace(entry.ExpiresAt)
+		entry.Models = trimNonEmpty(entry.Models)
+		entry.Endpoints = trimNonEmpty(entry.Endpoints)
+		entry.Budget.DailyRequests = max(entry.Budget.DailyRequests, 0)
+		entry.Budget.MonthlyRequests = max(entry.Budget.MonthlyRequests, 0)
+		entry.Budget.DailyTokens = max(entry.Budget.DailyTokens, 0)
+		entry.Budget.MonthlyTokens = max(entry.Budget.MonthlyTokens, 0)
+		out = append(out, entry)
+	}
+	cfg.ClientKeys = out
+}
+
+func trimNonEmpty(values []string) []string {
+	if len(values) == 0 {
+		return nil
+	}
+	out := make([]string, 0, len(values))
+	for _, value := range values {
+		if trimmed := strings.TrimSpace(value); trimmed != "" {
+			out = append(out, trimmed)
+		}
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+// matchWildcardPattern matches value against a pattern where '*' matches any substring.
+func matchWildcardPattern(pattern, value string) bool {
+	if pattern == "*" {
+		return true
+	}
+	if !strings.Contains(pattern, "*") {
+		return pattern == value
+	}
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(value, parts[0]) {
+		return false
+	}
+	value = value[len(parts[0]):]
+	last := parts[len(parts)-1]
+	if !strings.HasSuffix(value, last) {
+		return false
+	}
+	value = value[:len(value)-len(last)]
+	for _, segment := range parts[1 : len(parts)-1] {
+		index := strings.Index(value, segment)
+		if index < 0 {
+			return false
+		}
+		value = value[index+len(segment):]
+	}
+	return true
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClientKey_ExpiryAndPatterns(t *testing.T) {
+	key := &ClientKey{
+		Key:       "sk-test",
+		Models:    []string{"claude-*", "gpt-5"},
+		Endpoints: []string{"/v1/chat/completions", "/v1beta/*"},
+		ExpiresAt: "2026-03-01",
+	}
+
+	if key.Expired(time.Date(2026, 3, 1, 23, 59, 0, 0, time.UTC)) {
+		t.Fatalf("key expired during its last day")
+	}
+	if !key.Expired(time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("key not expired after its last day")
+	}
+	if !key.AllowsModel("Claude-Opus-4") || !key.AllowsModel("gpt-5") || key.AllowsModel("gpt-5-mini") {
+		t.Fatalf("unexpected model allowlist result")
+	}
+	if !key.AllowsEndpoint("/v1beta/models/gemini:generateContent") || key.AllowsEndpoint("/v1/messages") {
+		t.Fatalf("unexpected endpoint allowlist result")
+	}
+
+	key.ExpiresAt = "soon"
+	if _, err := key.Expiry(); err == nil {
+		t.Fatalf("Expiry() error = nil for invalid value")
+	}
+	if !key.Expired(time.Now()) {
+		t.Fatalf("key with invalid expiry is not treated as expired")
+	}
+}
+
+func TestSanitizeClientKeys_DropsEmptyAndDuplicateKeys(t *testing.T) {
+	cfg := &SDKConfig{ClientKeys: []ClientKey{
+		{Key: "  sk-a ", Name: " a ", Models: []string{" ", "m"}},
+		{Key: ""},
+		{Key: "sk-a", Name: "dup"},
+		{Key: "sk-b", Budget: ClientKeyBudget{DailyRequests: -1}},
+	}}
+	cfg.SanitizeClientKeys()
+
+	if len(cfg.ClientKeys) != 2 {
+		t.Fatalf("client keys = %d, want 2", len(cfg.ClientKeys))
+	}
+	if got := cfg.ClientKeys[0]; got.Key != "sk-a" || got.Name != "a" || len(got.Models) != 1 {
+		t.Fatalf("first entry = %+v", got)
+	}
+	if cfg.ClientKeys[1].Budget.DailyRequests != 0 {
+		t.Fatalf("negative budget not cleared")
+	}
+}
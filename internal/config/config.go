@@ -42,6 +42,9 @@ type Config struct {
 	// AuthDir is the directory where authentication token files are stored.
 	AuthDir string `yaml:"auth-dir" json:"-"`
 
+	// TokenEncryption configures envelope encryption of auth files at rest.
+	TokenEncryption TokenEncryptionConfig `yaml:"token-encryption,omitempty" json:"token-encryption,omitempty"`
+
 	// Debug enables or disables debug-level logging and other debug features.
 	Debug bool `yaml:"debug" json:"debug"`
 
@@ -135,15 +138,30 @@ type Config struct {
 	// gemini-api-key, codex-api-key, claude-api-key, openai-compatibility, vertex-api-key, and ampcode.
 	OAuthModelAlias map[string][]OAuthModelAlias `yaml:"oauth-model-alias,omitempty" json:"oauth-model-alias,omitempty"`
 
+	// ModelFallbacks maps a requested model to an ordered list of fallback models.
+	// When every credential for the requested model is cooling down, the request is
+	// retried against each fallback in order, translated for the fallback's provider.
+	ModelFallbacks map[string][]string `yaml:"model-fallbacks,omitempty" json:"model-fallbacks,omitempty"`
+
 	// Payload defines default and override rules for provider payload parameters.
 	Payload PayloadConfig `yaml:"payload" json:"payload"`
 
+	// ResponsesStore configures server-side storage of /v1/responses calls.
+	ResponsesStore ResponsesStoreConfig `yaml:"responses-store,omitempty" json:"responses-store,omitempty"`
+
+	// Batches configures the local job queue behind the batch APIs.
+	Batches BatchConfig `yaml:"batches,omitempty" json:"batches,omitempty"`
+
 	// IncognitoBrowser enables opening OAuth URLs in incognito/private browsing mode.
 	// This is useful when you want to login with a different account without logging out
 	// from your current session. Default: false.
 	IncognitoBrowser bool `yaml:"incognito-browser" json:"incognito-browser"`
 
 	legacyMigrationPending bool `yaml:"-" json:"-"`
+
+	// secretRefs maps the YAML path of each value resolved from an env:, file: or ${VAR}
+	// reference to that reference.
+	secretRefs map[string]secretRef `yaml:"-" json:"-"`
 }
 
 // ClaudeHeaderDefaults configures default header values injected into Claude API requests.
@@ -177,6 +195,12 @@ type TLSConfig struct {
 	Cert string `yaml:"cert" json:"cert"`
 	// Key is the path to the TLS private key file.
 	Key string `yaml:"key" json:"key"`
+	// ClientCA is the path to a PEM bundle of CAs trusted to issue client certificates.
+	// When set, client certificates are verified and can authenticate through the mtls provider.
+	ClientCA string `yaml:"client-ca,omitempty" json:"client-ca,omitempty"`
+	// ClientAuth selects how client certificates are requested when ClientCA is set:
+	// "verify-if-given" (default) accepts clients without a certificate, "require" rejects them.
+	ClientAuth string `yaml:"client-auth,omitempty" json:"client-auth,omitempty"`
 }
 
 // PprofConfig holds pprof HTTP server settings.
@@ -201,6 +225,10 @@ type RemoteManagement struct {
 	// PanelGitHubRepository overrides the GitHub repository used to fetch the management panel asset.
 	// Accepts either a repository URL (https://github.com/org/repo) or an API releases endpoint.
 	PanelGitHubRepository string `yaml:"panel-github-repository"`
+	// Keys lists additional role-bound management keys. The secret-key always has the admin role.
+	Keys []ManagementKey `yaml:"keys,omitempty"`
+	// DisableAuditLog stops recording management mutations to logs/audit when true.
+	DisableAuditLog bool `yaml:"disable-audit-log,omitempty"`
 }
 
 // QuotaExceeded defines the behavior when API quota limits are exceeded.
@@ -220,8 +248,62 @@ type QuotaExceeded struct {
 // RoutingConfig configures how credentials are selected for requests.
 type RoutingConfig struct {
 	// Strategy selects the credential selection strategy.
-	// Supported values: "round-robin" (default), "fill-first".
+	// Supported values: "round-robin" (default), "fill-first", "adaptive".
+	// "adaptive" prefers the credential with the best recent latency and failure rate.
 	Strategy string `yaml:"strategy,omitempty" json:"strategy,omitempty"`
+
+	// SessionAffinity keeps multi-turn conversations on the same credential so
+	// upstream prompt caches can be reused.
+	SessionAffinity SessionAffinityConfig `yaml:"session-affinity,omitempty" json:"session-affinity,omitempty"`
+
+	// Hedging fires a second non-streaming attempt on another credential when the
+	// first has not answered within a latency percentile.
+	Hedging HedgingConfig `yaml:"hedging,omitempty" json:"hedging,omitempty"`
+
+	// Queue holds requests while every credential is busy or cooling down instead
+	// of failing them immediately.
+	Queue QueueConfig `yaml:"queue,omitempty" json:"queue,omitempty"`
+}
+
+// QueueConfig configures the bounded wait queue used when no credential is ready.
+type QueueConfig struct {
+	// Enabled turns on queueing.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// MaxDepth caps the number of queued requests across all clients (default 256).
+	MaxDepth int `yaml:"max-depth,omitempty" json:"max-depth,omitempty"`
+
+	// MaxPerClient caps the number of queued requests per client API key (default: no per-client cap).
+	MaxPerClient int `yaml:"max-per-client,omitempty" json:"max-per-client,omitempty"`
+
+	// TimeoutSeconds is the longest a request waits in the queue (default 60).
+	TimeoutSeconds int `yaml:"timeout-seconds,omitempty" json:"timeout-seconds,omitempty"`
+}
+
+// HedgingConfig configures hedged non-streaming requests.
+type HedgingConfig struct {
+	// Models lists model name patterns eligible for hedging; '*' matches any substring.
+	// Hedging is disabled when the list is empty.
+	Models []string `yaml:"models,omitempty" json:"models,omitempty"`
+
+	// Percentile selects the recent latency percentile used as the hedge delay (default 95).
+	Percentile float64 `yaml:"percentile,omitempty" json:"percentile,omitempty"`
+
+	// DelayMS is the hedge delay used until enough latency samples exist (default 2000).
+	DelayMS int `yaml:"delay-ms,omitempty" json:"delay-ms,omitempty"`
+
+	// MinDelayMS is the lower bound applied to the computed delay (default 250).
+	MinDelayMS int `yaml:"min-delay-ms,omitempty" json:"min-delay-ms,omitempty"`
+}
+
+// SessionAffinityConfig configures conversation-sticky credential selection.
+type SessionAffinityConfig struct {
+	// Enabled pins each conversation to the credential that served it last.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// TTLSeconds is how long a conversation stays pinned after its last request.
+	// Values <= 0 use the default of 3600 seconds.
+	TTLSeconds int `yaml:"ttl-seconds,omitempty" json:"ttl-seconds,omitempty"`
 }
 
 // OAuthModelAlias defines a model ID alias for a specific channel.
@@ -363,6 +445,17 @@ type ClaudeKey struct {
 	// Higher values are preferred; defaults to 0.
 	Priority int `yaml:"priority,omitempty" json:"priority,omitempty"`
 
+	// Weight sets this credential's relative share of traffic among ready credentials
+	// at the same priority level. Values <= 0 are treated as 1.
+	Weight int `yaml:"weight,omitempty" json:"weight,omitempty"`
+
+	// MaxConcurrency caps the number of requests this credential serves at once.
+	// Values <= 0 leave it unlimited.
+	MaxConcurrency int `yaml:"max-concurrency,omitempty" json:"max-concurrency,omitempty"`
+
+	// Availability restricts when this credential may be used.
+	Availability *AvailabilitySchedule `yaml:"availability,omitempty" json:"availability,omitempty"`
+
 	// Prefix optionally namespaces models for this credential (e.g., "teamA/claude-sonnet-4").
 	Prefix string `yaml:"prefix,omitempty" json:"prefix,omitempty"`
 
@@ -416,6 +509,17 @@ type CodexKey struct {
 	// Higher values are preferred; defaults to 0.
 	Priority int `yaml:"priority,omitempty" json:"priority,omitempty"`
 
+	// Weight sets this credential's relative share of traffic among ready credentials
+	// at the same priority level. Values <= 0 are treated as 1.
+	Weight int `yaml:"weight,omitempty" json:"weight,omitempty"`
+
+	// MaxConcurrency caps the number of requests this credential serves at once.
+	// Values <= 0 leave it unlimited.
+	MaxConcurrency int `yaml:"max-concurrency,omitempty" json:"max-concurrency,omitempty"`
+
+	// Availability restricts when this credential may be used.
+	Availability *AvailabilitySchedule `yaml:"availability,omitempty" json:"availability,omitempty"`
+
 	// Prefix optionally namespaces models for this credential (e.g., "teamA/gpt-5-codex").
 	Prefix string `yaml:"prefix,omitempty" json:"prefix,omitempty"`
 
@@ -464,6 +568,17 @@ type GeminiKey struct {
 	// Higher values are preferred; defaults to 0.
 	Priority int `yaml:"priority,omitempty" json:"priority,omitempty"`
 
+	// Weight sets this credential's relative share of traffic among ready credentials
+	// at the same priority level. Values <= 0 are treated as 1.
+	Weight int `yaml:"weight,omitempty" json:"weight,omitempty"`
+
+	// MaxConcurrency caps the number of requests this credential serves at once.
+	// Values <= 0 leave it unlimited.
+	MaxConcurrency int `yaml:"max-concurrency,omitempty" json:"max-concurrency,omitempty"`
+
+	// Availability restricts when this credential may be used.
+	Availability *AvailabilitySchedule `yaml:"availability,omitempty" json:"availability,omitempty"`
+
 	// Prefix optionally namespaces models for this credential (e.g., "teamA/gemini-3-pro-preview").
 	Prefix string `yaml:"prefix,omitempty" json:"prefix,omitempty"`
 
@@ -577,6 +692,17 @@ type OpenAICompatibilityAPIKey struct {
 
 	// ProxyURL overrides the global proxy setting for this API key if provided.
 	ProxyURL string `yaml:"proxy-url,omitempty" json:"proxy-url,omitempty"`
+
+	// Weight sets this key's relative share of traffic among ready credentials
+	// at the same priority level. Values <= 0 are treated as 1.
+	Weight int `yaml:"weight,omitempty" json:"weight,omitempty"`
+
+	// MaxConcurrency caps the number of requests this key serves at once.
+	// Values <= 0 leave it unlimited.
+	MaxConcurrency int `yaml:"max-concurrency,omitempty" json:"max-concurrency,omitempty"`
+
+	// Availability restricts when this key may be used.
+	Availability *AvailabilitySchedule `yaml:"availability,omitempty" json:"availability,omitempty"`
 }
 
 // OpenAICompatibilityModel represents a model configuration for OpenAI compatibility,
@@ -654,6 +780,11 @@ func LoadConfigOptional(configFile string, optional bool) (*Config, error) {
 		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
 
+	// Resolve env:, file: and ${VAR} secret references before anything inspects the values.
+	if err = cfg.ResolveSecretRefs(); err != nil {
+		return nil, fmt.Errorf("failed to resolve secret references: %w", err)
+	}
+
 	// NOTE: Startup legacy key migration is intentionally disabled.
 	// Reason: avoid mutating config.yaml during server startup.
 	// Re-enable the block below if automatic startup migration is needed again.
@@ -677,13 +808,21 @@ func LoadConfigOptional(configFile string, optional bool) (*Config, error) {
 		if errHash != nil {
 			return nil, fmt.Errorf("failed to hash remote management key: %w", errHash)
 		}
-		cfg.RemoteManagement.SecretKey = hashed
+		if _, isRef := cfg.SecretRef("remote-management.secret-key"); isRef {
+			// Keep the reference in the file; the hash only lives in memory.
+			cfg.rememberSecretRef("remote-management.secret-key", hashed)
+			cfg.RemoteManagement.SecretKey = hashed
+		} else {
+			cfg.RemoteManagement.SecretKey = hashed
 
-		// Persist the hashed value back to the config file to avoid re-hashing on next startup.
-		// Preserve YAML comments and ordering; update only the nested key.
-		_ = SaveConfigPreserveCommentsUpdateNestedScalar(configFile, []string{"remote-management", "secret-key"}, hashed)
+			// Persist the hashed value back to the config file to avoid re-hashing on next startup.
+			// Preserve YAML comments and ordering; update only the nested key.
+			_ = SaveConfigPreserveCommentsUpdateNestedScalar(configFile, []string{"remote-management", "secret-key"}, hashed)
+		}
 	}
 
+	cfg.SanitizeManagementKeys()
+
 	cfg.RemoteManagement.PanelGitHubRepository = strings.TrimSpace(cfg.RemoteManagement.PanelGitHubRepository)
 	if cfg.RemoteManagement.PanelGitHubRepository == "" {
 		cfg.RemoteManagement.PanelGitHubRepository = DefaultPanelGitHubRepository
@@ -736,6 +875,12 @@ func LoadConfigOptional(configFile string, optional bool) (*Config, error) {
 	// Normalize global OAuth model name aliases.
 	cfg.SanitizeOAuthModelAlias()
 
+	// Normalize cross-model fallback chains.
+	cfg.SanitizeModelFallbacks()
+
+	// Normalize managed client keys and drop entries without a key.
+	cfg.SanitizeClientKeys()
+
 	// Validate raw payload rules and drop invalid entries.
 	cfg.SanitizePayloadRules()
 
@@ -907,6 +1052,44 @@ func (cfg *Config) SanitizeOAuthModelAlias() {
 	cfg.OAuthModelAlias = out
 }
 
+// SanitizeModelFallbacks normalizes cross-model fallback chains.
+// Source models are trimmed and lower-cased; fallback entries are trimmed, deduplicated
+// case-insensitively, and entries pointing back at the source model are dropped.
+func (cfg *Config) SanitizeModelFallbacks() {
+	if cfg == nil || len(cfg.ModelFallbacks) == 0 {
+		return
+	}
+	out := make(map[string][]string, len(cfg.ModelFallbacks))
+	for rawModel, fallbacks := range cfg.ModelFallbacks {
+		model := strings.ToLower(strings.TrimSpace(rawModel))
+		if model == "" {
+			continue
+		}
+		seen := make(map[string]struct{}, len(fallbacks)+1)
+		seen[model] = struct{}{}
+		clean := out[model]
+		for _, existing := range clean {
+			seen[strings.ToLower(existing)] = struct{}{}
+		}
+		for _, fallback := range fallbacks {
+			trimmed := strings.TrimSpace(fallback)
+			if trimmed == "" {
+				continue
+			}
+			key := strings.ToLower(trimmed)
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			clean = append(clean, trimmed)
+		}
+		if len(clean) > 0 {
+			out[model] = clean
+		}
+	}
+	cfg.ModelFallbacks = out
+}
+
 // SanitizeOpenAICompatibility removes OpenAI-compatibility provider entries that are
 // not actionable, specifically those missing a BaseURL. It trims whitespace before
 // evaluation and preserves the relative order of remaining entries.
@@ -1141,6 +1324,8 @@ func SaveConfigPreserveComments(configFile string, cfg *Config) error {
 	if generated.Content[0].Kind != yaml.MappingNode {
 		return fmt.Errorf("expected generated root mapping node")
 	}
+	// Write secret references back instead of the values they resolved to.
+	restoreSecretRefsInNode(generated.Content[0], "", persistCfg.secretRefs)
 
 	// Remove deprecated sections before merging back the sanitized config.
 	removeLegacyAuthBlock(original.Content[0])
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Management API roles, from least to most privileged.
+const (
+	// ManagementRoleViewer may read usage, logs and non-secret settings.
+	ManagementRoleViewer = "viewer"
+	// ManagementRoleOperator may additionally run OAuth logins, toggle credentials and clear logs.
+	ManagementRoleOperator = "operator"
+	// ManagementRoleAdmin may do everything, including editing config and downloading tokens.
+	ManagementRoleAdmin = "admin"
+)
+
+// ManagementKey is an additional management API key bound to a role.
+type ManagementKey struct {
+	// Name identifies the key in logs and audit records.
+	Name string `yaml:"name,omitempty"`
+
+	// Key is the secret callers present, in plaintext or as a bcrypt hash.
+	Key string `yaml:"key"`
+
+	// Role is one of viewer, operator or admin.
+	Role string `yaml:"role"`
+}
+
+// HasManagementSecret reports whether any management credential is configured.
+func (r *RemoteManagement) HasManagementSecret() bool {
+	return r != nil && (r.SecretKey != "" || len(r.Keys) > 0)
+}
+
+// IsValidManagementRole reports whether role names a known management role.
+func IsValidManagementRole(role string) bool {
+	switch role {
+	case ManagementRoleViewer, ManagementRoleOperator, ManagementRoleAdmin:
+		return true
+	default:
+		return false
+	}
+}
+
+// SanitizeManagementKeys trims management keys, lower-cases roles and drops entries
+// without a key or with an unknown role.
+func (cfg *Config) SanitizeManagementKeys() {
+	if cfg == nil || len(cfg.RemoteManagement.Keys) == 0 {
+		return
+	}
+	out := make([]ManagementKey, 0, len(cfg.RemoteManagement.Keys))
+	for _, entry := range cfg.RemoteManagement.Keys {
+		entry.Name = strings.TrimSpace(entry.Name)
+		entry.Key = strings.TrimSpace(entry.Key)
+		entry.Role = strings.ToLower(strings.TrimSpace(entry.Role))
+		if entry.Key == "" {
+			continue
+		}
+		if !IsValidManagementRole(entry.Role) {
+			log.Warnf("remote-management.keys: ignoring key %q with unknown role %q", entry.Name, entry.Role)
+			continue
+		}
+		out = append(out, entry)
+	}
+	cfg.RemoteManagement.Keys = out
+}
@@ -0,0 +1,25 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSanitizeModelFallbacks_NormalizesChains(t *testing.T) {
+	cfg := &Config{
+		ModelFallbacks: map[string][]string{
+			" Claude-Opus-4-5 ": {" gemini-3-pro-preview ", "", "GPT-5", "gpt-5", "claude-opus-4-5"},
+			"   ":               {"gpt-5"},
+			"empty":             {" "},
+		},
+	}
+
+	cfg.SanitizeModelFallbacks()
+
+	want := map[string][]string{
+		"claude-opus-4-5": {"gemini-3-pro-preview", "GPT-5"},
+	}
+	if !reflect.DeepEqual(cfg.ModelFallbacks, want) {
+		t.Fatalf("ModelFallbacks = %#v, want %#v", cfg.ModelFallbacks, want)
+	}
+}
@@ -0,0 +1,77 @@
+package config
+
+import "strings"
+
+// MTLSConfig configures authentication by verified TLS client certificates.
+// It requires tls.enable and tls.client-ca.
+type MTLSConfig struct {
+	// Enabled turns the provider on.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// Principals maps certificates to principals. The first matching entry wins.
+	Principals []MTLSPrincipal `yaml:"principals,omitempty" json:"principals,omitempty"`
+
+	// AllowUnmapped accepts verified certificates that match no entry in Principals, using the
+	// subject common name (or the first SAN when it has none) as principal.
+	AllowUnmapped bool `yaml:"allow-unmapped,omitempty" json:"allow-unmapped,omitempty"`
+}
+
+// MTLSPrincipal maps client certificates to a principal. All set match fields must match.
+// Match fields support '*' wildcards.
+type MTLSPrincipal struct {
+	// Principal is the identity assigned to matching certificates.
+	Principal string `yaml:"principal" json:"principal"`
+
+	// CommonName matches the certificate subject common name.
+	CommonName string `yaml:"common-name,omitempty" json:"common-name,omitempty"`
+
+	// SAN matches any DNS, URI (e.g. spiffe://...), email or IP subject alternative name.
+	SAN string `yaml:"san,omitempty" json:"san,omitempty"`
+
+	// Organization matches any subject organization.
+	Organization string `yaml:"organization,omitempty" json:"organization,omitempty"`
+}
+
+// Normalized returns a copy with trimmed fields and entries without a principal or match field removed.
+func (c MTLSConfig) Normalized() MTLSConfig {
+	principals := make([]MTLSPrincipal, 0, len(c.Principals))
+	for _, entry := range c.Principals {
+		entry.Principal = strings.TrimSpace(entry.Principal)
+		entry.CommonName = strings.TrimSpace(entry.CommonName)
+		entry.SAN = strings.TrimSpace(entry.SAN)
+		entry.Organization = strings.TrimSpace(entry.Organization)
+		if entry.Principal == "" || (entry.CommonName == "" && entry.SAN == "" && entry.Organization == "") {
+			continue
+		}
+		principals = append(principals, entry)
+	}
+	c.Principals = principals
+	return c
+}
+
+// Matches reports whether a certificate with the given subject fields matches every
+// match field set on the entry.
+func (p *MTLSPrincipal) Matches(commonName string, sans, organizations []string) bool {
+	if p == nil || (p.CommonName == "" && p.SAN == "" && p.Organization == "") {
+		return false
+	}
+	if p.CommonName != "" && !matchWildcardPattern(p.CommonName, commonName) {
+		return false
+	}
+	if p.SAN != "" && !matchAnyWildcard(p.SAN, sans) {
+		return false
+	}
+	if p.Organization != "" && !matchAnyWildcard(p.Organization, organizations) {
+		return false
+	}
+	return true
+}
+
+func matchAnyWildcard(pattern string, values []string) bool {
+	for _, value := range values {
+		if matchWildcardPattern(pattern, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package config
+
+import "strings"
+
+// OIDCJWTConfig configures validation of bearer JWTs issued by an OpenID Connect provider.
+type OIDCJWTConfig struct {
+	// Enabled turns the provider on.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// Issuer is the expected "iss" claim. When no JWKS source is configured the keys are
+	// discovered from <issuer>/.well-known/openid-configuration.
+	Issuer string `yaml:"issuer,omitempty" json:"issuer,omitempty"`
+
+	// Audiences lists accepted "aud" values; a token must carry at least one. The provider stays
+	// disabled without audiences unless AllowAnyAudience is set.
+	Audiences []string `yaml:"audiences,omitempty" json:"audiences,omitempty"`
+
+	// AllowAnyAudience skips the "aud" check, accepting tokens the issuer minted for any client.
+	AllowAnyAudience bool `yaml:"allow-any-audience,omitempty" json:"allow-any-audience,omitempty"`
+
+	// JWKSURL is the URL of the JSON Web Key Set used to verify token signatures.
+	JWKSURL string `yaml:"jwks-url,omitempty" json:"jwks-url,omitempty"`
+
+	// JWKSFile is a local JSON Web Key Set, reloaded when the file changes. It takes precedence over JWKSURL.
+	JWKSFile string `yaml:"jwks-file,omitempty" json:"jwks-file,omitempty"`
+
+	// JWKSRefreshSeconds controls how often remote keys are refetched (default 3600).
+	// Unknown key IDs trigger an early refresh at most once per minute.
+	JWKSRefreshSeconds int `yaml:"jwks-refresh-seconds,omitempty" json:"jwks-refresh-seconds,omitempty"`
+
+	// ClockSkewSeconds is the leeway applied to exp, nbf and iat (default 60).
+	ClockSkewSeconds int `yaml:"clock-skew-seconds,omitempty" json:"clock-skew-seconds,omitempty"`
+
+	// PrincipalClaim selects the claim used as the request principal (default "sub").
+	PrincipalClaim string `yaml:"principal-claim,omitempty" json:"principal-claim,omitempty"`
+
+	// GroupsClaim selects the claim holding group membership (default "groups").
+	GroupsClaim string `yaml:"groups-claim,omitempty" json:"groups-claim,omitempty"`
+
+	// AllowedGroups, when set, rejects tokens that carry none of these groups.
+	AllowedGroups []string `yaml:"allowed-groups,omitempty" json:"allowed-groups,omitempty"`
+
+	// MetadataClaims lists additional claims copied into the access metadata, e.g. "email".
+	MetadataClaims []string `yaml:"metadata-claims,omitempty" json:"metadata-claims,omitempty"`
+}
+
+// Normalized returns a copy with trimmed fields and defaults applied.
+func (c OIDCJWTConfig) Normalized() OIDCJWTConfig {
+	c.Issuer = strings.TrimSpace(c.Issuer)
+	c.JWKSURL = strings.TrimSpace(c.JWKSURL)
+	c.JWKSFile = strings.TrimSpace(c.JWKSFile)
+	c.Audiences = trimNonEmpty(c.Audiences)
+	c.AllowedGroups = trimNonEmpty(c.AllowedGroups)
+	c.MetadataClaims = trimNonEmpty(c.MetadataClaims)
+	if c.JWKSRefreshSeconds <= 0 {
+		c.JWKSRefreshSeconds = 3600
+	}
+	if c.ClockSkewSeconds <= 0 {
+		c.ClockSkewSeconds = 60
+	}
+	if c.PrincipalClaim = strings.TrimSpace(c.PrincipalClaim); c.PrincipalClaim == "" {
+		c.PrincipalClaim = "sub"
+	}
+	if c.GroupsClaim = strings.TrimSpace(c.GroupsClaim); c.GroupsClaim == "" {
+		c.GroupsClaim = "groups"
+	}
+	return c
+}
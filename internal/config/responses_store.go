@@ -0,0 +1,68 @@
+package config
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	// ResponsesStoreMemory keeps stored responses in process memory.
+	ResponsesStoreMemory = "memory"
+	// ResponsesStoreFile keeps one JSON file per stored response.
+	ResponsesStoreFile = "file"
+	// ResponsesStorePostgres keeps stored responses in a PostgreSQL table.
+	ResponsesStorePostgres = "postgres"
+
+	// DefaultResponsesStoreTTL is how long stored responses are kept when no TTL is configured.
+	DefaultResponsesStoreTTL = 24 * time.Hour
+)
+
+// ResponsesStoreConfig configures server-side storage of /v1/responses calls, which backs
+// previous_response_id and the GET/DELETE /v1/responses/{id} endpoints for every backend.
+type ResponsesStoreConfig struct {
+	// Enabled turns the store on. Responses are stored unless the request sets "store": false.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// Type selects the backend: "memory" (default), "file" or "postgres".
+	Type string `yaml:"type,omitempty" json:"type,omitempty"`
+
+	// TTLSeconds is how long stored responses are kept. 0 uses the default of one day and a
+	// negative value keeps responses until they are deleted.
+	TTLSeconds int `yaml:"ttl-seconds,omitempty" json:"ttl-seconds,omitempty"`
+
+	// Dir is the directory of the file backend.
+	Dir string `yaml:"dir,omitempty" json:"dir,omitempty"`
+
+	// DSN is the connection string of the postgres backend.
+	DSN string `yaml:"dsn,omitempty" json:"-"`
+
+	// Table is the table of the postgres backend. Defaults to "responses_store".
+	Table string `yaml:"table,omitempty" json:"table,omitempty"`
+}
+
+// Normalized returns a copy with trimmed fields and defaults applied.
+func (c ResponsesStoreConfig) Normalized() ResponsesStoreConfig {
+	c.Type = strings.ToLower(strings.TrimSpace(c.Type))
+	if c.Type == "" {
+		c.Type = ResponsesStoreMemory
+	}
+	c.Dir = strings.TrimSpace(c.Dir)
+	c.DSN = strings.TrimSpace(c.DSN)
+	c.Table = strings.TrimSpace(c.Table)
+	if c.Table == "" {
+		c.Table = "responses_store"
+	}
+	return c
+}
+
+// TTL returns how long stored responses are kept; zero means forever.
+func (c ResponsesStoreConfig) TTL() time.Duration {
+	switch {
+	case c.TTLSeconds < 0:
+		return 0
+	case c.TTLSeconds == 0:
+		return DefaultResponsesStoreTTL
+	default:
+		return time.Duration(c.TTLSeconds) * time.Second
+	}
+}
@@ -30,6 +30,15 @@ type SDKConfig struct {
 	// APIKeys is a list of keys for authenticating clients to this proxy server.
 	APIKeys []string `yaml:"api-keys" json:"api-keys"`
 
+	// ClientKeys lists managed client keys carrying per-key identity and usage policy.
+	ClientKeys []ClientKey `yaml:"client-keys" json:"client-keys"`
+
+	// OIDCJWT configures the oidc-jwt access provider that accepts SSO-issued bearer JWTs.
+	OIDCJWT OIDCJWTConfig `yaml:"oidc-jwt,omitempty" json:"oidc-jwt,omitempty"`
+
+	// MTLS configures the mtls access provider that authenticates verified client certificates.
+	MTLS MTLSConfig `yaml:"mtls,omitempty" json:"mtls,omitempty"`
+
 	// PassthroughHeaders controls whether upstream response headers are forwarded to downstream clients.
 	// Default is false (disabled).
 	PassthroughHeaders bool `yaml:"passthrough-headers" json:"passthrough-headers"`
@@ -0,0 +1,375 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// SecretRefEnvPrefix marks a value read from the named environment variable, e.g. "env:ANTHROPIC_KEY".
+	SecretRefEnvPrefix = "env:"
+	// SecretRefFilePrefix marks a value read from a file, e.g. "file:/run/secrets/claude".
+	// Surrounding whitespace, including the trailing newline, is trimmed.
+	SecretRefFilePrefix = "file:"
+)
+
+// secretRef records where a secret reference was loaded and what it resolved to.
+type secretRef struct {
+	// jsonPath locates the value in the config's JSON rendering; it is empty when the field
+	// is not rendered as JSON.
+	jsonPath string
+	value    string
+	ref      string
+}
+
+// ResolveSecretRefs replaces every secret reference in the string fields of cfg with the
+// value it points to. A value is a reference when it is "env:NAME", "file:PATH", or
+// contains ${VAR} or ${VAR:-default} interpolations; "$${" produces a literal "${".
+// The references are remembered by their YAML path so that saving the config or rendering
+// it for the management API yields the reference again instead of the resolved secret.
+func (cfg *Config) ResolveSecretRefs() error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.secretRefs == nil {
+		cfg.secretRefs = make(map[string]secretRef)
+	}
+	return resolveSecretRefsIn(reflect.ValueOf(cfg).Elem(), "", "", cfg.secretRefs)
+}
+
+// SecretRef returns the reference the value at path (e.g. "claude-api-key[0].api-key") was
+// loaded from, if any.
+func (cfg *Config) SecretRef(path string) (string, bool) {
+	if cfg == nil {
+		return "", false
+	}
+	entry, ok := cfg.secretRefs[path]
+	return entry.ref, ok
+}
+
+// rememberSecretRef records that the value at path still stands for its reference after it
+// was transformed to value.
+func (cfg *Config) rememberSecretRef(path, value string) {
+	if cfg == nil || value == "" {
+		return
+	}
+	if entry, ok := cfg.secretRefs[path]; ok {
+		entry.value = value
+		cfg.secretRefs[path] = entry
+	}
+}
+
+// WithSecretRefs renders cfg as its JSON document with resolved secrets replaced by the
+// references they were loaded from.
+func (cfg *Config) WithSecretRefs() (any, error) {
+	if cfg == nil {
+		return map[string]any{}, nil
+	}
+	raw, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var doc any
+	if err = decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+	if len(cfg.secretRefs) == 0 {
+		return doc, nil
+	}
+	byJSONPath := make(map[string]secretRef, len(cfg.secretRefs))
+	for _, entry := range cfg.secretRefs {
+		if entry.jsonPath != "" {
+			byJSONPath[entry.jsonPath] = entry
+		}
+	}
+	return restoreSecretRefsInJSON(doc, "", byJSONPath), nil
+}
+
+// restoreSecretRefsInJSON swaps a value back to its reference only at the path it was loaded
+// from, and only while it still holds the resolved value.
+func restoreSecretRefsInJSON(value any, path string, refs map[string]secretRef) any {
+	switch typed := value.(type) {
+	case map[string]any:
+		for key, item := range typed {
+			typed[key] = restoreSecretRefsInJSON(item, joinSecretRefPath(path, key), refs)
+		}
+		return typed
+	case []any:
+		for index, item := range typed {
+			typed[index] = restoreSecretRefsInJSON(item, fmt.Sprintf("%s[%d]", path, index), refs)
+		}
+		return typed
+	case string:
+		if entry, ok := refs[path]; ok && typed != "" && typed == entry.value {
+			return entry.ref
+		}
+		return typed
+	default:
+		return value
+	}
+}
+
+// restoreSecretRefsInNode rewrites scalar values in a generated YAML tree back to their
+// references, matching them by path. Mapping keys are left untouched.
+func restoreSecretRefsInNode(node *yaml.Node, path string, refs map[string]secretRef) {
+	if node == nil || len(refs) == 0 {
+		return
+	}
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			restoreSecretRefsInNode(child, path, refs)
+		}
+	case yaml.SequenceNode:
+		for index, child := range node.Content {
+			restoreSecretRefsInNode(child, fmt.Sprintf("%s[%d]", path, index), refs)
+		}
+	case yaml.MappingNode:
+		for i := 1; i < len(node.Content); i += 2 {
+			restoreSecretRefsInNode(node.Content[i], joinSecretRefPath(path, node.Content[i-1].Value), refs)
+		}
+	case yaml.ScalarNode:
+		if node.Value == "" {
+			return
+		}
+		if entry, ok := refs[path]; ok && node.Value == entry.value {
+			node.Value = entry.ref
+			node.Tag = "!!str"
+			node.Style = 0
+		}
+	}
+}
+
+// resolveSecretRefsIn resolves every reference below v and records it in refs.
+func resolveSecretRefsIn(v reflect.Value, path, jsonPath string, refs map[string]secretRef) error {
+	return walkSecretRefStrings(v, path, jsonPath, func(v reflect.Value, path, jsonPath string) error {
+		raw := v.String()
+		resolved, isRef, err := resolveSecretRef(raw)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if isRef {
+			v.SetString(resolved)
+			if resolved != "" {
+				entry := secretRef{value: resolved, ref: raw}
+				if jsonPath != "-" {
+					entry.jsonPath = jsonPath
+				}
+				refs[path] = entry
+			}
+		}
+		return nil
+	})
+}
+
+// UnknownSecretRefs returns the YAML paths of values in cfg that are still unresolved secret
+// references and were not loaded, unchanged and at the same path, into known. The management
+// API uses it to refuse references that would make the server read arbitrary files or
+// environment variables on a remote caller's behalf.
+func (cfg *Config) UnknownSecretRefs(known *Config) []string {
+	if cfg == nil {
+		return nil
+	}
+	var paths []string
+	_ = walkSecretRefStrings(reflect.ValueOf(cfg).Elem(), "", "", func(v reflect.Value, path, _ string) error {
+		raw := v.String()
+		if !isSecretRef(raw) {
+			return nil
+		}
+		if known != nil {
+			if entry, ok := known.secretRefs[path]; ok && entry.ref == raw {
+				return nil
+			}
+		}
+		paths = append(paths, path)
+		return nil
+	})
+	return paths
+}
+
+// walkSecretRefStrings calls visit for every settable string below v, tracking both the YAML
+// path used by the config file and the JSON path used by the management API. A jsonPath of
+// "-" marks values hidden from JSON.
+func walkSecretRefStrings(v reflect.Value, path, jsonPath string, visit func(v reflect.Value, path, jsonPath string) error) error {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return nil
+		}
+		return walkSecretRefStrings(v.Elem(), path, jsonPath, visit)
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name := field.Name
+			if tag, _, _ := strings.Cut(field.Tag.Get("yaml"), ","); tag == "-" {
+				continue
+			} else if tag != "" {
+				name = tag
+			}
+			childPath := path
+			if !field.Anonymous || field.Tag.Get("yaml") == "" {
+				childPath = joinSecretRefPath(path, name)
+			}
+			childJSONPath := jsonPath
+			if jsonName, _, _ := strings.Cut(field.Tag.Get("json"), ","); jsonPath == "-" || jsonName == "-" {
+				childJSONPath = "-"
+			} else if jsonName != "" {
+				childJSONPath = joinSecretRefPath(jsonPath, jsonName)
+			} else if !field.Anonymous {
+				childJSONPath = joinSecretRefPath(jsonPath, field.Name)
+			}
+			if err := walkSecretRefStrings(v.Field(i), childPath, childJSONPath, visit); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			childJSONPath := jsonPath
+			if jsonPath != "-" {
+				childJSONPath = fmt.Sprintf("%s[%d]", jsonPath, i)
+			}
+			if err := walkSecretRefStrings(v.Index(i), fmt.Sprintf("%s[%d]", path, i), childJSONPath, visit); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			key := fmt.Sprint(iter.Key().Interface())
+			childJSONPath := jsonPath
+			if jsonPath != "-" {
+				childJSONPath = joinSecretRefPath(jsonPath, key)
+			}
+			elem := reflect.New(v.Type().Elem()).Elem()
+			elem.Set(iter.Value())
+			if err := walkSecretRefStrings(elem, joinSecretRefPath(path, key), childJSONPath, visit); err != nil {
+				return err
+			}
+			v.SetMapIndex(iter.Key(), elem)
+		}
+	case reflect.String:
+		if !v.CanSet() {
+			return nil
+		}
+		return visit(v, path, jsonPath)
+	}
+	return nil
+}
+
+func joinSecretRefPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// isSecretRef reports whether raw is a reference resolveSecretRef would expand.
+func isSecretRef(raw string) bool {
+	trimmed := strings.TrimSpace(raw)
+	if strings.HasPrefix(trimmed, SecretRefEnvPrefix) || strings.HasPrefix(trimmed, SecretRefFilePrefix) {
+		return true
+	}
+	return strings.Contains(strings.ReplaceAll(raw, "$${", ""), "${")
+}
+
+// resolveSecretRef resolves a single value and reports whether it was a reference.
+func resolveSecretRef(raw string) (string, bool, error) {
+	trimmed := strings.TrimSpace(raw)
+	switch {
+	case strings.HasPrefix(trimmed, SecretRefEnvPrefix):
+		name := strings.TrimSpace(strings.TrimPrefix(trimmed, SecretRefEnvPrefix))
+		if !isEnvVarName(name) {
+			return "", false, fmt.Errorf("invalid environment variable name in %q", trimmed)
+		}
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return "", false, fmt.Errorf("environment variable %s is not set", name)
+		}
+		return strings.TrimSpace(value), true, nil
+	case strings.HasPrefix(trimmed, SecretRefFilePrefix):
+		path := strings.TrimSpace(strings.TrimPrefix(trimmed, SecretRefFilePrefix))
+		if path == "" {
+			return "", false, fmt.Errorf("empty file path in %q", trimmed)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", false, fmt.Errorf("read secret file: %w", err)
+		}
+		return strings.TrimSpace(string(data)), true, nil
+	case strings.Contains(raw, "${"):
+		return interpolateEnv(raw)
+	default:
+		return raw, false, nil
+	}
+}
+
+// interpolateEnv expands ${VAR} and ${VAR:-default} in raw. "$${" is an escaped literal "${".
+func interpolateEnv(raw string) (string, bool, error) {
+	var out strings.Builder
+	rest := raw
+	for {
+		index := strings.Index(rest, "${")
+		if index < 0 {
+			out.WriteString(rest)
+			break
+		}
+		if index > 0 && rest[index-1] == '$' {
+			out.WriteString(rest[:index-1])
+			out.WriteString("${")
+			rest = rest[index+2:]
+			continue
+		}
+		out.WriteString(rest[:index])
+		end := strings.IndexByte(rest[index+2:], '}')
+		if end < 0 {
+			return "", false, fmt.Errorf("unterminated ${ in %q", raw)
+		}
+		expr := rest[index+2 : index+2+end]
+		name, fallback, hasFallback := strings.Cut(expr, ":-")
+		name = strings.TrimSpace(name)
+		if !isEnvVarName(name) {
+			return "", false, fmt.Errorf("invalid environment variable name %q", name)
+		}
+		value, ok := os.LookupEnv(name)
+		switch {
+		case ok && value != "":
+			out.WriteString(value)
+		case hasFallback:
+			out.WriteString(fallback)
+		case !ok:
+			return "", false, fmt.Errorf("environment variable %s is not set", name)
+		}
+		rest = rest[index+2+end+1:]
+	}
+	return out.String(), true, nil
+}
+
+func isEnvVarName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		switch {
+		case r == '_', r >= 'A' && r <= 'Z', r >= 'a' && r <= 'z':
+		case r >= '0' && r <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,164 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeSecretRefsConfig(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	return path
+}
+
+func TestLoadConfig_ResolvesSecretRefs(t *testing.T) {
+	t.Setenv("TEST_CLAUDE_KEY", "sk-ant-from-env")
+	t.Setenv("TEST_UPSTREAM_HOST", "upstream.example.com")
+	secretFile := filepath.Join(t.TempDir(), "codex")
+	if err := os.WriteFile(secretFile, []byte("sk-codex-from-file\n"), 0o600); err != nil {
+		t.Fatalf("write secret: %v", err)
+	}
+	path := writeSecretRefsConfig(t, `
+claude-api-key:
+  - api-key: env:TEST_CLAUDE_KEY
+codex-api-key:
+  - api-key: file:`+secretFile+`
+    base-url: https://${TEST_UPSTREAM_HOST}/v1
+ampcode:
+  upstream-api-key: ${TEST_AMP_KEY:-amp-default}
+  upstream-url: "$${literal}"
+`)
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if got := cfg.ClaudeKey[0].APIKey; got != "sk-ant-from-env" {
+		t.Fatalf("claude api-key = %q", got)
+	}
+	if got := cfg.CodexKey[0].APIKey; got != "sk-codex-from-file" {
+		t.Fatalf("codex api-key = %q", got)
+	}
+	if got := cfg.CodexKey[0].BaseURL; got != "https://upstream.example.com/v1" {
+		t.Fatalf("codex base-url = %q", got)
+	}
+	if cfg.AmpCode.UpstreamAPIKey != "amp-default" || cfg.AmpCode.UpstreamURL != "${literal}" {
+		t.Fatalf("ampcode = %+v", cfg.AmpCode)
+	}
+
+	view, err := cfg.WithSecretRefs()
+	if err != nil {
+		t.Fatalf("WithSecretRefs() error = %v", err)
+	}
+	claude := view.(map[string]any)["claude-api-key"].([]any)[0].(map[string]any)
+	if claude["api-key"] != "env:TEST_CLAUDE_KEY" {
+		t.Fatalf("rendered claude api-key = %v, want reference", claude["api-key"])
+	}
+
+	cfg.ClaudeKey = append(cfg.ClaudeKey, ClaudeKey{APIKey: "sk-inline"})
+	if err = SaveConfigPreserveComments(path, cfg); err != nil {
+		t.Fatalf("SaveConfigPreserveComments() error = %v", err)
+	}
+	saved, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read saved config: %v", err)
+	}
+	text := string(saved)
+	for _, want := range []string{"env:TEST_CLAUDE_KEY", "file:" + secretFile, "${TEST_UPSTREAM_HOST}", "sk-inline"} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("saved config missing %q:\n%s", want, text)
+		}
+	}
+	for _, leaked := range []string{"sk-ant-from-env", "sk-codex-from-file"} {
+		if strings.Contains(text, leaked) {
+			t.Fatalf("saved config leaked %q:\n%s", leaked, text)
+		}
+	}
+}
+
+func TestLoadConfig_SecretRefErrors(t *testing.T) {
+	cases := map[string]string{
+		"unset env":      "claude-api-key:\n  - api-key: env:TEST_SECRET_REF_UNSET\n",
+		"missing file":   "claude-api-key:\n  - api-key: file:/nonexistent/secret\n",
+		"unset interp":   "proxy-url: http://${TEST_SECRET_REF_UNSET}:8080\n",
+		"unterminated":   "proxy-url: http://${TEST_SECRET_REF_UNSET\n",
+		"invalid env id": "claude-api-key:\n  - api-key: env:1BAD\n",
+	}
+	for name, body := range cases {
+		if _, err := LoadConfig(writeSecretRefsConfig(t, body)); err == nil {
+			t.Errorf("%s: LoadConfig() error = nil", name)
+		}
+	}
+}
+
+func TestLoadConfig_SecretKeyRefIsNotPersistedAsHash(t *testing.T) {
+	t.Setenv("TEST_MANAGEMENT_SECRET", "management-password")
+	path := writeSecretRefsConfig(t, "remote-management:\n  secret-key: env:TEST_MANAGEMENT_SECRET\n")
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if !looksLikeBcrypt(cfg.RemoteManagement.SecretKey) {
+		t.Fatalf("secret-key = %q, want bcrypt hash in memory", cfg.RemoteManagement.SecretKey)
+	}
+	if err = SaveConfigPreserveComments(path, cfg); err != nil {
+		t.Fatalf("SaveConfigPreserveComments() error = %v", err)
+	}
+	saved, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read saved config: %v", err)
+	}
+	if !strings.Contains(string(saved), "env:TEST_MANAGEMENT_SECRET") || strings.Contains(string(saved), "$2") {
+		t.Fatalf("saved config = %s, want the reference kept", saved)
+	}
+}
+
+func TestSecretRefs_RestoreOnlyAtTheirPath(t *testing.T) {
+	t.Setenv("TEST_SHARED_SECRET", "sk-shared")
+	path := writeSecretRefsConfig(t, "claude-api-key:\n  - api-key: env:TEST_SHARED_SECRET\n")
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	// An inline value that happens to equal the resolved secret must stay inline, and a
+	// referenced value that was edited must not be replaced by its old reference.
+	cfg.CodexKey = append(cfg.CodexKey, CodexKey{APIKey: "sk-shared"})
+	cfg.ClaudeKey = append(cfg.ClaudeKey, ClaudeKey{APIKey: "sk-second"})
+
+	view, err := cfg.WithSecretRefs()
+	if err != nil {
+		t.Fatalf("WithSecretRefs() error = %v", err)
+	}
+	doc := view.(map[string]any)
+	if got := doc["claude-api-key"].([]any)[0].(map[string]any)["api-key"]; got != "env:TEST_SHARED_SECRET" {
+		t.Fatalf("rendered claude api-key = %v, want reference", got)
+	}
+	if got := doc["codex-api-key"].([]any)[0].(map[string]any)["api-key"]; got != "sk-shared" {
+		t.Fatalf("rendered codex api-key = %v, want inline value", got)
+	}
+
+	cfg.ClaudeKey[0].APIKey = "sk-edited"
+	if err = SaveConfigPreserveComments(path, cfg); err != nil {
+		t.Fatalf("SaveConfigPreserveComments() error = %v", err)
+	}
+	saved, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read saved config: %v", err)
+	}
+	text := string(saved)
+	for _, want := range []string{"sk-edited", "sk-second", "api-key: sk-shared"} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("saved config missing %q:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "env:TEST_SHARED_SECRET") {
+		t.Fatalf("saved config restored a reference over an edited value:\n%s", text)
+	}
+}
@@ -0,0 +1,57 @@
+package config
+
+import "strings"
+
+// DefaultTokenEncryptionKeyEnv names the environment variable read when token encryption is
+// enabled without explicit keys.
+const DefaultTokenEncryptionKeyEnv = "TOKEN_ENCRYPTION_KEY"
+
+// TokenEncryptionConfig configures envelope encryption of stored auth files.
+type TokenEncryptionConfig struct {
+	// Enabled encrypts auth files on save. With Enabled false, configured keys are still used
+	// to read files that were encrypted earlier.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// Keys lists key-encryption keys. The first key encrypts new files; every key can decrypt,
+	// which allows rotating keys without re-encrypting everything at once.
+	Keys []TokenEncryptionKey `yaml:"keys,omitempty" json:"keys,omitempty"`
+}
+
+// TokenEncryptionKey references a 256-bit key by environment variable or file. The value is
+// base64 or hex encoded.
+type TokenEncryptionKey struct {
+	// ID is stored with each encrypted file to select the key on decryption.
+	ID string `yaml:"id" json:"id"`
+
+	// Env names the environment variable holding the key.
+	Env string `yaml:"env,omitempty" json:"env,omitempty"`
+
+	// File is the path of a file holding the key.
+	File string `yaml:"file,omitempty" json:"file,omitempty"`
+}
+
+// Normalized returns a copy with trimmed fields, keys without a source removed and the
+// default environment key applied when encryption is enabled without keys.
+func (c TokenEncryptionConfig) Normalized() TokenEncryptionConfig {
+	keys := make([]TokenEncryptionKey, 0, len(c.Keys))
+	for _, key := range c.Keys {
+		key.ID = strings.TrimSpace(key.ID)
+		key.Env = strings.TrimSpace(key.Env)
+		key.File = strings.TrimSpace(key.File)
+		if key.Env == "" && key.File == "" {
+			continue
+		}
+		if key.ID == "" {
+			key.ID = key.Env
+			if key.ID == "" {
+				key.ID = key.File
+			}
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 && c.Enabled {
+		keys = append(keys, TokenEncryptionKey{ID: "default", Env: DefaultTokenEncryptionKeyEnv})
+	}
+	c.Keys = keys
+	return c
+}
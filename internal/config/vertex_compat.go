@@ -17,6 +17,17 @@ type VertexCompatKey struct {
 	// Higher values are preferred; defaults to 0.
 	Priority int `yaml:"priority,omitempty" json:"priority,omitempty"`
 
+	// Weight sets this credential's relative share of traffic among ready credentials
+	// at the same priority level. Values <= 0 are treated as 1.
+	Weight int `yaml:"weight,omitempty" json:"weight,omitempty"`
+
+	// MaxConcurrency caps the number of requests this credential serves at once.
+	// Values <= 0 leave it unlimited.
+	MaxConcurrency int `yaml:"max-concurrency,omitempty" json:"max-concurrency,omitempty"`
+
+	// Availability restricts when this credential may be used.
+	Availability *AvailabilitySchedule `yaml:"availability,omitempty" json:"availability,omitempty"`
+
 	// Prefix optionally namespaces model aliases for this credential (e.g., "teamA/vertex-pro").
 	Prefix string `yaml:"prefix,omitempty" json:"prefix,omitempty"`
 
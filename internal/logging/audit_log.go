@@ -0,0 +1,252 @@
+package logging
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+const (
+	// AuditLogDirName is the log subdirectory holding management audit logs. Keeping them out
+	// of the main log directory protects them from size-based cleanup and log deletion.
+	AuditLogDirName  = "audit"
+	auditLogFileName = "audit.log"
+	// auditLogMaxSizeMB is the size at which the audit log rotates. Rotated files are kept.
+	auditLogMaxSizeMB = 10
+)
+
+// AuditChange records one changed value of a management mutation. Secrets are redacted.
+type AuditChange struct {
+	Path   string `json:"path"`
+	Before any    `json:"before,omitempty"`
+	After  any    `json:"after,omitempty"`
+}
+
+// AuditEntry is one line of the management audit log.
+type AuditEntry struct {
+	Timestamp  time.Time     `json:"timestamp"`
+	RemoteAddr string        `json:"remote_addr"`
+	Principal  string        `json:"principal"`
+	Role       string        `json:"role,omitempty"`
+	Method     string        `json:"method"`
+	Route      string        `json:"route"`
+	Query      string        `json:"query,omitempty"`
+	Status     int           `json:"status"`
+	Changes    []AuditChange `json:"changes,omitempty"`
+}
+
+// AuditFilter selects audit entries. Zero fields match everything.
+type AuditFilter struct {
+	Since     time.Time
+	Until     time.Time
+	Principal string
+	Route     string
+	Method    string
+	Limit     int
+}
+
+// Matches reports whether entry satisfies the filter. Route matches as a substring.
+func (f AuditFilter) Matches(entry *AuditEntry) bool {
+	if !f.Since.IsZero() && entry.Timestamp.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && entry.Timestamp.After(f.Until) {
+		return false
+	}
+	if f.Principal != "" && !strings.EqualFold(entry.Principal, f.Principal) {
+		return false
+	}
+	if f.Method != "" && !strings.EqualFold(entry.Method, f.Method) {
+		return false
+	}
+	if f.Route != "" && !strings.Contains(entry.Route, f.Route) {
+		return false
+	}
+	return true
+}
+
+// AuditLog appends audit entries as JSON lines to a rotating file.
+type AuditLog struct {
+	dir string
+
+	mu     sync.Mutex
+	writer *lumberjack.Logger
+}
+
+// NewAuditLog returns an audit log writing below dir. The directory is created on first write.
+func NewAuditLog(dir string) *AuditLog {
+	return &AuditLog{dir: dir}
+}
+
+// Dir returns the directory holding the audit log files.
+func (a *AuditLog) Dir() string {
+	if a == nil {
+		return ""
+	}
+	return a.dir
+}
+
+// Append writes entry as one JSON line.
+func (a *AuditLog) Append(entry AuditEntry) error {
+	if a == nil || a.dir == "" {
+		return fmt.Errorf("audit log directory not configured")
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("marshal audit entry: %w", err)
+	}
+	data = append(data, '\n')
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.writer == nil {
+		if err = os.MkdirAll(a.dir, 0o700); err != nil {
+			return fmt.Errorf("create audit log directory: %w", err)
+		}
+		a.writer = &lumberjack.Logger{
+			Filename: filepath.Join(a.dir, auditLogFileName),
+			MaxSize:  auditLogMaxSizeMB,
+		}
+	}
+	_, err = a.writer.Write(data)
+	return err
+}
+
+// Close releases the underlying file.
+func (a *AuditLog) Close() error {
+	if a == nil {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.writer == nil {
+		return nil
+	}
+	err := a.writer.Close()
+	a.writer = nil
+	return err
+}
+
+// Query reads the current and rotated audit files and returns matching entries, newest first.
+func (a *AuditLog) Query(filter AuditFilter) ([]AuditEntry, error) {
+	if a == nil || a.dir == "" {
+		return []AuditEntry{}, nil
+	}
+	files, err := os.ReadDir(a.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []AuditEntry{}, nil
+		}
+		return nil, fmt.Errorf("read audit log directory: %w", err)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	entries := make([]AuditEntry, 0)
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() || !strings.HasPrefix(name, "audit") || !strings.HasSuffix(name, ".log") {
+			continue
+		}
+		if err = readAuditFile(filepath.Join(a.dir, name), filter, &entries); err != nil {
+			return nil, err
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Timestamp.After(entries[j].Timestamp)
+	})
+	if filter.Limit > 0 && len(entries) > filter.Limit {
+		entries = entries[:filter.Limit]
+	}
+	return entries, nil
+}
+
+func readAuditFile(path string, filter AuditFilter, out *[]AuditEntry) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open audit log: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var entry AuditEntry
+		if json.Unmarshal(line, &entry) != nil {
+			continue
+		}
+		if filter.Matches(&entry) {
+			*out = append(*out, entry)
+		}
+	}
+	return scanner.Err()
+}
+
+// DiffAuditValues compares two JSON-compatible trees and lists changed leaves by dotted path.
+// Lists are compared element by element using "[index]" path segments.
+func DiffAuditValues(before, after any) []AuditChange {
+	changes := make([]AuditChange, 0)
+	diffAuditNode("", before, after, &changes)
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes
+}
+
+func diffAuditNode(path string, before, after any, changes *[]AuditChange) {
+	beforeMap, beforeIsMap := before.(map[string]any)
+	afterMap, afterIsMap := after.(map[string]any)
+	if beforeIsMap && afterIsMap {
+		for key, value := range beforeMap {
+			diffAuditNode(joinAuditPath(path, key), value, afterMap[key], changes)
+		}
+		for key, value := range afterMap {
+			if _, seen := beforeMap[key]; !seen {
+				diffAuditNode(joinAuditPath(path, key), nil, value, changes)
+			}
+		}
+		return
+	}
+	beforeList, beforeIsList := before.([]any)
+	afterList, afterIsList := after.([]any)
+	if beforeIsList && afterIsList {
+		for index := 0; index < max(len(beforeList), len(afterList)); index++ {
+			var beforeItem, afterItem any
+			if index < len(beforeList) {
+				beforeItem = beforeList[index]
+			}
+			if index < len(afterList) {
+				afterItem = afterList[index]
+			}
+			diffAuditNode(fmt.Sprintf("%s[%d]", path, index), beforeItem, afterItem, changes)
+		}
+		return
+	}
+	if auditValuesEqual(before, after) {
+		return
+	}
+	*changes = append(*changes, AuditChange{Path: path, Before: before, After: after})
+}
+
+func joinAuditPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func auditValuesEqual(a, b any) bool {
+	left, errLeft := json.Marshal(a)
+	right, errRight := json.Marshal(b)
+	return errLeft == nil && errRight == nil && string(left) == string(right)
+}
@@ -0,0 +1,68 @@
+package logging
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDiffAuditValues_ReportsChangedLeaves(t *testing.T) {
+	before := map[string]any{
+		"debug":    false,
+		"api-keys": []any{"a...1"},
+		"routing":  map[string]any{"strategy": "round-robin"},
+	}
+	after := map[string]any{
+		"debug":    true,
+		"api-keys": []any{"a...1", "b...2"},
+		"routing":  map[string]any{"strategy": "round-robin"},
+		"proxy":    "socks5://proxy",
+	}
+
+	changes := DiffAuditValues(before, after)
+	want := []AuditChange{
+		{Path: "api-keys[1]", After: "b...2"},
+		{Path: "debug", Before: false, After: true},
+		{Path: "proxy", After: "socks5://proxy"},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("changes = %+v, want %+v", changes, want)
+	}
+	for i := range want {
+		if changes[i].Path != want[i].Path || changes[i].Before != want[i].Before || changes[i].After != want[i].After {
+			t.Fatalf("changes[%d] = %+v, want %+v", i, changes[i], want[i])
+		}
+	}
+}
+
+func TestAuditLog_AppendAndQuery(t *testing.T) {
+	audit := NewAuditLog(t.TempDir())
+	defer func() { _ = audit.Close() }()
+
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	entries := []AuditEntry{
+		{Timestamp: base, Principal: "secret-key", Method: "PUT", Route: "/debug", Status: 200},
+		{Timestamp: base.Add(time.Minute), Principal: "oncall", Method: "PATCH", Route: "/auth-files/status", Status: 200},
+		{Timestamp: base.Add(2 * time.Minute), Principal: "secret-key", Method: "DELETE", Route: "/auth-files", Status: 200},
+	}
+	for _, entry := range entries {
+		if err := audit.Append(entry); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+
+	got, err := audit.Query(AuditFilter{Principal: "secret-key"})
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if len(got) != 2 || got[0].Route != "/auth-files" || got[1].Route != "/debug" {
+		t.Fatalf("Query(principal) = %+v, want newest-first secret-key entries", got)
+	}
+
+	got, err = audit.Query(AuditFilter{Route: "auth-files", Since: base.Add(30 * time.Second), Limit: 1})
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if len(got) != 1 || got[0].Method != "DELETE" {
+		t.Fatalf("Query(route, since, limit) = %+v, want the DELETE entry", got)
+	}
+}
@@ -13,6 +13,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	sdkaccess "github.com/router-for-me/CLIProxyAPI/v6/sdk/access"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -83,6 +84,11 @@ func GinLogrusLogger() gin.HandlerFunc {
 		}
 
 		entry := log.WithField("request_id", requestID)
+		if accessMetadata, exists := c.Get("accessMetadata"); exists {
+			if metadata, ok := accessMetadata.(map[string]string); ok && metadata[sdkaccess.MetadataClientKeyName] != "" {
+				entry = entry.WithField("client_key", metadata[sdkaccess.MetadataClientKeyName])
+			}
+		}
 
 		switch {
 		case statusCode >= http.StatusInternalServerError:
@@ -64,6 +64,20 @@ type ModelInfo struct {
 	UserDefined bool `json:"-"`
 }
 
+// IsEmbeddingOnly reports whether the model only serves embeddings: it advertises embedding
+// methods and no generation method, so it must not be offered for chat requests.
+func (m *ModelInfo) IsEmbeddingOnly() bool {
+	if m == nil || len(m.SupportedGenerationMethods) == 0 {
+		return false
+	}
+	for _, method := range m.SupportedGenerationMethods {
+		if method != "embedContent" && method != "batchEmbedContents" {
+			return false
+		}
+	}
+	return true
+}
+
 type availableModelsCacheEntry struct {
 	models    []map[string]any
 	expiresAt time.Time
@@ -808,6 +822,11 @@ func (r *ModelRegistry) buildAvailableModelsLocked(handlerType string, now time.
 	var expiresAt time.Time
 
 	for _, registration := range r.models {
+		// Only the Gemini listing describes generation methods, so embedding models are left
+		// out of the chat-oriented listings.
+		if handlerType != "gemini" && registration.Info.IsEmbeddingOnly() {
+			continue
+		}
 		availableClients := registration.Count
 
 		expiredClients := 0
@@ -52,3 +52,21 @@ func TestGetAvailableModelsInvalidatesCacheOnRegistryChanges(t *testing.T) {
 		t.Fatalf("expected model to reappear after resume, got %d", len(models))
 	}
 }
+
+func TestGetAvailableModelsListsEmbeddingModelsOnlyForGemini(t *testing.T) {
+	r := newTestModelRegistry()
+	r.RegisterClient("client-1", "gemini", []*ModelInfo{
+		{ID: "chat-model", SupportedGenerationMethods: []string{"generateContent", "countTokens"}},
+		{ID: "embed-model", SupportedGenerationMethods: []string{"embedContent", "batchEmbedContents"}},
+	})
+
+	for _, handlerType := range []string{"openai", "claude", ""} {
+		models := r.GetAvailableModels(handlerType)
+		if len(models) != 1 || models[0]["id"] != "chat-model" {
+			t.Fatalf("%q listing = %v, want only the chat model", handlerType, models)
+		}
+	}
+	if models := r.GetAvailableModels("gemini"); len(models) != 2 {
+		t.Fatalf("gemini listing has %d models, want both", len(models))
+	}
+}
@@ -0,0 +1,156 @@
+package responsestore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fileSweepInterval bounds how often Save scans the directory for expired records.
+const fileSweepInterval = 10 * time.Minute
+
+// FileStore keeps each record in "<dir>/<id>.json". Expired files are removed when they are read
+// and by a periodic sweep on save.
+type FileStore struct {
+	dir string
+	ttl time.Duration
+
+	mu        sync.Mutex
+	lastSweep time.Time
+}
+
+// NewFileStore returns a store writing to dir, creating it if needed.
+func NewFileStore(dir string, ttl time.Duration) (*FileStore, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("responsestore: file store directory is required")
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("responsestore: create directory: %w", err)
+	}
+	return &FileStore{dir: dir, ttl: ttl}, nil
+}
+
+// path maps id to its file, rejecting ids that could escape the directory.
+func (s *FileStore) path(id string) (string, error) {
+	if id == "" || id == "." || id == ".." || strings.ContainsAny(id, `/\`) {
+		return "", fmt.Errorf("responsestore: invalid response id %q", id)
+	}
+	return filepath.Join(s.dir, id+".json"), nil
+}
+
+// Save writes rec atomically.
+func (s *FileStore) Save(_ context.Context, rec *Record) error {
+	path, err := s.path(rec.ID)
+	if err != nil {
+		return err
+	}
+	stored := *rec
+	stamp(&stored, s.ttl)
+	raw, err := json.Marshal(&stored)
+	if err != nil {
+		return fmt.Errorf("responsestore: marshal record: %w", err)
+	}
+	tmp, err := os.CreateTemp(s.dir, ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("responsestore: create temp file: %w", err)
+	}
+	_, errWrite := tmp.Write(raw)
+	errClose := tmp.Close()
+	if err = errors.Join(errWrite, errClose); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("responsestore: write record: %w", err)
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("responsestore: write record: %w", err)
+	}
+
+	now := time.Now()
+	s.mu.Lock()
+	sweepDue := s.ttl > 0 && now.Sub(s.lastSweep) >= fileSweepInterval
+	if sweepDue {
+		s.lastSweep = now
+	}
+	s.mu.Unlock()
+	if sweepDue {
+		s.sweep(now)
+	}
+	return nil
+}
+
+// sweep removes expired record files. Only files last written more than a TTL ago are read,
+// since a record's expiry is stamped when it is saved.
+func (s *FileStore) sweep(now time.Time) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		info, errInfo := entry.Info()
+		if errInfo != nil || now.Sub(info.ModTime()) < s.ttl {
+			continue
+		}
+		path := filepath.Join(s.dir, name)
+		raw, errRead := os.ReadFile(path)
+		if errRead != nil {
+			continue
+		}
+		var rec Record
+		if json.Unmarshal(raw, &rec) == nil && rec.expired(now) {
+			_ = os.Remove(path)
+		}
+	}
+}
+
+// Load reads the record with the given id.
+func (s *FileStore) Load(_ context.Context, id string) (*Record, error) {
+	path, err := s.path(id)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("responsestore: read record: %w", err)
+	}
+	var rec Record
+	if err = json.Unmarshal(raw, &rec); err != nil {
+		return nil, fmt.Errorf("responsestore: decode record %s: %w", id, err)
+	}
+	if rec.expired(time.Now()) {
+		_ = os.Remove(path)
+		return nil, ErrNotFound
+	}
+	return &rec, nil
+}
+
+// Delete removes the record with the given id.
+func (s *FileStore) Delete(ctx context.Context, id string) error {
+	if _, err := s.Load(ctx, id); err != nil {
+		return err
+	}
+	path, _ := s.path(id)
+	if err := os.Remove(path); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("responsestore: delete record: %w", err)
+	}
+	return nil
+}
+
+// Close is a no-op.
+func (s *FileStore) Close() error { return nil }
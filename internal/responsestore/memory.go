@@ -0,0 +1,77 @@
+package responsestore
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// memorySweepInterval bounds how often Save scans for expired records.
+const memorySweepInterval = time.Minute
+
+// MemoryStore keeps records in process memory. Records are lost on restart.
+type MemoryStore struct {
+	mu        sync.Mutex
+	ttl       time.Duration
+	records   map[string]*Record
+	lastSweep time.Time
+}
+
+// NewMemoryStore returns an empty in-memory store whose records lapse after ttl (0 keeps them).
+func NewMemoryStore(ttl time.Duration) *MemoryStore {
+	return &MemoryStore{ttl: ttl, records: make(map[string]*Record)}
+}
+
+// Save stores a copy of rec.
+func (s *MemoryStore) Save(_ context.Context, rec *Record) error {
+	stored := *rec
+	stamp(&stored, s.ttl)
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[stored.ID] = &stored
+	if now.Sub(s.lastSweep) >= memorySweepInterval {
+		s.lastSweep = now
+		for id, existing := range s.records {
+			if existing.expired(now) {
+				delete(s.records, id)
+			}
+		}
+	}
+	return nil
+}
+
+// Load returns a copy of the record with the given id.
+func (s *MemoryStore) Load(_ context.Context, id string) (*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rec, ok := s.records[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if rec.expired(time.Now()) {
+		delete(s.records, id)
+		return nil, ErrNotFound
+	}
+	out := *rec
+	return &out, nil
+}
+
+// Delete removes the record with the given id.
+func (s *MemoryStore) Delete(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rec, ok := s.records[id]
+	if !ok {
+		return ErrNotFound
+	}
+	delete(s.records, id)
+	if rec.expired(time.Now()) {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Close is a no-op.
+func (s *MemoryStore) Close() error { return nil }
@@ -0,0 +1,137 @@
+package responsestore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+// postgresPurgeInterval bounds how often Save deletes expired rows.
+const postgresPurgeInterval = 10 * time.Minute
+
+// PostgresStore keeps records in a PostgreSQL table, so every replica can continue responses
+// created by the others.
+type PostgresStore struct {
+	db    *sql.DB
+	table string
+	ttl   time.Duration
+
+	mu         sync.Mutex
+	lastPurged time.Time
+}
+
+// NewPostgresStore connects to dsn and creates table if it does not exist.
+func NewPostgresStore(ctx context.Context, dsn, table string, ttl time.Duration) (*PostgresStore, error) {
+	if dsn == "" {
+		return nil, fmt.Errorf("responsestore: postgres DSN is required")
+	}
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("responsestore: open database connection: %w", err)
+	}
+	if err = db.PingContext(ctx); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("responsestore: ping database: %w", err)
+	}
+	s := &PostgresStore{db: db, table: quoteIdentifier(table), ttl: ttl}
+	query := fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			id TEXT PRIMARY KEY,
+			owner TEXT NOT NULL DEFAULT '',
+			previous_id TEXT NOT NULL DEFAULT '',
+			input JSONB NOT NULL,
+			response JSONB NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			expires_at TIMESTAMPTZ
+		)
+	`, s.table)
+	if _, err = db.ExecContext(ctx, query); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("responsestore: create table: %w", err)
+	}
+	return s, nil
+}
+
+// Save upserts rec and occasionally purges expired rows.
+func (s *PostgresStore) Save(ctx context.Context, rec *Record) error {
+	stored := *rec
+	stamp(&stored, s.ttl)
+	var expiresAt sql.NullTime
+	if !stored.ExpiresAt.IsZero() {
+		expiresAt = sql.NullTime{Time: stored.ExpiresAt, Valid: true}
+	}
+	query := fmt.Sprintf(`
+		INSERT INTO %s (id, owner, previous_id, input, response, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (id)
+		DO UPDATE SET owner = EXCLUDED.owner, previous_id = EXCLUDED.previous_id, input = EXCLUDED.input, response = EXCLUDED.response, created_at = EXCLUDED.created_at, expires_at = EXCLUDED.expires_at
+	`, s.table)
+	if _, err := s.db.ExecContext(ctx, query, stored.ID, stored.Owner, stored.PreviousID, string(stored.Input), string(stored.Response), stored.CreatedAt, expiresAt); err != nil {
+		return fmt.Errorf("responsestore: save record %s: %w", stored.ID, err)
+	}
+
+	now := time.Now()
+	s.mu.Lock()
+	purgeDue := now.Sub(s.lastPurged) >= postgresPurgeInterval
+	if purgeDue {
+		s.lastPurged = now
+	}
+	s.mu.Unlock()
+	if purgeDue {
+		purge := fmt.Sprintf("DELETE FROM %s WHERE expires_at IS NOT NULL AND expires_at < NOW()", s.table)
+		_, _ = s.db.ExecContext(ctx, purge)
+	}
+	return nil
+}
+
+// Load returns the record with the given id unless it has expired.
+func (s *PostgresStore) Load(ctx context.Context, id string) (*Record, error) {
+	query := fmt.Sprintf(`
+		SELECT owner, previous_id, input, response, created_at, expires_at FROM %s
+		WHERE id = $1 AND (expires_at IS NULL OR expires_at > NOW())
+	`, s.table)
+	var input, response string
+	var expiresAt sql.NullTime
+	rec := &Record{ID: id}
+	err := s.db.QueryRowContext(ctx, query, id).Scan(&rec.Owner, &rec.PreviousID, &input, &response, &rec.CreatedAt, &expiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("responsestore: load record %s: %w", id, err)
+	}
+	rec.Input = []byte(input)
+	rec.Response = []byte(response)
+	if expiresAt.Valid {
+		rec.ExpiresAt = expiresAt.Time
+	}
+	return rec, nil
+}
+
+// Delete removes the record with the given id.
+func (s *PostgresStore) Delete(ctx context.Context, id string) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE id = $1 AND (expires_at IS NULL OR expires_at > NOW())", s.table)
+	result, err := s.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("responsestore: delete record %s: %w", id, err)
+	}
+	if affected, errRows := result.RowsAffected(); errRows == nil && affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Close closes the database connection.
+func (s *PostgresStore) Close() error {
+	return s.db.Close()
+}
+
+func quoteIdentifier(identifier string) string {
+	return "\"" + strings.ReplaceAll(identifier, "\"", "\"\"") + "\""
+}
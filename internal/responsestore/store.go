@@ -0,0 +1,87 @@
+// Package responsestore keeps /v1/responses calls so that later requests can continue them with
+// previous_response_id and clients can fetch or delete them, regardless of which backend served
+// the original call.
+package responsestore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+)
+
+// ErrNotFound is returned for unknown, deleted and expired responses.
+var ErrNotFound = errors.New("responsestore: response not found")
+
+// Record is a stored response together with the conversation it was generated from.
+type Record struct {
+	// ID is the response id returned to the client.
+	ID string `json:"id"`
+	// Owner is the SHA-256 digest of the client key that created the response (see
+	// config.ClientKeyDigest); only that key may read it. The key itself is never stored.
+	Owner string `json:"owner,omitempty"`
+	// PreviousID is the previous_response_id the call continued, if any. The conversation is
+	// rebuilt by following these links, so each record only keeps its own items.
+	PreviousID string `json:"previous_response_id,omitempty"`
+	// Input holds the input items sent with this call as a JSON array, without the items
+	// carried over from PreviousID.
+	Input json.RawMessage `json:"input"`
+	// Response is the response object as returned to the client.
+	Response json.RawMessage `json:"response"`
+	// CreatedAt is when the record was stored.
+	CreatedAt time.Time `json:"created_at"`
+	// ExpiresAt is when the record lapses; zero keeps it until it is deleted.
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+}
+
+func (r *Record) expired(now time.Time) bool {
+	return !r.ExpiresAt.IsZero() && now.After(r.ExpiresAt)
+}
+
+// Store persists response records.
+type Store interface {
+	// Save stores rec, replacing any record with the same id.
+	Save(ctx context.Context, rec *Record) error
+	// Load returns the record with the given id or ErrNotFound.
+	Load(ctx context.Context, id string) (*Record, error)
+	// Delete removes the record with the given id or returns ErrNotFound.
+	Delete(ctx context.Context, id string) error
+	// Close releases resources held by the store.
+	Close() error
+}
+
+// New builds the store selected by cfg. It returns nil when the store is disabled.
+func New(ctx context.Context, cfg config.ResponsesStoreConfig) (Store, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	cfg = cfg.Normalized()
+	switch cfg.Type {
+	case config.ResponsesStoreMemory:
+		return NewMemoryStore(cfg.TTL()), nil
+	case config.ResponsesStoreFile:
+		dir, err := util.ResolveAuthDir(cfg.Dir)
+		if err != nil {
+			return nil, fmt.Errorf("responsestore: resolve directory: %w", err)
+		}
+		return NewFileStore(dir, cfg.TTL())
+	case config.ResponsesStorePostgres:
+		return NewPostgresStore(ctx, cfg.DSN, cfg.Table, cfg.TTL())
+	default:
+		return nil, fmt.Errorf("responsestore: unknown store type %q", cfg.Type)
+	}
+}
+
+// stamp fills in the creation and expiry times of rec.
+func stamp(rec *Record, ttl time.Duration) {
+	if rec.CreatedAt.IsZero() {
+		rec.CreatedAt = time.Now().UTC()
+	}
+	if ttl > 0 && rec.ExpiresAt.IsZero() {
+		rec.ExpiresAt = rec.CreatedAt.Add(ttl)
+	}
+}
@@ -0,0 +1,89 @@
+package responsestore
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreExpiresRecords(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore(time.Hour)
+	if err := store.Save(ctx, &Record{ID: "resp_live", Input: []byte(`[]`), Response: []byte(`{}`)}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	past := time.Now().Add(-2 * time.Hour)
+	if err := store.Save(ctx, &Record{ID: "resp_old", Input: []byte(`[]`), Response: []byte(`{}`), CreatedAt: past, ExpiresAt: past.Add(time.Hour)}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	if _, err := store.Load(ctx, "resp_live"); err != nil {
+		t.Fatalf("Load live: %v", err)
+	}
+	if _, err := store.Load(ctx, "resp_old"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Load expired error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestFileStoreRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewFileStore(t.TempDir(), 0)
+	if err != nil {
+		t.Fatalf("NewFileStore: %v", err)
+	}
+	rec := &Record{ID: "resp_1", Input: []byte(`[{"type":"message"}]`), Response: []byte(`{"id":"resp_1"}`)}
+	if err = store.Save(ctx, rec); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	loaded, err := store.Load(ctx, "resp_1")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if string(loaded.Response) != `{"id":"resp_1"}` || string(loaded.Input) != `[{"type":"message"}]` {
+		t.Fatalf("loaded = %+v", loaded)
+	}
+	if !loaded.ExpiresAt.IsZero() {
+		t.Fatalf("ExpiresAt = %v, want zero without TTL", loaded.ExpiresAt)
+	}
+
+	if err = store.Delete(ctx, "resp_1"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err = store.Delete(ctx, "resp_1"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("second Delete error = %v, want ErrNotFound", err)
+	}
+	if _, err = store.Load(ctx, "../resp_1"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Load traversal error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestFileStoreSweepsExpiredFiles(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store, err := NewFileStore(dir, time.Hour)
+	if err != nil {
+		t.Fatalf("NewFileStore: %v", err)
+	}
+	past := time.Now().Add(-2 * time.Hour)
+	if err = store.Save(ctx, &Record{ID: "resp_old", Input: []byte(`[]`), Response: []byte(`{}`), CreatedAt: past, ExpiresAt: past.Add(time.Hour)}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	oldPath := filepath.Join(dir, "resp_old.json")
+	if err = os.Chtimes(oldPath, past, past); err != nil {
+		t.Fatalf("Chtimes: %v", err)
+	}
+
+	store.lastSweep = time.Time{}
+	if err = store.Save(ctx, &Record{ID: "resp_new", Input: []byte(`[]`), Response: []byte(`{}`)}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if _, err = os.Stat(oldPath); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expired file still present after sweep: %v", err)
+	}
+	if _, err = store.Load(ctx, "resp_new"); err != nil {
+		t.Fatalf("Load live: %v", err)
+	}
+}
@@ -183,7 +183,7 @@ func (e *CodexExecutor) Execute(ctx context.Context, auth *cliproxyauth.Auth, re
 
 		var param any
 		out := sdktranslator.TranslateNonStream(ctx, to, from, req.Model, originalPayload, body, line, &param)
-		resp = cliproxyexecutor.Response{Payload: out, Headers: httpResp.Header.Clone()}
+		resp = cliproxyexecutor.Response{Payload: out, Headers: httpResp.Header.Clone(), RateLimit: cliproxyexecutor.ParseRateLimitHeaders(httpResp.Header, time.Now())}
 		return resp, nil
 	}
 	err = statusErr{code: 408, msg: "stream error: stream disconnected before completion: stream closed before response.completed"}
@@ -274,7 +274,7 @@ func (e *CodexExecutor) executeCompact(ctx context.Context, auth *cliproxyauth.A
 	reporter.EnsurePublished(ctx)
 	var param any
 	out := sdktranslator.TranslateNonStream(ctx, to, from, req.Model, originalPayload, body, data, &param)
-	resp = cliproxyexecutor.Response{Payload: out, Headers: httpResp.Header.Clone()}
+	resp = cliproxyexecutor.Response{Payload: out, Headers: httpResp.Header.Clone(), RateLimit: cliproxyexecutor.ParseRateLimitHeaders(httpResp.Header, time.Now())}
 	return resp, nil
 }
 
@@ -398,7 +398,7 @@ func (e *CodexExecutor) ExecuteStream(ctx context.Context, auth *cliproxyauth.Au
 			out <- cliproxyexecutor.StreamChunk{Err: errScan}
 		}
 	}()
-	return &cliproxyexecutor.StreamResult{Headers: httpResp.Header.Clone(), Chunks: out}, nil
+	return &cliproxyexecutor.StreamResult{Headers: httpResp.Header.Clone(), RateLimit: cliproxyexecutor.ParseRateLimitHeaders(httpResp.Header, time.Now()), Chunks: out}, nil
 }
 
 func (e *CodexExecutor) CountTokens(ctx context.Context, auth *cliproxyauth.Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
@@ -559,6 +559,24 @@ func countCodexInputTokens(enc tokenizer.Codec, body []byte) (int64, error) {
 	return int64(count), nil
 }
 
+// Embed creates embeddings with the OpenAI "/embeddings" endpoint. Only API key credentials can
+// do so; the ChatGPT backend used by OAuth logins has no embeddings endpoint.
+func (e *CodexExecutor) Embed(ctx context.Context, auth *cliproxyauth.Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	var apiKey, baseURL string
+	if auth != nil && auth.Attributes != nil {
+		apiKey = strings.TrimSpace(auth.Attributes["api_key"])
+		baseURL = strings.TrimSpace(auth.Attributes["base_url"])
+	}
+	if apiKey == "" {
+		return cliproxyexecutor.Response{}, statusErr{code: http.StatusNotImplemented, msg: "embeddings require a codex API key"}
+	}
+	if baseURL == "" || strings.HasPrefix(baseURL, "https://chatgpt.com/") {
+		baseURL = "https://api.openai.com/v1"
+	}
+	url := strings.TrimSuffix(baseURL, "/") + "/embeddings"
+	return embedWithOpenAI(ctx, e.cfg, e.Identifier(), auth, req, opts, url, apiKey, "")
+}
+
 func (e *CodexExecutor) Refresh(ctx context.Context, auth *cliproxyauth.Auth) (*cliproxyauth.Auth, error) {
 	log.Debugf("codex executor: refresh called")
 	if auth == nil {
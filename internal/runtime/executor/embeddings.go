@@ -0,0 +1,377 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/runtime/executor/helps"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/thinking"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// embeddingRequest is the provider-neutral form of an OpenAI or Gemini embeddings request.
+type embeddingRequest struct {
+	inputs     []string
+	dimensions int64
+	taskType   string
+	title      string
+	// batch reports whether a Gemini request used batchEmbedContents.
+	batch bool
+	// base64 reports whether an OpenAI request asked for base64 encoded vectors.
+	base64 bool
+}
+
+// embeddingResult holds the vectors returned upstream as raw JSON arrays, in input order.
+type embeddingResult struct {
+	vectors     []gjson.Result
+	inputTokens int64
+}
+
+func invalidEmbeddingRequest(format string, args ...any) error {
+	body, _ := json.Marshal(map[string]any{
+		"error": map[string]any{
+			"message": fmt.Sprintf(format, args...),
+			"type":    "invalid_request_error",
+		},
+	})
+	return statusErr{code: http.StatusBadRequest, msg: string(body)}
+}
+
+// embeddingAction returns the Gemini method recorded by the handler, if any.
+func embeddingAction(req cliproxyexecutor.Request) string {
+	if req.Metadata == nil {
+		return ""
+	}
+	action, _ := req.Metadata["action"].(string)
+	return action
+}
+
+// parseEmbeddingRequest reads an embeddings request in the OpenAI or Gemini format.
+func parseEmbeddingRequest(from sdktranslator.Format, action string, payload []byte) (embeddingRequest, error) {
+	var out embeddingRequest
+	if from.String() == "gemini" {
+		requests := gjson.GetBytes(payload, "requests")
+		if action == "batchEmbedContents" || requests.IsArray() {
+			out.batch = true
+			for i, item := range requests.Array() {
+				if i == 0 {
+					out.taskType = item.Get("taskType").String()
+					out.title = item.Get("title").String()
+					out.dimensions = item.Get("outputDimensionality").Int()
+				}
+				out.inputs = append(out.inputs, geminiContentText(item.Get("content")))
+			}
+		} else {
+			root := gjson.ParseBytes(payload)
+			out.taskType = root.Get("taskType").String()
+			out.title = root.Get("title").String()
+			out.dimensions = root.Get("outputDimensionality").Int()
+			if content := root.Get("content"); content.Exists() {
+				out.inputs = append(out.inputs, geminiContentText(content))
+			}
+		}
+	} else {
+		root := gjson.ParseBytes(payload)
+		input := root.Get("input")
+		switch {
+		case input.Type == gjson.String:
+			out.inputs = append(out.inputs, input.String())
+		case input.IsArray():
+			for _, item := range input.Array() {
+				if item.Type != gjson.String {
+					return out, invalidEmbeddingRequest("token array inputs are only supported by OpenAI-compatible upstreams")
+				}
+				out.inputs = append(out.inputs, item.String())
+			}
+		}
+		out.dimensions = root.Get("dimensions").Int()
+		out.base64 = root.Get("encoding_format").String() == "base64"
+	}
+	if len(out.inputs) == 0 {
+		return out, invalidEmbeddingRequest("embeddings request has no input")
+	}
+	return out, nil
+}
+
+func geminiContentText(content gjson.Result) string {
+	var parts []string
+	for _, part := range content.Get("parts").Array() {
+		if text := part.Get("text"); text.Exists() {
+			parts = append(parts, text.String())
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// buildGeminiEmbeddingRequest builds a batchEmbedContents body, which covers single inputs too.
+func buildGeminiEmbeddingRequest(model string, req embeddingRequest) []byte {
+	out := []byte(`{"requests":[]}`)
+	for _, text := range req.inputs {
+		item := []byte(`{}`)
+		item, _ = sjson.SetBytes(item, "model", "models/"+model)
+		item, _ = sjson.SetBytes(item, "content.parts.0.text", text)
+		if req.taskType != "" {
+			item, _ = sjson.SetBytes(item, "taskType", req.taskType)
+		}
+		if req.title != "" {
+			item, _ = sjson.SetBytes(item, "title", req.title)
+		}
+		if req.dimensions > 0 {
+			item, _ = sjson.SetBytes(item, "outputDimensionality", req.dimensions)
+		}
+		out, _ = sjson.SetRawBytes(out, "requests.-1", item)
+	}
+	return out
+}
+
+func parseGeminiEmbeddingResponse(data []byte) embeddingResult {
+	var out embeddingResult
+	if single := gjson.GetBytes(data, "embedding.values"); single.Exists() {
+		out.vectors = append(out.vectors, single)
+		return out
+	}
+	for _, item := range gjson.GetBytes(data, "embeddings").Array() {
+		out.vectors = append(out.vectors, item.Get("values"))
+	}
+	return out
+}
+
+// buildVertexEmbeddingRequest builds a Vertex AI predict body.
+func buildVertexEmbeddingRequest(req embeddingRequest) []byte {
+	out := []byte(`{"instances":[]}`)
+	for _, text := range req.inputs {
+		item := []byte(`{}`)
+		item, _ = sjson.SetBytes(item, "content", text)
+		if req.taskType != "" {
+			item, _ = sjson.SetBytes(item, "task_type", req.taskType)
+		}
+		if req.title != "" {
+			item, _ = sjson.SetBytes(item, "title", req.title)
+		}
+		out, _ = sjson.SetRawBytes(out, "instances.-1", item)
+	}
+	if req.dimensions > 0 {
+		out, _ = sjson.SetBytes(out, "parameters.outputDimensionality", req.dimensions)
+	}
+	return out
+}
+
+func parseVertexEmbeddingResponse(data []byte) embeddingResult {
+	var out embeddingResult
+	for _, prediction := range gjson.GetBytes(data, "predictions").Array() {
+		out.vectors = append(out.vectors, prediction.Get("embeddings.values"))
+		out.inputTokens += prediction.Get("embeddings.statistics.token_count").Int()
+	}
+	return out
+}
+
+func buildOpenAIEmbeddingRequest(model string, req embeddingRequest) []byte {
+	out := []byte(`{}`)
+	out, _ = sjson.SetBytes(out, "model", model)
+	out, _ = sjson.SetBytes(out, "input", req.inputs)
+	if req.dimensions > 0 {
+		out, _ = sjson.SetBytes(out, "dimensions", req.dimensions)
+	}
+	return out
+}
+
+func parseOpenAIEmbeddingResponse(data []byte) embeddingResult {
+	items := gjson.GetBytes(data, "data").Array()
+	out := embeddingResult{
+		vectors:     make([]gjson.Result, len(items)),
+		inputTokens: gjson.GetBytes(data, "usage.prompt_tokens").Int(),
+	}
+	for i, item := range items {
+		index := i
+		if idx := item.Get("index"); idx.Exists() && idx.Int() >= 0 && idx.Int() < int64(len(items)) {
+			index = int(idx.Int())
+		}
+		out.vectors[index] = item.Get("embedding")
+	}
+	return out
+}
+
+// renderEmbeddingResponse renders result in the request's source format.
+func renderEmbeddingResponse(from sdktranslator.Format, model string, req embeddingRequest, result embeddingResult) ([]byte, error) {
+	if len(result.vectors) != len(req.inputs) {
+		return nil, statusErr{code: http.StatusBadGateway, msg: fmt.Sprintf("upstream returned %d embeddings for %d inputs", len(result.vectors), len(req.inputs))}
+	}
+	if from.String() == "gemini" {
+		if !req.batch {
+			out, _ := sjson.SetRawBytes([]byte(`{}`), "embedding.values", []byte(result.vectors[0].Raw))
+			return out, nil
+		}
+		out := []byte(`{"embeddings":[]}`)
+		for _, vector := range result.vectors {
+			item, _ := sjson.SetRawBytes([]byte(`{}`), "values", []byte(vector.Raw))
+			out, _ = sjson.SetRawBytes(out, "embeddings.-1", item)
+		}
+		return out, nil
+	}
+
+	out := []byte(`{"object":"list","data":[]}`)
+	for i, vector := range result.vectors {
+		item := []byte(`{"object":"embedding"}`)
+		item, _ = sjson.SetBytes(item, "index", i)
+		if req.base64 {
+			item, _ = sjson.SetBytes(item, "embedding", encodeEmbeddingBase64(vector))
+		} else {
+			item, _ = sjson.SetRawBytes(item, "embedding", []byte(vector.Raw))
+		}
+		out, _ = sjson.SetRawBytes(out, "data.-1", item)
+	}
+	out, _ = sjson.SetBytes(out, "model", model)
+	out, _ = sjson.SetBytes(out, "usage.prompt_tokens", result.inputTokens)
+	out, _ = sjson.SetBytes(out, "usage.total_tokens", result.inputTokens)
+	return out, nil
+}
+
+// encodeEmbeddingBase64 encodes a vector the way OpenAI does for encoding_format=base64:
+// little-endian float32 values.
+func encodeEmbeddingBase64(vector gjson.Result) string {
+	values := vector.Array()
+	buf := make([]byte, 4*len(values))
+	for i, value := range values {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(float32(value.Float())))
+	}
+	return base64.StdEncoding.EncodeToString(buf)
+}
+
+// estimateEmbeddingTokens approximates input usage for upstreams that do not report it.
+func estimateEmbeddingTokens(model string, inputs []string) int64 {
+	enc, err := helps.TokenizerForModel(model)
+	if err != nil {
+		return 0
+	}
+	var total int64
+	for _, input := range inputs {
+		if count, errCount := enc.Count(input); errCount == nil {
+			total += int64(count)
+		}
+	}
+	return total
+}
+
+func publishEmbeddingUsage(ctx context.Context, reporter *helps.UsageReporter, inputTokens int64) {
+	if inputTokens > 0 {
+		reporter.Publish(ctx, usage.Detail{InputTokens: inputTokens, TotalTokens: inputTokens})
+		return
+	}
+	reporter.EnsurePublished(ctx)
+}
+
+// sendEmbeddingRequest performs an embeddings call and returns the successful response body.
+func sendEmbeddingRequest(ctx context.Context, cfg *config.Config, provider string, auth *cliproxyauth.Auth, httpReq *http.Request, body []byte) ([]byte, http.Header, error) {
+	var authID, authLabel, authType, authValue string
+	if auth != nil {
+		authID = auth.ID
+		authLabel = auth.Label
+		authType, authValue = auth.AccountInfo()
+	}
+	helps.RecordAPIRequest(ctx, cfg, helps.UpstreamRequestLog{
+		URL:       httpReq.URL.String(),
+		Method:    httpReq.Method,
+		Headers:   httpReq.Header.Clone(),
+		Body:      body,
+		Provider:  provider,
+		AuthID:    authID,
+		AuthLabel: authLabel,
+		AuthType:  authType,
+		AuthValue: authValue,
+	})
+
+	httpClient := helps.NewProxyAwareHTTPClient(ctx, cfg, auth, 0)
+	httpResp, err := httpClient.Do(httpReq)
+	if err != nil {
+		helps.RecordAPIResponseError(ctx, cfg, err)
+		return nil, nil, err
+	}
+	defer func() {
+		if errClose := httpResp.Body.Close(); errClose != nil {
+			helps.LogWithRequestID(ctx).Errorf("response body close error: %v", errClose)
+		}
+	}()
+	helps.RecordAPIResponseMetadata(ctx, cfg, httpResp.StatusCode, httpResp.Header.Clone())
+
+	data, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		helps.RecordAPIResponseError(ctx, cfg, err)
+		return nil, nil, err
+	}
+	helps.AppendAPIResponseChunk(ctx, cfg, data)
+	if httpResp.StatusCode < 200 || httpResp.StatusCode >= 300 {
+		helps.LogWithRequestID(ctx).Debugf("request error, error status: %d, error message: %s", httpResp.StatusCode, helps.SummarizeErrorBody(httpResp.Header.Get("Content-Type"), data))
+		return nil, nil, statusErr{code: httpResp.StatusCode, msg: string(data)}
+	}
+	return data, httpResp.Header.Clone(), nil
+}
+
+// embedWithOpenAI creates embeddings against an OpenAI-compatible "/embeddings" endpoint.
+// OpenAI requests are forwarded unchanged apart from the model, so token array inputs and
+// encoding_format keep working; Gemini requests are converted both ways.
+func embedWithOpenAI(ctx context.Context, cfg *config.Config, provider string, auth *cliproxyauth.Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options, url, apiKey, userAgent string) (resp cliproxyexecutor.Response, err error) {
+	baseModel := thinking.ParseSuffix(req.Model).ModelName
+
+	reporter := helps.NewUsageReporter(ctx, provider, baseModel, auth)
+	defer reporter.TrackFailure(ctx, &err)
+
+	passthrough := opts.SourceFormat.String() != "gemini"
+	var parsed embeddingRequest
+	var body []byte
+	if passthrough {
+		body, _ = sjson.SetBytes(req.Payload, "model", baseModel)
+	} else {
+		parsed, err = parseEmbeddingRequest(opts.SourceFormat, embeddingAction(req), req.Payload)
+		if err != nil {
+			return resp, err
+		}
+		body = buildOpenAIEmbeddingRequest(baseModel, parsed)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return resp, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+	if userAgent != "" {
+		httpReq.Header.Set("User-Agent", userAgent)
+	}
+	var attrs map[string]string
+	if auth != nil {
+		attrs = auth.Attributes
+	}
+	util.ApplyCustomHeadersFromAttrs(httpReq, attrs)
+
+	data, headers, err := sendEmbeddingRequest(ctx, cfg, provider, auth, httpReq, body)
+	if err != nil {
+		return resp, err
+	}
+	result := parseOpenAIEmbeddingResponse(data)
+	publishEmbeddingUsage(ctx, reporter, result.inputTokens)
+	if passthrough {
+		return cliproxyexecutor.Response{Payload: data, Headers: headers}, nil
+	}
+	out, err := renderEmbeddingResponse(opts.SourceFormat, baseModel, parsed, result)
+	if err != nil {
+		return resp, err
+	}
+	return cliproxyexecutor.Response{Payload: out, Headers: headers}, nil
+}
@@ -0,0 +1,103 @@
+package executor
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+	"github.com/tidwall/gjson"
+)
+
+func TestGeminiExecutorEmbedTranslatesOpenAIRequest(t *testing.T) {
+	var gotPath string
+	var gotBody []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotBody, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"embeddings":[{"values":[0.5,-1]},{"values":[0.25,2]}]}`))
+	}))
+	defer server.Close()
+
+	executor := NewGeminiExecutor(&config.Config{})
+	auth := &cliproxyauth.Auth{Attributes: map[string]string{
+		"api_key":  "test",
+		"base_url": server.URL,
+	}}
+	resp, err := executor.Embed(context.Background(), auth, cliproxyexecutor.Request{
+		Model:   "gemini-embedding-001",
+		Payload: []byte(`{"model":"gemini-embedding-001","input":["a","b"],"dimensions":2,"encoding_format":"base64"}`),
+	}, cliproxyexecutor.Options{SourceFormat: sdktranslator.FromString("openai")})
+	if err != nil {
+		t.Fatalf("Embed error: %v", err)
+	}
+	if gotPath != "/v1beta/models/gemini-embedding-001:batchEmbedContents" {
+		t.Fatalf("path = %q", gotPath)
+	}
+	if got := gjson.GetBytes(gotBody, "requests.1.content.parts.0.text").String(); got != "b" {
+		t.Fatalf("second request text = %q, body %s", got, gotBody)
+	}
+	if got := gjson.GetBytes(gotBody, "requests.0.outputDimensionality").Int(); got != 2 {
+		t.Fatalf("outputDimensionality = %d", got)
+	}
+
+	if got := gjson.GetBytes(resp.Payload, "data.#").Int(); got != 2 {
+		t.Fatalf("data length = %d, payload %s", got, resp.Payload)
+	}
+	raw, err := base64.StdEncoding.DecodeString(gjson.GetBytes(resp.Payload, "data.0.embedding").String())
+	if err != nil || len(raw) != 8 {
+		t.Fatalf("base64 embedding = %v (%d bytes)", err, len(raw))
+	}
+	if got := math.Float32frombits(binary.LittleEndian.Uint32(raw[4:])); got != -1 {
+		t.Fatalf("decoded value = %v, want -1", got)
+	}
+}
+
+func TestOpenAICompatExecutorEmbedRendersGeminiResponse(t *testing.T) {
+	var gotBody []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotBody, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"object":"list","data":[{"object":"embedding","index":0,"embedding":[0.1,0.2]}],"usage":{"prompt_tokens":3,"total_tokens":3}}`))
+	}))
+	defer server.Close()
+
+	executor := NewOpenAICompatExecutor("openai-compatibility", &config.Config{})
+	auth := &cliproxyauth.Auth{Attributes: map[string]string{
+		"base_url": server.URL + "/v1",
+		"api_key":  "test",
+	}}
+	resp, err := executor.Embed(context.Background(), auth, cliproxyexecutor.Request{
+		Model:    "text-embedding-3-small",
+		Payload:  []byte(`{"content":{"parts":[{"text":"hello"}]}}`),
+		Metadata: map[string]any{"action": "embedContent"},
+	}, cliproxyexecutor.Options{SourceFormat: sdktranslator.FromString("gemini")})
+	if err != nil {
+		t.Fatalf("Embed error: %v", err)
+	}
+	if got := gjson.GetBytes(gotBody, "input.0").String(); got != "hello" {
+		t.Fatalf("upstream input = %q, body %s", got, gotBody)
+	}
+	if got := gjson.GetBytes(resp.Payload, "embedding.values").Raw; got != "[0.1,0.2]" {
+		t.Fatalf("payload = %s", resp.Payload)
+	}
+}
+
+func TestParseEmbeddingRequestRejectsTokenArraysForConversion(t *testing.T) {
+	_, err := parseEmbeddingRequest(sdktranslator.FromString("openai"), "", []byte(`{"input":[[1,2,3]]}`))
+	if err == nil {
+		t.Fatal("expected error for token array input")
+	}
+	if se, ok := err.(statusErr); !ok || se.code != http.StatusBadRequest {
+		t.Fatalf("error = %#v, want 400 statusErr", err)
+	}
+}
@@ -426,6 +426,49 @@ func (e *GeminiExecutor) CountTokens(ctx context.Context, auth *cliproxyauth.Aut
 	return cliproxyexecutor.Response{Payload: translated, Headers: resp.Header.Clone()}, nil
 }
 
+// Embed creates embeddings with the batchEmbedContents method, which serves single and batch
+// requests alike. The Gemini API does not report usage, so input tokens are estimated.
+func (e *GeminiExecutor) Embed(ctx context.Context, auth *cliproxyauth.Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (resp cliproxyexecutor.Response, err error) {
+	baseModel := thinking.ParseSuffix(req.Model).ModelName
+
+	reporter := helps.NewUsageReporter(ctx, e.Identifier(), baseModel, auth)
+	defer reporter.TrackFailure(ctx, &err)
+
+	parsed, err := parseEmbeddingRequest(opts.SourceFormat, embeddingAction(req), req.Payload)
+	if err != nil {
+		return resp, err
+	}
+	body := buildGeminiEmbeddingRequest(baseModel, parsed)
+
+	apiKey, bearer := geminiCreds(auth)
+	baseURL := resolveGeminiBaseURL(auth)
+	url := fmt.Sprintf("%s/%s/models/%s:%s", baseURL, glAPIVersion, baseModel, "batchEmbedContents")
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return resp, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		httpReq.Header.Set("x-goog-api-key", apiKey)
+	} else {
+		httpReq.Header.Set("Authorization", "Bearer "+bearer)
+	}
+	applyGeminiHeaders(httpReq, auth)
+
+	data, headers, err := sendEmbeddingRequest(ctx, e.cfg, e.Identifier(), auth, httpReq, body)
+	if err != nil {
+		return resp, err
+	}
+	result := parseGeminiEmbeddingResponse(data)
+	result.inputTokens = estimateEmbeddingTokens(baseModel, parsed.inputs)
+	publishEmbeddingUsage(ctx, reporter, result.inputTokens)
+	out, err := renderEmbeddingResponse(opts.SourceFormat, baseModel, parsed, result)
+	if err != nil {
+		return resp, err
+	}
+	return cliproxyexecutor.Response{Payload: out, Headers: headers}, nil
+}
+
 // Refresh refreshes the authentication credentials (no-op for Gemini API key).
 func (e *GeminiExecutor) Refresh(_ context.Context, auth *cliproxyauth.Auth) (*cliproxyauth.Auth, error) {
 	return auth, nil
@@ -293,6 +293,71 @@ func (e *GeminiVertexExecutor) CountTokens(ctx context.Context, auth *cliproxyau
 	return e.countTokensWithAPIKey(ctx, auth, req, opts, apiKey, baseURL)
 }
 
+// Embed creates embeddings with the Vertex AI predict method, authenticating with an API key
+// when one is configured and with the service account otherwise.
+func (e *GeminiVertexExecutor) Embed(ctx context.Context, auth *cliproxyauth.Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (resp cliproxyexecutor.Response, err error) {
+	baseModel := thinking.ParseSuffix(req.Model).ModelName
+
+	reporter := helps.NewUsageReporter(ctx, e.Identifier(), baseModel, auth)
+	defer reporter.TrackFailure(ctx, &err)
+
+	parsed, err := parseEmbeddingRequest(opts.SourceFormat, embeddingAction(req), req.Payload)
+	if err != nil {
+		return resp, err
+	}
+	body := buildVertexEmbeddingRequest(parsed)
+
+	var url, bearer string
+	apiKey, baseURL := vertexAPICreds(auth)
+	if apiKey != "" {
+		if baseURL == "" {
+			baseURL = "https://aiplatform.googleapis.com"
+		}
+		url = fmt.Sprintf("%s/%s/publishers/google/models/%s:%s", baseURL, vertexAPIVersion, baseModel, "predict")
+	} else {
+		projectID, location, saJSON, errCreds := vertexCreds(auth)
+		if errCreds != nil {
+			return resp, errCreds
+		}
+		token, errTok := vertexAccessToken(ctx, e.cfg, auth, saJSON)
+		if errTok != nil {
+			log.Errorf("vertex executor: access token error: %v", errTok)
+			return resp, statusErr{code: 500, msg: "internal server error"}
+		}
+		bearer = token
+		url = fmt.Sprintf("%s/%s/projects/%s/locations/%s/publishers/google/models/%s:%s", vertexBaseURL(location), vertexAPIVersion, projectID, location, baseModel, "predict")
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return resp, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		httpReq.Header.Set("x-goog-api-key", apiKey)
+	} else if bearer != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+bearer)
+	}
+	applyGeminiHeaders(httpReq, auth)
+	var attrs map[string]string
+	if auth != nil {
+		attrs = auth.Attributes
+	}
+	util.ApplyCustomHeadersFromAttrs(httpReq, attrs)
+
+	data, headers, err := sendEmbeddingRequest(ctx, e.cfg, e.Identifier(), auth, httpReq, body)
+	if err != nil {
+		return resp, err
+	}
+	result := parseVertexEmbeddingResponse(data)
+	publishEmbeddingUsage(ctx, reporter, result.inputTokens)
+	out, err := renderEmbeddingResponse(opts.SourceFormat, baseModel, parsed, result)
+	if err != nil {
+		return resp, err
+	}
+	return cliproxyexecutor.Response{Payload: out, Headers: headers}, nil
+}
+
 // Refresh refreshes the authentication credentials (no-op for Vertex).
 func (e *GeminiVertexExecutor) Refresh(_ context.Context, auth *cliproxyauth.Auth) (*cliproxyauth.Auth, error) {
 	return auth, nil
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	sdkaccess "github.com/router-for-me/CLIProxyAPI/v6/sdk/access"
 	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
 	"github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
 	"github.com/tidwall/gjson"
@@ -21,6 +22,7 @@ type UsageReporter struct {
 	authID      string
 	authIndex   string
 	apiKey      string
+	clientKey   string
 	source      string
 	requestedAt time.Time
 	once        sync.Once
@@ -33,6 +35,7 @@ func NewUsageReporter(ctx context.Context, provider, model string, auth *cliprox
 		model:       model,
 		requestedAt: time.Now(),
 		apiKey:      apiKey,
+		clientKey:   ClientKeyNameFromContext(ctx),
 		source:      resolveUsageSource(auth, apiKey),
 	}
 	if auth != nil {
@@ -99,6 +102,7 @@ func (r *UsageReporter) buildRecord(detail usage.Detail, failed bool) usage.Reco
 		Model:       r.model,
 		Source:      r.source,
 		APIKey:      r.apiKey,
+		ClientKey:   r.clientKey,
 		AuthID:      r.authID,
 		AuthIndex:   r.authIndex,
 		RequestedAt: r.requestedAt,
@@ -140,6 +144,24 @@ func APIKeyFromContext(ctx context.Context) string {
 	return ""
 }
 
+// ClientKeyNameFromContext returns the name of the managed client key that authenticated the
+// request, or "" for plain API keys.
+func ClientKeyNameFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	ginCtx, ok := ctx.Value("gin").(*gin.Context)
+	if !ok || ginCtx == nil {
+		return ""
+	}
+	if v, exists := ginCtx.Get("accessMetadata"); exists {
+		if metadata, ok := v.(map[string]string); ok {
+			return metadata[sdkaccess.MetadataClientKeyName]
+		}
+	}
+	return ""
+}
+
 func resolveUsageSource(auth *cliproxyauth.Auth, ctxAPIKey string) string {
 	if auth != nil {
 		provider := strings.TrimSpace(auth.Provider)
@@ -23,6 +23,7 @@ import (
 	"github.com/google/uuid"
 	kiroauth "github.com/router-for-me/CLIProxyAPI/v6/internal/auth/kiro"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/tokencrypt"
 	kiroclaude "github.com/router-for-me/CLIProxyAPI/v6/internal/translator/kiro/claude"
 	kirocommon "github.com/router-for-me/CLIProxyAPI/v6/internal/translator/kiro/common"
 	kiroopenai "github.com/router-for-me/CLIProxyAPI/v6/internal/translator/kiro/openai"
@@ -3816,6 +3817,9 @@ func (e *KiroExecutor) persistRefreshedAuth(auth *cliproxyauth.Auth) error {
 	if err != nil {
 		return fmt.Errorf("kiro executor: marshal metadata failed: %w", err)
 	}
+	if raw, err = tokencrypt.Seal(raw); err != nil {
+		return fmt.Errorf("kiro executor: encrypt metadata failed: %w", err)
+	}
 
 	// Write to temp file first, then rename (atomic write)
 	tmp := authPath + ".tmp"
@@ -3905,7 +3909,7 @@ func (e *KiroExecutor) reloadAuthFromFile(auth *cliproxyauth.Auth) (*cliproxyaut
 	}
 
 	// 读取文件
-	raw, err := os.ReadFile(authPath)
+	raw, err := tokencrypt.ReadFile(authPath)
 	if err != nil {
 		return nil, fmt.Errorf("kiro executor: failed to read auth file %s: %w", authPath, err)
 	}
@@ -17,7 +17,9 @@ import (
 
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
+	baseauth "github.com/router-for-me/CLIProxyAPI/v6/internal/auth"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/misc"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/tokencrypt"
 	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
 	log "github.com/sirupsen/logrus"
 )
@@ -38,6 +40,8 @@ type ObjectStoreConfig struct {
 	LocalRoot string
 	UseSSL    bool
 	PathStyle bool
+	// PollInterval controls how often WatchRemoteChanges checks the bucket; zero uses the default.
+	PollInterval time.Duration
 }
 
 // ObjectTokenStore persists configuration and authentication metadata using an S3-compatible object storage backend.
@@ -49,6 +53,11 @@ type ObjectTokenStore struct {
 	configPath string
 	authDir    string
 	mu         sync.Mutex
+
+	// remoteMu guards the ETags last mirrored from the bucket, keyed by full object key.
+	remoteMu   sync.Mutex
+	authETags  map[string]string
+	configETag string
 }
 
 // NewObjectTokenStore initializes an object storage backed token store.
@@ -115,6 +124,7 @@ func NewObjectTokenStore(cfg ObjectStoreConfig) (*ObjectTokenStore, error) {
 		spoolRoot:  absRoot,
 		configPath: filepath.Join(configDir, "config.yaml"),
 		authDir:    authDir,
+		authETags:  make(map[string]string),
 	}, nil
 }
 
@@ -184,7 +194,7 @@ func (s *ObjectTokenStore) Save(ctx context.Context, auth *cliproxyauth.Auth) (s
 
 	switch {
 	case auth.Storage != nil:
-		if err = auth.Storage.SaveTokenToFile(path); err != nil {
+		if err = baseauth.SaveSealedToken(auth.Storage, path); err != nil {
 			return "", err
 		}
 	case auth.Metadata != nil:
@@ -193,12 +203,15 @@ func (s *ObjectTokenStore) Save(ctx context.Context, auth *cliproxyauth.Auth) (s
 			return "", fmt.Errorf("object store: marshal metadata: %w", errMarshal)
 		}
 		if existing, errRead := os.ReadFile(path); errRead == nil {
-			if jsonEqual(existing, raw) {
+			if plain, errOpen := tokencrypt.Open(existing); errOpen == nil && jsonEqual(plain, raw) && tokencrypt.UpToDate(existing) {
 				return path, nil
 			}
 		} else if errRead != nil && !errors.Is(errRead, fs.ErrNotExist) {
 			return "", fmt.Errorf("object store: read existing metadata: %w", errRead)
 		}
+		if raw, errMarshal = tokencrypt.Seal(raw); errMarshal != nil {
+			return "", fmt.Errorf("object store: encrypt metadata: %w", errMarshal)
+		}
 		tmp := path + ".tmp"
 		if errWrite := os.WriteFile(tmp, raw, 0o600); errWrite != nil {
 			return "", fmt.Errorf("object store: write temp auth file: %w", errWrite)
@@ -340,7 +353,7 @@ func (s *ObjectTokenStore) ensureBucket(ctx context.Context) error {
 
 func (s *ObjectTokenStore) syncConfigFromBucket(ctx context.Context, example string) error {
 	key := s.prefixedKey(objectStoreConfigKey)
-	_, err := s.client.StatObject(ctx, s.cfg.Bucket, key, minio.StatObjectOptions{})
+	info, err := s.client.StatObject(ctx, s.cfg.Bucket, key, minio.StatObjectOptions{})
 	switch {
 	case err == nil:
 		object, errGet := s.client.GetObject(ctx, s.cfg.Bucket, key, minio.GetObjectOptions{})
@@ -355,6 +368,9 @@ func (s *ObjectTokenStore) syncConfigFromBucket(ctx context.Context, example str
 		if errWrite := os.WriteFile(s.configPath, normalizeLineEndingsBytes(data), 0o600); errWrite != nil {
 			return fmt.Errorf("object store: write config: %w", errWrite)
 		}
+		s.remoteMu.Lock()
+		s.configETag = info.ETag
+		s.remoteMu.Unlock()
 	case isObjectNotFound(err):
 		if _, statErr := os.Stat(s.configPath); errors.Is(statErr, fs.ErrNotExist) {
 			if example != "" {
@@ -403,21 +419,10 @@ func (s *ObjectTokenStore) syncAuthFromBucket(ctx context.Context) error {
 		if object.Err != nil {
 			return fmt.Errorf("object store: list auth objects: %w", object.Err)
 		}
-		rel := strings.TrimPrefix(object.Key, prefix)
-		if rel == "" || strings.HasSuffix(rel, "/") {
-			continue
-		}
-		relPath := filepath.FromSlash(rel)
-		if filepath.IsAbs(relPath) {
-			log.WithField("key", object.Key).Warn("object store: skip auth outside mirror")
-			continue
-		}
-		cleanRel := filepath.Clean(relPath)
-		if cleanRel == "." || cleanRel == ".." || strings.HasPrefix(cleanRel, ".."+string(os.PathSeparator)) {
-			log.WithField("key", object.Key).Warn("object store: skip auth outside mirror")
+		local, ok := s.authMirrorPath(prefix, object.Key)
+		if !ok {
 			continue
 		}
-		local := filepath.Join(s.authDir, cleanRel)
 		if err := os.MkdirAll(filepath.Dir(local), 0o700); err != nil {
 			return fmt.Errorf("object store: prepare auth subdir: %w", err)
 		}
@@ -433,10 +438,33 @@ func (s *ObjectTokenStore) syncAuthFromBucket(ctx context.Context) error {
 		if errWrite := os.WriteFile(local, data, 0o600); errWrite != nil {
 			return fmt.Errorf("object store: write auth %s: %w", local, errWrite)
 		}
+		s.remoteMu.Lock()
+		s.authETags[object.Key] = object.ETag
+		s.remoteMu.Unlock()
 	}
 	return nil
 }
 
+// authMirrorPath maps an auth object key below prefix to its mirror file, rejecting keys that
+// would escape the auth directory.
+func (s *ObjectTokenStore) authMirrorPath(prefix, key string) (string, bool) {
+	rel := strings.TrimPrefix(key, prefix)
+	if rel == "" || strings.HasSuffix(rel, "/") {
+		return "", false
+	}
+	relPath := filepath.FromSlash(rel)
+	if filepath.IsAbs(relPath) {
+		log.WithField("key", key).Warn("object store: skip auth outside mirror")
+		return "", false
+	}
+	cleanRel := filepath.Clean(relPath)
+	if cleanRel == "." || cleanRel == ".." || strings.HasPrefix(cleanRel, ".."+string(os.PathSeparator)) {
+		log.WithField("key", key).Warn("object store: skip auth outside mirror")
+		return "", false
+	}
+	return filepath.Join(s.authDir, cleanRel), true
+}
+
 func (s *ObjectTokenStore) uploadAuth(ctx context.Context, path string) error {
 	if path == "" {
 		return nil
@@ -561,6 +589,9 @@ func (s *ObjectTokenStore) readAuthFile(path, baseDir string) (*cliproxyauth.Aut
 	if len(data) == 0 {
 		return nil, nil
 	}
+	if data, err = tokencrypt.Open(data); err != nil {
+		return nil, err
+	}
 	metadata := make(map[string]any)
 	if err = json.Unmarshal(data, &metadata); err != nil {
 		return nil, fmt.Errorf("unmarshal auth json: %w", err)
@@ -0,0 +1,168 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	log "github.com/sirupsen/logrus"
+)
+
+const objectStoreLockPrefix = "locks"
+
+// storeInstanceID identifies this process in refresh leases and change notifications.
+var storeInstanceID = newStoreInstanceID()
+
+func newStoreInstanceID() string {
+	host, _ := os.Hostname()
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+	return host + "-" + strconv.Itoa(os.Getpid()) + "-" + hex.EncodeToString(suffix)
+}
+
+type objectRefreshLease struct {
+	Holder     string    `json:"holder,omitempty"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	ReleasedAt time.Time `json:"released_at,omitempty"`
+}
+
+// LockRefresh takes the refresh lease of auth stored at "locks/<auth>.lock" in the bucket.
+// Leases are swapped with If-Match conditional writes, so the backend must support them
+// (MinIO and S3 do). A lease whose holder died lapses after ttl.
+func (s *ObjectTokenStore) LockRefresh(ctx context.Context, auth *cliproxyauth.Auth, ttl time.Duration) (func(), error) {
+	path, err := s.resolveAuthPath(auth)
+	if err != nil {
+		return nil, err
+	}
+	rel, err := filepath.Rel(s.authDir, path)
+	if err != nil {
+		return nil, fmt.Errorf("object store: resolve auth relative path: %w", err)
+	}
+	key := objectStoreLockPrefix + "/" + filepath.ToSlash(rel) + ".lock"
+
+	for {
+		data, etag, found, errGet := s.getObjectWithETag(ctx, key)
+		if errGet != nil {
+			return nil, errGet
+		}
+		if !found {
+			// Seed a free lease so the swap below has an ETag to match. Two replicas seeding at
+			// once both write a free lease, which is harmless.
+			raw, _ := json.Marshal(objectRefreshLease{ReleasedAt: time.Now()})
+			if errPut := s.putObject(ctx, key, raw, "application/json"); errPut != nil {
+				return nil, errPut
+			}
+			continue
+		}
+		var lease objectRefreshLease
+		if json.Unmarshal(data, &lease) == nil && lease.Holder != "" && lease.Holder != storeInstanceID && time.Now().Before(lease.ExpiresAt) {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(refreshLockPollInterval):
+			}
+			continue
+		}
+		raw, _ := json.Marshal(objectRefreshLease{Holder: storeInstanceID, ExpiresAt: time.Now().Add(ttl)})
+		ownETag, errSwap := s.swapObject(ctx, key, raw, etag)
+		if errSwap != nil {
+			if isPreconditionFailed(errSwap) {
+				continue
+			}
+			return nil, fmt.Errorf("object store: take refresh lease %s: %w", key, errSwap)
+		}
+		return func() { s.releaseRefreshLease(key, ownETag) }, nil
+	}
+}
+
+// releaseRefreshLease frees the lease unless it has been taken over since it was acquired.
+func (s *ObjectTokenStore) releaseRefreshLease(key, etag string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	raw, _ := json.Marshal(objectRefreshLease{ReleasedAt: time.Now()})
+	if _, err := s.swapObject(ctx, key, raw, etag); err != nil && !isPreconditionFailed(err) {
+		log.WithError(err).Warnf("object store: release refresh lease %s", key)
+	}
+}
+
+// ReloadAuth downloads the latest version of auth from the bucket and refreshes its local mirror.
+func (s *ObjectTokenStore) ReloadAuth(ctx context.Context, auth *cliproxyauth.Auth) (*cliproxyauth.Auth, error) {
+	path, err := s.resolveAuthPath(auth)
+	if err != nil {
+		return nil, err
+	}
+	rel, err := filepath.Rel(s.authDir, path)
+	if err != nil {
+		return nil, fmt.Errorf("object store: resolve auth relative path: %w", err)
+	}
+	data, _, found, err := s.getObjectWithETag(ctx, objectStoreAuthPrefix+"/"+filepath.ToSlash(rel))
+	if err != nil {
+		return nil, err
+	}
+	if !found || len(data) == 0 {
+		return nil, nil
+	}
+
+	if err = s.writeMirrorFile(path, data); err != nil {
+		return nil, err
+	}
+	reloaded, err := s.readAuthFile(path, s.authDir)
+	if err != nil {
+		return nil, fmt.Errorf("object store: reload auth %s: %w", rel, err)
+	}
+	return reloaded, nil
+}
+
+func (s *ObjectTokenStore) getObjectWithETag(ctx context.Context, key string) ([]byte, string, bool, error) {
+	fullKey := s.prefixedKey(key)
+	object, err := s.client.GetObject(ctx, s.cfg.Bucket, fullKey, minio.GetObjectOptions{})
+	if err != nil {
+		if isObjectNotFound(err) {
+			return nil, "", false, nil
+		}
+		return nil, "", false, fmt.Errorf("object store: get object %s: %w", fullKey, err)
+	}
+	defer object.Close()
+	info, err := object.Stat()
+	if err != nil {
+		if isObjectNotFound(err) {
+			return nil, "", false, nil
+		}
+		return nil, "", false, fmt.Errorf("object store: stat object %s: %w", fullKey, err)
+	}
+	data, err := io.ReadAll(object)
+	if err != nil {
+		return nil, "", false, fmt.Errorf("object store: read object %s: %w", fullKey, err)
+	}
+	return data, info.ETag, true, nil
+}
+
+// swapObject writes data to key only if the object still has the given ETag and returns the new ETag.
+func (s *ObjectTokenStore) swapObject(ctx context.Context, key string, data []byte, etag string) (string, error) {
+	opts := minio.PutObjectOptions{ContentType: "application/json"}
+	opts.SetMatchETag(etag)
+	info, err := s.client.PutObject(ctx, s.cfg.Bucket, s.prefixedKey(key), bytes.NewReader(data), int64(len(data)), opts)
+	if err != nil {
+		return "", err
+	}
+	return info.ETag, nil
+}
+
+func isPreconditionFailed(err error) bool {
+	if err == nil {
+		return false
+	}
+	resp := minio.ToErrorResponse(err)
+	return resp.StatusCode == http.StatusPreconditionFailed || resp.Code == "PreconditionFailed"
+}
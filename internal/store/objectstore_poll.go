@@ -0,0 +1,161 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	log "github.com/sirupsen/logrus"
+)
+
+// defaultObjectStorePollInterval is how often the bucket is checked for changes made by other replicas.
+const defaultObjectStorePollInterval = 30 * time.Second
+
+// WatchRemoteChanges periodically compares object ETags in the bucket with the ones last mirrored
+// and pulls changed, new and deleted config and auth objects into the local workspace, where the
+// file watcher picks them up. It returns when ctx is canceled.
+func (s *ObjectTokenStore) WatchRemoteChanges(ctx context.Context) {
+	interval := s.cfg.PollInterval
+	if interval <= 0 {
+		interval = defaultObjectStorePollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := s.pollRemoteChanges(ctx); err != nil && ctx.Err() == nil {
+			log.WithError(err).Warn("object store: poll remote changes")
+		}
+	}
+}
+
+func (s *ObjectTokenStore) pollRemoteChanges(ctx context.Context) error {
+	prefix := s.prefixedKey(objectStoreAuthPrefix + "/")
+	objectCh := s.client.ListObjects(ctx, s.cfg.Bucket, minio.ListObjectsOptions{
+		Prefix:    prefix,
+		Recursive: true,
+	})
+	seen := make(map[string]string)
+	for object := range objectCh {
+		if object.Err != nil {
+			return fmt.Errorf("object store: list auth objects: %w", object.Err)
+		}
+		local, ok := s.authMirrorPath(prefix, object.Key)
+		if !ok {
+			continue
+		}
+		s.remoteMu.Lock()
+		known, tracked := s.authETags[object.Key]
+		s.remoteMu.Unlock()
+		if tracked && known == object.ETag {
+			seen[object.Key] = object.ETag
+			continue
+		}
+		data, errDownload := s.downloadObject(ctx, object.Key)
+		if errDownload != nil {
+			log.WithError(errDownload).Warnf("object store: pull auth %s", object.Key)
+			continue
+		}
+		if errWrite := s.writeMirrorFile(local, data); errWrite != nil {
+			log.WithError(errWrite).Warnf("object store: mirror auth %s", object.Key)
+			continue
+		}
+		seen[object.Key] = object.ETag
+	}
+
+	// Only objects that were mirrored before and have disappeared since count as remote deletions,
+	// so local files that are still being uploaded are left alone.
+	s.remoteMu.Lock()
+	var removed []string
+	for key := range s.authETags {
+		if _, ok := seen[key]; !ok {
+			removed = append(removed, key)
+		}
+	}
+	s.authETags = seen
+	s.remoteMu.Unlock()
+	for _, key := range removed {
+		local, ok := s.authMirrorPath(prefix, key)
+		if !ok {
+			continue
+		}
+		s.mu.Lock()
+		errRemove := os.Remove(local)
+		s.mu.Unlock()
+		if errRemove != nil && !errors.Is(errRemove, fs.ErrNotExist) {
+			log.WithError(errRemove).Warnf("object store: remove auth %s", local)
+		}
+	}
+
+	return s.pollConfig(ctx)
+}
+
+func (s *ObjectTokenStore) pollConfig(ctx context.Context) error {
+	key := s.prefixedKey(objectStoreConfigKey)
+	info, err := s.client.StatObject(ctx, s.cfg.Bucket, key, minio.StatObjectOptions{})
+	if err != nil {
+		if isObjectNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("object store: stat config: %w", err)
+	}
+	s.remoteMu.Lock()
+	unchanged := s.configETag == info.ETag
+	s.remoteMu.Unlock()
+	if unchanged {
+		return nil
+	}
+	data, err := s.downloadObject(ctx, key)
+	if err != nil {
+		return err
+	}
+	if err = s.writeMirrorFile(s.configPath, normalizeLineEndingsBytes(data)); err != nil {
+		return err
+	}
+	s.remoteMu.Lock()
+	s.configETag = info.ETag
+	s.remoteMu.Unlock()
+	return nil
+}
+
+// downloadObject reads the object stored under the full key.
+func (s *ObjectTokenStore) downloadObject(ctx context.Context, fullKey string) ([]byte, error) {
+	object, err := s.client.GetObject(ctx, s.cfg.Bucket, fullKey, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("object store: get object %s: %w", fullKey, err)
+	}
+	defer object.Close()
+	data, err := io.ReadAll(object)
+	if err != nil {
+		return nil, fmt.Errorf("object store: read object %s: %w", fullKey, err)
+	}
+	return data, nil
+}
+
+// writeMirrorFile replaces a workspace file with data unless it already has that content, so the
+// watcher only sees real changes.
+func (s *ObjectTokenStore) writeMirrorFile(path string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if existing, err := os.ReadFile(path); err == nil && bytes.Equal(existing, data) {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("object store: create mirror directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("object store: write mirror file %s: %w", path, err)
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package store
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -14,15 +15,25 @@ import (
 	"time"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
+	baseauth "github.com/router-for-me/CLIProxyAPI/v6/internal/auth"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/misc"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/tokencrypt"
 	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
 	log "github.com/sirupsen/logrus"
 )
 
 const (
+	// refreshLockPollInterval is how often a waiting replica retries a held refresh lock.
+	refreshLockPollInterval = 250 * time.Millisecond
+
 	defaultConfigTable = "config_store"
 	defaultAuthTable   = "auth_store"
 	defaultConfigKey   = "config"
+
+	// defaultRuntimeStateTable holds the saved auth cooldown state and client key usage rows.
+	defaultRuntimeStateTable = "runtime_state"
+	defaultRuntimeStateKey   = "runtime"
+	clientKeyUsageKey        = "client-key-usage"
 )
 
 // PostgresStoreConfig captures configuration required to initialize a Postgres-backed store.
@@ -43,6 +54,11 @@ type PostgresStore struct {
 	configPath string
 	authDir    string
 	mu         sync.Mutex
+
+	// remoteAuthIDs tracks the auth records known to exist in the database, so that
+	// reconciliation only removes mirror files whose record was deleted remotely.
+	remoteMu      sync.Mutex
+	remoteAuthIDs map[string]struct{}
 }
 
 // NewPostgresStore establishes a connection to PostgreSQL and prepares the local workspace.
@@ -95,6 +111,8 @@ func NewPostgresStore(ctx context.Context, cfg PostgresStoreConfig) (*PostgresSt
 		spoolRoot:  absSpool,
 		configPath: filepath.Join(configDir, "config.yaml"),
 		authDir:    authDir,
+
+		remoteAuthIDs: make(map[string]struct{}),
 	}
 	return store, nil
 }
@@ -140,6 +158,16 @@ func (s *PostgresStore) EnsureSchema(ctx context.Context) error {
 	`, authTable)); err != nil {
 		return fmt.Errorf("postgres store: create auth table: %w", err)
 	}
+	runtimeTable := s.fullTableName(defaultRuntimeStateTable)
+	if _, err := s.db.ExecContext(ctx, fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			id TEXT PRIMARY KEY,
+			content JSONB NOT NULL,
+			updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)
+	`, runtimeTable)); err != nil {
+		return fmt.Errorf("postgres store: create runtime state table: %w", err)
+	}
 	return nil
 }
 
@@ -214,7 +242,7 @@ func (s *PostgresStore) Save(ctx context.Context, auth *cliproxyauth.Auth) (stri
 
 	switch {
 	case auth.Storage != nil:
-		if err = auth.Storage.SaveTokenToFile(path); err != nil {
+		if err = baseauth.SaveSealedToken(auth.Storage, path); err != nil {
 			return "", err
 		}
 	case auth.Metadata != nil:
@@ -223,12 +251,15 @@ func (s *PostgresStore) Save(ctx context.Context, auth *cliproxyauth.Auth) (stri
 			return "", fmt.Errorf("postgres store: marshal metadata: %w", errMarshal)
 		}
 		if existing, errRead := os.ReadFile(path); errRead == nil {
-			if jsonEqual(existing, raw) {
+			if plain, errOpen := tokencrypt.Open(existing); errOpen == nil && jsonEqual(plain, raw) && tokencrypt.UpToDate(existing) {
 				return path, nil
 			}
 		} else if errRead != nil && !errors.Is(errRead, fs.ErrNotExist) {
 			return "", fmt.Errorf("postgres store: read existing metadata: %w", errRead)
 		}
+		if raw, errMarshal = tokencrypt.Seal(raw); errMarshal != nil {
+			return "", fmt.Errorf("postgres store: encrypt metadata: %w", errMarshal)
+		}
 		tmp := path + ".tmp"
 		if errWrite := os.WriteFile(tmp, raw, 0o600); errWrite != nil {
 			return "", fmt.Errorf("postgres store: write temp auth file: %w", errWrite)
@@ -279,38 +310,11 @@ func (s *PostgresStore) List(ctx context.Context) ([]*cliproxyauth.Auth, error)
 		if err = rows.Scan(&id, &payload, &createdAt, &updatedAt); err != nil {
 			return nil, fmt.Errorf("postgres store: scan auth row: %w", err)
 		}
-		path, errPath := s.absoluteAuthPath(id)
-		if errPath != nil {
-			log.WithError(errPath).Warnf("postgres store: skipping auth %s outside spool", id)
+		auth, errRecord := s.authFromRecord(id, payload, createdAt, updatedAt)
+		if errRecord != nil {
+			log.WithError(errRecord).Warnf("postgres store: skipping auth %s", id)
 			continue
 		}
-		metadata := make(map[string]any)
-		if err = json.Unmarshal([]byte(payload), &metadata); err != nil {
-			log.WithError(err).Warnf("postgres store: skipping auth %s with invalid json", id)
-			continue
-		}
-		provider := strings.TrimSpace(valueAsString(metadata["type"]))
-		if provider == "" {
-			provider = "unknown"
-		}
-		attr := map[string]string{"path": path}
-		if email := strings.TrimSpace(valueAsString(metadata["email"])); email != "" {
-			attr["email"] = email
-		}
-		auth := &cliproxyauth.Auth{
-			ID:               normalizeAuthID(id),
-			Provider:         provider,
-			FileName:         normalizeAuthID(id),
-			Label:            labelFor(metadata),
-			Status:           cliproxyauth.StatusActive,
-			Attributes:       attr,
-			Metadata:         metadata,
-			CreatedAt:        createdAt,
-			UpdatedAt:        updatedAt,
-			LastRefreshedAt:  time.Time{},
-			NextRefreshAfter: time.Time{},
-		}
-		cliproxyauth.ApplyCustomHeadersFromMetadata(auth)
 		auths = append(auths, auth)
 	}
 	if err = rows.Err(); err != nil {
@@ -319,6 +323,128 @@ func (s *PostgresStore) List(ctx context.Context) ([]*cliproxyauth.Auth, error)
 	return auths, nil
 }
 
+// authFromRecord builds an auth from a stored auth row.
+func (s *PostgresStore) authFromRecord(id, payload string, createdAt, updatedAt time.Time) (*cliproxyauth.Auth, error) {
+	path, err := s.absoluteAuthPath(id)
+	if err != nil {
+		return nil, fmt.Errorf("outside spool: %w", err)
+	}
+	plain, err := tokencrypt.Open([]byte(payload))
+	if err != nil {
+		return nil, fmt.Errorf("cannot be decrypted: %w", err)
+	}
+	metadata := make(map[string]any)
+	if err = json.Unmarshal(plain, &metadata); err != nil {
+		return nil, fmt.Errorf("invalid json: %w", err)
+	}
+	provider := strings.TrimSpace(valueAsString(metadata["type"]))
+	if provider == "" {
+		provider = "unknown"
+	}
+	attr := map[string]string{"path": path}
+	if email := strings.TrimSpace(valueAsString(metadata["email"])); email != "" {
+		attr["email"] = email
+	}
+	auth := &cliproxyauth.Auth{
+		ID:               normalizeAuthID(id),
+		Provider:         provider,
+		FileName:         normalizeAuthID(id),
+		Label:            labelFor(metadata),
+		Status:           cliproxyauth.StatusActive,
+		Attributes:       attr,
+		Metadata:         metadata,
+		CreatedAt:        createdAt,
+		UpdatedAt:        updatedAt,
+		LastRefreshedAt:  time.Time{},
+		NextRefreshAfter: time.Time{},
+	}
+	cliproxyauth.ApplyCustomHeadersFromMetadata(auth)
+	return auth, nil
+}
+
+// LockRefresh takes a PostgreSQL advisory lock keyed by the auth record on a dedicated
+// connection. The lock is tied to that session, so it is released when the holder's connection
+// ends; ttl is therefore not needed.
+func (s *PostgresStore) LockRefresh(ctx context.Context, auth *cliproxyauth.Auth, _ time.Duration) (func(), error) {
+	path, err := s.resolveAuthPath(auth)
+	if err != nil {
+		return nil, err
+	}
+	relID, err := s.relativeAuthID(path)
+	if err != nil {
+		return nil, err
+	}
+	lockKey := s.fullTableName(s.cfg.AuthTable) + ":" + relID
+
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("postgres store: acquire lock connection: %w", err)
+	}
+	// Poll with pg_try_advisory_lock rather than blocking in pg_advisory_lock so a canceled
+	// wait never leaves a lock behind on a pooled connection.
+	for {
+		var acquired bool
+		err = conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock(hashtextextended($1, 0))", lockKey).Scan(&acquired)
+		if err != nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("postgres store: try refresh lock: %w", err)
+		}
+		if acquired {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			_ = conn.Close()
+			return nil, ctx.Err()
+		case <-time.After(refreshLockPollInterval):
+		}
+	}
+	return func() {
+		unlockCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if _, errUnlock := conn.ExecContext(unlockCtx, "SELECT pg_advisory_unlock(hashtextextended($1, 0))", lockKey); errUnlock != nil {
+			log.WithError(errUnlock).Warnf("postgres store: release refresh lock for %s", relID)
+			// Discard the session instead of returning it to the pool with the lock held.
+			_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+		}
+		_ = conn.Close()
+	}, nil
+}
+
+// ReloadAuth reads the latest version of auth from PostgreSQL and refreshes its local mirror.
+func (s *PostgresStore) ReloadAuth(ctx context.Context, auth *cliproxyauth.Auth) (*cliproxyauth.Auth, error) {
+	path, err := s.resolveAuthPath(auth)
+	if err != nil {
+		return nil, err
+	}
+	relID, err := s.relativeAuthID(path)
+	if err != nil {
+		return nil, err
+	}
+	query := fmt.Sprintf("SELECT content, created_at, updated_at FROM %s WHERE id = $1", s.fullTableName(s.cfg.AuthTable))
+	var (
+		payload   string
+		createdAt time.Time
+		updatedAt time.Time
+	)
+	err = s.db.QueryRowContext(ctx, query, relID).Scan(&payload, &createdAt, &updatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("postgres store: reload auth: %w", err)
+	}
+	reloaded, err := s.authFromRecord(relID, payload, createdAt, updatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("postgres store: reload auth %s: %w", relID, err)
+	}
+
+	if err = s.writeAuthMirror(relID, payload); err != nil {
+		return nil, err
+	}
+	return reloaded, nil
+}
+
 // Delete removes an auth file and the corresponding database record.
 func (s *PostgresStore) Delete(ctx context.Context, id string) error {
 	id = strings.TrimSpace(id)
@@ -469,6 +595,7 @@ func (s *PostgresStore) syncAuthFromDatabase(ctx context.Context) error {
 		if err = os.WriteFile(path, []byte(payload), 0o600); err != nil {
 			return fmt.Errorf("postgres store: write auth file: %w", err)
 		}
+		s.rememberRemoteAuth(id, true)
 	}
 	if err = rows.Err(); err != nil {
 		return fmt.Errorf("postgres store: iterate auth rows: %w", err)
@@ -503,45 +630,144 @@ func (s *PostgresStore) upsertAuthRecord(ctx context.Context, relID, path string
 
 func (s *PostgresStore) persistAuth(ctx context.Context, relID string, data []byte) error {
 	jsonPayload := json.RawMessage(data)
+	// Unchanged content is not rewritten so that mirroring a remote change does not echo it back.
 	query := fmt.Sprintf(`
-		INSERT INTO %s (id, content, created_at, updated_at)
+		INSERT INTO %s AS t (id, content, created_at, updated_at)
 		VALUES ($1, $2, NOW(), NOW())
 		ON CONFLICT (id)
 		DO UPDATE SET content = EXCLUDED.content, updated_at = NOW()
+		WHERE t.content IS DISTINCT FROM EXCLUDED.content
 	`, s.fullTableName(s.cfg.AuthTable))
-	if _, err := s.db.ExecContext(ctx, query, relID, jsonPayload); err != nil {
+	result, err := s.db.ExecContext(ctx, query, relID, jsonPayload)
+	if err != nil {
 		return fmt.Errorf("postgres store: upsert auth record: %w", err)
 	}
+	s.rememberRemoteAuth(relID, true)
+	if changed, _ := result.RowsAffected(); changed > 0 {
+		s.notifyChange(ctx, s.cfg.AuthTable, relID)
+	}
 	return nil
 }
 
 func (s *PostgresStore) deleteAuthRecord(ctx context.Context, relID string) error {
 	query := fmt.Sprintf("DELETE FROM %s WHERE id = $1", s.fullTableName(s.cfg.AuthTable))
-	if _, err := s.db.ExecContext(ctx, query, relID); err != nil {
+	result, err := s.db.ExecContext(ctx, query, relID)
+	if err != nil {
 		return fmt.Errorf("postgres store: delete auth record: %w", err)
 	}
+	s.rememberRemoteAuth(relID, false)
+	if changed, _ := result.RowsAffected(); changed > 0 {
+		s.notifyChange(ctx, s.cfg.AuthTable, relID)
+	}
+	return nil
+}
+
+// LoadRuntimeState reads the saved cooldown state from the database.
+func (s *PostgresStore) LoadRuntimeState(ctx context.Context) (map[string]*cliproxyauth.RuntimeState, error) {
+	states := make(map[string]*cliproxyauth.RuntimeState)
+	if found, err := s.readRuntimeRecord(ctx, defaultRuntimeStateKey, &states); err != nil || !found {
+		return nil, err
+	}
+	return states, nil
+}
+
+// SaveRuntimeState replaces the saved cooldown state. Replicas sharing the database each write
+// their full view, so the last writer wins.
+func (s *PostgresStore) SaveRuntimeState(ctx context.Context, states map[string]*cliproxyauth.RuntimeState) error {
+	if len(states) == 0 {
+		return s.writeRuntimeRecord(ctx, defaultRuntimeStateKey, nil)
+	}
+	return s.writeRuntimeRecord(ctx, defaultRuntimeStateKey, states)
+}
+
+// LoadClientKeyUsage reads the saved client key budget counters from the database.
+func (s *PostgresStore) LoadClientKeyUsage(ctx context.Context) (map[string]*cliproxyauth.ClientKeyCounters, error) {
+	usage := make(map[string]*cliproxyauth.ClientKeyCounters)
+	if found, err := s.readRuntimeRecord(ctx, clientKeyUsageKey, &usage); err != nil || !found {
+		return nil, err
+	}
+	return usage, nil
+}
+
+// SaveClientKeyUsage replaces the saved client key budget counters. Like runtime state, the
+// last replica to write wins, so budgets shared across replicas stay approximate.
+func (s *PostgresStore) SaveClientKeyUsage(ctx context.Context, usage map[string]*cliproxyauth.ClientKeyCounters) error {
+	if len(usage) == 0 {
+		return s.writeRuntimeRecord(ctx, clientKeyUsageKey, nil)
+	}
+	return s.writeRuntimeRecord(ctx, clientKeyUsageKey, usage)
+}
+
+// readRuntimeRecord decodes the runtime state row id into out and reports whether it exists.
+func (s *PostgresStore) readRuntimeRecord(ctx context.Context, id string, out any) (bool, error) {
+	query := fmt.Sprintf("SELECT content FROM %s WHERE id = $1", s.fullTableName(defaultRuntimeStateTable))
+	var content []byte
+	err := s.db.QueryRowContext(ctx, query, id).Scan(&content)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("postgres store: read %s: %w", id, err)
+	}
+	if err = json.Unmarshal(content, out); err != nil {
+		return false, fmt.Errorf("postgres store: decode %s: %w", id, err)
+	}
+	return true, nil
+}
+
+// writeRuntimeRecord replaces the runtime state row id with value, or deletes it when value is nil.
+func (s *PostgresStore) writeRuntimeRecord(ctx context.Context, id string, value any) error {
+	table := s.fullTableName(defaultRuntimeStateTable)
+	if value == nil {
+		if _, err := s.db.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE id = $1", table), id); err != nil {
+			return fmt.Errorf("postgres store: delete %s: %w", id, err)
+		}
+		return nil
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("postgres store: marshal %s: %w", id, err)
+	}
+	query := fmt.Sprintf(`
+		INSERT INTO %s (id, content, updated_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (id)
+		DO UPDATE SET content = EXCLUDED.content, updated_at = NOW()
+	`, table)
+	if _, err = s.db.ExecContext(ctx, query, id, string(raw)); err != nil {
+		return fmt.Errorf("postgres store: upsert %s: %w", id, err)
+	}
 	return nil
 }
 
 func (s *PostgresStore) persistConfig(ctx context.Context, data []byte) error {
 	query := fmt.Sprintf(`
-		INSERT INTO %s (id, content, created_at, updated_at)
+		INSERT INTO %s AS t (id, content, created_at, updated_at)
 		VALUES ($1, $2, NOW(), NOW())
 		ON CONFLICT (id)
 		DO UPDATE SET content = EXCLUDED.content, updated_at = NOW()
+		WHERE t.content IS DISTINCT FROM EXCLUDED.content
 	`, s.fullTableName(s.cfg.ConfigTable))
 	normalized := normalizeLineEndings(string(data))
-	if _, err := s.db.ExecContext(ctx, query, defaultConfigKey, normalized); err != nil {
+	result, err := s.db.ExecContext(ctx, query, defaultConfigKey, normalized)
+	if err != nil {
 		return fmt.Errorf("postgres store: upsert config: %w", err)
 	}
+	if changed, _ := result.RowsAffected(); changed > 0 {
+		s.notifyChange(ctx, s.cfg.ConfigTable, defaultConfigKey)
+	}
 	return nil
 }
 
 func (s *PostgresStore) deleteConfigRecord(ctx context.Context) error {
 	query := fmt.Sprintf("DELETE FROM %s WHERE id = $1", s.fullTableName(s.cfg.ConfigTable))
-	if _, err := s.db.ExecContext(ctx, query, defaultConfigKey); err != nil {
+	result, err := s.db.ExecContext(ctx, query, defaultConfigKey)
+	if err != nil {
 		return fmt.Errorf("postgres store: delete config: %w", err)
 	}
+	if changed, _ := result.RowsAffected(); changed > 0 {
+		s.notifyChange(ctx, s.cfg.ConfigTable, defaultConfigKey)
+	}
 	return nil
 }
 
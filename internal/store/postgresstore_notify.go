@@ -0,0 +1,245 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jackc/pgx/v5/stdlib"
+	log "github.com/sirupsen/logrus"
+)
+
+// postgresChangeChannel is the LISTEN/NOTIFY channel replicas use to announce store writes.
+const postgresChangeChannel = "cliproxy_store_changes"
+
+// postgresChange is the payload of a change notification.
+type postgresChange struct {
+	Table  string `json:"table"`
+	ID     string `json:"id"`
+	Origin string `json:"origin"`
+}
+
+// WatchRemoteChanges applies config and auth changes written by other replicas to the local
+// workspace, where the file watcher picks them up, until ctx is canceled. Changes are announced
+// with LISTEN/NOTIFY; after every (re)connect the workspace is reconciled with the database to
+// catch up on notifications missed while disconnected.
+func (s *PostgresStore) WatchRemoteChanges(ctx context.Context) {
+	backoff := time.Second
+	for {
+		listening, err := s.listenForChanges(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if listening {
+			backoff = time.Second
+		}
+		log.WithError(err).Warnf("postgres store: change listener stopped, retrying in %s", backoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		if backoff < time.Minute {
+			backoff *= 2
+		}
+	}
+}
+
+// listenForChanges holds a dedicated connection subscribed to the change channel and applies
+// notifications until the connection fails. It reports whether the subscription was established.
+func (s *PostgresStore) listenForChanges(ctx context.Context) (bool, error) {
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return false, fmt.Errorf("postgres store: acquire listener connection: %w", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	listening := false
+	err = conn.Raw(func(driverConn any) error {
+		stdConn, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return fmt.Errorf("postgres store: unexpected driver connection %T", driverConn)
+		}
+		pgConn := stdConn.Conn()
+		if _, errListen := pgConn.Exec(ctx, "LISTEN "+quoteIdentifier(postgresChangeChannel)); errListen != nil {
+			return errors.Join(fmt.Errorf("postgres store: listen for changes: %w", errListen), driver.ErrBadConn)
+		}
+		listening = true
+		if errReconcile := s.reconcileFromDatabase(ctx); errReconcile != nil {
+			log.WithError(errReconcile).Warn("postgres store: reconcile workspace")
+		}
+		for {
+			notification, errWait := pgConn.WaitForNotification(ctx)
+			if errWait != nil {
+				// Never hand a subscribed session back to the pool.
+				return errors.Join(fmt.Errorf("postgres store: wait for notification: %w", errWait), driver.ErrBadConn)
+			}
+			s.applyChangeNotification(ctx, notification.Payload)
+		}
+	})
+	return listening, err
+}
+
+// notifyChange announces a committed write to the other replicas. Failures are logged only: the
+// write itself succeeded and peers catch up when they reconcile.
+func (s *PostgresStore) notifyChange(ctx context.Context, table, id string) {
+	payload, err := json.Marshal(postgresChange{Table: s.fullTableName(table), ID: id, Origin: storeInstanceID})
+	if err != nil {
+		return
+	}
+	if _, err = s.db.ExecContext(ctx, "SELECT pg_notify($1, $2)", postgresChangeChannel, string(payload)); err != nil {
+		log.WithError(err).Warnf("postgres store: notify change of %s", id)
+	}
+}
+
+func (s *PostgresStore) applyChangeNotification(ctx context.Context, payload string) {
+	var change postgresChange
+	if err := json.Unmarshal([]byte(payload), &change); err != nil {
+		log.WithError(err).Debug("postgres store: ignoring malformed change notification")
+		return
+	}
+	if change.Origin == storeInstanceID {
+		return
+	}
+	var err error
+	switch change.Table {
+	case s.fullTableName(s.cfg.ConfigTable):
+		err = s.pullConfig(ctx)
+	case s.fullTableName(s.cfg.AuthTable):
+		err = s.pullAuth(ctx, change.ID)
+	default:
+		return
+	}
+	if err != nil {
+		log.WithError(err).Warnf("postgres store: apply remote change of %s", change.ID)
+	}
+}
+
+// reconcileFromDatabase brings the whole workspace in line with the database.
+func (s *PostgresStore) reconcileFromDatabase(ctx context.Context) error {
+	query := fmt.Sprintf("SELECT id, content FROM %s", s.fullTableName(s.cfg.AuthTable))
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("postgres store: load auth from database: %w", err)
+	}
+	defer rows.Close()
+
+	seen := make(map[string]struct{})
+	for rows.Next() {
+		var id, payload string
+		if err = rows.Scan(&id, &payload); err != nil {
+			return fmt.Errorf("postgres store: scan auth row: %w", err)
+		}
+		seen[id] = struct{}{}
+		if errWrite := s.writeAuthMirror(id, payload); errWrite != nil {
+			log.WithError(errWrite).Warnf("postgres store: mirror auth %s", id)
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("postgres store: iterate auth rows: %w", err)
+	}
+
+	s.remoteMu.Lock()
+	var removed []string
+	for id := range s.remoteAuthIDs {
+		if _, ok := seen[id]; !ok {
+			removed = append(removed, id)
+		}
+	}
+	s.remoteAuthIDs = seen
+	s.remoteMu.Unlock()
+	for _, id := range removed {
+		if errRemove := s.removeAuthMirror(id); errRemove != nil {
+			log.WithError(errRemove).Warnf("postgres store: remove auth %s", id)
+		}
+	}
+	return s.pullConfig(ctx)
+}
+
+func (s *PostgresStore) pullAuth(ctx context.Context, relID string) error {
+	query := fmt.Sprintf("SELECT content FROM %s WHERE id = $1", s.fullTableName(s.cfg.AuthTable))
+	var payload string
+	err := s.db.QueryRowContext(ctx, query, relID).Scan(&payload)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		s.rememberRemoteAuth(relID, false)
+		return s.removeAuthMirror(relID)
+	case err != nil:
+		return fmt.Errorf("postgres store: load auth %s: %w", relID, err)
+	}
+	s.rememberRemoteAuth(relID, true)
+	return s.writeAuthMirror(relID, payload)
+}
+
+func (s *PostgresStore) pullConfig(ctx context.Context) error {
+	query := fmt.Sprintf("SELECT content FROM %s WHERE id = $1", s.fullTableName(s.cfg.ConfigTable))
+	var content string
+	err := s.db.QueryRowContext(ctx, query, defaultConfigKey).Scan(&content)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil
+	case err != nil:
+		return fmt.Errorf("postgres store: load config: %w", err)
+	}
+	normalized := normalizeLineEndings(content)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if existing, errRead := os.ReadFile(s.configPath); errRead == nil && normalizeLineEndings(string(existing)) == normalized {
+		return nil
+	}
+	if err = os.WriteFile(s.configPath, []byte(normalized), 0o600); err != nil {
+		return fmt.Errorf("postgres store: write config to spool: %w", err)
+	}
+	return nil
+}
+
+// writeAuthMirror writes payload to the mirror file of relID unless it already holds the same JSON.
+func (s *PostgresStore) writeAuthMirror(relID, payload string) error {
+	path, err := s.absoluteAuthPath(relID)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if existing, errRead := os.ReadFile(path); errRead == nil && jsonEqual(existing, []byte(payload)) {
+		return nil
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("postgres store: create auth subdir: %w", err)
+	}
+	if err = os.WriteFile(path, []byte(payload), 0o600); err != nil {
+		return fmt.Errorf("postgres store: update auth mirror: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) removeAuthMirror(relID string) error {
+	path, err := s.absoluteAuthPath(relID)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err = os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("postgres store: remove auth mirror: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) rememberRemoteAuth(relID string, exists bool) {
+	s.remoteMu.Lock()
+	defer s.remoteMu.Unlock()
+	if exists {
+		s.remoteAuthIDs[relID] = struct{}{}
+	} else {
+		delete(s.remoteAuthIDs, relID)
+	}
+}
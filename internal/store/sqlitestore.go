@@ -0,0 +1,845 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	baseauth "github.com/router-for-me/CLIProxyAPI/v6/internal/auth"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/misc"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/tokencrypt"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	log "github.com/sirupsen/logrus"
+	_ "modernc.org/sqlite"
+)
+
+const (
+	// defaultSQLiteHistoryLimit is the number of revisions kept per config or auth record.
+	defaultSQLiteHistoryLimit = 20
+
+	// SQLiteHistoryConfig and SQLiteHistoryAuth select the record kind in SQLiteStore.History.
+	SQLiteHistoryConfig = "config"
+	SQLiteHistoryAuth   = "auth"
+
+	sqliteOperationUpsert = "upsert"
+	sqliteOperationDelete = "delete"
+)
+
+// sqliteMigrations are applied in order; PRAGMA user_version records how many have run.
+// Append new migrations, never edit applied ones.
+var sqliteMigrations = [][]string{
+	{
+		`CREATE TABLE IF NOT EXISTS config_store (
+			id TEXT PRIMARY KEY,
+			content TEXT NOT NULL,
+			created_at TEXT NOT NULL,
+			updated_at TEXT NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS auth_store (
+			id TEXT PRIMARY KEY,
+			content TEXT NOT NULL,
+			created_at TEXT NOT NULL,
+			updated_at TEXT NOT NULL
+		)`,
+	},
+	{
+		`CREATE TABLE IF NOT EXISTS store_history (
+			seq INTEGER PRIMARY KEY AUTOINCREMENT,
+			kind TEXT NOT NULL,
+			id TEXT NOT NULL,
+			operation TEXT NOT NULL,
+			content TEXT,
+			recorded_at TEXT NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS store_history_record ON store_history (kind, id, seq)`,
+	},
+	{
+		`CREATE TABLE IF NOT EXISTS runtime_state (
+			id TEXT PRIMARY KEY,
+			content TEXT NOT NULL,
+			updated_at TEXT NOT NULL
+		)`,
+	},
+}
+
+// SQLiteStoreConfig captures configuration required to initialize a SQLite-backed store.
+type SQLiteStoreConfig struct {
+	// Path is the database file. Defaults to <SpoolDir>/store.db.
+	Path string
+	// SpoolDir is the local workspace that mirrors the config file and auth files.
+	SpoolDir string
+	// HistoryLimit caps the revisions kept per record. Zero uses the default; negative disables history.
+	HistoryLimit int
+}
+
+// SQLiteHistoryEntry is one recorded revision of a config or auth record.
+type SQLiteHistoryEntry struct {
+	Seq        int64     `json:"seq"`
+	Kind       string    `json:"kind"`
+	ID         string    `json:"id"`
+	Operation  string    `json:"operation"`
+	Content    string    `json:"content,omitempty"`
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+// SQLiteStore persists configuration and authentication metadata in an embedded SQLite database
+// while mirroring data to a local workspace so existing file-based workflows continue to operate.
+// Every change is written together with its history entry in a single transaction.
+type SQLiteStore struct {
+	db         *sql.DB
+	cfg        SQLiteStoreConfig
+	spoolRoot  string
+	configPath string
+	authDir    string
+	mu         sync.Mutex
+}
+
+// NewSQLiteStore opens (creating if needed) the SQLite database and prepares the local workspace.
+func NewSQLiteStore(ctx context.Context, cfg SQLiteStoreConfig) (*SQLiteStore, error) {
+	spoolRoot := strings.TrimSpace(cfg.SpoolDir)
+	if spoolRoot == "" {
+		if cwd, err := os.Getwd(); err == nil {
+			spoolRoot = filepath.Join(cwd, "sqlitestore")
+		} else {
+			spoolRoot = filepath.Join(os.TempDir(), "sqlitestore")
+		}
+	}
+	absSpool, err := filepath.Abs(spoolRoot)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite store: resolve spool directory: %w", err)
+	}
+	configDir := filepath.Join(absSpool, "config")
+	authDir := filepath.Join(absSpool, "auths")
+	if err = os.MkdirAll(configDir, 0o700); err != nil {
+		return nil, fmt.Errorf("sqlite store: create config directory: %w", err)
+	}
+	if err = os.MkdirAll(authDir, 0o700); err != nil {
+		return nil, fmt.Errorf("sqlite store: create auth directory: %w", err)
+	}
+
+	dbPath := strings.TrimSpace(cfg.Path)
+	if dbPath == "" {
+		dbPath = filepath.Join(absSpool, "store.db")
+	}
+	if dbPath, err = filepath.Abs(dbPath); err != nil {
+		return nil, fmt.Errorf("sqlite store: resolve database path: %w", err)
+	}
+	if err = os.MkdirAll(filepath.Dir(dbPath), 0o700); err != nil {
+		return nil, fmt.Errorf("sqlite store: create database directory: %w", err)
+	}
+	cfg.Path = dbPath
+	if cfg.HistoryLimit == 0 {
+		cfg.HistoryLimit = defaultSQLiteHistoryLimit
+	}
+
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite store: open database: %w", err)
+	}
+	// A single connection serializes writers and keeps the per-connection pragmas below in effect.
+	db.SetMaxOpenConns(1)
+	for _, pragma := range []string{
+		"PRAGMA journal_mode = WAL",
+		"PRAGMA synchronous = NORMAL",
+		"PRAGMA busy_timeout = 5000",
+	} {
+		if _, err = db.ExecContext(ctx, pragma); err != nil {
+			_ = db.Close()
+			return nil, fmt.Errorf("sqlite store: %s: %w", pragma, err)
+		}
+	}
+	if err = os.Chmod(dbPath, 0o600); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		log.WithError(err).Warnf("sqlite store: restrict database permissions")
+	}
+
+	store := &SQLiteStore{
+		db:         db,
+		cfg:        cfg,
+		spoolRoot:  absSpool,
+		configPath: filepath.Join(configDir, "config.yaml"),
+		authDir:    authDir,
+	}
+	return store, nil
+}
+
+// Close releases the underlying database handle.
+func (s *SQLiteStore) Close() error {
+	if s == nil || s.db == nil {
+		return nil
+	}
+	return s.db.Close()
+}
+
+// EnsureSchema applies pending schema migrations, each in its own transaction.
+func (s *SQLiteStore) EnsureSchema(ctx context.Context) error {
+	if s == nil || s.db == nil {
+		return fmt.Errorf("sqlite store: not initialized")
+	}
+	var version int
+	if err := s.db.QueryRowContext(ctx, "PRAGMA user_version").Scan(&version); err != nil {
+		return fmt.Errorf("sqlite store: read schema version: %w", err)
+	}
+	if version > len(sqliteMigrations) {
+		return fmt.Errorf("sqlite store: database schema version %d is newer than supported version %d", version, len(sqliteMigrations))
+	}
+	for index := version; index < len(sqliteMigrations); index++ {
+		tx, err := s.db.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("sqlite store: begin migration %d: %w", index+1, err)
+		}
+		for _, statement := range sqliteMigrations[index] {
+			if _, err = tx.ExecContext(ctx, statement); err != nil {
+				_ = tx.Rollback()
+				return fmt.Errorf("sqlite store: apply migration %d: %w", index+1, err)
+			}
+		}
+		if _, err = tx.ExecContext(ctx, fmt.Sprintf("PRAGMA user_version = %d", index+1)); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("sqlite store: record migration %d: %w", index+1, err)
+		}
+		if err = tx.Commit(); err != nil {
+			return fmt.Errorf("sqlite store: commit migration %d: %w", index+1, err)
+		}
+	}
+	return nil
+}
+
+// Bootstrap synchronizes configuration and auth records between SQLite and the local workspace.
+func (s *SQLiteStore) Bootstrap(ctx context.Context, exampleConfigPath string) error {
+	if err := s.EnsureSchema(ctx); err != nil {
+		return err
+	}
+	if err := s.syncConfigFromDatabase(ctx, exampleConfigPath); err != nil {
+		return err
+	}
+	if err := s.syncAuthFromDatabase(ctx); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ConfigPath returns the managed configuration file path inside the spool directory.
+func (s *SQLiteStore) ConfigPath() string {
+	if s == nil {
+		return ""
+	}
+	return s.configPath
+}
+
+// AuthDir returns the local directory containing mirrored auth files.
+func (s *SQLiteStore) AuthDir() string {
+	if s == nil {
+		return ""
+	}
+	return s.authDir
+}
+
+// WorkDir exposes the root spool directory used for mirroring.
+func (s *SQLiteStore) WorkDir() string {
+	if s == nil {
+		return ""
+	}
+	return s.spoolRoot
+}
+
+// DatabasePath returns the SQLite database file.
+func (s *SQLiteStore) DatabasePath() string {
+	if s == nil {
+		return ""
+	}
+	return s.cfg.Path
+}
+
+// SetBaseDir implements the optional interface used by authenticators; it is a no-op because
+// the SQLite-backed store controls its own workspace.
+func (s *SQLiteStore) SetBaseDir(string) {}
+
+// Save persists authentication metadata to disk and SQLite.
+func (s *SQLiteStore) Save(ctx context.Context, auth *cliproxyauth.Auth) (string, error) {
+	if auth == nil {
+		return "", fmt.Errorf("sqlite store: auth is nil")
+	}
+
+	path, err := s.resolveAuthPath(auth)
+	if err != nil {
+		return "", err
+	}
+	if path == "" {
+		return "", fmt.Errorf("sqlite store: missing file path attribute for %s", auth.ID)
+	}
+
+	if auth.Disabled {
+		if _, statErr := os.Stat(path); errors.Is(statErr, fs.ErrNotExist) {
+			return "", nil
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err = os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return "", fmt.Errorf("sqlite store: create auth directory: %w", err)
+	}
+
+	switch {
+	case auth.Storage != nil:
+		if err = baseauth.SaveSealedToken(auth.Storage, path); err != nil {
+			return "", err
+		}
+	case auth.Metadata != nil:
+		raw, errMarshal := json.Marshal(auth.Metadata)
+		if errMarshal != nil {
+			return "", fmt.Errorf("sqlite store: marshal metadata: %w", errMarshal)
+		}
+		if existing, errRead := os.ReadFile(path); errRead == nil {
+			if plain, errOpen := tokencrypt.Open(existing); errOpen == nil && jsonEqual(plain, raw) && tokencrypt.UpToDate(existing) {
+				return path, nil
+			}
+		} else if errRead != nil && !errors.Is(errRead, fs.ErrNotExist) {
+			return "", fmt.Errorf("sqlite store: read existing metadata: %w", errRead)
+		}
+		if raw, errMarshal = tokencrypt.Seal(raw); errMarshal != nil {
+			return "", fmt.Errorf("sqlite store: encrypt metadata: %w", errMarshal)
+		}
+		tmp := path + ".tmp"
+		if errWrite := os.WriteFile(tmp, raw, 0o600); errWrite != nil {
+			return "", fmt.Errorf("sqlite store: write temp auth file: %w", errWrite)
+		}
+		if errRename := os.Rename(tmp, path); errRename != nil {
+			return "", fmt.Errorf("sqlite store: rename auth file: %w", errRename)
+		}
+	default:
+		return "", fmt.Errorf("sqlite store: nothing to persist for %s", auth.ID)
+	}
+
+	if auth.Attributes == nil {
+		auth.Attributes = make(map[string]string)
+	}
+	auth.Attributes["path"] = path
+
+	if strings.TrimSpace(auth.FileName) == "" {
+		auth.FileName = auth.ID
+	}
+
+	relID, err := s.relativeAuthID(path)
+	if err != nil {
+		return "", err
+	}
+	if err = s.syncAuthFile(ctx, relID, path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// List enumerates all auth records stored in SQLite.
+func (s *SQLiteStore) List(ctx context.Context) ([]*cliproxyauth.Auth, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT id, content, created_at, updated_at FROM auth_store ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("sqlite store: list auth: %w", err)
+	}
+	defer rows.Close()
+
+	auths := make([]*cliproxyauth.Auth, 0, 32)
+	for rows.Next() {
+		var (
+			id        string
+			payload   string
+			createdAt string
+			updatedAt string
+		)
+		if err = rows.Scan(&id, &payload, &createdAt, &updatedAt); err != nil {
+			return nil, fmt.Errorf("sqlite store: scan auth row: %w", err)
+		}
+		path, errPath := s.absoluteAuthPath(id)
+		if errPath != nil {
+			log.WithError(errPath).Warnf("sqlite store: skipping auth %s outside spool", id)
+			continue
+		}
+		plain, errOpen := tokencrypt.Open([]byte(payload))
+		if errOpen != nil {
+			log.WithError(errOpen).Warnf("sqlite store: skipping auth %s that cannot be decrypted", id)
+			continue
+		}
+		metadata := make(map[string]any)
+		if err = json.Unmarshal(plain, &metadata); err != nil {
+			log.WithError(err).Warnf("sqlite store: skipping auth %s with invalid json", id)
+			continue
+		}
+		provider := strings.TrimSpace(valueAsString(metadata["type"]))
+		if provider == "" {
+			provider = "unknown"
+		}
+		attr := map[string]string{"path": path}
+		if email := strings.TrimSpace(valueAsString(metadata["email"])); email != "" {
+			attr["email"] = email
+		}
+		auth := &cliproxyauth.Auth{
+			ID:               normalizeAuthID(id),
+			Provider:         provider,
+			FileName:         normalizeAuthID(id),
+			Label:            labelFor(metadata),
+			Status:           cliproxyauth.StatusActive,
+			Attributes:       attr,
+			Metadata:         metadata,
+			CreatedAt:        parseSQLiteTime(createdAt),
+			UpdatedAt:        parseSQLiteTime(updatedAt),
+			LastRefreshedAt:  time.Time{},
+			NextRefreshAfter: time.Time{},
+		}
+		cliproxyauth.ApplyCustomHeadersFromMetadata(auth)
+		auths = append(auths, auth)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("sqlite store: iterate auth rows: %w", err)
+	}
+	return auths, nil
+}
+
+// Delete removes an auth file and the corresponding database record.
+func (s *SQLiteStore) Delete(ctx context.Context, id string) error {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return fmt.Errorf("sqlite store: id is empty")
+	}
+	path, err := s.resolveDeletePath(id)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err = os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("sqlite store: delete auth file: %w", err)
+	}
+	relID, err := s.relativeAuthID(path)
+	if err != nil {
+		return err
+	}
+	return s.writeRecord(ctx, SQLiteHistoryAuth, relID, nil)
+}
+
+// PersistAuthFiles stores the provided auth file changes in SQLite.
+func (s *SQLiteStore) PersistAuthFiles(ctx context.Context, _ string, paths ...string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, p := range paths {
+		trimmed := strings.TrimSpace(p)
+		if trimmed == "" {
+			continue
+		}
+		relID, err := s.relativeAuthID(trimmed)
+		if err != nil {
+			// Attempt to resolve absolute path under authDir.
+			abs := trimmed
+			if !filepath.IsAbs(abs) {
+				abs = filepath.Join(s.authDir, trimmed)
+			}
+			relID, err = s.relativeAuthID(abs)
+			if err != nil {
+				log.WithError(err).Warnf("sqlite store: ignoring auth path %s", trimmed)
+				continue
+			}
+			trimmed = abs
+		}
+		if err = s.syncAuthFile(ctx, relID, trimmed); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PersistConfig mirrors the local configuration file to SQLite.
+func (s *SQLiteStore) PersistConfig(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := os.ReadFile(s.configPath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return s.writeRecord(ctx, SQLiteHistoryConfig, defaultConfigKey, nil)
+		}
+		return fmt.Errorf("sqlite store: read config file: %w", err)
+	}
+	content := normalizeLineEndings(string(data))
+	return s.writeRecord(ctx, SQLiteHistoryConfig, defaultConfigKey, &content)
+}
+
+// History returns the most recent revisions of a record, newest first. An empty id returns
+// revisions of every record of that kind. A limit of zero or less returns all retained revisions.
+func (s *SQLiteStore) History(ctx context.Context, kind, id string, limit int) ([]SQLiteHistoryEntry, error) {
+	if kind != SQLiteHistoryConfig && kind != SQLiteHistoryAuth {
+		return nil, fmt.Errorf("sqlite store: unknown history kind %q", kind)
+	}
+	if kind == SQLiteHistoryConfig && id == "" {
+		id = defaultConfigKey
+	}
+	query := "SELECT seq, kind, id, operation, content, recorded_at FROM store_history WHERE kind = ?"
+	args := []any{kind}
+	if id != "" {
+		query += " AND id = ?"
+		args = append(args, normalizeAuthID(id))
+	}
+	query += " ORDER BY seq DESC"
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite store: query history: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make([]SQLiteHistoryEntry, 0)
+	for rows.Next() {
+		var (
+			entry      SQLiteHistoryEntry
+			content    sql.NullString
+			recordedAt string
+		)
+		if err = rows.Scan(&entry.Seq, &entry.Kind, &entry.ID, &entry.Operation, &content, &recordedAt); err != nil {
+			return nil, fmt.Errorf("sqlite store: scan history row: %w", err)
+		}
+		entry.Content = content.String
+		entry.RecordedAt = parseSQLiteTime(recordedAt)
+		entries = append(entries, entry)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("sqlite store: iterate history rows: %w", err)
+	}
+	return entries, nil
+}
+
+// LoadRuntimeState reads the saved cooldown state from the database.
+func (s *SQLiteStore) LoadRuntimeState(ctx context.Context) (map[string]*cliproxyauth.RuntimeState, error) {
+	states := make(map[string]*cliproxyauth.RuntimeState)
+	if found, err := s.readRuntimeRecord(ctx, defaultRuntimeStateKey, &states); err != nil || !found {
+		return nil, err
+	}
+	return states, nil
+}
+
+// SaveRuntimeState replaces the saved cooldown state. Runtime state is not recorded in the history.
+func (s *SQLiteStore) SaveRuntimeState(ctx context.Context, states map[string]*cliproxyauth.RuntimeState) error {
+	if len(states) == 0 {
+		return s.writeRuntimeRecord(ctx, defaultRuntimeStateKey, nil)
+	}
+	return s.writeRuntimeRecord(ctx, defaultRuntimeStateKey, states)
+}
+
+// LoadClientKeyUsage reads the saved client key budget counters from the database.
+func (s *SQLiteStore) LoadClientKeyUsage(ctx context.Context) (map[string]*cliproxyauth.ClientKeyCounters, error) {
+	usage := make(map[string]*cliproxyauth.ClientKeyCounters)
+	if found, err := s.readRuntimeRecord(ctx, clientKeyUsageKey, &usage); err != nil || !found {
+		return nil, err
+	}
+	return usage, nil
+}
+
+// SaveClientKeyUsage replaces the saved client key budget counters.
+func (s *SQLiteStore) SaveClientKeyUsage(ctx context.Context, usage map[string]*cliproxyauth.ClientKeyCounters) error {
+	if len(usage) == 0 {
+		return s.writeRuntimeRecord(ctx, clientKeyUsageKey, nil)
+	}
+	return s.writeRuntimeRecord(ctx, clientKeyUsageKey, usage)
+}
+
+// readRuntimeRecord decodes the runtime_state row id into out and reports whether it exists.
+func (s *SQLiteStore) readRuntimeRecord(ctx context.Context, id string, out any) (bool, error) {
+	var content string
+	err := s.db.QueryRowContext(ctx, "SELECT content FROM runtime_state WHERE id = ?", id).Scan(&content)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("sqlite store: read %s: %w", id, err)
+	}
+	if err = json.Unmarshal([]byte(content), out); err != nil {
+		return false, fmt.Errorf("sqlite store: decode %s: %w", id, err)
+	}
+	return true, nil
+}
+
+// writeRuntimeRecord replaces the runtime_state row id with value, or deletes it when value is nil.
+func (s *SQLiteStore) writeRuntimeRecord(ctx context.Context, id string, value any) error {
+	if value == nil {
+		if _, err := s.db.ExecContext(ctx, "DELETE FROM runtime_state WHERE id = ?", id); err != nil {
+			return fmt.Errorf("sqlite store: delete %s: %w", id, err)
+		}
+		return nil
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("sqlite store: marshal %s: %w", id, err)
+	}
+	if _, err = s.db.ExecContext(ctx, `
+		INSERT INTO runtime_state (id, content, updated_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT (id)
+		DO UPDATE SET content = excluded.content, updated_at = excluded.updated_at
+	`, id, string(raw), formatSQLiteTime(time.Now())); err != nil {
+		return fmt.Errorf("sqlite store: upsert %s: %w", id, err)
+	}
+	return nil
+}
+
+// syncConfigFromDatabase writes the database-stored config to disk or seeds the database from template.
+func (s *SQLiteStore) syncConfigFromDatabase(ctx context.Context, exampleConfigPath string) error {
+	var content string
+	err := s.db.QueryRowContext(ctx, "SELECT content FROM config_store WHERE id = ?", defaultConfigKey).Scan(&content)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		if _, errStat := os.Stat(s.configPath); errors.Is(errStat, fs.ErrNotExist) {
+			if exampleConfigPath != "" {
+				if errCopy := misc.CopyConfigTemplate(exampleConfigPath, s.configPath); errCopy != nil {
+					return fmt.Errorf("sqlite store: copy example config: %w", errCopy)
+				}
+			} else {
+				if errCreate := os.MkdirAll(filepath.Dir(s.configPath), 0o700); errCreate != nil {
+					return fmt.Errorf("sqlite store: prepare config directory: %w", errCreate)
+				}
+				if errWrite := os.WriteFile(s.configPath, []byte{}, 0o600); errWrite != nil {
+					return fmt.Errorf("sqlite store: create empty config: %w", errWrite)
+				}
+			}
+		}
+		data, errRead := os.ReadFile(s.configPath)
+		if errRead != nil {
+			return fmt.Errorf("sqlite store: read local config: %w", errRead)
+		}
+		normalized := normalizeLineEndings(string(data))
+		if errPersist := s.writeRecord(ctx, SQLiteHistoryConfig, defaultConfigKey, &normalized); errPersist != nil {
+			return errPersist
+		}
+	case err != nil:
+		return fmt.Errorf("sqlite store: load config from database: %w", err)
+	default:
+		if err = os.MkdirAll(filepath.Dir(s.configPath), 0o700); err != nil {
+			return fmt.Errorf("sqlite store: prepare config directory: %w", err)
+		}
+		normalized := normalizeLineEndings(content)
+		if err = os.WriteFile(s.configPath, []byte(normalized), 0o600); err != nil {
+			return fmt.Errorf("sqlite store: write config to spool: %w", err)
+		}
+	}
+	return nil
+}
+
+// syncAuthFromDatabase populates the local auth directory from SQLite data.
+func (s *SQLiteStore) syncAuthFromDatabase(ctx context.Context) error {
+	rows, err := s.db.QueryContext(ctx, "SELECT id, content FROM auth_store")
+	if err != nil {
+		return fmt.Errorf("sqlite store: load auth from database: %w", err)
+	}
+	defer rows.Close()
+
+	if err = os.RemoveAll(s.authDir); err != nil {
+		return fmt.Errorf("sqlite store: reset auth directory: %w", err)
+	}
+	if err = os.MkdirAll(s.authDir, 0o700); err != nil {
+		return fmt.Errorf("sqlite store: recreate auth directory: %w", err)
+	}
+
+	for rows.Next() {
+		var (
+			id      string
+			payload string
+		)
+		if err = rows.Scan(&id, &payload); err != nil {
+			return fmt.Errorf("sqlite store: scan auth row: %w", err)
+		}
+		path, errPath := s.absoluteAuthPath(id)
+		if errPath != nil {
+			log.WithError(errPath).Warnf("sqlite store: skipping auth %s outside spool", id)
+			continue
+		}
+		if err = os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			return fmt.Errorf("sqlite store: create auth subdir: %w", err)
+		}
+		if err = os.WriteFile(path, []byte(payload), 0o600); err != nil {
+			return fmt.Errorf("sqlite store: write auth file: %w", err)
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("sqlite store: iterate auth rows: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) syncAuthFile(ctx context.Context, relID, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return s.writeRecord(ctx, SQLiteHistoryAuth, relID, nil)
+		}
+		return fmt.Errorf("sqlite store: read auth file: %w", err)
+	}
+	if len(data) == 0 {
+		return s.writeRecord(ctx, SQLiteHistoryAuth, relID, nil)
+	}
+	content := string(data)
+	return s.writeRecord(ctx, SQLiteHistoryAuth, relID, &content)
+}
+
+// writeRecord upserts (or, with a nil content, deletes) a config or auth record and appends the
+// change to the history in one transaction. Writes that do not change the record are skipped.
+func (s *SQLiteStore) writeRecord(ctx context.Context, kind, id string, content *string) error {
+	table := "auth_store"
+	if kind == SQLiteHistoryConfig {
+		table = "config_store"
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("sqlite store: begin %s transaction: %w", kind, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var existing string
+	err = tx.QueryRowContext(ctx, "SELECT content FROM "+table+" WHERE id = ?", id).Scan(&existing)
+	exists := err == nil
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("sqlite store: read %s record: %w", kind, err)
+	}
+
+	now := formatSQLiteTime(time.Now())
+	operation := sqliteOperationUpsert
+	if content == nil {
+		if !exists {
+			return nil
+		}
+		operation = sqliteOperationDelete
+		if _, err = tx.ExecContext(ctx, "DELETE FROM "+table+" WHERE id = ?", id); err != nil {
+			return fmt.Errorf("sqlite store: delete %s record: %w", kind, err)
+		}
+	} else {
+		if exists && existing == *content {
+			return nil
+		}
+		if _, err = tx.ExecContext(ctx, `
+			INSERT INTO `+table+` (id, content, created_at, updated_at)
+			VALUES (?, ?, ?, ?)
+			ON CONFLICT (id)
+			DO UPDATE SET content = excluded.content, updated_at = excluded.updated_at
+		`, id, *content, now, now); err != nil {
+			return fmt.Errorf("sqlite store: upsert %s record: %w", kind, err)
+		}
+	}
+
+	if s.cfg.HistoryLimit > 0 {
+		var historyContent any
+		if content != nil {
+			historyContent = *content
+		}
+		if _, err = tx.ExecContext(ctx,
+			"INSERT INTO store_history (kind, id, operation, content, recorded_at) VALUES (?, ?, ?, ?, ?)",
+			kind, id, operation, historyContent, now); err != nil {
+			return fmt.Errorf("sqlite store: record %s history: %w", kind, err)
+		}
+		if _, err = tx.ExecContext(ctx, `
+			DELETE FROM store_history
+			WHERE kind = ? AND id = ? AND seq NOT IN (
+				SELECT seq FROM store_history WHERE kind = ? AND id = ? ORDER BY seq DESC LIMIT ?
+			)
+		`, kind, id, kind, id, s.cfg.HistoryLimit); err != nil {
+			return fmt.Errorf("sqlite store: prune %s history: %w", kind, err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("sqlite store: commit %s record: %w", kind, err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) resolveAuthPath(auth *cliproxyauth.Auth) (string, error) {
+	if auth == nil {
+		return "", fmt.Errorf("sqlite store: auth is nil")
+	}
+	if auth.Attributes != nil {
+		if p := strings.TrimSpace(auth.Attributes["path"]); p != "" {
+			return p, nil
+		}
+	}
+	if fileName := strings.TrimSpace(auth.FileName); fileName != "" {
+		if filepath.IsAbs(fileName) {
+			return fileName, nil
+		}
+		return filepath.Join(s.authDir, fileName), nil
+	}
+	if auth.ID == "" {
+		return "", fmt.Errorf("sqlite store: missing id")
+	}
+	if filepath.IsAbs(auth.ID) {
+		return auth.ID, nil
+	}
+	return filepath.Join(s.authDir, filepath.FromSlash(auth.ID)), nil
+}
+
+func (s *SQLiteStore) resolveDeletePath(id string) (string, error) {
+	if strings.ContainsRune(id, os.PathSeparator) || filepath.IsAbs(id) {
+		return id, nil
+	}
+	return filepath.Join(s.authDir, filepath.FromSlash(id)), nil
+}
+
+func (s *SQLiteStore) relativeAuthID(path string) (string, error) {
+	if s == nil {
+		return "", fmt.Errorf("sqlite store: store not initialized")
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(s.authDir, path)
+	}
+	clean := filepath.Clean(path)
+	rel, err := filepath.Rel(s.authDir, clean)
+	if err != nil {
+		return "", fmt.Errorf("sqlite store: compute relative path: %w", err)
+	}
+	if strings.HasPrefix(rel, "..") {
+		return "", fmt.Errorf("sqlite store: path %s outside managed directory", path)
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+func (s *SQLiteStore) absoluteAuthPath(id string) (string, error) {
+	if s == nil {
+		return "", fmt.Errorf("sqlite store: store not initialized")
+	}
+	clean := filepath.Clean(filepath.FromSlash(id))
+	if strings.HasPrefix(clean, "..") {
+		return "", fmt.Errorf("sqlite store: invalid auth identifier %s", id)
+	}
+	path := filepath.Join(s.authDir, clean)
+	rel, err := filepath.Rel(s.authDir, path)
+	if err != nil {
+		return "", err
+	}
+	if strings.HasPrefix(rel, "..") {
+		return "", fmt.Errorf("sqlite store: resolved auth path escapes auth directory")
+	}
+	return path, nil
+}
+
+func formatSQLiteTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+func parseSQLiteTime(value string) time.Time {
+	parsed, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}
+	}
+	return parsed
+}
@@ -0,0 +1,232 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/tokencrypt"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+)
+
+func newTestSQLiteStore(t *testing.T, cfg SQLiteStoreConfig) *SQLiteStore {
+	t.Helper()
+	if cfg.SpoolDir == "" {
+		cfg.SpoolDir = t.TempDir()
+	}
+	s, err := NewSQLiteStore(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("NewSQLiteStore() error = %v", err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+	if err = s.Bootstrap(context.Background(), ""); err != nil {
+		t.Fatalf("Bootstrap() error = %v", err)
+	}
+	return s
+}
+
+func sqliteTestAuth(email string) *cliproxyauth.Auth {
+	return &cliproxyauth.Auth{
+		ID:       "claude-a.json",
+		FileName: "claude-a.json",
+		Provider: "claude",
+		Metadata: map[string]any{"type": "claude", "email": email},
+	}
+}
+
+func TestSQLiteStoreEnsureSchema(t *testing.T) {
+	ctx := context.Background()
+	s := newTestSQLiteStore(t, SQLiteStoreConfig{})
+
+	if err := s.EnsureSchema(ctx); err != nil {
+		t.Fatalf("second EnsureSchema() error = %v", err)
+	}
+	var version int
+	if err := s.db.QueryRowContext(ctx, "PRAGMA user_version").Scan(&version); err != nil {
+		t.Fatalf("read user_version: %v", err)
+	}
+	if version != len(sqliteMigrations) {
+		t.Fatalf("user_version = %d, want %d", version, len(sqliteMigrations))
+	}
+	for _, table := range []string{"config_store", "auth_store", "store_history", "runtime_state"} {
+		var name string
+		if err := s.db.QueryRowContext(ctx, "SELECT name FROM sqlite_master WHERE type = 'table' AND name = ?", table).Scan(&name); err != nil {
+			t.Fatalf("table %s missing: %v", table, err)
+		}
+	}
+
+	if _, err := s.db.ExecContext(ctx, "PRAGMA user_version = 99"); err != nil {
+		t.Fatalf("set user_version: %v", err)
+	}
+	if err := s.EnsureSchema(ctx); err == nil {
+		t.Fatalf("EnsureSchema() on a newer schema error = nil")
+	}
+}
+
+func TestSQLiteStoreSaveListDelete(t *testing.T) {
+	for _, sealed := range []bool{false, true} {
+		name := "plaintext"
+		if sealed {
+			name = "sealed"
+		}
+		t.Run(name, func(t *testing.T) {
+			if sealed {
+				ring, err := tokencrypt.NewKeyring([]tokencrypt.Key{{ID: "k1", Secret: bytes.Repeat([]byte{9}, 32)}}, true)
+				if err != nil {
+					t.Fatalf("NewKeyring() error = %v", err)
+				}
+				tokencrypt.SetDefault(ring)
+				t.Cleanup(func() { tokencrypt.SetDefault(nil) })
+			}
+			ctx := context.Background()
+			s := newTestSQLiteStore(t, SQLiteStoreConfig{})
+
+			path, err := s.Save(ctx, sqliteTestAuth("a@example.com"))
+			if err != nil {
+				t.Fatalf("Save() error = %v", err)
+			}
+			if path != filepath.Join(s.AuthDir(), "claude-a.json") {
+				t.Fatalf("Save() path = %q", path)
+			}
+			var stored string
+			if err = s.db.QueryRowContext(ctx, "SELECT content FROM auth_store WHERE id = ?", "claude-a.json").Scan(&stored); err != nil {
+				t.Fatalf("read auth row: %v", err)
+			}
+			if got := tokencrypt.IsSealed([]byte(stored)); got != sealed {
+				t.Fatalf("stored row sealed = %v, want %v: %s", got, sealed, stored)
+			}
+
+			auths, err := s.List(ctx)
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			if len(auths) != 1 || auths[0].ID != "claude-a.json" || auths[0].Provider != "claude" || auths[0].Attributes["email"] != "a@example.com" {
+				t.Fatalf("List() = %+v", auths)
+			}
+
+			if err = s.Delete(ctx, "claude-a.json"); err != nil {
+				t.Fatalf("Delete() error = %v", err)
+			}
+			if auths, err = s.List(ctx); err != nil || len(auths) != 0 {
+				t.Fatalf("List() after Delete = %+v, %v", auths, err)
+			}
+			if _, err = os.Stat(path); !os.IsNotExist(err) {
+				t.Fatalf("auth file still present after Delete: %v", err)
+			}
+		})
+	}
+}
+
+func TestSQLiteStoreRecordsAndPrunesHistory(t *testing.T) {
+	ctx := context.Background()
+	s := newTestSQLiteStore(t, SQLiteStoreConfig{HistoryLimit: 2})
+
+	for _, email := range []string{"first@example.com", "second@example.com", "second@example.com", "third@example.com"} {
+		if _, err := s.Save(ctx, sqliteTestAuth(email)); err != nil {
+			t.Fatalf("Save(%s) error = %v", email, err)
+		}
+	}
+	history, err := s.History(ctx, SQLiteHistoryAuth, "claude-a.json", 0)
+	if err != nil {
+		t.Fatalf("History() error = %v", err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("History() has %d entries, want 2 after pruning: %+v", len(history), history)
+	}
+	if !bytes.Contains([]byte(history[0].Content), []byte("third@example.com")) || !bytes.Contains([]byte(history[1].Content), []byte("second@example.com")) {
+		t.Fatalf("History() = %+v, want the two newest revisions newest first", history)
+	}
+
+	if err = s.Delete(ctx, "claude-a.json"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	history, err = s.History(ctx, SQLiteHistoryAuth, "claude-a.json", 1)
+	if err != nil {
+		t.Fatalf("History() error = %v", err)
+	}
+	if len(history) != 1 || history[0].Operation != sqliteOperationDelete || history[0].Content != "" {
+		t.Fatalf("History() after Delete = %+v, want a delete revision", history)
+	}
+
+	if _, err = s.History(ctx, "unknown", "", 0); err == nil {
+		t.Fatalf("History() with unknown kind error = nil")
+	}
+}
+
+func TestSQLiteStoreBootstrapsFromLocalMirror(t *testing.T) {
+	ctx := context.Background()
+	firstSpool := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(firstSpool, "config"), 0o700); err != nil {
+		t.Fatalf("create config dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(firstSpool, "config", "config.yaml"), []byte("port: 8317\r\n"), 0o600); err != nil {
+		t.Fatalf("write local config: %v", err)
+	}
+	first := newTestSQLiteStore(t, SQLiteStoreConfig{SpoolDir: firstSpool})
+	history, err := first.History(ctx, SQLiteHistoryConfig, "", 0)
+	if err != nil || len(history) != 1 || history[0].Content != "port: 8317\n" {
+		t.Fatalf("config history after seeding = %+v, %v", history, err)
+	}
+	if _, err = first.Save(ctx, sqliteTestAuth("a@example.com")); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	// A second node sharing the database gets the stored config and auth files, and stale
+	// local auth files are dropped.
+	secondSpool := t.TempDir()
+	if err = os.MkdirAll(filepath.Join(secondSpool, "auths"), 0o700); err != nil {
+		t.Fatalf("create auth dir: %v", err)
+	}
+	stale := filepath.Join(secondSpool, "auths", "stale.json")
+	if err = os.WriteFile(stale, []byte(`{"type":"claude"}`), 0o600); err != nil {
+		t.Fatalf("write stale auth: %v", err)
+	}
+	second := newTestSQLiteStore(t, SQLiteStoreConfig{Path: first.DatabasePath(), SpoolDir: secondSpool})
+
+	config, err := os.ReadFile(second.ConfigPath())
+	if err != nil || string(config) != "port: 8317\n" {
+		t.Fatalf("mirrored config = %q, %v", config, err)
+	}
+	if _, err = os.Stat(filepath.Join(second.AuthDir(), "claude-a.json")); err != nil {
+		t.Fatalf("mirrored auth file missing: %v", err)
+	}
+	if _, err = os.Stat(stale); !os.IsNotExist(err) {
+		t.Fatalf("stale auth file kept after bootstrap: %v", err)
+	}
+}
+
+func TestSQLiteStoreRuntimeState(t *testing.T) {
+	ctx := context.Background()
+	s := newTestSQLiteStore(t, SQLiteStoreConfig{})
+
+	if states, err := s.LoadRuntimeState(ctx); err != nil || states != nil {
+		t.Fatalf("LoadRuntimeState() on empty store = %+v, %v", states, err)
+	}
+	until := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	saved := map[string]*cliproxyauth.RuntimeState{
+		"claude-a.json": {Unavailable: true, NextRetryAfter: until},
+	}
+	if err := s.SaveRuntimeState(ctx, saved); err != nil {
+		t.Fatalf("SaveRuntimeState() error = %v", err)
+	}
+	states, err := s.LoadRuntimeState(ctx)
+	if err != nil {
+		t.Fatalf("LoadRuntimeState() error = %v", err)
+	}
+	if state := states["claude-a.json"]; state == nil || !state.Unavailable || !state.NextRetryAfter.Equal(until) {
+		t.Fatalf("LoadRuntimeState() = %+v, want the saved cooldown", states)
+	}
+	if history, _ := s.History(ctx, SQLiteHistoryAuth, "", 0); len(history) != 0 {
+		t.Fatalf("runtime state was recorded in history: %+v", history)
+	}
+
+	if err = s.SaveRuntimeState(ctx, nil); err != nil {
+		t.Fatalf("SaveRuntimeState(nil) error = %v", err)
+	}
+	if states, err = s.LoadRuntimeState(ctx); err != nil || states != nil {
+		t.Fatalf("LoadRuntimeState() after clearing = %+v, %v", states, err)
+	}
+}
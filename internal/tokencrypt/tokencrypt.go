@@ -0,0 +1,389 @@
+// Package tokencrypt implements envelope encryption for auth files at rest.
+//
+// Each sealed file gets a random data key that encrypts the JSON payload with AES-256-GCM.
+// The data key is itself encrypted ("wrapped") with a configured key-encryption key, whose ID
+// is stored next to it. Sealed files remain JSON objects, so stores that keep auth content as
+// JSON (for example PostgreSQL jsonb columns) handle them unchanged. Plaintext files are
+// passed through on read, which lets encrypted and unencrypted files coexist while migrating.
+package tokencrypt
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+)
+
+const (
+	// envelopeVersion marks sealed files and is bound into the ciphertext.
+	envelopeVersion = "v1"
+	// envelopeMarker is the JSON field identifying a sealed file.
+	envelopeMarker = "cliproxy_envelope"
+	keySize        = 32
+)
+
+// ErrNoKey is returned when a sealed file is read without a matching key.
+var ErrNoKey = errors.New("tokencrypt: auth file is encrypted but no matching key is configured")
+
+type envelope struct {
+	Version    string `json:"cliproxy_envelope"`
+	KeyID      string `json:"kid"`
+	WrappedKey string `json:"wrapped_key"`
+	Nonce      string `json:"nonce"`
+	Ciphertext string `json:"ciphertext"`
+}
+
+// Key is a named key-encryption key.
+type Key struct {
+	ID     string
+	Secret []byte
+}
+
+// Keyring seals with its primary key and opens with any of its keys.
+type Keyring struct {
+	sealing bool
+	primary string
+	aeads   map[string]cipher.AEAD
+}
+
+// NewKeyring builds a keyring. The first key is primary. When sealing is false the keyring
+// only decrypts.
+func NewKeyring(keys []Key, sealing bool) (*Keyring, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("tokencrypt: no keys configured")
+	}
+	ring := &Keyring{sealing: sealing, primary: keys[0].ID, aeads: make(map[string]cipher.AEAD, len(keys))}
+	for _, key := range keys {
+		if key.ID == "" {
+			return nil, fmt.Errorf("tokencrypt: key without id")
+		}
+		if _, exists := ring.aeads[key.ID]; exists {
+			return nil, fmt.Errorf("tokencrypt: duplicate key id %q", key.ID)
+		}
+		if len(key.Secret) != keySize {
+			return nil, fmt.Errorf("tokencrypt: key %q must be %d bytes, got %d", key.ID, keySize, len(key.Secret))
+		}
+		aead, err := newAEAD(key.Secret)
+		if err != nil {
+			return nil, fmt.Errorf("tokencrypt: key %q: %w", key.ID, err)
+		}
+		ring.aeads[key.ID] = aead
+	}
+	return ring, nil
+}
+
+// LoadKeyring resolves the configured key references. It returns nil when no key is configured.
+func LoadKeyring(cfg config.TokenEncryptionConfig) (*Keyring, error) {
+	cfg = cfg.Normalized()
+	if len(cfg.Keys) == 0 {
+		return nil, nil
+	}
+	keys := make([]Key, 0, len(cfg.Keys))
+	for _, ref := range cfg.Keys {
+		var raw string
+		switch {
+		case ref.Env != "":
+			value, ok := os.LookupEnv(ref.Env)
+			if !ok || strings.TrimSpace(value) == "" {
+				return nil, fmt.Errorf("tokencrypt: key %q: environment variable %s is not set", ref.ID, ref.Env)
+			}
+			raw = value
+		default:
+			data, err := os.ReadFile(ref.File)
+			if err != nil {
+				return nil, fmt.Errorf("tokencrypt: key %q: %w", ref.ID, err)
+			}
+			raw = string(data)
+		}
+		secret, err := DecodeKey(raw)
+		if err != nil {
+			return nil, fmt.Errorf("tokencrypt: key %q: %w", ref.ID, err)
+		}
+		keys = append(keys, Key{ID: ref.ID, Secret: secret})
+	}
+	return NewKeyring(keys, cfg.Enabled)
+}
+
+// DecodeKey parses a 256-bit key given as hex or standard/URL-safe base64.
+func DecodeKey(raw string) ([]byte, error) {
+	raw = strings.TrimSpace(raw)
+	if len(raw) == 2*keySize {
+		if decoded, err := hex.DecodeString(raw); err == nil {
+			return decoded, nil
+		}
+	}
+	for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if decoded, err := encoding.DecodeString(raw); err == nil && len(decoded) == keySize {
+			return decoded, nil
+		}
+	}
+	return nil, fmt.Errorf("key must be %d bytes encoded as hex or base64", keySize)
+}
+
+// Sealing reports whether the keyring encrypts on save.
+func (k *Keyring) Sealing() bool {
+	return k != nil && k.sealing
+}
+
+// Seal encrypts plaintext under the primary key.
+func (k *Keyring) Seal(plaintext []byte) ([]byte, error) {
+	if k == nil {
+		return nil, ErrNoKey
+	}
+	dataKey := make([]byte, keySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, fmt.Errorf("tokencrypt: generate data key: %w", err)
+	}
+	dataAEAD, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	wrapped, err := sealWith(k.aeads[k.primary], dataKey, additionalData(k.primary))
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, dataAEAD.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("tokencrypt: generate nonce: %w", err)
+	}
+	ciphertext := dataAEAD.Seal(nil, nonce, plaintext, additionalData(k.primary))
+	return json.Marshal(envelope{
+		Version:    envelopeVersion,
+		KeyID:      k.primary,
+		WrappedKey: base64.StdEncoding.EncodeToString(wrapped),
+		Nonce:      base64.StdEncoding.EncodeToString(nonce),
+		Ciphertext: base64.StdEncoding.EncodeToString(ciphertext),
+	})
+}
+
+// Open decrypts a sealed payload and returns plaintext payloads unchanged.
+func (k *Keyring) Open(data []byte) ([]byte, error) {
+	env, sealed := parseEnvelope(data)
+	if !sealed {
+		return data, nil
+	}
+	if k == nil {
+		return nil, ErrNoKey
+	}
+	keyAEAD, ok := k.aeads[env.KeyID]
+	if !ok {
+		return nil, fmt.Errorf("%w (kid %q)", ErrNoKey, env.KeyID)
+	}
+	wrapped, errWrapped := base64.StdEncoding.DecodeString(env.WrappedKey)
+	nonce, errNonce := base64.StdEncoding.DecodeString(env.Nonce)
+	ciphertext, errCiphertext := base64.StdEncoding.DecodeString(env.Ciphertext)
+	if errWrapped != nil || errNonce != nil || errCiphertext != nil {
+		return nil, fmt.Errorf("tokencrypt: malformed envelope encoding")
+	}
+	dataKey, err := openWith(keyAEAD, wrapped, additionalData(env.KeyID))
+	if err != nil {
+		return nil, fmt.Errorf("tokencrypt: unwrap data key: %w", err)
+	}
+	dataAEAD, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != dataAEAD.NonceSize() {
+		return nil, fmt.Errorf("tokencrypt: malformed envelope nonce")
+	}
+	plaintext, err := dataAEAD.Open(nil, nonce, ciphertext, additionalData(env.KeyID))
+	if err != nil {
+		return nil, fmt.Errorf("tokencrypt: decrypt payload: %w", err)
+	}
+	return plaintext, nil
+}
+
+// UpToDate reports whether data is already stored the way the keyring would store it: sealed
+// under the primary key when sealing, plaintext otherwise.
+func (k *Keyring) UpToDate(data []byte) bool {
+	env, sealed := parseEnvelope(data)
+	if !k.Sealing() {
+		return !sealed
+	}
+	return sealed && env.KeyID == k.primary
+}
+
+// IsSealed reports whether data is an encrypted envelope.
+func IsSealed(data []byte) bool {
+	_, sealed := parseEnvelope(data)
+	return sealed
+}
+
+func parseEnvelope(data []byte) (envelope, bool) {
+	var env envelope
+	if !bytes.Contains(data, []byte(envelopeMarker)) {
+		return env, false
+	}
+	if err := json.Unmarshal(data, &env); err != nil || env.Version == "" {
+		return env, false
+	}
+	return env, true
+}
+
+func additionalData(keyID string) []byte {
+	return []byte(envelopeMarker + "/" + envelopeVersion + "/" + keyID)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("tokencrypt: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("tokencrypt: %w", err)
+	}
+	return aead, nil
+}
+
+// sealWith encrypts plaintext and prefixes the random nonce.
+func sealWith(aead cipher.AEAD, plaintext, additional []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("tokencrypt: generate nonce: %w", err)
+	}
+	return aead.Seal(nonce, nonce, plaintext, additional), nil
+}
+
+func openWith(aead cipher.AEAD, data, additional []byte) ([]byte, error) {
+	if len(data) < aead.NonceSize() {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+	return aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], additional)
+}
+
+var (
+	defaultMu      sync.RWMutex
+	defaultKeyring *Keyring
+)
+
+// Configure loads the keyring described by cfg and installs it as the process default.
+func Configure(cfg config.TokenEncryptionConfig) error {
+	ring, err := LoadKeyring(cfg)
+	if err != nil {
+		return err
+	}
+	SetDefault(ring)
+	return nil
+}
+
+// SetDefault installs ring as the process-wide keyring. A nil ring disables encryption.
+func SetDefault(ring *Keyring) {
+	defaultMu.Lock()
+	defaultKeyring = ring
+	defaultMu.Unlock()
+}
+
+// Default returns the process-wide keyring, or nil when none is configured.
+func Default() *Keyring {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultKeyring
+}
+
+// Seal encrypts plaintext with the default keyring when sealing is enabled and returns it
+// unchanged otherwise.
+func Seal(plaintext []byte) ([]byte, error) {
+	ring := Default()
+	if !ring.Sealing() {
+		return plaintext, nil
+	}
+	return ring.Seal(plaintext)
+}
+
+// Open decrypts data with the default keyring. Plaintext data is returned unchanged.
+func Open(data []byte) ([]byte, error) {
+	return Default().Open(data)
+}
+
+// UpToDate reports whether data matches how the default keyring stores files.
+func UpToDate(data []byte) bool {
+	return Default().UpToDate(data)
+}
+
+// ReadFile reads path and decrypts it with the default keyring.
+func ReadFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Open(data)
+}
+
+// SealFile rewrites the file at path so it matches the default keyring: plaintext files are
+// encrypted when sealing is enabled and files under an older key are re-encrypted with the
+// primary key. It reports whether the file changed.
+func SealFile(path string) (bool, error) {
+	return Default().RewriteFile(path)
+}
+
+// RewriteFile brings the file at path in line with the keyring, see SealFile.
+func (k *Keyring) RewriteFile(path string) (bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	if len(bytes.TrimSpace(data)) == 0 || k.UpToDate(data) {
+		return false, nil
+	}
+	plaintext, err := k.Open(data)
+	if err != nil {
+		return false, err
+	}
+	out := plaintext
+	if k.Sealing() {
+		if out, err = k.Seal(plaintext); err != nil {
+			return false, err
+		}
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	if err = replaceFile(path, out, info.Mode().Perm()); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// WriteFile seals plaintext with the default keyring and writes it to path, see
+// Keyring.WriteFile.
+func WriteFile(path string, plaintext []byte, perm os.FileMode) error {
+	return Default().WriteFile(path, plaintext, perm)
+}
+
+// WriteFile seals plaintext when sealing is enabled and replaces path with the result through
+// a temporary file, so the plaintext never reaches the disk.
+func (k *Keyring) WriteFile(path string, plaintext []byte, perm os.FileMode) error {
+	out := plaintext
+	if k.Sealing() {
+		var err error
+		if out, err = k.Seal(plaintext); err != nil {
+			return err
+		}
+	}
+	return replaceFile(path, out, perm)
+}
+
+func replaceFile(path string, data []byte, perm os.FileMode) error {
+	tmp := filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err := os.WriteFile(tmp, data, perm); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return nil
+}
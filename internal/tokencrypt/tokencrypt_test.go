@@ -0,0 +1,128 @@
+package tokencrypt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+)
+
+func testKey(fill byte) []byte {
+	return bytes.Repeat([]byte{fill}, keySize)
+}
+
+func TestKeyring_SealOpenAndRotate(t *testing.T) {
+	oldRing, err := NewKeyring([]Key{{ID: "old", Secret: testKey(1)}}, true)
+	if err != nil {
+		t.Fatalf("NewKeyring() error = %v", err)
+	}
+	plaintext := []byte(`{"type":"claude","refresh_token":"rt-secret"}`)
+
+	sealed, err := oldRing.Seal(plaintext)
+	if err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+	if bytes.Contains(sealed, []byte("rt-secret")) || !IsSealed(sealed) {
+		t.Fatalf("sealed payload leaks plaintext or is not recognised: %s", sealed)
+	}
+	opened, err := oldRing.Open(sealed)
+	if err != nil || !bytes.Equal(opened, plaintext) {
+		t.Fatalf("Open() = %s, %v; want original plaintext", opened, err)
+	}
+	if passthrough, errOpen := oldRing.Open(plaintext); errOpen != nil || !bytes.Equal(passthrough, plaintext) {
+		t.Fatalf("Open(plaintext) = %s, %v; want passthrough", passthrough, errOpen)
+	}
+
+	rotated, err := NewKeyring([]Key{{ID: "new", Secret: testKey(2)}, {ID: "old", Secret: testKey(1)}}, true)
+	if err != nil {
+		t.Fatalf("NewKeyring() error = %v", err)
+	}
+	if rotated.UpToDate(sealed) {
+		t.Fatal("file sealed under the old key should need re-encryption")
+	}
+	if opened, err = rotated.Open(sealed); err != nil || !bytes.Equal(opened, plaintext) {
+		t.Fatalf("rotated Open() = %s, %v", opened, err)
+	}
+
+	newOnly, err := NewKeyring([]Key{{ID: "new", Secret: testKey(2)}}, true)
+	if err != nil {
+		t.Fatalf("NewKeyring() error = %v", err)
+	}
+	if _, err = newOnly.Open(sealed); !errors.Is(err, ErrNoKey) {
+		t.Fatalf("Open() without old key error = %v, want ErrNoKey", err)
+	}
+
+	tampered := bytes.Replace(sealed, []byte(`"kid":"old"`), []byte(`"kid":"new"`), 1)
+	if _, err = rotated.Open(tampered); err == nil {
+		t.Fatal("Open() accepted an envelope whose key id was changed")
+	}
+}
+
+func TestKeyring_RewriteFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "claude.json")
+	plaintext := []byte(`{"type":"claude","access_token":"at"}`)
+	if err := os.WriteFile(path, plaintext, 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	ring, err := NewKeyring([]Key{{ID: "k1", Secret: testKey(3)}}, true)
+	if err != nil {
+		t.Fatalf("NewKeyring() error = %v", err)
+	}
+
+	changed, err := ring.RewriteFile(path)
+	if err != nil || !changed {
+		t.Fatalf("RewriteFile() = %t, %v; want encrypted", changed, err)
+	}
+	data, _ := os.ReadFile(path)
+	if !IsSealed(data) {
+		t.Fatalf("file not sealed: %s", data)
+	}
+	if changed, err = ring.RewriteFile(path); err != nil || changed {
+		t.Fatalf("second RewriteFile() = %t, %v; want no change", changed, err)
+	}
+
+	decryptOnly, err := NewKeyring([]Key{{ID: "k1", Secret: testKey(3)}}, false)
+	if err != nil {
+		t.Fatalf("NewKeyring() error = %v", err)
+	}
+	if changed, err = decryptOnly.RewriteFile(path); err != nil || !changed {
+		t.Fatalf("decrypting RewriteFile() = %t, %v", changed, err)
+	}
+	data, _ = os.ReadFile(path)
+	if !bytes.Equal(data, plaintext) {
+		t.Fatalf("decrypted file = %s, want %s", data, plaintext)
+	}
+}
+
+func TestLoadKeyring_ReadsEnvAndFileKeys(t *testing.T) {
+	t.Setenv("TEST_TOKEN_KEY", base64.StdEncoding.EncodeToString(testKey(4)))
+	keyFile := filepath.Join(t.TempDir(), "old.key")
+	if err := os.WriteFile(keyFile, []byte(base64.RawURLEncoding.EncodeToString(testKey(5))+"\n"), 0o600); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+
+	ring, err := LoadKeyring(config.TokenEncryptionConfig{
+		Enabled: true,
+		Keys: []config.TokenEncryptionKey{
+			{ID: "current", Env: "TEST_TOKEN_KEY"},
+			{ID: "previous", File: keyFile},
+		},
+	})
+	if err != nil {
+		t.Fatalf("LoadKeyring() error = %v", err)
+	}
+	if !ring.Sealing() || ring.primary != "current" || len(ring.aeads) != 2 {
+		t.Fatalf("keyring = %+v", ring)
+	}
+
+	if _, err = LoadKeyring(config.TokenEncryptionConfig{Enabled: true, Keys: []config.TokenEncryptionKey{{ID: "x", Env: "TEST_TOKEN_KEY_MISSING"}}}); err == nil {
+		t.Fatal("LoadKeyring() accepted a missing environment variable")
+	}
+	if ring, err = LoadKeyring(config.TokenEncryptionConfig{}); err != nil || ring != nil {
+		t.Fatalf("LoadKeyring(disabled) = %v, %v; want nil keyring", ring, err)
+	}
+}
@@ -5,6 +5,7 @@ package from_ir
 import (
 	"encoding/json"
 	"fmt"
+	"slices"
 	"strings"
 	"time"
 
@@ -486,7 +487,8 @@ func ToOllamaShowResponse(modelName string) []byte {
 	maxOutputTokens := 16384
 	architecture := "llama"
 
-	if info := registry.GetGlobalRegistry().GetModelInfo(cleanModelName, ""); info != nil {
+	info := registry.GetGlobalRegistry().GetModelInfo(cleanModelName, "")
+	if info != nil {
 		if isKnownArchitecture(info.Type) {
 			architecture = info.Type
 		}
@@ -528,7 +530,7 @@ func ToOllamaShowResponse(modelName string) []byte {
 			"quantization_level": "Q4_K_M",
 		},
 		"model_info":   modelInfo,
-		"capabilities": inferCapabilities(cleanModelName),
+		"capabilities": ollamaCapabilities(info, cleanModelName),
 	}
 
 	jsonBytes, _ := json.Marshal(result)
@@ -543,6 +545,35 @@ func isKnownArchitecture(modelType string) bool {
 	return false
 }
 
+// ollamaCapabilities derives Ollama capabilities from registry metadata. Name heuristics are
+// only used for details the registry entry does not carry.
+func ollamaCapabilities(info *registry.ModelInfo, modelID string) []string {
+	if info == nil {
+		return inferCapabilities(modelID)
+	}
+	if slices.Contains(info.SupportedGenerationMethods, "embedContent") || strings.Contains(strings.ToLower(info.ID), "embedding") {
+		return []string{"embedding"}
+	}
+
+	capabilities := []string{"completion"}
+	if len(info.SupportedParameters) == 0 || slices.Contains(info.SupportedParameters, "tools") {
+		capabilities = append(capabilities, "tools")
+	}
+	if len(info.SupportedInputModalities) > 0 {
+		if slices.ContainsFunc(info.SupportedInputModalities, func(modality string) bool {
+			return strings.EqualFold(modality, "image")
+		}) {
+			capabilities = append(capabilities, "vision")
+		}
+	} else if slices.Contains(inferCapabilities(modelID), "vision") {
+		capabilities = append(capabilities, "vision")
+	}
+	if info.Thinking != nil {
+		capabilities = append(capabilities, "thinking")
+	}
+	return capabilities
+}
+
 func inferCapabilities(modelID string) []string {
 	name := strings.ToLower(modelID)
 	capabilities := []string{"completion", "tools"}
@@ -87,6 +87,27 @@ func BuildConfigChangeDetails(oldCfg, newCfg *config.Config) []string {
 	if oldCfg.Routing.Strategy != newCfg.Routing.Strategy {
 		changes = append(changes, fmt.Sprintf("routing.strategy: %s -> %s", oldCfg.Routing.Strategy, newCfg.Routing.Strategy))
 	}
+	if oldCfg.Routing.SessionAffinity.Enabled != newCfg.Routing.SessionAffinity.Enabled {
+		changes = append(changes, fmt.Sprintf("routing.session-affinity.enabled: %t -> %t", oldCfg.Routing.SessionAffinity.Enabled, newCfg.Routing.SessionAffinity.Enabled))
+	}
+	if oldCfg.Routing.SessionAffinity.TTLSeconds != newCfg.Routing.SessionAffinity.TTLSeconds {
+		changes = append(changes, fmt.Sprintf("routing.session-affinity.ttl-seconds: %d -> %d", oldCfg.Routing.SessionAffinity.TTLSeconds, newCfg.Routing.SessionAffinity.TTLSeconds))
+	}
+	if !reflect.DeepEqual(oldCfg.Routing.Hedging, newCfg.Routing.Hedging) {
+		changes = append(changes, "routing.hedging: updated")
+	}
+	if oldCfg.Routing.Queue.Enabled != newCfg.Routing.Queue.Enabled {
+		changes = append(changes, fmt.Sprintf("routing.queue.enabled: %t -> %t", oldCfg.Routing.Queue.Enabled, newCfg.Routing.Queue.Enabled))
+	}
+	if oldCfg.Routing.Queue.MaxDepth != newCfg.Routing.Queue.MaxDepth {
+		changes = append(changes, fmt.Sprintf("routing.queue.max-depth: %d -> %d", oldCfg.Routing.Queue.MaxDepth, newCfg.Routing.Queue.MaxDepth))
+	}
+	if oldCfg.Routing.Queue.MaxPerClient != newCfg.Routing.Queue.MaxPerClient {
+		changes = append(changes, fmt.Sprintf("routing.queue.max-per-client: %d -> %d", oldCfg.Routing.Queue.MaxPerClient, newCfg.Routing.Queue.MaxPerClient))
+	}
+	if oldCfg.Routing.Queue.TimeoutSeconds != newCfg.Routing.Queue.TimeoutSeconds {
+		changes = append(changes, fmt.Sprintf("routing.queue.timeout-seconds: %d -> %d", oldCfg.Routing.Queue.TimeoutSeconds, newCfg.Routing.Queue.TimeoutSeconds))
+	}
 
 	// API keys (redacted) and counts
 	if len(oldCfg.APIKeys) != len(newCfg.APIKeys) {
@@ -94,6 +115,20 @@ func BuildConfigChangeDetails(oldCfg, newCfg *config.Config) []string {
 	} else if !reflect.DeepEqual(trimStrings(oldCfg.APIKeys), trimStrings(newCfg.APIKeys)) {
 		changes = append(changes, "api-keys: values updated (count unchanged, redacted)")
 	}
+	if len(oldCfg.ClientKeys) != len(newCfg.ClientKeys) {
+		changes = append(changes, fmt.Sprintf("client-keys count: %d -> %d", len(oldCfg.ClientKeys), len(newCfg.ClientKeys)))
+	} else if !reflect.DeepEqual(oldCfg.ClientKeys, newCfg.ClientKeys) {
+		changes = append(changes, "client-keys: entries updated (count unchanged, redacted)")
+	}
+	if !reflect.DeepEqual(oldCfg.OIDCJWT, newCfg.OIDCJWT) {
+		changes = append(changes, fmt.Sprintf("oidc-jwt: updated (enabled %t -> %t)", oldCfg.OIDCJWT.Enabled, newCfg.OIDCJWT.Enabled))
+	}
+	if !reflect.DeepEqual(oldCfg.TokenEncryption, newCfg.TokenEncryption) {
+		changes = append(changes, fmt.Sprintf("token-encryption: updated (enabled %t -> %t, keys %d -> %d; restart required)", oldCfg.TokenEncryption.Enabled, newCfg.TokenEncryption.Enabled, len(oldCfg.TokenEncryption.Keys), len(newCfg.TokenEncryption.Keys)))
+	}
+	if !reflect.DeepEqual(oldCfg.MTLS, newCfg.MTLS) {
+		changes = append(changes, fmt.Sprintf("mtls: updated (enabled %t -> %t, principals %d -> %d)", oldCfg.MTLS.Enabled, newCfg.MTLS.Enabled, len(oldCfg.MTLS.Principals), len(newCfg.MTLS.Principals)))
+	}
 	if len(oldCfg.GeminiKey) != len(newCfg.GeminiKey) {
 		changes = append(changes, fmt.Sprintf("gemini-api-key count: %d -> %d", len(oldCfg.GeminiKey), len(newCfg.GeminiKey)))
 	} else {
@@ -251,6 +286,9 @@ func BuildConfigChangeDetails(oldCfg, newCfg *config.Config) []string {
 	if entries, _ := DiffOAuthModelAliasChanges(oldCfg.OAuthModelAlias, newCfg.OAuthModelAlias); len(entries) > 0 {
 		changes = append(changes, entries...)
 	}
+	if !reflect.DeepEqual(oldCfg.ModelFallbacks, newCfg.ModelFallbacks) {
+		changes = append(changes, fmt.Sprintf("model-fallbacks: updated (%d -> %d entries)", len(oldCfg.ModelFallbacks), len(newCfg.ModelFallbacks)))
+	}
 
 	// Remote management (never print the key)
 	if oldCfg.RemoteManagement.AllowRemote != newCfg.RemoteManagement.AllowRemote {
@@ -277,6 +315,14 @@ func BuildConfigChangeDetails(oldCfg, newCfg *config.Config) []string {
 			changes = append(changes, "remote-management.secret-key: updated")
 		}
 	}
+	if len(oldCfg.RemoteManagement.Keys) != len(newCfg.RemoteManagement.Keys) {
+		changes = append(changes, fmt.Sprintf("remote-management.keys count: %d -> %d", len(oldCfg.RemoteManagement.Keys), len(newCfg.RemoteManagement.Keys)))
+	} else if !reflect.DeepEqual(oldCfg.RemoteManagement.Keys, newCfg.RemoteManagement.Keys) {
+		changes = append(changes, "remote-management.keys: entries updated (count unchanged, redacted)")
+	}
+	if oldCfg.RemoteManagement.DisableAuditLog != newCfg.RemoteManagement.DisableAuditLog {
+		changes = append(changes, fmt.Sprintf("remote-management.disable-audit-log: %t -> %t", oldCfg.RemoteManagement.DisableAuditLog, newCfg.RemoteManagement.DisableAuditLog))
+	}
 
 	// OpenAI compatibility providers (summarized)
 	if compat := DiffOpenAICompatibility(oldCfg.OpenAICompatibility, newCfg.OpenAICompatibility); len(compat) > 0 {
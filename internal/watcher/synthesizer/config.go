@@ -67,6 +67,15 @@ func (s *ConfigSynthesizer) synthesizeGeminiKeys(ctx *SynthesisContext) []*corea
 		if entry.Priority != 0 {
 			attrs["priority"] = strconv.Itoa(entry.Priority)
 		}
+		if entry.Weight > 0 {
+			attrs["weight"] = strconv.Itoa(entry.Weight)
+		}
+		if entry.MaxConcurrency > 0 {
+			attrs["max_concurrency"] = strconv.Itoa(entry.MaxConcurrency)
+		}
+		if availability := encodeAvailability(entry.Availability); availability != "" {
+			attrs["availability"] = availability
+		}
 		if base != "" {
 			attrs["base_url"] = base
 		}
@@ -114,6 +123,15 @@ func (s *ConfigSynthesizer) synthesizeClaudeKeys(ctx *SynthesisContext) []*corea
 		if ck.Priority != 0 {
 			attrs["priority"] = strconv.Itoa(ck.Priority)
 		}
+		if ck.Weight > 0 {
+			attrs["weight"] = strconv.Itoa(ck.Weight)
+		}
+		if ck.MaxConcurrency > 0 {
+			attrs["max_concurrency"] = strconv.Itoa(ck.MaxConcurrency)
+		}
+		if availability := encodeAvailability(ck.Availability); availability != "" {
+			attrs["availability"] = availability
+		}
 		if base != "" {
 			attrs["base_url"] = base
 		}
@@ -161,6 +179,15 @@ func (s *ConfigSynthesizer) synthesizeCodexKeys(ctx *SynthesisContext) []*coreau
 		if ck.Priority != 0 {
 			attrs["priority"] = strconv.Itoa(ck.Priority)
 		}
+		if ck.Weight > 0 {
+			attrs["weight"] = strconv.Itoa(ck.Weight)
+		}
+		if ck.MaxConcurrency > 0 {
+			attrs["max_concurrency"] = strconv.Itoa(ck.MaxConcurrency)
+		}
+		if availability := encodeAvailability(ck.Availability); availability != "" {
+			attrs["availability"] = availability
+		}
 		if ck.BaseURL != "" {
 			attrs["base_url"] = ck.BaseURL
 		}
@@ -222,6 +249,15 @@ func (s *ConfigSynthesizer) synthesizeOpenAICompat(ctx *SynthesisContext) []*cor
 			if compat.Priority != 0 {
 				attrs["priority"] = strconv.Itoa(compat.Priority)
 			}
+			if entry.Weight > 0 {
+				attrs["weight"] = strconv.Itoa(entry.Weight)
+			}
+			if entry.MaxConcurrency > 0 {
+				attrs["max_concurrency"] = strconv.Itoa(entry.MaxConcurrency)
+			}
+			if availability := encodeAvailability(entry.Availability); availability != "" {
+				attrs["availability"] = availability
+			}
 			if key != "" {
 				attrs["api_key"] = key
 			}
@@ -301,6 +337,15 @@ func (s *ConfigSynthesizer) synthesizeVertexCompat(ctx *SynthesisContext) []*cor
 		if compat.Priority != 0 {
 			attrs["priority"] = strconv.Itoa(compat.Priority)
 		}
+		if compat.Weight > 0 {
+			attrs["weight"] = strconv.Itoa(compat.Weight)
+		}
+		if compat.MaxConcurrency > 0 {
+			attrs["max_concurrency"] = strconv.Itoa(compat.MaxConcurrency)
+		}
+		if availability := encodeAvailability(compat.Availability); availability != "" {
+			attrs["availability"] = availability
+		}
 		if key != "" {
 			attrs["api_key"] = key
 		}
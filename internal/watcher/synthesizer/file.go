@@ -12,7 +12,9 @@ import (
 
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/auth/codex"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/runtime/geminicli"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/tokencrypt"
 	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	log "github.com/sirupsen/logrus"
 )
 
 // FileSynthesizer generates Auth entries from OAuth JSON files.
@@ -71,6 +73,11 @@ func synthesizeFileAuths(ctx *SynthesisContext, fullPath string, data []byte) []
 	}
 	now := ctx.Now
 	cfg := ctx.Config
+	data, errOpen := tokencrypt.Open(data)
+	if errOpen != nil {
+		log.Warnf("auth file %s: %v", filepath.Base(fullPath), errOpen)
+		return nil
+	}
 	var metadata map[string]any
 	if errUnmarshal := json.Unmarshal(data, &metadata); errUnmarshal != nil {
 		return nil
@@ -149,6 +156,38 @@ func synthesizeFileAuths(ctx *SynthesisContext, fullPath string, data []byte) []
 			}
 		}
 	}
+	// Read weight from auth file.
+	if rawWeight, ok := metadata["weight"]; ok {
+		switch v := rawWeight.(type) {
+		case float64:
+			if v > 0 {
+				a.Attributes["weight"] = strconv.Itoa(int(v))
+			}
+		case string:
+			weight := strings.TrimSpace(v)
+			if parsed, errAtoi := strconv.Atoi(weight); errAtoi == nil && parsed > 0 {
+				a.Attributes["weight"] = weight
+			}
+		}
+	}
+	// Read max concurrency from auth file.
+	if rawMax, ok := metadata["max_concurrency"]; ok {
+		switch v := rawMax.(type) {
+		case float64:
+			if v > 0 {
+				a.Attributes["max_concurrency"] = strconv.Itoa(int(v))
+			}
+		case string:
+			maxConcurrency := strings.TrimSpace(v)
+			if parsed, errAtoi := strconv.Atoi(maxConcurrency); errAtoi == nil && parsed > 0 {
+				a.Attributes["max_concurrency"] = maxConcurrency
+			}
+		}
+	}
+	// Read availability schedule from auth file.
+	if availability := availabilityFromMetadata(metadata["availability"]); availability != "" {
+		a.Attributes["availability"] = availability
+	}
 	// Read note from auth file.
 	if rawNote, ok := metadata["note"]; ok {
 		if note, isStr := rawNote.(string); isStr {
@@ -230,6 +269,18 @@ func SynthesizeGeminiVirtualAuths(primary *coreauth.Auth, metadata map[string]an
 		if priorityVal, hasPriority := primary.Attributes["priority"]; hasPriority && priorityVal != "" {
 			attrs["priority"] = priorityVal
 		}
+		// Propagate weight from primary auth to virtual auths
+		if weightVal, hasWeight := primary.Attributes["weight"]; hasWeight && weightVal != "" {
+			attrs["weight"] = weightVal
+		}
+		// Propagate max concurrency from primary auth to virtual auths
+		if maxVal, hasMax := primary.Attributes["max_concurrency"]; hasMax && maxVal != "" {
+			attrs["max_concurrency"] = maxVal
+		}
+		// Propagate availability schedule from primary auth to virtual auths
+		if availabilityVal, hasAvailability := primary.Attributes["availability"]; hasAvailability && availabilityVal != "" {
+			attrs["availability"] = availabilityVal
+		}
 		// Propagate note from primary auth to virtual auths
 		if noteVal, hasNote := primary.Attributes["note"]; hasNote && noteVal != "" {
 			attrs["note"] = noteVal
@@ -3,6 +3,7 @@ package synthesizer
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"sort"
 	"strings"
@@ -118,3 +119,32 @@ func addConfigHeadersToAttrs(headers map[string]string, attrs map[string]string)
 		attrs["header:"+key] = val
 	}
 }
+
+// encodeAvailability serialises an availability schedule into its auth attribute form.
+// It returns an empty string when the schedule imposes no restriction.
+func encodeAvailability(schedule *config.AvailabilitySchedule) string {
+	if schedule.IsZero() {
+		return ""
+	}
+	raw, err := json.Marshal(schedule)
+	if err != nil {
+		return ""
+	}
+	return string(raw)
+}
+
+// availabilityFromMetadata reads an auth file "availability" object and encodes it as an attribute.
+func availabilityFromMetadata(raw any) string {
+	if raw == nil {
+		return ""
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return ""
+	}
+	var schedule config.AvailabilitySchedule
+	if err = json.Unmarshal(data, &schedule); err != nil {
+		return ""
+	}
+	return encodeAvailability(&schedule)
+}
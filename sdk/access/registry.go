@@ -20,6 +20,14 @@ type Result struct {
 	Metadata  map[string]string
 }
 
+// Metadata keys set by providers that authenticate managed client keys.
+const (
+	// MetadataClientKeyName names the managed client key that authenticated the request.
+	MetadataClientKeyName = "client_key_name"
+	// MetadataClientKeyOwner records the owner of the managed client key.
+	MetadataClientKeyOwner = "client_key_owner"
+)
+
 var (
 	registryMu sync.RWMutex
 	registry   = make(map[string]Provider)
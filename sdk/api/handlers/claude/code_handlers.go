@@ -16,6 +16,7 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/batch"
 	. "github.com/router-for-me/CLIProxyAPI/v6/internal/constant"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
@@ -28,6 +29,9 @@ import (
 // It holds a pool of clients to interact with the backend service.
 type ClaudeCodeAPIHandler struct {
 	*handlers.BaseAPIHandler
+
+	// batches runs /v1/messages/batches jobs; nil disables the batch endpoints.
+	batches *batch.Manager
 }
 
 // NewClaudeCodeAPIHandler creates a new Claude API handlers instance.
@@ -174,32 +178,36 @@ func (h *ClaudeCodeAPIHandler) handleNonStreamingResponse(c *gin.Context, rawJSO
 		return
 	}
 
-	// Decompress gzipped responses - Claude API sometimes returns gzip without Content-Encoding header
-	// This fixes title generation and other non-streaming responses that arrive compressed
-	if len(resp) >= 2 && resp[0] == 0x1f && resp[1] == 0x8b {
-		gzReader, errGzip := gzip.NewReader(bytes.NewReader(resp))
-		if errGzip != nil {
-			log.Warnf("failed to decompress gzipped Claude response: %v", errGzip)
-		} else {
-			defer func() {
-				if errClose := gzReader.Close(); errClose != nil {
-					log.Warnf("failed to close Claude gzip reader: %v", errClose)
-				}
-			}()
-			decompressed, errRead := io.ReadAll(gzReader)
-			if errRead != nil {
-				log.Warnf("failed to read decompressed Claude response: %v", errRead)
-			} else {
-				resp = decompressed
-			}
-		}
-	}
-
 	handlers.WriteUpstreamHeaders(c.Writer.Header(), upstreamHeaders)
-	_, _ = c.Writer.Write(resp)
+	_, _ = c.Writer.Write(decompressClaudeResponse(resp))
 	cliCancel()
 }
 
+// decompressClaudeResponse inflates gzipped responses - Claude API sometimes returns gzip without
+// Content-Encoding header. This fixes title generation and other non-streaming responses that
+// arrive compressed.
+func decompressClaudeResponse(resp []byte) []byte {
+	if len(resp) < 2 || resp[0] != 0x1f || resp[1] != 0x8b {
+		return resp
+	}
+	gzReader, errGzip := gzip.NewReader(bytes.NewReader(resp))
+	if errGzip != nil {
+		log.Warnf("failed to decompress gzipped Claude response: %v", errGzip)
+		return resp
+	}
+	defer func() {
+		if errClose := gzReader.Close(); errClose != nil {
+			log.Warnf("failed to close Claude gzip reader: %v", errClose)
+		}
+	}()
+	decompressed, errRead := io.ReadAll(gzReader)
+	if errRead != nil {
+		log.Warnf("failed to read decompressed Claude response: %v", errRead)
+		return resp
+	}
+	return decompressed
+}
+
 // handleStreamingResponse streams Claude-compatible responses backed by Gemini.
 // It sets up SSE, selects a backend client with rotation/quota logic,
 // forwards chunks, and translates them to Claude CLI format.
@@ -248,7 +256,7 @@ func (h *ClaudeCodeAPIHandler) handleStreamingResponse(c *gin.Context, rawJSON [
 				continue
 			}
 			// Upstream failed immediately. Return proper error status and JSON.
-			h.WriteErrorResponse(c, errMsg)
+			h.WriteStreamErrorResponse(c, errMsg, func(errMsg *interfaces.ErrorMessage) { h.writeClaudeStreamError(c, errMsg) })
 			if errMsg != nil {
 				cliCancel(errMsg.Error)
 			} else {
@@ -291,21 +299,26 @@ func (h *ClaudeCodeAPIHandler) forwardClaudeStream(c *gin.Context, flusher http.
 			_, _ = c.Writer.Write(chunk)
 		},
 		WriteTerminalError: func(errMsg *interfaces.ErrorMessage) {
-			if errMsg == nil {
-				return
-			}
-			status := http.StatusInternalServerError
-			if errMsg.StatusCode > 0 {
-				status = errMsg.StatusCode
-			}
-			c.Status(status)
-
-			errorBytes, _ := json.Marshal(h.toClaudeError(errMsg))
-			_, _ = fmt.Fprintf(c.Writer, "event: error\ndata: %s\n\n", errorBytes)
+			h.writeClaudeStreamError(c, errMsg)
 		},
 	})
 }
 
+// writeClaudeStreamError writes errMsg as an Anthropic error event.
+func (h *ClaudeCodeAPIHandler) writeClaudeStreamError(c *gin.Context, errMsg *interfaces.ErrorMessage) {
+	if errMsg == nil {
+		return
+	}
+	status := http.StatusInternalServerError
+	if errMsg.StatusCode > 0 {
+		status = errMsg.StatusCode
+	}
+	c.Status(status)
+
+	errorBytes, _ := json.Marshal(h.toClaudeError(errMsg))
+	_, _ = fmt.Fprintf(c.Writer, "event: error\ndata: %s\n\n", errorBytes)
+}
+
 type claudeErrorDetail struct {
 	Type    string `json:"type"`
 	Message string `json:"message"`
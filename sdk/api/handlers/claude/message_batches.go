@@ -0,0 +1,371 @@
+package claude
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/batch"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/api/handlers"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+const (
+	// messageBatchKind identifies Anthropic message batches in the batch queue.
+	messageBatchKind = "anthropic-messages"
+
+	maxMessageBatchRequests = 100000
+	defaultBatchListLimit   = 20
+	maxBatchListLimit       = 1000
+)
+
+var messageBatchCustomIDPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// SetBatchManager enables the /v1/messages/batches endpoints backed by manager and registers the
+// runner that executes batch items through the normal request path.
+func (h *ClaudeCodeAPIHandler) SetBatchManager(manager *batch.Manager) {
+	h.batches = manager
+	if manager != nil {
+		manager.RegisterRunner(messageBatchKind, h.runMessageBatchItem)
+	}
+}
+
+// runMessageBatchItem executes one batch item on behalf of the client key that created the batch.
+func (h *ClaudeCodeAPIHandler) runMessageBatchItem(ctx context.Context, job batch.Job, item batch.Item) ([]byte, int, error) {
+	key, err := h.BatchIdentity(job.Owner, job.Principal)
+	if err != nil {
+		return nil, http.StatusForbidden, err
+	}
+	ctx = handlers.WithBackgroundPriority(handlers.WithClientKey(ctx, key, job.OwnerName))
+	modelName := gjson.GetBytes(item.Params, "model").String()
+	resp, _, errMsg := h.ExecuteWithAuthManager(ctx, h.HandlerType(), modelName, item.Params, "")
+	if errMsg != nil {
+		status := errMsg.StatusCode
+		if status <= 0 {
+			status = http.StatusInternalServerError
+		}
+		err := errMsg.Error
+		if err == nil {
+			return nil, status, errors.New(http.StatusText(status))
+		}
+		if status == http.StatusTooManyRequests && !coreauth.IsTransientRateLimit(err) {
+			err = batch.Final(err)
+		}
+		return nil, status, err
+	}
+	return decompressClaudeResponse(resp), 0, nil
+}
+
+// CreateMessageBatch handles POST /v1/messages/batches.
+func (h *ClaudeCodeAPIHandler) CreateMessageBatch(c *gin.Context) {
+	if !h.requireBatches(c) {
+		return
+	}
+	rawJSON, err := c.GetRawData()
+	if err != nil {
+		writeClaudeAPIError(c, http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("Invalid request: %v", err))
+		return
+	}
+	items, errMessage := parseMessageBatchRequests(rawJSON)
+	if errMessage != "" {
+		writeClaudeAPIError(c, http.StatusBadRequest, "invalid_request_error", errMessage)
+		return
+	}
+
+	_, ownerName := handlers.RequestClientKey(c)
+	job, err := h.batches.Create(batch.Spec{
+		Kind:      messageBatchKind,
+		IDPrefix:  "msgbatch_",
+		Owner:     handlers.RequestOwner(c),
+		OwnerName: ownerName,
+		Principal: h.ExternalPrincipal(c),
+		Items:     items,
+	})
+	if err != nil {
+		log.Errorf("failed to create message batch: %v", err)
+		writeClaudeAPIError(c, http.StatusInternalServerError, "api_error", "Failed to create message batch")
+		return
+	}
+	c.Data(http.StatusOK, "application/json", messageBatchObject(c, job))
+}
+
+// parseMessageBatchRequests validates the requests of a create call. It returns a message
+// describing the first problem found.
+func parseMessageBatchRequests(rawJSON []byte) ([]batch.Item, string) {
+	if !gjson.ValidBytes(rawJSON) {
+		return nil, "Invalid request: body must be valid JSON"
+	}
+	requests := gjson.GetBytes(rawJSON, "requests")
+	if !requests.IsArray() || len(requests.Array()) == 0 {
+		return nil, "requests: must be a non-empty array"
+	}
+	entries := requests.Array()
+	if len(entries) > maxMessageBatchRequests {
+		return nil, fmt.Sprintf("requests: a batch may contain at most %d requests", maxMessageBatchRequests)
+	}
+
+	items := make([]batch.Item, 0, len(entries))
+	seen := make(map[string]struct{}, len(entries))
+	for i, entry := range entries {
+		customID := entry.Get("custom_id").String()
+		if !messageBatchCustomIDPattern.MatchString(customID) {
+			return nil, fmt.Sprintf("requests.%d.custom_id: must be 1 to 64 letters, digits, underscores or hyphens", i)
+		}
+		if _, dup := seen[customID]; dup {
+			return nil, fmt.Sprintf("requests.%d.custom_id: duplicate custom_id %q", i, customID)
+		}
+		seen[customID] = struct{}{}
+		params := entry.Get("params")
+		if !params.IsObject() {
+			return nil, fmt.Sprintf("requests.%d.params: must be an object", i)
+		}
+		if params.Get("model").String() == "" {
+			return nil, fmt.Sprintf("requests.%d.params.model: field required", i)
+		}
+		if params.Get("stream").Bool() {
+			return nil, fmt.Sprintf("requests.%d.params.stream: streaming is not supported in batches", i)
+		}
+		items = append(items, batch.Item{CustomID: customID, Params: json.RawMessage(params.Raw)})
+	}
+	return items, ""
+}
+
+// ListMessageBatches handles GET /v1/messages/batches.
+func (h *ClaudeCodeAPIHandler) ListMessageBatches(c *gin.Context) {
+	if !h.requireBatches(c) {
+		return
+	}
+	limit := defaultBatchListLimit
+	if raw := c.Query("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > maxBatchListLimit {
+			writeClaudeAPIError(c, http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("limit: must be an integer between 1 and %d", maxBatchListLimit))
+			return
+		}
+		limit = parsed
+	}
+
+	owner := handlers.RequestOwner(c)
+	jobs := h.batches.List(messageBatchKind, owner)
+	var page []batch.Job
+	hasMore := false
+	if beforeID := c.Query("before_id"); beforeID != "" {
+		// Batches are listed newest first, so the page before before_id ends right above it.
+		end := indexOfBatch(jobs, beforeID)
+		if end < 0 {
+			end = 0
+		}
+		start := max(0, end-limit)
+		page, hasMore = jobs[start:end], start > 0
+	} else {
+		start := 0
+		if afterID := c.Query("after_id"); afterID != "" {
+			start = len(jobs)
+			if index := indexOfBatch(jobs, afterID); index >= 0 {
+				start = index + 1
+			}
+		}
+		end := min(len(jobs), start+limit)
+		page, hasMore = jobs[start:end], end < len(jobs)
+	}
+
+	out := []byte(`{"data":[],"has_more":false,"first_id":null,"last_id":null}`)
+	for _, job := range page {
+		out, _ = sjson.SetRawBytes(out, "data.-1", messageBatchObject(c, job))
+	}
+	out, _ = sjson.SetBytes(out, "has_more", hasMore)
+	if len(page) > 0 {
+		out, _ = sjson.SetBytes(out, "first_id", page[0].ID)
+		out, _ = sjson.SetBytes(out, "last_id", page[len(page)-1].ID)
+	}
+	c.Data(http.StatusOK, "application/json", out)
+}
+
+// GetMessageBatch handles GET /v1/messages/batches/{id}.
+func (h *ClaudeCodeAPIHandler) GetMessageBatch(c *gin.Context) {
+	job, ok := h.loadMessageBatch(c)
+	if !ok {
+		return
+	}
+	c.Data(http.StatusOK, "application/json", messageBatchObject(c, job))
+}
+
+// CancelMessageBatch handles POST /v1/messages/batches/{id}/cancel.
+func (h *ClaudeCodeAPIHandler) CancelMessageBatch(c *gin.Context) {
+	job, ok := h.loadMessageBatch(c)
+	if !ok {
+		return
+	}
+	job, err := h.batches.Cancel(job.ID)
+	if err != nil {
+		writeClaudeAPIError(c, http.StatusNotFound, "not_found_error", fmt.Sprintf("Message batch %s not found", c.Param("id")))
+		return
+	}
+	c.Data(http.StatusOK, "application/json", messageBatchObject(c, job))
+}
+
+// MessageBatchResults handles GET /v1/messages/batches/{id}/results. It streams one JSON line per
+// request once the batch has ended.
+func (h *ClaudeCodeAPIHandler) MessageBatchResults(c *gin.Context) {
+	job, ok := h.loadMessageBatch(c)
+	if !ok {
+		return
+	}
+	if job.Status != batch.StatusEnded {
+		writeClaudeAPIError(c, http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("Message batch %s has not finished processing; results are available once processing_status is \"ended\"", job.ID))
+		return
+	}
+
+	c.Header("Content-Type", "application/x-jsonl")
+	c.Status(http.StatusOK)
+	w := bufio.NewWriter(c.Writer)
+	errResults := h.batches.Results(job.ID, func(result batch.Result) error {
+		if _, err := w.Write(messageBatchResultLine(result)); err != nil {
+			return err
+		}
+		return w.WriteByte('\n')
+	})
+	if errResults != nil {
+		log.Errorf("message batch %s: write results: %v", job.ID, errResults)
+	}
+	_ = w.Flush()
+}
+
+// messageBatchResultLine renders a result in Anthropic's results format.
+func messageBatchResultLine(result batch.Result) []byte {
+	line := []byte(`{"custom_id":"","result":{"type":""}}`)
+	line, _ = sjson.SetBytes(line, "custom_id", result.CustomID)
+	line, _ = sjson.SetBytes(line, "result.type", result.Type)
+	switch result.Type {
+	case batch.ResultSucceeded:
+		if gjson.ValidBytes(result.Body) {
+			line, _ = sjson.SetRawBytes(line, "result.message", result.Body)
+		}
+	case batch.ResultErrored:
+		errType, message := claudeErrorFromResult(result)
+		body := []byte(`{"type":"error","error":{"type":"","message":""}}`)
+		body, _ = sjson.SetBytes(body, "error.type", errType)
+		body, _ = sjson.SetBytes(body, "error.message", message)
+		line, _ = sjson.SetRawBytes(line, "result.error", body)
+	}
+	return line
+}
+
+// claudeErrorFromResult returns the Anthropic error type and message of an errored result,
+// preferring the details of an upstream Anthropic error body.
+func claudeErrorFromResult(result batch.Result) (string, string) {
+	if parsed := gjson.Parse(result.Error); parsed.IsObject() {
+		if errType, message := parsed.Get("error.type").String(), parsed.Get("error.message").String(); errType != "" && message != "" {
+			return errType, message
+		}
+	}
+	message := result.Error
+	if message == "" {
+		message = http.StatusText(result.StatusCode)
+	}
+	return claudeErrorType(result.StatusCode), message
+}
+
+// claudeErrorType maps an HTTP status to the matching Anthropic error type.
+func claudeErrorType(status int) string {
+	switch status {
+	case http.StatusBadRequest, http.StatusRequestEntityTooLarge:
+		return "invalid_request_error"
+	case http.StatusUnauthorized:
+		return "authentication_error"
+	case http.StatusForbidden:
+		return "permission_error"
+	case http.StatusNotFound:
+		return "not_found_error"
+	case http.StatusTooManyRequests:
+		return "rate_limit_error"
+	case 529:
+		return "overloaded_error"
+	default:
+		return "api_error"
+	}
+}
+
+// messageBatchObject renders job as an Anthropic message batch.
+func messageBatchObject(c *gin.Context, job batch.Job) []byte {
+	out := []byte(`{"id":"","type":"message_batch","processing_status":"","request_counts":{},"ended_at":null,"created_at":"","expires_at":"","archived_at":null,"cancel_initiated_at":null,"results_url":null}`)
+	out, _ = sjson.SetBytes(out, "id", job.ID)
+	out, _ = sjson.SetBytes(out, "processing_status", job.Status)
+	out, _ = sjson.SetBytes(out, "request_counts", job.Counts)
+	out, _ = sjson.SetBytes(out, "created_at", job.CreatedAt.Format(time.RFC3339Nano))
+	out, _ = sjson.SetBytes(out, "expires_at", job.ExpiresAt.Format(time.RFC3339Nano))
+	if !job.CancelInitiatedAt.IsZero() {
+		out, _ = sjson.SetBytes(out, "cancel_initiated_at", job.CancelInitiatedAt.Format(time.RFC3339Nano))
+	}
+	if !job.EndedAt.IsZero() {
+		out, _ = sjson.SetBytes(out, "ended_at", job.EndedAt.Format(time.RFC3339Nano))
+	}
+	if job.Status == batch.StatusEnded {
+		out, _ = sjson.SetBytes(out, "results_url", requestBaseURL(c)+"/v1/messages/batches/"+job.ID+"/results")
+	}
+	return out
+}
+
+// requestBaseURL returns the scheme and host the client used to reach the proxy.
+func requestBaseURL(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	if forwarded := c.GetHeader("X-Forwarded-Proto"); forwarded == "http" || forwarded == "https" {
+		scheme = forwarded
+	}
+	host := c.Request.Host
+	if forwardedHost := c.GetHeader("X-Forwarded-Host"); forwardedHost != "" {
+		host = forwardedHost
+	}
+	return scheme + "://" + host
+}
+
+func indexOfBatch(jobs []batch.Job, id string) int {
+	for i, job := range jobs {
+		if job.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (h *ClaudeCodeAPIHandler) loadMessageBatch(c *gin.Context) (batch.Job, bool) {
+	if !h.requireBatches(c) {
+		return batch.Job{}, false
+	}
+	id := c.Param("id")
+	job, err := h.batches.Get(id)
+	owner := handlers.RequestOwner(c)
+	if err != nil || job.Kind != messageBatchKind || job.Owner != owner {
+		writeClaudeAPIError(c, http.StatusNotFound, "not_found_error", fmt.Sprintf("Message batch %s not found", id))
+		return batch.Job{}, false
+	}
+	return job, true
+}
+
+func (h *ClaudeCodeAPIHandler) requireBatches(c *gin.Context) bool {
+	if h.batches != nil {
+		return true
+	}
+	writeClaudeAPIError(c, http.StatusNotFound, "not_found_error", "Message batches are disabled; enable batches in the configuration.")
+	return false
+}
+
+func writeClaudeAPIError(c *gin.Context, status int, errType, message string) {
+	c.JSON(status, claudeErrorResponse{
+		Type:  "error",
+		Error: claudeErrorDetail{Type: errType, Message: message},
+	})
+}
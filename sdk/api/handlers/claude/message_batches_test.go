@@ -0,0 +1,141 @@
+package claude
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/batch"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/api/handlers"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	"github.com/tidwall/gjson"
+)
+
+type batchMessagesExecutor struct{}
+
+func (e *batchMessagesExecutor) Identifier() string { return "test-batch-provider" }
+
+func (e *batchMessagesExecutor) Execute(ctx context.Context, auth *coreauth.Auth, req coreexecutor.Request, opts coreexecutor.Options) (coreexecutor.Response, error) {
+	if background, _ := opts.Metadata[coreexecutor.BackgroundMetadataKey].(bool); !background {
+		return coreexecutor.Response{}, errors.New("batch item not marked as background work")
+	}
+	if strings.Contains(string(req.Payload), "fail") {
+		return coreexecutor.Response{}, &coreauth.Error{Message: "bad prompt", HTTPStatus: http.StatusBadRequest}
+	}
+	return coreexecutor.Response{Payload: []byte(`{"id":"msg_1","type":"message","role":"assistant","content":[{"type":"text","text":"hi"}]}`)}, nil
+}
+
+func (e *batchMessagesExecutor) ExecuteStream(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (*coreexecutor.StreamResult, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (e *batchMessagesExecutor) Refresh(ctx context.Context, auth *coreauth.Auth) (*coreauth.Auth, error) {
+	return auth, nil
+}
+
+func (e *batchMessagesExecutor) CountTokens(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (coreexecutor.Response, error) {
+	return coreexecutor.Response{}, errors.New("not implemented")
+}
+
+func (e *batchMessagesExecutor) HttpRequest(context.Context, *coreauth.Auth, *http.Request) (*http.Response, error) {
+	return nil, errors.New("not implemented")
+}
+
+func TestMessageBatchLifecycle(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	executor := &batchMessagesExecutor{}
+	manager := coreauth.NewManager(nil, nil, nil)
+	manager.RegisterExecutor(executor)
+	auth := &coreauth.Auth{ID: "batch-auth", Provider: executor.Identifier(), Status: coreauth.StatusActive}
+	if _, err := manager.Register(context.Background(), auth); err != nil {
+		t.Fatalf("Register auth: %v", err)
+	}
+	registry.GetGlobalRegistry().RegisterClient(auth.ID, auth.Provider, []*registry.ModelInfo{{ID: "batch-model"}})
+	t.Cleanup(func() {
+		registry.GetGlobalRegistry().UnregisterClient(auth.ID)
+	})
+
+	batches, err := batch.New(config.BatchConfig{}, t.TempDir())
+	if err != nil {
+		t.Fatalf("batch.New: %v", err)
+	}
+	h := NewClaudeCodeAPIHandler(handlers.NewBaseAPIHandlers(&sdkconfig.SDKConfig{}, manager))
+	h.SetBatchManager(batches)
+	batches.Start(context.Background())
+	t.Cleanup(batches.Stop)
+
+	router := gin.New()
+	router.POST("/v1/messages/batches", h.CreateMessageBatch)
+	router.GET("/v1/messages/batches", h.ListMessageBatches)
+	router.GET("/v1/messages/batches/:id", h.GetMessageBatch)
+	router.GET("/v1/messages/batches/:id/results", h.MessageBatchResults)
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	if resp := serve(http.MethodPost, "/v1/messages/batches", `{"requests":[{"custom_id":"a","params":{"model":"batch-model","stream":true}}]}`); resp.Code != http.StatusBadRequest {
+		t.Fatalf("streaming item status = %d, want 400", resp.Code)
+	}
+
+	created := serve(http.MethodPost, "/v1/messages/batches", `{"requests":[
+		{"custom_id":"ok","params":{"model":"batch-model","max_tokens":10,"messages":[{"role":"user","content":"hello"}]}},
+		{"custom_id":"broken","params":{"model":"batch-model","max_tokens":10,"messages":[{"role":"user","content":"fail"}]}}
+	]}`)
+	if created.Code != http.StatusOK {
+		t.Fatalf("create status = %d, body = %s", created.Code, created.Body.String())
+	}
+	id := gjson.Get(created.Body.String(), "id").String()
+	if !strings.HasPrefix(id, "msgbatch_") || gjson.Get(created.Body.String(), "type").String() != "message_batch" {
+		t.Fatalf("create body = %s", created.Body.String())
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	var status string
+	for time.Now().Before(deadline) {
+		got := serve(http.MethodGet, "/v1/messages/batches/"+id, "")
+		status = gjson.Get(got.Body.String(), "processing_status").String()
+		if status == "ended" {
+			if url := gjson.Get(got.Body.String(), "results_url").String(); !strings.HasSuffix(url, "/v1/messages/batches/"+id+"/results") {
+				t.Fatalf("results_url = %q", url)
+			}
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if status != "ended" {
+		t.Fatalf("processing_status = %q, want ended", status)
+	}
+
+	results := serve(http.MethodGet, "/v1/messages/batches/"+id+"/results", "")
+	if results.Code != http.StatusOK {
+		t.Fatalf("results status = %d", results.Code)
+	}
+	byID := make(map[string]gjson.Result)
+	for _, line := range strings.Split(strings.TrimSpace(results.Body.String()), "\n") {
+		parsed := gjson.Parse(line)
+		byID[parsed.Get("custom_id").String()] = parsed.Get("result")
+	}
+	if byID["ok"].Get("type").String() != "succeeded" || byID["ok"].Get("message.id").String() != "msg_1" {
+		t.Fatalf("ok result = %s", byID["ok"].Raw)
+	}
+	if byID["broken"].Get("type").String() != "errored" || byID["broken"].Get("error.error.type").String() != "invalid_request_error" {
+		t.Fatalf("broken result = %s", byID["broken"].Raw)
+	}
+
+	list := serve(http.MethodGet, "/v1/messages/batches?limit=1", "")
+	if gjson.Get(list.Body.String(), "first_id").String() != id {
+		t.Fatalf("list = %s", list.Body.String())
+	}
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+)
+
+func TestBatchIdentity_ResolvesOwnerFromConfig(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &sdkconfig.SDKConfig{
+		APIKeys:    []string{"plain-key"},
+		ClientKeys: []sdkconfig.ClientKey{{Key: "managed-key", Name: "ci"}},
+	}
+	h := NewBaseAPIHandlers(cfg, nil)
+
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Set("apiKey", "managed-key")
+	owner := RequestOwner(ctx)
+	if owner == "" || owner == "managed-key" {
+		t.Fatalf("RequestOwner() = %q, want the digest of the key", owner)
+	}
+	if principal := h.ExternalPrincipal(ctx); principal != "" {
+		t.Fatalf("ExternalPrincipal() = %q for a configured key, want empty", principal)
+	}
+	if key, err := h.BatchIdentity(owner, ""); err != nil || key != "managed-key" {
+		t.Fatalf("BatchIdentity() = %q, %v; want the managed key", key, err)
+	}
+	if key, err := h.BatchIdentity(sdkconfig.ClientKeyDigest("plain-key"), ""); err != nil || key != "plain-key" {
+		t.Fatalf("BatchIdentity() = %q, %v; want the api-keys entry", key, err)
+	}
+
+	ctx.Set("apiKey", "oidc:alice")
+	if principal := h.ExternalPrincipal(ctx); principal != "oidc:alice" {
+		t.Fatalf("ExternalPrincipal() = %q, want the external principal", principal)
+	}
+	if key, err := h.BatchIdentity(RequestOwner(ctx), "oidc:alice"); err != nil || key != "oidc:alice" {
+		t.Fatalf("BatchIdentity() = %q, %v; want the external principal", key, err)
+	}
+
+	cfg.ClientKeys[0].Disabled = true
+	if _, err := h.BatchIdentity(owner, ""); !errors.Is(err, ErrOwnerKeyRevoked) {
+		t.Fatalf("BatchIdentity() for a disabled key error = %v, want ErrOwnerKeyRevoked", err)
+	}
+	cfg.ClientKeys = nil
+	if _, err := h.BatchIdentity(owner, ""); !errors.Is(err, ErrOwnerKeyRevoked) {
+		t.Fatalf("BatchIdentity() for a removed key error = %v, want ErrOwnerKeyRevoked", err)
+	}
+}
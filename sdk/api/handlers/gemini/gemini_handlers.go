@@ -160,6 +160,8 @@ func (h *GeminiAPIHandler) GeminiHandler(c *gin.Context) {
 		h.handleStreamGenerateContent(c, action[0], rawJSON)
 	case "countTokens":
 		h.handleCountTokens(c, action[0], rawJSON)
+	case "embedContent", "batchEmbedContents":
+		h.handleEmbedContent(c, action[0], method, rawJSON)
 	}
 }
 
@@ -210,7 +212,7 @@ func (h *GeminiAPIHandler) handleStreamGenerateContent(c *gin.Context, modelName
 				continue
 			}
 			// Upstream failed immediately. Return proper error status and JSON.
-			h.WriteErrorResponse(c, errMsg)
+			h.WriteStreamErrorResponse(c, errMsg, func(errMsg *interfaces.ErrorMessage) { writeGeminiStreamError(c, alt, errMsg) })
 			if errMsg != nil {
 				cliCancel(errMsg.Error)
 			} else {
@@ -275,6 +277,27 @@ func (h *GeminiAPIHandler) handleCountTokens(c *gin.Context, modelName string, r
 	cliCancel()
 }
 
+// handleEmbedContent handles embedContent and batchEmbedContents requests for Gemini models.
+//
+// Parameters:
+//   - c: The Gin context for the request
+//   - modelName: The name of the embedding model
+//   - method: The Gemini method, "embedContent" or "batchEmbedContents"
+//   - rawJSON: The raw JSON request body containing the content to embed
+func (h *GeminiAPIHandler) handleEmbedContent(c *gin.Context, modelName, method string, rawJSON []byte) {
+	c.Header("Content-Type", "application/json")
+	cliCtx, cliCancel := h.GetContextWithCancel(h, c, context.Background())
+	resp, upstreamHeaders, errMsg := h.ExecuteEmbedWithAuthManager(cliCtx, h.HandlerType(), modelName, rawJSON, method)
+	if errMsg != nil {
+		h.WriteErrorResponse(c, errMsg)
+		cliCancel(errMsg.Error)
+		return
+	}
+	handlers.WriteUpstreamHeaders(c.Writer.Header(), upstreamHeaders)
+	_, _ = c.Writer.Write(resp)
+	cliCancel()
+}
+
 // handleGenerateContent handles non-streaming content generation requests for Gemini models.
 // This function processes the request synchronously and returns the complete generated
 // response in a single API call. It supports various generation parameters and
@@ -319,23 +342,28 @@ func (h *GeminiAPIHandler) forwardGeminiStream(c *gin.Context, flusher http.Flus
 			}
 		},
 		WriteTerminalError: func(errMsg *interfaces.ErrorMessage) {
-			if errMsg == nil {
-				return
-			}
-			status := http.StatusInternalServerError
-			if errMsg.StatusCode > 0 {
-				status = errMsg.StatusCode
-			}
-			errText := http.StatusText(status)
-			if errMsg.Error != nil && errMsg.Error.Error() != "" {
-				errText = errMsg.Error.Error()
-			}
-			body := handlers.BuildErrorResponseBody(status, errText)
-			if alt == "" {
-				_, _ = fmt.Fprintf(c.Writer, "event: error\ndata: %s\n\n", string(body))
-			} else {
-				_, _ = c.Writer.Write(body)
-			}
+			writeGeminiStreamError(c, alt, errMsg)
 		},
 	})
 }
+
+// writeGeminiStreamError writes errMsg as an error event, or as a bare JSON body for alt formats.
+func writeGeminiStreamError(c *gin.Context, alt string, errMsg *interfaces.ErrorMessage) {
+	if errMsg == nil {
+		return
+	}
+	status := http.StatusInternalServerError
+	if errMsg.StatusCode > 0 {
+		status = errMsg.StatusCode
+	}
+	errText := http.StatusText(status)
+	if errMsg.Error != nil && errMsg.Error.Error() != "" {
+		errText = errMsg.Error.Error()
+	}
+	body := handlers.BuildErrorResponseBody(status, errText)
+	if alt == "" {
+		_, _ = fmt.Fprintf(c.Writer, "event: error\ndata: %s\n\n", string(body))
+	} else {
+		_, _ = c.Writer.Write(body)
+	}
+}
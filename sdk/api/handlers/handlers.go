@@ -6,6 +6,7 @@ package handlers
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
@@ -19,6 +20,7 @@ import (
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/thinking"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	sdkaccess "github.com/router-for-me/CLIProxyAPI/v6/sdk/access"
 	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
 	coreexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
 	"github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
@@ -57,6 +59,15 @@ const (
 type pinnedAuthContextKey struct{}
 type selectedAuthCallbackContextKey struct{}
 type executionSessionContextKey struct{}
+type clientKeyContextKey struct{}
+type backgroundContextKey struct{}
+type errorResponseCallbackContextKey struct{}
+
+// clientKeyIdentity is the downstream client key a request runs on behalf of.
+type clientKeyIdentity struct {
+	key  string
+	name string
+}
 
 // WithPinnedAuthID returns a child context that requests execution on a specific auth ID.
 func WithPinnedAuthID(ctx context.Context, authID string) context.Context {
@@ -93,6 +104,41 @@ func WithExecutionSessionID(ctx context.Context, sessionID string) context.Conte
 	return context.WithValue(ctx, executionSessionContextKey{}, sessionID)
 }
 
+// WithClientKey returns a child context that runs requests on behalf of a downstream client
+// key, for work that executes outside the client's HTTP request such as batch items.
+func WithClientKey(ctx context.Context, key, name string) context.Context {
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return ctx
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, clientKeyContextKey{}, clientKeyIdentity{key: key, name: strings.TrimSpace(name)})
+}
+
+// WithBackgroundPriority returns a child context whose requests run at low priority: they fail
+// instead of waiting in the request queue when no credential is ready.
+func WithBackgroundPriority(ctx context.Context) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, backgroundContextKey{}, true)
+}
+
+// WithErrorResponseCallback returns a child context whose HTTP request reports the error written
+// by WriteErrorResponse to callback, for callers that run requests through the handlers and need
+// the underlying error rather than the rendered response.
+func WithErrorResponseCallback(ctx context.Context, callback func(*interfaces.ErrorMessage)) context.Context {
+	if callback == nil {
+		return ctx
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, errorResponseCallbackContextKey{}, callback)
+}
+
 // BuildErrorResponseBody builds an OpenAI-compatible JSON error response body.
 // If errText is already valid JSON, it is returned as-is to preserve upstream error payloads.
 func BuildErrorResponseBody(status int, errText string) []byte {
@@ -187,13 +233,88 @@ func PassthroughHeadersEnabled(cfg *config.SDKConfig) bool {
 	return cfg != nil && cfg.PassthroughHeaders
 }
 
+// RequestClientKey returns the client API key that authenticated c and the name of its managed
+// client key entry, if any.
+func RequestClientKey(c *gin.Context) (string, string) {
+	if c == nil {
+		return "", ""
+	}
+	clientKey := ""
+	clientKeyName := ""
+	if apiKey, exists := c.Get("apiKey"); exists {
+		clientKey, _ = apiKey.(string)
+	}
+	if accessMetadata, exists := c.Get("accessMetadata"); exists {
+		if values, ok := accessMetadata.(map[string]string); ok {
+			clientKeyName = values[sdkaccess.MetadataClientKeyName]
+		}
+	}
+	return clientKey, clientKeyName
+}
+
+// RequestOwner returns the digest of the client key that authenticated c. Batches, files and
+// stored responses are recorded under it so that the key itself is never persisted.
+func RequestOwner(c *gin.Context) string {
+	key, _ := RequestClientKey(c)
+	return config.ClientKeyDigest(key)
+}
+
+// ErrOwnerKeyRevoked is returned by BatchIdentity when the client key that created a batch has
+// been removed from the configuration, disabled or has expired.
+var ErrOwnerKeyRevoked = errors.New("the client key that created this batch is no longer valid")
+
+// ExternalPrincipal returns the principal that authenticated c when it is not a configured API
+// key, such as an OIDC subject or a client certificate identity. Such principals are not secrets,
+// so batches keep them to run their items as the same principal.
+func (h *BaseAPIHandler) ExternalPrincipal(c *gin.Context) string {
+	key, _ := RequestClientKey(c)
+	if key == "" {
+		return ""
+	}
+	if _, configured := h.Cfg.FindKeyByDigest(config.ClientKeyDigest(key)); configured {
+		return ""
+	}
+	return key
+}
+
+// BatchIdentity returns the principal the items of a batch run as. owner is the digest recorded
+// with the batch and principal the external principal, if any. Configured keys are looked up
+// again by digest in the current configuration, so a key that was removed, disabled or expired
+// since the batch was created stops its remaining items with ErrOwnerKeyRevoked.
+func (h *BaseAPIHandler) BatchIdentity(owner, principal string) (string, error) {
+	switch {
+	case owner == "":
+		return "", nil
+	case principal != "":
+		return principal, nil
+	}
+	key, ok := h.Cfg.FindKeyByDigest(owner)
+	if !ok {
+		return "", ErrOwnerKeyRevoked
+	}
+	if clientKey := h.Cfg.FindClientKey(key); clientKey != nil && (clientKey.Disabled || clientKey.Expired(time.Now())) {
+		return "", ErrOwnerKeyRevoked
+	}
+	return key, nil
+}
+
 func requestExecutionMetadata(ctx context.Context) map[string]any {
 	// Idempotency-Key is an optional client-supplied header used to correlate retries.
 	// It is forwarded as execution metadata; when absent we generate a UUID.
 	key := ""
+	sessionKey := ""
+	clientKey := ""
+	clientKeyName := ""
 	if ctx != nil {
 		if ginCtx, ok := ctx.Value("gin").(*gin.Context); ok && ginCtx != nil && ginCtx.Request != nil {
 			key = strings.TrimSpace(ginCtx.GetHeader("Idempotency-Key"))
+			sessionKey = strings.TrimSpace(ginCtx.GetHeader(coreauth.SessionAffinityHeader))
+			clientKey, clientKeyName = RequestClientKey(ginCtx)
+		}
+		if clientKey == "" {
+			if identity, ok := ctx.Value(clientKeyContextKey{}).(clientKeyIdentity); ok {
+				clientKey, clientKeyName = identity.key, identity.name
+			}
 		}
 	}
 	if key == "" {
@@ -201,6 +322,15 @@ func requestExecutionMetadata(ctx context.Context) map[string]any {
 	}
 
 	meta := map[string]any{idempotencyKeyMetadataKey: key}
+	if sessionKey != "" {
+		meta[coreexecutor.SessionKeyMetadataKey] = sessionKey
+	}
+	if clientKey != "" {
+		meta[coreexecutor.ClientKeyMetadataKey] = clientKey
+	}
+	if clientKeyName != "" {
+		meta[coreexecutor.ClientKeyNameMetadataKey] = clientKeyName
+	}
 	if pinnedAuthID := pinnedAuthIDFromContext(ctx); pinnedAuthID != "" {
 		meta[coreexecutor.PinnedAuthMetadataKey] = pinnedAuthID
 	}
@@ -210,9 +340,28 @@ func requestExecutionMetadata(ctx context.Context) map[string]any {
 	if executionSessionID := executionSessionIDFromContext(ctx); executionSessionID != "" {
 		meta[coreexecutor.ExecutionSessionMetadataKey] = executionSessionID
 	}
+	if isBackgroundContext(ctx) {
+		meta[coreexecutor.BackgroundMetadataKey] = true
+	}
 	return meta
 }
 
+// isBackgroundContext reports whether ctx, or the request of the gin context it carries, was
+// marked by WithBackgroundPriority.
+func isBackgroundContext(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	if background, _ := ctx.Value(backgroundContextKey{}).(bool); background {
+		return true
+	}
+	if ginCtx, ok := ctx.Value("gin").(*gin.Context); ok && ginCtx != nil && ginCtx.Request != nil {
+		background, _ := ginCtx.Request.Context().Value(backgroundContextKey{}).(bool)
+		return background
+	}
+	return false
+}
+
 func pinnedAuthIDFromContext(ctx context.Context) string {
 	if ctx == nil {
 		return ""
@@ -430,6 +579,11 @@ func (h *BaseAPIHandler) StartNonStreamingKeepAlive(c *gin.Context, ctx context.
 	if interval <= 0 {
 		return func() {}
 	}
+	// Background requests have no client connection to keep alive, and an early newline would
+	// commit a success status before the outcome is known.
+	if c.Request != nil && isBackgroundContext(c.Request.Context()) {
+		return func() {}
+	}
 	flusher, ok := c.Writer.(http.Flusher)
 	if !ok {
 		return func() {}
@@ -467,6 +621,99 @@ func (h *BaseAPIHandler) StartNonStreamingKeepAlive(c *gin.Context, ctx context.
 	}
 }
 
+// queuedKeepAliveStartedKey marks a gin context whose SSE response was committed by queued
+// keep-alives before the stream produced any data.
+const queuedKeepAliveStartedKey = "QUEUED_KEEP_ALIVE_STARTED"
+
+// QueuedKeepAliveStarted reports whether keep-alives sent while the request waited in the
+// credential queue already committed a 200 event-stream response for c.
+func QueuedKeepAliveStarted(c *gin.Context) bool {
+	if c == nil {
+		return false
+	}
+	return c.GetBool(queuedKeepAliveStartedKey)
+}
+
+// WriteStreamErrorResponse reports an error that ended a streaming request before any data was
+// sent. Normally that is a regular JSON error response; once queued keep-alives committed the
+// event stream, the status can no longer change, so writeEvent sends the error as a terminal SSE
+// event in the handler's format instead.
+func (h *BaseAPIHandler) WriteStreamErrorResponse(c *gin.Context, msg *interfaces.ErrorMessage, writeEvent func(*interfaces.ErrorMessage)) {
+	if writeEvent == nil || !QueuedKeepAliveStarted(c) {
+		h.WriteErrorResponse(c, msg)
+		return
+	}
+	writeEvent(msg)
+	if flusher, ok := c.Writer.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// queuedStreamKeepAlive returns a callback for the credential wait queue. Once invoked it commits
+// SSE headers and emits keep-alive comments at the streaming keep-alive interval until stop is
+// called, marking the context for QueuedKeepAliveStarted. The callback is nil when keep-alives are
+// disabled or the response is not SSE.
+func (h *BaseAPIHandler) queuedStreamKeepAlive(ctx context.Context, alt string) (func(), func()) {
+	noop := func() {}
+	if h == nil || ctx == nil || alt != "" {
+		return nil, noop
+	}
+	interval := StreamingKeepAliveInterval(h.Cfg)
+	if interval <= 0 {
+		return nil, noop
+	}
+	c, ok := ctx.Value("gin").(*gin.Context)
+	if !ok || c == nil {
+		return nil, noop
+	}
+	// Handlers streaming another framing, such as Ollama's NDJSON, set their content type upfront.
+	if contentType := c.Writer.Header().Get("Content-Type"); contentType != "" && !strings.HasPrefix(contentType, "text/event-stream") {
+		return nil, noop
+	}
+	flusher, ok := c.Writer.(http.Flusher)
+	if !ok {
+		return nil, noop
+	}
+
+	stopChan := make(chan struct{})
+	var startOnce, stopOnce sync.Once
+	var wg sync.WaitGroup
+	start := func() {
+		startOnce.Do(func() {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				ticker := time.NewTicker(interval)
+				defer ticker.Stop()
+				for {
+					select {
+					case <-stopChan:
+						return
+					case <-ctx.Done():
+						return
+					case <-ticker.C:
+						if !c.Writer.Written() {
+							c.Header("Content-Type", "text/event-stream")
+							c.Header("Cache-Control", "no-cache")
+							c.Header("Connection", "keep-alive")
+							c.Set(queuedKeepAliveStartedKey, true)
+						}
+						_, _ = c.Writer.Write([]byte(": keep-alive\n\n"))
+						flusher.Flush()
+					}
+				}
+			}()
+		})
+	}
+	stop := func() {
+		stopOnce.Do(func() {
+			close(stopChan)
+		})
+		wg.Wait()
+	}
+	return start, stop
+}
+
 // appendAPIResponse preserves any previously captured API response and appends new data.
 func appendAPIResponse(c *gin.Context, data []byte) {
 	if c == nil || len(data) == 0 {
@@ -498,6 +745,9 @@ func appendAPIResponse(c *gin.Context, data []byte) {
 // This path is the only supported execution route.
 func (h *BaseAPIHandler) ExecuteWithAuthManager(ctx context.Context, handlerType, modelName string, rawJSON []byte, alt string) ([]byte, http.Header, *interfaces.ErrorMessage) {
 	providers, normalizedModel, errMsg := h.getRequestDetails(modelName)
+	if errMsg == nil {
+		errMsg = rejectEmbeddingModel(normalizedModel)
+	}
 	if errMsg != nil {
 		return nil, nil, errMsg
 	}
@@ -545,6 +795,9 @@ func (h *BaseAPIHandler) ExecuteWithAuthManager(ctx context.Context, handlerType
 // This path is the only supported execution route.
 func (h *BaseAPIHandler) ExecuteCountWithAuthManager(ctx context.Context, handlerType, modelName string, rawJSON []byte, alt string) ([]byte, http.Header, *interfaces.ErrorMessage) {
 	providers, normalizedModel, errMsg := h.getRequestDetails(modelName)
+	if errMsg == nil {
+		errMsg = rejectEmbeddingModel(normalizedModel)
+	}
 	if errMsg != nil {
 		return nil, nil, errMsg
 	}
@@ -588,11 +841,58 @@ func (h *BaseAPIHandler) ExecuteCountWithAuthManager(ctx context.Context, handle
 	return resp.Payload, FilterUpstreamHeaders(resp.Headers), nil
 }
 
+// ExecuteEmbedWithAuthManager creates embeddings via the core auth manager. action carries the
+// Gemini method ("embedContent" or "batchEmbedContents") for Gemini-format requests.
+func (h *BaseAPIHandler) ExecuteEmbedWithAuthManager(ctx context.Context, handlerType, modelName string, rawJSON []byte, action string) ([]byte, http.Header, *interfaces.ErrorMessage) {
+	providers, normalizedModel, errMsg := h.getRequestDetails(modelName)
+	if errMsg != nil {
+		return nil, nil, errMsg
+	}
+	reqMeta := requestExecutionMetadata(ctx)
+	reqMeta[coreexecutor.RequestedModelMetadataKey] = normalizedModel
+	req := coreexecutor.Request{
+		Model:   normalizedModel,
+		Payload: cloneBytes(rawJSON),
+	}
+	if action != "" {
+		req.Metadata = map[string]any{"action": action}
+	}
+	opts := coreexecutor.Options{
+		Stream:          false,
+		OriginalRequest: rawJSON,
+		SourceFormat:    sdktranslator.FromString(handlerType),
+	}
+	opts.Metadata = reqMeta
+	resp, err := h.AuthManager.ExecuteEmbed(ctx, providers, req, opts)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if se, ok := err.(interface{ StatusCode() int }); ok && se != nil {
+			if code := se.StatusCode(); code > 0 {
+				status = code
+			}
+		}
+		var addon http.Header
+		if he, ok := err.(interface{ Headers() http.Header }); ok && he != nil {
+			if hdr := he.Headers(); hdr != nil {
+				addon = hdr.Clone()
+			}
+		}
+		return nil, nil, &interfaces.ErrorMessage{StatusCode: status, Error: err, Addon: addon}
+	}
+	if !PassthroughHeadersEnabled(h.Cfg) {
+		return resp.Payload, nil, nil
+	}
+	return resp.Payload, FilterUpstreamHeaders(resp.Headers), nil
+}
+
 // ExecuteStreamWithAuthManager executes a streaming request via the core auth manager.
 // This path is the only supported execution route.
 // The returned http.Header carries upstream response headers captured before streaming begins.
 func (h *BaseAPIHandler) ExecuteStreamWithAuthManager(ctx context.Context, handlerType, modelName string, rawJSON []byte, alt string) (<-chan []byte, http.Header, <-chan *interfaces.ErrorMessage) {
 	providers, normalizedModel, errMsg := h.getRequestDetails(modelName)
+	if errMsg == nil {
+		errMsg = rejectEmbeddingModel(normalizedModel)
+	}
 	if errMsg != nil {
 		errChan := make(chan *interfaces.ErrorMessage, 1)
 		errChan <- errMsg
@@ -616,8 +916,13 @@ func (h *BaseAPIHandler) ExecuteStreamWithAuthManager(ctx context.Context, handl
 		OriginalRequest: rawJSON,
 		SourceFormat:    sdktranslator.FromString(handlerType),
 	}
+	onQueued, stopQueuedKeepAlive := h.queuedStreamKeepAlive(ctx, alt)
+	if onQueued != nil {
+		reqMeta[coreexecutor.QueuedCallbackMetadataKey] = onQueued
+	}
 	opts.Metadata = reqMeta
 	streamResult, err := h.AuthManager.ExecuteStream(ctx, providers, req, opts)
+	stopQueuedKeepAlive()
 	if err != nil {
 		errChan := make(chan *interfaces.ErrorMessage, 1)
 		status := http.StatusInternalServerError
@@ -847,6 +1152,15 @@ func (h *BaseAPIHandler) getRequestDetails(modelName string) (providers []string
 	return providers, resolvedModelName, nil
 }
 
+// rejectEmbeddingModel refuses chat-style requests for models that only serve embeddings.
+func rejectEmbeddingModel(modelName string) *interfaces.ErrorMessage {
+	baseModel := thinking.ParseSuffix(modelName).ModelName
+	if info := registry.LookupModelInfo(baseModel); info != nil && info.IsEmbeddingOnly() {
+		return &interfaces.ErrorMessage{StatusCode: http.StatusBadRequest, Error: fmt.Errorf("model %s only supports embeddings", modelName)}
+	}
+	return nil
+}
+
 func restoreSuffix(normalizedBaseModel string, parsed thinking.SuffixResult) string {
 	if !parsed.HasSuffix {
 		return normalizedBaseModel
@@ -885,6 +1199,11 @@ func replaceHeader(dst http.Header, src http.Header) {
 
 // WriteErrorResponse writes an error message to the response writer using the HTTP status embedded in the message.
 func (h *BaseAPIHandler) WriteErrorResponse(c *gin.Context, msg *interfaces.ErrorMessage) {
+	if c.Request != nil {
+		if callback, ok := c.Request.Context().Value(errorResponseCallbackContextKey{}).(func(*interfaces.ErrorMessage)); ok {
+			callback(msg)
+		}
+	}
 	status := http.StatusInternalServerError
 	if msg != nil && msg.StatusCode > 0 {
 		status = msg.StatusCode
@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"context"
+	"net/http"
 	"reflect"
 	"testing"
 	"time"
@@ -116,3 +118,22 @@ func TestGetRequestDetails_PreservesSuffix(t *testing.T) {
 		})
 	}
 }
+
+func TestExecuteWithAuthManager_RejectsEmbeddingOnlyModels(t *testing.T) {
+	modelRegistry := registry.GetGlobalRegistry()
+	modelRegistry.RegisterClient("test-request-details-embedding", "gemini", []*registry.ModelInfo{
+		{ID: "test-embedding-only", SupportedGenerationMethods: []string{"embedContent", "batchEmbedContents"}},
+	})
+	t.Cleanup(func() {
+		modelRegistry.UnregisterClient("test-request-details-embedding")
+	})
+	handler := NewBaseAPIHandlers(&sdkconfig.SDKConfig{}, coreauth.NewManager(nil, nil, nil))
+
+	_, _, errMsg := handler.ExecuteWithAuthManager(context.Background(), "openai", "test-embedding-only", []byte(`{}`), "")
+	if errMsg == nil || errMsg.StatusCode != http.StatusBadRequest {
+		t.Fatalf("chat request error = %+v, want 400 for an embedding-only model", errMsg)
+	}
+	if _, _, errMsg = handler.ExecuteCountWithAuthManager(context.Background(), "gemini", "test-embedding-only", []byte(`{}`), ""); errMsg == nil || errMsg.StatusCode != http.StatusBadRequest {
+		t.Fatalf("count request error = %+v, want 400 for an embedding-only model", errMsg)
+	}
+}
@@ -0,0 +1,128 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/constant"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/api/handlers"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// Embed handles the /api/embed endpoint. The request is forwarded as an OpenAI embeddings
+// request, so any provider serving the model's embeddings can answer it.
+func (h *OllamaAPIHandler) Embed(c *gin.Context) {
+	rawJSON, ok := h.readEmbedRequest(c)
+	if !ok {
+		return
+	}
+	body := gjson.ParseBytes(rawJSON)
+	modelName := body.Get("model").String()
+	input := body.Get("input")
+	if !input.Exists() || (input.Type != gjson.String && !input.IsArray()) {
+		writeEmbedBadRequest(c, "input is required")
+		return
+	}
+
+	openaiRequest := []byte(`{}`)
+	openaiRequest, _ = sjson.SetBytes(openaiRequest, "model", modelName)
+	openaiRequest, _ = sjson.SetRawBytes(openaiRequest, "input", []byte(input.Raw))
+	if dimensions := body.Get("dimensions").Int(); dimensions > 0 {
+		openaiRequest, _ = sjson.SetBytes(openaiRequest, "dimensions", dimensions)
+	}
+
+	started := time.Now()
+	h.trackModel(modelName, body.Get("keep_alive"))
+	resp, ok := h.executeEmbed(c, modelName, openaiRequest)
+	if !ok {
+		return
+	}
+
+	embeddings := make([]json.RawMessage, 0)
+	for _, item := range gjson.GetBytes(resp, "data").Array() {
+		embeddings = append(embeddings, json.RawMessage(item.Get("embedding").Raw))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"model":             modelName,
+		"embeddings":        embeddings,
+		"total_duration":    time.Since(started).Nanoseconds(),
+		"load_duration":     0,
+		"prompt_eval_count": gjson.GetBytes(resp, "usage.prompt_tokens").Int(),
+	})
+}
+
+// Embeddings handles the legacy /api/embeddings endpoint, which embeds a single prompt.
+func (h *OllamaAPIHandler) Embeddings(c *gin.Context) {
+	rawJSON, ok := h.readEmbedRequest(c)
+	if !ok {
+		return
+	}
+	body := gjson.ParseBytes(rawJSON)
+	modelName := body.Get("model").String()
+	prompt := body.Get("prompt")
+	if prompt.Type != gjson.String {
+		writeEmbedBadRequest(c, "prompt is required")
+		return
+	}
+
+	openaiRequest := []byte(`{}`)
+	openaiRequest, _ = sjson.SetBytes(openaiRequest, "model", modelName)
+	openaiRequest, _ = sjson.SetBytes(openaiRequest, "input", prompt.String())
+
+	h.trackModel(modelName, body.Get("keep_alive"))
+	resp, ok := h.executeEmbed(c, modelName, openaiRequest)
+	if !ok {
+		return
+	}
+	embedding := gjson.GetBytes(resp, "data.0.embedding").Raw
+	if embedding == "" {
+		embedding = "[]"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"embedding": json.RawMessage(embedding),
+	})
+}
+
+// readEmbedRequest reads the request body and checks that it names a model.
+func (h *OllamaAPIHandler) readEmbedRequest(c *gin.Context) ([]byte, bool) {
+	c.Header("Access-Control-Allow-Origin", "*")
+	c.Header("Server", fmt.Sprintf("ollama/%s", OllamaVersion))
+
+	rawJSON, err := c.GetRawData()
+	if err != nil {
+		writeEmbedBadRequest(c, fmt.Sprintf("Invalid request: %v", err))
+		return nil, false
+	}
+	if gjson.GetBytes(rawJSON, "model").String() == "" {
+		writeEmbedBadRequest(c, "model is required")
+		return nil, false
+	}
+	return rawJSON, true
+}
+
+// executeEmbed runs an OpenAI embeddings request and writes the error response on failure.
+func (h *OllamaAPIHandler) executeEmbed(c *gin.Context, modelName string, openaiRequest []byte) ([]byte, bool) {
+	cliCtx, cliCancel := h.GetContextWithCancel(h, c, context.Background())
+	resp, _, errMsg := h.ExecuteEmbedWithAuthManager(cliCtx, constant.OpenAI, modelName, openaiRequest, "")
+	if errMsg != nil {
+		h.WriteErrorResponse(c, errMsg)
+		cliCancel(errMsg.Error)
+		return nil, false
+	}
+	cliCancel()
+	return resp, true
+}
+
+func writeEmbedBadRequest(c *gin.Context, message string) {
+	c.JSON(http.StatusBadRequest, handlers.ErrorResponse{
+		Error: handlers.ErrorDetail{
+			Message: message,
+			Type:    "invalid_request_error",
+		},
+	})
+}
@@ -1,6 +1,7 @@
 // Package ollama provides HTTP handlers for Ollama API endpoints.
 // This package implements the Ollama-compatible API interface, including model listing,
-// chat completion, and generate functionality. It supports both streaming and non-streaming responses.
+// chat completion, generate, embeddings and model management endpoints. It supports both
+// streaming and non-streaming responses.
 package ollama
 
 import (
@@ -27,12 +28,16 @@ const (
 // OllamaAPIHandler contains the handlers for Ollama API endpoints.
 type OllamaAPIHandler struct {
 	*handlers.BaseAPIHandler
+
+	// loaded tracks the models reported by /api/ps.
+	loaded *loadedModels
 }
 
 // NewOllamaAPIHandler creates a new Ollama API handlers instance.
 func NewOllamaAPIHandler(apiHandlers *handlers.BaseAPIHandler) *OllamaAPIHandler {
 	return &OllamaAPIHandler{
 		BaseAPIHandler: apiHandlers,
+		loaded:         newLoadedModels(),
 	}
 }
 
@@ -131,12 +136,14 @@ func (h *OllamaAPIHandler) Show(c *gin.Context) {
 		modelName = name
 	}
 
-	if modelName == "" {
-		modelName = "unknown"
+	resolved, info := lookupModel(modelName)
+	if info == nil {
+		writeModelNotFound(c, modelName)
+		return
 	}
 
 	// Generate Ollama show response
-	showResponse := from_ir.ToOllamaShowResponse(modelName)
+	showResponse := from_ir.ToOllamaShowResponse(resolved)
 	c.Data(http.StatusOK, "application/json", showResponse)
 }
 
@@ -197,6 +204,7 @@ func (h *OllamaAPIHandler) Chat(c *gin.Context) {
 		return
 	}
 
+	h.trackModel(modelName, ollamaRequest.Get("keep_alive"))
 	if stream {
 		h.handleOllamaChatStream(c, openaiRequest, modelName)
 	} else {
@@ -261,6 +269,7 @@ func (h *OllamaAPIHandler) Generate(c *gin.Context) {
 		return
 	}
 
+	h.trackModel(modelName, ollamaRequest.Get("keep_alive"))
 	if stream {
 		h.handleOllamaGenerateStream(c, openaiRequest, modelName)
 	} else {
@@ -0,0 +1,146 @@
+package ollama
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/api/handlers"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	"github.com/tidwall/gjson"
+)
+
+type embedCaptureExecutor struct {
+	payload []byte
+}
+
+func (e *embedCaptureExecutor) Identifier() string { return "ollama-test-provider" }
+
+func (e *embedCaptureExecutor) Execute(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (coreexecutor.Response, error) {
+	return coreexecutor.Response{}, errors.New("not implemented")
+}
+
+func (e *embedCaptureExecutor) ExecuteStream(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (*coreexecutor.StreamResult, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (e *embedCaptureExecutor) Refresh(ctx context.Context, auth *coreauth.Auth) (*coreauth.Auth, error) {
+	return auth, nil
+}
+
+func (e *embedCaptureExecutor) CountTokens(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (coreexecutor.Response, error) {
+	return coreexecutor.Response{}, errors.New("not implemented")
+}
+
+func (e *embedCaptureExecutor) HttpRequest(context.Context, *coreauth.Auth, *http.Request) (*http.Response, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (e *embedCaptureExecutor) Embed(_ context.Context, _ *coreauth.Auth, req coreexecutor.Request, _ coreexecutor.Options) (coreexecutor.Response, error) {
+	e.payload = req.Payload
+	return coreexecutor.Response{Payload: []byte(`{"object":"list","data":[{"index":0,"embedding":[0.1,0.2]},{"index":1,"embedding":[0.3,0.4]}],"usage":{"prompt_tokens":4,"total_tokens":4}}`)}, nil
+}
+
+func newOllamaTestRouter(t *testing.T, executor *embedCaptureExecutor, modelID string) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	manager := coreauth.NewManager(nil, nil, nil)
+	manager.RegisterExecutor(executor)
+	auth := &coreauth.Auth{ID: "ollama-auth-" + t.Name(), Provider: executor.Identifier(), Status: coreauth.StatusActive}
+	if _, err := manager.Register(context.Background(), auth); err != nil {
+		t.Fatalf("Register auth: %v", err)
+	}
+	registry.GetGlobalRegistry().RegisterClient(auth.ID, auth.Provider, []*registry.ModelInfo{{
+		ID:                  modelID,
+		Type:                "openai",
+		ContextLength:       8192,
+		SupportedParameters: []string{"tools"},
+	}})
+	t.Cleanup(func() {
+		registry.GetGlobalRegistry().UnregisterClient(auth.ID)
+	})
+
+	h := NewOllamaAPIHandler(handlers.NewBaseAPIHandlers(&sdkconfig.SDKConfig{}, manager))
+	router := gin.New()
+	router.POST("/api/embed", h.Embed)
+	router.GET("/api/ps", h.PS)
+	router.POST("/api/pull", h.Pull)
+	router.DELETE("/api/delete", h.Delete)
+	router.POST("/api/show", h.Show)
+	return router
+}
+
+func serve(router *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	return resp
+}
+
+func TestOllamaEmbedAndProcessList(t *testing.T) {
+	executor := &embedCaptureExecutor{}
+	router := newOllamaTestRouter(t, executor, "ollama-embed-model")
+
+	resp := serve(router, http.MethodPost, "/api/embed", `{"model":"ollama-embed-model","input":["a","b"],"keep_alive":"10m"}`)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("embed status = %d, body %s", resp.Code, resp.Body.String())
+	}
+	if got := gjson.GetBytes(executor.payload, "input.1").String(); got != "b" {
+		t.Fatalf("forwarded input = %s", executor.payload)
+	}
+	if got := gjson.Get(resp.Body.String(), "embeddings.1").Raw; got != "[0.3,0.4]" {
+		t.Fatalf("embeddings = %s", resp.Body.String())
+	}
+	if got := gjson.Get(resp.Body.String(), "prompt_eval_count").Int(); got != 4 {
+		t.Fatalf("prompt_eval_count = %d", got)
+	}
+
+	resp = serve(router, http.MethodGet, "/api/ps", "")
+	if got := gjson.Get(resp.Body.String(), "models.0.name").String(); got != "ollama-embed-model" {
+		t.Fatalf("ps = %s", resp.Body.String())
+	}
+	if got := gjson.Get(resp.Body.String(), "models.0.context_length").Int(); got != 8192 {
+		t.Fatalf("ps context_length = %d", got)
+	}
+
+	resp = serve(router, http.MethodDelete, "/api/delete", `{"model":"ollama-embed-model:latest"}`)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("delete status = %d", resp.Code)
+	}
+	resp = serve(router, http.MethodGet, "/api/ps", "")
+	if got := gjson.Get(resp.Body.String(), "models.#").Int(); got != 0 {
+		t.Fatalf("ps after delete = %s", resp.Body.String())
+	}
+}
+
+func TestOllamaPullAndShowUseRegistry(t *testing.T) {
+	router := newOllamaTestRouter(t, &embedCaptureExecutor{}, "ollama-chat-model")
+
+	resp := serve(router, http.MethodPost, "/api/pull", `{"model":"ollama-chat-model","stream":false}`)
+	if resp.Code != http.StatusOK || gjson.Get(resp.Body.String(), "status").String() != "success" {
+		t.Fatalf("pull = %d %s", resp.Code, resp.Body.String())
+	}
+	resp = serve(router, http.MethodPost, "/api/pull", `{"model":"missing-model"}`)
+	if resp.Code != http.StatusNotFound {
+		t.Fatalf("pull of unknown model status = %d", resp.Code)
+	}
+
+	resp = serve(router, http.MethodPost, "/api/show", `{"model":"ollama-chat-model"}`)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("show status = %d", resp.Code)
+	}
+	if got := gjson.Get(resp.Body.String(), "model_info.general\\.context_length").Int(); got != 8192 {
+		t.Fatalf("context length = %d, body %s", got, resp.Body.String())
+	}
+	if got := gjson.Get(resp.Body.String(), "capabilities").Raw; got != `["completion","tools"]` {
+		t.Fatalf("capabilities = %s", got)
+	}
+}
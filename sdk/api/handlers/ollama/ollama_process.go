@@ -0,0 +1,250 @@
+package ollama
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/api/handlers"
+	"github.com/tidwall/gjson"
+)
+
+// defaultKeepAlive mirrors Ollama's default time a model stays loaded after its last request.
+const defaultKeepAlive = 5 * time.Minute
+
+// loadedModels emulates Ollama's set of loaded models: a model counts as loaded from its first
+// request until its keep-alive lapses.
+type loadedModels struct {
+	mu        sync.Mutex
+	expiresAt map[string]time.Time
+}
+
+func newLoadedModels() *loadedModels {
+	return &loadedModels{expiresAt: make(map[string]time.Time)}
+}
+
+// touch marks model as used now. A zero keepAlive unloads the model, a negative one keeps it
+// loaded indefinitely.
+func (l *loadedModels) touch(model string, keepAlive time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	switch {
+	case keepAlive == 0:
+		delete(l.expiresAt, model)
+	case keepAlive < 0:
+		l.expiresAt[model] = time.Time{}
+	default:
+		l.expiresAt[model] = time.Now().Add(keepAlive)
+	}
+}
+
+func (l *loadedModels) remove(model string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.expiresAt, model)
+}
+
+// active returns the loaded models with their expiry, dropping lapsed entries.
+func (l *loadedModels) active(now time.Time) map[string]time.Time {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	out := make(map[string]time.Time, len(l.expiresAt))
+	for model, expiresAt := range l.expiresAt {
+		if !expiresAt.IsZero() && now.After(expiresAt) {
+			delete(l.expiresAt, model)
+			continue
+		}
+		out[model] = expiresAt
+	}
+	return out
+}
+
+// trackModel records a request for model in /api/ps using the request's keep_alive.
+func (h *OllamaAPIHandler) trackModel(model string, keepAlive gjson.Result) {
+	resolved, info := lookupModel(model)
+	if info == nil {
+		return
+	}
+	h.loaded.touch(resolved, parseKeepAlive(keepAlive))
+}
+
+// parseKeepAlive reads an Ollama keep_alive value, which is either a duration string such as
+// "10m" or a number of seconds.
+func parseKeepAlive(value gjson.Result) time.Duration {
+	switch value.Type {
+	case gjson.Number:
+		return time.Duration(value.Float() * float64(time.Second))
+	case gjson.String:
+		if d, err := time.ParseDuration(value.String()); err == nil {
+			return d
+		}
+	}
+	return defaultKeepAlive
+}
+
+// lookupModel resolves an Ollama model name in the registry, accepting the implicit ":latest" tag.
+func lookupModel(name string) (string, *registry.ModelInfo) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", nil
+	}
+	modelRegistry := registry.GetGlobalRegistry()
+	if info := modelRegistry.GetModelInfo(name, ""); info != nil {
+		return name, info
+	}
+	if trimmed, ok := strings.CutSuffix(name, ":latest"); ok {
+		if info := modelRegistry.GetModelInfo(trimmed, ""); info != nil {
+			return trimmed, info
+		}
+	}
+	return name, nil
+}
+
+func modelNameFromRequest(body gjson.Result) string {
+	if name := body.Get("model").String(); name != "" {
+		return name
+	}
+	return body.Get("name").String()
+}
+
+func modelDetails(info *registry.ModelInfo) map[string]interface{} {
+	family := "Ollama"
+	if info != nil && info.Type != "" {
+		family = info.Type
+	}
+	return map[string]interface{}{
+		"parent_model":       "",
+		"format":             "gguf",
+		"family":             family,
+		"families":           []string{family},
+		"parameter_size":     "0B",
+		"quantization_level": "Q4_0",
+	}
+}
+
+func contextLength(info *registry.ModelInfo) int {
+	if info == nil {
+		return 0
+	}
+	if info.ContextLength > 0 {
+		return info.ContextLength
+	}
+	return info.InputTokenLimit
+}
+
+func writeModelNotFound(c *gin.Context, name string) {
+	c.JSON(http.StatusNotFound, handlers.ErrorResponse{
+		Error: handlers.ErrorDetail{
+			Message: fmt.Sprintf("model '%s' not found", name),
+			Type:    "invalid_request_error",
+		},
+	})
+}
+
+// PS handles the /api/ps endpoint (running models). Models are reported as running while their
+// keep-alive from the last Ollama request lasts and a provider still serves them.
+func (h *OllamaAPIHandler) PS(c *gin.Context) {
+	c.Header("Content-Type", "application/json")
+	c.Header("Access-Control-Allow-Origin", "*")
+	c.Header("Server", fmt.Sprintf("ollama/%s", OllamaVersion))
+
+	active := h.loaded.active(time.Now())
+	names := make([]string, 0, len(active))
+	for name := range active {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	running := make([]map[string]interface{}, 0, len(names))
+	for _, name := range names {
+		_, info := lookupModel(name)
+		if info == nil {
+			continue
+		}
+		expiresAt := active[name]
+		if expiresAt.IsZero() {
+			// Ollama reports models kept alive indefinitely with a far-future expiry.
+			expiresAt = time.Date(2318, time.January, 1, 0, 0, 0, 0, time.UTC)
+		}
+		running = append(running, map[string]interface{}{
+			"name":           name,
+			"model":          name,
+			"size":           0,
+			"digest":         "",
+			"details":        modelDetails(info),
+			"expires_at":     expiresAt.UTC().Format(time.RFC3339Nano),
+			"size_vram":      0,
+			"context_length": contextLength(info),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"models": running,
+	})
+}
+
+// Pull handles the /api/pull endpoint. Models are served by upstream providers, so pulling only
+// checks that the model is available and reports success.
+func (h *OllamaAPIHandler) Pull(c *gin.Context) {
+	c.Header("Access-Control-Allow-Origin", "*")
+	c.Header("Server", fmt.Sprintf("ollama/%s", OllamaVersion))
+
+	rawJSON, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handlers.ErrorResponse{
+			Error: handlers.ErrorDetail{
+				Message: fmt.Sprintf("Invalid request: %v", err),
+				Type:    "invalid_request_error",
+			},
+		})
+		return
+	}
+	body := gjson.ParseBytes(rawJSON)
+	name := modelNameFromRequest(body)
+	if _, info := lookupModel(name); info == nil {
+		writeModelNotFound(c, name)
+		return
+	}
+
+	// Ollama streams progress unless "stream" is explicitly false.
+	if stream := body.Get("stream"); stream.Exists() && !stream.Bool() {
+		c.JSON(http.StatusOK, gin.H{"status": "success"})
+		return
+	}
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Status(http.StatusOK)
+	for _, status := range []string{"pulling manifest", "verifying sha256 digest", "writing manifest", "success"} {
+		_, _ = fmt.Fprintf(c.Writer, "{\"status\":%q}\n", status)
+	}
+}
+
+// Delete handles the /api/delete endpoint. Nothing is stored locally, so deleting only unloads
+// the model from /api/ps.
+func (h *OllamaAPIHandler) Delete(c *gin.Context) {
+	c.Header("Access-Control-Allow-Origin", "*")
+	c.Header("Server", fmt.Sprintf("ollama/%s", OllamaVersion))
+
+	rawJSON, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handlers.ErrorResponse{
+			Error: handlers.ErrorDetail{
+				Message: fmt.Sprintf("Invalid request: %v", err),
+				Type:    "invalid_request_error",
+			},
+		})
+		return
+	}
+	name := modelNameFromRequest(gjson.ParseBytes(rawJSON))
+	resolved, info := lookupModel(name)
+	if info == nil {
+		writeModelNotFound(c, name)
+		return
+	}
+	h.loaded.remove(resolved)
+	c.Status(http.StatusOK)
+}
@@ -0,0 +1,550 @@
+package openai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/batch"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	sdkaccess "github.com/router-for-me/CLIProxyAPI/v6/sdk/access"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/api/handlers"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+const (
+	// openAIBatchKind identifies OpenAI batches in the batch queue.
+	openAIBatchKind = "openai-batch"
+
+	// Keys of the batch job metadata.
+	batchMetaEndpoint         = "endpoint"
+	batchMetaInputFileID      = "input_file_id"
+	batchMetaCompletionWindow = "completion_window"
+	batchMetaMetadata         = "metadata"
+
+	maxOpenAIBatchRequests      = 50000
+	maxOpenAIBatchMetadataPairs = 16
+	defaultOpenAIBatchListLimit = 20
+	maxOpenAIBatchListLimit     = 100
+)
+
+// openAIBatchEndpoints lists the endpoints a batch may target.
+var openAIBatchEndpoints = []string{"/v1/chat/completions", "/v1/responses", "/v1/embeddings"}
+
+// batchIdentityContextKey carries the identity a batch item request runs as.
+type batchIdentityContextKey struct{}
+
+// batchIdentity is the principal and client key name of the batch an item belongs to.
+type batchIdentity struct {
+	key  string
+	name string
+}
+
+// SetBatchManager enables the /v1/files and /v1/batches endpoints backed by manager and registers
+// the runner that executes batch lines through the chat completions, responses and embeddings
+// handlers, exactly as if the client had sent them.
+func (h *OpenAIAPIHandler) SetBatchManager(manager *batch.Manager, responses *OpenAIResponsesAPIHandler) {
+	h.batches = manager
+	if manager == nil {
+		return
+	}
+	router := gin.New()
+	router.Use(batchItemIdentity)
+	router.POST("/v1/chat/completions", h.ChatCompletions)
+	router.POST("/v1/embeddings", h.Embeddings)
+	if responses != nil {
+		router.POST("/v1/responses", responses.Responses)
+	}
+	h.batchRouter = router
+	manager.RegisterRunner(openAIBatchKind, h.runBatchItem)
+}
+
+// batchItemIdentity authenticates a batch item request as the client key that created the batch.
+func batchItemIdentity(c *gin.Context) {
+	if identity, ok := c.Request.Context().Value(batchIdentityContextKey{}).(batchIdentity); ok && identity.key != "" {
+		c.Set("apiKey", identity.key)
+		if identity.name != "" {
+			c.Set("accessMetadata", map[string]string{sdkaccess.MetadataClientKeyName: identity.name})
+		}
+	}
+	c.Next()
+}
+
+// runBatchItem executes one batch line at background priority and returns the response body.
+func (h *OpenAIAPIHandler) runBatchItem(ctx context.Context, job batch.Job, item batch.Item) ([]byte, int, error) {
+	key, err := h.BatchIdentity(job.Owner, job.Principal)
+	if err != nil {
+		return nil, http.StatusForbidden, err
+	}
+	if endpoint := job.Metadata[batchMetaEndpoint]; !h.Cfg.FindClientKey(key).AllowsEndpoint(endpoint) {
+		return nil, http.StatusForbidden, fmt.Errorf("API key not allowed to call %s", endpoint)
+	}
+	var errMsg *interfaces.ErrorMessage
+	ctx = handlers.WithErrorResponseCallback(ctx, func(msg *interfaces.ErrorMessage) { errMsg = msg })
+	ctx = handlers.WithBackgroundPriority(context.WithValue(ctx, batchIdentityContextKey{}, batchIdentity{key: key, name: job.OwnerName}))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, job.Metadata[batchMetaEndpoint], bytes.NewReader(item.Params))
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	w := &batchResponseWriter{header: make(http.Header)}
+	h.batchRouter.ServeHTTP(w, req)
+
+	body := bytes.TrimSpace(w.body.Bytes())
+	switch {
+	case w.status == 0:
+		return nil, http.StatusInternalServerError, errors.New("empty response")
+	case w.status >= http.StatusBadRequest:
+		err = errors.New(http.StatusText(w.status))
+		if len(body) > 0 {
+			err = errors.New(string(body))
+		}
+		// Rejections such as a spent client key budget also answer 429 but never clear by retrying.
+		if w.status == http.StatusTooManyRequests && errMsg != nil && errMsg.Error != nil && !coreauth.IsTransientRateLimit(errMsg.Error) {
+			err = batch.Final(err)
+		}
+		return nil, w.status, err
+	}
+	return body, 0, nil
+}
+
+// batchResponseWriter buffers the response of a batch item.
+type batchResponseWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *batchResponseWriter) Header() http.Header { return w.header }
+
+func (w *batchResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *batchResponseWriter) Write(data []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	return w.body.Write(data)
+}
+
+func (w *batchResponseWriter) Flush() {}
+
+// CreateBatch handles POST /v1/batches. The input file is validated completely before the
+// batch is queued, so a malformed line rejects the whole batch.
+func (h *OpenAIAPIHandler) CreateBatch(c *gin.Context) {
+	if !h.requireBatches(c) {
+		return
+	}
+	rawJSON, err := c.GetRawData()
+	if err != nil || !gjson.ValidBytes(rawJSON) {
+		writeOpenAIError(c, http.StatusBadRequest, "invalid_request_error", "Invalid request: body must be valid JSON")
+		return
+	}
+	inputFileID := gjson.GetBytes(rawJSON, "input_file_id").String()
+	if inputFileID == "" {
+		writeOpenAIError(c, http.StatusBadRequest, "invalid_request_error", "Missing required parameter: 'input_file_id'.")
+		return
+	}
+	endpoint := gjson.GetBytes(rawJSON, "endpoint").String()
+	if !isOpenAIBatchEndpoint(endpoint) {
+		writeOpenAIError(c, http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("Invalid 'endpoint': expected one of %s.", strings.Join(openAIBatchEndpoints, ", ")))
+		return
+	}
+	if window := gjson.GetBytes(rawJSON, "completion_window").String(); window != "24h" {
+		writeOpenAIError(c, http.StatusBadRequest, "invalid_request_error", "Invalid 'completion_window': expected '24h'.")
+		return
+	}
+	metadata := gjson.GetBytes(rawJSON, "metadata")
+	if errMessage := validateBatchMetadata(metadata); errMessage != "" {
+		writeOpenAIError(c, http.StatusBadRequest, "invalid_request_error", errMessage)
+		return
+	}
+
+	owner := handlers.RequestOwner(c)
+	key, ownerName := handlers.RequestClientKey(c)
+	// Items are replayed on the internal router, so the key's endpoint allowlist is applied here.
+	if !h.Cfg.FindClientKey(key).AllowsEndpoint(endpoint) {
+		writeOpenAIError(c, http.StatusForbidden, "permission_error", fmt.Sprintf("API key not allowed to call %s.", endpoint))
+		return
+	}
+	file, err := h.batches.Files().Get(inputFileID)
+	if err != nil || file.Owner != owner {
+		writeOpenAIError(c, http.StatusNotFound, "invalid_request_error", fmt.Sprintf("No such File object: %s", inputFileID))
+		return
+	}
+	if file.Purpose != "batch" {
+		writeOpenAIError(c, http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("File %s was not uploaded with purpose 'batch'.", inputFileID))
+		return
+	}
+	content, err := h.batches.Files().Open(file.ID)
+	if err != nil {
+		log.Errorf("failed to open batch input file %s: %v", file.ID, err)
+		writeOpenAIError(c, http.StatusInternalServerError, "server_error", "Failed to read input file")
+		return
+	}
+	items, errMessage := parseOpenAIBatchInput(content, endpoint)
+	_ = content.Close()
+	if errMessage != "" {
+		writeOpenAIError(c, http.StatusBadRequest, "invalid_request_error", errMessage)
+		return
+	}
+
+	jobMetadata := map[string]string{
+		batchMetaEndpoint:         endpoint,
+		batchMetaInputFileID:      inputFileID,
+		batchMetaCompletionWindow: "24h",
+	}
+	if metadata.IsObject() {
+		jobMetadata[batchMetaMetadata] = metadata.Raw
+	}
+	job, err := h.batches.Create(batch.Spec{
+		Kind:      openAIBatchKind,
+		IDPrefix:  "batch_",
+		Owner:     owner,
+		OwnerName: ownerName,
+		Principal: h.ExternalPrincipal(c),
+		Items:     items,
+		Metadata:  jobMetadata,
+	})
+	if err != nil {
+		log.Errorf("failed to create batch: %v", err)
+		writeOpenAIError(c, http.StatusInternalServerError, "server_error", "Failed to create batch")
+		return
+	}
+	c.Data(http.StatusOK, "application/json", h.batchObject(job))
+}
+
+func isOpenAIBatchEndpoint(endpoint string) bool {
+	for _, candidate := range openAIBatchEndpoints {
+		if endpoint == candidate {
+			return true
+		}
+	}
+	return false
+}
+
+// validateBatchMetadata checks the optional metadata of a create call. It returns a message
+// describing the first problem found.
+func validateBatchMetadata(metadata gjson.Result) string {
+	if !metadata.Exists() || metadata.Type == gjson.Null {
+		return ""
+	}
+	if !metadata.IsObject() {
+		return "Invalid 'metadata': expected an object."
+	}
+	pairs := 0
+	errMessage := ""
+	metadata.ForEach(func(key, value gjson.Result) bool {
+		pairs++
+		if value.Type != gjson.String {
+			errMessage = fmt.Sprintf("Invalid 'metadata.%s': expected a string.", key.String())
+			return false
+		}
+		return true
+	})
+	if errMessage == "" && pairs > maxOpenAIBatchMetadataPairs {
+		errMessage = fmt.Sprintf("Invalid 'metadata': at most %d key-value pairs are allowed.", maxOpenAIBatchMetadataPairs)
+	}
+	return errMessage
+}
+
+// parseOpenAIBatchInput validates the JSONL input of a batch whose lines must all target
+// endpoint. It returns a message describing the first problem found.
+func parseOpenAIBatchInput(r io.Reader, endpoint string) ([]batch.Item, string) {
+	var items []batch.Item
+	seen := make(map[string]struct{})
+	reader := bufio.NewReaderSize(r, 64*1024)
+	for lineNumber := 1; ; lineNumber++ {
+		line, err := reader.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Sprintf("Failed to read input file: %v", err)
+		}
+		if trimmed := bytes.TrimSpace(line); len(trimmed) > 0 {
+			item, errMessage := parseOpenAIBatchLine(trimmed, endpoint, seen)
+			if errMessage != "" {
+				return nil, fmt.Sprintf("Line %d: %s", lineNumber, errMessage)
+			}
+			if len(items) == maxOpenAIBatchRequests {
+				return nil, fmt.Sprintf("Input file may contain at most %d requests.", maxOpenAIBatchRequests)
+			}
+			items = append(items, item)
+		}
+		if err != nil {
+			break
+		}
+	}
+	if len(items) == 0 {
+		return nil, "Input file contains no requests."
+	}
+	return items, ""
+}
+
+func parseOpenAIBatchLine(line []byte, endpoint string, seen map[string]struct{}) (batch.Item, string) {
+	if !gjson.ValidBytes(line) {
+		return batch.Item{}, "invalid JSON."
+	}
+	parsed := gjson.ParseBytes(line)
+	customID := parsed.Get("custom_id").String()
+	if customID == "" {
+		return batch.Item{}, "missing required field 'custom_id'."
+	}
+	if _, dup := seen[customID]; dup {
+		return batch.Item{}, fmt.Sprintf("duplicate custom_id %q.", customID)
+	}
+	seen[customID] = struct{}{}
+	if method := parsed.Get("method").String(); method != http.MethodPost {
+		return batch.Item{}, "'method' must be 'POST'."
+	}
+	if url := parsed.Get("url").String(); url != endpoint {
+		return batch.Item{}, fmt.Sprintf("'url' must match the batch endpoint %s.", endpoint)
+	}
+	body := parsed.Get("body")
+	if !body.IsObject() {
+		return batch.Item{}, "'body' must be an object."
+	}
+	if body.Get("model").String() == "" {
+		return batch.Item{}, "missing required field 'body.model'."
+	}
+	if body.Get("stream").Bool() {
+		return batch.Item{}, "streaming is not supported in batches."
+	}
+	return batch.Item{CustomID: customID, Params: []byte(body.Raw)}, ""
+}
+
+// ListBatches handles GET /v1/batches.
+func (h *OpenAIAPIHandler) ListBatches(c *gin.Context) {
+	if !h.requireBatches(c) {
+		return
+	}
+	limit := defaultOpenAIBatchListLimit
+	if raw := c.Query("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > maxOpenAIBatchListLimit {
+			writeOpenAIError(c, http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("Invalid 'limit': expected an integer between 1 and %d.", maxOpenAIBatchListLimit))
+			return
+		}
+		limit = parsed
+	}
+
+	owner := handlers.RequestOwner(c)
+	jobs := h.batches.List(openAIBatchKind, owner)
+	if after := c.Query("after"); after != "" {
+		for i, job := range jobs {
+			if job.ID == after {
+				jobs = jobs[i+1:]
+				break
+			}
+		}
+	}
+	hasMore := len(jobs) > limit
+	if hasMore {
+		jobs = jobs[:limit]
+	}
+
+	out := []byte(`{"object":"list","data":[],"first_id":null,"last_id":null,"has_more":false}`)
+	for _, job := range jobs {
+		out, _ = sjson.SetRawBytes(out, "data.-1", h.batchObject(job))
+	}
+	if len(jobs) > 0 {
+		out, _ = sjson.SetBytes(out, "first_id", jobs[0].ID)
+		out, _ = sjson.SetBytes(out, "last_id", jobs[len(jobs)-1].ID)
+	}
+	out, _ = sjson.SetBytes(out, "has_more", hasMore)
+	c.Data(http.StatusOK, "application/json", out)
+}
+
+// GetBatch handles GET /v1/batches/{id}.
+func (h *OpenAIAPIHandler) GetBatch(c *gin.Context) {
+	job, ok := h.loadBatch(c)
+	if !ok {
+		return
+	}
+	c.Data(http.StatusOK, "application/json", h.batchObject(job))
+}
+
+// CancelBatch handles POST /v1/batches/{id}/cancel.
+func (h *OpenAIAPIHandler) CancelBatch(c *gin.Context) {
+	job, ok := h.loadBatch(c)
+	if !ok {
+		return
+	}
+	job, err := h.batches.Cancel(job.ID)
+	if err != nil {
+		writeOpenAIError(c, http.StatusNotFound, "invalid_request_error", fmt.Sprintf("No such batch: %s", c.Param("id")))
+		return
+	}
+	c.Data(http.StatusOK, "application/json", h.batchObject(job))
+}
+
+// batchObject renders job as an OpenAI batch, writing its output and error files once it ended.
+func (h *OpenAIAPIHandler) batchObject(job batch.Job) []byte {
+	out := []byte(`{"id":"","object":"batch","endpoint":"","errors":null,"input_file_id":"","completion_window":"","status":"","output_file_id":null,"error_file_id":null,"created_at":0,"in_progress_at":0,"expires_at":0,"finalizing_at":null,"completed_at":null,"failed_at":null,"expired_at":null,"cancelling_at":null,"cancelled_at":null,"request_counts":{"total":0,"completed":0,"failed":0},"metadata":null}`)
+	out, _ = sjson.SetBytes(out, "id", job.ID)
+	out, _ = sjson.SetBytes(out, "endpoint", job.Metadata[batchMetaEndpoint])
+	out, _ = sjson.SetBytes(out, "input_file_id", job.Metadata[batchMetaInputFileID])
+	out, _ = sjson.SetBytes(out, "completion_window", job.Metadata[batchMetaCompletionWindow])
+	out, _ = sjson.SetBytes(out, "created_at", job.CreatedAt.Unix())
+	// Input files are validated before the batch is queued, so batches start in progress.
+	out, _ = sjson.SetBytes(out, "in_progress_at", job.CreatedAt.Unix())
+	out, _ = sjson.SetBytes(out, "expires_at", job.ExpiresAt.Unix())
+	out, _ = sjson.SetBytes(out, "request_counts.total", job.Total)
+	out, _ = sjson.SetBytes(out, "request_counts.completed", job.Counts.Succeeded)
+	out, _ = sjson.SetBytes(out, "request_counts.failed", job.Counts.Errored+job.Counts.Canceled+job.Counts.Expired)
+	if metadata := job.Metadata[batchMetaMetadata]; metadata != "" {
+		out, _ = sjson.SetRawBytes(out, "metadata", []byte(metadata))
+	}
+	if !job.CancelInitiatedAt.IsZero() {
+		out, _ = sjson.SetBytes(out, "cancelling_at", job.CancelInitiatedAt.Unix())
+	}
+
+	status := "in_progress"
+	switch {
+	case job.Status == batch.StatusCanceling:
+		status = "cancelling"
+	case job.Status != batch.StatusEnded:
+	case !job.CancelInitiatedAt.IsZero():
+		status = "cancelled"
+		out, _ = sjson.SetBytes(out, "cancelled_at", job.EndedAt.Unix())
+	case job.Counts.Expired > 0:
+		status = "expired"
+		out, _ = sjson.SetBytes(out, "expired_at", job.EndedAt.Unix())
+	default:
+		status = "completed"
+		out, _ = sjson.SetBytes(out, "finalizing_at", job.EndedAt.Unix())
+		out, _ = sjson.SetBytes(out, "completed_at", job.EndedAt.Unix())
+	}
+	out, _ = sjson.SetBytes(out, "status", status)
+
+	if job.Status == batch.StatusEnded {
+		outputFileID, errorFileID := h.batchResultFiles(job)
+		if outputFileID != "" {
+			out, _ = sjson.SetBytes(out, "output_file_id", outputFileID)
+		}
+		if errorFileID != "" {
+			out, _ = sjson.SetBytes(out, "error_file_id", errorFileID)
+		}
+	}
+	return out
+}
+
+// batchResultFiles returns the ids of the output and error files of an ended batch, writing them
+// from the batch results the first time they are needed. An id is empty when the batch has no
+// lines for that file.
+func (h *OpenAIAPIHandler) batchResultFiles(job batch.Job) (string, string) {
+	outputFileID, errorFileID := "", ""
+	if job.Counts.Succeeded > 0 {
+		outputFileID = "file-" + job.ID + "-output"
+	}
+	if job.Counts.Errored+job.Counts.Canceled+job.Counts.Expired > 0 {
+		errorFileID = "file-" + job.ID + "-errors"
+	}
+
+	h.batchFilesMu.Lock()
+	defer h.batchFilesMu.Unlock()
+	files := h.batches.Files()
+	missing := func(id string) bool {
+		if id == "" {
+			return false
+		}
+		_, err := files.Get(id)
+		return err != nil
+	}
+	if !missing(outputFileID) && !missing(errorFileID) {
+		return outputFileID, errorFileID
+	}
+
+	var output, errorLines bytes.Buffer
+	errResults := h.batches.Results(job.ID, func(result batch.Result) error {
+		target := &errorLines
+		if result.Type == batch.ResultSucceeded {
+			target = &output
+		}
+		target.Write(openAIBatchResultLine(result))
+		return target.WriteByte('\n')
+	})
+	if errResults != nil {
+		log.Errorf("batch %s: read results: %v", job.ID, errResults)
+		return "", ""
+	}
+	for _, file := range []struct {
+		id      *string
+		name    string
+		content *bytes.Buffer
+	}{
+		{id: &outputFileID, name: "batch_" + job.ID + "_output.jsonl", content: &output},
+		{id: &errorFileID, name: "batch_" + job.ID + "_error.jsonl", content: &errorLines},
+	} {
+		if !missing(*file.id) {
+			continue
+		}
+		if _, err := files.Put(*file.id, job.Owner, file.name, "batch_output", file.content, 0); err != nil {
+			log.Errorf("batch %s: write result file: %v", job.ID, err)
+			*file.id = ""
+		}
+	}
+	return outputFileID, errorFileID
+}
+
+// openAIBatchResultLine renders a result in OpenAI's batch output format.
+func openAIBatchResultLine(result batch.Result) []byte {
+	line := []byte(`{"id":"","custom_id":"","response":null,"error":null}`)
+	line, _ = sjson.SetBytes(line, "id", "batch_req_"+strings.ReplaceAll(uuid.NewString(), "-", ""))
+	line, _ = sjson.SetBytes(line, "custom_id", result.CustomID)
+	switch result.Type {
+	case batch.ResultSucceeded, batch.ResultErrored:
+		status, body := http.StatusOK, []byte(result.Body)
+		if result.Type == batch.ResultErrored {
+			status = result.StatusCode
+			if status <= 0 {
+				status = http.StatusInternalServerError
+			}
+			body = handlers.BuildErrorResponseBody(status, result.Error)
+		}
+		response := []byte(`{"status_code":0,"request_id":"","body":null}`)
+		response, _ = sjson.SetBytes(response, "status_code", status)
+		response, _ = sjson.SetBytes(response, "request_id", "req_"+strings.ReplaceAll(uuid.NewString(), "-", ""))
+		if gjson.ValidBytes(body) {
+			response, _ = sjson.SetRawBytes(response, "body", body)
+		}
+		line, _ = sjson.SetRawBytes(line, "response", response)
+	case batch.ResultCanceled:
+		line, _ = sjson.SetRawBytes(line, "error", []byte(`{"code":"batch_cancelled","message":"This request was not executed because the batch was cancelled."}`))
+	case batch.ResultExpired:
+		line, _ = sjson.SetRawBytes(line, "error", []byte(`{"code":"batch_expired","message":"This request could not be executed before the completion window expired."}`))
+	}
+	return line
+}
+
+func (h *OpenAIAPIHandler) loadBatch(c *gin.Context) (batch.Job, bool) {
+	if !h.requireBatches(c) {
+		return batch.Job{}, false
+	}
+	id := c.Param("id")
+	job, err := h.batches.Get(id)
+	owner := handlers.RequestOwner(c)
+	if err != nil || job.Kind != openAIBatchKind || job.Owner != owner {
+		writeOpenAIError(c, http.StatusNotFound, "invalid_request_error", fmt.Sprintf("No such batch: %s", id))
+		return batch.Job{}, false
+	}
+	return job, true
+}
+
+func (h *OpenAIAPIHandler) requireBatches(c *gin.Context) bool {
+	if h.batches != nil {
+		return true
+	}
+	writeOpenAIError(c, http.StatusNotFound, "invalid_request_error", "Files and batches are disabled; enable batches in the configuration.")
+	return false
+}
@@ -0,0 +1,208 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/batch"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/api/handlers"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	"github.com/tidwall/gjson"
+)
+
+type batchChatExecutor struct{}
+
+func (e *batchChatExecutor) Identifier() string { return "test-openai-batch-provider" }
+
+func (e *batchChatExecutor) Execute(ctx context.Context, auth *coreauth.Auth, req coreexecutor.Request, opts coreexecutor.Options) (coreexecutor.Response, error) {
+	if background, _ := opts.Metadata[coreexecutor.BackgroundMetadataKey].(bool); !background {
+		return coreexecutor.Response{}, errors.New("batch item not marked as background work")
+	}
+	if key, _ := opts.Metadata[coreexecutor.ClientKeyMetadataKey].(string); key != "key-a" {
+		return coreexecutor.Response{}, errors.New("batch item not run as the batch owner")
+	}
+	if strings.Contains(string(req.Payload), "fail") {
+		return coreexecutor.Response{}, &coreauth.Error{Message: "bad prompt", HTTPStatus: http.StatusBadRequest}
+	}
+	return coreexecutor.Response{Payload: []byte(`{"id":"chatcmpl-1","object":"chat.completion","choices":[{"index":0,"message":{"role":"assistant","content":"hi"},"finish_reason":"stop"}]}`)}, nil
+}
+
+func (e *batchChatExecutor) ExecuteStream(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (*coreexecutor.StreamResult, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (e *batchChatExecutor) Refresh(ctx context.Context, auth *coreauth.Auth) (*coreauth.Auth, error) {
+	return auth, nil
+}
+
+func (e *batchChatExecutor) CountTokens(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (coreexecutor.Response, error) {
+	return coreexecutor.Response{}, errors.New("not implemented")
+}
+
+func (e *batchChatExecutor) HttpRequest(context.Context, *coreauth.Auth, *http.Request) (*http.Response, error) {
+	return nil, errors.New("not implemented")
+}
+
+func TestOpenAIBatchLifecycle(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	executor := &batchChatExecutor{}
+	manager := coreauth.NewManager(nil, nil, nil)
+	manager.RegisterExecutor(executor)
+	auth := &coreauth.Auth{ID: "openai-batch-auth", Provider: executor.Identifier(), Status: coreauth.StatusActive}
+	if _, err := manager.Register(context.Background(), auth); err != nil {
+		t.Fatalf("Register auth: %v", err)
+	}
+	registry.GetGlobalRegistry().RegisterClient(auth.ID, auth.Provider, []*registry.ModelInfo{{ID: "openai-batch-model"}})
+	t.Cleanup(func() {
+		registry.GetGlobalRegistry().UnregisterClient(auth.ID)
+	})
+
+	batches, err := batch.New(config.BatchConfig{}, t.TempDir())
+	if err != nil {
+		t.Fatalf("batch.New: %v", err)
+	}
+	base := handlers.NewBaseAPIHandlers(&sdkconfig.SDKConfig{APIKeys: []string{"key-a", "key-b"}}, manager)
+	h := NewOpenAIAPIHandler(base)
+	h.SetBatchManager(batches, NewOpenAIResponsesAPIHandler(base))
+	batches.Start(context.Background())
+	t.Cleanup(batches.Stop)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("apiKey", c.GetHeader("X-Test-Key"))
+	})
+	router.POST("/v1/files", h.UploadFile)
+	router.GET("/v1/files/:id", h.GetFile)
+	router.GET("/v1/files/:id/content", h.GetFileContent)
+	router.POST("/v1/batches", h.CreateBatch)
+	router.GET("/v1/batches", h.ListBatches)
+	router.GET("/v1/batches/:id", h.GetBatch)
+	serve := func(req *http.Request, key string) *httptest.ResponseRecorder {
+		req.Header.Set("X-Test-Key", key)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+	get := func(path, key string) *httptest.ResponseRecorder {
+		return serve(httptest.NewRequest(http.MethodGet, path, nil), key)
+	}
+	upload := func(content string) string {
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		_ = form.WriteField("purpose", "batch")
+		part, _ := form.CreateFormFile("file", "input.jsonl")
+		_, _ = part.Write([]byte(content))
+		_ = form.Close()
+		req := httptest.NewRequest(http.MethodPost, "/v1/files", &body)
+		req.Header.Set("Content-Type", form.FormDataContentType())
+		resp := serve(req, "key-a")
+		if resp.Code != http.StatusOK {
+			t.Fatalf("upload status = %d, body = %s", resp.Code, resp.Body.String())
+		}
+		return gjson.Get(resp.Body.String(), "id").String()
+	}
+	createBatch := func(fileID string) *httptest.ResponseRecorder {
+		body := `{"input_file_id":"` + fileID + `","endpoint":"/v1/chat/completions","completion_window":"24h","metadata":{"job":"nightly"}}`
+		return serve(httptest.NewRequest(http.MethodPost, "/v1/batches", strings.NewReader(body)), "key-a")
+	}
+
+	streaming := upload(`{"custom_id":"a","method":"POST","url":"/v1/chat/completions","body":{"model":"openai-batch-model","stream":true}}`)
+	if resp := createBatch(streaming); resp.Code != http.StatusBadRequest {
+		t.Fatalf("streaming line status = %d, want 400", resp.Code)
+	}
+
+	inputID := upload(`{"custom_id":"ok","method":"POST","url":"/v1/chat/completions","body":{"model":"openai-batch-model","messages":[{"role":"user","content":"hello"}]}}
+{"custom_id":"broken","method":"POST","url":"/v1/chat/completions","body":{"model":"openai-batch-model","messages":[{"role":"user","content":"fail"}]}}
+`)
+	if resp := get("/v1/files/"+inputID, "key-b"); resp.Code != http.StatusNotFound {
+		t.Fatalf("other key file status = %d, want 404", resp.Code)
+	}
+	created := createBatch(inputID)
+	if created.Code != http.StatusOK {
+		t.Fatalf("create status = %d, body = %s", created.Code, created.Body.String())
+	}
+	id := gjson.Get(created.Body.String(), "id").String()
+	if !strings.HasPrefix(id, "batch_") || gjson.Get(created.Body.String(), "metadata.job").String() != "nightly" {
+		t.Fatalf("create body = %s", created.Body.String())
+	}
+	if job, errGet := batches.Get(id); errGet != nil || job.Owner != config.ClientKeyDigest("key-a") || job.Principal != "" {
+		t.Fatalf("stored batch = %+v, %v; want only the digest of the configured key", job, errGet)
+	}
+	if file, errGet := batches.Files().Get(inputID); errGet != nil || file.Owner != config.ClientKeyDigest("key-a") {
+		t.Fatalf("stored file = %+v, %v; want the digest of the key as owner", file, errGet)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	var got gjson.Result
+	for time.Now().Before(deadline) {
+		got = gjson.Parse(get("/v1/batches/"+id, "key-a").Body.String())
+		if got.Get("status").String() == "completed" {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got.Get("status").String() != "completed" {
+		t.Fatalf("batch = %s", got.Raw)
+	}
+	if got.Get("request_counts.completed").Int() != 1 || got.Get("request_counts.failed").Int() != 1 {
+		t.Fatalf("request_counts = %s", got.Get("request_counts").Raw)
+	}
+
+	output := get("/v1/files/"+got.Get("output_file_id").String()+"/content", "key-a")
+	line := gjson.Parse(strings.TrimSpace(output.Body.String()))
+	if line.Get("custom_id").String() != "ok" || line.Get("response.status_code").Int() != http.StatusOK || line.Get("response.body.id").String() != "chatcmpl-1" {
+		t.Fatalf("output file = %s", output.Body.String())
+	}
+	errorsFile := get("/v1/files/"+got.Get("error_file_id").String()+"/content", "key-a")
+	line = gjson.Parse(strings.TrimSpace(errorsFile.Body.String()))
+	if line.Get("custom_id").String() != "broken" || line.Get("response.status_code").Int() != http.StatusBadRequest {
+		t.Fatalf("error file = %s", errorsFile.Body.String())
+	}
+
+	if list := get("/v1/batches", "key-b"); len(gjson.Get(list.Body.String(), "data").Array()) != 0 {
+		t.Fatalf("other key batches = %s", list.Body.String())
+	}
+	if list := get("/v1/batches?limit=1", "key-a"); gjson.Get(list.Body.String(), "first_id").String() != id {
+		t.Fatalf("list = %s", list.Body.String())
+	}
+}
+
+func TestCreateBatch_RejectsEndpointOutsideKeyAllowlist(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	batches, err := batch.New(config.BatchConfig{}, t.TempDir())
+	if err != nil {
+		t.Fatalf("batch.New: %v", err)
+	}
+	cfg := &sdkconfig.SDKConfig{ClientKeys: []sdkconfig.ClientKey{{Key: "embed-only", Endpoints: []string{"/v1/embeddings", "/v1/files", "/v1/batches"}}}}
+	base := handlers.NewBaseAPIHandlers(cfg, coreauth.NewManager(nil, nil, nil))
+	h := NewOpenAIAPIHandler(base)
+	h.SetBatchManager(batches, NewOpenAIResponsesAPIHandler(base))
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("apiKey", "embed-only")
+	})
+	router.POST("/v1/batches", h.CreateBatch)
+
+	body := `{"input_file_id":"file-any","endpoint":"/v1/chat/completions","completion_window":"24h"}`
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/v1/batches", strings.NewReader(body)))
+	if resp.Code != http.StatusForbidden {
+		t.Fatalf("create status = %d, want 403, body = %s", resp.Code, resp.Body.String())
+	}
+	if jobs := batches.List(openAIBatchKind, config.ClientKeyDigest("embed-only")); len(jobs) != 0 {
+		t.Fatalf("jobs = %+v, want none", jobs)
+	}
+}
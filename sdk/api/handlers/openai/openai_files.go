@@ -0,0 +1,194 @@
+package openai
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/batch"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/api/handlers"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/sjson"
+)
+
+const (
+	// maxUploadFileBytes is the size limit of an uploaded file, matching OpenAI's batch input limit.
+	maxUploadFileBytes = 200 << 20
+
+	defaultFileListLimit = 10000
+	maxFileListLimit     = 10000
+)
+
+// UploadFile handles POST /v1/files. The multipart form carries the content in "file" and its
+// intended use in "purpose".
+func (h *OpenAIAPIHandler) UploadFile(c *gin.Context) {
+	if !h.requireBatches(c) {
+		return
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxUploadFileBytes+1<<20)
+	purpose := strings.TrimSpace(c.PostForm("purpose"))
+	if purpose == "" {
+		writeOpenAIError(c, http.StatusBadRequest, "invalid_request_error", "Missing required parameter: 'purpose'.")
+		return
+	}
+	header, err := c.FormFile("file")
+	if err != nil {
+		writeOpenAIError(c, http.StatusBadRequest, "invalid_request_error", "Missing required parameter: 'file'.")
+		return
+	}
+	content, err := header.Open()
+	if err != nil {
+		writeOpenAIError(c, http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("Invalid file: %v", err))
+		return
+	}
+	defer func() { _ = content.Close() }()
+
+	owner := handlers.RequestOwner(c)
+	file, err := h.batches.Files().Create(owner, header.Filename, purpose, content, maxUploadFileBytes)
+	if errors.Is(err, batch.ErrFileTooLarge) {
+		writeOpenAIError(c, http.StatusRequestEntityTooLarge, "invalid_request_error", fmt.Sprintf("File exceeds the maximum size of %d bytes.", maxUploadFileBytes))
+		return
+	}
+	if err != nil {
+		log.Errorf("failed to store uploaded file: %v", err)
+		writeOpenAIError(c, http.StatusInternalServerError, "server_error", "Failed to store file")
+		return
+	}
+	c.Data(http.StatusOK, "application/json", openAIFileObject(file))
+}
+
+// ListFiles handles GET /v1/files.
+func (h *OpenAIAPIHandler) ListFiles(c *gin.Context) {
+	if !h.requireBatches(c) {
+		return
+	}
+	limit := defaultFileListLimit
+	if raw := c.Query("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > maxFileListLimit {
+			writeOpenAIError(c, http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("Invalid 'limit': expected an integer between 1 and %d.", maxFileListLimit))
+			return
+		}
+		limit = parsed
+	}
+	order := c.DefaultQuery("order", "desc")
+	if order != "asc" && order != "desc" {
+		writeOpenAIError(c, http.StatusBadRequest, "invalid_request_error", "Invalid 'order': expected 'asc' or 'desc'.")
+		return
+	}
+
+	owner := handlers.RequestOwner(c)
+	files := h.batches.Files().List(owner, c.Query("purpose"))
+	if order == "asc" {
+		for i, j := 0, len(files)-1; i < j; i, j = i+1, j-1 {
+			files[i], files[j] = files[j], files[i]
+		}
+	}
+	if after := c.Query("after"); after != "" {
+		for i, file := range files {
+			if file.ID == after {
+				files = files[i+1:]
+				break
+			}
+		}
+	}
+	hasMore := len(files) > limit
+	if hasMore {
+		files = files[:limit]
+	}
+
+	out := []byte(`{"object":"list","data":[],"first_id":null,"last_id":null,"has_more":false}`)
+	for _, file := range files {
+		out, _ = sjson.SetRawBytes(out, "data.-1", openAIFileObject(file))
+	}
+	if len(files) > 0 {
+		out, _ = sjson.SetBytes(out, "first_id", files[0].ID)
+		out, _ = sjson.SetBytes(out, "last_id", files[len(files)-1].ID)
+	}
+	out, _ = sjson.SetBytes(out, "has_more", hasMore)
+	c.Data(http.StatusOK, "application/json", out)
+}
+
+// GetFile handles GET /v1/files/{id}.
+func (h *OpenAIAPIHandler) GetFile(c *gin.Context) {
+	file, ok := h.loadFile(c)
+	if !ok {
+		return
+	}
+	c.Data(http.StatusOK, "application/json", openAIFileObject(file))
+}
+
+// GetFileContent handles GET /v1/files/{id}/content.
+func (h *OpenAIAPIHandler) GetFileContent(c *gin.Context) {
+	file, ok := h.loadFile(c)
+	if !ok {
+		return
+	}
+	content, err := h.batches.Files().Open(file.ID)
+	if err != nil {
+		log.Errorf("failed to open file %s: %v", file.ID, err)
+		writeOpenAIError(c, http.StatusInternalServerError, "server_error", "Failed to read file")
+		return
+	}
+	defer func() { _ = content.Close() }()
+	c.Header("Content-Type", "application/octet-stream")
+	c.Header("Content-Length", strconv.FormatInt(file.Bytes, 10))
+	c.Status(http.StatusOK)
+	if _, err = io.Copy(c.Writer, content); err != nil {
+		log.Errorf("failed to write file %s: %v", file.ID, err)
+	}
+}
+
+// DeleteFile handles DELETE /v1/files/{id}.
+func (h *OpenAIAPIHandler) DeleteFile(c *gin.Context) {
+	file, ok := h.loadFile(c)
+	if !ok {
+		return
+	}
+	if err := h.batches.Files().Delete(file.ID); err != nil && !errors.Is(err, batch.ErrFileNotFound) {
+		log.Errorf("failed to delete file %s: %v", file.ID, err)
+		writeOpenAIError(c, http.StatusInternalServerError, "server_error", "Failed to delete file")
+		return
+	}
+	out := []byte(`{"id":"","object":"file","deleted":true}`)
+	out, _ = sjson.SetBytes(out, "id", file.ID)
+	c.Data(http.StatusOK, "application/json", out)
+}
+
+// openAIFileObject renders file as an OpenAI file object.
+func openAIFileObject(file batch.File) []byte {
+	out := []byte(`{"id":"","object":"file","bytes":0,"created_at":0,"filename":"","purpose":"","status":"processed","status_details":null,"expires_at":null}`)
+	out, _ = sjson.SetBytes(out, "id", file.ID)
+	out, _ = sjson.SetBytes(out, "bytes", file.Bytes)
+	out, _ = sjson.SetBytes(out, "created_at", file.CreatedAt.Unix())
+	out, _ = sjson.SetBytes(out, "filename", file.Filename)
+	out, _ = sjson.SetBytes(out, "purpose", file.Purpose)
+	return out
+}
+
+func (h *OpenAIAPIHandler) loadFile(c *gin.Context) (batch.File, bool) {
+	if !h.requireBatches(c) {
+		return batch.File{}, false
+	}
+	id := c.Param("id")
+	file, err := h.batches.Files().Get(id)
+	owner := handlers.RequestOwner(c)
+	if err != nil || file.Owner != owner {
+		writeOpenAIError(c, http.StatusNotFound, "invalid_request_error", fmt.Sprintf("No such File object: %s", id))
+		return batch.File{}, false
+	}
+	return file, true
+}
+
+func writeOpenAIError(c *gin.Context, status int, errType, message string) {
+	c.JSON(status, handlers.ErrorResponse{
+		Error: handlers.ErrorDetail{
+			Message: message,
+			Type:    errType,
+		},
+	})
+}
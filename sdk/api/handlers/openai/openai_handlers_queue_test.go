@@ -0,0 +1,117 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/api/handlers"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	"github.com/tidwall/gjson"
+)
+
+// blockingQueueExecutor holds its credential's only slot until release is closed.
+type blockingQueueExecutor struct {
+	release chan struct{}
+}
+
+func (e *blockingQueueExecutor) Identifier() string { return "test-queue-stream-provider" }
+
+func (e *blockingQueueExecutor) Execute(ctx context.Context, auth *coreauth.Auth, req coreexecutor.Request, opts coreexecutor.Options) (coreexecutor.Response, error) {
+	select {
+	case <-e.release:
+	case <-ctx.Done():
+	}
+	return coreexecutor.Response{Payload: []byte(`{}`)}, nil
+}
+
+func (e *blockingQueueExecutor) ExecuteStream(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (*coreexecutor.StreamResult, error) {
+	return nil, errors.New("stream should never get a slot")
+}
+
+func (e *blockingQueueExecutor) Refresh(ctx context.Context, auth *coreauth.Auth) (*coreauth.Auth, error) {
+	return auth, nil
+}
+
+func (e *blockingQueueExecutor) CountTokens(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (coreexecutor.Response, error) {
+	return coreexecutor.Response{}, errors.New("not implemented")
+}
+
+func (e *blockingQueueExecutor) HttpRequest(context.Context, *coreauth.Auth, *http.Request) (*http.Response, error) {
+	return nil, errors.New("not implemented")
+}
+
+func TestChatCompletionsStream_QueueTimeoutAfterKeepAliveSendsSSEError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	executor := &blockingQueueExecutor{release: make(chan struct{})}
+	manager := coreauth.NewManager(nil, nil, nil)
+	manager.RegisterExecutor(executor)
+	manager.SetConfig(&internalconfig.Config{
+		Routing: internalconfig.RoutingConfig{
+			Queue: internalconfig.QueueConfig{Enabled: true, TimeoutSeconds: 2},
+		},
+	})
+	auth := &coreauth.Auth{ID: "queue-stream-auth", Provider: executor.Identifier(), Status: coreauth.StatusActive, Attributes: map[string]string{"max_concurrency": "1"}}
+	if _, err := manager.Register(context.Background(), auth); err != nil {
+		t.Fatalf("Register auth: %v", err)
+	}
+	registry.GetGlobalRegistry().RegisterClient(auth.ID, auth.Provider, []*registry.ModelInfo{{ID: "queue-stream-model"}})
+	t.Cleanup(func() {
+		registry.GetGlobalRegistry().UnregisterClient(auth.ID)
+	})
+
+	blocked := make(chan struct{})
+	go func() {
+		defer close(blocked)
+		_, _ = manager.Execute(context.Background(), []string{executor.Identifier()}, coreexecutor.Request{Model: "queue-stream-model"}, coreexecutor.Options{})
+	}()
+	t.Cleanup(func() {
+		close(executor.release)
+		<-blocked
+	})
+	deadline := time.Now().Add(time.Second)
+	for manager.InFlight(auth.ID) != 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("blocking request never became in-flight")
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	cfg := &sdkconfig.SDKConfig{}
+	cfg.Streaming.KeepAliveSeconds = 1
+	h := NewOpenAIAPIHandler(handlers.NewBaseAPIHandlers(cfg, manager))
+	router := gin.New()
+	router.POST("/v1/chat/completions", h.ChatCompletions)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{"model":"queue-stream-model","stream":true,"messages":[{"role":"user","content":"hi"}]}`))
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	if resp.Code != http.StatusOK || !strings.HasPrefix(resp.Header().Get("Content-Type"), "text/event-stream") {
+		t.Fatalf("status = %d, content type = %q; want a committed event stream", resp.Code, resp.Header().Get("Content-Type"))
+	}
+	body := resp.Body.String()
+	if !strings.HasPrefix(body, ": keep-alive\n\n") {
+		t.Fatalf("body = %q, want queued keep-alives first", body)
+	}
+	var errorEvent string
+	for _, line := range strings.Split(body, "\n") {
+		if strings.HasPrefix(line, "data: ") {
+			errorEvent = strings.TrimPrefix(line, "data: ")
+		} else if strings.HasPrefix(line, "{") {
+			t.Fatalf("bare JSON error appended to the event stream: %q", body)
+		}
+	}
+	if !gjson.Get(errorEvent, "error.message").Exists() {
+		t.Fatalf("body = %q, want a terminal SSE error event", body)
+	}
+}
@@ -0,0 +1,458 @@
+package openai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/responsestore"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/api/handlers"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+const (
+	// responseStoreSaveTimeout bounds how long saving a finished response may take once the
+	// client request has ended.
+	responseStoreSaveTimeout = 10 * time.Second
+
+	defaultInputItemsLimit = 20
+	maxInputItemsLimit     = 100
+)
+
+// SetResponseStore enables server-side storage of /v1/responses calls. With a store set,
+// previous_response_id is expanded into the conversation before the request is translated, so
+// every backend can continue stored responses. A nil store disables the feature.
+func (h *OpenAIResponsesAPIHandler) SetResponseStore(store responsestore.Store) {
+	h.store = store
+}
+
+// responseRecorder captures the response written for one /v1/responses call and stores it
+// together with the conversation input once the handler has finished.
+type responseRecorder struct {
+	store      responsestore.Store
+	writer     *responseCaptureWriter
+	owner      string
+	previousID string
+	input      []json.RawMessage
+}
+
+// prepareStoredResponse expands previous_response_id in rawJSON and, unless the request opts
+// out with "store": false, starts capturing the response written to c. It reports false after
+// writing an error response.
+func (h *OpenAIResponsesAPIHandler) prepareStoredResponse(c *gin.Context, rawJSON []byte) ([]byte, *responseRecorder, bool) {
+	if h.store == nil {
+		return rawJSON, nil, true
+	}
+
+	owner := handlers.RequestOwner(c)
+	input := responsesInputItems(gjson.GetBytes(rawJSON, "input"))
+	previousID := strings.TrimSpace(gjson.GetBytes(rawJSON, "previous_response_id").String())
+	if previousID != "" {
+		chain, err := h.loadResponseChain(c.Request.Context(), owner, previousID)
+		if err != nil {
+			if errors.Is(err, responsestore.ErrNotFound) {
+				c.JSON(http.StatusBadRequest, handlers.ErrorResponse{
+					Error: handlers.ErrorDetail{
+						Message: fmt.Sprintf("Previous response with id '%s' not found.", previousID),
+						Type:    "invalid_request_error",
+						Code:    "previous_response_not_found",
+					},
+				})
+				return nil, nil, false
+			}
+			log.Errorf("responses store: load %s: %v", previousID, err)
+			c.JSON(http.StatusInternalServerError, handlers.ErrorResponse{
+				Error: handlers.ErrorDetail{
+					Message: "Failed to load previous response",
+					Type:    "server_error",
+				},
+			})
+			return nil, nil, false
+		}
+		var conversation []json.RawMessage
+		for _, rec := range chain {
+			conversation = append(conversation, replayedResponseItems(rec)...)
+		}
+		conversation = append(conversation, input...)
+
+		if updated, errSet := sjson.SetRawBytes(rawJSON, "input", joinJSONArray(conversation)); errSet == nil {
+			rawJSON = updated
+		}
+		if updated, errDel := sjson.DeleteBytes(rawJSON, "previous_response_id"); errDel == nil {
+			rawJSON = updated
+		}
+	}
+
+	if storeFlag := gjson.GetBytes(rawJSON, "store"); storeFlag.Exists() && !storeFlag.Bool() {
+		return rawJSON, nil, true
+	}
+	writer := &responseCaptureWriter{ResponseWriter: c.Writer}
+	c.Writer = writer
+	return rawJSON, &responseRecorder{store: h.store, writer: writer, owner: owner, previousID: previousID, input: input}, true
+}
+
+// loadResponseChain returns the stored response id together with the responses it continues,
+// oldest first. Responses created by another client key are reported as not found.
+func (h *OpenAIResponsesAPIHandler) loadResponseChain(ctx context.Context, owner, id string) ([]*responsestore.Record, error) {
+	var chain []*responsestore.Record
+	seen := make(map[string]struct{})
+	for id != "" {
+		if _, loop := seen[id]; loop {
+			return nil, fmt.Errorf("response %s continues itself", id)
+		}
+		seen[id] = struct{}{}
+		rec, err := h.loadOwnedResponse(ctx, owner, id)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, rec)
+		id = rec.PreviousID
+	}
+	for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+		chain[i], chain[j] = chain[j], chain[i]
+	}
+	return chain, nil
+}
+
+// loadOwnedResponse loads the stored response id if it was created by owner, the digest of a
+// client key (see handlers.RequestOwner).
+func (h *OpenAIResponsesAPIHandler) loadOwnedResponse(ctx context.Context, owner, id string) (*responsestore.Record, error) {
+	rec, err := h.store.Load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if rec.Owner != owner {
+		return nil, responsestore.ErrNotFound
+	}
+	return rec, nil
+}
+
+// save stores the captured response if the call succeeded.
+func (r *responseRecorder) save() {
+	if r == nil || r.writer.Status() >= http.StatusBadRequest {
+		return
+	}
+	response := r.writer.response()
+	id := gjson.GetBytes(response, "id").String()
+	if id == "" {
+		return
+	}
+	response, _ = sjson.SetBytes(response, "store", true)
+	if r.previousID != "" {
+		response, _ = sjson.SetBytes(response, "previous_response_id", r.previousID)
+	}
+
+	input := make([]json.RawMessage, 0, len(r.input))
+	for _, item := range r.input {
+		if !gjson.GetBytes(item, "id").Exists() {
+			item, _ = sjson.SetBytes(item, "id", "msg_"+strings.ReplaceAll(uuid.NewString(), "-", ""))
+		}
+		input = append(input, item)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), responseStoreSaveTimeout)
+	defer cancel()
+	rec := &responsestore.Record{ID: id, Owner: r.owner, PreviousID: r.previousID, Input: joinJSONArray(input), Response: response}
+	if err := r.store.Save(ctx, rec); err != nil {
+		log.Errorf("responses store: save %s: %v", id, err)
+	}
+}
+
+// responsesInputItems returns the items of a Responses input, turning a plain string into a
+// user message.
+func responsesInputItems(input gjson.Result) []json.RawMessage {
+	if input.Type == gjson.String {
+		item, _ := sjson.SetBytes([]byte(`{"type":"message","role":"user","content":[{"type":"input_text","text":""}]}`), "content.0.text", input.String())
+		return []json.RawMessage{item}
+	}
+	if !input.IsArray() {
+		return nil
+	}
+	var items []json.RawMessage
+	input.ForEach(func(_, item gjson.Result) bool {
+		items = append(items, json.RawMessage(item.Raw))
+		return true
+	})
+	return items
+}
+
+func joinJSONArray(items []json.RawMessage) []byte {
+	out := make([]byte, 0, 256)
+	out = append(out, '[')
+	for i, item := range items {
+		if i > 0 {
+			out = append(out, ',')
+		}
+		out = append(out, item...)
+	}
+	return append(out, ']')
+}
+
+// replayedResponseItems returns the turn stored in rec, its input followed by its output, as
+// input items for a follow-up request. Item ids are dropped because backends cannot resolve
+// them, and so are reasoning items that carry nothing the backend can replay.
+func replayedResponseItems(rec *responsestore.Record) []json.RawMessage {
+	var items []json.RawMessage
+	appendItem := func(_, item gjson.Result) bool {
+		if item.Get("type").String() == "reasoning" && item.Get("encrypted_content").String() == "" {
+			return true
+		}
+		raw := []byte(item.Raw)
+		if item.Get("id").Exists() {
+			raw, _ = sjson.DeleteBytes(raw, "id")
+		}
+		items = append(items, raw)
+		return true
+	}
+	gjson.ParseBytes(rec.Input).ForEach(appendItem)
+	gjson.GetBytes(rec.Response, "output").ForEach(appendItem)
+	return items
+}
+
+// responseCaptureWriter passes writes through to the client while keeping the final response
+// object: the body of a JSON response, or the response of the last response.completed or
+// response.incomplete event of a stream.
+type responseCaptureWriter struct {
+	gin.ResponseWriter
+	body      bytes.Buffer
+	pending   []byte
+	completed []byte
+}
+
+func (w *responseCaptureWriter) Write(data []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(data)
+	w.capture(data[:n])
+	return n, err
+}
+
+func (w *responseCaptureWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *responseCaptureWriter) capture(data []byte) {
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "text/event-stream") {
+		w.body.Write(data)
+		return
+	}
+	w.pending = append(w.pending, data...)
+	for {
+		idx := bytes.IndexByte(w.pending, '\n')
+		if idx < 0 {
+			return
+		}
+		w.captureLine(w.pending[:idx])
+		w.pending = w.pending[idx+1:]
+	}
+}
+
+func (w *responseCaptureWriter) captureLine(line []byte) {
+	line = bytes.TrimSpace(line)
+	if !bytes.HasPrefix(line, []byte("data:")) {
+		return
+	}
+	data := bytes.TrimSpace(line[len("data:"):])
+	switch gjson.GetBytes(data, "type").String() {
+	case "response.completed", "response.incomplete":
+		if response := gjson.GetBytes(data, "response"); response.IsObject() {
+			w.completed = []byte(response.Raw)
+		}
+	}
+}
+
+// response returns the captured response object or nil.
+func (w *responseCaptureWriter) response() []byte {
+	if len(w.pending) > 0 {
+		w.captureLine(w.pending)
+		w.pending = nil
+	}
+	if w.completed != nil {
+		return w.completed
+	}
+	body := bytes.TrimSpace(w.body.Bytes())
+	if gjson.GetBytes(body, "object").String() != "response" {
+		return nil
+	}
+	return bytes.Clone(body)
+}
+
+// GetResponse handles GET /v1/responses/{id}.
+func (h *OpenAIResponsesAPIHandler) GetResponse(c *gin.Context) {
+	rec, ok := h.loadStoredResponse(c)
+	if !ok {
+		return
+	}
+	if c.Query("stream") == "true" {
+		h.writeStoredResponseStream(c, rec)
+		return
+	}
+	c.Data(http.StatusOK, "application/json", rec.Response)
+}
+
+// DeleteResponse handles DELETE /v1/responses/{id}.
+func (h *OpenAIResponsesAPIHandler) DeleteResponse(c *gin.Context) {
+	if !h.requireResponseStore(c) {
+		return
+	}
+	id := c.Param("id")
+	owner := handlers.RequestOwner(c)
+	if _, err := h.loadOwnedResponse(c.Request.Context(), owner, id); err != nil {
+		h.writeStoredResponseError(c, id, err)
+		return
+	}
+	if err := h.store.Delete(c.Request.Context(), id); err != nil {
+		h.writeStoredResponseError(c, id, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"id": id, "object": "response.deleted", "deleted": true})
+}
+
+// ListResponseInputItems handles GET /v1/responses/{id}/input_items.
+func (h *OpenAIResponsesAPIHandler) ListResponseInputItems(c *gin.Context) {
+	rec, ok := h.loadStoredResponse(c)
+	if !ok {
+		return
+	}
+
+	limit := defaultInputItemsLimit
+	if raw := c.Query("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > maxInputItemsLimit {
+			c.JSON(http.StatusBadRequest, handlers.ErrorResponse{
+				Error: handlers.ErrorDetail{
+					Message: fmt.Sprintf("Invalid 'limit': expected an integer between 1 and %d.", maxInputItemsLimit),
+					Type:    "invalid_request_error",
+				},
+			})
+			return
+		}
+		limit = parsed
+	}
+	order := c.DefaultQuery("order", "desc")
+	if order != "asc" && order != "desc" {
+		c.JSON(http.StatusBadRequest, handlers.ErrorResponse{
+			Error: handlers.ErrorDetail{
+				Message: "Invalid 'order': expected 'asc' or 'desc'.",
+				Type:    "invalid_request_error",
+			},
+		})
+		return
+	}
+
+	// The input of a continued response includes the earlier turns of its conversation.
+	var items []gjson.Result
+	if rec.PreviousID != "" {
+		owner := handlers.RequestOwner(c)
+		chain, err := h.loadResponseChain(c.Request.Context(), owner, rec.PreviousID)
+		if err != nil {
+			h.writeStoredResponseError(c, rec.ID, err)
+			return
+		}
+		for _, earlier := range chain {
+			items = append(items, gjson.ParseBytes(earlier.Input).Array()...)
+			items = append(items, gjson.GetBytes(earlier.Response, "output").Array()...)
+		}
+	}
+	items = append(items, gjson.ParseBytes(rec.Input).Array()...)
+	if order == "desc" {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+	if after := c.Query("after"); after != "" {
+		for i, item := range items {
+			if item.Get("id").String() == after {
+				items = items[i+1:]
+				break
+			}
+		}
+	}
+	hasMore := len(items) > limit
+	if hasMore {
+		items = items[:limit]
+	}
+
+	out := []byte(`{"object":"list","data":[],"first_id":null,"last_id":null,"has_more":false}`)
+	for _, item := range items {
+		out, _ = sjson.SetRawBytes(out, "data.-1", []byte(item.Raw))
+	}
+	if len(items) > 0 {
+		out, _ = sjson.SetBytes(out, "first_id", items[0].Get("id").String())
+		out, _ = sjson.SetBytes(out, "last_id", items[len(items)-1].Get("id").String())
+	}
+	out, _ = sjson.SetBytes(out, "has_more", hasMore)
+	c.Data(http.StatusOK, "application/json", out)
+}
+
+// writeStoredResponseStream replays a stored response as a minimal event stream.
+func (h *OpenAIResponsesAPIHandler) writeStoredResponseStream(c *gin.Context, rec *responsestore.Record) {
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Status(http.StatusOK)
+	w := bufio.NewWriter(c.Writer)
+	for seq, eventType := range []string{"response.created", "response.completed"} {
+		event := []byte(`{"type":"","sequence_number":0}`)
+		event, _ = sjson.SetBytes(event, "type", eventType)
+		event, _ = sjson.SetBytes(event, "sequence_number", seq)
+		event, _ = sjson.SetRawBytes(event, "response", rec.Response)
+		_, _ = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", eventType, event)
+	}
+	_ = w.Flush()
+}
+
+func (h *OpenAIResponsesAPIHandler) loadStoredResponse(c *gin.Context) (*responsestore.Record, bool) {
+	if !h.requireResponseStore(c) {
+		return nil, false
+	}
+	id := c.Param("id")
+	owner := handlers.RequestOwner(c)
+	rec, err := h.loadOwnedResponse(c.Request.Context(), owner, id)
+	if err != nil {
+		h.writeStoredResponseError(c, id, err)
+		return nil, false
+	}
+	return rec, true
+}
+
+func (h *OpenAIResponsesAPIHandler) requireResponseStore(c *gin.Context) bool {
+	if h.store != nil {
+		return true
+	}
+	c.JSON(http.StatusNotFound, handlers.ErrorResponse{
+		Error: handlers.ErrorDetail{
+			Message: "Response storage is disabled; enable responses-store in the configuration.",
+			Type:    "invalid_request_error",
+		},
+	})
+	return false
+}
+
+func (h *OpenAIResponsesAPIHandler) writeStoredResponseError(c *gin.Context, id string, err error) {
+	if errors.Is(err, responsestore.ErrNotFound) {
+		c.JSON(http.StatusNotFound, handlers.ErrorResponse{
+			Error: handlers.ErrorDetail{
+				Message: fmt.Sprintf("Response with id '%s' not found.", id),
+				Type:    "invalid_request_error",
+			},
+		})
+		return
+	}
+	log.Errorf("responses store: %s: %v", id, err)
+	c.JSON(http.StatusInternalServerError, handlers.ErrorResponse{
+		Error: handlers.ErrorDetail{
+			Message: "Failed to access stored response",
+			Type:    "server_error",
+		},
+	})
+}
@@ -0,0 +1,208 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/responsestore"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/api/handlers"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	"github.com/tidwall/gjson"
+)
+
+type storedResponsesExecutor struct {
+	payloads [][]byte
+}
+
+func (e *storedResponsesExecutor) Identifier() string { return "test-store-provider" }
+
+func (e *storedResponsesExecutor) Execute(ctx context.Context, auth *coreauth.Auth, req coreexecutor.Request, opts coreexecutor.Options) (coreexecutor.Response, error) {
+	e.payloads = append(e.payloads, req.Payload)
+	n := len(e.payloads)
+	body := fmt.Sprintf(`{"id":"resp_%d","object":"response","status":"completed","output":[{"id":"msg_out_%d","type":"message","role":"assistant","content":[{"type":"output_text","text":"answer %d"}]},{"id":"rs_%d","type":"reasoning","summary":[]}]}`, n, n, n, n)
+	return coreexecutor.Response{Payload: []byte(body)}, nil
+}
+
+func (e *storedResponsesExecutor) ExecuteStream(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (*coreexecutor.StreamResult, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (e *storedResponsesExecutor) Refresh(ctx context.Context, auth *coreauth.Auth) (*coreauth.Auth, error) {
+	return auth, nil
+}
+
+func (e *storedResponsesExecutor) CountTokens(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (coreexecutor.Response, error) {
+	return coreexecutor.Response{}, errors.New("not implemented")
+}
+
+func (e *storedResponsesExecutor) HttpRequest(context.Context, *coreauth.Auth, *http.Request) (*http.Response, error) {
+	return nil, errors.New("not implemented")
+}
+
+func newStoredResponsesRouter(t *testing.T) (*gin.Engine, *storedResponsesExecutor, responsestore.Store) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	executor := &storedResponsesExecutor{}
+	manager := coreauth.NewManager(nil, nil, nil)
+	manager.RegisterExecutor(executor)
+
+	auth := &coreauth.Auth{ID: "store-auth", Provider: executor.Identifier(), Status: coreauth.StatusActive}
+	if _, err := manager.Register(context.Background(), auth); err != nil {
+		t.Fatalf("Register auth: %v", err)
+	}
+	registry.GetGlobalRegistry().RegisterClient(auth.ID, auth.Provider, []*registry.ModelInfo{{ID: "store-model"}})
+	t.Cleanup(func() {
+		registry.GetGlobalRegistry().UnregisterClient(auth.ID)
+	})
+
+	h := NewOpenAIResponsesAPIHandler(handlers.NewBaseAPIHandlers(&sdkconfig.SDKConfig{}, manager))
+	store := responsestore.NewMemoryStore(0)
+	h.SetResponseStore(store)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("apiKey", c.GetHeader("X-Test-Key"))
+	})
+	router.POST("/v1/responses", h.Responses)
+	router.GET("/v1/responses/:id", h.GetResponse)
+	router.DELETE("/v1/responses/:id", h.DeleteResponse)
+	router.GET("/v1/responses/:id/input_items", h.ListResponseInputItems)
+	return router, executor, store
+}
+
+func serveStoredResponses(router *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	return serveStoredResponsesAs(router, "", method, path, body)
+}
+
+func serveStoredResponsesAs(router *gin.Engine, key, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Test-Key", key)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	return resp
+}
+
+func TestResponsesStoreExpandsPreviousResponseID(t *testing.T) {
+	router, executor, _ := newStoredResponsesRouter(t)
+
+	first := serveStoredResponses(router, http.MethodPost, "/v1/responses", `{"model":"store-model","input":"first question"}`)
+	if first.Code != http.StatusOK {
+		t.Fatalf("first status = %d, body = %s", first.Code, first.Body.String())
+	}
+
+	second := serveStoredResponses(router, http.MethodPost, "/v1/responses", `{"model":"store-model","previous_response_id":"resp_1","input":[{"type":"message","role":"user","content":"second question"}]}`)
+	if second.Code != http.StatusOK {
+		t.Fatalf("second status = %d, body = %s", second.Code, second.Body.String())
+	}
+	payload := executor.payloads[1]
+	if gjson.GetBytes(payload, "previous_response_id").Exists() {
+		t.Fatalf("previous_response_id was forwarded upstream: %s", payload)
+	}
+	input := gjson.GetBytes(payload, "input").Array()
+	if len(input) != 3 {
+		t.Fatalf("expanded input has %d items, want 3: %s", len(input), payload)
+	}
+	if got := input[0].Get("content.0.text").String(); got != "first question" {
+		t.Fatalf("input[0] text = %q", got)
+	}
+	if input[1].Get("role").String() != "assistant" || input[1].Get("id").Exists() {
+		t.Fatalf("input[1] = %s, want assistant message without id", input[1].Raw)
+	}
+	if got := input[2].Get("content").String(); got != "second question" {
+		t.Fatalf("input[2] content = %q", got)
+	}
+
+	stored := serveStoredResponses(router, http.MethodGet, "/v1/responses/resp_2", "")
+	if stored.Code != http.StatusOK {
+		t.Fatalf("get status = %d", stored.Code)
+	}
+	if got := gjson.Get(stored.Body.String(), "previous_response_id").String(); got != "resp_1" {
+		t.Fatalf("stored previous_response_id = %q", got)
+	}
+
+	items := serveStoredResponses(router, http.MethodGet, "/v1/responses/resp_2/input_items?order=asc&limit=2", "")
+	if items.Code != http.StatusOK {
+		t.Fatalf("input_items status = %d", items.Code)
+	}
+	body := items.Body.String()
+	if n := len(gjson.Get(body, "data").Array()); n != 2 || !gjson.Get(body, "has_more").Bool() {
+		t.Fatalf("input_items = %s, want 2 items with has_more", body)
+	}
+	if gjson.Get(body, "first_id").String() == "" {
+		t.Fatalf("input_items first_id missing: %s", body)
+	}
+}
+
+func TestResponsesStoreHonoursStoreFalseAndDelete(t *testing.T) {
+	router, _, _ := newStoredResponsesRouter(t)
+
+	if resp := serveStoredResponses(router, http.MethodPost, "/v1/responses", `{"model":"store-model","input":"hi","store":false}`); resp.Code != http.StatusOK {
+		t.Fatalf("status = %d", resp.Code)
+	}
+	if resp := serveStoredResponses(router, http.MethodGet, "/v1/responses/resp_1", ""); resp.Code != http.StatusNotFound {
+		t.Fatalf("unstored response status = %d, want 404", resp.Code)
+	}
+
+	if resp := serveStoredResponses(router, http.MethodPost, "/v1/responses", `{"model":"store-model","input":"hi"}`); resp.Code != http.StatusOK {
+		t.Fatalf("status = %d", resp.Code)
+	}
+	deleted := serveStoredResponses(router, http.MethodDelete, "/v1/responses/resp_2", "")
+	if deleted.Code != http.StatusOK || !gjson.Get(deleted.Body.String(), "deleted").Bool() {
+		t.Fatalf("delete = %d %s", deleted.Code, deleted.Body.String())
+	}
+	missing := serveStoredResponses(router, http.MethodPost, "/v1/responses", `{"model":"store-model","input":"again","previous_response_id":"resp_2"}`)
+	if missing.Code != http.StatusBadRequest {
+		t.Fatalf("continuing a deleted response status = %d, want 400", missing.Code)
+	}
+}
+
+func TestResponsesStoreChainsTurnsPerOwner(t *testing.T) {
+	router, executor, store := newStoredResponsesRouter(t)
+
+	for i, body := range []string{
+		`{"model":"store-model","input":"first question"}`,
+		`{"model":"store-model","previous_response_id":"resp_1","input":"second question"}`,
+		`{"model":"store-model","previous_response_id":"resp_2","input":"third question"}`,
+	} {
+		if resp := serveStoredResponsesAs(router, "key-a", http.MethodPost, "/v1/responses", body); resp.Code != http.StatusOK {
+			t.Fatalf("turn %d status = %d, body = %s", i+1, resp.Code, resp.Body.String())
+		}
+	}
+	if n := len(gjson.GetBytes(executor.payloads[2], "input").Array()); n != 5 {
+		t.Fatalf("third turn sent %d input items, want the whole conversation of 5: %s", n, executor.payloads[2])
+	}
+	rec, err := store.Load(context.Background(), "resp_3")
+	if err != nil {
+		t.Fatalf("Load resp_3: %v", err)
+	}
+	if rec.Owner != internalconfig.ClientKeyDigest("key-a") || rec.PreviousID != "resp_2" || len(gjson.ParseBytes(rec.Input).Array()) != 1 {
+		t.Fatalf("stored record = %+v, want the key digest as owner and only its own input linked to resp_2", rec)
+	}
+	items := serveStoredResponsesAs(router, "key-a", http.MethodGet, "/v1/responses/resp_3/input_items?order=asc&limit=100", "")
+	if n := len(gjson.Get(items.Body.String(), "data").Array()); n != 7 {
+		t.Fatalf("input_items = %s, want the 7 items of the conversation", items.Body.String())
+	}
+
+	if resp := serveStoredResponsesAs(router, "key-b", http.MethodGet, "/v1/responses/resp_1", ""); resp.Code != http.StatusNotFound {
+		t.Fatalf("other key get status = %d, want 404", resp.Code)
+	}
+	if resp := serveStoredResponsesAs(router, "key-b", http.MethodDelete, "/v1/responses/resp_1", ""); resp.Code != http.StatusNotFound {
+		t.Fatalf("other key delete status = %d, want 404", resp.Code)
+	}
+	if resp := serveStoredResponsesAs(router, "key-b", http.MethodPost, "/v1/responses", `{"model":"store-model","previous_response_id":"resp_3","input":"steal"}`); resp.Code != http.StatusBadRequest {
+		t.Fatalf("other key continuation status = %d, want 400", resp.Code)
+	}
+	if resp := serveStoredResponsesAs(router, "key-a", http.MethodGet, "/v1/responses/resp_1", ""); resp.Code != http.StatusOK {
+		t.Fatalf("owner get status = %d after rejected access", resp.Code)
+	}
+}
@@ -15,6 +15,8 @@ import (
 	"sync"
 	"time"
 
+	baseauth "github.com/router-for-me/CLIProxyAPI/v6/internal/auth"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/tokencrypt"
 	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
 )
 
@@ -75,7 +77,7 @@ func (s *FileTokenStore) Save(ctx context.Context, auth *cliproxyauth.Auth) (str
 		if setter, ok := auth.Storage.(metadataSetter); ok {
 			setter.SetMetadata(auth.Metadata)
 		}
-		if err = auth.Storage.SaveTokenToFile(path); err != nil {
+		if err = baseauth.SaveSealedToken(auth.Storage, path); err != nil {
 			return "", err
 		}
 	case auth.Metadata != nil:
@@ -85,9 +87,12 @@ func (s *FileTokenStore) Save(ctx context.Context, auth *cliproxyauth.Auth) (str
 			return "", fmt.Errorf("auth filestore: marshal metadata failed: %w", errMarshal)
 		}
 		if existing, errRead := os.ReadFile(path); errRead == nil {
-			if jsonEqual(existing, raw) {
+			if plain, errOpen := tokencrypt.Open(existing); errOpen == nil && jsonEqual(plain, raw) && tokencrypt.UpToDate(existing) {
 				return path, nil
 			}
+			if raw, errMarshal = tokencrypt.Seal(raw); errMarshal != nil {
+				return "", fmt.Errorf("auth filestore: encrypt metadata failed: %w", errMarshal)
+			}
 			file, errOpen := os.OpenFile(path, os.O_WRONLY|os.O_TRUNC, 0o600)
 			if errOpen != nil {
 				return "", fmt.Errorf("auth filestore: open existing failed: %w", errOpen)
@@ -103,6 +108,9 @@ func (s *FileTokenStore) Save(ctx context.Context, auth *cliproxyauth.Auth) (str
 		} else if !os.IsNotExist(errRead) {
 			return "", fmt.Errorf("auth filestore: read existing failed: %w", errRead)
 		}
+		if raw, errMarshal = tokencrypt.Seal(raw); errMarshal != nil {
+			return "", fmt.Errorf("auth filestore: encrypt metadata failed: %w", errMarshal)
+		}
 		if errWrite := os.WriteFile(path, raw, 0o600); errWrite != nil {
 			return "", fmt.Errorf("auth filestore: write file failed: %w", errWrite)
 		}
@@ -154,6 +162,103 @@ func (s *FileTokenStore) List(ctx context.Context) ([]*cliproxyauth.Auth, error)
 	return entries, nil
 }
 
+// runtimeStateFileName and clientKeyUsageFileName are the side-car files holding cooldown state
+// and client key budget counters. They deliberately lack a .json suffix so the auth directory
+// scanners never mistake them for credentials.
+const (
+	runtimeStateFileName   = ".runtime-state"
+	clientKeyUsageFileName = ".client-key-usage"
+)
+
+// LoadRuntimeState reads the saved cooldown state from the auth directory.
+func (s *FileTokenStore) LoadRuntimeState(ctx context.Context) (map[string]*cliproxyauth.RuntimeState, error) {
+	states := make(map[string]*cliproxyauth.RuntimeState)
+	if found, err := s.readSideCar(runtimeStateFileName, &states); err != nil || !found {
+		return nil, err
+	}
+	return states, nil
+}
+
+// SaveRuntimeState atomically replaces the saved cooldown state in the auth directory.
+func (s *FileTokenStore) SaveRuntimeState(ctx context.Context, states map[string]*cliproxyauth.RuntimeState) error {
+	if len(states) == 0 {
+		return s.writeSideCar(runtimeStateFileName, nil)
+	}
+	return s.writeSideCar(runtimeStateFileName, states)
+}
+
+// LoadClientKeyUsage reads the saved client key budget counters from the auth directory.
+func (s *FileTokenStore) LoadClientKeyUsage(ctx context.Context) (map[string]*cliproxyauth.ClientKeyCounters, error) {
+	usage := make(map[string]*cliproxyauth.ClientKeyCounters)
+	if found, err := s.readSideCar(clientKeyUsageFileName, &usage); err != nil || !found {
+		return nil, err
+	}
+	return usage, nil
+}
+
+// SaveClientKeyUsage atomically replaces the saved client key budget counters in the auth directory.
+func (s *FileTokenStore) SaveClientKeyUsage(ctx context.Context, usage map[string]*cliproxyauth.ClientKeyCounters) error {
+	if len(usage) == 0 {
+		return s.writeSideCar(clientKeyUsageFileName, nil)
+	}
+	return s.writeSideCar(clientKeyUsageFileName, usage)
+}
+
+// readSideCar decodes the named side-car file into out and reports whether it exists.
+func (s *FileTokenStore) readSideCar(name string, out any) (bool, error) {
+	dir := s.baseDirSnapshot()
+	if dir == "" {
+		return false, nil
+	}
+	raw, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("auth filestore: read %s failed: %w", name, err)
+	}
+	if err = json.Unmarshal(raw, out); err != nil {
+		return false, fmt.Errorf("auth filestore: decode %s failed: %w", name, err)
+	}
+	return true, nil
+}
+
+// writeSideCar atomically replaces the named side-car file with value, or removes it when
+// value is nil.
+func (s *FileTokenStore) writeSideCar(name string, value any) error {
+	dir := s.baseDirSnapshot()
+	if dir == "" {
+		return nil
+	}
+	path := filepath.Join(dir, name)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if value == nil {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("auth filestore: remove %s failed: %w", name, err)
+		}
+		return nil
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("auth filestore: marshal %s failed: %w", name, err)
+	}
+	if err = os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("auth filestore: create dir failed: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err = os.WriteFile(tmp, raw, 0o600); err != nil {
+		return fmt.Errorf("auth filestore: write %s failed: %w", name, err)
+	}
+	if err = os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("auth filestore: replace %s failed: %w", name, err)
+	}
+	return nil
+}
+
 // Delete removes the auth file.
 func (s *FileTokenStore) Delete(ctx context.Context, id string) error {
 	id = strings.TrimSpace(id)
@@ -189,6 +294,9 @@ func (s *FileTokenStore) readAuthFile(path, baseDir string) (*cliproxyauth.Auth,
 	if len(data) == 0 {
 		return nil, nil
 	}
+	if data, err = tokencrypt.Open(data); err != nil {
+		return nil, err
+	}
 	metadata := make(map[string]any)
 	if err = json.Unmarshal(data, &metadata); err != nil {
 		return nil, fmt.Errorf("unmarshal auth json: %w", err)
@@ -218,6 +326,9 @@ func (s *FileTokenStore) readAuthFile(path, baseDir string) (*cliproxyauth.Auth,
 				if errFetch == nil && strings.TrimSpace(fetchedProjectID) != "" {
 					metadata["project_id"] = strings.TrimSpace(fetchedProjectID)
 					if raw, errMarshal := json.Marshal(metadata); errMarshal == nil {
+						if raw, errMarshal = tokencrypt.Seal(raw); errMarshal != nil {
+							return nil, fmt.Errorf("encrypt auth json: %w", errMarshal)
+						}
 						if file, errOpen := os.OpenFile(path, os.O_WRONLY|os.O_TRUNC, 0o600); errOpen == nil {
 							_, _ = file.Write(raw)
 							_ = file.Close()
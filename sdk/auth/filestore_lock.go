@@ -0,0 +1,120 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+	"time"
+
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+)
+
+// refreshLockPollInterval is how often a waiting replica checks whether a refresh lease was released.
+const refreshLockPollInterval = 250 * time.Millisecond
+
+// refreshLockHolder identifies this process in lease files.
+var refreshLockHolder = newRefreshLockHolder()
+
+func newRefreshLockHolder() string {
+	host, _ := os.Hostname()
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+	return host + "-" + strconv.Itoa(os.Getpid()) + "-" + hex.EncodeToString(suffix)
+}
+
+type refreshLease struct {
+	Holder    string    `json:"holder"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// LockRefresh takes the refresh lease of auth by exclusively creating "<auth file>.lock" next to
+// the auth file, so processes sharing the auth directory refresh one credential at a time.
+// An expired lease left behind by a crashed holder is broken.
+func (s *FileTokenStore) LockRefresh(ctx context.Context, auth *cliproxyauth.Auth, ttl time.Duration) (func(), error) {
+	path, err := s.resolveAuthPath(auth)
+	if err != nil {
+		return nil, err
+	}
+	return acquireFileLease(ctx, path+".lock", ttl)
+}
+
+// ReloadAuth re-reads auth from its file.
+func (s *FileTokenStore) ReloadAuth(_ context.Context, auth *cliproxyauth.Auth) (*cliproxyauth.Auth, error) {
+	path, err := s.resolveAuthPath(auth)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return s.readAuthFile(path, s.baseDirSnapshot())
+}
+
+func acquireFileLease(ctx context.Context, lockPath string, ttl time.Duration) (func(), error) {
+	for {
+		file, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if err == nil {
+			raw, _ := json.Marshal(refreshLease{Holder: refreshLockHolder, ExpiresAt: time.Now().Add(ttl)})
+			_, errWrite := file.Write(raw)
+			errClose := file.Close()
+			if errWrite != nil || errClose != nil {
+				_ = os.Remove(lockPath)
+				return nil, fmt.Errorf("auth filestore: write refresh lease: %w", errors.Join(errWrite, errClose))
+			}
+			return func() { releaseFileLease(lockPath) }, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, fmt.Errorf("auth filestore: create refresh lease: %w", err)
+		}
+		if fileLeaseExpired(lockPath, ttl) {
+			// Move the lease aside before deleting it. If another waiter already broke it and
+			// took a fresh lease in the meantime, put that lease back instead.
+			stale := lockPath + "." + refreshLockHolder + ".stale"
+			if errRename := os.Rename(lockPath, stale); errRename == nil {
+				if !fileLeaseExpired(stale, ttl) {
+					_ = os.Link(stale, lockPath)
+				}
+				_ = os.Remove(stale)
+			}
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(refreshLockPollInterval):
+		}
+	}
+}
+
+// fileLeaseExpired reports whether the lease at lockPath has lapsed. Unreadable leases count as
+// expired once they are older than ttl.
+func fileLeaseExpired(lockPath string, ttl time.Duration) bool {
+	raw, err := os.ReadFile(lockPath)
+	if err != nil {
+		return false
+	}
+	var lease refreshLease
+	if err = json.Unmarshal(raw, &lease); err == nil && !lease.ExpiresAt.IsZero() {
+		return time.Now().After(lease.ExpiresAt)
+	}
+	info, err := os.Stat(lockPath)
+	return err == nil && time.Since(info.ModTime()) > ttl
+}
+
+func releaseFileLease(lockPath string) {
+	raw, err := os.ReadFile(lockPath)
+	if err != nil {
+		return
+	}
+	var lease refreshLease
+	if err = json.Unmarshal(raw, &lease); err != nil || lease.Holder != refreshLockHolder {
+		return
+	}
+	_ = os.Remove(lockPath)
+}
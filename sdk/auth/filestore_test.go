@@ -1,6 +1,16 @@
 package auth
 
-import "testing"
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/tokencrypt"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+)
 
 func TestExtractAccessToken(t *testing.T) {
 	t.Parallel()
@@ -78,3 +88,188 @@ func TestExtractAccessToken(t *testing.T) {
 		})
 	}
 }
+
+func TestFileTokenStore_RuntimeStateRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	store := NewFileTokenStore()
+	store.SetBaseDir(dir)
+	ctx := context.Background()
+
+	retryAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	states := map[string]*cliproxyauth.RuntimeState{
+		"auth-a": {
+			ModelStates: map[string]*cliproxyauth.ModelState{
+				"model-x": {Unavailable: true, NextRetryAfter: retryAt, Quota: cliproxyauth.QuotaState{Exceeded: true, BackoffLevel: 3}},
+			},
+		},
+	}
+	if err := store.SaveRuntimeState(ctx, states); err != nil {
+		t.Fatalf("SaveRuntimeState() error = %v", err)
+	}
+	listed, err := store.List(ctx)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(listed) != 0 {
+		t.Fatalf("List() = %d auths, want the state file to be ignored", len(listed))
+	}
+
+	loaded, err := store.LoadRuntimeState(ctx)
+	if err != nil {
+		t.Fatalf("LoadRuntimeState() error = %v", err)
+	}
+	modelState := loaded["auth-a"].ModelStates["model-x"]
+	if modelState == nil || !modelState.NextRetryAfter.Equal(retryAt) || modelState.Quota.BackoffLevel != 3 {
+		t.Fatalf("LoadRuntimeState() model state = %+v, want retry %v and backoff 3", modelState, retryAt)
+	}
+
+	if err = store.SaveRuntimeState(ctx, nil); err != nil {
+		t.Fatalf("SaveRuntimeState(nil) error = %v", err)
+	}
+	if _, errStat := os.Stat(filepath.Join(dir, runtimeStateFileName)); !os.IsNotExist(errStat) {
+		t.Fatalf("state file still present after clearing: %v", errStat)
+	}
+}
+
+func TestFileTokenStore_EncryptsMetadataAtRest(t *testing.T) {
+	ring, err := tokencrypt.NewKeyring([]tokencrypt.Key{{ID: "test", Secret: []byte("0123456789abcdef0123456789abcdef")}}, true)
+	if err != nil {
+		t.Fatalf("NewKeyring() error = %v", err)
+	}
+	tokencrypt.SetDefault(ring)
+	t.Cleanup(func() { tokencrypt.SetDefault(nil) })
+
+	dir := t.TempDir()
+	store := NewFileTokenStore()
+	store.SetBaseDir(dir)
+	auth := &cliproxyauth.Auth{
+		ID:       "claude-user.json",
+		FileName: "claude-user.json",
+		Provider: "claude",
+		Metadata: map[string]any{"type": "claude", "email": "user@example.com", "refresh_token": "rt-secret"},
+	}
+	path, err := store.Save(context.Background(), auth)
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read saved file: %v", err)
+	}
+	if !tokencrypt.IsSealed(raw) || strings.Contains(string(raw), "rt-secret") {
+		t.Fatalf("saved file is not encrypted: %s", raw)
+	}
+
+	auths, err := store.List(context.Background())
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(auths) != 1 || auths[0].Metadata["refresh_token"] != "rt-secret" || auths[0].Label != "user@example.com" {
+		t.Fatalf("List() = %+v, want decrypted metadata", auths)
+	}
+}
+
+// plaintextTrapStorage fails the test if the store falls back to writing plaintext.
+type plaintextTrapStorage struct {
+	t    *testing.T
+	data string
+}
+
+func (s *plaintextTrapStorage) SaveTokenToFile(string) error {
+	s.t.Fatalf("SaveTokenToFile() called; token storage must be sealed before it is written")
+	return nil
+}
+
+func (s *plaintextTrapStorage) MarshalToken() ([]byte, error) {
+	return []byte(s.data), nil
+}
+
+func TestFileTokenStore_SealsTokenStorageBeforeWriting(t *testing.T) {
+	ring, err := tokencrypt.NewKeyring([]tokencrypt.Key{{ID: "test", Secret: []byte("0123456789abcdef0123456789abcdef")}}, true)
+	if err != nil {
+		t.Fatalf("NewKeyring() error = %v", err)
+	}
+	tokencrypt.SetDefault(ring)
+	t.Cleanup(func() { tokencrypt.SetDefault(nil) })
+
+	dir := t.TempDir()
+	store := NewFileTokenStore()
+	store.SetBaseDir(dir)
+	auth := &cliproxyauth.Auth{
+		ID:       "claude-user.json",
+		FileName: "claude-user.json",
+		Provider: "claude",
+		Storage:  &plaintextTrapStorage{t: t, data: `{"type":"claude","email":"user@example.com","refresh_token":"rt-secret"}`},
+	}
+	path, err := store.Save(context.Background(), auth)
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read saved file: %v", err)
+	}
+	if !tokencrypt.IsSealed(raw) || strings.Contains(string(raw), "rt-secret") {
+		t.Fatalf("saved file is not encrypted: %s", raw)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("read dir: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("auth dir has %d entries, want only the sealed file", len(entries))
+	}
+	plain, err := tokencrypt.ReadFile(path)
+	if err != nil || !strings.Contains(string(plain), "rt-secret") {
+		t.Fatalf("ReadFile() = %s, %v; want the decrypted token", plain, err)
+	}
+}
+
+func TestFileTokenStore_RefreshLeaseIsExclusive(t *testing.T) {
+	dir := t.TempDir()
+	store := NewFileTokenStore()
+	store.SetBaseDir(dir)
+	auth := &cliproxyauth.Auth{ID: "claude.json", FileName: "claude.json", Metadata: map[string]any{"type": "claude", "refresh_token": "rt-1"}}
+	if _, err := store.Save(context.Background(), auth); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	unlock, err := store.LockRefresh(context.Background(), auth, time.Minute)
+	if err != nil {
+		t.Fatalf("LockRefresh() error = %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	if _, err = store.LockRefresh(ctx, auth, time.Minute); err == nil {
+		t.Fatalf("second LockRefresh() succeeded while the lease was held")
+	}
+	unlock()
+	unlock2, err := store.LockRefresh(context.Background(), auth, time.Minute)
+	if err != nil {
+		t.Fatalf("LockRefresh() after release error = %v", err)
+	}
+	unlock2()
+
+	expired := `{"holder":"crashed","expires_at":"2000-01-01T00:00:00Z"}`
+	if err = os.WriteFile(filepath.Join(dir, "claude.json.lock"), []byte(expired), 0o600); err != nil {
+		t.Fatalf("write stale lease: %v", err)
+	}
+	unlock3, err := store.LockRefresh(context.Background(), auth, time.Minute)
+	if err != nil {
+		t.Fatalf("LockRefresh() over expired lease error = %v", err)
+	}
+	unlock3()
+	if _, err = os.Stat(filepath.Join(dir, "claude.json.lock")); !os.IsNotExist(err) {
+		t.Fatalf("lease file still present after unlock: %v", err)
+	}
+
+	if err = os.WriteFile(filepath.Join(dir, "claude.json"), []byte(`{"type":"claude","refresh_token":"rt-2"}`), 0o600); err != nil {
+		t.Fatalf("write auth: %v", err)
+	}
+	reloaded, err := store.ReloadAuth(context.Background(), auth)
+	if err != nil || reloaded == nil || reloaded.Metadata["refresh_token"] != "rt-2" {
+		t.Fatalf("ReloadAuth() = %+v, %v", reloaded, err)
+	}
+}
@@ -0,0 +1,329 @@
+package auth
+
+import (
+	"context"
+	"math/rand/v2"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+const (
+	// adaptiveDefaultAlpha is the EWMA smoothing factor applied to each new observation.
+	adaptiveDefaultAlpha = 0.2
+	// adaptiveDefaultExploreRate is the probability of picking a random ready auth so
+	// slow or failing credentials are periodically re-measured.
+	adaptiveDefaultExploreRate = 0.05
+	// adaptiveFailurePenaltyFloor bounds the success ratio used to scale latency costs.
+	adaptiveFailurePenaltyFloor = 0.05
+	// adaptiveFailurePenalty is added per unit of failure rate so fast failures never
+	// outrank slow successes.
+	adaptiveFailurePenalty = 30 * time.Second
+	// adaptiveMaxStats caps the number of tracked auth/model pairs.
+	adaptiveMaxStats = 8192
+	// adaptiveEvictBatch is how many of the least recently observed pairs are dropped once
+	// the cap is reached, so eviction cost is amortized over many observations.
+	adaptiveEvictBatch = adaptiveMaxStats / 8
+)
+
+// AdaptiveSelector prefers the healthiest ready credential at each priority level.
+// It keeps an exponentially weighted moving average of latency, time-to-first-byte
+// and failure rate per auth and model, fed from Manager.MarkResult.
+type AdaptiveSelector struct {
+	mu    sync.Mutex
+	stats map[string]*adaptiveStats
+	// observations counts observed results; each stats entry records the count of its last update.
+	observations uint64
+	// cursors rotates among equally scored (e.g. unmeasured) candidates.
+	cursors map[string]int
+
+	// Alpha overrides the EWMA smoothing factor; values outside (0,1] use the default.
+	Alpha float64
+	// ExploreRate overrides the random exploration probability; negative disables exploration.
+	ExploreRate float64
+}
+
+// adaptiveStats stores the moving averages tracked for one auth/model pair.
+type adaptiveStats struct {
+	latency     float64
+	firstByte   float64
+	failureRate float64
+	samples     int
+	// lastObserved orders entries for eviction, oldest first.
+	lastObserved uint64
+}
+
+// ResultObserver is implemented by selectors that learn from execution outcomes.
+type ResultObserver interface {
+	ObserveResult(result Result)
+}
+
+// NewAdaptiveSelector constructs an adaptive selector with default tuning.
+func NewAdaptiveSelector() *AdaptiveSelector {
+	return &AdaptiveSelector{
+		Alpha:       adaptiveDefaultAlpha,
+		ExploreRate: adaptiveDefaultExploreRate,
+	}
+}
+
+func adaptiveStatsKey(authID, model string) string {
+	return authID + "|" + canonicalModelKey(model)
+}
+
+func (s *AdaptiveSelector) alpha() float64 {
+	if s.Alpha <= 0 || s.Alpha > 1 {
+		return adaptiveDefaultAlpha
+	}
+	return s.Alpha
+}
+
+func (s *AdaptiveSelector) exploreRate() float64 {
+	if s.ExploreRate < 0 {
+		return 0
+	}
+	if s.ExploreRate == 0 {
+		return adaptiveDefaultExploreRate
+	}
+	return s.ExploreRate
+}
+
+// ObserveResult folds an execution outcome into the per-auth and per-model averages.
+func (s *AdaptiveSelector) ObserveResult(result Result) {
+	if s == nil || strings.TrimSpace(result.AuthID) == "" {
+		return
+	}
+	if !result.Success && !adaptiveCountsAsFailure(result.Error) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stats == nil {
+		s.stats = make(map[string]*adaptiveStats)
+	}
+	alpha := s.alpha()
+	s.observations++
+	keys := []string{adaptiveStatsKey(result.AuthID, "")}
+	if modelKey := canonicalModelKey(result.Model); modelKey != "" {
+		keys = append(keys, adaptiveStatsKey(result.AuthID, modelKey))
+	}
+	for _, key := range keys {
+		stats := s.stats[key]
+		if stats == nil {
+			if len(s.stats) >= adaptiveMaxStats {
+				s.evictOldestLocked(adaptiveEvictBatch)
+			}
+			stats = &adaptiveStats{}
+			s.stats[key] = stats
+		}
+		stats.lastObserved = s.observations
+		stats.observe(result, alpha)
+	}
+}
+
+// evictOldestLocked drops the count least recently observed stats entries.
+func (s *AdaptiveSelector) evictOldestLocked(count int) {
+	keys := make([]string, 0, len(s.stats))
+	for key := range s.stats {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return s.stats[keys[i]].lastObserved < s.stats[keys[j]].lastObserved })
+	for _, key := range keys[:min(count, len(keys))] {
+		delete(s.stats, key)
+	}
+}
+
+func (st *adaptiveStats) observe(result Result, alpha float64) {
+	failure := 0.0
+	if !result.Success {
+		failure = 1
+	}
+	if st.samples == 0 {
+		st.failureRate = failure
+	} else {
+		st.failureRate = ewma(st.failureRate, failure, alpha)
+	}
+	if result.Latency > 0 {
+		value := float64(result.Latency)
+		if st.latency == 0 {
+			st.latency = value
+		} else {
+			st.latency = ewma(st.latency, value, alpha)
+		}
+	}
+	if result.FirstByteLatency > 0 {
+		value := float64(result.FirstByteLatency)
+		if st.firstByte == 0 {
+			st.firstByte = value
+		} else {
+			st.firstByte = ewma(st.firstByte, value, alpha)
+		}
+	}
+	st.samples++
+}
+
+func ewma(previous, sample, alpha float64) float64 {
+	return previous + alpha*(sample-previous)
+}
+
+// measured reports whether at least one timed observation has been recorded.
+func (st *adaptiveStats) measured() bool {
+	return st != nil && st.samples > 0 && (st.latency > 0 || st.firstByte > 0)
+}
+
+// cost converts the tracked averages into a comparable score; lower is better.
+func (st *adaptiveStats) cost() float64 {
+	latency := st.latency
+	if st.firstByte > 0 {
+		if latency > 0 {
+			latency = (latency + st.firstByte) / 2
+		} else {
+			latency = st.firstByte
+		}
+	}
+	success := 1 - st.failureRate
+	if success < adaptiveFailurePenaltyFloor {
+		success = adaptiveFailurePenaltyFloor
+	}
+	return latency/success + st.failureRate*float64(adaptiveFailurePenalty)
+}
+
+// adaptiveCountsAsFailure reports whether a failed result reflects credential health.
+// Client-side request errors are ignored so bad payloads do not penalize healthy auths.
+func adaptiveCountsAsFailure(err *Error) bool {
+	if err == nil {
+		return true
+	}
+	status := err.StatusCode()
+	switch {
+	case status == 0:
+		return true
+	case status == http.StatusUnauthorized, status == http.StatusPaymentRequired, status == http.StatusForbidden:
+		return true
+	case status == http.StatusRequestTimeout, status == http.StatusTooManyRequests:
+		return true
+	case status >= http.StatusInternalServerError:
+		return true
+	default:
+		return false
+	}
+}
+
+// scoreLocked returns the cost for authID and model, falling back to auth-wide stats.
+// The second return value is false when the auth has never been observed.
+func (s *AdaptiveSelector) scoreLocked(authID, model string) (float64, bool) {
+	if s.stats == nil {
+		return 0, false
+	}
+	if modelKey := canonicalModelKey(model); modelKey != "" {
+		if stats := s.stats[adaptiveStatsKey(authID, modelKey)]; stats.measured() {
+			return stats.cost(), true
+		}
+	}
+	if stats := s.stats[adaptiveStatsKey(authID, "")]; stats.measured() {
+		return stats.cost(), true
+	}
+	return 0, false
+}
+
+// choose selects the best candidate among ids; ids must already be sorted for determinism.
+func (s *AdaptiveSelector) choose(cursorKey, model string, ids []string) int {
+	if len(ids) == 0 {
+		return -1
+	}
+	if len(ids) == 1 {
+		return 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if rate := s.exploreRate(); rate > 0 && rand.Float64() < rate {
+		return rand.IntN(len(ids))
+	}
+	// Unmeasured candidates are tried first so every credential gets a baseline.
+	unmeasured := make([]int, 0, len(ids))
+	best := -1
+	bestCost := 0.0
+	for i, id := range ids {
+		cost, ok := s.scoreLocked(id, model)
+		if !ok {
+			unmeasured = append(unmeasured, i)
+			continue
+		}
+		if best < 0 || cost < bestCost {
+			best = i
+			bestCost = cost
+		}
+	}
+	if len(unmeasured) > 0 {
+		if s.cursors == nil {
+			s.cursors = make(map[string]int)
+		}
+		if _, ok := s.cursors[cursorKey]; !ok && len(s.cursors) >= adaptiveMaxStats {
+			s.cursors = make(map[string]int)
+		}
+		cursor := s.cursors[cursorKey]
+		s.cursors[cursorKey] = cursor + 1
+		return unmeasured[cursor%len(unmeasured)]
+	}
+	return best
+}
+
+// Snapshot returns the current averages for an auth/model pair (model may be empty for
+// the auth-wide aggregate). The boolean is false when nothing has been recorded yet.
+func (s *AdaptiveSelector) Snapshot(authID, model string) (latency, firstByte time.Duration, failureRate float64, ok bool) {
+	if s == nil {
+		return 0, 0, 0, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stats == nil {
+		return 0, 0, 0, false
+	}
+	stats := s.stats[adaptiveStatsKey(authID, model)]
+	if stats == nil || stats.samples == 0 {
+		return 0, 0, 0, false
+	}
+	return time.Duration(stats.latency), time.Duration(stats.firstByte), stats.failureRate, true
+}
+
+// Pick selects the ready auth with the lowest latency/failure cost at the highest priority.
+func (s *AdaptiveSelector) Pick(ctx context.Context, provider, model string, opts cliproxyexecutor.Options, auths []*Auth) (*Auth, error) {
+	now := time.Now()
+	available, err := getAvailableAuths(auths, provider, model, now)
+	if err != nil {
+		return nil, err
+	}
+	available = preferCodexWebsocketAuths(ctx, provider, available)
+	// choose breaks ties by position, so candidates must be in a stable order.
+	sort.Slice(available, func(i, j int) bool { return available[i].ID < available[j].ID })
+	scoreModel := model
+	if scoreModel == "" {
+		scoreModel = requestedModelFromMetadata(opts.Metadata)
+	}
+	ids := make([]string, len(available))
+	for i, candidate := range available {
+		ids[i] = candidate.ID
+	}
+	index := s.choose(provider+":"+canonicalModelKey(scoreModel), scoreModel, ids)
+	if index < 0 {
+		return nil, &Error{Code: "auth_not_found", Message: "no auth available"}
+	}
+	return available[index], nil
+}
+
+func requestedModelFromMetadata(meta map[string]any) string {
+	if len(meta) == 0 {
+		return ""
+	}
+	switch v := meta[cliproxyexecutor.RequestedModelMetadataKey].(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case []byte:
+		return strings.TrimSpace(string(v))
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,179 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+func newTestAdaptiveSelector() *AdaptiveSelector {
+	return &AdaptiveSelector{Alpha: 0.5, ExploreRate: -1}
+}
+
+func TestAdaptiveSelectorPick_TriesUnmeasuredFirst(t *testing.T) {
+	t.Parallel()
+
+	selector := newTestAdaptiveSelector()
+	selector.ObserveResult(Result{AuthID: "a", Model: "m", Success: true, Latency: 100 * time.Millisecond})
+	auths := []*Auth{{ID: "a"}, {ID: "b"}}
+
+	got, err := selector.Pick(context.Background(), "gemini", "m", cliproxyexecutor.Options{}, auths)
+	if err != nil {
+		t.Fatalf("Pick() error = %v", err)
+	}
+	if got.ID != "b" {
+		t.Fatalf("Pick() auth.ID = %q, want %q", got.ID, "b")
+	}
+}
+
+func TestAdaptiveSelectorPick_PrefersLowerLatency(t *testing.T) {
+	t.Parallel()
+
+	selector := newTestAdaptiveSelector()
+	selector.ObserveResult(Result{AuthID: "slow", Model: "m", Success: true, Latency: 4 * time.Second})
+	selector.ObserveResult(Result{AuthID: "fast", Model: "m", Success: true, Latency: 500 * time.Millisecond})
+	auths := []*Auth{{ID: "slow"}, {ID: "fast"}}
+
+	for i := 0; i < 3; i++ {
+		got, err := selector.Pick(context.Background(), "gemini", "m", cliproxyexecutor.Options{}, auths)
+		if err != nil {
+			t.Fatalf("Pick() #%d error = %v", i, err)
+		}
+		if got.ID != "fast" {
+			t.Fatalf("Pick() #%d auth.ID = %q, want %q", i, got.ID, "fast")
+		}
+	}
+}
+
+func TestAdaptiveSelectorPick_PenalizesFailures(t *testing.T) {
+	t.Parallel()
+
+	selector := newTestAdaptiveSelector()
+	selector.ObserveResult(Result{AuthID: "flaky", Model: "m", Success: false, Latency: 50 * time.Millisecond, Error: &Error{HTTPStatus: http.StatusBadGateway}})
+	selector.ObserveResult(Result{AuthID: "steady", Model: "m", Success: true, Latency: 3 * time.Second})
+	auths := []*Auth{{ID: "flaky"}, {ID: "steady"}}
+
+	got, err := selector.Pick(context.Background(), "gemini", "m", cliproxyexecutor.Options{}, auths)
+	if err != nil {
+		t.Fatalf("Pick() error = %v", err)
+	}
+	if got.ID != "steady" {
+		t.Fatalf("Pick() auth.ID = %q, want %q", got.ID, "steady")
+	}
+}
+
+func TestAdaptiveSelectorObserveResult_EvictsOldestStatsAtCap(t *testing.T) {
+	t.Parallel()
+
+	selector := newTestAdaptiveSelector()
+	selector.ObserveResult(Result{AuthID: "hot", Success: true, Latency: time.Second})
+	for i := 0; len(selector.stats) < adaptiveMaxStats; i++ {
+		selector.ObserveResult(Result{AuthID: fmt.Sprintf("cold-%d", i), Success: true, Latency: time.Second})
+	}
+	// Refreshing the first entry keeps it out of the evicted batch.
+	selector.ObserveResult(Result{AuthID: "hot", Success: true, Latency: time.Second})
+	selector.ObserveResult(Result{AuthID: "new", Success: true, Latency: time.Second})
+
+	if got, want := len(selector.stats), adaptiveMaxStats-adaptiveEvictBatch+1; got != want {
+		t.Fatalf("tracked stats = %d, want %d", got, want)
+	}
+	for _, id := range []string{"hot", "new"} {
+		if _, _, _, ok := selector.Snapshot(id, ""); !ok {
+			t.Fatalf("Snapshot(%s) missing after eviction", id)
+		}
+	}
+	if _, _, _, ok := selector.Snapshot("cold-0", ""); ok {
+		t.Fatalf("Snapshot(cold-0) kept, want the oldest entries evicted")
+	}
+}
+
+func TestAdaptiveSelectorObserveResult_IgnoresClientErrors(t *testing.T) {
+	t.Parallel()
+
+	selector := newTestAdaptiveSelector()
+	selector.ObserveResult(Result{AuthID: "a", Model: "m", Success: false, Latency: time.Second, Error: &Error{HTTPStatus: http.StatusBadRequest}})
+	if _, _, _, ok := selector.Snapshot("a", "m"); ok {
+		t.Fatalf("Snapshot() ok = true, want false for client error")
+	}
+
+	selector.ObserveResult(Result{AuthID: "a", Model: "m", Success: true, Latency: time.Second, FirstByteLatency: 200 * time.Millisecond})
+	latency, firstByte, failureRate, ok := selector.Snapshot("a", "m")
+	if !ok {
+		t.Fatalf("Snapshot() ok = false, want true")
+	}
+	if latency != time.Second || firstByte != 200*time.Millisecond || failureRate != 0 {
+		t.Fatalf("Snapshot() = (%v, %v, %v), want (1s, 200ms, 0)", latency, firstByte, failureRate)
+	}
+}
+
+func TestSchedulerPick_AdaptivePrefersHealthiestAtHighestPriority(t *testing.T) {
+	t.Parallel()
+
+	selector := newTestAdaptiveSelector()
+	selector.ObserveResult(Result{AuthID: "high-slow", Success: true, Latency: 5 * time.Second})
+	selector.ObserveResult(Result{AuthID: "high-fast", Success: true, Latency: 200 * time.Millisecond})
+	selector.ObserveResult(Result{AuthID: "low-fastest", Success: true, Latency: 10 * time.Millisecond})
+
+	scheduler := newSchedulerForTest(
+		selector,
+		&Auth{ID: "low-fastest", Provider: "gemini", Attributes: map[string]string{"priority": "0"}},
+		&Auth{ID: "high-slow", Provider: "gemini", Attributes: map[string]string{"priority": "10"}},
+		&Auth{ID: "high-fast", Provider: "gemini", Attributes: map[string]string{"priority": "10"}},
+	)
+
+	for index := 0; index < 3; index++ {
+		got, errPick := scheduler.pickSingle(context.Background(), "gemini", "", cliproxyexecutor.Options{}, nil)
+		if errPick != nil {
+			t.Fatalf("pickSingle() #%d error = %v", index, errPick)
+		}
+		if got == nil || got.ID != "high-fast" {
+			t.Fatalf("pickSingle() #%d auth = %v, want high-fast", index, got)
+		}
+	}
+}
+
+func TestSchedulerPickMixed_AdaptiveComparesAcrossProviders(t *testing.T) {
+	t.Parallel()
+
+	selector := newTestAdaptiveSelector()
+	selector.ObserveResult(Result{AuthID: "gemini-a", Success: true, Latency: 2 * time.Second})
+	selector.ObserveResult(Result{AuthID: "claude-a", Success: true, Latency: 300 * time.Millisecond})
+
+	scheduler := newSchedulerForTest(
+		selector,
+		&Auth{ID: "gemini-a", Provider: "gemini"},
+		&Auth{ID: "claude-a", Provider: "claude"},
+	)
+
+	got, provider, errPick := scheduler.pickMixed(context.Background(), []string{"gemini", "claude"}, "", cliproxyexecutor.Options{}, nil)
+	if errPick != nil {
+		t.Fatalf("pickMixed() error = %v", errPick)
+	}
+	if got == nil || got.ID != "claude-a" || provider != "claude" {
+		t.Fatalf("pickMixed() = (%v, %q), want (claude-a, claude)", got, provider)
+	}
+}
+
+func TestManagerMarkResult_FeedsAdaptiveSelector(t *testing.T) {
+	t.Parallel()
+
+	selector := newTestAdaptiveSelector()
+	manager := NewManager(nil, selector, nil)
+	if _, err := manager.Register(context.Background(), &Auth{ID: "a", Provider: "gemini"}); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	manager.MarkResult(context.Background(), Result{AuthID: "a", Provider: "gemini", Model: "m", Success: true, Latency: 750 * time.Millisecond})
+
+	latency, _, _, ok := selector.Snapshot("a", "m")
+	if !ok {
+		t.Fatalf("Snapshot() ok = false, want true")
+	}
+	if latency != 750*time.Millisecond {
+		t.Fatalf("Snapshot() latency = %v, want %v", latency, 750*time.Millisecond)
+	}
+}
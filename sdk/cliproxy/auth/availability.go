@@ -0,0 +1,262 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	log "github.com/sirupsen/logrus"
+)
+
+const minutesPerDay = 24 * 60
+
+// availabilitySchedule is an availability attribute parsed for evaluation.
+type availabilitySchedule struct {
+	location *time.Location
+	windows  []availabilityWindow
+	// reserve is the share of the upstream rate-limit window kept for other users, 0-1.
+	reserve float64
+}
+
+// availabilityWindow is one recurring window; end <= start means it runs past midnight.
+type availabilityWindow struct {
+	days  [7]bool
+	start int
+	end   int
+}
+
+// availabilityCache memoises parsed schedules by their raw attribute value.
+var availabilityCache sync.Map
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// authAvailability returns the parsed availability schedule of auth, or nil when it has none.
+// Invalid schedules are logged once and ignored.
+func authAvailability(auth *Auth) *availabilitySchedule {
+	if auth == nil || auth.Attributes == nil {
+		return nil
+	}
+	raw := strings.TrimSpace(auth.Attributes["availability"])
+	if raw == "" {
+		return nil
+	}
+	if cached, ok := availabilityCache.Load(raw); ok {
+		schedule, _ := cached.(*availabilitySchedule)
+		return schedule
+	}
+	schedule, err := parseAvailabilitySchedule(raw)
+	if err != nil {
+		log.Warnf("auth %s: ignoring invalid availability schedule: %v", auth.ID, err)
+		schedule = nil
+	}
+	availabilityCache.Store(raw, schedule)
+	return schedule
+}
+
+func parseAvailabilitySchedule(raw string) (*availabilitySchedule, error) {
+	var cfg internalconfig.AvailabilitySchedule
+	if err := json.Unmarshal([]byte(raw), &cfg); err != nil {
+		return nil, err
+	}
+	return compileAvailabilitySchedule(&cfg)
+}
+
+// ValidateAvailabilitySchedule reports whether cfg can be evaluated by the scheduler.
+func ValidateAvailabilitySchedule(cfg *internalconfig.AvailabilitySchedule) error {
+	_, err := compileAvailabilitySchedule(cfg)
+	return err
+}
+
+func compileAvailabilitySchedule(cfg *internalconfig.AvailabilitySchedule) (*availabilitySchedule, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	schedule := &availabilitySchedule{location: time.UTC}
+	if zone := strings.TrimSpace(cfg.Timezone); zone != "" {
+		location, err := time.LoadLocation(zone)
+		if err != nil {
+			return nil, fmt.Errorf("timezone %q: %w", zone, err)
+		}
+		schedule.location = location
+	}
+	if cfg.IsZero() {
+		return nil, nil
+	}
+	for index, window := range cfg.Windows {
+		days, err := parseWeekdays(window.Days)
+		if err != nil {
+			return nil, fmt.Errorf("window %d: %w", index, err)
+		}
+		start, err := parseClock(window.Start, 0)
+		if err != nil {
+			return nil, fmt.Errorf("window %d start: %w", index, err)
+		}
+		end, err := parseClock(window.End, minutesPerDay)
+		if err != nil {
+			return nil, fmt.Errorf("window %d end: %w", index, err)
+		}
+		schedule.windows = append(schedule.windows, availabilityWindow{days: days, start: start, end: end})
+	}
+	if cfg.ReservePercent > 0 {
+		schedule.reserve = min(float64(cfg.ReservePercent), 100) / 100
+	}
+	return schedule, nil
+}
+
+// parseWeekdays parses day lists such as "mon-fri", "sat,sun" or "*".
+func parseWeekdays(spec string) ([7]bool, error) {
+	var days [7]bool
+	spec = strings.ToLower(strings.TrimSpace(spec))
+	if spec == "" || spec == "*" {
+		for index := range days {
+			days[index] = true
+		}
+		return days, nil
+	}
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		from, to, isRange := strings.Cut(part, "-")
+		first, ok := weekdayNames[strings.TrimSpace(from)]
+		if !ok {
+			return days, fmt.Errorf("unknown weekday %q", from)
+		}
+		last := first
+		if isRange {
+			if last, ok = weekdayNames[strings.TrimSpace(to)]; !ok {
+				return days, fmt.Errorf("unknown weekday %q", to)
+			}
+		}
+		for day := first; ; day = (day + 1) % 7 {
+			days[day] = true
+			if day == last {
+				break
+			}
+		}
+	}
+	return days, nil
+}
+
+// parseClock parses HH:MM into minutes since midnight; 24:00 is allowed as an end of day.
+func parseClock(raw string, fallback int) (int, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return fallback, nil
+	}
+	hourPart, minutePart, ok := strings.Cut(raw, ":")
+	if !ok {
+		return 0, fmt.Errorf("invalid time %q, want HH:MM", raw)
+	}
+	hour, errHour := strconv.Atoi(hourPart)
+	minute, errMinute := strconv.Atoi(minutePart)
+	if errHour != nil || errMinute != nil || hour < 0 || minute < 0 || minute > 59 || hour*60+minute > minutesPerDay {
+		return 0, fmt.Errorf("invalid time %q, want HH:MM", raw)
+	}
+	return hour*60 + minute, nil
+}
+
+// openAt reports whether any window covers t. Schedules without windows are always open.
+func (s *availabilitySchedule) openAt(t time.Time) bool {
+	if s == nil || len(s.windows) == 0 {
+		return true
+	}
+	local := t.In(s.location)
+	minute := local.Hour()*60 + local.Minute()
+	today := local.Weekday()
+	yesterday := (today + 6) % 7
+	for _, window := range s.windows {
+		if window.start < window.end {
+			if window.days[today] && minute >= window.start && minute < window.end {
+				return true
+			}
+			continue
+		}
+		if window.days[today] && minute >= window.start {
+			return true
+		}
+		if window.days[yesterday] && minute < window.end {
+			return true
+		}
+	}
+	return false
+}
+
+// nextChange returns the first window boundary after now at which openAt flips, or zero when
+// the schedule never changes.
+func (s *availabilitySchedule) nextChange(now time.Time) time.Time {
+	if s == nil || len(s.windows) == 0 {
+		return time.Time{}
+	}
+	local := now.In(s.location)
+	year, month, day := local.Date()
+	candidates := make([]time.Time, 0, 8*2*len(s.windows))
+	for offset := 0; offset <= 8; offset++ {
+		for _, window := range s.windows {
+			for _, boundary := range []int{window.start, window.end} {
+				at := time.Date(year, month, day+offset, 0, boundary, 0, 0, s.location)
+				if at.After(now) {
+					candidates = append(candidates, at)
+				}
+			}
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].Before(candidates[j]) })
+	current := s.openAt(now)
+	for _, candidate := range candidates {
+		if s.openAt(candidate) != current {
+			return candidate
+		}
+	}
+	return time.Time{}
+}
+
+// availabilityBlocked reports whether auth's schedule keeps it out of rotation for model at
+// now, and when that may change.
+func availabilityBlocked(auth *Auth, model string, now time.Time) (bool, time.Time) {
+	schedule := authAvailability(auth)
+	if schedule == nil {
+		return false, time.Time{}
+	}
+	if !schedule.openAt(now) {
+		return true, schedule.nextChange(now)
+	}
+	if schedule.reserve > 0 {
+		if rateLimit := rateLimitStateFor(auth, model); rateLimit.below(schedule.reserve, now) {
+			return true, rateLimit.expiresAt()
+		}
+	}
+	return false, time.Time{}
+}
+
+// newOutsideAvailabilityWindowError reports that every credential for model is held back by
+// its availability schedule until opensAt (zero when unknown). Callers tell it apart from a
+// cooldown by its code: the request is not queued, sent to a fallback model or retried.
+func newOutsideAvailabilityWindowError(model string, opensAt, now time.Time) *Error {
+	if model == "" {
+		model = "requested model"
+	}
+	message := fmt.Sprintf("All credentials for model %s are outside their availability window", model)
+	if !opensAt.IsZero() {
+		message = fmt.Sprintf("%s; the next window opens in %s", message, opensAt.Sub(now).Round(time.Second))
+	}
+	return &Error{Code: "outside_availability_window", Message: message, HTTPStatus: http.StatusServiceUnavailable}
+}
+
+// AuthAvailability reports whether the availability schedule of auth permits use at now and
+// when that next changes; changeAt is zero when it never does. Auths without a schedule are
+// always available.
+func AuthAvailability(auth *Auth, now time.Time) (available bool, changeAt time.Time) {
+	blocked, next := availabilityBlocked(auth, "", now)
+	if blocked {
+		return false, next
+	}
+	return true, authAvailability(auth).nextChange(now)
+}
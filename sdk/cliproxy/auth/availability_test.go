@@ -0,0 +1,207 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+func availabilityAttribute(t *testing.T, schedule internalconfig.AvailabilitySchedule) string {
+	t.Helper()
+	raw, err := json.Marshal(schedule)
+	if err != nil {
+		t.Fatalf("marshal availability: %v", err)
+	}
+	return string(raw)
+}
+
+func TestAvailabilitySchedule_OvernightWindowInTimezone(t *testing.T) {
+	t.Parallel()
+
+	schedule, err := parseAvailabilitySchedule(`{"timezone":"Europe/Berlin","windows":[{"days":"mon-fri","start":"18:00","end":"08:00"}]}`)
+	if err != nil {
+		t.Fatalf("parseAvailabilitySchedule() error = %v", err)
+	}
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("timezone data unavailable: %v", err)
+	}
+
+	cases := []struct {
+		name string
+		at   time.Time
+		want bool
+	}{
+		{"friday evening", time.Date(2025, 6, 13, 19, 0, 0, 0, berlin), true},
+		{"saturday early morning", time.Date(2025, 6, 14, 7, 59, 0, 0, berlin), true},
+		{"saturday evening", time.Date(2025, 6, 14, 19, 0, 0, 0, berlin), false},
+		{"monday noon", time.Date(2025, 6, 16, 12, 0, 0, 0, berlin), false},
+		{"monday noon in utc", time.Date(2025, 6, 16, 16, 30, 0, 0, time.UTC), true},
+	}
+	for _, tc := range cases {
+		if got := schedule.openAt(tc.at); got != tc.want {
+			t.Errorf("openAt(%s) = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+
+	next := schedule.nextChange(time.Date(2025, 6, 14, 7, 0, 0, 0, berlin))
+	if want := time.Date(2025, 6, 14, 8, 0, 0, 0, berlin); !next.Equal(want) {
+		t.Fatalf("nextChange() = %v, want %v", next, want)
+	}
+	next = schedule.nextChange(time.Date(2025, 6, 14, 12, 0, 0, 0, berlin))
+	if want := time.Date(2025, 6, 16, 18, 0, 0, 0, berlin); !next.Equal(want) {
+		t.Fatalf("nextChange() over weekend = %v, want %v", next, want)
+	}
+}
+
+func TestAvailabilitySchedule_RejectsInvalidInput(t *testing.T) {
+	t.Parallel()
+
+	invalid := []internalconfig.AvailabilitySchedule{
+		{Timezone: "Mars/Olympus"},
+		{Windows: []internalconfig.AvailabilityWindow{{Days: "funday"}}},
+		{Windows: []internalconfig.AvailabilityWindow{{Start: "25:00"}}},
+		{Windows: []internalconfig.AvailabilityWindow{{End: "8pm"}}},
+	}
+	for index, schedule := range invalid {
+		if err := ValidateAvailabilitySchedule(&schedule); err == nil {
+			t.Errorf("ValidateAvailabilitySchedule(#%d) error = nil, want error", index)
+		}
+	}
+}
+
+func TestAvailabilityBlocked_ReservePercent(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	auth := &Auth{
+		ID:         "shared",
+		Provider:   "claude",
+		Attributes: map[string]string{"availability": availabilityAttribute(t, internalconfig.AvailabilitySchedule{ReservePercent: 20})},
+		Quota:      QuotaState{RateLimit: &RateLimitState{Remaining: 0.5, ResetAt: now.Add(time.Hour), ObservedAt: now}},
+	}
+	if blocked, _ := availabilityBlocked(auth, "", now); blocked {
+		t.Fatalf("availabilityBlocked() with 50%% left = true, want false")
+	}
+
+	auth.Quota.RateLimit.Remaining = 0.1
+	blocked, next := availabilityBlocked(auth, "", now)
+	if !blocked {
+		t.Fatalf("availabilityBlocked() with 10%% left = false, want true")
+	}
+	if !next.Equal(auth.Quota.RateLimit.ResetAt) {
+		t.Fatalf("availabilityBlocked() next = %v, want window reset %v", next, auth.Quota.RateLimit.ResetAt)
+	}
+	if blocked, _ := availabilityBlocked(auth, "", now.Add(2*time.Hour)); blocked {
+		t.Fatalf("availabilityBlocked() after reset = true, want false")
+	}
+}
+
+func TestSchedulerPick_SkipsAuthOutsideAvailabilityWindow(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now().UTC()
+	closed := internalconfig.AvailabilitySchedule{Windows: []internalconfig.AvailabilityWindow{{
+		Start: now.Add(2 * time.Hour).Format("15:04"),
+		End:   now.Add(4 * time.Hour).Format("15:04"),
+	}}}
+	scheduler := newSchedulerForTest(
+		&RoundRobinSelector{},
+		&Auth{ID: "night", Provider: "gemini", Attributes: map[string]string{"priority": "1", "availability": availabilityAttribute(t, closed)}},
+		&Auth{ID: "always", Provider: "gemini"},
+	)
+
+	for index := 0; index < 3; index++ {
+		got, errPick := scheduler.pickSingle(context.Background(), "gemini", "", cliproxyexecutor.Options{}, nil)
+		if errPick != nil {
+			t.Fatalf("pickSingle() #%d error = %v", index, errPick)
+		}
+		if got == nil || got.ID != "always" {
+			t.Fatalf("pickSingle() #%d auth = %v, want always", index, got)
+		}
+	}
+}
+
+func TestSchedulerPick_AllAuthsOutsideWindowReportsWindowError(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now().UTC()
+	closed := internalconfig.AvailabilitySchedule{Windows: []internalconfig.AvailabilityWindow{{
+		Start: now.Add(2 * time.Hour).Format("15:04"),
+		End:   now.Add(4 * time.Hour).Format("15:04"),
+	}}}
+	model := "gemini-window-closed"
+	registerSchedulerModels(t, "gemini", model, "night", "cooling")
+	scheduler := newSchedulerForTest(
+		&RoundRobinSelector{},
+		&Auth{ID: "night", Provider: "gemini", Attributes: map[string]string{"availability": availabilityAttribute(t, closed)}},
+	)
+
+	_, errPick := scheduler.pickSingle(context.Background(), "gemini", model, cliproxyexecutor.Options{}, nil)
+	var authErr *Error
+	if !errors.As(errPick, &authErr) {
+		t.Fatalf("pickSingle() error = %v, want *Error", errPick)
+	}
+	if authErr.Code != "outside_availability_window" || authErr.StatusCode() != http.StatusServiceUnavailable {
+		t.Fatalf("pickSingle() error = %s/%d, want outside_availability_window/503", authErr.Code, authErr.StatusCode())
+	}
+	if authErr.Retryable {
+		t.Fatalf("outside window error is retryable, want not retryable")
+	}
+
+	scheduler.upsertAuth(&Auth{
+		ID:       "cooling",
+		Provider: "gemini",
+		ModelStates: map[string]*ModelState{
+			model: {Unavailable: true, NextRetryAfter: now.Add(time.Minute), Quota: QuotaState{Exceeded: true}},
+		},
+	})
+	_, errPick = scheduler.pickSingle(context.Background(), "gemini", model, cliproxyexecutor.Options{}, nil)
+	var cooldownErr *modelCooldownError
+	if !errors.As(errPick, &cooldownErr) {
+		t.Fatalf("pickSingle() with a cooling auth error = %v, want model cooldown", errPick)
+	}
+}
+
+func TestSchedulerPromoteExpired_DemotesAuthWhenWindowCloses(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now().UTC()
+	open := internalconfig.AvailabilitySchedule{Windows: []internalconfig.AvailabilityWindow{{
+		Start: now.Add(-time.Hour).Format("15:04"),
+		End:   now.Add(time.Hour).Format("15:04"),
+	}}}
+	scheduler := newSchedulerForTest(
+		&RoundRobinSelector{},
+		&Auth{ID: "daytime", Provider: "gemini", Attributes: map[string]string{"availability": availabilityAttribute(t, open)}},
+	)
+
+	got, errPick := scheduler.pickSingle(context.Background(), "gemini", "", cliproxyexecutor.Options{}, nil)
+	if errPick != nil || got == nil || got.ID != "daytime" {
+		t.Fatalf("pickSingle() inside window = %v, %v; want daytime", got, errPick)
+	}
+
+	scheduler.mu.Lock()
+	shard := scheduler.providers["gemini"].ensureModelLocked("", now)
+	if shard.nextWindowClose.IsZero() {
+		scheduler.mu.Unlock()
+		t.Fatalf("nextWindowClose is zero, want window end")
+	}
+	shard.promoteExpiredLocked(now.Add(2 * time.Hour))
+	entry := shard.entries["daytime"]
+	state, retryAt := entry.state, entry.nextRetryAt
+	scheduler.mu.Unlock()
+
+	if state != scheduledStateOutsideWindow {
+		t.Fatalf("entry state after window closed = %v, want outside window", state)
+	}
+	if !retryAt.After(now.Add(2 * time.Hour)) {
+		t.Fatalf("entry nextRetryAt = %v, want next window opening", retryAt)
+	}
+}
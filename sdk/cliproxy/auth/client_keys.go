@@ -0,0 +1,257 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+)
+
+// ClientKeyUsage reports what a managed client key has consumed in the current UTC day and month.
+type ClientKeyUsage struct {
+	DailyRequests   int64 `json:"daily_requests"`
+	MonthlyRequests int64 `json:"monthly_requests"`
+	DailyTokens     int64 `json:"daily_tokens"`
+	MonthlyTokens   int64 `json:"monthly_tokens"`
+}
+
+// ClientKeyCounters is the usage of one client key together with the UTC day and month it
+// was counted in. It is the unit persisted by a ClientKeyUsageStore.
+type ClientKeyCounters struct {
+	Day   string         `json:"day"`
+	Month string         `json:"month"`
+	Usage ClientKeyUsage `json:"usage"`
+}
+
+// clientKeyUsageTracker counts requests and tokens per managed client key so budgets can
+// be enforced. Requests are counted on admission; tokens arrive through usage records.
+type clientKeyUsageTracker struct {
+	mu       sync.Mutex
+	counters map[string]*ClientKeyCounters
+	// restored holds counters loaded from the store, keyed by key digest, until their key
+	// is seen again.
+	restored map[string]*ClientKeyCounters
+	// onChange is called after the counters change so they can be saved.
+	onChange func()
+}
+
+func newClientKeyUsageTracker() *clientKeyUsageTracker {
+	return &clientKeyUsageTracker{counters: make(map[string]*ClientKeyCounters)}
+}
+
+// clientKeyDigest identifies a client key in saved counters without writing the key itself.
+func clientKeyDigest(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// rollLocked resets the counters whose UTC day or month has ended.
+func (c *ClientKeyCounters) rollLocked(now time.Time) {
+	day := now.UTC().Format(time.DateOnly)
+	month := day[:7]
+	if c.Month != month {
+		c.Month = month
+		c.Usage.MonthlyRequests = 0
+		c.Usage.MonthlyTokens = 0
+	}
+	if c.Day != day {
+		c.Day = day
+		c.Usage.DailyRequests = 0
+		c.Usage.DailyTokens = 0
+	}
+}
+
+// countersLocked returns the rolled counters for key, creating them when create is set.
+func (t *clientKeyUsageTracker) countersLocked(key string, now time.Time, create bool) *ClientKeyCounters {
+	counters, ok := t.counters[key]
+	if !ok {
+		digest := clientKeyDigest(key)
+		if saved, found := t.restored[digest]; found {
+			delete(t.restored, digest)
+			counters = saved
+		} else if !create {
+			return nil
+		} else {
+			counters = &ClientKeyCounters{}
+		}
+		t.counters[key] = counters
+	}
+	counters.rollLocked(now)
+	return counters
+}
+
+// restore replaces the counters awaiting their key with saved, keyed by key digest.
+func (t *clientKeyUsageTracker) restore(saved map[string]*ClientKeyCounters) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.restored = make(map[string]*ClientKeyCounters, len(saved))
+	for digest, counters := range saved {
+		if counters != nil {
+			copied := *counters
+			t.restored[digest] = &copied
+		}
+	}
+}
+
+// export returns the counters of the current day or month keyed by key digest.
+func (t *clientKeyUsageTracker) export(now time.Time) map[string]*ClientKeyCounters {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := make(map[string]*ClientKeyCounters, len(t.counters)+len(t.restored))
+	add := func(digest string, counters *ClientKeyCounters) {
+		copied := *counters
+		copied.rollLocked(now)
+		if copied.Usage != (ClientKeyUsage{}) {
+			out[digest] = &copied
+		}
+	}
+	for digest, counters := range t.restored {
+		add(digest, counters)
+	}
+	for key, counters := range t.counters {
+		add(clientKeyDigest(key), counters)
+	}
+	return out
+}
+
+// changed reports a counter update to the onChange callback.
+func (t *clientKeyUsageTracker) changed() {
+	if t.onChange != nil {
+		t.onChange()
+	}
+}
+
+// admit counts one request against key unless budget is already spent, in which case it
+// returns the name of the exhausted limit.
+func (t *clientKeyUsageTracker) admit(key string, budget internalconfig.ClientKeyBudget, now time.Time) (string, bool) {
+	limit, ok := t.admitRequest(key, budget, now)
+	if ok {
+		t.changed()
+	}
+	return limit, ok
+}
+
+func (t *clientKeyUsageTracker) admitRequest(key string, budget internalconfig.ClientKeyBudget, now time.Time) (string, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	counters := t.countersLocked(key, now, true)
+	spent := counters.Usage
+	switch {
+	case budget.DailyRequests > 0 && spent.DailyRequests >= budget.DailyRequests:
+		return "daily request", false
+	case budget.MonthlyRequests > 0 && spent.MonthlyRequests >= budget.MonthlyRequests:
+		return "monthly request", false
+	case budget.DailyTokens > 0 && spent.DailyTokens >= budget.DailyTokens:
+		return "daily token", false
+	case budget.MonthlyTokens > 0 && spent.MonthlyTokens >= budget.MonthlyTokens:
+		return "monthly token", false
+	}
+	counters.Usage.DailyRequests++
+	counters.Usage.MonthlyRequests++
+	return "", true
+}
+
+// snapshot returns the current usage of key.
+func (t *clientKeyUsageTracker) snapshot(key string, now time.Time) ClientKeyUsage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if counters := t.countersLocked(key, now, false); counters != nil {
+		return counters.Usage
+	}
+	return ClientKeyUsage{}
+}
+
+// HandleUsage implements usage.Plugin and adds reported tokens to admitted client keys.
+func (t *clientKeyUsageTracker) HandleUsage(_ context.Context, record usage.Record) {
+	if t == nil || record.APIKey == "" {
+		return
+	}
+	tokens := record.Detail.TotalTokens
+	if tokens <= 0 {
+		return
+	}
+	now := record.RequestedAt
+	if now.IsZero() {
+		now = time.Now()
+	}
+	if t.addTokens(record.APIKey, tokens, now) {
+		t.changed()
+	}
+}
+
+func (t *clientKeyUsageTracker) addTokens(key string, tokens int64, now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	counters := t.countersLocked(key, now, false)
+	if counters == nil {
+		return false
+	}
+	counters.Usage.DailyTokens += tokens
+	counters.Usage.MonthlyTokens += tokens
+	return true
+}
+
+// ClientKeyUsagePlugin returns the usage plugin that feeds token counts into client key budgets.
+// The host registers it with the usage manager.
+func (m *Manager) ClientKeyUsagePlugin() usage.Plugin {
+	if m == nil {
+		return nil
+	}
+	return m.clientKeyUsage
+}
+
+// ClientKeyUsage returns what the managed client key has consumed in the current day and month.
+func (m *Manager) ClientKeyUsage(key string) ClientKeyUsage {
+	if m == nil || m.clientKeyUsage == nil {
+		return ClientKeyUsage{}
+	}
+	return m.clientKeyUsage.snapshot(key, time.Now())
+}
+
+// checkClientKeyPolicy enforces the model allowlist, expiry and budget of the managed client
+// key behind the request. Requests from plain API keys pass unchanged. When countRequest is
+// set, an admitted request is counted against the request budget.
+func (m *Manager) checkClientKeyPolicy(opts cliproxyexecutor.Options, model string, countRequest bool) error {
+	key, _ := opts.Metadata[cliproxyexecutor.ClientKeyMetadataKey].(string)
+	if key == "" {
+		return nil
+	}
+	cfg, _ := m.runtimeConfig.Load().(*internalconfig.Config)
+	if cfg == nil {
+		return nil
+	}
+	clientKey := cfg.FindClientKey(key)
+	if clientKey == nil {
+		return nil
+	}
+	now := time.Now()
+	if clientKey.Disabled || clientKey.Expired(now) {
+		return &Error{Code: "client_key_rejected", Message: "API key disabled or expired", HTTPStatus: http.StatusForbidden}
+	}
+	if !clientKey.AllowsModel(model) {
+		return &Error{
+			Code:       "model_not_allowed",
+			Message:    fmt.Sprintf("API key %s is not allowed to use model %s", clientKey.DisplayName(), strings.TrimSpace(model)),
+			HTTPStatus: http.StatusForbidden,
+		}
+	}
+	if !countRequest {
+		return nil
+	}
+	if limit, ok := m.clientKeyUsage.admit(key, clientKey.Budget, now); !ok {
+		return &Error{
+			Code:       "client_key_budget_exceeded",
+			Message:    fmt.Sprintf("API key %s has used its %s budget", clientKey.DisplayName(), limit),
+			HTTPStatus: http.StatusTooManyRequests,
+		}
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+)
+
+func newClientKeyTestManager(t *testing.T, clientKey internalconfig.ClientKey) *Manager {
+	t.Helper()
+	manager := NewManager(nil, nil, nil)
+	cfg := &internalconfig.Config{}
+	cfg.ClientKeys = []internalconfig.ClientKey{clientKey}
+	manager.SetConfig(cfg)
+	return manager
+}
+
+func clientKeyOptions(key string) cliproxyexecutor.Options {
+	return cliproxyexecutor.Options{Metadata: map[string]any{cliproxyexecutor.ClientKeyMetadataKey: key}}
+}
+
+func TestCheckClientKeyPolicy_ModelAllowlist(t *testing.T) {
+	t.Parallel()
+
+	manager := newClientKeyTestManager(t, internalconfig.ClientKey{Key: "sk-a", Name: "team-a", Models: []string{"claude-*"}})
+
+	if err := manager.checkClientKeyPolicy(clientKeyOptions("sk-a"), "Claude-Sonnet-4", true); err != nil {
+		t.Fatalf("allowed model error = %v", err)
+	}
+	err := manager.checkClientKeyPolicy(clientKeyOptions("sk-a"), "gpt-5", true)
+	var authErr *Error
+	if !errors.As(err, &authErr) || authErr.HTTPStatus != http.StatusForbidden {
+		t.Fatalf("disallowed model error = %v, want 403", err)
+	}
+	if err := manager.checkClientKeyPolicy(clientKeyOptions("plain-key"), "gpt-5", true); err != nil {
+		t.Fatalf("plain api key error = %v, want nil", err)
+	}
+}
+
+func TestCheckClientKeyPolicy_Budgets(t *testing.T) {
+	t.Parallel()
+
+	manager := newClientKeyTestManager(t, internalconfig.ClientKey{
+		Key:    "sk-b",
+		Budget: internalconfig.ClientKeyBudget{DailyRequests: 2, DailyTokens: 100},
+	})
+
+	for index := 0; index < 2; index++ {
+		if err := manager.checkClientKeyPolicy(clientKeyOptions("sk-b"), "m", true); err != nil {
+			t.Fatalf("request #%d error = %v", index, err)
+		}
+	}
+	err := manager.checkClientKeyPolicy(clientKeyOptions("sk-b"), "m", true)
+	var authErr *Error
+	if !errors.As(err, &authErr) || authErr.HTTPStatus != http.StatusTooManyRequests {
+		t.Fatalf("third request error = %v, want 429", err)
+	}
+	if IsTransientRateLimit(err) {
+		t.Fatalf("budget error reported as a transient rate limit")
+	}
+	if got := manager.ClientKeyUsage("sk-b"); got.DailyRequests != 2 || got.MonthlyRequests != 2 {
+		t.Fatalf("usage = %+v, want 2 daily and monthly requests", got)
+	}
+
+	tokenManager := newClientKeyTestManager(t, internalconfig.ClientKey{
+		Key:    "sk-c",
+		Budget: internalconfig.ClientKeyBudget{DailyTokens: 100},
+	})
+	if err := tokenManager.checkClientKeyPolicy(clientKeyOptions("sk-c"), "m", true); err != nil {
+		t.Fatalf("first token-budget request error = %v", err)
+	}
+	tokenManager.ClientKeyUsagePlugin().HandleUsage(context.Background(), usage.Record{APIKey: "sk-c", Detail: usage.Detail{TotalTokens: 150}})
+	if err := tokenManager.checkClientKeyPolicy(clientKeyOptions("sk-c"), "m", true); err == nil {
+		t.Fatalf("request after token budget spent error = nil, want budget error")
+	}
+	if err := tokenManager.checkClientKeyPolicy(clientKeyOptions("sk-c"), "m", false); err != nil {
+		t.Fatalf("uncounted request error = %v, want nil", err)
+	}
+}
+
+type clientKeyUsageTestStore struct {
+	mu    sync.Mutex
+	usage map[string]*ClientKeyCounters
+}
+
+func (s *clientKeyUsageTestStore) List(context.Context) ([]*Auth, error) { return nil, nil }
+
+func (s *clientKeyUsageTestStore) Save(context.Context, *Auth) (string, error) { return "", nil }
+
+func (s *clientKeyUsageTestStore) Delete(context.Context, string) error { return nil }
+
+func (s *clientKeyUsageTestStore) LoadClientKeyUsage(context.Context) (map[string]*ClientKeyCounters, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.usage, nil
+}
+
+func (s *clientKeyUsageTestStore) SaveClientKeyUsage(_ context.Context, usage map[string]*ClientKeyCounters) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.usage = usage
+	return nil
+}
+
+func TestClientKeyUsage_SurvivesRestart(t *testing.T) {
+	t.Parallel()
+
+	store := &clientKeyUsageTestStore{}
+	cfg := &internalconfig.Config{}
+	cfg.ClientKeys = []internalconfig.ClientKey{{Key: "sk-d", Budget: internalconfig.ClientKeyBudget{DailyRequests: 2}}}
+
+	first := NewManager(store, nil, nil)
+	first.SetConfig(cfg)
+	if errLoad := first.Load(context.Background()); errLoad != nil {
+		t.Fatalf("Load() error = %v", errLoad)
+	}
+	if err := first.checkClientKeyPolicy(clientKeyOptions("sk-d"), "m", true); err != nil {
+		t.Fatalf("first request error = %v", err)
+	}
+	first.ClientKeyUsagePlugin().HandleUsage(context.Background(), usage.Record{APIKey: "sk-d", Detail: usage.Detail{TotalTokens: 40}})
+	if errSave := first.SaveRuntimeState(context.Background()); errSave != nil {
+		t.Fatalf("SaveRuntimeState() error = %v", errSave)
+	}
+	if _, ok := store.usage["sk-d"]; ok || len(store.usage) != 1 {
+		t.Fatalf("saved usage = %+v, want one entry keyed by digest", store.usage)
+	}
+
+	second := NewManager(store, nil, nil)
+	second.SetConfig(cfg)
+	if errLoad := second.Load(context.Background()); errLoad != nil {
+		t.Fatalf("Load() after restart error = %v", errLoad)
+	}
+	if got := second.ClientKeyUsage("sk-d"); got.DailyRequests != 1 || got.DailyTokens != 40 {
+		t.Fatalf("usage after restart = %+v, want 1 request and 40 tokens", got)
+	}
+	if err := second.checkClientKeyPolicy(clientKeyOptions("sk-d"), "m", true); err != nil {
+		t.Fatalf("second request error = %v", err)
+	}
+	if err := second.checkClientKeyPolicy(clientKeyOptions("sk-d"), "m", true); err == nil {
+		t.Fatalf("third request after restart error = nil, want budget error")
+	}
+}
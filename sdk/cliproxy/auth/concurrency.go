@@ -0,0 +1,154 @@
+package auth
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// newAuthSaturatedError reports that every otherwise-ready auth is at its concurrency limit.
+func newAuthSaturatedError() *Error {
+	return &Error{
+		Code:       "auth_saturated",
+		Message:    "all credentials are at their concurrency limit",
+		Retryable:  true,
+		HTTPStatus: http.StatusTooManyRequests,
+	}
+}
+
+// tryAcquire reserves one in-flight slot for auth. It fails when the auth
+// already serves max-concurrency requests; auths without a limit always succeed.
+func (s *authScheduler) tryAcquire(auth *Auth) bool {
+	if s == nil || auth == nil {
+		return true
+	}
+	authID := strings.TrimSpace(auth.ID)
+	if authID == "" {
+		return true
+	}
+	limit := authMaxConcurrency(auth)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if limit > 0 && s.inFlight[authID] >= limit {
+		return false
+	}
+	if s.inFlight == nil {
+		s.inFlight = make(map[string]int)
+	}
+	s.inFlight[authID]++
+	return true
+}
+
+// release frees one in-flight slot previously reserved by tryAcquire.
+func (s *authScheduler) release(authID string) {
+	if s == nil {
+		return
+	}
+	authID = strings.TrimSpace(authID)
+	if authID == "" {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.inFlight[authID] <= 1 {
+		delete(s.inFlight, authID)
+		return
+	}
+	s.inFlight[authID]--
+}
+
+// inFlightCount returns the number of requests currently served by authID.
+func (s *authScheduler) inFlightCount(authID string) int {
+	if s == nil {
+		return 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.inFlight[strings.TrimSpace(authID)]
+}
+
+// saturated reports whether auth has reached its concurrency limit.
+func (s *authScheduler) saturated(auth *Auth) bool {
+	if s == nil || auth == nil {
+		return false
+	}
+	limit := authMaxConcurrency(auth)
+	if limit <= 0 {
+		return false
+	}
+	return s.inFlightCount(auth.ID) >= limit
+}
+
+// saturatedLocked reports whether a scheduled entry has reached its concurrency limit.
+func (s *authScheduler) saturatedLocked(entry *scheduledAuth) bool {
+	if entry == nil || entry.meta == nil || entry.auth == nil || entry.meta.maxConcurrency <= 0 {
+		return false
+	}
+	return s.inFlight[entry.auth.ID] >= entry.meta.maxConcurrency
+}
+
+// withCapacityLocked narrows predicate to auths that still have a free concurrency slot.
+func (s *authScheduler) withCapacityLocked(predicate func(*scheduledAuth) bool) func(*scheduledAuth) bool {
+	if len(s.inFlight) == 0 {
+		return predicate
+	}
+	return func(entry *scheduledAuth) bool {
+		if predicate != nil && !predicate(entry) {
+			return false
+		}
+		return !s.saturatedLocked(entry)
+	}
+}
+
+// saturatedErrorLocked returns the saturation error when a ready auth matching predicate
+// exists in any shard but every such auth is busy. Callers invoke it only after a
+// capacity-aware pick failed, so one saturated ready match is enough.
+func (s *authScheduler) saturatedErrorLocked(predicate func(*scheduledAuth) bool, shards ...*modelScheduler) error {
+	if len(s.inFlight) == 0 {
+		return nil
+	}
+	for _, shard := range shards {
+		if shard == nil {
+			continue
+		}
+		for _, entry := range shard.entries {
+			if entry == nil || entry.state != scheduledStateReady {
+				continue
+			}
+			if predicate != nil && !predicate(entry) {
+				continue
+			}
+			if s.saturatedLocked(entry) {
+				return newAuthSaturatedError()
+			}
+		}
+	}
+	return nil
+}
+
+// InFlight returns the number of requests currently executing on the auth.
+func (m *Manager) InFlight(authID string) int {
+	if m == nil {
+		return 0
+	}
+	return m.scheduler.inFlightCount(authID)
+}
+
+// acquireAuthSlot reserves a concurrency slot on auth for one execution.
+// The returned release function is safe to call more than once.
+func (m *Manager) acquireAuthSlot(auth *Auth) (func(), bool) {
+	if m == nil || m.scheduler == nil || auth == nil {
+		return func() {}, true
+	}
+	if !m.scheduler.tryAcquire(auth) {
+		return nil, false
+	}
+	authID := auth.ID
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			m.scheduler.release(authID)
+			m.queue.notify()
+		})
+	}, true
+}
@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+func TestManagerExecute_RoutesAroundSaturatedAuth(t *testing.T) {
+	model := "concurrency-model"
+	executor := &hedgeTestExecutor{
+		id:     "concurrency-provider",
+		delays: map[string]time.Duration{"concurrency-limited": 200 * time.Millisecond},
+	}
+	registerSchedulerModels(t, executor.id, model, "concurrency-limited", "concurrency-spare")
+	manager := NewManager(nil, &RoundRobinSelector{}, nil)
+	manager.RegisterExecutor(executor)
+	limited := &Auth{ID: "concurrency-limited", Provider: executor.id, Attributes: map[string]string{"priority": "10", "max_concurrency": "1"}}
+	if _, err := manager.Register(context.Background(), limited); err != nil {
+		t.Fatalf("Register(limited) error = %v", err)
+	}
+	if _, err := manager.Register(context.Background(), &Auth{ID: "concurrency-spare", Provider: executor.id}); err != nil {
+		t.Fatalf("Register(spare) error = %v", err)
+	}
+
+	firstDone := make(chan string, 1)
+	go func() {
+		resp, err := manager.Execute(context.Background(), []string{executor.id}, cliproxyexecutor.Request{Model: model}, cliproxyexecutor.Options{})
+		if err != nil {
+			firstDone <- "error: " + err.Error()
+			return
+		}
+		firstDone <- string(resp.Payload)
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for manager.InFlight("concurrency-limited") != 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("InFlight(limited) never reached 1")
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	resp, err := manager.Execute(context.Background(), []string{executor.id}, cliproxyexecutor.Request{Model: model}, cliproxyexecutor.Options{})
+	if err != nil {
+		t.Fatalf("Execute() while saturated error = %v", err)
+	}
+	if string(resp.Payload) != "concurrency-spare" {
+		t.Fatalf("Execute() while saturated payload = %q, want concurrency-spare", resp.Payload)
+	}
+
+	if got := <-firstDone; got != "concurrency-limited" {
+		t.Fatalf("first Execute() = %q, want concurrency-limited", got)
+	}
+	if got := manager.InFlight("concurrency-limited"); got != 0 {
+		t.Fatalf("InFlight(limited) after completion = %d, want 0", got)
+	}
+}
+
+func TestManagerExecuteStream_ReleasesSlotWhenDrained(t *testing.T) {
+	model := "concurrency-stream-model"
+	registerSchedulerModels(t, "concurrency-stream", model, "concurrency-stream-auth")
+	executor := &openAICompatPoolExecutor{id: "concurrency-stream"}
+	manager := NewManager(nil, &RoundRobinSelector{}, nil)
+	manager.RegisterExecutor(executor)
+	auth := &Auth{ID: "concurrency-stream-auth", Provider: executor.id, Attributes: map[string]string{"max_concurrency": "1"}}
+	if _, err := manager.Register(context.Background(), auth); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	result, err := manager.ExecuteStream(context.Background(), []string{executor.id}, cliproxyexecutor.Request{Model: model}, cliproxyexecutor.Options{})
+	if err != nil {
+		t.Fatalf("ExecuteStream() error = %v", err)
+	}
+	if got := manager.InFlight(auth.ID); got != 1 {
+		t.Fatalf("InFlight() while streaming = %d, want 1", got)
+	}
+	for range result.Chunks {
+	}
+	if got := manager.InFlight(auth.ID); got != 0 {
+		t.Fatalf("InFlight() after drain = %d, want 0", got)
+	}
+}
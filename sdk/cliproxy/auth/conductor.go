@@ -43,6 +43,13 @@ type ProviderExecutor interface {
 	HttpRequest(ctx context.Context, auth *Auth, req *http.Request) (*http.Response, error)
 }
 
+// EmbeddingExecutor is implemented by executors that can create embeddings.
+type EmbeddingExecutor interface {
+	// Embed creates embeddings for the request and returns the provider response translated
+	// back to the request's source format ("openai" or "gemini").
+	Embed(ctx context.Context, auth *Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error)
+}
+
 // ExecutionSessionCloser allows executors to release per-session runtime resources.
 type ExecutionSessionCloser interface {
 	CloseExecutionSession(sessionID string)
@@ -64,6 +71,8 @@ const (
 	refreshMaxConcurrency = 16
 	refreshPendingBackoff = time.Minute
 	refreshFailureBackoff = 1 * time.Minute
+	refreshLockTimeout    = 30 * time.Second
+	refreshLeaseTTL       = 2 * time.Minute
 	quotaBackoffBase      = time.Second
 	quotaBackoffMax       = 30 * time.Minute
 )
@@ -96,6 +105,12 @@ type Result struct {
 	Success bool
 	// RetryAfter carries a provider supplied retry hint (e.g. 429 retryDelay).
 	RetryAfter *time.Duration
+	// Latency is the wall-clock duration of the upstream call when measured.
+	Latency time.Duration
+	// FirstByteLatency is the delay until the first streamed payload arrived when measured.
+	FirstByteLatency time.Duration
+	// RateLimit carries the upstream rate-limit budget reported on a successful response.
+	RateLimit *cliproxyexecutor.RateLimit
 	// Error describes the failure when Success is false.
 	Error *Error
 }
@@ -158,6 +173,23 @@ type Manager struct {
 	// It is initialized in NewManager; never Load() before first Store().
 	runtimeConfig atomic.Value
 
+	// affinity pins conversations to the auth that served them last.
+	affinity *sessionAffinity
+
+	// hedgeLatency tracks recent non-streaming latencies used to derive hedge delays.
+	hedgeLatency *hedgeLatencyTracker
+
+	// queue parks requests while every credential is busy or cooling down.
+	queue *requestQueue
+
+	// clientKeyUsage counts consumption of managed client keys for budget enforcement.
+	clientKeyUsage *clientKeyUsageTracker
+
+	// pendingRuntimeState holds restored cooldown state for auths not registered yet.
+	pendingRuntimeState map[string]*RuntimeState
+	runtimeStateMu      sync.Mutex
+	runtimeStateTimer   *time.Timer
+
 	// Optional HTTP RoundTripper provider injected by host.
 	rtProvider RoundTripperProvider
 
@@ -182,18 +214,23 @@ func NewManager(store Store, selector Selector, hook Hook) *Manager {
 		auths:            make(map[string]*Auth),
 		providerOffsets:  make(map[string]int),
 		modelPoolOffsets: make(map[string]int),
+		affinity:         newSessionAffinity(),
+		hedgeLatency:     newHedgeLatencyTracker(),
+		queue:            newRequestQueue(),
+		clientKeyUsage:   newClientKeyUsageTracker(),
 		refreshSemaphore: make(chan struct{}, refreshMaxConcurrency),
 	}
 	// atomic.Value requires non-nil initial value.
 	manager.runtimeConfig.Store(&internalconfig.Config{})
 	manager.apiKeyModelAlias.Store(apiKeyModelAliasTable(nil))
 	manager.scheduler = newAuthScheduler(selector)
+	manager.clientKeyUsage.onChange = manager.scheduleRuntimeStateSave
 	return manager
 }
 
 func isBuiltInSelector(selector Selector) bool {
 	switch selector.(type) {
-	case *RoundRobinSelector, *FillFirstSelector:
+	case *RoundRobinSelector, *FillFirstSelector, *AdaptiveSelector:
 		return true
 	default:
 		return false
@@ -509,37 +546,28 @@ func (m *Manager) availableAuthsForRouteModel(auths []*Auth, provider, routeMode
 	}
 
 	availableByPriority := make(map[int][]*Auth)
-	cooldownCount := 0
-	var earliest time.Time
+	summary := blockedSummary{total: len(auths)}
+	saturatedCount := 0
 	for _, candidate := range auths {
 		checkModel := m.selectionModelForAuth(candidate, routeModel)
 		blocked, reason, next := isAuthBlockedForModel(candidate, checkModel, now)
 		if !blocked {
+			if m.scheduler.saturated(candidate) {
+				saturatedCount++
+				continue
+			}
 			priority := authPriority(candidate)
 			availableByPriority[priority] = append(availableByPriority[priority], candidate)
 			continue
 		}
-		if reason == blockReasonCooldown {
-			cooldownCount++
-			if !next.IsZero() && (earliest.IsZero() || next.Before(earliest)) {
-				earliest = next
-			}
-		}
+		summary.add(reason, next)
 	}
 
 	if len(availableByPriority) == 0 {
-		if cooldownCount == len(auths) && !earliest.IsZero() {
-			providerForError := provider
-			if providerForError == "mixed" {
-				providerForError = ""
-			}
-			resetIn := earliest.Sub(now)
-			if resetIn < 0 {
-				resetIn = 0
-			}
-			return nil, newModelCooldownError(routeModel, providerForError, resetIn)
+		if saturatedCount > 0 {
+			return nil, newAuthSaturatedError()
 		}
-		return nil, &Error{Code: "auth_unavailable", Message: "no auth available"}
+		return nil, summary.unavailableError(provider, routeModel, now)
 	}
 
 	bestPriority := 0
@@ -675,10 +703,13 @@ func readStreamBootstrap(ctx context.Context, ch <-chan cliproxyexecutor.StreamC
 	}
 }
 
-func (m *Manager) wrapStreamResult(ctx context.Context, auth *Auth, provider, resultModel string, headers http.Header, buffered []cliproxyexecutor.StreamChunk, remaining <-chan cliproxyexecutor.StreamChunk) *cliproxyexecutor.StreamResult {
+func (m *Manager) wrapStreamResult(ctx context.Context, auth *Auth, provider, resultModel string, started time.Time, firstByte time.Duration, headers http.Header, rateLimit *cliproxyexecutor.RateLimit, buffered []cliproxyexecutor.StreamChunk, remaining <-chan cliproxyexecutor.StreamChunk, release func()) *cliproxyexecutor.StreamResult {
 	out := make(chan cliproxyexecutor.StreamChunk)
 	go func() {
 		defer close(out)
+		if release != nil {
+			defer release()
+		}
 		var failed bool
 		forward := true
 		emit := func(chunk cliproxyexecutor.StreamChunk) bool {
@@ -688,7 +719,7 @@ func (m *Manager) wrapStreamResult(ctx context.Context, auth *Auth, provider, re
 				if se, ok := errors.AsType[cliproxyexecutor.StatusError](chunk.Err); ok && se != nil {
 					rerr.HTTPStatus = se.StatusCode()
 				}
-				m.MarkResult(ctx, Result{AuthID: auth.ID, Provider: provider, Model: resultModel, Success: false, Error: rerr})
+				m.MarkResult(ctx, Result{AuthID: auth.ID, Provider: provider, Model: resultModel, Success: false, Error: rerr, Latency: time.Since(started), FirstByteLatency: firstByte})
 			}
 			if !forward {
 				return false
@@ -718,13 +749,16 @@ func (m *Manager) wrapStreamResult(ctx context.Context, auth *Auth, provider, re
 			}
 		}
 		if !failed {
-			m.MarkResult(ctx, Result{AuthID: auth.ID, Provider: provider, Model: resultModel, Success: true})
+			m.MarkResult(ctx, Result{AuthID: auth.ID, Provider: provider, Model: resultModel, Success: true, Latency: time.Since(started), FirstByteLatency: firstByte, RateLimit: rateLimit})
 		}
 	}()
-	return &cliproxyexecutor.StreamResult{Headers: headers, Chunks: out}
+	return &cliproxyexecutor.StreamResult{Headers: headers, RateLimit: rateLimit, Chunks: out}
 }
 
-func (m *Manager) executeStreamWithModelPool(ctx context.Context, executor ProviderExecutor, auth *Auth, provider string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options, routeModel string, execModels []string, pooled bool) (*cliproxyexecutor.StreamResult, error) {
+// executeStreamWithModelPool opens a stream on auth, walking its upstream model pool.
+// On success the returned stream calls release once it is drained; on error the caller keeps
+// ownership of release.
+func (m *Manager) executeStreamWithModelPool(ctx context.Context, executor ProviderExecutor, auth *Auth, provider string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options, routeModel string, execModels []string, pooled bool, release func()) (*cliproxyexecutor.StreamResult, error) {
 	if executor == nil {
 		return nil, &Error{Code: "executor_not_found", Message: "executor not registered"}
 	}
@@ -733,6 +767,7 @@ func (m *Manager) executeStreamWithModelPool(ctx context.Context, executor Provi
 		resultModel := m.stateModelForExecution(auth, routeModel, execModel, pooled)
 		execReq := req
 		execReq.Model = execModel
+		started := time.Now()
 		streamResult, errStream := executor.ExecuteStream(ctx, auth, execReq, opts)
 		if errStream != nil {
 			if errCtx := ctx.Err(); errCtx != nil {
@@ -742,7 +777,7 @@ func (m *Manager) executeStreamWithModelPool(ctx context.Context, executor Provi
 			if se, ok := errors.AsType[cliproxyexecutor.StatusError](errStream); ok && se != nil {
 				rerr.HTTPStatus = se.StatusCode()
 			}
-			result := Result{AuthID: auth.ID, Provider: provider, Model: resultModel, Success: false, Error: rerr}
+			result := Result{AuthID: auth.ID, Provider: provider, Model: resultModel, Success: false, Error: rerr, Latency: time.Since(started)}
 			result.RetryAfter = retryAfterFromError(errStream)
 			m.MarkResult(ctx, result)
 			if isRequestInvalidError(errStream) {
@@ -763,7 +798,7 @@ func (m *Manager) executeStreamWithModelPool(ctx context.Context, executor Provi
 				if se, ok := errors.AsType[cliproxyexecutor.StatusError](bootstrapErr); ok && se != nil {
 					rerr.HTTPStatus = se.StatusCode()
 				}
-				result := Result{AuthID: auth.ID, Provider: provider, Model: resultModel, Success: false, Error: rerr}
+				result := Result{AuthID: auth.ID, Provider: provider, Model: resultModel, Success: false, Error: rerr, Latency: time.Since(started)}
 				result.RetryAfter = retryAfterFromError(bootstrapErr)
 				m.MarkResult(ctx, result)
 				discardStreamChunks(streamResult.Chunks)
@@ -774,7 +809,7 @@ func (m *Manager) executeStreamWithModelPool(ctx context.Context, executor Provi
 				if se, ok := errors.AsType[cliproxyexecutor.StatusError](bootstrapErr); ok && se != nil {
 					rerr.HTTPStatus = se.StatusCode()
 				}
-				result := Result{AuthID: auth.ID, Provider: provider, Model: resultModel, Success: false, Error: rerr}
+				result := Result{AuthID: auth.ID, Provider: provider, Model: resultModel, Success: false, Error: rerr, Latency: time.Since(started)}
 				result.RetryAfter = retryAfterFromError(bootstrapErr)
 				m.MarkResult(ctx, result)
 				discardStreamChunks(streamResult.Chunks)
@@ -785,7 +820,7 @@ func (m *Manager) executeStreamWithModelPool(ctx context.Context, executor Provi
 			if se, ok := errors.AsType[cliproxyexecutor.StatusError](bootstrapErr); ok && se != nil {
 				rerr.HTTPStatus = se.StatusCode()
 			}
-			result := Result{AuthID: auth.ID, Provider: provider, Model: resultModel, Success: false, Error: rerr}
+			result := Result{AuthID: auth.ID, Provider: provider, Model: resultModel, Success: false, Error: rerr, Latency: time.Since(started)}
 			result.RetryAfter = retryAfterFromError(bootstrapErr)
 			m.MarkResult(ctx, result)
 			discardStreamChunks(streamResult.Chunks)
@@ -794,7 +829,7 @@ func (m *Manager) executeStreamWithModelPool(ctx context.Context, executor Provi
 
 		if closed && len(buffered) == 0 {
 			emptyErr := &Error{Code: "empty_stream", Message: "upstream stream closed before first payload", Retryable: true}
-			result := Result{AuthID: auth.ID, Provider: provider, Model: resultModel, Success: false, Error: emptyErr}
+			result := Result{AuthID: auth.ID, Provider: provider, Model: resultModel, Success: false, Error: emptyErr, Latency: time.Since(started)}
 			m.MarkResult(ctx, result)
 			if idx < len(execModels)-1 {
 				lastErr = emptyErr
@@ -803,13 +838,14 @@ func (m *Manager) executeStreamWithModelPool(ctx context.Context, executor Provi
 			return nil, newStreamBootstrapError(emptyErr, streamResult.Headers)
 		}
 
+		firstByte := time.Since(started)
 		remaining := streamResult.Chunks
 		if closed {
 			closedCh := make(chan cliproxyexecutor.StreamChunk)
 			close(closedCh)
 			remaining = closedCh
 		}
-		return m.wrapStreamResult(ctx, auth.Clone(), provider, resultModel, streamResult.Headers, buffered, remaining), nil
+		return m.wrapStreamResult(ctx, auth.Clone(), provider, resultModel, started, firstByte, streamResult.Headers, streamResult.RateLimit, buffered, remaining, release), nil
 	}
 	if lastErr == nil {
 		lastErr = &Error{Code: "auth_not_found", Message: "no upstream model available"}
@@ -1002,8 +1038,9 @@ func (m *Manager) Register(ctx context.Context, auth *Auth) (*Auth, error) {
 		auth.ID = uuid.NewString()
 	}
 	auth.EnsureIndex()
-	authClone := auth.Clone()
 	m.mu.Lock()
+	m.applyPendingRuntimeStateLocked(auth)
+	authClone := auth.Clone()
 	m.auths[auth.ID] = authClone
 	m.mu.Unlock()
 	m.rebuildAPIKeyModelAliasFromRuntimeConfig()
@@ -1065,10 +1102,12 @@ func (m *Manager) Load(ctx context.Context) error {
 		auth.EnsureIndex()
 		m.auths[auth.ID] = auth.Clone()
 	}
+	m.restoreRuntimeStateLocked(ctx)
 	cfg, _ := m.runtimeConfig.Load().(*internalconfig.Config)
 	if cfg == nil {
 		cfg = &internalconfig.Config{}
 	}
+	m.restoreClientKeyUsage(ctx, cfg)
 	m.rebuildAPIKeyModelAliasLocked(cfg)
 	m.mu.Unlock()
 	m.syncScheduler()
@@ -1077,12 +1116,42 @@ func (m *Manager) Load(ctx context.Context) error {
 
 // Execute performs a non-streaming execution using the configured selector and executor.
 // It supports multiple providers for the same model and round-robins the starting provider per model.
+// When every credential for the model is cooling down, configured model fallbacks are tried in order.
+// When queueing is enabled, requests that find no ready credential wait in the queue and retry.
 func (m *Manager) Execute(ctx context.Context, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
 	normalized := m.normalizeProviders(providers)
 	if len(normalized) == 0 {
 		return cliproxyexecutor.Response{}, &Error{Code: "provider_not_found", Message: "no provider supplied"}
 	}
+	if errPolicy := m.checkClientKeyPolicy(opts, req.Model, true); errPolicy != nil {
+		return cliproxyexecutor.Response{}, errPolicy
+	}
+
+	var resp cliproxyexecutor.Response
+	attempt := func() error {
+		var errExec error
+		resp, errExec = m.executeWithCooldownRetry(ctx, normalized, req, opts)
+		if errExec == nil {
+			return nil
+		}
+		if errCtx := ctx.Err(); errCtx != nil {
+			return errExec
+		}
+		resp, errExec = m.executeModelFallbacks(ctx, normalized, req, opts, errExec)
+		return errExec
+	}
+	errExec := m.attemptOrQueue(opts, req.Model, attempt)
+	if errExec != nil && ctx.Err() == nil {
+		errExec = m.waitInQueue(ctx, normalized, req.Model, opts, errExec, attempt)
+	}
+	if errExec != nil {
+		return cliproxyexecutor.Response{}, errExec
+	}
+	return resp, nil
+}
 
+// executeWithCooldownRetry runs non-streaming attempts, waiting for cooldowns within the retry budget.
+func (m *Manager) executeWithCooldownRetry(ctx context.Context, normalized []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
 	_, maxRetryCredentials, maxWait := m.retrySettings()
 
 	var lastErr error
@@ -1113,12 +1182,53 @@ func (m *Manager) ExecuteCount(ctx context.Context, providers []string, req clip
 	if len(normalized) == 0 {
 		return cliproxyexecutor.Response{}, &Error{Code: "provider_not_found", Message: "no provider supplied"}
 	}
+	if errPolicy := m.checkClientKeyPolicy(opts, req.Model, false); errPolicy != nil {
+		return cliproxyexecutor.Response{}, errPolicy
+	}
+	return m.executeUnaryWithRetry(ctx, normalized, req, opts, countTokensCall)
+}
+
+// ExecuteEmbed creates embeddings using the configured selector and executor, rotating
+// credentials and retrying like non-streaming executions. Only providers whose executor
+// implements EmbeddingExecutor are used.
+func (m *Manager) ExecuteEmbed(ctx context.Context, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	normalized := m.normalizeProviders(providers)
+	if len(normalized) == 0 {
+		return cliproxyexecutor.Response{}, &Error{Code: "provider_not_found", Message: "no provider supplied"}
+	}
+	if errPolicy := m.checkClientKeyPolicy(opts, req.Model, true); errPolicy != nil {
+		return cliproxyexecutor.Response{}, errPolicy
+	}
+	return m.executeUnaryWithRetry(ctx, normalized, req, opts, embedCall)
+}
 
+// unaryCall performs a single executor call for executeUnaryWithRetry.
+type unaryCall func(ctx context.Context, executor ProviderExecutor, auth *Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error)
+
+func countTokensCall(ctx context.Context, executor ProviderExecutor, auth *Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	return executor.CountTokens(ctx, auth, req, opts)
+}
+
+// errEmbeddingsUnsupported marks executors that cannot create embeddings; such auths are skipped
+// without recording a failure.
+var errEmbeddingsUnsupported = &Error{Code: "embeddings_not_supported", Message: "no credential supporting embeddings is available for this model", HTTPStatus: http.StatusBadRequest}
+
+func embedCall(ctx context.Context, executor ProviderExecutor, auth *Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	embedder, ok := executor.(EmbeddingExecutor)
+	if !ok {
+		return cliproxyexecutor.Response{}, errEmbeddingsUnsupported
+	}
+	return embedder.Embed(ctx, auth, req, opts)
+}
+
+// executeUnaryWithRetry runs single-response calls without model fallbacks or queueing,
+// waiting for cooldowns within the retry budget.
+func (m *Manager) executeUnaryWithRetry(ctx context.Context, normalized []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options, call unaryCall) (cliproxyexecutor.Response, error) {
 	_, maxRetryCredentials, maxWait := m.retrySettings()
 
 	var lastErr error
 	for attempt := 0; ; attempt++ {
-		resp, errExec := m.executeCountMixedOnce(ctx, normalized, req, opts, maxRetryCredentials)
+		resp, errExec := m.executeUnaryMixedOnce(ctx, normalized, req, opts, maxRetryCredentials, call)
 		if errExec == nil {
 			return resp, nil
 		}
@@ -1139,12 +1249,42 @@ func (m *Manager) ExecuteCount(ctx context.Context, providers []string, req clip
 
 // ExecuteStream performs a streaming execution using the configured selector and executor.
 // It supports multiple providers for the same model and round-robins the starting provider per model.
+// When every credential for the model is cooling down, configured model fallbacks are tried in order.
+// When queueing is enabled, requests that find no ready credential wait in the queue and retry.
 func (m *Manager) ExecuteStream(ctx context.Context, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (*cliproxyexecutor.StreamResult, error) {
 	normalized := m.normalizeProviders(providers)
 	if len(normalized) == 0 {
 		return nil, &Error{Code: "provider_not_found", Message: "no provider supplied"}
 	}
+	if errPolicy := m.checkClientKeyPolicy(opts, req.Model, true); errPolicy != nil {
+		return nil, errPolicy
+	}
+
+	var result *cliproxyexecutor.StreamResult
+	attempt := func() error {
+		var errStream error
+		result, errStream = m.executeStreamWithCooldownRetry(ctx, normalized, req, opts)
+		if errStream == nil {
+			return nil
+		}
+		if errCtx := ctx.Err(); errCtx != nil {
+			return errStream
+		}
+		result, errStream = m.executeStreamModelFallbacks(ctx, normalized, req, opts, errStream)
+		return errStream
+	}
+	errStream := m.attemptOrQueue(opts, req.Model, attempt)
+	if errStream != nil && ctx.Err() == nil {
+		errStream = m.waitInQueue(ctx, normalized, req.Model, opts, errStream, attempt)
+	}
+	if errStream != nil {
+		return nil, errStream
+	}
+	return result, nil
+}
 
+// executeStreamWithCooldownRetry runs streaming attempts, waiting for cooldowns within the retry budget.
+func (m *Manager) executeStreamWithCooldownRetry(ctx context.Context, normalized []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (*cliproxyexecutor.StreamResult, error) {
 	_, maxRetryCredentials, maxWait := m.retrySettings()
 
 	var lastErr error
@@ -1174,6 +1314,7 @@ func (m *Manager) executeMixedOnce(ctx context.Context, providers []string, req
 	}
 	routeModel := req.Model
 	opts = ensureRequestedModelMetadata(opts, routeModel)
+	hedgeDelay, hedge := m.hedgeDelayFor(routeModel, opts)
 	tried := make(map[string]struct{})
 	attempted := make(map[string]struct{})
 	var lastErr error
@@ -1194,59 +1335,90 @@ func (m *Manager) executeMixedOnce(ctx context.Context, providers []string, req
 
 		entry := logEntryWithRequestID(ctx)
 		debugLogAuthSelection(entry, auth, provider, req.Model)
-		publishSelectedAuthMetadata(opts.Metadata, auth.ID)
 
 		tried[auth.ID] = struct{}{}
-		execCtx := ctx
-		if rt := m.roundTripperFor(auth); rt != nil {
-			execCtx = context.WithValue(execCtx, roundTripperContextKey{}, rt)
-			execCtx = context.WithValue(execCtx, "cliproxy.roundtripper", rt)
-		}
-
 		models, pooled := m.preparedExecutionModels(auth, routeModel)
 		if len(models) == 0 {
 			continue
 		}
 		attempted[auth.ID] = struct{}{}
-		var authErr error
-		for _, upstreamModel := range models {
-			resultModel := m.stateModelForExecution(auth, routeModel, upstreamModel, pooled)
-			execReq := req
-			execReq.Model = upstreamModel
-			resp, errExec := executor.Execute(execCtx, auth, execReq, opts)
-			result := Result{AuthID: auth.ID, Provider: provider, Model: resultModel, Success: errExec == nil}
-			if errExec != nil {
-				if errCtx := execCtx.Err(); errCtx != nil {
-					return cliproxyexecutor.Response{}, errCtx
-				}
-				result.Error = &Error{Message: errExec.Error()}
-				if se, ok := errors.AsType[cliproxyexecutor.StatusError](errExec); ok && se != nil {
-					result.Error.HTTPStatus = se.StatusCode()
-				}
-				if ra := retryAfterFromError(errExec); ra != nil {
-					result.RetryAfter = ra
-				}
-				m.MarkResult(execCtx, result)
-				if isRequestInvalidError(errExec) {
-					return cliproxyexecutor.Response{}, errExec
-				}
-				authErr = errExec
-				continue
-			}
-			m.MarkResult(execCtx, result)
+		var (
+			resp    cliproxyexecutor.Response
+			authErr error
+		)
+		if hedge {
+			// Only the first attempt is hedged; later retries run sequentially.
+			hedge = false
+			resp, authErr = m.executeHedged(ctx, providers, req, opts, tried, attempted, auth, executor, provider, hedgeDelay)
+		} else {
+			publishSelectedAuthMetadata(opts.Metadata, auth.ID)
+			resp, authErr = m.executeWithAuth(ctx, auth, executor, provider, req, opts, routeModel, models, pooled)
+		}
+		if authErr == nil {
 			return resp, nil
 		}
-		if authErr != nil {
-			if isRequestInvalidError(authErr) {
-				return cliproxyexecutor.Response{}, authErr
+		if errCtx := ctx.Err(); errCtx != nil {
+			return cliproxyexecutor.Response{}, errCtx
+		}
+		if isRequestInvalidError(authErr) {
+			return cliproxyexecutor.Response{}, authErr
+		}
+		lastErr = authErr
+	}
+}
+
+// executeWithAuth runs a non-streaming request on auth, walking its upstream model pool.
+// Each upstream attempt is recorded through MarkResult unless ctx was cancelled.
+// The auth holds one concurrency slot for the duration of the call.
+func (m *Manager) executeWithAuth(ctx context.Context, auth *Auth, executor ProviderExecutor, provider string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options, routeModel string, models []string, pooled bool) (cliproxyexecutor.Response, error) {
+	release, acquired := m.acquireAuthSlot(auth)
+	if !acquired {
+		// Another request took the last slot between selection and execution.
+		return cliproxyexecutor.Response{}, newAuthSaturatedError()
+	}
+	defer release()
+	execCtx := ctx
+	if rt := m.roundTripperFor(auth); rt != nil {
+		execCtx = context.WithValue(execCtx, roundTripperContextKey{}, rt)
+		execCtx = context.WithValue(execCtx, "cliproxy.roundtripper", rt)
+	}
+	var authErr error
+	for _, upstreamModel := range models {
+		resultModel := m.stateModelForExecution(auth, routeModel, upstreamModel, pooled)
+		execReq := req
+		execReq.Model = upstreamModel
+		started := time.Now()
+		resp, errExec := executor.Execute(execCtx, auth, execReq, opts)
+		result := Result{AuthID: auth.ID, Provider: provider, Model: resultModel, Success: errExec == nil, Latency: time.Since(started), RateLimit: resp.RateLimit}
+		if errExec != nil {
+			if errCtx := execCtx.Err(); errCtx != nil {
+				return cliproxyexecutor.Response{}, errCtx
+			}
+			result.Error = &Error{Message: errExec.Error()}
+			if se, ok := errors.AsType[cliproxyexecutor.StatusError](errExec); ok && se != nil {
+				result.Error.HTTPStatus = se.StatusCode()
+			}
+			if ra := retryAfterFromError(errExec); ra != nil {
+				result.RetryAfter = ra
+			}
+			m.MarkResult(execCtx, result)
+			if isRequestInvalidError(errExec) {
+				return cliproxyexecutor.Response{}, errExec
 			}
-			lastErr = authErr
+			authErr = errExec
 			continue
 		}
+		m.MarkResult(execCtx, result)
+		m.hedgeLatency.observe(routeModel, result.Latency)
+		return resp, nil
 	}
+	if authErr == nil {
+		authErr = &Error{Code: "auth_not_found", Message: "no auth available"}
+	}
+	return cliproxyexecutor.Response{}, authErr
 }
 
-func (m *Manager) executeCountMixedOnce(ctx context.Context, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options, maxRetryCredentials int) (cliproxyexecutor.Response, error) {
+func (m *Manager) executeUnaryMixedOnce(ctx context.Context, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options, maxRetryCredentials int, call unaryCall) (cliproxyexecutor.Response, error) {
 	if len(providers) == 0 {
 		return cliproxyexecutor.Response{}, &Error{Code: "provider_not_found", Message: "no provider supplied"}
 	}
@@ -1275,53 +1447,78 @@ func (m *Manager) executeCountMixedOnce(ctx context.Context, providers []string,
 		publishSelectedAuthMetadata(opts.Metadata, auth.ID)
 
 		tried[auth.ID] = struct{}{}
-		execCtx := ctx
-		if rt := m.roundTripperFor(auth); rt != nil {
-			execCtx = context.WithValue(execCtx, roundTripperContextKey{}, rt)
-			execCtx = context.WithValue(execCtx, "cliproxy.roundtripper", rt)
-		}
-
 		models, pooled := m.preparedExecutionModels(auth, routeModel)
 		if len(models) == 0 {
 			continue
 		}
 		attempted[auth.ID] = struct{}{}
-		var authErr error
-		for _, upstreamModel := range models {
-			resultModel := m.stateModelForExecution(auth, routeModel, upstreamModel, pooled)
-			execReq := req
-			execReq.Model = upstreamModel
-			resp, errExec := executor.CountTokens(execCtx, auth, execReq, opts)
-			result := Result{AuthID: auth.ID, Provider: provider, Model: resultModel, Success: errExec == nil}
-			if errExec != nil {
-				if errCtx := execCtx.Err(); errCtx != nil {
-					return cliproxyexecutor.Response{}, errCtx
-				}
-				result.Error = &Error{Message: errExec.Error()}
-				if se, ok := errors.AsType[cliproxyexecutor.StatusError](errExec); ok && se != nil {
-					result.Error.HTTPStatus = se.StatusCode()
-				}
-				if ra := retryAfterFromError(errExec); ra != nil {
-					result.RetryAfter = ra
-				}
-				m.MarkResult(execCtx, result)
-				if isRequestInvalidError(errExec) {
-					return cliproxyexecutor.Response{}, errExec
-				}
-				authErr = errExec
-				continue
-			}
-			m.MarkResult(execCtx, result)
+		resp, authErr := m.executeUnaryWithAuth(ctx, auth, executor, provider, req, opts, routeModel, models, pooled, call)
+		if authErr == nil {
 			return resp, nil
 		}
-		if authErr != nil {
-			if isRequestInvalidError(authErr) {
-				return cliproxyexecutor.Response{}, authErr
+		if errors.Is(authErr, errEmbeddingsUnsupported) {
+			// Not a failed attempt: the auth's executor simply has no embeddings endpoint.
+			delete(attempted, auth.ID)
+		}
+		if errCtx := ctx.Err(); errCtx != nil {
+			return cliproxyexecutor.Response{}, errCtx
+		}
+		if isRequestInvalidError(authErr) {
+			return cliproxyexecutor.Response{}, authErr
+		}
+		lastErr = authErr
+	}
+}
+
+// executeUnaryWithAuth runs call on auth, walking its upstream model pool. Like executeWithAuth,
+// the auth holds one concurrency slot for the duration of the call.
+func (m *Manager) executeUnaryWithAuth(ctx context.Context, auth *Auth, executor ProviderExecutor, provider string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options, routeModel string, models []string, pooled bool, call unaryCall) (cliproxyexecutor.Response, error) {
+	release, acquired := m.acquireAuthSlot(auth)
+	if !acquired {
+		// Another request took the last slot between selection and execution.
+		return cliproxyexecutor.Response{}, newAuthSaturatedError()
+	}
+	defer release()
+	execCtx := ctx
+	if rt := m.roundTripperFor(auth); rt != nil {
+		execCtx = context.WithValue(execCtx, roundTripperContextKey{}, rt)
+		execCtx = context.WithValue(execCtx, "cliproxy.roundtripper", rt)
+	}
+	var authErr error
+	for _, upstreamModel := range models {
+		resultModel := m.stateModelForExecution(auth, routeModel, upstreamModel, pooled)
+		execReq := req
+		execReq.Model = upstreamModel
+		resp, errExec := call(execCtx, executor, auth, execReq, opts)
+		if errors.Is(errExec, errEmbeddingsUnsupported) {
+			return cliproxyexecutor.Response{}, errExec
+		}
+		result := Result{AuthID: auth.ID, Provider: provider, Model: resultModel, Success: errExec == nil}
+		if errExec != nil {
+			if errCtx := execCtx.Err(); errCtx != nil {
+				return cliproxyexecutor.Response{}, errCtx
 			}
-			lastErr = authErr
+			result.Error = &Error{Message: errExec.Error()}
+			if se, ok := errors.AsType[cliproxyexecutor.StatusError](errExec); ok && se != nil {
+				result.Error.HTTPStatus = se.StatusCode()
+			}
+			if ra := retryAfterFromError(errExec); ra != nil {
+				result.RetryAfter = ra
+			}
+			m.MarkResult(execCtx, result)
+			if isRequestInvalidError(errExec) {
+				return cliproxyexecutor.Response{}, errExec
+			}
+			authErr = errExec
 			continue
 		}
+		m.MarkResult(execCtx, result)
+		return resp, nil
+	}
+	if authErr == nil {
+		authErr = &Error{Code: "auth_not_found", Message: "no auth available"}
 	}
+	return cliproxyexecutor.Response{}, authErr
 }
 
 func (m *Manager) executeStreamMixedOnce(ctx context.Context, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options, maxRetryCredentials int) (*cliproxyexecutor.StreamResult, error) {
@@ -1371,8 +1568,14 @@ func (m *Manager) executeStreamMixedOnce(ctx context.Context, providers []string
 			continue
 		}
 		attempted[auth.ID] = struct{}{}
-		streamResult, errStream := m.executeStreamWithModelPool(execCtx, executor, auth, provider, req, opts, routeModel, models, pooled)
+		release, acquired := m.acquireAuthSlot(auth)
+		if !acquired {
+			lastErr = newAuthSaturatedError()
+			continue
+		}
+		streamResult, errStream := m.executeStreamWithModelPool(execCtx, executor, auth, provider, req, opts, routeModel, models, pooled, release)
 		if errStream != nil {
+			release()
 			if errCtx := execCtx.Err(); errCtx != nil {
 				return nil, errCtx
 			}
@@ -1753,7 +1956,7 @@ func (m *Manager) closestCooldownWait(providers []string, model string, attempt
 			continue
 		}
 		blocked, reason, next := isAuthBlockedForModel(auth, model, now)
-		if !blocked || next.IsZero() || reason == blockReasonDisabled {
+		if !blocked || next.IsZero() || reason == blockReasonDisabled || reason == blockReasonAvailabilityWindow {
 			continue
 		}
 		wait := next.Sub(now)
@@ -1813,16 +2016,29 @@ func (m *Manager) MarkResult(ctx context.Context, result Result) {
 	suspendReason := ""
 	clearModelQuota := false
 	setModelQuota := false
+	saveRuntimeState := false
 	var authSnapshot *Auth
 
 	m.mu.Lock()
+	selector := m.selector
 	if auth, ok := m.auths[result.AuthID]; ok && auth != nil {
 		now := time.Now()
+		// Failures may start a cooldown and successes may end one; either changes saved state.
+		saveRuntimeState = !result.Success || captureRuntimeState(auth, now) != nil
 
 		if result.Success {
+			rateLimit := newRateLimitState(result.RateLimit, now)
+			if rateLimit != nil {
+				auth.Quota.RateLimit = rateLimit
+			}
 			if result.Model != "" {
 				state := ensureModelState(auth, result.Model)
+				observed := state.Quota.RateLimit
 				resetModelState(state, now)
+				state.Quota.RateLimit = observed
+				if rateLimit != nil {
+					state.Quota.RateLimit = rateLimit
+				}
 				updateAggregatedAvailability(auth, now)
 				if !hasModelError(auth, now) {
 					auth.LastError = nil
@@ -1885,12 +2101,10 @@ func (m *Manager) MarkResult(ctx context.Context, result Result) {
 								backoffLevel = nextLevel
 							}
 							state.NextRetryAfter = next
-							state.Quota = QuotaState{
-								Exceeded:      true,
-								Reason:        "quota",
-								NextRecoverAt: next,
-								BackoffLevel:  backoffLevel,
-							}
+							state.Quota.Exceeded = true
+							state.Quota.Reason = "quota"
+							state.Quota.NextRecoverAt = next
+							state.Quota.BackoffLevel = backoffLevel
 							suspendReason = "quota"
 							shouldSuspendModel = true
 							setModelQuota = true
@@ -1922,6 +2136,9 @@ func (m *Manager) MarkResult(ctx context.Context, result Result) {
 	if m.scheduler != nil && authSnapshot != nil {
 		m.scheduler.upsertAuth(authSnapshot)
 	}
+	if saveRuntimeState {
+		m.scheduleRuntimeStateSave()
+	}
 
 	if clearModelQuota && result.Model != "" {
 		registry.GetGlobalRegistry().ClearModelQuotaExceeded(result.AuthID, result.Model)
@@ -1935,6 +2152,10 @@ func (m *Manager) MarkResult(ctx context.Context, result Result) {
 		registry.GetGlobalRegistry().SuspendClientModel(result.AuthID, result.Model, suspendReason)
 	}
 
+	if observer, ok := selector.(ResultObserver); ok && observer != nil {
+		observer.ObserveResult(result)
+	}
+
 	m.hook.OnResult(ctx, result)
 }
 
@@ -2446,7 +2667,31 @@ func (m *Manager) pickNextLegacy(ctx context.Context, provider, model string, op
 	return authCopy, executor, nil
 }
 
+// pickNext selects the next auth for provider, keeping conversations on their
+// previously used auth while it stays available.
 func (m *Manager) pickNext(ctx context.Context, provider, model string, opts cliproxyexecutor.Options, tried map[string]struct{}) (*Auth, ProviderExecutor, error) {
+	affinityKey := m.sessionAffinityKey(provider, model, opts)
+	if affinityKey == "" {
+		return m.pickNextWithoutAffinity(ctx, provider, model, opts, tried)
+	}
+	_, ttl := m.sessionAffinitySettings()
+	if authID := m.affinity.lookup(affinityKey, time.Now()); authID != "" {
+		if _, used := tried[authID]; !used {
+			auth, executor, errPick := m.pickNextWithoutAffinity(ctx, provider, model, pinnedAffinityOptions(opts, authID), tried)
+			if errPick == nil {
+				m.affinity.remember(affinityKey, auth.ID, time.Now(), ttl)
+				return auth, executor, nil
+			}
+		}
+	}
+	auth, executor, errPick := m.pickNextWithoutAffinity(ctx, provider, model, opts, tried)
+	if errPick == nil {
+		m.affinity.remember(affinityKey, auth.ID, time.Now(), ttl)
+	}
+	return auth, executor, errPick
+}
+
+func (m *Manager) pickNextWithoutAffinity(ctx context.Context, provider, model string, opts cliproxyexecutor.Options, tried map[string]struct{}) (*Auth, ProviderExecutor, error) {
 	if !m.useSchedulerFastPath() {
 		return m.pickNextLegacy(ctx, provider, model, opts, tried)
 	}
@@ -2581,7 +2826,31 @@ func (m *Manager) pickNextMixedLegacy(ctx context.Context, providers []string, m
 	return authCopy, executor, providerKey, nil
 }
 
+// pickNextMixed selects the next auth across providers, keeping conversations on
+// their previously used auth while it stays available.
 func (m *Manager) pickNextMixed(ctx context.Context, providers []string, model string, opts cliproxyexecutor.Options, tried map[string]struct{}) (*Auth, ProviderExecutor, string, error) {
+	affinityKey := m.sessionAffinityKey("mixed", model, opts)
+	if affinityKey == "" {
+		return m.pickNextMixedWithoutAffinity(ctx, providers, model, opts, tried)
+	}
+	_, ttl := m.sessionAffinitySettings()
+	if authID := m.affinity.lookup(affinityKey, time.Now()); authID != "" {
+		if _, used := tried[authID]; !used {
+			auth, executor, provider, errPick := m.pickNextMixedWithoutAffinity(ctx, providers, model, pinnedAffinityOptions(opts, authID), tried)
+			if errPick == nil {
+				m.affinity.remember(affinityKey, auth.ID, time.Now(), ttl)
+				return auth, executor, provider, nil
+			}
+		}
+	}
+	auth, executor, provider, errPick := m.pickNextMixedWithoutAffinity(ctx, providers, model, opts, tried)
+	if errPick == nil {
+		m.affinity.remember(affinityKey, auth.ID, time.Now(), ttl)
+	}
+	return auth, executor, provider, errPick
+}
+
+func (m *Manager) pickNextMixedWithoutAffinity(ctx context.Context, providers []string, model string, opts cliproxyexecutor.Options, tried map[string]struct{}) (*Auth, ProviderExecutor, string, error) {
 	if !m.useSchedulerFastPath() {
 		return m.pickNextMixedLegacy(ctx, providers, model, opts, tried)
 	}
@@ -2989,10 +3258,32 @@ func (m *Manager) refreshAuth(ctx context.Context, id string) {
 	if auth != nil {
 		exec = m.executors[auth.Provider]
 	}
+	store := m.store
 	m.mu.RUnlock()
 	if auth == nil || exec == nil {
 		return
 	}
+	// Replicas sharing a store refresh one auth at a time; whoever comes second picks up the
+	// credentials the first one persisted instead of spending the rotated refresh token again.
+	if locker, ok := store.(RefreshLocker); ok {
+		lockCtx, cancel := context.WithTimeout(ctx, refreshLockTimeout)
+		unlock, errLock := locker.LockRefresh(lockCtx, auth, refreshLeaseTTL)
+		cancel()
+		switch {
+		case errLock == nil:
+			defer unlock()
+		case ctx.Err() != nil:
+			return
+		case errors.Is(errLock, context.DeadlineExceeded):
+			log.Debugf("refresh lease for %s, %s is held elsewhere, retrying later", auth.Provider, auth.ID)
+			return
+		default:
+			log.Warnf("refresh lease for %s, %s unavailable, refreshing without it: %v", auth.Provider, auth.ID, errLock)
+		}
+		if m.adoptPersistedRefresh(ctx, store, auth) {
+			return
+		}
+	}
 	cloned := auth.Clone()
 	updated, err := exec.Refresh(ctx, cloned)
 	if err != nil && errors.Is(err, context.Canceled) {
@@ -3031,6 +3322,40 @@ func (m *Manager) refreshAuth(ctx context.Context, id string) {
 	_, _ = m.Update(ctx, updated)
 }
 
+// adoptPersistedRefresh re-reads auth from a shared store and, when another replica has already
+// persisted different credentials, adopts them in place of refreshing. It reports whether it did.
+func (m *Manager) adoptPersistedRefresh(ctx context.Context, store Store, auth *Auth) bool {
+	reloader, ok := store.(AuthReloader)
+	if !ok {
+		return false
+	}
+	persisted, err := reloader.ReloadAuth(ctx, auth)
+	if err != nil {
+		log.Warnf("reload %s, %s before refresh failed: %v", auth.Provider, auth.ID, err)
+		return false
+	}
+	if persisted == nil || persisted.Metadata == nil || metadataEqual(persisted.Metadata, auth.Metadata) {
+		return false
+	}
+	log.Debugf("adopting credentials persisted by another replica for %s, %s", auth.Provider, auth.ID)
+	now := time.Now()
+	updated := auth.Clone()
+	updated.Metadata = persisted.Metadata
+	updated.Storage = nil
+	updated.LastRefreshedAt = now
+	updated.NextRefreshAfter = time.Time{}
+	updated.LastError = nil
+	updated.UpdatedAt = now
+	_, _ = m.Update(WithSkipPersist(ctx), updated)
+	return true
+}
+
+func metadataEqual(a, b map[string]any) bool {
+	rawA, errA := json.Marshal(a)
+	rawB, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(rawA, rawB)
+}
+
 func (m *Manager) executorFor(provider string) ProviderExecutor {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
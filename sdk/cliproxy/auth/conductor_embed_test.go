@@ -0,0 +1,106 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+type embeddingPoolExecutor struct {
+	openAICompatPoolExecutor
+	embedded []string
+	observe  func(auth *Auth)
+}
+
+func (e *embeddingPoolExecutor) Embed(_ context.Context, auth *Auth, req cliproxyexecutor.Request, _ cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	e.mu.Lock()
+	e.embedded = append(e.embedded, auth.ID)
+	e.mu.Unlock()
+	if e.observe != nil {
+		e.observe(auth)
+	}
+	return cliproxyexecutor.Response{Payload: []byte("embedded:" + req.Model)}, nil
+}
+
+func registerEmbedTestAuth(t *testing.T, m *Manager, id, provider, model string) {
+	t.Helper()
+	if _, err := m.Register(context.Background(), &Auth{ID: id, Provider: provider, Status: StatusActive}); err != nil {
+		t.Fatalf("register auth: %v", err)
+	}
+	reg := registry.GetGlobalRegistry()
+	reg.RegisterClient(id, provider, []*registry.ModelInfo{{ID: model}})
+	t.Cleanup(func() {
+		reg.UnregisterClient(id)
+	})
+}
+
+func TestManagerExecuteEmbed_SkipsExecutorsWithoutEmbeddings(t *testing.T) {
+	model := "embed-model-" + t.Name()
+	m := NewManager(nil, nil, nil)
+	plain := &openAICompatPoolExecutor{id: "plain"}
+	embedder := &embeddingPoolExecutor{openAICompatPoolExecutor: openAICompatPoolExecutor{id: "embedder"}}
+	m.RegisterExecutor(plain)
+	m.RegisterExecutor(embedder)
+	registerEmbedTestAuth(t, m, "plain-"+t.Name(), "plain", model)
+	registerEmbedTestAuth(t, m, "embedder-"+t.Name(), "embedder", model)
+
+	for i := 0; i < 3; i++ {
+		resp, err := m.ExecuteEmbed(context.Background(), []string{"plain", "embedder"}, cliproxyexecutor.Request{Model: model}, cliproxyexecutor.Options{})
+		if err != nil {
+			t.Fatalf("ExecuteEmbed() error = %v", err)
+		}
+		if string(resp.Payload) != "embedded:"+model {
+			t.Fatalf("payload = %q", string(resp.Payload))
+		}
+	}
+	if len(embedder.embedded) != 3 {
+		t.Fatalf("embed calls = %d, want 3", len(embedder.embedded))
+	}
+	current, ok := m.GetByID("plain-" + t.Name())
+	if !ok || current.Status != StatusActive || current.LastError != nil {
+		t.Fatalf("auth without embeddings support was marked failed: %+v", current)
+	}
+}
+
+func TestManagerExecuteEmbed_NoEmbeddingExecutor(t *testing.T) {
+	model := "embed-model-" + t.Name()
+	m := NewManager(nil, nil, nil)
+	m.RegisterExecutor(&openAICompatPoolExecutor{id: "plain"})
+	registerEmbedTestAuth(t, m, "plain-"+t.Name(), "plain", model)
+
+	_, err := m.ExecuteEmbed(context.Background(), []string{"plain"}, cliproxyexecutor.Request{Model: model}, cliproxyexecutor.Options{})
+	if !errors.Is(err, errEmbeddingsUnsupported) {
+		t.Fatalf("ExecuteEmbed() error = %v, want %v", err, errEmbeddingsUnsupported)
+	}
+}
+
+func TestManagerExecuteEmbed_HoldsConcurrencySlot(t *testing.T) {
+	model := "embed-model-" + t.Name()
+	m := NewManager(nil, nil, nil)
+	embedder := &embeddingPoolExecutor{openAICompatPoolExecutor: openAICompatPoolExecutor{id: "embedder"}}
+	inFlight := -1
+	embedder.observe = func(auth *Auth) { inFlight = m.InFlight(auth.ID) }
+	m.RegisterExecutor(embedder)
+	authID := "embedder-" + t.Name()
+	if _, err := m.Register(context.Background(), &Auth{ID: authID, Provider: "embedder", Status: StatusActive, Attributes: map[string]string{"max_concurrency": "1"}}); err != nil {
+		t.Fatalf("register auth: %v", err)
+	}
+	reg := registry.GetGlobalRegistry()
+	reg.RegisterClient(authID, "embedder", []*registry.ModelInfo{{ID: model}})
+	t.Cleanup(func() {
+		reg.UnregisterClient(authID)
+	})
+
+	if _, err := m.ExecuteEmbed(context.Background(), []string{"embedder"}, cliproxyexecutor.Request{Model: model}, cliproxyexecutor.Options{}); err != nil {
+		t.Fatalf("ExecuteEmbed() error = %v", err)
+	}
+	if inFlight != 1 {
+		t.Fatalf("in-flight during Embed = %d, want the call to hold the slot", inFlight)
+	}
+	if got := m.InFlight(authID); got != 0 {
+		t.Fatalf("in-flight after Embed = %d, want the slot released", got)
+	}
+}
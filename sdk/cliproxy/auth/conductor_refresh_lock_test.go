@@ -0,0 +1,135 @@
+package auth
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+type lockingStore struct {
+	mu        sync.Mutex
+	persisted map[string]any
+	held      bool
+	events    []string
+}
+
+func (s *lockingStore) record(event string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, event)
+}
+
+func (s *lockingStore) List(context.Context) ([]*Auth, error) { return nil, nil }
+
+func (s *lockingStore) Save(_ context.Context, auth *Auth) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.persisted = auth.Metadata
+	if !s.held {
+		s.events = append(s.events, "save-unlocked")
+	} else {
+		s.events = append(s.events, "save")
+	}
+	return "", nil
+}
+
+func (s *lockingStore) Delete(context.Context, string) error { return nil }
+
+func (s *lockingStore) LockRefresh(context.Context, *Auth, time.Duration) (func(), error) {
+	s.mu.Lock()
+	s.held = true
+	s.events = append(s.events, "lock")
+	s.mu.Unlock()
+	return func() {
+		s.mu.Lock()
+		s.held = false
+		s.events = append(s.events, "unlock")
+		s.mu.Unlock()
+	}, nil
+}
+
+func (s *lockingStore) ReloadAuth(_ context.Context, auth *Auth) (*Auth, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.persisted == nil {
+		return nil, nil
+	}
+	reloaded := auth.Clone()
+	reloaded.Metadata = s.persisted
+	return reloaded, nil
+}
+
+type rotatingRefreshExecutor struct {
+	replaceAwareExecutor
+	store *lockingStore
+	calls int
+}
+
+func (e *rotatingRefreshExecutor) Refresh(_ context.Context, auth *Auth) (*Auth, error) {
+	e.calls++
+	e.store.record("refresh")
+	auth.Metadata["refresh_token"] = "rt-2"
+	return auth, nil
+}
+
+func TestRefreshAuth_HoldsLeaseWhileRefreshingAndSaving(t *testing.T) {
+	store := &lockingStore{persisted: map[string]any{"type": "claude", "refresh_token": "rt-1"}}
+	mgr := NewManager(store, nil, nil)
+	exec := &rotatingRefreshExecutor{replaceAwareExecutor: replaceAwareExecutor{id: "claude"}, store: store}
+	mgr.RegisterExecutor(exec)
+	if _, err := mgr.Register(WithSkipPersist(context.Background()), &Auth{
+		ID:       "claude-1",
+		Provider: "claude",
+		Metadata: map[string]any{"type": "claude", "refresh_token": "rt-1"},
+	}); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	mgr.refreshAuth(context.Background(), "claude-1")
+
+	if exec.calls != 1 {
+		t.Fatalf("Refresh calls = %d, want 1", exec.calls)
+	}
+	want := []string{"lock", "refresh", "save", "unlock"}
+	if len(store.events) != len(want) {
+		t.Fatalf("events = %v, want %v", store.events, want)
+	}
+	for i := range want {
+		if store.events[i] != want[i] {
+			t.Fatalf("events = %v, want %v", store.events, want)
+		}
+	}
+}
+
+func TestRefreshAuth_AdoptsTokenPersistedByAnotherReplica(t *testing.T) {
+	store := &lockingStore{persisted: map[string]any{"type": "claude", "refresh_token": "rt-from-peer"}}
+	mgr := NewManager(store, nil, nil)
+	exec := &rotatingRefreshExecutor{replaceAwareExecutor: replaceAwareExecutor{id: "claude"}, store: store}
+	mgr.RegisterExecutor(exec)
+	if _, err := mgr.Register(WithSkipPersist(context.Background()), &Auth{
+		ID:       "claude-1",
+		Provider: "claude",
+		Metadata: map[string]any{"type": "claude", "refresh_token": "rt-1"},
+	}); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	mgr.refreshAuth(context.Background(), "claude-1")
+
+	if exec.calls != 0 {
+		t.Fatalf("Refresh calls = %d, want 0 after adopting the persisted token", exec.calls)
+	}
+	current, ok := mgr.GetByID("claude-1")
+	if !ok || current.Metadata["refresh_token"] != "rt-from-peer" {
+		t.Fatalf("auth metadata = %v, want the persisted token", current.Metadata)
+	}
+	if current.LastRefreshedAt.IsZero() {
+		t.Fatalf("LastRefreshedAt not updated after adopting")
+	}
+	for _, event := range store.events {
+		if event == "save" || event == "save-unlocked" {
+			t.Fatalf("adopted token was saved again: %v", store.events)
+		}
+	}
+}
@@ -1,5 +1,10 @@
 package auth
 
+import (
+	"errors"
+	"net/http"
+)
+
 // Error describes an authentication related failure in a provider agnostic format.
 type Error struct {
 	// Code is a short machine readable identifier.
@@ -30,3 +35,22 @@ func (e *Error) StatusCode() int {
 	}
 	return e.HTTPStatus
 }
+
+// IsTransientRateLimit reports whether err is a 429 that clears on its own: an upstream rate
+// limit, a model cooldown, saturated credentials or a full request queue. Rate limits imposed by
+// the client key policy, such as a spent budget, are not transient.
+func IsTransientRateLimit(err error) bool {
+	if statusCodeFromError(err) != http.StatusTooManyRequests {
+		return false
+	}
+	var authErr *Error
+	if errors.As(err, &authErr) && authErr != nil {
+		switch authErr.Code {
+		case "auth_saturated", "queue_full":
+			return true
+		default:
+			return authErr.Retryable
+		}
+	}
+	return true
+}
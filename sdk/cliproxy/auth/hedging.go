@@ -0,0 +1,249 @@
+package auth
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/thinking"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+const (
+	hedgeDefaultPercentile = 95
+	hedgeDefaultDelay      = 2 * time.Second
+	hedgeDefaultMinDelay   = 250 * time.Millisecond
+	// hedgeMinSamples is the number of observed latencies required before the percentile is trusted.
+	hedgeMinSamples = 20
+	// hedgeMaxSamples bounds the per-model latency window.
+	hedgeMaxSamples = 128
+	// hedgeMaxModels caps the number of tracked models.
+	hedgeMaxModels = 1024
+)
+
+// hedgeLatencyTracker keeps a sliding window of successful latencies per model.
+type hedgeLatencyTracker struct {
+	mu      sync.Mutex
+	samples map[string]*hedgeLatencyWindow
+}
+
+type hedgeLatencyWindow struct {
+	values []time.Duration
+	next   int
+}
+
+func newHedgeLatencyTracker() *hedgeLatencyTracker {
+	return &hedgeLatencyTracker{samples: make(map[string]*hedgeLatencyWindow)}
+}
+
+// observe records a successful latency for model.
+func (t *hedgeLatencyTracker) observe(model string, latency time.Duration) {
+	key := canonicalModelKey(model)
+	if t == nil || key == "" || latency <= 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	window := t.samples[key]
+	if window == nil {
+		if len(t.samples) >= hedgeMaxModels {
+			t.samples = make(map[string]*hedgeLatencyWindow)
+		}
+		window = &hedgeLatencyWindow{}
+		t.samples[key] = window
+	}
+	if len(window.values) < hedgeMaxSamples {
+		window.values = append(window.values, latency)
+		return
+	}
+	window.values[window.next] = latency
+	window.next = (window.next + 1) % hedgeMaxSamples
+}
+
+// percentile returns the p-th percentile latency for model, or false when too few samples exist.
+func (t *hedgeLatencyTracker) percentile(model string, p float64) (time.Duration, bool) {
+	key := canonicalModelKey(model)
+	if t == nil || key == "" {
+		return 0, false
+	}
+	t.mu.Lock()
+	window := t.samples[key]
+	if window == nil || len(window.values) < hedgeMinSamples {
+		t.mu.Unlock()
+		return 0, false
+	}
+	values := append([]time.Duration(nil), window.values...)
+	t.mu.Unlock()
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+	index := int(math.Ceil(p/100*float64(len(values)))) - 1
+	if index < 0 {
+		index = 0
+	}
+	if index >= len(values) {
+		index = len(values) - 1
+	}
+	return values[index], true
+}
+
+// hedgeDelayFor reports whether a non-streaming request for model should be hedged and after how long.
+func (m *Manager) hedgeDelayFor(model string, opts cliproxyexecutor.Options) (time.Duration, bool) {
+	if m == nil || opts.Stream || pinnedAuthIDFromMetadata(opts.Metadata) != "" {
+		return 0, false
+	}
+	cfg, _ := m.runtimeConfig.Load().(*internalconfig.Config)
+	if cfg == nil || len(cfg.Routing.Hedging.Models) == 0 {
+		return 0, false
+	}
+	hedging := cfg.Routing.Hedging
+	baseModel := strings.ToLower(strings.TrimSpace(thinking.ParseSuffix(model).ModelName))
+	matched := false
+	for _, pattern := range hedging.Models {
+		if matchHedgeModelPattern(strings.ToLower(strings.TrimSpace(pattern)), baseModel) {
+			matched = true
+			break
+		}
+	}
+	if !matched {
+		return 0, false
+	}
+	percentile := hedging.Percentile
+	if percentile <= 0 || percentile > 100 {
+		percentile = hedgeDefaultPercentile
+	}
+	delay := hedgeDefaultDelay
+	if hedging.DelayMS > 0 {
+		delay = time.Duration(hedging.DelayMS) * time.Millisecond
+	}
+	if observed, ok := m.hedgeLatency.percentile(model, percentile); ok {
+		delay = observed
+	}
+	minDelay := hedgeDefaultMinDelay
+	if hedging.MinDelayMS > 0 {
+		minDelay = time.Duration(hedging.MinDelayMS) * time.Millisecond
+	}
+	if delay < minDelay {
+		delay = minDelay
+	}
+	return delay, true
+}
+
+// matchHedgeModelPattern performs glob matching where '*' matches zero or more characters.
+func matchHedgeModelPattern(pattern, model string) bool {
+	if pattern == "" {
+		return false
+	}
+	if pattern == "*" {
+		return true
+	}
+	pi, si := 0, 0
+	starIdx := -1
+	matchIdx := 0
+	for si < len(model) {
+		if pi < len(pattern) && pattern[pi] == model[si] {
+			pi++
+			si++
+			continue
+		}
+		if pi < len(pattern) && pattern[pi] == '*' {
+			starIdx = pi
+			matchIdx = si
+			pi++
+			continue
+		}
+		if starIdx != -1 {
+			pi = starIdx + 1
+			matchIdx++
+			si = matchIdx
+			continue
+		}
+		return false
+	}
+	for pi < len(pattern) && pattern[pi] == '*' {
+		pi++
+	}
+	return pi == len(pattern)
+}
+
+// hedgeOutcome carries the result of one hedged attempt.
+type hedgeOutcome struct {
+	authID string
+	resp   cliproxyexecutor.Response
+	err    error
+}
+
+// executeHedged runs primary and, if it has not answered within delay, a second attempt on
+// another auth. The first success wins and the other attempt is cancelled. Cancelled attempts
+// are not marked as credential failures; executors still report their usage.
+func (m *Manager) executeHedged(ctx context.Context, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options, tried, attempted map[string]struct{}, primary *Auth, primaryExecutor ProviderExecutor, primaryProvider string, delay time.Duration) (cliproxyexecutor.Response, error) {
+	routeModel := req.Model
+	hedgeCtx, cancelAll := context.WithCancel(ctx)
+	defer cancelAll()
+
+	results := make(chan hedgeOutcome, 2)
+	launch := func(auth *Auth, executor ProviderExecutor, provider string, models []string, pooled bool) {
+		// Each attempt gets its own metadata so concurrent executors never share a map.
+		attemptOpts := opts
+		attemptOpts.Metadata = cloneMetadata(opts.Metadata)
+		delete(attemptOpts.Metadata, cliproxyexecutor.SelectedAuthCallbackMetadataKey)
+		go func() {
+			resp, errExec := m.executeWithAuth(hedgeCtx, auth, executor, provider, req, attemptOpts, routeModel, models, pooled)
+			results <- hedgeOutcome{authID: auth.ID, resp: resp, err: errExec}
+		}()
+	}
+
+	models, pooled := m.preparedExecutionModels(primary, routeModel)
+	launch(primary, primaryExecutor, primaryProvider, models, pooled)
+	inFlight := 1
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	timerC := timer.C
+
+	var lastErr error
+	for inFlight > 0 {
+		select {
+		case <-ctx.Done():
+			return cliproxyexecutor.Response{}, ctx.Err()
+		case <-timerC:
+			timerC = nil
+			auth, executor, provider, errPick := m.pickNextMixed(ctx, providers, routeModel, opts, tried)
+			if errPick != nil {
+				continue
+			}
+			tried[auth.ID] = struct{}{}
+			hedgeModels, hedgePooled := m.preparedExecutionModels(auth, routeModel)
+			if len(hedgeModels) == 0 {
+				continue
+			}
+			attempted[auth.ID] = struct{}{}
+			logEntryWithRequestID(ctx).Debugf("hedging request for model %s on auth %s after %s", routeModel, auth.ID, delay)
+			launch(auth, executor, provider, hedgeModels, hedgePooled)
+			inFlight++
+		case outcome := <-results:
+			inFlight--
+			if outcome.err == nil {
+				cancelAll()
+				publishSelectedAuthMetadata(opts.Metadata, outcome.authID)
+				m.rememberSessionAffinity("mixed", routeModel, opts, outcome.authID)
+				return outcome.resp, nil
+			}
+			if isRequestInvalidError(outcome.err) {
+				return cliproxyexecutor.Response{}, outcome.err
+			}
+			lastErr = outcome.err
+		}
+	}
+	return cliproxyexecutor.Response{}, lastErr
+}
+
+func cloneMetadata(meta map[string]any) map[string]any {
+	out := make(map[string]any, len(meta))
+	for k, v := range meta {
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,165 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+type hedgeTestExecutor struct {
+	id     string
+	delays map[string]time.Duration
+
+	mu        sync.Mutex
+	calls     []string
+	cancelled []string
+}
+
+func (e *hedgeTestExecutor) Identifier() string { return e.id }
+
+func (e *hedgeTestExecutor) Execute(ctx context.Context, auth *Auth, _ cliproxyexecutor.Request, _ cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	e.mu.Lock()
+	e.calls = append(e.calls, auth.ID)
+	delay := e.delays[auth.ID]
+	e.mu.Unlock()
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		e.mu.Lock()
+		e.cancelled = append(e.cancelled, auth.ID)
+		e.mu.Unlock()
+		return cliproxyexecutor.Response{}, ctx.Err()
+	case <-timer.C:
+	}
+	return cliproxyexecutor.Response{Payload: []byte(auth.ID)}, nil
+}
+
+func (e *hedgeTestExecutor) ExecuteStream(context.Context, *Auth, cliproxyexecutor.Request, cliproxyexecutor.Options) (*cliproxyexecutor.StreamResult, error) {
+	return nil, &Error{HTTPStatus: http.StatusNotImplemented, Message: "ExecuteStream not implemented"}
+}
+
+func (e *hedgeTestExecutor) Refresh(_ context.Context, auth *Auth) (*Auth, error) { return auth, nil }
+
+func (e *hedgeTestExecutor) CountTokens(context.Context, *Auth, cliproxyexecutor.Request, cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	return cliproxyexecutor.Response{}, &Error{HTTPStatus: http.StatusNotImplemented, Message: "CountTokens not implemented"}
+}
+
+func (e *hedgeTestExecutor) HttpRequest(context.Context, *Auth, *http.Request) (*http.Response, error) {
+	return nil, &Error{HTTPStatus: http.StatusNotImplemented, Message: "HttpRequest not implemented"}
+}
+
+func (e *hedgeTestExecutor) snapshot() ([]string, []string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]string(nil), e.calls...), append([]string(nil), e.cancelled...)
+}
+
+func newHedgeTestManager(t *testing.T, model string, executor *hedgeTestExecutor, slowID, fastID string) *Manager {
+	t.Helper()
+
+	registerSchedulerModels(t, executor.id, model, slowID, fastID)
+	manager := NewManager(nil, &RoundRobinSelector{}, nil)
+	manager.RegisterExecutor(executor)
+	manager.SetConfig(&internalconfig.Config{
+		Routing: internalconfig.RoutingConfig{
+			Hedging: internalconfig.HedgingConfig{Models: []string{"hedge-*"}, DelayMS: 20, MinDelayMS: 1},
+		},
+	})
+	// The slow auth has the higher priority so it is always tried first.
+	if _, err := manager.Register(context.Background(), &Auth{ID: slowID, Provider: executor.id, Attributes: map[string]string{"priority": "10"}}); err != nil {
+		t.Fatalf("Register(slow) error = %v", err)
+	}
+	if _, err := manager.Register(context.Background(), &Auth{ID: fastID, Provider: executor.id}); err != nil {
+		t.Fatalf("Register(fast) error = %v", err)
+	}
+	return manager
+}
+
+func TestHedgeLatencyTracker_Percentile(t *testing.T) {
+	t.Parallel()
+
+	tracker := newHedgeLatencyTracker()
+	for i := 1; i < hedgeMinSamples; i++ {
+		tracker.observe("m", time.Duration(i)*time.Millisecond)
+	}
+	if _, ok := tracker.percentile("m", 95); ok {
+		t.Fatalf("percentile() ok = true before %d samples", hedgeMinSamples)
+	}
+	tracker.observe("m", time.Duration(hedgeMinSamples)*time.Millisecond)
+	got, ok := tracker.percentile("m", 95)
+	if !ok {
+		t.Fatalf("percentile() ok = false, want true")
+	}
+	if got != 19*time.Millisecond {
+		t.Fatalf("percentile() = %v, want %v", got, 19*time.Millisecond)
+	}
+}
+
+func TestManagerExecute_HedgesSlowRequestAndCancelsLoser(t *testing.T) {
+	model := "hedge-slow-model"
+	executor := &hedgeTestExecutor{
+		id:     "hedge-provider-a",
+		delays: map[string]time.Duration{"hedge-slow": 5 * time.Second},
+	}
+	manager := newHedgeTestManager(t, model, executor, "hedge-slow", "hedge-fast")
+
+	resp, err := manager.Execute(context.Background(), []string{executor.id}, cliproxyexecutor.Request{Model: model}, cliproxyexecutor.Options{})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if string(resp.Payload) != "hedge-fast" {
+		t.Fatalf("Execute() payload = %q, want %q", resp.Payload, "hedge-fast")
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		calls, cancelled := executor.snapshot()
+		if len(cancelled) == 1 {
+			if len(calls) != 2 || calls[0] != "hedge-slow" || calls[1] != "hedge-fast" {
+				t.Fatalf("executor calls = %v, want [hedge-slow hedge-fast]", calls)
+			}
+			if cancelled[0] != "hedge-slow" {
+				t.Fatalf("cancelled = %v, want [hedge-slow]", cancelled)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("loser was not cancelled; calls = %v", calls)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	slow, ok := manager.GetByID("hedge-slow")
+	if !ok {
+		t.Fatalf("GetByID(hedge-slow) not found")
+	}
+	if state := slow.ModelStates[model]; state != nil && state.Unavailable {
+		t.Fatalf("cancelled hedge loser was marked unavailable: %+v", state)
+	}
+}
+
+func TestManagerExecute_DoesNotHedgeUnmatchedModel(t *testing.T) {
+	model := "plain-slow-model"
+	executor := &hedgeTestExecutor{
+		id:     "hedge-provider-b",
+		delays: map[string]time.Duration{"plain-slow": 60 * time.Millisecond},
+	}
+	manager := newHedgeTestManager(t, model, executor, "plain-slow", "plain-fast")
+
+	resp, err := manager.Execute(context.Background(), []string{executor.id}, cliproxyexecutor.Request{Model: model}, cliproxyexecutor.Options{})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if string(resp.Payload) != "plain-slow" {
+		t.Fatalf("Execute() payload = %q, want %q", resp.Payload, "plain-slow")
+	}
+	if calls, _ := executor.snapshot(); len(calls) != 1 {
+		t.Fatalf("executor calls = %v, want a single attempt", calls)
+	}
+}
@@ -0,0 +1,177 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/thinking"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+// modelFallback is one resolved entry of a configured fallback chain.
+type modelFallback struct {
+	model     string
+	providers []string
+}
+
+// modelFallbacksFor returns the fallback chain to try after err, or nil when the
+// error does not indicate that every credential for model is cooling down.
+// Requests pinned to a specific auth never fall back to another model, and fallbacks
+// outside the model allowlist of the request's client key are skipped.
+func (m *Manager) modelFallbacksFor(providers []string, model string, opts cliproxyexecutor.Options, err error) []modelFallback {
+	if m == nil || err == nil {
+		return nil
+	}
+	if pinnedAuthIDFromMetadata(opts.Metadata) != "" {
+		return nil
+	}
+	cfg, _ := m.runtimeConfig.Load().(*internalconfig.Config)
+	if cfg == nil || len(cfg.ModelFallbacks) == 0 {
+		return nil
+	}
+	requested := thinking.ParseSuffix(model)
+	chain := cfg.ModelFallbacks[strings.ToLower(strings.TrimSpace(requested.ModelName))]
+	if len(chain) == 0 {
+		return nil
+	}
+	if !m.shouldFallbackAfterError(err, providers, model) {
+		return nil
+	}
+	var clientKey *internalconfig.ClientKey
+	if key, _ := opts.Metadata[cliproxyexecutor.ClientKeyMetadataKey].(string); key != "" {
+		clientKey = cfg.FindClientKey(key)
+	}
+	out := make([]modelFallback, 0, len(chain))
+	for _, candidate := range chain {
+		fallbackModel := preserveResolvedModelSuffix(candidate, requested)
+		baseModel := thinking.ParseSuffix(fallbackModel).ModelName
+		normalizedModel, providerID := registry.ParseProviderPrefixedModelID(baseModel)
+		var fallbackProviders []string
+		if providerID != "" {
+			fallbackProviders = []string{providerID}
+			fallbackModel = preserveResolvedModelSuffix(normalizedModel, thinking.ParseSuffix(fallbackModel))
+		} else {
+			fallbackProviders = util.GetProviderName(baseModel)
+		}
+		fallbackProviders = m.normalizeProviders(fallbackProviders)
+		if len(fallbackProviders) == 0 {
+			continue
+		}
+		if clientKey != nil && !clientKey.AllowsModel(fallbackModel) {
+			continue
+		}
+		out = append(out, modelFallback{model: fallbackModel, providers: fallbackProviders})
+	}
+	return out
+}
+
+// shouldFallbackAfterError reports whether err means the requested model is fully cooling down.
+// An upstream 429 only qualifies once no credential for the model remains ready.
+func (m *Manager) shouldFallbackAfterError(err error, providers []string, model string) bool {
+	if err == nil || isRequestInvalidError(err) {
+		return false
+	}
+	var cooldownErr *modelCooldownError
+	if errors.As(err, &cooldownErr) {
+		return true
+	}
+	if statusCodeFromError(err) != http.StatusTooManyRequests {
+		return false
+	}
+	return m.modelFullyCoolingDown(providers, model)
+}
+
+// modelFullyCoolingDown reports whether every enabled credential able to serve model is blocked.
+func (m *Manager) modelFullyCoolingDown(providers []string, model string) bool {
+	if m == nil || len(providers) == 0 {
+		return false
+	}
+	providerSet := make(map[string]struct{}, len(providers))
+	for _, provider := range providers {
+		providerSet[strings.TrimSpace(strings.ToLower(provider))] = struct{}{}
+	}
+	now := time.Now()
+	registryRef := registry.GetGlobalRegistry()
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	candidates := 0
+	for _, auth := range m.auths {
+		if auth == nil || auth.Disabled || auth.Status == StatusDisabled {
+			continue
+		}
+		if _, ok := providerSet[strings.TrimSpace(strings.ToLower(auth.Provider))]; !ok {
+			continue
+		}
+		if !m.authSupportsRouteModel(registryRef, auth, model) {
+			continue
+		}
+		blocked, reason, _ := isAuthBlockedForModel(auth, m.selectionModelForAuth(auth, model), now)
+		if blocked && reason == blockReasonAvailabilityWindow {
+			// Credentials held back by their schedule neither count as cooling down nor as ready.
+			continue
+		}
+		candidates++
+		if !blocked || reason == blockReasonDisabled {
+			return false
+		}
+	}
+	return candidates > 0
+}
+
+// fallbackRequest rewrites req and opts so executors translate the payload for fallbackModel.
+func fallbackRequest(req cliproxyexecutor.Request, opts cliproxyexecutor.Options, fallbackModel string) (cliproxyexecutor.Request, cliproxyexecutor.Options) {
+	req.Model = fallbackModel
+	meta := make(map[string]any, len(opts.Metadata)+1)
+	for k, v := range opts.Metadata {
+		meta[k] = v
+	}
+	meta[cliproxyexecutor.RequestedModelMetadataKey] = fallbackModel
+	opts.Metadata = meta
+	return req, opts
+}
+
+// executeModelFallbacks runs the configured fallback chain for a non-streaming request.
+// It returns the original error when no fallback succeeds.
+func (m *Manager) executeModelFallbacks(ctx context.Context, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options, cause error) (cliproxyexecutor.Response, error) {
+	for _, fallback := range m.modelFallbacksFor(providers, req.Model, opts, cause) {
+		logEntryWithRequestID(ctx).Infof("all credentials for model %s are cooling down, falling back to %s", req.Model, fallback.model)
+		fallbackReq, fallbackOpts := fallbackRequest(req, opts, fallback.model)
+		resp, errExec := m.executeWithCooldownRetry(ctx, fallback.providers, fallbackReq, fallbackOpts)
+		if errExec == nil {
+			return resp, nil
+		}
+		if errCtx := ctx.Err(); errCtx != nil {
+			return cliproxyexecutor.Response{}, errCtx
+		}
+		if isRequestInvalidError(errExec) {
+			return cliproxyexecutor.Response{}, errExec
+		}
+	}
+	return cliproxyexecutor.Response{}, cause
+}
+
+// executeStreamModelFallbacks runs the configured fallback chain for a streaming request.
+// It returns the original error when no fallback succeeds.
+func (m *Manager) executeStreamModelFallbacks(ctx context.Context, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options, cause error) (*cliproxyexecutor.StreamResult, error) {
+	for _, fallback := range m.modelFallbacksFor(providers, req.Model, opts, cause) {
+		logEntryWithRequestID(ctx).Infof("all credentials for model %s are cooling down, falling back to %s", req.Model, fallback.model)
+		fallbackReq, fallbackOpts := fallbackRequest(req, opts, fallback.model)
+		result, errStream := m.executeStreamWithCooldownRetry(ctx, fallback.providers, fallbackReq, fallbackOpts)
+		if errStream == nil {
+			return result, nil
+		}
+		if errCtx := ctx.Err(); errCtx != nil {
+			return nil, errCtx
+		}
+		if isRequestInvalidError(errStream) {
+			return nil, errStream
+		}
+	}
+	return nil, cause
+}
@@ -0,0 +1,169 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+func newModelFallbackTestManager(t *testing.T, sourceModel, targetModel string, sourceState *ModelState) (*Manager, *openAICompatPoolExecutor, *openAICompatPoolExecutor) {
+	t.Helper()
+
+	registerSchedulerModels(t, "fallback-source", sourceModel, "fallback-source-auth")
+	registerSchedulerModels(t, "fallback-target", targetModel, "fallback-target-auth")
+
+	source := &openAICompatPoolExecutor{id: "fallback-source"}
+	target := &openAICompatPoolExecutor{id: "fallback-target"}
+	manager := NewManager(nil, nil, nil)
+	manager.RegisterExecutor(source)
+	manager.RegisterExecutor(target)
+	manager.SetConfig(&internalconfig.Config{
+		ModelFallbacks: map[string][]string{sourceModel: {targetModel}},
+	})
+
+	sourceAuth := &Auth{ID: "fallback-source-auth", Provider: "fallback-source"}
+	if sourceState != nil {
+		sourceAuth.ModelStates = map[string]*ModelState{sourceModel: sourceState}
+	}
+	if _, err := manager.Register(context.Background(), sourceAuth); err != nil {
+		t.Fatalf("Register(source) error = %v", err)
+	}
+	if _, err := manager.Register(context.Background(), &Auth{ID: "fallback-target-auth", Provider: "fallback-target"}); err != nil {
+		t.Fatalf("Register(target) error = %v", err)
+	}
+	return manager, source, target
+}
+
+func coolingDownModelState() *ModelState {
+	return &ModelState{
+		Status:         StatusError,
+		Unavailable:    true,
+		NextRetryAfter: time.Now().Add(time.Hour),
+		Quota:          QuotaState{Exceeded: true, NextRecoverAt: time.Now().Add(time.Hour)},
+	}
+}
+
+func TestManagerExecute_FallsBackWhenModelCoolingDown(t *testing.T) {
+	sourceModel := "fallback-exec-source"
+	targetModel := "fallback-exec-target"
+	manager, source, target := newModelFallbackTestManager(t, sourceModel, targetModel, coolingDownModelState())
+
+	resp, err := manager.Execute(context.Background(), []string{"fallback-source"}, cliproxyexecutor.Request{Model: sourceModel}, cliproxyexecutor.Options{})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if string(resp.Payload) != targetModel {
+		t.Fatalf("Execute() payload = %q, want %q", resp.Payload, targetModel)
+	}
+	if got := source.ExecuteModels(); len(got) != 0 {
+		t.Fatalf("source executor calls = %v, want none", got)
+	}
+	if got := target.ExecuteModels(); len(got) != 1 || got[0] != targetModel {
+		t.Fatalf("target executor calls = %v, want [%s]", got, targetModel)
+	}
+}
+
+func TestManagerExecuteStream_FallsBackWhenModelCoolingDown(t *testing.T) {
+	sourceModel := "fallback-stream-source"
+	targetModel := "fallback-stream-target"
+	manager, _, target := newModelFallbackTestManager(t, sourceModel, targetModel, coolingDownModelState())
+
+	result, err := manager.ExecuteStream(context.Background(), []string{"fallback-source"}, cliproxyexecutor.Request{Model: sourceModel}, cliproxyexecutor.Options{})
+	if err != nil {
+		t.Fatalf("ExecuteStream() error = %v", err)
+	}
+	var payload []byte
+	for chunk := range result.Chunks {
+		if chunk.Err != nil {
+			t.Fatalf("stream chunk error = %v", chunk.Err)
+		}
+		payload = append(payload, chunk.Payload...)
+	}
+	if string(payload) != targetModel {
+		t.Fatalf("stream payload = %q, want %q", payload, targetModel)
+	}
+	if got := target.StreamModels(); len(got) != 1 || got[0] != targetModel {
+		t.Fatalf("target stream calls = %v, want [%s]", got, targetModel)
+	}
+}
+
+func TestManagerExecute_FallbackKeepsThinkingSuffix(t *testing.T) {
+	sourceModel := "fallback-suffix-source"
+	targetModel := "fallback-suffix-target"
+	manager, _, target := newModelFallbackTestManager(t, sourceModel, targetModel, coolingDownModelState())
+
+	if _, err := manager.Execute(context.Background(), []string{"fallback-source"}, cliproxyexecutor.Request{Model: sourceModel + "(high)"}, cliproxyexecutor.Options{}); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if got := target.ExecuteModels(); len(got) != 1 || got[0] != targetModel+"(high)" {
+		t.Fatalf("target executor calls = %v, want [%s(high)]", got, targetModel)
+	}
+}
+
+func TestManagerExecute_NoFallbackForNonCooldownErrors(t *testing.T) {
+	sourceModel := "fallback-error-source"
+	targetModel := "fallback-error-target"
+	manager, source, target := newModelFallbackTestManager(t, sourceModel, targetModel, nil)
+	source.executeErrors = map[string]error{
+		sourceModel: &Error{HTTPStatus: http.StatusInternalServerError, Message: "boom"},
+	}
+
+	if _, err := manager.Execute(context.Background(), []string{"fallback-source"}, cliproxyexecutor.Request{Model: sourceModel}, cliproxyexecutor.Options{}); err == nil {
+		t.Fatalf("Execute() error = nil, want upstream error")
+	}
+	if got := target.ExecuteModels(); len(got) != 0 {
+		t.Fatalf("target executor calls = %v, want none", got)
+	}
+}
+
+func TestManagerExecute_FallsBackAfterLastCredentialHitsRateLimit(t *testing.T) {
+	sourceModel := "fallback-429-source"
+	targetModel := "fallback-429-target"
+	manager, source, target := newModelFallbackTestManager(t, sourceModel, targetModel, nil)
+	source.executeErrors = map[string]error{
+		sourceModel: &Error{HTTPStatus: http.StatusTooManyRequests, Message: "rate limited"},
+	}
+
+	resp, err := manager.Execute(context.Background(), []string{"fallback-source"}, cliproxyexecutor.Request{Model: sourceModel}, cliproxyexecutor.Options{})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if string(resp.Payload) != targetModel {
+		t.Fatalf("Execute() payload = %q, want %q", resp.Payload, targetModel)
+	}
+	if got := target.ExecuteModels(); len(got) != 1 {
+		t.Fatalf("target executor calls = %v, want one call", got)
+	}
+}
+
+func TestManagerExecute_FallbackRespectsClientKeyModels(t *testing.T) {
+	sourceModel := "fallback-key-source"
+	targetModel := "fallback-key-target"
+	manager, _, target := newModelFallbackTestManager(t, sourceModel, targetModel, coolingDownModelState())
+	cfg := &internalconfig.Config{ModelFallbacks: map[string][]string{sourceModel: {targetModel}}}
+	cfg.ClientKeys = []internalconfig.ClientKey{{Key: "sk-restricted", Models: []string{"fallback-key-source*"}}}
+	manager.SetConfig(cfg)
+
+	opts := clientKeyOptions("sk-restricted")
+	if _, err := manager.Execute(context.Background(), []string{"fallback-source"}, cliproxyexecutor.Request{Model: sourceModel}, opts); err == nil {
+		t.Fatalf("Execute() error = nil, want the cooldown error instead of a disallowed fallback")
+	}
+	if _, err := manager.ExecuteStream(context.Background(), []string{"fallback-source"}, cliproxyexecutor.Request{Model: sourceModel}, opts); err == nil {
+		t.Fatalf("ExecuteStream() error = nil, want the cooldown error instead of a disallowed fallback")
+	}
+	if got := target.ExecuteModels(); len(got) != 0 {
+		t.Fatalf("target executor calls = %v, want none", got)
+	}
+	if got := target.StreamModels(); len(got) != 0 {
+		t.Fatalf("target stream calls = %v, want none", got)
+	}
+
+	// Plain API keys keep the fallback.
+	if _, err := manager.Execute(context.Background(), []string{"fallback-source"}, cliproxyexecutor.Request{Model: sourceModel}, cliproxyexecutor.Options{}); err != nil {
+		t.Fatalf("Execute() without a client key error = %v", err)
+	}
+}
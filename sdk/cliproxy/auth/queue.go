@@ -0,0 +1,362 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+const (
+	requestQueueDefaultDepth   = 256
+	requestQueueDefaultTimeout = 60 * time.Second
+	// requestQueuePollInterval is how long the queue waits for a release before it wakes a
+	// waiter on its own, covering wake-ups lost while a request was on its way into the queue.
+	requestQueuePollInterval = time.Second
+)
+
+// requestQueue parks requests while no credential is ready. Waiters are woken one at a
+// time, rotating across client keys so a single busy client cannot starve the others. Only
+// the woken waiter retries: released slots call notify, and a single timer calls it when the
+// cooldown a waiter is parked on expires.
+type requestQueue struct {
+	mu      sync.Mutex
+	clients map[string][]*queueWaiter
+	order   []string
+	next    int
+	depth   int
+
+	// timer is the single wake-up timer, due at wakeAt; generation tells a fired timer that
+	// it has been replaced.
+	timer      *time.Timer
+	wakeAt     time.Time
+	generation uint64
+}
+
+// queueWaiter is one parked request; ready is closed when it is its turn to retry.
+type queueWaiter struct {
+	client string
+	model  string
+	// notBefore keeps the waiter parked until the cooldown it failed on has expired.
+	notBefore time.Time
+	ready     chan struct{}
+}
+
+func newRequestQueue() *requestQueue {
+	return &requestQueue{clients: make(map[string][]*queueWaiter)}
+}
+
+// enqueue parks a waiter for a request of client for model, not to be woken before notBefore.
+// It fails when the queue or the client's share is full. Waiters re-queued after a failed turn
+// go to the front of their client's line.
+func (q *requestQueue) enqueue(client, model string, notBefore time.Time, maxDepth, maxPerClient int, front bool) (*queueWaiter, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	defer q.armLocked()
+	waiters := q.clients[client]
+	if !front {
+		if maxDepth > 0 && q.depth >= maxDepth {
+			return nil, false
+		}
+		if maxPerClient > 0 && len(waiters) >= maxPerClient {
+			return nil, false
+		}
+	}
+	waiter := &queueWaiter{client: client, model: model, notBefore: notBefore, ready: make(chan struct{})}
+	if len(waiters) == 0 {
+		q.order = append(q.order, client)
+	}
+	if front {
+		waiters = append([]*queueWaiter{waiter}, waiters...)
+	} else {
+		waiters = append(waiters, waiter)
+	}
+	q.clients[client] = waiters
+	q.depth++
+	return waiter, true
+}
+
+// remove takes waiter out of the queue. It returns false when the waiter was already woken.
+func (q *requestQueue) remove(waiter *queueWaiter) bool {
+	if waiter == nil {
+		return false
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	defer q.armLocked()
+	waiters := q.clients[waiter.client]
+	for index, candidate := range waiters {
+		if candidate != waiter {
+			continue
+		}
+		q.clients[waiter.client] = append(waiters[:index], waiters[index+1:]...)
+		q.depth--
+		if len(q.clients[waiter.client]) == 0 {
+			q.dropClientLocked(waiter.client)
+		}
+		return true
+	}
+	return false
+}
+
+// notify wakes the first waiter of the next client in rotation that is not parked on a
+// cooldown.
+func (q *requestQueue) notify() {
+	if q == nil {
+		return
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	defer q.armLocked()
+	now := time.Now()
+	for offset := range len(q.order) {
+		index := (q.next + offset) % len(q.order)
+		client := q.order[index]
+		waiters := q.clients[client]
+		for position, waiter := range waiters {
+			if waiter.notBefore.After(now) {
+				continue
+			}
+			q.clients[client] = append(waiters[:position], waiters[position+1:]...)
+			q.depth--
+			q.next = index + 1
+			if len(q.clients[client]) == 0 {
+				q.dropClientLocked(client)
+			}
+			close(waiter.ready)
+			return
+		}
+	}
+}
+
+// waiting reports whether requests for model are parked in the queue.
+func (q *requestQueue) waiting(model string) bool {
+	if q == nil {
+		return false
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, waiters := range q.clients {
+		for _, waiter := range waiters {
+			if waiter.model == model {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// armLocked points the wake-up timer at the next moment a waiter should be woken without a
+// release: the earliest cooldown expiry, or one poll interval away while a waiter is eligible.
+func (q *requestQueue) armLocked() {
+	var due time.Time
+	now := time.Now()
+	for _, waiters := range q.clients {
+		for _, waiter := range waiters {
+			at := waiter.notBefore
+			if !at.After(now) {
+				at = now.Add(requestQueuePollInterval)
+			}
+			if due.IsZero() || at.Before(due) {
+				due = at
+			}
+		}
+	}
+	if due.IsZero() {
+		if q.timer != nil {
+			q.timer.Stop()
+			q.timer = nil
+		}
+		q.wakeAt = time.Time{}
+		return
+	}
+	if q.timer != nil && !due.Before(q.wakeAt) {
+		return
+	}
+	if q.timer != nil {
+		q.timer.Stop()
+	}
+	q.generation++
+	generation := q.generation
+	q.wakeAt = due
+	q.timer = time.AfterFunc(due.Sub(now), func() {
+		q.mu.Lock()
+		if q.generation != generation {
+			q.mu.Unlock()
+			return
+		}
+		q.timer = nil
+		q.wakeAt = time.Time{}
+		q.mu.Unlock()
+		q.notify()
+	})
+}
+
+// size returns the number of parked requests.
+func (q *requestQueue) size() int {
+	if q == nil {
+		return 0
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.depth
+}
+
+func (q *requestQueue) dropClientLocked(client string) {
+	delete(q.clients, client)
+	for index, candidate := range q.order {
+		if candidate != client {
+			continue
+		}
+		q.order = append(q.order[:index], q.order[index+1:]...)
+		if index < q.next {
+			q.next--
+		}
+		return
+	}
+}
+
+// queueSettings returns whether queueing is enabled and its limits.
+func (m *Manager) queueSettings() (bool, int, int, time.Duration) {
+	if m == nil || m.queue == nil {
+		return false, 0, 0, 0
+	}
+	cfg, _ := m.runtimeConfig.Load().(*internalconfig.Config)
+	if cfg == nil || !cfg.Routing.Queue.Enabled {
+		return false, 0, 0, 0
+	}
+	queue := cfg.Routing.Queue
+	maxDepth := queue.MaxDepth
+	if maxDepth <= 0 {
+		maxDepth = requestQueueDefaultDepth
+	}
+	timeout := time.Duration(queue.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = requestQueueDefaultTimeout
+	}
+	return true, maxDepth, queue.MaxPerClient, timeout
+}
+
+// attemptOrQueue runs attempt unless requests for model already wait in the queue. A new
+// request then lines up behind them instead of taking the credential they are waiting for.
+func (m *Manager) attemptOrQueue(opts cliproxyexecutor.Options, model string, attempt func() error) error {
+	if enabled, _, _, _ := m.queueSettings(); enabled && !isBackgroundRequest(opts) && m.queue.waiting(model) {
+		return &Error{Code: "queue_waiters_ahead", Message: "requests for this model are already waiting for a credential", Retryable: true, HTTPStatus: http.StatusTooManyRequests}
+	}
+	return attempt()
+}
+
+// shouldQueueAfterError reports whether err means no credential is ready now but one may be
+// within timeout: every credential is busy or the model is cooling down.
+func (m *Manager) shouldQueueAfterError(err error, providers []string, model string, timeout time.Duration) bool {
+	if err == nil || isRequestInvalidError(err) {
+		return false
+	}
+	var authErr *Error
+	if errors.As(err, &authErr) && authErr != nil && (authErr.Code == "auth_saturated" || authErr.Code == "queue_waiters_ahead") {
+		return true
+	}
+	var cooldownErr *modelCooldownError
+	if errors.As(err, &cooldownErr) && cooldownErr != nil {
+		return cooldownErr.resetIn <= timeout
+	}
+	if statusCodeFromError(err) != http.StatusTooManyRequests {
+		return false
+	}
+	return m.modelFullyCoolingDown(providers, model)
+}
+
+// waitInQueue parks a request that failed with cause and retries attempt whenever it is
+// woken, until attempt succeeds, fails for an unrelated reason, or the queue timeout passes.
+// A waiter that got a credential passes the wake-up on, so the next waiter can check whether
+// another one is free.
+func (m *Manager) waitInQueue(ctx context.Context, providers []string, model string, opts cliproxyexecutor.Options, cause error, attempt func() error) error {
+	enabled, maxDepth, maxPerClient, timeout := m.queueSettings()
+	if !enabled || isBackgroundRequest(opts) || !m.shouldQueueAfterError(cause, providers, model, timeout) {
+		return cause
+	}
+	client := queueClientKey(opts)
+	waiter, ok := m.queue.enqueue(client, model, queueNotBefore(cause), maxDepth, maxPerClient, false)
+	if !ok {
+		return &Error{Code: "queue_full", Message: "request queue is full", Retryable: true, HTTPStatus: http.StatusTooManyRequests}
+	}
+	logEntryWithRequestID(ctx).Debugf("queued request for model %s (depth %d)", model, m.queue.size())
+	if callback, okCallback := opts.Metadata[cliproxyexecutor.QueuedCallbackMetadataKey].(func()); okCallback && callback != nil {
+		callback()
+	}
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	lastErr := cause
+	for {
+		select {
+		case <-ctx.Done():
+			m.leaveQueue(waiter)
+			return ctx.Err()
+		case <-deadline.C:
+			m.leaveQueue(waiter)
+			return lastErr
+		case <-waiter.ready:
+		}
+
+		errAttempt := attempt()
+		if errAttempt == nil {
+			m.queue.notify()
+			return nil
+		}
+		if ctx.Err() != nil || !m.shouldQueueAfterError(errAttempt, providers, model, timeout) {
+			m.queue.notify()
+			return errAttempt
+		}
+		lastErr = errAttempt
+		waiter, _ = m.queue.enqueue(client, model, queueNotBefore(errAttempt), maxDepth, maxPerClient, true)
+	}
+}
+
+// queueNotBefore returns when the cooldown err reports expires, or the zero time when err
+// does not name one.
+func queueNotBefore(err error) time.Time {
+	var cooldownErr *modelCooldownError
+	if errors.As(err, &cooldownErr) && cooldownErr != nil && cooldownErr.resetIn > 0 {
+		return time.Now().Add(cooldownErr.resetIn)
+	}
+	return time.Time{}
+}
+
+// leaveQueue removes waiter, handing its wake-up to the next waiter if it was already woken.
+func (m *Manager) leaveQueue(waiter *queueWaiter) {
+	if waiter == nil {
+		return
+	}
+	if !m.queue.remove(waiter) {
+		m.queue.notify()
+	}
+}
+
+// QueueDepth returns the number of requests waiting in the request queue. Background work
+// such as batch items pauses while it is non-zero.
+func (m *Manager) QueueDepth() int {
+	if m == nil {
+		return 0
+	}
+	return m.queue.size()
+}
+
+// isBackgroundRequest reports whether opts mark low-priority work that must not be queued.
+func isBackgroundRequest(opts cliproxyexecutor.Options) bool {
+	background, _ := opts.Metadata[cliproxyexecutor.BackgroundMetadataKey].(bool)
+	return background
+}
+
+func queueClientKey(opts cliproxyexecutor.Options) string {
+	if raw, ok := opts.Metadata[cliproxyexecutor.ClientKeyMetadataKey].(string); ok {
+		return strings.TrimSpace(raw)
+	}
+	return ""
+}
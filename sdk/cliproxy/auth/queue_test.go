@@ -0,0 +1,234 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+func TestRequestQueue_NotifyRotatesAcrossClients(t *testing.T) {
+	t.Parallel()
+
+	queue := newRequestQueue()
+	var waiters []*queueWaiter
+	for _, client := range []string{"a", "a", "a", "b", "c"} {
+		waiter, ok := queue.enqueue(client, "m", time.Time{}, 0, 0, false)
+		if !ok {
+			t.Fatalf("enqueue(%s) = false, want true", client)
+		}
+		waiters = append(waiters, waiter)
+	}
+
+	woken := func() string {
+		for _, waiter := range waiters {
+			select {
+			case <-waiter.ready:
+				waiters = removeWaiter(waiters, waiter)
+				return waiter.client
+			default:
+			}
+		}
+		return ""
+	}
+	var order []string
+	for range 5 {
+		queue.notify()
+		order = append(order, woken())
+	}
+	want := []string{"a", "b", "c", "a", "a"}
+	for index := range want {
+		if order[index] != want[index] {
+			t.Fatalf("wake order = %v, want %v", order, want)
+		}
+	}
+	if size := queue.size(); size != 0 {
+		t.Fatalf("size() = %d, want 0", size)
+	}
+}
+
+func removeWaiter(waiters []*queueWaiter, target *queueWaiter) []*queueWaiter {
+	out := waiters[:0]
+	for _, waiter := range waiters {
+		if waiter != target {
+			out = append(out, waiter)
+		}
+	}
+	return out
+}
+
+func TestRequestQueue_EnforcesLimits(t *testing.T) {
+	t.Parallel()
+
+	queue := newRequestQueue()
+	if _, ok := queue.enqueue("a", "m", time.Time{}, 2, 1, false); !ok {
+		t.Fatalf("first enqueue = false, want true")
+	}
+	if _, ok := queue.enqueue("a", "m", time.Time{}, 2, 1, false); ok {
+		t.Fatalf("enqueue over per-client cap = true, want false")
+	}
+	if _, ok := queue.enqueue("b", "m", time.Time{}, 2, 1, false); !ok {
+		t.Fatalf("enqueue for second client = false, want true")
+	}
+	if _, ok := queue.enqueue("c", "m", time.Time{}, 2, 1, false); ok {
+		t.Fatalf("enqueue over max depth = true, want false")
+	}
+	if _, ok := queue.enqueue("a", "m", time.Time{}, 2, 1, true); !ok {
+		t.Fatalf("front re-enqueue = false, want limits to be bypassed")
+	}
+}
+
+func TestRequestQueue_ParksCooldownWaitersUntilExpiry(t *testing.T) {
+	t.Parallel()
+
+	queue := newRequestQueue()
+	parked, _ := queue.enqueue("a", "m", time.Now().Add(80*time.Millisecond), 0, 0, false)
+	ready, _ := queue.enqueue("a", "m", time.Time{}, 0, 0, false)
+
+	queue.notify()
+	select {
+	case <-ready.ready:
+	default:
+		t.Fatal("notify() did not wake the waiter behind the one parked on a cooldown")
+	}
+	select {
+	case <-parked.ready:
+		t.Fatal("notify() woke a waiter before its cooldown expired")
+	default:
+	}
+
+	// No further notify: the queue's own timer wakes the parked waiter once the cooldown ends.
+	select {
+	case <-parked.ready:
+	case <-time.After(time.Second):
+		t.Fatal("parked waiter was not woken when its cooldown expired")
+	}
+	if size := queue.size(); size != 0 {
+		t.Fatalf("size() = %d, want 0", size)
+	}
+}
+
+func newQueueTestManager(t *testing.T, model string, queueEnabled bool) (*Manager, *hedgeTestExecutor) {
+	t.Helper()
+
+	executor := &hedgeTestExecutor{
+		id:     "queue-provider",
+		delays: map[string]time.Duration{"queue-auth": 150 * time.Millisecond},
+	}
+	registerSchedulerModels(t, executor.id, model, "queue-auth")
+	manager := NewManager(nil, &RoundRobinSelector{}, nil)
+	manager.RegisterExecutor(executor)
+	manager.SetConfig(&internalconfig.Config{
+		Routing: internalconfig.RoutingConfig{
+			Queue: internalconfig.QueueConfig{Enabled: queueEnabled, TimeoutSeconds: 5},
+		},
+	})
+	auth := &Auth{ID: "queue-auth", Provider: executor.id, Attributes: map[string]string{"max_concurrency": "1"}}
+	if _, err := manager.Register(context.Background(), auth); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	return manager, executor
+}
+
+func startBlockingExecute(t *testing.T, manager *Manager, model string) <-chan error {
+	t.Helper()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := manager.Execute(context.Background(), []string{"queue-provider"}, cliproxyexecutor.Request{Model: model}, cliproxyexecutor.Options{})
+		done <- err
+	}()
+	deadline := time.Now().Add(time.Second)
+	for manager.InFlight("queue-auth") != 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("first request never became in-flight")
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+	return done
+}
+
+func TestManagerExecute_QueuesUntilSlotFrees(t *testing.T) {
+	model := "queue-wait-model"
+	manager, executor := newQueueTestManager(t, model, true)
+	first := startBlockingExecute(t, manager, model)
+
+	queued := make(chan struct{}, 1)
+	opts := cliproxyexecutor.Options{Metadata: map[string]any{
+		cliproxyexecutor.QueuedCallbackMetadataKey: func() { queued <- struct{}{} },
+	}}
+	resp, err := manager.Execute(context.Background(), []string{executor.id}, cliproxyexecutor.Request{Model: model}, opts)
+	if err != nil {
+		t.Fatalf("queued Execute() error = %v", err)
+	}
+	if string(resp.Payload) != "queue-auth" {
+		t.Fatalf("queued Execute() payload = %q, want queue-auth", resp.Payload)
+	}
+	select {
+	case <-queued:
+	default:
+		t.Fatalf("queued callback was not invoked")
+	}
+	if errFirst := <-first; errFirst != nil {
+		t.Fatalf("first Execute() error = %v", errFirst)
+	}
+	if size := manager.queue.size(); size != 0 {
+		t.Fatalf("queue size = %d, want 0", size)
+	}
+}
+
+func TestManagerExecute_SaturatedWithoutQueueFailsFast(t *testing.T) {
+	model := "queue-disabled-model"
+	manager, executor := newQueueTestManager(t, model, false)
+	first := startBlockingExecute(t, manager, model)
+
+	_, err := manager.Execute(context.Background(), []string{executor.id}, cliproxyexecutor.Request{Model: model}, cliproxyexecutor.Options{})
+	var authErr *Error
+	if !errors.As(err, &authErr) || authErr.Code != "auth_saturated" {
+		t.Fatalf("Execute() error = %v, want auth_saturated", err)
+	}
+	if errFirst := <-first; errFirst != nil {
+		t.Fatalf("first Execute() error = %v", errFirst)
+	}
+}
+
+func TestManagerExecute_NewRequestsQueueBehindWaiters(t *testing.T) {
+	model := "queue-behind-model"
+	manager, executor := newQueueTestManager(t, model, true)
+	ahead, _ := manager.queue.enqueue("", model, time.Time{}, 0, 0, false)
+
+	queued := make(chan struct{}, 1)
+	opts := cliproxyexecutor.Options{Metadata: map[string]any{
+		cliproxyexecutor.QueuedCallbackMetadataKey: func() { queued <- struct{}{} },
+	}}
+	done := make(chan error, 1)
+	go func() {
+		_, err := manager.Execute(context.Background(), []string{executor.id}, cliproxyexecutor.Request{Model: model}, opts)
+		done <- err
+	}()
+	select {
+	case <-queued:
+	case <-time.After(time.Second):
+		t.Fatal("new request took a free credential instead of queueing behind the waiter")
+	}
+	executor.mu.Lock()
+	calls := len(executor.calls)
+	executor.mu.Unlock()
+	if calls != 0 {
+		t.Fatalf("executor calls = %d before the waiter ahead was served, want 0", calls)
+	}
+
+	manager.queue.notify()
+	select {
+	case <-ahead.ready:
+	default:
+		t.Fatal("notify() did not wake the earlier waiter first")
+	}
+	manager.queue.notify()
+	if err := <-done; err != nil {
+		t.Fatalf("queued Execute() error = %v", err)
+	}
+}
@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"time"
+
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+const (
+	// quotaHeadroomThreshold is the remaining share below which a credential counts as nearly
+	// exhausted and is only picked when no credential with more headroom is ready.
+	quotaHeadroomThreshold = 0.05
+	// rateLimitStaleAfter bounds how long a snapshot without a reset time is trusted.
+	rateLimitStaleAfter = time.Minute
+)
+
+// newRateLimitState converts an executor rate-limit snapshot into persisted quota state.
+func newRateLimitState(rateLimit *cliproxyexecutor.RateLimit, now time.Time) *RateLimitState {
+	if rateLimit == nil {
+		return nil
+	}
+	return &RateLimitState{
+		Remaining:  rateLimit.Remaining,
+		ResetAt:    rateLimit.ResetAt,
+		Window:     rateLimit.Window,
+		ObservedAt: now,
+	}
+}
+
+// below reports whether the snapshot shows less than threshold of the window left and
+// the window has not yet reset.
+func (s *RateLimitState) below(threshold float64, now time.Time) bool {
+	if s == nil || s.Remaining >= threshold {
+		return false
+	}
+	return now.Before(s.expiresAt())
+}
+
+// expiresAt is when the snapshot stops describing the window: its reset time when known.
+func (s *RateLimitState) expiresAt() time.Time {
+	if !s.ResetAt.IsZero() {
+		return s.ResetAt
+	}
+	return s.ObservedAt.Add(rateLimitStaleAfter)
+}
+
+// rateLimitStateFor returns the latest rate-limit snapshot for auth and model. A model-level
+// snapshot takes precedence over the credential-wide one.
+func rateLimitStateFor(auth *Auth, model string) *RateLimitState {
+	if auth == nil {
+		return nil
+	}
+	if model != "" && len(auth.ModelStates) > 0 {
+		state, ok := auth.ModelStates[model]
+		if !ok || state == nil {
+			state = auth.ModelStates[canonicalModelKey(model)]
+		}
+		if state != nil && state.Quota.RateLimit != nil {
+			return state.Quota.RateLimit
+		}
+	}
+	return auth.Quota.RateLimit
+}
+
+// quotaNearlyExhausted reports whether the latest rate-limit headers for auth show it close
+// to its limit.
+func quotaNearlyExhausted(auth *Auth, model string, now time.Time) bool {
+	return rateLimitStateFor(auth, model).below(quotaHeadroomThreshold, now)
+}
+
+// preferQuotaHeadroom drops nearly exhausted auths from the candidate tiers as long as at
+// least one candidate with headroom remains, so near-limit credentials become a last resort.
+func preferQuotaHeadroom(available map[int][]*Auth, model string, now time.Time) map[int][]*Auth {
+	filtered := make(map[int][]*Auth, len(available))
+	for priority, candidates := range available {
+		for _, candidate := range candidates {
+			if quotaNearlyExhausted(candidate, model, now) {
+				continue
+			}
+			filtered[priority] = append(filtered[priority], candidate)
+		}
+	}
+	if len(filtered) == 0 {
+		return available
+	}
+	return filtered
+}
+
+// withHeadroomLocked narrows predicate to auths that are not close to their upstream rate
+// limit, unless that would leave no ready auth in shards.
+func (s *authScheduler) withHeadroomLocked(predicate func(*scheduledAuth) bool, modelKey string, shards ...*modelScheduler) func(*scheduledAuth) bool {
+	now := time.Now()
+	exhausted, healthy := false, false
+	for _, shard := range shards {
+		if shard == nil {
+			continue
+		}
+		for _, entry := range shard.entries {
+			if entry == nil || entry.state != scheduledStateReady {
+				continue
+			}
+			if predicate != nil && !predicate(entry) {
+				continue
+			}
+			if quotaNearlyExhausted(entry.auth, modelKey, now) {
+				exhausted = true
+			} else {
+				healthy = true
+			}
+		}
+	}
+	if !exhausted || !healthy {
+		return predicate
+	}
+	return func(entry *scheduledAuth) bool {
+		if predicate != nil && !predicate(entry) {
+			return false
+		}
+		return !quotaNearlyExhausted(entry.auth, modelKey, now)
+	}
+}
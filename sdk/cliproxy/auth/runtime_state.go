@@ -0,0 +1,247 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// runtimeStateSaveDelay coalesces bursts of result updates into a single state write.
+const runtimeStateSaveDelay = time.Second
+
+// RuntimeState is the persisted subset of an auth's runtime availability state.
+type RuntimeState struct {
+	// Status and StatusMessage mirror the auth lifecycle status at save time.
+	Status        Status `json:"status,omitempty"`
+	StatusMessage string `json:"status_message,omitempty"`
+	// Unavailable and NextRetryAfter carry an auth-wide cooldown.
+	Unavailable    bool      `json:"unavailable,omitempty"`
+	NextRetryAfter time.Time `json:"next_retry_after"`
+	// Quota keeps the quota backoff level and recovery time.
+	Quota QuotaState `json:"quota"`
+	// LastError records the failure that caused the cooldown.
+	LastError *Error `json:"last_error,omitempty"`
+	// ModelStates holds per-model cooldowns.
+	ModelStates map[string]*ModelState `json:"model_states,omitempty"`
+	// UpdatedAt is when the state was captured.
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// captureRuntimeState returns the cooldown state of auth worth restoring after a restart,
+// or nil when nothing is cooling down.
+func captureRuntimeState(auth *Auth, now time.Time) *RuntimeState {
+	if auth == nil || auth.Disabled || auth.Status == StatusDisabled {
+		return nil
+	}
+	state := &RuntimeState{
+		Status:        auth.Status,
+		StatusMessage: auth.StatusMessage,
+		LastError:     cloneError(auth.LastError),
+		UpdatedAt:     now,
+	}
+	if auth.Unavailable && auth.NextRetryAfter.After(now) {
+		state.Unavailable = true
+		state.NextRetryAfter = auth.NextRetryAfter
+	}
+	if auth.Quota.Exceeded && auth.Quota.NextRecoverAt.After(now) {
+		state.Quota = auth.Quota
+		state.Quota.RateLimit = nil
+	}
+	for model, modelState := range auth.ModelStates {
+		if modelState == nil || !modelState.Unavailable || !modelState.NextRetryAfter.After(now) {
+			continue
+		}
+		if state.ModelStates == nil {
+			state.ModelStates = make(map[string]*ModelState)
+		}
+		state.ModelStates[model] = modelState.Clone()
+	}
+	if !state.active(now) {
+		return nil
+	}
+	return state
+}
+
+// active reports whether any part of the saved state has not yet expired.
+func (s *RuntimeState) active(now time.Time) bool {
+	if s == nil {
+		return false
+	}
+	if s.Unavailable && s.NextRetryAfter.After(now) {
+		return true
+	}
+	if s.Quota.Exceeded && s.Quota.NextRecoverAt.After(now) {
+		return true
+	}
+	for _, modelState := range s.ModelStates {
+		if modelState != nil && modelState.NextRetryAfter.After(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyRuntimeState restores the parts of state that have not yet expired onto auth.
+// Model states the auth already tracks are left untouched.
+func applyRuntimeState(auth *Auth, state *RuntimeState, now time.Time) bool {
+	if auth == nil || state == nil || auth.Disabled || auth.Status == StatusDisabled {
+		return false
+	}
+	applied := false
+	for model, modelState := range state.ModelStates {
+		if modelState == nil || !modelState.NextRetryAfter.After(now) {
+			continue
+		}
+		if existing, ok := auth.ModelStates[model]; ok && existing != nil {
+			continue
+		}
+		if auth.ModelStates == nil {
+			auth.ModelStates = make(map[string]*ModelState)
+		}
+		auth.ModelStates[model] = modelState.Clone()
+		applied = true
+	}
+	if state.Unavailable && state.NextRetryAfter.After(now) {
+		auth.Unavailable = true
+		auth.NextRetryAfter = state.NextRetryAfter
+		applied = true
+	}
+	if state.Quota.Exceeded && state.Quota.NextRecoverAt.After(now) {
+		rateLimit := auth.Quota.RateLimit
+		auth.Quota = state.Quota
+		auth.Quota.RateLimit = rateLimit
+		applied = true
+	}
+	if !applied {
+		return false
+	}
+	if len(state.ModelStates) > 0 {
+		updateAggregatedAvailability(auth, now)
+	}
+	auth.Status = StatusError
+	if state.StatusMessage != "" {
+		auth.StatusMessage = state.StatusMessage
+	}
+	if auth.LastError == nil {
+		auth.LastError = cloneError(state.LastError)
+	}
+	return true
+}
+
+// restoreRuntimeStateLocked loads saved runtime state from the store and applies it to the
+// loaded auths. States for auths registered later are kept until Register sees them.
+func (m *Manager) restoreRuntimeStateLocked(ctx context.Context) {
+	stateStore, ok := m.store.(RuntimeStateStore)
+	if !ok || stateStore == nil {
+		log.Warnf("auth manager: store %T cannot persist runtime state; cooldowns and quota backoff reset on restart", m.store)
+		return
+	}
+	states, err := stateStore.LoadRuntimeState(ctx)
+	if err != nil {
+		log.Warnf("auth manager: load runtime state failed: %v", err)
+		return
+	}
+	now := time.Now()
+	m.pendingRuntimeState = make(map[string]*RuntimeState, len(states))
+	for id, state := range states {
+		if !state.active(now) {
+			continue
+		}
+		if auth, exists := m.auths[id]; exists {
+			applyRuntimeState(auth, state, now)
+			continue
+		}
+		m.pendingRuntimeState[id] = state
+	}
+}
+
+// applyPendingRuntimeStateLocked restores saved runtime state for an auth registered after Load.
+func (m *Manager) applyPendingRuntimeStateLocked(auth *Auth) {
+	if auth == nil || len(m.pendingRuntimeState) == 0 {
+		return
+	}
+	state, ok := m.pendingRuntimeState[auth.ID]
+	if !ok {
+		return
+	}
+	delete(m.pendingRuntimeState, auth.ID)
+	applyRuntimeState(auth, state, time.Now())
+}
+
+// restoreClientKeyUsage loads the saved client key budget counters from the store. Without
+// a store that keeps them, budgets restart from zero and only a warning is logged.
+func (m *Manager) restoreClientKeyUsage(ctx context.Context, cfg *internalconfig.Config) {
+	usageStore, ok := m.store.(ClientKeyUsageStore)
+	if !ok || usageStore == nil {
+		for index := range cfg.ClientKeys {
+			if !cfg.ClientKeys[index].Budget.IsZero() {
+				log.Warnf("auth manager: store %T cannot persist client key usage; budgets reset on restart", m.store)
+				break
+			}
+		}
+		return
+	}
+	saved, err := usageStore.LoadClientKeyUsage(ctx)
+	if err != nil {
+		log.Warnf("auth manager: load client key usage failed: %v", err)
+		return
+	}
+	m.clientKeyUsage.restore(saved)
+}
+
+// scheduleRuntimeStateSave queues a debounced write of the runtime state and client key usage.
+func (m *Manager) scheduleRuntimeStateSave() {
+	_, saveState := m.store.(RuntimeStateStore)
+	_, saveUsage := m.store.(ClientKeyUsageStore)
+	if !saveState && !saveUsage {
+		return
+	}
+	m.runtimeStateMu.Lock()
+	defer m.runtimeStateMu.Unlock()
+	if m.runtimeStateTimer != nil {
+		return
+	}
+	m.runtimeStateTimer = time.AfterFunc(runtimeStateSaveDelay, func() {
+		m.runtimeStateMu.Lock()
+		m.runtimeStateTimer = nil
+		m.runtimeStateMu.Unlock()
+		if errSave := m.SaveRuntimeState(context.Background()); errSave != nil {
+			log.Warnf("auth manager: save runtime state failed: %v", errSave)
+		}
+	})
+}
+
+// SaveRuntimeState writes the current cooldown state of every auth and the client key usage
+// counters to the store, for the parts the store supports. It is called automatically after
+// results and should be called on shutdown.
+func (m *Manager) SaveRuntimeState(ctx context.Context) error {
+	if m == nil {
+		return nil
+	}
+	var errUsage error
+	if usageStore, ok := m.store.(ClientKeyUsageStore); ok && usageStore != nil {
+		errUsage = usageStore.SaveClientKeyUsage(ctx, m.clientKeyUsage.export(time.Now()))
+	}
+	stateStore, ok := m.store.(RuntimeStateStore)
+	if !ok || stateStore == nil {
+		return errUsage
+	}
+	now := time.Now()
+	m.mu.RLock()
+	states := make(map[string]*RuntimeState, len(m.pendingRuntimeState))
+	for id, state := range m.pendingRuntimeState {
+		if state.active(now) {
+			states[id] = state
+		}
+	}
+	for id, auth := range m.auths {
+		if state := captureRuntimeState(auth, now); state != nil {
+			states[id] = state
+		}
+	}
+	m.mu.RUnlock()
+	return errors.Join(stateStore.SaveRuntimeState(ctx, states), errUsage)
+}
@@ -0,0 +1,114 @@
+package auth
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+type runtimeStateTestStore struct {
+	mu     sync.Mutex
+	auths  []*Auth
+	states map[string]*RuntimeState
+}
+
+func (s *runtimeStateTestStore) List(context.Context) ([]*Auth, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]*Auth, 0, len(s.auths))
+	for _, auth := range s.auths {
+		out = append(out, auth.Clone())
+	}
+	return out, nil
+}
+
+func (s *runtimeStateTestStore) Save(context.Context, *Auth) (string, error) { return "", nil }
+
+func (s *runtimeStateTestStore) Delete(context.Context, string) error { return nil }
+
+func (s *runtimeStateTestStore) LoadRuntimeState(context.Context) (map[string]*RuntimeState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.states, nil
+}
+
+func (s *runtimeStateTestStore) SaveRuntimeState(_ context.Context, states map[string]*RuntimeState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.states = states
+	return nil
+}
+
+func TestManagerRuntimeState_RestoresCooldownAfterRestart(t *testing.T) {
+	t.Parallel()
+
+	model := "runtime-state-model"
+	registerSchedulerModels(t, "gemini", model, "state-a", "state-b")
+	store := &runtimeStateTestStore{auths: []*Auth{{ID: "state-a", Provider: "gemini"}}}
+
+	first := NewManager(store, &RoundRobinSelector{}, nil)
+	if errLoad := first.Load(context.Background()); errLoad != nil {
+		t.Fatalf("Load() error = %v", errLoad)
+	}
+	if _, errRegister := first.Register(context.Background(), &Auth{ID: "state-b", Provider: "gemini"}); errRegister != nil {
+		t.Fatalf("Register(state-b) error = %v", errRegister)
+	}
+	for _, id := range []string{"state-a", "state-b"} {
+		first.MarkResult(context.Background(), Result{AuthID: id, Provider: "gemini", Model: model, Error: &Error{HTTPStatus: 429, Message: "quota"}})
+	}
+	if errSave := first.SaveRuntimeState(context.Background()); errSave != nil {
+		t.Fatalf("SaveRuntimeState() error = %v", errSave)
+	}
+	if len(store.states) != 2 {
+		t.Fatalf("saved states = %d, want 2", len(store.states))
+	}
+
+	second := NewManager(store, &RoundRobinSelector{}, nil)
+	if errLoad := second.Load(context.Background()); errLoad != nil {
+		t.Fatalf("Load() after restart error = %v", errLoad)
+	}
+	if _, errRegister := second.Register(context.Background(), &Auth{ID: "state-b", Provider: "gemini"}); errRegister != nil {
+		t.Fatalf("Register(state-b) after restart error = %v", errRegister)
+	}
+	for _, id := range []string{"state-a", "state-b"} {
+		auth, ok := second.GetByID(id)
+		if !ok {
+			t.Fatalf("GetByID(%s) missing after restart", id)
+		}
+		state := auth.ModelStates[model]
+		if state == nil || !state.Unavailable || !state.NextRetryAfter.After(time.Now()) || !state.Quota.Exceeded {
+			t.Fatalf("%s model state after restart = %+v, want active quota cooldown", id, state)
+		}
+	}
+	if _, errPick := second.scheduler.pickSingle(context.Background(), "gemini", model, cliproxyexecutor.Options{}, nil); errPick == nil {
+		t.Fatalf("pickSingle() after restart error = nil, want cooldown error")
+	}
+}
+
+func TestManagerRuntimeState_DropsExpiredCooldown(t *testing.T) {
+	t.Parallel()
+
+	past := time.Now().Add(-time.Minute)
+	store := &runtimeStateTestStore{
+		auths: []*Auth{{ID: "expired-a", Provider: "gemini"}},
+		states: map[string]*RuntimeState{
+			"expired-a": {ModelStates: map[string]*ModelState{
+				"expired-model": {Unavailable: true, Status: StatusError, NextRetryAfter: past},
+			}},
+		},
+	}
+	manager := NewManager(store, &RoundRobinSelector{}, nil)
+	if errLoad := manager.Load(context.Background()); errLoad != nil {
+		t.Fatalf("Load() error = %v", errLoad)
+	}
+	auth, ok := manager.GetByID("expired-a")
+	if !ok {
+		t.Fatalf("GetByID(expired-a) missing")
+	}
+	if len(auth.ModelStates) != 0 || auth.Status == StatusError {
+		t.Fatalf("auth after load = %+v, want expired cooldown dropped", auth)
+	}
+}
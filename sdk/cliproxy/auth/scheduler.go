@@ -18,6 +18,7 @@ const (
 	schedulerStrategyCustom schedulerStrategy = iota
 	schedulerStrategyRoundRobin
 	schedulerStrategyFillFirst
+	schedulerStrategyAdaptive
 )
 
 // scheduledState describes how an auth currently participates in a model shard.
@@ -28,15 +29,20 @@ const (
 	scheduledStateCooldown
 	scheduledStateBlocked
 	scheduledStateDisabled
+	// scheduledStateOutsideWindow holds an auth back until its availability schedule lets it in.
+	scheduledStateOutsideWindow
 )
 
 // authScheduler keeps the incremental provider/model scheduling state used by Manager.
 type authScheduler struct {
 	mu            sync.Mutex
 	strategy      schedulerStrategy
+	adaptive      *AdaptiveSelector
 	providers     map[string]*providerScheduler
 	authProviders map[string]string
 	mixedCursors  map[string]int
+	// inFlight counts requests currently executing per auth ID; it survives rebuilds.
+	inFlight map[string]int
 }
 
 // providerScheduler stores auth metadata and model shards for a single provider.
@@ -51,6 +57,8 @@ type scheduledAuthMeta struct {
 	auth              *Auth
 	providerKey       string
 	priority          int
+	weight            int
+	maxConcurrency    int
 	virtualParent     string
 	websocketEnabled  bool
 	supportedModelSet map[string]struct{}
@@ -63,6 +71,8 @@ type modelScheduler struct {
 	priorityOrder   []int
 	readyByPriority map[int]*readyBucket
 	blocked         cooldownQueue
+	// nextWindowClose is the earliest availableUntil among ready entries.
+	nextWindowClose time.Time
 }
 
 // scheduledAuth stores the runtime scheduling state for a single auth inside a model shard.
@@ -71,6 +81,8 @@ type scheduledAuth struct {
 	auth        *Auth
 	state       scheduledState
 	nextRetryAt time.Time
+	// availableUntil is when a ready auth's availability window closes; zero when it never does.
+	availableUntil time.Time
 }
 
 // readyBucket keeps the ready views for one priority level.
@@ -79,13 +91,18 @@ type readyBucket struct {
 	ws  readyView
 }
 
-// readyView holds the selection order for flat or grouped round-robin traversal.
+// readyView holds the selection order for flat, weighted, or grouped round-robin traversal.
 type readyView struct {
 	flat         []*scheduledAuth
 	cursor       int
 	parentOrder  []string
 	parentCursor int
 	children     map[string]*childBucket
+	// weighted is set when entries carry non-uniform weights; current holds the
+	// smooth weighted round-robin counters aligned with flat.
+	weighted    bool
+	current     []int
+	totalWeight int
 }
 
 // childBucket keeps the per-parent rotation state for grouped Gemini virtual auths.
@@ -99,11 +116,14 @@ type cooldownQueue []*scheduledAuth
 
 // newAuthScheduler constructs an empty scheduler configured for the supplied selector strategy.
 func newAuthScheduler(selector Selector) *authScheduler {
+	adaptive, _ := selector.(*AdaptiveSelector)
 	return &authScheduler{
 		strategy:      selectorStrategy(selector),
+		adaptive:      adaptive,
 		providers:     make(map[string]*providerScheduler),
 		authProviders: make(map[string]string),
 		mixedCursors:  make(map[string]int),
+		inFlight:      make(map[string]int),
 	}
 }
 
@@ -112,6 +132,8 @@ func selectorStrategy(selector Selector) schedulerStrategy {
 	switch selector.(type) {
 	case *FillFirstSelector:
 		return schedulerStrategyFillFirst
+	case *AdaptiveSelector:
+		return schedulerStrategyAdaptive
 	case nil, *RoundRobinSelector:
 		return schedulerStrategyRoundRobin
 	default:
@@ -127,6 +149,7 @@ func (s *authScheduler) setSelector(selector Selector) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.strategy = selectorStrategy(selector)
+	s.adaptive, _ = selector.(*AdaptiveSelector)
 	clear(s.mixedCursors)
 }
 
@@ -204,9 +227,17 @@ func (s *authScheduler) pickSingle(ctx context.Context, provider, model string,
 		}
 		return true
 	}
-	if picked := shard.pickReadyLocked(preferWebsocket, s.strategy, predicate); picked != nil {
+	available := s.withHeadroomLocked(s.withCapacityLocked(predicate), modelKey, shard)
+	if s.strategy == schedulerStrategyAdaptive && s.adaptive != nil {
+		if picked := shard.pickAdaptiveLocked(preferWebsocket, s.adaptive, providerKey, available); picked != nil {
+			return picked, nil
+		}
+	} else if picked := shard.pickReadyLocked(preferWebsocket, s.strategy, available); picked != nil {
 		return picked, nil
 	}
+	if errSaturated := s.saturatedErrorLocked(predicate, shard); errSaturated != nil {
+		return nil, errSaturated
+	}
 	return nil, shard.unavailableErrorLocked(provider, model, predicate)
 }
 
@@ -257,24 +288,31 @@ func (s *authScheduler) pickMixed(ctx context.Context, providers []string, model
 			_, ok := tried[pinnedAuthID]
 			return !ok
 		}
-		if picked := shard.pickReadyLocked(false, s.strategy, predicate); picked != nil {
+		available := s.withCapacityLocked(predicate)
+		if s.strategy == schedulerStrategyAdaptive && s.adaptive != nil {
+			if picked := shard.pickAdaptiveLocked(false, s.adaptive, providerKey, available); picked != nil {
+				return picked, providerKey, nil
+			}
+		} else if picked := shard.pickReadyLocked(false, s.strategy, available); picked != nil {
 			return picked, providerKey, nil
 		}
+		if errSaturated := s.saturatedErrorLocked(predicate, shard); errSaturated != nil {
+			return nil, "", errSaturated
+		}
 		return nil, "", shard.unavailableErrorLocked("mixed", model, predicate)
 	}
 
-	predicate := triedPredicate(tried)
 	candidateShards := make([]*modelScheduler, len(normalized))
-	bestPriority := 0
-	hasCandidate := false
 	now := time.Now()
 	for providerIndex, providerKey := range normalized {
-		providerState := s.providers[providerKey]
-		if providerState == nil {
-			continue
+		if providerState := s.providers[providerKey]; providerState != nil {
+			candidateShards[providerIndex] = providerState.ensureModelLocked(modelKey, now)
 		}
-		shard := providerState.ensureModelLocked(modelKey, now)
-		candidateShards[providerIndex] = shard
+	}
+	predicate := s.withHeadroomLocked(s.withCapacityLocked(triedPredicate(tried)), modelKey, candidateShards...)
+	bestPriority := 0
+	hasCandidate := false
+	for _, shard := range candidateShards {
 		if shard == nil {
 			continue
 		}
@@ -305,6 +343,27 @@ func (s *authScheduler) pickMixed(ctx context.Context, providers []string, model
 		return nil, "", s.mixedUnavailableErrorLocked(normalized, model, tried)
 	}
 
+	if s.strategy == schedulerStrategyAdaptive && s.adaptive != nil {
+		var (
+			entries   []*scheduledAuth
+			providers []string
+		)
+		for providerIndex, providerKey := range normalized {
+			shard := candidateShards[providerIndex]
+			if shard == nil {
+				continue
+			}
+			for _, entry := range shard.readyEntriesAtPriorityLocked(false, bestPriority, predicate) {
+				entries = append(entries, entry)
+				providers = append(providers, providerKey)
+			}
+		}
+		if picked, index := pickAdaptiveEntry(s.adaptive, strings.Join(normalized, ",")+":"+modelKey, modelKey, entries); picked != nil {
+			return picked.auth, providers[index], nil
+		}
+		return nil, "", s.mixedUnavailableErrorLocked(normalized, model, tried)
+	}
+
 	cursorKey := strings.Join(normalized, ",") + ":" + modelKey
 	weights := make([]int, len(normalized))
 	segmentStarts := make([]int, len(normalized))
@@ -313,7 +372,7 @@ func (s *authScheduler) pickMixed(ctx context.Context, providers []string, model
 	for providerIndex, shard := range candidateShards {
 		segmentStarts[providerIndex] = totalWeight
 		if shard != nil {
-			weights[providerIndex] = shard.readyCountAtPriorityLocked(false, bestPriority)
+			weights[providerIndex] = shard.readyWeightAtPriorityLocked(false, bestPriority)
 		}
 		totalWeight += weights[providerIndex]
 		segmentEnds[providerIndex] = totalWeight
@@ -364,9 +423,7 @@ func (s *authScheduler) pickMixed(ctx context.Context, providers []string, model
 // mixedUnavailableErrorLocked synthesizes the mixed-provider cooldown or unavailable error.
 func (s *authScheduler) mixedUnavailableErrorLocked(providers []string, model string, tried map[string]struct{}) error {
 	now := time.Now()
-	total := 0
-	cooldownCount := 0
-	earliest := time.Time{}
+	var summary blockedSummary
 	for _, providerKey := range providers {
 		providerState := s.providers[providerKey]
 		if providerState == nil {
@@ -376,24 +433,15 @@ func (s *authScheduler) mixedUnavailableErrorLocked(providers []string, model st
 		if shard == nil {
 			continue
 		}
-		localTotal, localCooldownCount, localEarliest := shard.availabilitySummaryLocked(triedPredicate(tried))
-		total += localTotal
-		cooldownCount += localCooldownCount
-		if !localEarliest.IsZero() && (earliest.IsZero() || localEarliest.Before(earliest)) {
-			earliest = localEarliest
+		if errSaturated := s.saturatedErrorLocked(triedPredicate(tried), shard); errSaturated != nil {
+			return errSaturated
 		}
+		summary.merge(shard.availabilitySummaryLocked(triedPredicate(tried)))
 	}
-	if total == 0 {
+	if summary.total == 0 {
 		return &Error{Code: "auth_not_found", Message: "no auth available"}
 	}
-	if cooldownCount == total && !earliest.IsZero() {
-		resetIn := earliest.Sub(now)
-		if resetIn < 0 {
-			resetIn = 0
-		}
-		return newModelCooldownError(model, "", resetIn)
-	}
-	return &Error{Code: "auth_unavailable", Message: "no auth available"}
+	return summary.unavailableError("mixed", model, now)
 }
 
 // triedPredicate builds a filter that excludes auths already attempted for the current request.
@@ -500,6 +548,8 @@ func buildScheduledAuthMeta(auth *Auth) *scheduledAuthMeta {
 		auth:              auth,
 		providerKey:       providerKey,
 		priority:          authPriority(auth),
+		weight:            authWeight(auth),
+		maxConcurrency:    authMaxConcurrency(auth),
 		virtualParent:     virtualParent,
 		websocketEnabled:  authWebsocketsEnabled(auth),
 		supportedModelSet: supportedModelSetForAuth(auth.ID),
@@ -617,32 +667,22 @@ func (m *modelScheduler) upsertEntryLocked(meta *scheduledAuthMeta, now time.Tim
 	previousState := entry.state
 	previousNextRetryAt := entry.nextRetryAt
 	previousPriority := 0
+	previousWeight := 0
 	previousParent := ""
 	previousWebsocketEnabled := false
 	if entry.meta != nil {
 		previousPriority = entry.meta.priority
+		previousWeight = entry.meta.weight
 		previousParent = entry.meta.virtualParent
 		previousWebsocketEnabled = entry.meta.websocketEnabled
 	}
 
 	entry.meta = meta
 	entry.auth = meta.auth
-	entry.nextRetryAt = time.Time{}
-	blocked, reason, next := isAuthBlockedForModel(meta.auth, m.modelKey, now)
-	switch {
-	case !blocked:
-		entry.state = scheduledStateReady
-	case reason == blockReasonCooldown:
-		entry.state = scheduledStateCooldown
-		entry.nextRetryAt = next
-	case reason == blockReasonDisabled:
-		entry.state = scheduledStateDisabled
-	default:
-		entry.state = scheduledStateBlocked
-		entry.nextRetryAt = next
-	}
+	previousAvailableUntil := entry.availableUntil
+	entry.refreshStateLocked(m.modelKey, now)
 
-	if ok && previousState == entry.state && previousNextRetryAt.Equal(entry.nextRetryAt) && previousPriority == meta.priority && previousParent == meta.virtualParent && previousWebsocketEnabled == meta.websocketEnabled {
+	if ok && previousState == entry.state && previousNextRetryAt.Equal(entry.nextRetryAt) && previousAvailableUntil.Equal(entry.availableUntil) && previousPriority == meta.priority && previousWeight == meta.weight && previousParent == meta.virtualParent && previousWebsocketEnabled == meta.websocketEnabled {
 		return
 	}
 	m.rebuildIndexesLocked()
@@ -660,9 +700,35 @@ func (m *modelScheduler) removeEntryLocked(authID string) {
 	m.rebuildIndexesLocked()
 }
 
-// promoteExpiredLocked reevaluates blocked auths whose retry time has elapsed.
+// refreshStateLocked recomputes the entry state from its auth snapshot.
+func (e *scheduledAuth) refreshStateLocked(modelKey string, now time.Time) {
+	e.nextRetryAt = time.Time{}
+	e.availableUntil = time.Time{}
+	blocked, reason, next := isAuthBlockedForModel(e.auth, modelKey, now)
+	switch {
+	case !blocked:
+		e.state = scheduledStateReady
+		if _, closesAt := AuthAvailability(e.auth, now); !closesAt.IsZero() {
+			e.availableUntil = closesAt
+		}
+	case reason == blockReasonCooldown:
+		e.state = scheduledStateCooldown
+		e.nextRetryAt = next
+	case reason == blockReasonAvailabilityWindow:
+		e.state = scheduledStateOutsideWindow
+		e.nextRetryAt = next
+	case reason == blockReasonDisabled:
+		e.state = scheduledStateDisabled
+	default:
+		e.state = scheduledStateBlocked
+		e.nextRetryAt = next
+	}
+}
+
+// promoteExpiredLocked reevaluates blocked auths whose retry time has elapsed and ready auths
+// whose availability window has closed.
 func (m *modelScheduler) promoteExpiredLocked(now time.Time) {
-	if m == nil || len(m.blocked) == 0 {
+	if m == nil {
 		return
 	}
 	changed := false
@@ -673,23 +739,18 @@ func (m *modelScheduler) promoteExpiredLocked(now time.Time) {
 		if entry.nextRetryAt.IsZero() || entry.nextRetryAt.After(now) {
 			continue
 		}
-		blocked, reason, next := isAuthBlockedForModel(entry.auth, m.modelKey, now)
-		switch {
-		case !blocked:
-			entry.state = scheduledStateReady
-			entry.nextRetryAt = time.Time{}
-		case reason == blockReasonCooldown:
-			entry.state = scheduledStateCooldown
-			entry.nextRetryAt = next
-		case reason == blockReasonDisabled:
-			entry.state = scheduledStateDisabled
-			entry.nextRetryAt = time.Time{}
-		default:
-			entry.state = scheduledStateBlocked
-			entry.nextRetryAt = next
-		}
+		entry.refreshStateLocked(m.modelKey, now)
 		changed = true
 	}
+	if !m.nextWindowClose.IsZero() && !m.nextWindowClose.After(now) {
+		for _, entry := range m.entries {
+			if entry == nil || entry.state != scheduledStateReady || entry.availableUntil.IsZero() || entry.availableUntil.After(now) {
+				continue
+			}
+			entry.refreshStateLocked(m.modelKey, now)
+			changed = true
+		}
+	}
 	if changed {
 		m.rebuildIndexesLocked()
 	}
@@ -765,7 +826,77 @@ func (m *modelScheduler) pickReadyAtPriorityLocked(preferWebsocket bool, priorit
 	return picked.auth
 }
 
-func (m *modelScheduler) readyCountAtPriorityLocked(preferWebsocket bool, priority int) int {
+// pickAdaptiveLocked selects the lowest-cost ready auth from the highest available priority bucket.
+func (m *modelScheduler) pickAdaptiveLocked(preferWebsocket bool, selector *AdaptiveSelector, providerKey string, predicate func(*scheduledAuth) bool) *Auth {
+	if m == nil {
+		return nil
+	}
+	m.promoteExpiredLocked(time.Now())
+	priorityReady, okPriority := m.highestReadyPriorityLocked(preferWebsocket, predicate)
+	if !okPriority {
+		return nil
+	}
+	entries := m.readyEntriesAtPriorityLocked(preferWebsocket, priorityReady, predicate)
+	picked, _ := pickAdaptiveEntry(selector, providerKey+":"+m.modelKey, m.modelKey, entries)
+	if picked == nil {
+		return nil
+	}
+	return picked.auth
+}
+
+// readyEntriesAtPriorityLocked lists the ready entries in a priority bucket that satisfy predicate.
+func (m *modelScheduler) readyEntriesAtPriorityLocked(preferWebsocket bool, priority int, predicate func(*scheduledAuth) bool) []*scheduledAuth {
+	if m == nil {
+		return nil
+	}
+	bucket := m.readyByPriority[priority]
+	if bucket == nil {
+		return nil
+	}
+	view := &bucket.all
+	if preferWebsocket && bucket.ws.pickFirst(predicate) != nil {
+		view = &bucket.ws
+	}
+	out := make([]*scheduledAuth, 0, len(view.flat))
+	for _, entry := range view.flat {
+		if entry == nil || entry.auth == nil {
+			continue
+		}
+		if predicate != nil && !predicate(entry) {
+			continue
+		}
+		out = append(out, entry)
+	}
+	return out
+}
+
+// pickAdaptiveEntry lets the adaptive selector choose among entries and returns the entry and its index.
+func pickAdaptiveEntry(selector *AdaptiveSelector, cursorKey, modelKey string, entries []*scheduledAuth) (*scheduledAuth, int) {
+	if selector == nil || len(entries) == 0 {
+		return nil, -1
+	}
+	order := make([]int, len(entries))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return entries[order[i]].auth.ID < entries[order[j]].auth.ID
+	})
+	ids := make([]string, len(order))
+	for i, index := range order {
+		ids[i] = entries[index].auth.ID
+	}
+	chosen := selector.choose(cursorKey, modelKey, ids)
+	if chosen < 0 {
+		return nil, -1
+	}
+	index := order[chosen]
+	return entries[index], index
+}
+
+// readyWeightAtPriorityLocked returns the summed weight of ready auths in a priority bucket.
+// With default weights this equals the number of ready auths.
+func (m *modelScheduler) readyWeightAtPriorityLocked(preferWebsocket bool, priority int) int {
 	if m == nil {
 		return 0
 	}
@@ -774,57 +905,43 @@ func (m *modelScheduler) readyCountAtPriorityLocked(preferWebsocket bool, priori
 		return 0
 	}
 	if preferWebsocket && len(bucket.ws.flat) > 0 {
-		return len(bucket.ws.flat)
+		return bucket.ws.totalWeight
 	}
-	return len(bucket.all.flat)
+	return bucket.all.totalWeight
 }
 
 // unavailableErrorLocked returns the correct unavailable or cooldown error for the shard.
 func (m *modelScheduler) unavailableErrorLocked(provider, model string, predicate func(*scheduledAuth) bool) error {
-	now := time.Now()
-	total, cooldownCount, earliest := m.availabilitySummaryLocked(predicate)
-	if total == 0 {
+	summary := m.availabilitySummaryLocked(predicate)
+	if summary.total == 0 {
 		return &Error{Code: "auth_not_found", Message: "no auth available"}
 	}
-	if cooldownCount == total && !earliest.IsZero() {
-		providerForError := provider
-		if providerForError == "mixed" {
-			providerForError = ""
-		}
-		resetIn := earliest.Sub(now)
-		if resetIn < 0 {
-			resetIn = 0
-		}
-		return newModelCooldownError(model, providerForError, resetIn)
-	}
-	return &Error{Code: "auth_unavailable", Message: "no auth available"}
+	return summary.unavailableError(provider, model, time.Now())
 }
 
-// availabilitySummaryLocked summarizes total candidates, cooldown count, and earliest retry time.
-func (m *modelScheduler) availabilitySummaryLocked(predicate func(*scheduledAuth) bool) (int, int, time.Time) {
+// availabilitySummaryLocked counts the candidates and those cooling down or outside their
+// availability window, with the earliest time each clears.
+func (m *modelScheduler) availabilitySummaryLocked(predicate func(*scheduledAuth) bool) blockedSummary {
+	var summary blockedSummary
 	if m == nil {
-		return 0, 0, time.Time{}
+		return summary
 	}
-	total := 0
-	cooldownCount := 0
-	earliest := time.Time{}
 	for _, entry := range m.entries {
 		if predicate != nil && !predicate(entry) {
 			continue
 		}
-		total++
+		summary.total++
 		if entry == nil || entry.auth == nil {
 			continue
 		}
-		if entry.state != scheduledStateCooldown {
-			continue
-		}
-		cooldownCount++
-		if !entry.nextRetryAt.IsZero() && (earliest.IsZero() || entry.nextRetryAt.Before(earliest)) {
-			earliest = entry.nextRetryAt
+		switch entry.state {
+		case scheduledStateCooldown:
+			summary.add(blockReasonCooldown, entry.nextRetryAt)
+		case scheduledStateOutsideWindow:
+			summary.add(blockReasonAvailabilityWindow, entry.nextRetryAt)
 		}
 	}
-	return total, cooldownCount, earliest
+	return summary
 }
 
 // rebuildIndexesLocked reconstructs ready and blocked views from the current entry map.
@@ -832,6 +949,7 @@ func (m *modelScheduler) rebuildIndexesLocked() {
 	m.readyByPriority = make(map[int]*readyBucket)
 	m.priorityOrder = m.priorityOrder[:0]
 	m.blocked = m.blocked[:0]
+	m.nextWindowClose = time.Time{}
 	priorityBuckets := make(map[int][]*scheduledAuth)
 	for _, entry := range m.entries {
 		if entry == nil || entry.auth == nil {
@@ -839,9 +957,12 @@ func (m *modelScheduler) rebuildIndexesLocked() {
 		}
 		switch entry.state {
 		case scheduledStateReady:
+			if !entry.availableUntil.IsZero() && (m.nextWindowClose.IsZero() || entry.availableUntil.Before(m.nextWindowClose)) {
+				m.nextWindowClose = entry.availableUntil
+			}
 			priority := entry.meta.priority
 			priorityBuckets[priority] = append(priorityBuckets[priority], entry)
-		case scheduledStateCooldown, scheduledStateBlocked:
+		case scheduledStateCooldown, scheduledStateBlocked, scheduledStateOutsideWindow:
 			m.blocked = append(m.blocked, entry)
 		}
 	}
@@ -894,6 +1015,22 @@ func buildReadyView(entries []*scheduledAuth) readyView {
 	if len(entries) == 0 {
 		return view
 	}
+	for _, entry := range entries {
+		weight := 1
+		if entry != nil && entry.meta != nil && entry.meta.weight > 0 {
+			weight = entry.meta.weight
+		}
+		if weight != 1 {
+			view.weighted = true
+		}
+		view.totalWeight += weight
+	}
+	if view.weighted {
+		// Weighted rotation spreads traffic by weight across the flat view, so grouped
+		// parent/child rotation is not built for this bucket.
+		view.current = make([]int, len(entries))
+		return view
+	}
 	groups := make(map[string][]*scheduledAuth)
 	for _, entry := range entries {
 		if entry == nil || entry.meta == nil || entry.meta.virtualParent == "" {
@@ -928,6 +1065,9 @@ func (v *readyView) pickFirst(predicate func(*scheduledAuth) bool) *scheduledAut
 
 // pickRoundRobin returns the next ready entry using flat or grouped round-robin traversal.
 func (v *readyView) pickRoundRobin(predicate func(*scheduledAuth) bool) *scheduledAuth {
+	if v.weighted {
+		return v.pickWeightedRoundRobin(predicate)
+	}
 	if len(v.parentOrder) > 1 && len(v.children) > 0 {
 		return v.pickGroupedRoundRobin(predicate)
 	}
@@ -950,6 +1090,38 @@ func (v *readyView) pickRoundRobin(predicate func(*scheduledAuth) bool) *schedul
 	return nil
 }
 
+// pickWeightedRoundRobin applies smooth weighted round-robin over the flat view.
+// Entries rejected by predicate are skipped for this pick without disturbing the others.
+func (v *readyView) pickWeightedRoundRobin(predicate func(*scheduledAuth) bool) *scheduledAuth {
+	if len(v.current) != len(v.flat) {
+		v.current = make([]int, len(v.flat))
+	}
+	best := -1
+	total := 0
+	for index, entry := range v.flat {
+		if entry == nil || entry.meta == nil {
+			continue
+		}
+		if predicate != nil && !predicate(entry) {
+			continue
+		}
+		weight := entry.meta.weight
+		if weight <= 0 {
+			weight = 1
+		}
+		v.current[index] += weight
+		total += weight
+		if best < 0 || v.current[index] > v.current[best] {
+			best = index
+		}
+	}
+	if best < 0 {
+		return nil
+	}
+	v.current[best] -= total
+	return v.flat[best]
+}
+
 // pickGroupedRoundRobin rotates across parents first and then within the selected parent.
 func (v *readyView) pickGroupedRoundRobin(predicate func(*scheduledAuth) bool) *scheduledAuth {
 	start := 0
@@ -2,6 +2,7 @@ package auth
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"testing"
 	"time"
@@ -95,6 +96,161 @@ func TestSchedulerPick_RoundRobinHighestPriority(t *testing.T) {
 	}
 }
 
+func TestSchedulerPick_RoundRobinHonorsWeights(t *testing.T) {
+	t.Parallel()
+
+	scheduler := newSchedulerForTest(
+		&RoundRobinSelector{},
+		&Auth{ID: "heavy", Provider: "gemini", Attributes: map[string]string{"weight": "4"}},
+		&Auth{ID: "light", Provider: "gemini"},
+	)
+
+	counts := make(map[string]int)
+	for index := 0; index < 50; index++ {
+		got, errPick := scheduler.pickSingle(context.Background(), "gemini", "", cliproxyexecutor.Options{}, nil)
+		if errPick != nil {
+			t.Fatalf("pickSingle() #%d error = %v", index, errPick)
+		}
+		if got == nil {
+			t.Fatalf("pickSingle() #%d auth = nil", index)
+		}
+		counts[got.ID]++
+	}
+	if counts["heavy"] != 40 || counts["light"] != 10 {
+		t.Fatalf("pick counts = %v, want heavy=40 light=10", counts)
+	}
+}
+
+func TestSchedulerPick_WeightedRoundRobinSkipsBlockedAuth(t *testing.T) {
+	t.Parallel()
+
+	model := "gemini-weighted-skip"
+	registerSchedulerModels(t, "gemini", model, "weighted-heavy", "weighted-light")
+	scheduler := newSchedulerForTest(
+		&RoundRobinSelector{},
+		&Auth{ID: "weighted-heavy", Provider: "gemini", Attributes: map[string]string{"weight": "3"}},
+		&Auth{ID: "weighted-light", Provider: "gemini"},
+	)
+	scheduler.upsertAuth(&Auth{
+		ID:         "weighted-heavy",
+		Provider:   "gemini",
+		Attributes: map[string]string{"weight": "3"},
+		ModelStates: map[string]*ModelState{
+			model: {Unavailable: true, NextRetryAfter: time.Now().Add(time.Hour)},
+		},
+	})
+
+	for index := 0; index < 3; index++ {
+		got, errPick := scheduler.pickSingle(context.Background(), "gemini", model, cliproxyexecutor.Options{}, nil)
+		if errPick != nil {
+			t.Fatalf("pickSingle() #%d error = %v", index, errPick)
+		}
+		if got == nil || got.ID != "weighted-light" {
+			t.Fatalf("pickSingle() #%d auth = %v, want weighted-light", index, got)
+		}
+	}
+}
+
+func TestSchedulerPick_SkipsSaturatedAuth(t *testing.T) {
+	t.Parallel()
+
+	limited := &Auth{ID: "limited", Provider: "gemini", Attributes: map[string]string{"max_concurrency": "1", "priority": "1"}}
+	scheduler := newSchedulerForTest(
+		&RoundRobinSelector{},
+		limited,
+		&Auth{ID: "spare", Provider: "gemini"},
+	)
+
+	if !scheduler.tryAcquire(limited) {
+		t.Fatalf("tryAcquire(limited) = false, want true")
+	}
+	if scheduler.tryAcquire(limited) {
+		t.Fatalf("tryAcquire(limited) second call = true, want saturated")
+	}
+	got, errPick := scheduler.pickSingle(context.Background(), "gemini", "", cliproxyexecutor.Options{}, nil)
+	if errPick != nil {
+		t.Fatalf("pickSingle() error = %v", errPick)
+	}
+	if got == nil || got.ID != "spare" {
+		t.Fatalf("pickSingle() auth = %v, want spare while limited is saturated", got)
+	}
+
+	scheduler.release("limited")
+	got, errPick = scheduler.pickSingle(context.Background(), "gemini", "", cliproxyexecutor.Options{}, nil)
+	if errPick != nil {
+		t.Fatalf("pickSingle() after release error = %v", errPick)
+	}
+	if got == nil || got.ID != "limited" {
+		t.Fatalf("pickSingle() after release auth = %v, want limited", got)
+	}
+}
+
+func TestSchedulerPick_AllSaturatedReturnsSaturatedError(t *testing.T) {
+	t.Parallel()
+
+	limited := &Auth{ID: "limited", Provider: "gemini", Attributes: map[string]string{"max_concurrency": "1"}}
+	scheduler := newSchedulerForTest(&RoundRobinSelector{}, limited)
+	if !scheduler.tryAcquire(limited) {
+		t.Fatalf("tryAcquire(limited) = false, want true")
+	}
+
+	_, errPick := scheduler.pickSingle(context.Background(), "gemini", "", cliproxyexecutor.Options{}, nil)
+	var authErr *Error
+	if !errors.As(errPick, &authErr) || authErr.Code != "auth_saturated" {
+		t.Fatalf("pickSingle() error = %v, want auth_saturated", errPick)
+	}
+	if authErr.HTTPStatus != http.StatusTooManyRequests {
+		t.Fatalf("pickSingle() error status = %d, want %d", authErr.HTTPStatus, http.StatusTooManyRequests)
+	}
+
+	_, _, errMixed := scheduler.pickMixed(context.Background(), []string{"gemini", "claude"}, "", cliproxyexecutor.Options{}, nil)
+	if !errors.As(errMixed, &authErr) || authErr.Code != "auth_saturated" {
+		t.Fatalf("pickMixed() error = %v, want auth_saturated", errMixed)
+	}
+}
+
+func TestSchedulerPick_DeprioritizesNearlyExhaustedAuth(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	low := &Auth{
+		ID:         "low",
+		Provider:   "gemini",
+		Attributes: map[string]string{"priority": "1"},
+		Quota:      QuotaState{RateLimit: &RateLimitState{Remaining: 0.01, ResetAt: now.Add(time.Minute), ObservedAt: now}},
+	}
+	scheduler := newSchedulerForTest(&RoundRobinSelector{}, low, &Auth{ID: "healthy", Provider: "gemini"})
+
+	for index := 0; index < 3; index++ {
+		got, errPick := scheduler.pickSingle(context.Background(), "gemini", "", cliproxyexecutor.Options{}, nil)
+		if errPick != nil {
+			t.Fatalf("pickSingle() #%d error = %v", index, errPick)
+		}
+		if got == nil || got.ID != "healthy" {
+			t.Fatalf("pickSingle() #%d auth = %v, want healthy", index, got)
+		}
+	}
+
+	got, errPick := scheduler.pickSingle(context.Background(), "gemini", "", cliproxyexecutor.Options{}, map[string]struct{}{"healthy": {}})
+	if errPick != nil {
+		t.Fatalf("pickSingle() with healthy tried error = %v", errPick)
+	}
+	if got == nil || got.ID != "low" {
+		t.Fatalf("pickSingle() with healthy tried auth = %v, want low as last resort", got)
+	}
+
+	expired := low.Clone()
+	expired.Quota.RateLimit = &RateLimitState{Remaining: 0.01, ResetAt: now.Add(-time.Second), ObservedAt: now.Add(-time.Minute)}
+	scheduler.upsertAuth(expired)
+	got, errPick = scheduler.pickSingle(context.Background(), "gemini", "", cliproxyexecutor.Options{}, nil)
+	if errPick != nil {
+		t.Fatalf("pickSingle() after reset error = %v", errPick)
+	}
+	if got == nil || got.ID != "low" {
+		t.Fatalf("pickSingle() after reset auth = %v, want low once its window reset", got)
+	}
+}
+
 func TestSchedulerPick_FillFirstSticksToFirstReady(t *testing.T) {
 	t.Parallel()
 
@@ -527,3 +683,82 @@ func TestManager_SchedulerTracksMarkResultCooldownAndRecovery(t *testing.T) {
 		t.Fatalf("len(seen) = %d, want %d", len(seen), 2)
 	}
 }
+
+func TestManager_MarkResultRecordsRateLimitHeadroom(t *testing.T) {
+	t.Parallel()
+
+	manager := NewManager(nil, &RoundRobinSelector{}, nil)
+	registerSchedulerModels(t, "gemini", "rate-limit-model", "rl-a", "rl-b")
+	for _, id := range []string{"rl-a", "rl-b"} {
+		if _, errRegister := manager.Register(context.Background(), &Auth{ID: id, Provider: "gemini"}); errRegister != nil {
+			t.Fatalf("Register(%s) error = %v", id, errRegister)
+		}
+	}
+
+	manager.MarkResult(context.Background(), Result{
+		AuthID:    "rl-a",
+		Provider:  "gemini",
+		Model:     "rate-limit-model",
+		Success:   true,
+		RateLimit: &cliproxyexecutor.RateLimit{Remaining: 0.02, ResetAt: time.Now().Add(time.Minute), Window: "requests"},
+	})
+	manager.MarkResult(context.Background(), Result{AuthID: "rl-a", Provider: "gemini", Model: "rate-limit-model", Success: true})
+
+	auth, ok := manager.GetByID("rl-a")
+	if !ok || auth == nil {
+		t.Fatalf("GetByID(rl-a) missing")
+	}
+	state := auth.ModelStates["rate-limit-model"]
+	if state == nil || state.Quota.RateLimit == nil || state.Quota.RateLimit.Window != "requests" {
+		t.Fatalf("model rate limit = %+v, want requests snapshot kept across responses without headers", state)
+	}
+	if auth.Quota.RateLimit == nil || auth.Quota.RateLimit.Remaining != 0.02 {
+		t.Fatalf("auth rate limit = %+v, want remaining 0.02", auth.Quota.RateLimit)
+	}
+
+	for index := 0; index < 3; index++ {
+		got, errPick := manager.scheduler.pickSingle(context.Background(), "gemini", "rate-limit-model", cliproxyexecutor.Options{}, nil)
+		if errPick != nil {
+			t.Fatalf("scheduler.pickSingle() #%d error = %v", index, errPick)
+		}
+		if got == nil || got.ID != "rl-b" {
+			t.Fatalf("scheduler.pickSingle() #%d auth = %v, want rl-b", index, got)
+		}
+	}
+}
+
+func TestManager_MarkResultKeepsRateLimitAcross429(t *testing.T) {
+	t.Parallel()
+
+	manager := NewManager(nil, &RoundRobinSelector{}, nil)
+	registerSchedulerModels(t, "gemini", "rate-limit-429-model", "rl-429")
+	if _, errRegister := manager.Register(context.Background(), &Auth{ID: "rl-429", Provider: "gemini"}); errRegister != nil {
+		t.Fatalf("Register(rl-429) error = %v", errRegister)
+	}
+
+	manager.MarkResult(context.Background(), Result{
+		AuthID:    "rl-429",
+		Provider:  "gemini",
+		Model:     "rate-limit-429-model",
+		Success:   true,
+		RateLimit: &cliproxyexecutor.RateLimit{Remaining: 0.5, ResetAt: time.Now().Add(time.Hour), Window: "tokens"},
+	})
+	manager.MarkResult(context.Background(), Result{
+		AuthID:   "rl-429",
+		Provider: "gemini",
+		Model:    "rate-limit-429-model",
+		Error:    &Error{Message: "rate limited", HTTPStatus: http.StatusTooManyRequests},
+	})
+
+	auth, ok := manager.GetByID("rl-429")
+	if !ok || auth == nil {
+		t.Fatalf("GetByID(rl-429) missing")
+	}
+	state := auth.ModelStates["rate-limit-429-model"]
+	if state == nil || !state.Quota.Exceeded {
+		t.Fatalf("model state = %+v, want quota exceeded", state)
+	}
+	if state.Quota.RateLimit == nil || state.Quota.RateLimit.Window != "tokens" {
+		t.Fatalf("model rate limit = %+v, want tokens snapshot kept after 429", state.Quota.RateLimit)
+	}
+}
@@ -36,8 +36,67 @@ const (
 	blockReasonCooldown
 	blockReasonDisabled
 	blockReasonOther
+	// blockReasonAvailabilityWindow marks an auth held back by its availability schedule: a
+	// closed window or a reserve cap. Unlike a cooldown it triggers no fallback, queueing or
+	// cooldown wait, since the credential was reserved on purpose.
+	blockReasonAvailabilityWindow
 )
 
+// blockedSummary counts the candidates for a model and those blocked by a cooldown or by
+// their availability schedule, with the earliest time each kind clears.
+type blockedSummary struct {
+	total         int
+	cooldown      int
+	window        int
+	cooldownUntil time.Time
+	windowUntil   time.Time
+}
+
+func (b *blockedSummary) add(reason blockReason, next time.Time) {
+	switch reason {
+	case blockReasonCooldown:
+		b.cooldown++
+		if !next.IsZero() && (b.cooldownUntil.IsZero() || next.Before(b.cooldownUntil)) {
+			b.cooldownUntil = next
+		}
+	case blockReasonAvailabilityWindow:
+		b.window++
+		if !next.IsZero() && (b.windowUntil.IsZero() || next.Before(b.windowUntil)) {
+			b.windowUntil = next
+		}
+	}
+}
+
+// merge adds the counts of other, for summaries spanning several providers.
+func (b *blockedSummary) merge(other blockedSummary) {
+	b.total += other.total
+	b.cooldown += other.cooldown
+	b.window += other.window
+	if !other.cooldownUntil.IsZero() && (b.cooldownUntil.IsZero() || other.cooldownUntil.Before(b.cooldownUntil)) {
+		b.cooldownUntil = other.cooldownUntil
+	}
+	if !other.windowUntil.IsZero() && (b.windowUntil.IsZero() || other.windowUntil.Before(b.windowUntil)) {
+		b.windowUntil = other.windowUntil
+	}
+}
+
+// unavailableError returns the error for a model without a ready candidate: a cooldown error
+// once every candidate is cooling down or outside its availability window, with at least one
+// cooling down; an availability window error when all are outside their window; and
+// auth_unavailable otherwise.
+func (b blockedSummary) unavailableError(provider, model string, now time.Time) error {
+	if provider == "mixed" {
+		provider = ""
+	}
+	if b.total > 0 && b.cooldown > 0 && b.cooldown+b.window == b.total && !b.cooldownUntil.IsZero() {
+		return newModelCooldownError(model, provider, b.cooldownUntil.Sub(now))
+	}
+	if b.total > 0 && b.window == b.total {
+		return newOutsideAvailabilityWindowError(model, b.windowUntil, now)
+	}
+	return &Error{Code: "auth_unavailable", Message: "no auth available"}
+}
+
 type modelCooldownError struct {
 	model    string
 	resetIn  time.Duration
@@ -122,6 +181,65 @@ func authPriority(auth *Auth) int {
 	return parsed
 }
 
+// authWeight returns the relative traffic share configured for an auth.
+// Missing, invalid, or non-positive values default to 1.
+func authWeight(auth *Auth) int {
+	if auth == nil || auth.Attributes == nil {
+		return 1
+	}
+	raw := strings.TrimSpace(auth.Attributes["weight"])
+	if raw == "" {
+		return 1
+	}
+	parsed, err := strconv.Atoi(raw)
+	if err != nil || parsed <= 0 {
+		return 1
+	}
+	return parsed
+}
+
+// authMaxConcurrency returns the in-flight request limit configured for an auth.
+// Missing, invalid, or non-positive values mean unlimited and return 0.
+func authMaxConcurrency(auth *Auth) int {
+	if auth == nil || auth.Attributes == nil {
+		return 0
+	}
+	raw := strings.TrimSpace(auth.Attributes["max_concurrency"])
+	if raw == "" {
+		return 0
+	}
+	parsed, err := strconv.Atoi(raw)
+	if err != nil || parsed <= 0 {
+		return 0
+	}
+	return parsed
+}
+
+// weightedSlot maps a cursor slot onto auths laid out by cumulative weight.
+// It returns -1 when every auth has the default weight so callers keep plain rotation.
+func weightedSlot(auths []*Auth, slot int) int {
+	total := 0
+	uniform := true
+	for _, candidate := range auths {
+		weight := authWeight(candidate)
+		if weight != 1 {
+			uniform = false
+		}
+		total += weight
+	}
+	if uniform || total <= 0 {
+		return -1
+	}
+	slot %= total
+	for i, candidate := range auths {
+		slot -= authWeight(candidate)
+		if slot < 0 {
+			return i
+		}
+	}
+	return len(auths) - 1
+}
+
 func canonicalModelKey(model string) string {
 	model = strings.TrimSpace(model)
 	if model == "" {
@@ -191,8 +309,9 @@ func preferCodexWebsocketAuths(ctx context.Context, provider string, available [
 	return available
 }
 
-func collectAvailableByPriority(auths []*Auth, model string, now time.Time) (available map[int][]*Auth, cooldownCount int, earliest time.Time) {
+func collectAvailableByPriority(auths []*Auth, model string, now time.Time) (available map[int][]*Auth, summary blockedSummary) {
 	available = make(map[int][]*Auth)
+	summary.total = len(auths)
 	for i := 0; i < len(auths); i++ {
 		candidate := auths[i]
 		blocked, reason, next := isAuthBlockedForModel(candidate, model, now)
@@ -201,14 +320,9 @@ func collectAvailableByPriority(auths []*Auth, model string, now time.Time) (ava
 			available[priority] = append(available[priority], candidate)
 			continue
 		}
-		if reason == blockReasonCooldown {
-			cooldownCount++
-			if !next.IsZero() && (earliest.IsZero() || next.Before(earliest)) {
-				earliest = next
-			}
-		}
+		summary.add(reason, next)
 	}
-	return available, cooldownCount, earliest
+	return available, summary
 }
 
 func getAvailableAuths(auths []*Auth, provider, model string, now time.Time) ([]*Auth, error) {
@@ -216,20 +330,10 @@ func getAvailableAuths(auths []*Auth, provider, model string, now time.Time) ([]
 		return nil, &Error{Code: "auth_not_found", Message: "no auth candidates"}
 	}
 
-	availableByPriority, cooldownCount, earliest := collectAvailableByPriority(auths, model, now)
+	availableByPriority, summary := collectAvailableByPriority(auths, model, now)
+	availableByPriority = preferQuotaHeadroom(availableByPriority, model, now)
 	if len(availableByPriority) == 0 {
-		if cooldownCount == len(auths) && !earliest.IsZero() {
-			providerForError := provider
-			if providerForError == "mixed" {
-				providerForError = ""
-			}
-			resetIn := earliest.Sub(now)
-			if resetIn < 0 {
-				resetIn = 0
-			}
-			return nil, newModelCooldownError(model, providerForError, resetIn)
-		}
-		return nil, &Error{Code: "auth_unavailable", Message: "no auth available"}
+		return nil, summary.unavailableError(provider, model, now)
 	}
 
 	bestPriority := 0
@@ -310,6 +414,9 @@ func (s *RoundRobinSelector) Pick(ctx context.Context, provider, model string, o
 	}
 	s.cursors[key] = index + 1
 	s.mu.Unlock()
+	if weighted := weightedSlot(available, index); weighted >= 0 {
+		return available[weighted], nil
+	}
 	return available[index%len(available)], nil
 }
 
@@ -369,6 +476,9 @@ func isAuthBlockedForModel(auth *Auth, model string, now time.Time) (bool, block
 	if auth.Disabled || auth.Status == StatusDisabled {
 		return true, blockReasonDisabled, time.Time{}
 	}
+	if blocked, next := availabilityBlocked(auth, model, now); blocked {
+		return true, blockReasonAvailabilityWindow, next
+	}
 	if model != "" {
 		if len(auth.ModelStates) > 0 {
 			state, ok := auth.ModelStates[model]
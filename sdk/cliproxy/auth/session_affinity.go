@@ -0,0 +1,203 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	"github.com/tidwall/gjson"
+)
+
+const (
+	// SessionAffinityHeader carries an explicit client-chosen conversation key.
+	SessionAffinityHeader = "X-Session-Id"
+	// sessionAffinityDefaultTTL applies when routing.session-affinity.ttl-seconds is unset.
+	sessionAffinityDefaultTTL = time.Hour
+	// sessionAffinityMaxEntries caps the number of remembered conversations.
+	sessionAffinityMaxEntries = 65536
+)
+
+// sessionAffinity remembers which auth last served each conversation.
+type sessionAffinity struct {
+	mu      sync.Mutex
+	entries map[string]sessionAffinityEntry
+}
+
+type sessionAffinityEntry struct {
+	authID    string
+	expiresAt time.Time
+}
+
+func newSessionAffinity() *sessionAffinity {
+	return &sessionAffinity{entries: make(map[string]sessionAffinityEntry)}
+}
+
+// lookup returns the auth pinned to key, or "" when none is pinned or the pin expired.
+func (a *sessionAffinity) lookup(key string, now time.Time) string {
+	if a == nil || key == "" {
+		return ""
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	entry, ok := a.entries[key]
+	if !ok {
+		return ""
+	}
+	if !entry.expiresAt.After(now) {
+		delete(a.entries, key)
+		return ""
+	}
+	return entry.authID
+}
+
+// remember pins key to authID until now+ttl.
+func (a *sessionAffinity) remember(key, authID string, now time.Time, ttl time.Duration) {
+	if a == nil || key == "" || authID == "" {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, ok := a.entries[key]; !ok && len(a.entries) >= sessionAffinityMaxEntries {
+		for existingKey, entry := range a.entries {
+			if !entry.expiresAt.After(now) {
+				delete(a.entries, existingKey)
+			}
+		}
+		if len(a.entries) >= sessionAffinityMaxEntries {
+			a.entries = make(map[string]sessionAffinityEntry)
+		}
+	}
+	a.entries[key] = sessionAffinityEntry{authID: authID, expiresAt: now.Add(ttl)}
+}
+
+// sessionAffinitySettings returns whether affinity is enabled and the pin TTL.
+func (m *Manager) sessionAffinitySettings() (bool, time.Duration) {
+	if m == nil || m.affinity == nil {
+		return false, 0
+	}
+	cfg, _ := m.runtimeConfig.Load().(*internalconfig.Config)
+	if cfg == nil || !cfg.Routing.SessionAffinity.Enabled {
+		return false, 0
+	}
+	ttl := time.Duration(cfg.Routing.SessionAffinity.TTLSeconds) * time.Second
+	if ttl <= 0 {
+		ttl = sessionAffinityDefaultTTL
+	}
+	return true, ttl
+}
+
+// sessionAffinityKey derives the affinity key for a request, or "" when affinity does not apply.
+// Requests already pinned to an auth keep their explicit pin.
+func (m *Manager) sessionAffinityKey(scope, model string, opts cliproxyexecutor.Options) string {
+	if enabled, _ := m.sessionAffinitySettings(); !enabled {
+		return ""
+	}
+	if pinnedAuthIDFromMetadata(opts.Metadata) != "" {
+		return ""
+	}
+	session := sessionKeyFromOptions(opts)
+	if session == "" {
+		return ""
+	}
+	return scope + "|" + canonicalModelKey(model) + "|" + session
+}
+
+// sessionKeyFromOptions identifies the conversation a request belongs to.
+// It prefers an explicit session header, then prompt_cache_key, then metadata.user_id,
+// and finally a hash of the system prompt and first user turn.
+func sessionKeyFromOptions(opts cliproxyexecutor.Options) string {
+	if raw, ok := opts.Metadata[cliproxyexecutor.SessionKeyMetadataKey].(string); ok {
+		if key := strings.TrimSpace(raw); key != "" {
+			return "header:" + key
+		}
+	}
+	if opts.Headers != nil {
+		if key := strings.TrimSpace(opts.Headers.Get(SessionAffinityHeader)); key != "" {
+			return "header:" + key
+		}
+	}
+	payload := opts.OriginalRequest
+	if len(payload) == 0 || !gjson.ValidBytes(payload) {
+		return ""
+	}
+	if key := strings.TrimSpace(gjson.GetBytes(payload, "prompt_cache_key").String()); key != "" {
+		return "cache:" + key
+	}
+	if key := strings.TrimSpace(gjson.GetBytes(payload, "metadata.user_id").String()); key != "" {
+		return "user:" + key
+	}
+	return conversationFingerprint(payload)
+}
+
+// conversationFingerprint hashes the system prompt and first user turn of a request.
+// It understands Claude, OpenAI chat/responses, and Gemini request shapes.
+func conversationFingerprint(payload []byte) string {
+	firstUser := ""
+	for _, path := range []string{"messages", "input", "contents"} {
+		turns := gjson.GetBytes(payload, path)
+		if !turns.Exists() {
+			continue
+		}
+		if turns.Type == gjson.String {
+			firstUser = turns.Raw
+			break
+		}
+		turns.ForEach(func(_, turn gjson.Result) bool {
+			if turn.Get("role").String() == "user" {
+				firstUser = turn.Raw
+				return false
+			}
+			return true
+		})
+		if firstUser != "" {
+			break
+		}
+	}
+	if firstUser == "" {
+		return ""
+	}
+	hasher := sha256.New()
+	for _, path := range []string{"system", "instructions", "systemInstruction", "system_instruction"} {
+		if value := gjson.GetBytes(payload, path); value.Exists() {
+			hasher.Write([]byte(value.Raw))
+		}
+	}
+	if messages := gjson.GetBytes(payload, "messages"); messages.IsArray() {
+		messages.ForEach(func(_, turn gjson.Result) bool {
+			role := turn.Get("role").String()
+			if role != "system" && role != "developer" {
+				return false
+			}
+			hasher.Write([]byte(turn.Raw))
+			return true
+		})
+	}
+	hasher.Write([]byte{0})
+	hasher.Write([]byte(firstUser))
+	return "hash:" + hex.EncodeToString(hasher.Sum(nil))
+}
+
+// rememberSessionAffinity pins the conversation behind opts to authID.
+func (m *Manager) rememberSessionAffinity(scope, model string, opts cliproxyexecutor.Options, authID string) {
+	key := m.sessionAffinityKey(scope, model, opts)
+	if key == "" {
+		return
+	}
+	_, ttl := m.sessionAffinitySettings()
+	m.affinity.remember(key, authID, time.Now(), ttl)
+}
+
+// pinnedAffinityOptions returns opts with the affinity auth pinned for selection only.
+func pinnedAffinityOptions(opts cliproxyexecutor.Options, authID string) cliproxyexecutor.Options {
+	meta := make(map[string]any, len(opts.Metadata)+1)
+	for k, v := range opts.Metadata {
+		meta[k] = v
+	}
+	meta[cliproxyexecutor.PinnedAuthMetadataKey] = authID
+	opts.Metadata = meta
+	return opts
+}
@@ -0,0 +1,189 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+func TestSessionKeyFromOptions_Precedence(t *testing.T) {
+	t.Parallel()
+
+	payload := []byte(`{"prompt_cache_key":"cache-1","metadata":{"user_id":"user-1"},"messages":[{"role":"user","content":"hi"}]}`)
+	tests := []struct {
+		name string
+		opts cliproxyexecutor.Options
+		want string
+	}{
+		{
+			name: "metadata header",
+			opts: cliproxyexecutor.Options{
+				OriginalRequest: payload,
+				Metadata:        map[string]any{cliproxyexecutor.SessionKeyMetadataKey: " s-1 "},
+			},
+			want: "header:s-1",
+		},
+		{
+			name: "options header",
+			opts: cliproxyexecutor.Options{
+				OriginalRequest: payload,
+				Headers:         http.Header{SessionAffinityHeader: {"s-2"}},
+			},
+			want: "header:s-2",
+		},
+		{
+			name: "prompt cache key",
+			opts: cliproxyexecutor.Options{OriginalRequest: payload},
+			want: "cache:cache-1",
+		},
+		{
+			name: "metadata user id",
+			opts: cliproxyexecutor.Options{OriginalRequest: []byte(`{"metadata":{"user_id":"user-1"},"messages":[{"role":"user","content":"hi"}]}`)},
+			want: "user:user-1",
+		},
+		{
+			name: "no conversation",
+			opts: cliproxyexecutor.Options{OriginalRequest: []byte(`{"model":"m"}`)},
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sessionKeyFromOptions(tt.opts); got != tt.want {
+				t.Fatalf("sessionKeyFromOptions() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConversationFingerprint_StableAcrossTurns(t *testing.T) {
+	t.Parallel()
+
+	first := conversationFingerprint([]byte(`{"system":"be nice","messages":[{"role":"user","content":"hello"}]}`))
+	later := conversationFingerprint([]byte(`{"system":"be nice","messages":[{"role":"user","content":"hello"},{"role":"assistant","content":"hi"},{"role":"user","content":"more"}]}`))
+	other := conversationFingerprint([]byte(`{"system":"be nice","messages":[{"role":"user","content":"goodbye"}]}`))
+	if first == "" {
+		t.Fatalf("conversationFingerprint() = empty, want hash")
+	}
+	if first != later {
+		t.Fatalf("fingerprint changed across turns: %q vs %q", first, later)
+	}
+	if first == other {
+		t.Fatalf("fingerprint collided for different conversations: %q", first)
+	}
+
+	openAI := conversationFingerprint([]byte(`{"messages":[{"role":"system","content":"a"},{"role":"user","content":"hello"}]}`))
+	openAIOther := conversationFingerprint([]byte(`{"messages":[{"role":"system","content":"b"},{"role":"user","content":"hello"}]}`))
+	if openAI == openAIOther {
+		t.Fatalf("fingerprint ignored OpenAI system message")
+	}
+	gemini := conversationFingerprint([]byte(`{"systemInstruction":{"parts":[{"text":"x"}]},"contents":[{"role":"user","parts":[{"text":"hello"}]}]}`))
+	if gemini == "" {
+		t.Fatalf("conversationFingerprint() for Gemini = empty, want hash")
+	}
+}
+
+func newSessionAffinityTestManager(t *testing.T, enabled bool, model string, authIDs ...string) *Manager {
+	t.Helper()
+
+	registerSchedulerModels(t, "gemini", model, authIDs...)
+	manager := NewManager(nil, &RoundRobinSelector{}, nil)
+	manager.RegisterExecutor(&openAICompatPoolExecutor{id: "gemini"})
+	manager.SetConfig(&internalconfig.Config{
+		Routing: internalconfig.RoutingConfig{
+			SessionAffinity: internalconfig.SessionAffinityConfig{Enabled: enabled},
+		},
+	})
+	for _, id := range authIDs {
+		if _, err := manager.Register(context.Background(), &Auth{ID: id, Provider: "gemini"}); err != nil {
+			t.Fatalf("Register(%s) error = %v", id, err)
+		}
+	}
+	return manager
+}
+
+func TestManagerPickNextMixed_SessionAffinityKeepsConversationOnAuth(t *testing.T) {
+	model := "affinity-sticky-model"
+	manager := newSessionAffinityTestManager(t, true, model, "affinity-sticky-a", "affinity-sticky-b")
+	opts := cliproxyexecutor.Options{OriginalRequest: []byte(`{"prompt_cache_key":"conversation-1"}`)}
+
+	first, _, _, errPick := manager.pickNextMixed(context.Background(), []string{"gemini"}, model, opts, nil)
+	if errPick != nil {
+		t.Fatalf("pickNextMixed() error = %v", errPick)
+	}
+	for index := 0; index < 4; index++ {
+		got, _, _, errPick := manager.pickNextMixed(context.Background(), []string{"gemini"}, model, opts, nil)
+		if errPick != nil {
+			t.Fatalf("pickNextMixed() #%d error = %v", index, errPick)
+		}
+		if got.ID != first.ID {
+			t.Fatalf("pickNextMixed() #%d auth.ID = %q, want pinned %q", index, got.ID, first.ID)
+		}
+	}
+
+	other := cliproxyexecutor.Options{OriginalRequest: []byte(`{"prompt_cache_key":"conversation-2"}`)}
+	got, _, _, errPick := manager.pickNextMixed(context.Background(), []string{"gemini"}, model, other, nil)
+	if errPick != nil {
+		t.Fatalf("pickNextMixed(other) error = %v", errPick)
+	}
+	if got.ID == first.ID {
+		t.Fatalf("pickNextMixed(other) auth.ID = %q, want round-robin to move on", got.ID)
+	}
+}
+
+func TestManagerPickNextMixed_SessionAffinityMovesWhenAuthCoolsDown(t *testing.T) {
+	model := "affinity-cooldown-model"
+	manager := newSessionAffinityTestManager(t, true, model, "affinity-cooldown-a", "affinity-cooldown-b")
+	opts := cliproxyexecutor.Options{OriginalRequest: []byte(`{"prompt_cache_key":"conversation-1"}`)}
+
+	first, _, _, errPick := manager.pickNextMixed(context.Background(), []string{"gemini"}, model, opts, nil)
+	if errPick != nil {
+		t.Fatalf("pickNextMixed() error = %v", errPick)
+	}
+	retryAfter := time.Hour
+	manager.MarkResult(context.Background(), Result{
+		AuthID:     first.ID,
+		Provider:   "gemini",
+		Model:      model,
+		Success:    false,
+		RetryAfter: &retryAfter,
+		Error:      &Error{HTTPStatus: http.StatusTooManyRequests, Message: "quota"},
+	})
+
+	moved, _, _, errPick := manager.pickNextMixed(context.Background(), []string{"gemini"}, model, opts, nil)
+	if errPick != nil {
+		t.Fatalf("pickNextMixed() after cooldown error = %v", errPick)
+	}
+	if moved.ID == first.ID {
+		t.Fatalf("pickNextMixed() after cooldown auth.ID = %q, want a different auth", moved.ID)
+	}
+	again, _, _, errPick := manager.pickNextMixed(context.Background(), []string{"gemini"}, model, opts, nil)
+	if errPick != nil {
+		t.Fatalf("pickNextMixed() repeat error = %v", errPick)
+	}
+	if again.ID != moved.ID {
+		t.Fatalf("pickNextMixed() repeat auth.ID = %q, want new pin %q", again.ID, moved.ID)
+	}
+}
+
+func TestManagerPickNext_SessionAffinityDisabledRotates(t *testing.T) {
+	model := "affinity-disabled-model"
+	manager := newSessionAffinityTestManager(t, false, model, "affinity-disabled-a", "affinity-disabled-b")
+	opts := cliproxyexecutor.Options{OriginalRequest: []byte(`{"prompt_cache_key":"conversation-1"}`)}
+
+	first, _, errPick := manager.pickNext(context.Background(), "gemini", model, opts, nil)
+	if errPick != nil {
+		t.Fatalf("pickNext() error = %v", errPick)
+	}
+	second, _, errPick := manager.pickNext(context.Background(), "gemini", model, opts, nil)
+	if errPick != nil {
+		t.Fatalf("pickNext() error = %v", errPick)
+	}
+	if first.ID == second.ID {
+		t.Fatalf("pickNext() returned %q twice, want rotation when affinity is disabled", first.ID)
+	}
+}
@@ -1,6 +1,9 @@
 package auth
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 // Store abstracts persistence of Auth state across restarts.
 type Store interface {
@@ -11,3 +14,37 @@ type Store interface {
 	// Delete removes the auth record identified by id.
 	Delete(ctx context.Context, id string) error
 }
+
+// RuntimeStateStore is implemented by stores that also persist runtime availability state
+// (cooldowns, per-model state, quota backoff) so that it survives restarts.
+type RuntimeStateStore interface {
+	// LoadRuntimeState returns the saved runtime state keyed by auth ID.
+	LoadRuntimeState(ctx context.Context) (map[string]*RuntimeState, error)
+	// SaveRuntimeState replaces the saved runtime state with states.
+	SaveRuntimeState(ctx context.Context, states map[string]*RuntimeState) error
+}
+
+// ClientKeyUsageStore is implemented by stores that also persist the usage counters behind
+// client key budgets so that a restart does not hand every key a fresh budget.
+type ClientKeyUsageStore interface {
+	// LoadClientKeyUsage returns the saved counters keyed by client key digest.
+	LoadClientKeyUsage(ctx context.Context) (map[string]*ClientKeyCounters, error)
+	// SaveClientKeyUsage replaces the saved counters with usage.
+	SaveClientKeyUsage(ctx context.Context, usage map[string]*ClientKeyCounters) error
+}
+
+// RefreshLocker is implemented by stores that several proxy replicas may share. The manager holds
+// the refresh lease of an auth while refreshing it, so providers that rotate refresh tokens never
+// see the same refresh token used twice.
+type RefreshLocker interface {
+	// LockRefresh blocks until the refresh lease for auth is held or ctx ends. The lease lapses
+	// after ttl when it is never released, e.g. because its holder crashed.
+	LockRefresh(ctx context.Context, auth *Auth, ttl time.Duration) (unlock func(), err error)
+}
+
+// AuthReloader is implemented by stores that can re-read a single auth from the shared backend
+// rather than from the local mirror.
+type AuthReloader interface {
+	// ReloadAuth returns the latest persisted version of auth, or nil when it no longer exists.
+	ReloadAuth(ctx context.Context, auth *Auth) (*Auth, error)
+}
@@ -105,6 +105,20 @@ type QuotaState struct {
 	NextRecoverAt time.Time `json:"next_recover_at"`
 	// BackoffLevel stores the progressive cooldown exponent used for rate limits.
 	BackoffLevel int `json:"backoff_level,omitempty"`
+	// RateLimit holds the upstream budget last reported in rate-limit response headers.
+	RateLimit *RateLimitState `json:"rate_limit,omitempty"`
+}
+
+// RateLimitState records the tightest upstream rate-limit window seen on a response.
+type RateLimitState struct {
+	// Remaining is the share of the window still available, between 0 and 1.
+	Remaining float64 `json:"remaining"`
+	// ResetAt is when the window resets; zero when the provider did not say.
+	ResetAt time.Time `json:"reset_at"`
+	// Window names the reporting window, e.g. "requests", "tokens" or "codex-primary".
+	Window string `json:"window,omitempty"`
+	// ObservedAt is when the headers were received.
+	ObservedAt time.Time `json:"observed_at"`
 }
 
 // ModelState captures the execution state for a specific model under an auth entry.
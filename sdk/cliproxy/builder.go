@@ -215,6 +215,8 @@ func (b *Builder) Build() (*Service, error) {
 		switch strategy {
 		case "fill-first", "fillfirst", "ff":
 			selector = &coreauth.FillFirstSelector{}
+		case "adaptive":
+			selector = coreauth.NewAdaptiveSelector()
 		default:
 			selector = &coreauth.RoundRobinSelector{}
 		}
@@ -0,0 +1,148 @@
+package executor
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RateLimit summarises the upstream rate-limit budget advertised on a response.
+// Only the tightest window is kept because that is the one that will trip first.
+type RateLimit struct {
+	// Remaining is the share of the window still available, between 0 and 1.
+	Remaining float64
+	// ResetAt is when the window behind Remaining resets; zero when unknown.
+	ResetAt time.Time
+	// Window names the reporting window, e.g. "requests", "tokens" or "codex-primary".
+	Window string
+}
+
+// ParseRateLimitHeaders extracts the tightest rate-limit window from upstream response
+// headers. It understands Anthropic (anthropic-ratelimit-*), OpenAI-style
+// (x-ratelimit-*) and Codex usage (x-codex-*) headers and returns nil when none are set.
+func ParseRateLimitHeaders(headers http.Header, now time.Time) *RateLimit {
+	if len(headers) == 0 {
+		return nil
+	}
+	var tightest *RateLimit
+	consider := func(window string, remaining float64, resetAt time.Time) {
+		if remaining < 0 {
+			remaining = 0
+		} else if remaining > 1 {
+			remaining = 1
+		}
+		if tightest != nil {
+			if remaining > tightest.Remaining {
+				return
+			}
+			if remaining == tightest.Remaining && (resetAt.IsZero() || (!tightest.ResetAt.IsZero() && !resetAt.Before(tightest.ResetAt))) {
+				return
+			}
+		}
+		tightest = &RateLimit{Remaining: remaining, ResetAt: resetAt, Window: window}
+	}
+
+	for _, window := range []string{"requests", "tokens", "input-tokens", "output-tokens"} {
+		prefix := "Anthropic-Ratelimit-" + window
+		remaining, ok := remainingShare(headers.Get(prefix+"-Limit"), headers.Get(prefix+"-Remaining"))
+		if !ok {
+			continue
+		}
+		consider(window, remaining, parseResetTimestamp(headers.Get(prefix+"-Reset")))
+	}
+	for _, window := range []string{"5h", "7d"} {
+		prefix := "Anthropic-Ratelimit-Unified-" + window
+		used, ok := parseFloatHeader(headers.Get(prefix + "-Utilization"))
+		if !ok {
+			continue
+		}
+		consider("unified-"+window, 1-used, parseUnixHeader(headers.Get(prefix+"-Reset")))
+	}
+	if strings.EqualFold(strings.TrimSpace(headers.Get("Anthropic-Ratelimit-Unified-Status")), "rejected") {
+		consider("unified", 0, parseUnixHeader(headers.Get("Anthropic-Ratelimit-Unified-Reset")))
+	}
+
+	for _, window := range []string{"requests", "tokens"} {
+		remaining, ok := remainingShare(headers.Get("X-Ratelimit-Limit-"+window), headers.Get("X-Ratelimit-Remaining-"+window))
+		if !ok {
+			continue
+		}
+		consider(window, remaining, parseResetDelay(headers.Get("X-Ratelimit-Reset-"+window), now))
+	}
+
+	for _, window := range []string{"primary", "secondary"} {
+		prefix := "X-Codex-" + window
+		usedPercent, ok := parseFloatHeader(headers.Get(prefix + "-Used-Percent"))
+		if !ok {
+			continue
+		}
+		resetAt := parseUnixHeader(headers.Get(prefix + "-Reset-At"))
+		if resetAt.IsZero() {
+			resetAt = parseResetDelay(headers.Get(prefix+"-Reset-After-Seconds"), now)
+		}
+		consider("codex-"+window, (100-usedPercent)/100, resetAt)
+	}
+	return tightest
+}
+
+// remainingShare converts limit/remaining counters into the remaining share of the window.
+func remainingShare(rawLimit, rawRemaining string) (float64, bool) {
+	limit, okLimit := parseFloatHeader(rawLimit)
+	remaining, okRemaining := parseFloatHeader(rawRemaining)
+	if !okLimit || !okRemaining || limit <= 0 {
+		return 0, false
+	}
+	return remaining / limit, true
+}
+
+func parseFloatHeader(raw string) (float64, bool) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return 0, false
+	}
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return 0, false
+	}
+	return value, true
+}
+
+// parseResetTimestamp parses RFC 3339 reset times, falling back to Unix seconds.
+func parseResetTimestamp(raw string) time.Time {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return time.Time{}
+	}
+	if parsed, err := time.Parse(time.RFC3339, raw); err == nil {
+		return parsed
+	}
+	return parseUnixHeader(raw)
+}
+
+func parseUnixHeader(raw string) time.Time {
+	seconds, ok := parseFloatHeader(raw)
+	if !ok || seconds <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(int64(seconds), 0)
+}
+
+// parseResetDelay parses relative resets such as "20ms", "6m0s" or a bare number of seconds.
+func parseResetDelay(raw string, now time.Time) time.Time {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return time.Time{}
+	}
+	if seconds, ok := parseFloatHeader(raw); ok {
+		if seconds < 0 {
+			return time.Time{}
+		}
+		return now.Add(time.Duration(seconds * float64(time.Second)))
+	}
+	delay, err := time.ParseDuration(raw)
+	if err != nil || delay < 0 {
+		return time.Time{}
+	}
+	return now.Add(delay)
+}
@@ -0,0 +1,61 @@
+package executor
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseRateLimitHeaders_Anthropic(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	headers := http.Header{}
+	headers.Set("anthropic-ratelimit-requests-limit", "100")
+	headers.Set("anthropic-ratelimit-requests-remaining", "50")
+	headers.Set("anthropic-ratelimit-requests-reset", now.Add(time.Minute).Format(time.RFC3339))
+	headers.Set("anthropic-ratelimit-tokens-limit", "1000")
+	headers.Set("anthropic-ratelimit-tokens-remaining", "10")
+	headers.Set("anthropic-ratelimit-tokens-reset", now.Add(30*time.Second).Format(time.RFC3339))
+
+	got := ParseRateLimitHeaders(headers, now)
+	if got == nil {
+		t.Fatalf("ParseRateLimitHeaders() = nil, want tokens window")
+	}
+	if got.Window != "tokens" || got.Remaining != 0.01 || !got.ResetAt.Equal(now.Add(30*time.Second)) {
+		t.Fatalf("ParseRateLimitHeaders() = %+v, want tokens 0.01 resetting in 30s", got)
+	}
+}
+
+func TestParseRateLimitHeaders_OpenAIRelativeReset(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	headers := http.Header{}
+	headers.Set("x-ratelimit-limit-requests", "60")
+	headers.Set("x-ratelimit-remaining-requests", "3")
+	headers.Set("x-ratelimit-reset-requests", "6m0s")
+
+	got := ParseRateLimitHeaders(headers, now)
+	if got == nil || got.Window != "requests" || got.Remaining != 0.05 || !got.ResetAt.Equal(now.Add(6*time.Minute)) {
+		t.Fatalf("ParseRateLimitHeaders() = %+v, want requests 0.05 resetting in 6m", got)
+	}
+}
+
+func TestParseRateLimitHeaders_CodexUsage(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	headers := http.Header{}
+	headers.Set("x-codex-primary-used-percent", "40")
+	headers.Set("x-codex-primary-reset-after-seconds", "120")
+	headers.Set("x-codex-secondary-used-percent", "97.5")
+	headers.Set("x-codex-secondary-reset-at", "1767326400")
+
+	got := ParseRateLimitHeaders(headers, now)
+	if got == nil || got.Window != "codex-secondary" || got.Remaining != 0.025 || !got.ResetAt.Equal(time.Unix(1767326400, 0)) {
+		t.Fatalf("ParseRateLimitHeaders() = %+v, want codex-secondary at 0.025", got)
+	}
+}
+
+func TestParseRateLimitHeaders_NoneReported(t *testing.T) {
+	headers := http.Header{}
+	headers.Set("Content-Type", "application/json")
+	if got := ParseRateLimitHeaders(headers, time.Now()); got != nil {
+		t.Fatalf("ParseRateLimitHeaders() = %+v, want nil", got)
+	}
+}
@@ -19,6 +19,18 @@ const (
 	SelectedAuthCallbackMetadataKey = "selected_auth_callback"
 	// ExecutionSessionMetadataKey identifies a long-lived downstream execution session.
 	ExecutionSessionMetadataKey = "execution_session_id"
+	// SessionKeyMetadataKey carries a client-supplied conversation key used for credential affinity.
+	SessionKeyMetadataKey = "session_key"
+	// ClientKeyMetadataKey identifies the downstream client API key for queue fairness and
+	// managed client key policy.
+	ClientKeyMetadataKey = "client_key"
+	// ClientKeyNameMetadataKey carries the name of the managed client key behind the request.
+	ClientKeyNameMetadataKey = "client_key_name"
+	// QueuedCallbackMetadataKey carries an optional callback invoked when the request starts waiting in the queue.
+	QueuedCallbackMetadataKey = "queued_callback"
+	// BackgroundMetadataKey marks low-priority work, such as batch items, that must not wait in
+	// the request queue ahead of interactive requests.
+	BackgroundMetadataKey = "background"
 )
 
 // Request encapsulates the translated payload that will be sent to a provider executor.
@@ -59,6 +71,8 @@ type Response struct {
 	Metadata map[string]any
 	// Headers carries upstream HTTP response headers for passthrough to clients.
 	Headers http.Header
+	// RateLimit carries the upstream rate-limit budget parsed from Headers, when reported.
+	RateLimit *RateLimit
 }
 
 // StreamChunk represents a single streaming payload unit emitted by provider executors.
@@ -74,6 +88,8 @@ type StreamChunk struct {
 type StreamResult struct {
 	// Headers carries upstream HTTP response headers from the initial connection.
 	Headers http.Header
+	// RateLimit carries the upstream rate-limit budget parsed from Headers, when reported.
+	RateLimit *RateLimit
 	// Chunks is the channel of streaming payload units.
 	Chunks <-chan StreamChunk
 }
@@ -516,6 +516,9 @@ func (s *Service) Run(ctx context.Context) error {
 	}
 
 	usage.StartDefault(ctx)
+	if s.coreManager != nil {
+		usage.RegisterPlugin(s.coreManager.ClientKeyUsagePlugin())
+	}
 
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer shutdownCancel()
@@ -666,6 +669,8 @@ func (s *Service) Run(ctx context.Context) error {
 			switch strategy {
 			case "fill-first", "fillfirst", "ff":
 				return "fill-first"
+			case "adaptive":
+				return "adaptive"
 			default:
 				return "round-robin"
 			}
@@ -677,6 +682,8 @@ func (s *Service) Run(ctx context.Context) error {
 			switch nextStrategy {
 			case "fill-first":
 				selector = &coreauth.FillFirstSelector{}
+			case "adaptive":
+				selector = coreauth.NewAdaptiveSelector()
 			default:
 				selector = &coreauth.RoundRobinSelector{}
 			}
@@ -770,6 +777,9 @@ func (s *Service) Shutdown(ctx context.Context) error {
 		}
 		if s.coreManager != nil {
 			s.coreManager.StopAutoRefresh()
+			if err := s.coreManager.SaveRuntimeState(ctx); err != nil {
+				log.Errorf("failed to save auth runtime state: %v", err)
+			}
 		}
 		if s.watcher != nil {
 			if err := s.watcher.Stop(); err != nil {
@@ -13,6 +13,7 @@ type Record struct {
 	Provider    string
 	Model       string
 	APIKey      string
+	ClientKey   string
 	AuthID      string
 	AuthIndex   string
 	Source      string
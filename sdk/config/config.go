@@ -29,6 +29,12 @@ type OpenAICompatibility = internalconfig.OpenAICompatibility
 type OpenAICompatibilityAPIKey = internalconfig.OpenAICompatibilityAPIKey
 type OpenAICompatibilityModel = internalconfig.OpenAICompatibilityModel
 
+type ClientKey = internalconfig.ClientKey
+type ClientKeyBudget = internalconfig.ClientKeyBudget
+type OIDCJWTConfig = internalconfig.OIDCJWTConfig
+type MTLSConfig = internalconfig.MTLSConfig
+type MTLSPrincipal = internalconfig.MTLSPrincipal
+
 type TLS = internalconfig.TLSConfig
 
 const (
@@ -52,3 +58,5 @@ func SaveConfigPreserveCommentsUpdateNestedScalar(configFile string, path []stri
 func NormalizeCommentIndentation(data []byte) []byte {
 	return internalconfig.NormalizeCommentIndentation(data)
 }
+
+func ClientKeyDigest(key string) string { return internalconfig.ClientKeyDigest(key) }